@@ -0,0 +1,476 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/smeneguz/GoMerkle/merkletree"
+)
+
+// manifestFormat identifies the manifest layout written by the build command.
+const manifestFormat = "gomerkle-manifest-v1"
+
+// Manifest records everything needed to rebuild a tree and check its root.
+type Manifest struct {
+	Format         string               `json:"format"`
+	LibraryVersion string               `json:"libraryVersion"`
+	Config         Config               `json:"config"`
+	InputSHA256    string               `json:"inputSha256"`
+	LeafCount      int                  `json:"leafCount"`
+	Root           merkletree.HexString `json:"root"`
+}
+
+// BuildOutput is the document written by the build command:
+// the tree dump together with the manifest describing how it was built.
+type BuildOutput struct {
+	Manifest Manifest        `json:"manifest"`
+	Tree     json.RawMessage `json:"tree"`
+}
+
+// CodeRootMismatch is the error code of ErrRootMismatch.
+const CodeRootMismatch = "MERKLE_ROOT_MISMATCH"
+
+// ErrRootMismatch is returned by reproduce when the rebuilt root differs from the manifest.
+var ErrRootMismatch = merkletree.NewCodedError(CodeRootMismatch, "root mismatch")
+
+// readInput reads one value per line from path and returns the values
+// together with the hex-encoded SHA-256 checksum of the file contents.
+func readInput(path string) ([]string, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(data)
+
+	var values []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		values = append(values, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", err
+	}
+	return values, hex.EncodeToString(sum[:]), nil
+}
+
+// decodeChecksum validates a hex-encoded SHA-256 checksum.
+func decodeChecksum(s string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(s), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid checksum %q: %w", s, err)
+	}
+	if len(b) != sha256.Size {
+		return nil, fmt.Errorf("invalid checksum %q: expected %d bytes, got %d", s, sha256.Size, len(b))
+	}
+	return b, nil
+}
+
+// sameChecksum reports whether two hex checksums are equal, ignoring case and prefix.
+func sameChecksum(a, b string) bool {
+	return strings.TrimPrefix(strings.ToLower(a), "0x") == strings.TrimPrefix(strings.ToLower(b), "0x")
+}
+
+// builtTree is a tree built from a config, whatever the type of its values.
+type builtTree struct {
+	root       merkletree.HexString
+	stamp      func() (merkletree.Stamp, error)
+	dump       func() ([]byte, error)
+	claim      func(leaf any, requester string) (any, error)
+	setLog     func(record issuanceLog)
+	goSource   func(w io.Writer, opts merkletree.GoSourceOptions) error
+	proofStats func() (merkletree.ProofStatsReport, error)
+	value      func(s string) any // The tree's value for a value given on the command line
+}
+
+// newTree builds the tree described by cfg over values. Under the
+// "openzeppelin" leaf encoding each value holds the fields of one leaf,
+// separated by commas.
+func newTree(cfg Config, values []string) (builtTree, error) {
+	options := merkletree.MerkleTreeOptions{SortLeaves: merkletree.Bool(cfg.SortLeaves)}
+
+	switch {
+	case cfg.Tree == "simple":
+		leaves := make([]merkletree.BytesLike, len(values))
+		for i, v := range values {
+			leaves[i] = v
+		}
+		tree, err := merkletree.NewSimpleMerkleTree(leaves, merkletree.SimpleMerkleTreeOptions{MerkleTreeOptions: options, HashAlgorithm: cfg.Hash})
+		if err != nil {
+			return builtTree{}, err
+		}
+		return wrapTree(&tree.MerkleTreeImpl, tree.Dump, func(s string) any { return merkletree.BytesLike(s) }), nil
+	case cfg.Encoding == merkletree.LeafEncodingOpenZeppelin:
+		rows := make([][]any, len(values))
+		for i, v := range values {
+			rows[i] = leafFields(v)
+		}
+		options.PreserveOrder = !cfg.SortLeaves
+		tree, err := merkletree.NewStandardMerkleTreeWithEncoding(rows, cfg.leafTypes(), options)
+		if err != nil {
+			return builtTree{}, err
+		}
+		return wrapTree(&tree.MerkleTreeImpl, tree.Dump, func(s string) any { return leafFields(s) }), nil
+	default:
+		tree, err := merkletree.NewStandardMerkleTree(values, options)
+		if err != nil {
+			return builtTree{}, err
+		}
+		return wrapTree(&tree.MerkleTreeImpl, tree.Dump, func(s string) any { return s }), nil
+	}
+}
+
+// wrapTree returns the builtTree of tree, whose dump is made by dump.
+func wrapTree[T, D any](tree *merkletree.MerkleTreeImpl[T], dump func() (D, error), value func(string) any) builtTree {
+	return builtTree{
+		root:  tree.Root(),
+		stamp: tree.BuildStamp,
+		dump: func() ([]byte, error) {
+			data, err := dump()
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(data)
+		},
+		claim: func(leaf any, requester string) (any, error) {
+			return tree.ExportClaimFor(leaf, requester)
+		},
+		setLog: func(record issuanceLog) {
+			tree.SetIssuanceRecorder(record.recorder, record.options)
+		},
+		goSource:   tree.ExportGoSourceWithOptions,
+		proofStats: tree.ProofStats,
+		value:      value,
+	}
+}
+
+// leafFields splits an "openzeppelin" value into its fields.
+func leafFields(s string) []any {
+	parts := strings.Split(s, ",")
+	fields := make([]any, len(parts))
+	for i, part := range parts {
+		fields[i] = strings.TrimSpace(part)
+	}
+	return fields
+}
+
+// buildTree builds the tree described by cfg over values and returns its stamp and JSON dump.
+func buildTree(cfg Config, values []string) (merkletree.Stamp, []byte, error) {
+	tree, err := newTree(cfg, values)
+	if err != nil {
+		return merkletree.Stamp{}, nil, err
+	}
+	stamp, err := tree.stamp()
+	if err != nil {
+		return merkletree.Stamp{}, nil, err
+	}
+	dump, err := tree.dump()
+	return stamp, dump, err
+}
+
+// stampPath returns the path of the stamp written next to the build output at path.
+func stampPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".stamp.json"
+}
+
+// readStamp reads a stamp written by the build command.
+func readStamp(path string) (merkletree.Stamp, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return merkletree.Stamp{}, err
+	}
+	var stamp merkletree.Stamp
+	if err := json.Unmarshal(data, &stamp); err != nil {
+		return merkletree.Stamp{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return stamp, nil
+}
+
+// loadInput reads the input described by cfg and verifies its checksum when pinned.
+func loadInput(cfg Config) ([]string, string, error) {
+	values, checksum, err := readInput(cfg.Input)
+	if err != nil {
+		return nil, "", fmt.Errorf("reading input: %w", err)
+	}
+	if cfg.InputSHA256 != "" && !sameChecksum(cfg.InputSHA256, checksum) {
+		return nil, "", fmt.Errorf("input checksum mismatch for %s: config pins %s, file has %s",
+			cfg.Input, cfg.InputSHA256, checksum)
+	}
+	return values, checksum, nil
+}
+
+// estimateBuild returns the number of values in the input described by cfg
+// and the memory needed to build their tree.
+func estimateBuild(cfg Config) (int, merkletree.MemoryEstimate, error) {
+	values, _, err := loadInput(cfg)
+	if err != nil {
+		return 0, merkletree.MemoryEstimate{}, err
+	}
+	estimate, err := merkletree.EstimateBuildMemory(len(values), merkletree.MerkleTreeOptions{SortLeaves: merkletree.Bool(cfg.SortLeaves)})
+	return len(values), estimate, err
+}
+
+// build reads the input described by cfg, verifies its checksum when pinned,
+// and returns the tree dump with an embedded manifest, and the tree's stamp.
+func build(cfg Config) (BuildOutput, merkletree.Stamp, error) {
+	values, checksum, err := loadInput(cfg)
+	if err != nil {
+		return BuildOutput{}, merkletree.Stamp{}, err
+	}
+
+	stamp, dump, err := buildTree(cfg, values)
+	if err != nil {
+		return BuildOutput{}, merkletree.Stamp{}, fmt.Errorf("building tree: %w", err)
+	}
+
+	return BuildOutput{
+		Manifest: Manifest{
+			Format:         manifestFormat,
+			LibraryVersion: merkletree.Version(),
+			Config:         cfg,
+			InputSHA256:    checksum,
+			LeafCount:      len(values),
+			Root:           stamp.Root,
+		},
+		Tree: dump,
+	}, stamp, nil
+}
+
+// readBuildOutput reads a document written by the build command.
+func readBuildOutput(path string) (BuildOutput, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return BuildOutput{}, err
+	}
+
+	var output BuildOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return BuildOutput{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if output.Manifest.Format != manifestFormat {
+		return BuildOutput{}, fmt.Errorf("%s: unsupported manifest format %q", path, output.Manifest.Format)
+	}
+	return output, nil
+}
+
+// dumpValues returns the values of a dumped tree in insertion order. The
+// fields of an "openzeppelin" value are joined with commas, as in the input.
+func dumpValues(dump json.RawMessage) ([]string, error) {
+	var data struct {
+		Values []struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"values"`
+	}
+	if err := json.Unmarshal(dump, &data); err != nil {
+		return nil, fmt.Errorf("invalid tree dump: %w", err)
+	}
+
+	values := make([]string, len(data.Values))
+	for i, v := range data.Values {
+		var fields []string
+		if err := json.Unmarshal(v.Value, &values[i]); err == nil {
+			continue
+		}
+		if err := json.Unmarshal(v.Value, &fields); err != nil {
+			return nil, fmt.Errorf("invalid tree dump: value %d: %w", i, err)
+		}
+		values[i] = strings.Join(fields, ",")
+	}
+	return values, nil
+}
+
+// issuanceLog is where exportClaim records the claims it issues.
+type issuanceLog struct {
+	recorder  merkletree.IssuanceRecorder // nil records nothing
+	options   merkletree.RecorderOptions
+	requester string
+}
+
+// exportClaim rebuilds the tree from a build output and exports the claim for
+// the value index, or for value when byValue is set. The rebuilt root must
+// match the manifest so a claim is never issued, or recorded, for a
+// different tree.
+func exportClaim(output BuildOutput, index int, value string, byValue bool, record issuanceLog) (any, error) {
+	tree, err := rebuildTree(output)
+	if err != nil {
+		return nil, err
+	}
+	var leaf any = index
+	if byValue {
+		leaf = tree.value(value)
+	}
+	tree.setLog(record)
+	return tree.claim(leaf, record.requester)
+}
+
+// generateSource rebuilds the tree from a build output and returns it as Go
+// source. As for exportClaim, the rebuilt root must match the manifest.
+func generateSource(output BuildOutput, opts merkletree.GoSourceOptions) ([]byte, error) {
+	tree, err := rebuildTree(output)
+	if err != nil {
+		return nil, err
+	}
+	var source bytes.Buffer
+	if err := tree.goSource(&source, opts); err != nil {
+		return nil, err
+	}
+	return source.Bytes(), nil
+}
+
+// proofStats rebuilds the tree from a build output and reports its proof
+// statistics. As for exportClaim, the rebuilt root must match the manifest.
+func proofStats(output BuildOutput) (merkletree.ProofStatsReport, error) {
+	tree, err := rebuildTree(output)
+	if err != nil {
+		return merkletree.ProofStatsReport{}, err
+	}
+	return tree.proofStats()
+}
+
+// rebuildTree rebuilds the tree of a build output from the values in its
+// dump and checks that its root matches the manifest.
+func rebuildTree(output BuildOutput) (builtTree, error) {
+	values, err := dumpValues(output.Tree)
+	if err != nil {
+		return builtTree{}, err
+	}
+	tree, err := newTree(output.Manifest.Config, values)
+	if err != nil {
+		return builtTree{}, err
+	}
+	if tree.root != output.Manifest.Root {
+		return builtTree{}, fmt.Errorf("%w: manifest has %s, dump rebuilds to %s", ErrRootMismatch, output.Manifest.Root, tree.root)
+	}
+	return tree, nil
+}
+
+// readManifest reads a manifest from either a build output document or a bare manifest file.
+func readManifest(path string) (Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	var output BuildOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return Manifest{}, fmt.Errorf("%s: %w", path, err)
+	}
+	manifest := output.Manifest
+	if manifest.Format == "" {
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return Manifest{}, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if manifest.Format != manifestFormat {
+		return Manifest{}, fmt.Errorf("%s: unsupported manifest format %q", path, manifest.Format)
+	}
+	return manifest, nil
+}
+
+// reproduce rebuilds the tree recorded in manifest and checks that the root matches.
+// A mismatch is reported with both roots and, when the input changed, both checksums.
+func reproduce(manifest Manifest) (merkletree.HexString, error) {
+	if err := manifest.Config.Validate(); err != nil {
+		return "", fmt.Errorf("invalid manifest config: %w", err)
+	}
+
+	values, checksum, err := readInput(manifest.Config.Input)
+	if err != nil {
+		return "", fmt.Errorf("reading input: %w", err)
+	}
+
+	stamp, _, err := buildTree(manifest.Config, values)
+	if err != nil {
+		return "", fmt.Errorf("building tree: %w", err)
+	}
+	root := stamp.Root
+
+	if root != manifest.Root {
+		msg := fmt.Sprintf("manifest has %s, rebuilt %s", manifest.Root, root)
+		if !sameChecksum(checksum, manifest.InputSHA256) {
+			msg += fmt.Sprintf(" (input checksum changed: manifest has %s, file has %s)", manifest.InputSHA256, checksum)
+		}
+		return root, fmt.Errorf("%w: %s", ErrRootMismatch, msg)
+	}
+	return root, nil
+}
+
+// migrateFile upgrades the simple tree dump in path, which may be a bare dump
+// or a build output document, and rewrites the file only if the tree recomputes.
+func migrateFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var output BuildOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	var migrated []byte
+	if output.Manifest.Format == manifestFormat {
+		if output.Manifest.Config.Tree != "simple" {
+			return fmt.Errorf("%s: only simple trees need migrating, this is a %s tree", path, output.Manifest.Config.Tree)
+		}
+		tree, err := merkletree.MigrateDump(output.Tree)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		output.Tree = tree
+		migrated, err = json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return err
+		}
+	} else {
+		migrated, err = merkletree.MigrateDump(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return os.WriteFile(path, append(migrated, '\n'), 0644)
+}
+
+// fsckFile checks the integrity footer of the dump in path, which may be a
+// bare dump or a build output document. For a build output the manifest root
+// must also match the dump.
+func fsckFile(path string, allowLegacy bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var output BuildOutput
+	if json.Unmarshal(data, &output) != nil || output.Manifest.Format != manifestFormat {
+		if err := merkletree.VerifyDumpIntegrity(data, allowLegacy); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		return nil
+	}
+
+	if err := merkletree.VerifyDumpIntegrity(output.Tree, allowLegacy); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	var dump struct {
+		Tree []merkletree.HexString `json:"tree"`
+	}
+	if err := json.Unmarshal(output.Tree, &dump); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if len(dump.Tree) == 0 || dump.Tree[0] != output.Manifest.Root {
+		return fmt.Errorf("%s: %w: manifest has %s, dump does not", path, ErrRootMismatch, output.Manifest.Root)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/smeneguz/GoMerkle/merkletree"
+)
+
+// Config pins every option that affects the root of a tree built by the CLI.
+// It is read from a TOML file; only flat "key = value" pairs are supported.
+type Config struct {
+	Input       string `json:"input"`                 // Input file, one value per line
+	InputSHA256 string `json:"inputSha256,omitempty"` // Expected checksum of the input file (optional)
+	Output      string `json:"output,omitempty"`      // Output file for the dump and manifest
+	Tree        string `json:"tree"`                  // "standard" or "simple"
+	Hash        string `json:"hash"`                  // Node hash name, see merkletree.HashAlgorithms
+	SortLeaves  bool   `json:"sortLeaves"`            // Sort leaves before building
+	Encoding    string `json:"leafEncoding"`          // "packed" or "openzeppelin"
+	LeafTypes   string `json:"leafTypes,omitempty"`   // Solidity types of an "openzeppelin" leaf, comma-separated
+	Padding     string `json:"padding"`               // Leaf padding mode
+}
+
+// defaultConfig returns the configuration used for keys missing from the file.
+func defaultConfig() Config {
+	return Config{
+		Tree:       "standard",
+		Hash:       merkletree.HashAlgorithmKeccak256,
+		SortLeaves: true,
+		Encoding:   merkletree.LeafEncodingPacked,
+		Padding:    "none",
+	}
+}
+
+// loadConfig reads and validates a TOML config file.
+// Relative input and output paths are resolved against the config file's directory.
+func loadConfig(path string) (Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Config{}, err
+	}
+	defer f.Close()
+
+	cfg, err := parseConfig(f)
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	if cfg.Input != "" && !filepath.IsAbs(cfg.Input) {
+		cfg.Input = filepath.Join(dir, cfg.Input)
+	}
+	if cfg.Output != "" && !filepath.IsAbs(cfg.Output) {
+		cfg.Output = filepath.Join(dir, cfg.Output)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// parseConfig parses the flat TOML subset used by config files:
+// comments, blank lines, and "key = value" pairs with string or bool values.
+func parseConfig(r io.Reader) (Config, error) {
+	cfg := defaultConfig()
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(stripComment(scanner.Text()))
+		if text == "" {
+			continue
+		}
+		if strings.HasPrefix(text, "[") {
+			return Config{}, fmt.Errorf("line %d: tables are not supported", line)
+		}
+
+		key, raw, ok := strings.Cut(text, "=")
+		if !ok {
+			return Config{}, fmt.Errorf("line %d: expected key = value", line)
+		}
+		key = strings.TrimSpace(key)
+		raw = strings.TrimSpace(raw)
+		if seen[key] {
+			return Config{}, fmt.Errorf("line %d: duplicate key %q", line, key)
+		}
+		seen[key] = true
+
+		if err := cfg.set(key, raw); err != nil {
+			return Config{}, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// set assigns a single raw TOML value to the field named by key.
+func (c *Config) set(key, raw string) error {
+	switch key {
+	case "sort_leaves":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%s: expected true or false, got %s", key, raw)
+		}
+		c.SortLeaves = b
+		return nil
+	}
+
+	field := map[string]*string{
+		"input":         &c.Input,
+		"input_sha256":  &c.InputSHA256,
+		"output":        &c.Output,
+		"tree":          &c.Tree,
+		"hash":          &c.Hash,
+		"leaf_encoding": &c.Encoding,
+		"leaf_types":    &c.LeafTypes,
+		"padding":       &c.Padding,
+	}[key]
+	if field == nil {
+		return fmt.Errorf("unknown key %q", key)
+	}
+
+	s, err := strconv.Unquote(raw)
+	if err != nil || !strings.HasPrefix(raw, `"`) {
+		return fmt.Errorf("%s: expected a quoted string, got %s", key, raw)
+	}
+	*field = s
+	return nil
+}
+
+// stripComment removes a trailing "#" comment that is not inside a string.
+func stripComment(s string) string {
+	inString := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if inString {
+				i++
+			}
+		case '"':
+			inString = !inString
+		case '#':
+			if !inString {
+				return s[:i]
+			}
+		}
+	}
+	return s
+}
+
+// Validate checks that the configuration describes a tree this version can build.
+func (c Config) Validate() error {
+	var errs []error
+	if c.Input == "" {
+		errs = append(errs, errors.New("input is required"))
+	}
+	switch c.Tree {
+	case "standard", "simple":
+	default:
+		errs = append(errs, fmt.Errorf("tree: unknown tree type %q (want standard or simple)", c.Tree))
+	}
+	if names := merkletree.HashAlgorithms(); !slices.Contains(names, c.Hash) {
+		errs = append(errs, fmt.Errorf("hash: unknown hash %q (want one of %s)", c.Hash, strings.Join(names, ", ")))
+	} else if c.Tree == "standard" && c.Hash != merkletree.HashAlgorithmKeccak256 {
+		errs = append(errs, fmt.Errorf("hash: standard trees hash with keccak256, %q needs tree = \"simple\"", c.Hash))
+	}
+	switch c.Encoding {
+	case merkletree.LeafEncodingPacked:
+		if c.LeafTypes != "" {
+			errs = append(errs, fmt.Errorf("leaf_types: only used with leaf_encoding = %q", merkletree.LeafEncodingOpenZeppelin))
+		}
+	case merkletree.LeafEncodingOpenZeppelin:
+		if c.Tree == "simple" {
+			errs = append(errs, fmt.Errorf("leaf_encoding: simple trees hash values as bytes, %q needs tree = \"standard\"", c.Encoding))
+		}
+		if c.LeafTypes == "" {
+			errs = append(errs, fmt.Errorf("leaf_types: required by leaf_encoding = %q", c.Encoding))
+		} else if _, err := merkletree.ABIEncode(c.leafTypes(), nil); errors.Is(err, merkletree.ErrInvalidLeafEncoding) {
+			errs = append(errs, fmt.Errorf("leaf_types: %w", err))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("leaf_encoding: unknown encoding %q (want %s or %s)",
+			c.Encoding, merkletree.LeafEncodingPacked, merkletree.LeafEncodingOpenZeppelin))
+	}
+	if c.Padding != "none" {
+		errs = append(errs, fmt.Errorf("padding: unsupported padding %q (want none)", c.Padding))
+	}
+	if c.InputSHA256 != "" {
+		if _, err := decodeChecksum(c.InputSHA256); err != nil {
+			errs = append(errs, fmt.Errorf("input_sha256: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// leafTypes returns the Solidity types of an "openzeppelin" leaf.
+func (c Config) leafTypes() []string {
+	types := strings.Split(c.LeafTypes, ",")
+	for i := range types {
+		types[i] = strings.TrimSpace(types[i])
+	}
+	return types
+}
@@ -0,0 +1,534 @@
+// Command gomerkle builds and checks Merkle trees from the command line.
+//
+// Usage:
+//
+//	gomerkle build --config campaign.toml [--out tree.json] [--dry-run]
+//	gomerkle reproduce tree.json
+//	gomerkle prove (--index N | --value V) [--log issued.jsonl [--requester ID] [--fail-closed]] tree.json
+//	gomerkle migrate tree.json...
+//	gomerkle selftest
+//	gomerkle decode-claim --calldata 0x... --root 0x... --sig "claim(...)"
+//	gomerkle stamp-compare a.stamp.json b.stamp.json
+//	gomerkle fsck [--allow-legacy] tree.json...
+//	gomerkle codegen --tree tree.json --pkg allowlist [--var Tree] [--width 100] [--out allowlist_gen.go]
+//	gomerkle audit [--json] tree.json
+//	gomerkle why --root 0x... --value V --proof 0x...,0x... [--json]
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/smeneguz/GoMerkle/merkletree"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// command is a single gomerkle subcommand.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) error
+}
+
+// commands lists the available subcommands in the order they are printed by usage.
+var commands = []command{
+	{"build", "build a tree from a config file and write it with a manifest", runBuild},
+	{"reproduce", "rebuild a tree from its manifest and check the root", runReproduce},
+	{"prove", "print the claim for one value of a built tree", runProve},
+	{"migrate", "upgrade simple tree dumps in place to record their hash algorithms", runMigrate},
+	{"selftest", "check the hashing primitives against known-answer vectors", runSelfTest},
+	{"decode-claim", "decode claim calldata and verify its proof against a root", runDecodeClaim},
+	{"stamp-compare", "compare the build stamps of two builds", runStampCompare},
+	{"fsck", "check that tree files are complete and match their integrity footers", runFsck},
+	{"codegen", "write a built tree as Go source for embedding in a binary", runCodegen},
+	{"audit", "print proof length and claim size statistics of a built tree", runAudit},
+	{"why", "explain why a value and proof do not verify against a root", runWhy},
+}
+
+// run executes the CLI with the given arguments and returns the process exit status.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			if err := cmd.run(args[1:], stdout, stderr); err != nil {
+				fmt.Fprintf(stderr, "gomerkle %s: %v\n", cmd.name, err)
+				return exitStatus(err)
+			}
+			return 0
+		}
+	}
+
+	fmt.Fprintf(stderr, "gomerkle: unknown command %q\n", args[0])
+	usage(stderr)
+	return exitUsage
+}
+
+// Exit statuses, chosen by the merkletree.Code of the error so scripts can
+// tell failures apart without parsing messages.
+const (
+	exitFailure  = 1 // Any other error, and stamps that differ
+	exitUsage    = 2 // No or unknown command
+	exitInvalid  = 3 // A proof or root does not verify
+	exitCorrupt  = 4 // A file is truncated, corrupt or not a tree dump
+	exitNotFound = 5 // The value or index is not in the tree
+)
+
+// exitStatus maps a command's error to the process exit status.
+func exitStatus(err error) int {
+	switch merkletree.Code(err) {
+	case merkletree.CodeInvalidProof, merkletree.CodeInvalidMultiProof, CodeRootMismatch:
+		return exitInvalid
+	case merkletree.CodeInvalidDump, merkletree.CodeTruncatedDump, merkletree.CodeCorruptTree, merkletree.CodeInvalidCalldata:
+		return exitCorrupt
+	case merkletree.CodeValueNotFound, merkletree.CodeInvalidIndex, merkletree.CodeQuarantined:
+		return exitNotFound
+	default:
+		return exitFailure
+	}
+}
+
+// usage prints the list of subcommands.
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gomerkle <command> [arguments]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+// runBuild implements "gomerkle build".
+func runBuild(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", "", "path to the TOML config file")
+	out := fs.String("out", "", "output file (overrides the config's output)")
+	dryRun := fs.Bool("dry-run", false, "print the memory estimate without building")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *configPath == "" {
+		return fmt.Errorf("--config is required")
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		cfg.Output = *out
+	}
+
+	count, estimate, err := estimateBuild(cfg)
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		fmt.Fprintf(stdout, "%d values, estimated memory: %v\n", count, estimate)
+		return nil
+	}
+	fmt.Fprintf(stderr, "%d values, estimated memory: %v\n", count, estimate)
+
+	output, stamp, err := build(cfg)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return err
+	}
+	if cfg.Output == "" {
+		_, err = fmt.Fprintln(stdout, string(data))
+		return err
+	}
+	if err := os.WriteFile(cfg.Output, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	stampData, err := json.MarshalIndent(stamp, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(stampPath(cfg.Output), append(stampData, '\n'), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "root %s written to %s\n", output.Manifest.Root, cfg.Output)
+	return nil
+}
+
+// runReproduce implements "gomerkle reproduce".
+func runReproduce(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("reproduce", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	input := fs.String("input", "", "input file (overrides the path recorded in the manifest)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expected exactly one manifest file")
+	}
+
+	manifest, err := readManifest(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *input != "" {
+		manifest.Config.Input = *input
+	}
+
+	root, err := reproduce(manifest)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "root %s reproduced\n", root)
+	return nil
+}
+
+// runProve implements "gomerkle prove".
+func runProve(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("prove", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	index := fs.Int("index", -1, "value index to prove")
+	value := fs.String("value", "", "value to prove")
+	logPath := fs.String("log", "", "JSONL file recording every proof issued")
+	requester := fs.String("requester", "", "who the proof is for, as recorded in --log")
+	failClosed := fs.Bool("fail-closed", false, "refuse the proof if it cannot be recorded")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expected exactly one tree file")
+	}
+
+	byValue := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "value" {
+			byValue = true
+		}
+	})
+	if byValue == (*index >= 0) {
+		return fmt.Errorf("exactly one of --index or --value is required")
+	}
+
+	output, err := readBuildOutput(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	record := issuanceLog{requester: *requester}
+	if *logPath != "" {
+		recorder, err := merkletree.NewFileRecorder(*logPath, merkletree.FileRecorderOptions{})
+		if err != nil {
+			return err
+		}
+		defer recorder.Close()
+		record.recorder = recorder
+		record.options.Logf = func(format string, args ...any) {
+			fmt.Fprintf(stderr, "gomerkle prove: "+format+"\n", args...)
+		}
+		if *failClosed {
+			record.options.Policy = merkletree.RecordFailClosed
+		}
+	}
+
+	claim, err := exportClaim(output, *index, *value, byValue, record)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(claim, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, string(data))
+	return err
+}
+
+// runMigrate implements "gomerkle migrate".
+func runMigrate(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("expected at least one tree file")
+	}
+
+	for _, path := range fs.Args() {
+		if err := migrateFile(path); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%s migrated\n", path)
+	}
+	return nil
+}
+
+// runSelfTest implements "gomerkle selftest".
+func runSelfTest(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("selftest", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("selftest takes no arguments")
+	}
+
+	if err := merkletree.SelfTest(); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "self test passed (keccak backend: %s)\n", merkletree.SelectedBackend())
+	return nil
+}
+
+// runDecodeClaim implements "gomerkle decode-claim".
+func runDecodeClaim(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("decode-claim", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	calldata := fs.String("calldata", "", "hex transaction input data")
+	root := fs.String("root", "", "root the proof should verify against")
+	sig := fs.String("sig", "", `function signature, e.g. "claim(uint256,address,uint256,bytes32[])"`)
+	leafArgs := fs.String("leaf", "", "comma-separated indices of the arguments forming the leaf (default: all but the proof)")
+	encoding := fs.String("leaf-encoding", merkletree.LeafEncodingPacked, "leaf encoding: packed or openzeppelin")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *calldata == "" || *root == "" || *sig == "" {
+		return fmt.Errorf("--calldata, --root and --sig are required")
+	}
+
+	expectedRoot, err := merkletree.ParseRoot(*root)
+	if err != nil {
+		return fmt.Errorf("invalid --root: %w", err)
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(*calldata, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid --calldata: %w", err)
+	}
+	mapping := merkletree.ClaimMapping{LeafEncoding: *encoding}
+	if *leafArgs != "" {
+		for _, field := range strings.Split(*leafArgs, ",") {
+			index, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil {
+				return fmt.Errorf("invalid --leaf: %w", err)
+			}
+			mapping.LeafArgs = append(mapping.LeafArgs, index)
+		}
+	}
+
+	claim, err := merkletree.ParseSolidityProofCalldata(data, *sig)
+	if err != nil {
+		return err
+	}
+	valid, leaf, err := claim.Verify(expectedRoot, mapping)
+	if err != nil {
+		return err
+	}
+
+	report, err := json.MarshalIndent(struct {
+		merkletree.ParsedClaim
+		Leaf  merkletree.HexString `json:"leaf"`
+		Root  merkletree.HexString `json:"root"`
+		Valid bool                 `json:"valid"`
+	}{claim, leaf, expectedRoot, valid}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, string(report))
+	return err
+}
+
+// errStampsDiffer is returned by stamp-compare when the stamps disagree.
+var errStampsDiffer = errors.New("stamps differ")
+
+// runStampCompare implements "gomerkle stamp-compare".
+func runStampCompare(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("stamp-compare", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("expected exactly two stamp files")
+	}
+
+	a, err := readStamp(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	b, err := readStamp(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	diff, same := merkletree.CompareStamps(a, b)
+	if !same {
+		fmt.Fprintln(stdout, diff)
+		return errStampsDiffer
+	}
+	fmt.Fprintln(stdout, "stamps match")
+	return nil
+}
+
+// runFsck implements "gomerkle fsck".
+func runFsck(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("fsck", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	allowLegacy := fs.Bool("allow-legacy", false, "accept dumps written before integrity footers existed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("expected at least one tree file")
+	}
+
+	for _, path := range fs.Args() {
+		if err := fsckFile(path, *allowLegacy); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%s ok\n", path)
+	}
+	return nil
+}
+
+// runCodegen implements "gomerkle codegen".
+func runCodegen(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("codegen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	treePath := fs.String("tree", "", "tree file written by build")
+	pkg := fs.String("pkg", "", "package name of the generated file")
+	varName := fs.String("var", "Tree", "prefix of the generated identifiers")
+	width := fs.Int("width", merkletree.DefaultGoSourceLineWidth, "maximum line width")
+	out := fs.String("out", "", "output file (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *treePath == "" || *pkg == "" {
+		return fmt.Errorf("--tree and --pkg are required")
+	}
+
+	output, err := readBuildOutput(*treePath)
+	if err != nil {
+		return err
+	}
+	source, err := generateSource(output, merkletree.GoSourceOptions{Package: *pkg, VarName: *varName, LineWidth: *width})
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = stdout.Write(source)
+		return err
+	}
+	if err := os.WriteFile(*out, source, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "wrote %s\n", *out)
+	return nil
+}
+
+// runAudit implements "gomerkle audit".
+func runAudit(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	asJSON := fs.Bool("json", false, "print the statistics as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expected exactly one tree file")
+	}
+
+	output, err := readBuildOutput(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	report, err := proofStats(output)
+	if err != nil {
+		return err
+	}
+
+	if !*asJSON {
+		_, err = fmt.Fprintln(stdout, report)
+		return err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, string(data))
+	return err
+}
+
+// runWhy implements "gomerkle why".
+func runWhy(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("why", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	root := fs.String("root", "", "root the proof should verify against")
+	value := fs.String("value", "", "value the proof was generated for")
+	proofFlag := fs.String("proof", "", `proof nodes, comma-separated or as a JSON array`)
+	asJSON := fs.Bool("json", false, "print every candidate as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *root == "" || *value == "" {
+		return fmt.Errorf("--root and --value are required")
+	}
+
+	expectedRoot, err := merkletree.ParseRoot(*root)
+	if err != nil {
+		return fmt.Errorf("invalid --root: %w", err)
+	}
+	proof, err := parseProofFlag(*proofFlag)
+	if err != nil {
+		return fmt.Errorf("invalid --proof: %w", err)
+	}
+	report, err := merkletree.DiagnoseStandardVerification(expectedRoot, *value, proof)
+	if err != nil {
+		return err
+	}
+
+	if !*asJSON {
+		_, err = fmt.Fprintln(stdout, report)
+		return err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, string(data))
+	return err
+}
+
+// parseProofFlag reads proof nodes given as "0x..,0x.." or as a JSON array
+// of strings, as JavaScript tools print them.
+func parseProofFlag(s string) ([]merkletree.BytesLike, error) {
+	s = strings.TrimSpace(s)
+	var nodes []string
+	if strings.HasPrefix(s, "[") {
+		if err := json.Unmarshal([]byte(s), &nodes); err != nil {
+			return nil, err
+		}
+	} else if s != "" {
+		nodes = strings.Split(s, ",")
+	}
+	proof := make([]merkletree.BytesLike, len(nodes))
+	for i, node := range nodes {
+		proof[i] = strings.Trim(strings.TrimSpace(node), `"'`)
+	}
+	return proof, nil
+}
@@ -0,0 +1,748 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smeneguz/GoMerkle/merkletree"
+)
+
+// writeFile writes content to name inside dir and returns the full path.
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := parseConfig(strings.NewReader(`
+# campaign config
+input = "values.txt"   # one value per line
+tree = "simple"
+sort_leaves = false
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	if cfg.Input != "values.txt" || cfg.Tree != "simple" || cfg.SortLeaves {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+
+	// Unset keys keep their defaults
+	if cfg.Hash != "keccak256" || cfg.Encoding != "packed" || cfg.Padding != "none" {
+		t.Errorf("Defaults not applied: %+v", cfg)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"unknown key", `colour = "blue"`},
+		{"duplicate key", "tree = \"simple\"\ntree = \"standard\""},
+		{"unquoted string", `tree = simple`},
+		{"invalid bool", `sort_leaves = "yes"`},
+		{"table", `[build]`},
+		{"missing equals", `tree`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseConfig(strings.NewReader(tt.input)); err == nil {
+				t.Error("Expected parse error")
+			}
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Hash = "md5"
+	cfg.Padding = "zero"
+	cfg.InputSHA256 = "abc"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+
+	// All problems are reported at once
+	for _, want := range []string{"input is required", "hash", "padding", "input_sha256"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validation error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestConfigValidateNames(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		err    string // Substring of the error, or "" for a valid config
+	}{
+		{"sha256 simple", Config{Tree: "simple", Hash: merkletree.HashAlgorithmSHA256}, ""},
+		{"blake2b simple", Config{Tree: "simple", Hash: merkletree.HashAlgorithmBlake2b256}, ""},
+		{"sha256 standard", Config{Tree: "standard", Hash: merkletree.HashAlgorithmSHA256}, "needs tree"},
+		{"unknown hash", Config{Tree: "simple", Hash: "md5"}, "unknown hash"},
+		{"openzeppelin", Config{Encoding: merkletree.LeafEncodingOpenZeppelin, LeafTypes: "address, uint256"}, ""},
+		{"openzeppelin simple", Config{Tree: "simple", Encoding: merkletree.LeafEncodingOpenZeppelin, LeafTypes: "uint256"}, "simple trees"},
+		{"openzeppelin without types", Config{Encoding: merkletree.LeafEncodingOpenZeppelin}, "leaf_types: required"},
+		{"openzeppelin bad types", Config{Encoding: merkletree.LeafEncodingOpenZeppelin, LeafTypes: "address,uint7"}, "leaf_types"},
+		{"packed with types", Config{LeafTypes: "uint256"}, "leaf_types: only used"},
+		{"unknown encoding", Config{Encoding: "abi"}, "unknown encoding"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.Input = "values.txt"
+			if tt.config.Tree != "" {
+				cfg.Tree = tt.config.Tree
+			}
+			if tt.config.Hash != "" {
+				cfg.Hash = tt.config.Hash
+			}
+			if tt.config.Encoding != "" {
+				cfg.Encoding = tt.config.Encoding
+			}
+			cfg.LeafTypes = tt.config.LeafTypes
+
+			err := cfg.Validate()
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("Validate() = %v, want nil", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("Validate() = %v, want an error mentioning %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestBuildSelectedHashAndEncoding(t *testing.T) {
+	simpleValues := []merkletree.BytesLike{"alice", "bob", "charlie", "dave"}
+	sha256Tree, err := merkletree.NewSimpleMerkleTree(simpleValues, merkletree.SimpleMerkleTreeOptions{
+		MerkleTreeOptions: merkletree.MerkleTreeOptions{SortLeaves: merkletree.Bool(true)},
+		HashAlgorithm:     merkletree.HashAlgorithmSHA256,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	ozValues := [][]any{
+		{"0x1111111111111111111111111111111111111111", "5000000000000000000"},
+		{"0x2222222222222222222222222222222222222222", "2500000000000000000"},
+		{"0x3333333333333333333333333333333333333333", "1000000000000000000"},
+	}
+	ozTree, err := merkletree.NewStandardMerkleTreeWithEncoding(ozValues, []string{"address", "uint256"}, merkletree.MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		config string
+		values string
+		root   merkletree.HexString
+		value  string // A value to prove with --value
+	}{
+		{"sha256", "tree = \"simple\"\nhash = \"sha256\"\n", "alice\nbob\ncharlie\ndave\n", sha256Tree.Root(), "bob"},
+		{"openzeppelin", "leaf_encoding = \"openzeppelin\"\nleaf_types = \"address,uint256\"\n",
+			"0x1111111111111111111111111111111111111111,5000000000000000000\n" +
+				"0x2222222222222222222222222222222222222222, 2500000000000000000\n" +
+				"0x3333333333333333333333333333333333333333,1000000000000000000\n",
+			ozTree.Root(), "0x3333333333333333333333333333333333333333,1000000000000000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, dir, "values.txt", tt.values)
+			config := writeFile(t, dir, "campaign.toml", "input = \"values.txt\"\noutput = \"tree.json\"\n"+tt.config)
+			treeFile := filepath.Join(dir, "tree.json")
+
+			var stdout, stderr bytes.Buffer
+			if code := run([]string{"build", "--config", config}, &stdout, &stderr); code != 0 {
+				t.Fatalf("build exited with %d: %s", code, stderr.String())
+			}
+			manifest, err := readManifest(treeFile)
+			if err != nil {
+				t.Fatalf("Failed to read manifest: %v", err)
+			}
+			if manifest.Root != tt.root {
+				t.Errorf("Root %s, want %s", manifest.Root, tt.root)
+			}
+
+			if code := run([]string{"reproduce", treeFile}, &stdout, &stderr); code != 0 {
+				t.Fatalf("reproduce exited with %d: %s", code, stderr.String())
+			}
+			stdout.Reset()
+			if code := run([]string{"prove", "--value", tt.value, treeFile}, &stdout, &stderr); code != 0 {
+				t.Fatalf("prove exited with %d: %s", code, stderr.String())
+			}
+			var claim struct {
+				Root merkletree.HexString `json:"root"`
+			}
+			if err := json.Unmarshal(stdout.Bytes(), &claim); err != nil || claim.Root != tt.root {
+				t.Errorf("Claim root %s (%v), want %s", claim.Root, err, tt.root)
+			}
+		})
+	}
+}
+
+func TestBuildReproduceRoundTrip(t *testing.T) {
+	for _, tree := range []string{"standard", "simple"} {
+		t.Run(tree, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, dir, "values.txt", "alice\nbob\ncharlie\ndave\n")
+			config := writeFile(t, dir, "campaign.toml",
+				"input = \"values.txt\"\noutput = \"tree.json\"\ntree = \""+tree+"\"\n")
+
+			var stdout, stderr bytes.Buffer
+			if code := run([]string{"build", "--config", config}, &stdout, &stderr); code != 0 {
+				t.Fatalf("build exited with %d: %s", code, stderr.String())
+			}
+
+			manifest, err := readManifest(filepath.Join(dir, "tree.json"))
+			if err != nil {
+				t.Fatalf("Failed to read manifest: %v", err)
+			}
+			if manifest.LeafCount != 4 {
+				t.Errorf("Expected 4 leaves, got %d", manifest.LeafCount)
+			}
+			if manifest.LibraryVersion == "" {
+				t.Error("Manifest should record the library version")
+			}
+
+			stdout.Reset()
+			if code := run([]string{"reproduce", filepath.Join(dir, "tree.json")}, &stdout, &stderr); code != 0 {
+				t.Fatalf("reproduce exited with %d: %s", code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), string(manifest.Root)) {
+				t.Errorf("reproduce output %q does not contain root %s", stdout.String(), manifest.Root)
+			}
+		})
+	}
+}
+
+func TestReproduceInputChanged(t *testing.T) {
+	dir := t.TempDir()
+	input := writeFile(t, dir, "values.txt", "alice\nbob\ncharlie\n")
+	config := writeFile(t, dir, "campaign.toml", "input = \"values.txt\"\n")
+
+	cfg, err := loadConfig(config)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	output, _, err := build(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+
+	// Change the input after the build
+	writeFile(t, dir, "values.txt", "alice\nbob\nmallory\n")
+
+	root, err := reproduce(output.Manifest)
+	if !errors.Is(err, ErrRootMismatch) {
+		t.Fatalf("Expected ErrRootMismatch, got %v", err)
+	}
+
+	// Both roots are reported so the operator can compare them
+	for _, want := range []string{string(output.Manifest.Root), string(root), "input checksum changed"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Error %q does not mention %q", err, want)
+		}
+	}
+
+	// A pinned checksum makes the build itself refuse the changed input
+	cfg.InputSHA256 = output.Manifest.InputSHA256
+	cfg.Input = input
+	if _, _, err := build(cfg); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Expected checksum mismatch error, got %v", err)
+	}
+}
+
+func TestBuildPinnedChecksum(t *testing.T) {
+	dir := t.TempDir()
+	content := "one\ntwo\n"
+	writeFile(t, dir, "values.txt", content)
+	sum := sha256.Sum256([]byte(content))
+	config := writeFile(t, dir, "campaign.toml",
+		"input = \"values.txt\"\ninput_sha256 = \""+hex.EncodeToString(sum[:])+"\"\n")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"build", "--config", config}, &stdout, &stderr); code != 0 {
+		t.Fatalf("build exited with %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), `"manifest"`) {
+		t.Error("build without output should print the document to stdout")
+	}
+}
+
+func TestRunUnknownCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"frobnicate"}, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit status 2, got %d", code)
+	}
+}
+
+func TestProveSmallTrees(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		proofSize int
+	}{
+		{"one leaf", "only\n", 0},
+		{"two leaves", "left\nright\n", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, dir, "values.txt", tt.input)
+			config := writeFile(t, dir, "campaign.toml", "input = \"values.txt\"\noutput = \"tree.json\"\n")
+			treeFile := filepath.Join(dir, "tree.json")
+
+			var stdout, stderr bytes.Buffer
+			if code := run([]string{"build", "--config", config}, &stdout, &stderr); code != 0 {
+				t.Fatalf("build exited with %d: %s", code, stderr.String())
+			}
+
+			for _, args := range [][]string{{"--index", "0"}, {"--value", strings.Fields(tt.input)[0]}} {
+				stdout.Reset()
+				if code := run(append(append([]string{"prove"}, args...), treeFile), &stdout, &stderr); code != 0 {
+					t.Fatalf("prove %v exited with %d: %s", args, code, stderr.String())
+				}
+
+				var claim struct {
+					Proof []string `json:"proof"`
+					Note  string   `json:"note"`
+				}
+				if err := json.Unmarshal(stdout.Bytes(), &claim); err != nil {
+					t.Fatalf("prove output is not JSON: %v\n%s", err, stdout.String())
+				}
+				if claim.Proof == nil || len(claim.Proof) != tt.proofSize {
+					t.Errorf("Expected a %d-element proof array, got %s", tt.proofSize, stdout.String())
+				}
+				if tt.proofSize == 0 && claim.Note == "" {
+					t.Error("Single-leaf claim should carry a note")
+				}
+
+				valid, err := merkletree.VerifyEnvelope(stdout.Bytes(), nil)
+				if err != nil || !valid {
+					t.Errorf("Claim should verify: valid=%v err=%v", valid, err)
+				}
+			}
+		})
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "values.txt", "alice\nbob\n")
+	config := writeFile(t, dir, "campaign.toml", "input = \"values.txt\"\noutput = \"tree.json\"\n")
+	treeFile := filepath.Join(dir, "tree.json")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"build", "--config", config}, &stdout, &stderr); code != 0 {
+		t.Fatalf("build exited with %d: %s", code, stderr.String())
+	}
+	if code := run([]string{"prove", "--value", "mallory", treeFile}, &stdout, &stderr); code != exitNotFound {
+		t.Errorf("prove of a missing value exited with %d, want %d", code, exitNotFound)
+	}
+	if code := run([]string{"prove", "--index", "7", treeFile}, &stdout, &stderr); code != exitNotFound {
+		t.Errorf("prove of a missing index exited with %d, want %d", code, exitNotFound)
+	}
+
+	for err, want := range map[error]int{
+		fmt.Errorf("tree.json: %w", ErrRootMismatch):                  exitInvalid,
+		fmt.Errorf("wrapped: %w", merkletree.ErrInvalidProof):         exitInvalid,
+		errors.Join(errors.New("uncoded"), merkletree.ErrInvalidDump): exitCorrupt,
+		errors.New("uncoded"): exitFailure,
+	} {
+		if got := exitStatus(err); got != want {
+			t.Errorf("exitStatus(%v) = %d, want %d", err, got, want)
+		}
+	}
+}
+
+func TestBuildEmptyInput(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "values.txt", "\n")
+	config := writeFile(t, dir, "campaign.toml", "input = \"values.txt\"\n")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"build", "--config", config}, &stdout, &stderr); code != 1 {
+		t.Fatalf("Expected build of empty input to fail, got exit status %d", code)
+	}
+	if !strings.Contains(stderr.String(), "zero elements") {
+		t.Errorf("Expected empty-tree error, got %q", stderr.String())
+	}
+}
+
+func TestProveFlags(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"prove", "--index", "0", "--value", "x", "tree.json"}, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected both --index and --value to be rejected, got %d", code)
+	}
+	if code := run([]string{"prove", "tree.json"}, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected missing --index/--value to be rejected, got %d", code)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "values.txt", "alice\nbob\ncharlie\n")
+	config := writeFile(t, dir, "campaign.toml", "input = \"values.txt\"\noutput = \"tree.json\"\ntree = \"simple\"\n")
+	treeFile := filepath.Join(dir, "tree.json")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"build", "--config", config}, &stdout, &stderr); code != 0 {
+		t.Fatalf("build exited with %d: %s", code, stderr.String())
+	}
+
+	// Strip the hash algorithm fields and footer to get a dump as written by older versions
+	output, err := readBuildOutput(treeFile)
+	if err != nil {
+		t.Fatalf("Failed to read build output: %v", err)
+	}
+	var dump map[string]any
+	if err := json.Unmarshal(output.Tree, &dump); err != nil {
+		t.Fatalf("Failed to decode dump: %v", err)
+	}
+	delete(dump, "hashAlgorithm")
+	delete(dump, "leafHashAlgorithm")
+	delete(dump, "integrity")
+	legacy, _ := json.Marshal(dump)
+	dumpFile := writeFile(t, dir, "legacy.json", string(legacy))
+
+	if code := run([]string{"migrate", dumpFile}, &stdout, &stderr); code != 0 {
+		t.Fatalf("migrate exited with %d: %s", code, stderr.String())
+	}
+	migrated, err := os.ReadFile(dumpFile)
+	if err != nil {
+		t.Fatalf("Failed to read migrated file: %v", err)
+	}
+	if !strings.Contains(string(migrated), `"hashAlgorithm": "keccak256"`) {
+		t.Errorf("Migrated file does not record its hash algorithm:\n%s", migrated)
+	}
+
+	// A tampered tree is refused and the file left untouched
+	tampered := strings.Replace(string(legacy), string(output.Manifest.Root), "0x"+strings.Repeat("00", 32), 1)
+	writeFile(t, dir, "legacy.json", tampered)
+	if code := run([]string{"migrate", dumpFile}, &stdout, &stderr); code != exitCorrupt {
+		t.Errorf("Expected migrate of a tampered tree to fail, got %d", code)
+	}
+	if data, _ := os.ReadFile(dumpFile); string(data) != tampered {
+		t.Error("Failed migration should not rewrite the file")
+	}
+}
+
+func TestSelfTestCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"selftest"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("selftest exited with %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "passed") {
+		t.Errorf("Unexpected output %q", stdout.String())
+	}
+}
+
+func TestBuildDryRun(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "values.txt", "alice\nbob\ncharlie\n")
+	config := writeFile(t, dir, "campaign.toml", "input = \"values.txt\"\noutput = \"tree.json\"\n")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"build", "--config", config, "--dry-run"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("build --dry-run exited with %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "3 values, estimated memory: peak") {
+		t.Errorf("Unexpected dry-run output %q", stdout.String())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tree.json")); !os.IsNotExist(err) {
+		t.Error("Dry run should not write the output file")
+	}
+}
+
+func TestDecodeClaim(t *testing.T) {
+	data, err := os.ReadFile("../../merkletree/testdata/calldata.json")
+	if err != nil {
+		t.Fatalf("Failed to read fixtures: %v", err)
+	}
+	var fixtures []struct {
+		Signature string `json:"signature"`
+		Calldata  string `json:"calldata"`
+		Root      string `json:"root"`
+		Valid     bool   `json:"valid"`
+	}
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		t.Fatalf("Failed to decode fixtures: %v", err)
+	}
+
+	for _, f := range fixtures[:3] {
+		var stdout, stderr bytes.Buffer
+		args := []string{"decode-claim", "--calldata", f.Calldata, "--root", f.Root, "--sig", f.Signature, "--leaf", "0,1,2"}
+		if code := run(args, &stdout, &stderr); code != 0 {
+			t.Fatalf("decode-claim exited with %d: %s", code, stderr.String())
+		}
+
+		var report struct {
+			Valid bool     `json:"valid"`
+			Proof []string `json:"proof"`
+		}
+		if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+			t.Fatalf("decode-claim output is not JSON: %v\n%s", err, stdout.String())
+		}
+		if report.Valid != f.Valid || len(report.Proof) == 0 {
+			t.Errorf("Expected valid=%v with a proof, got %s", f.Valid, stdout.String())
+		}
+	}
+
+	// Roots pasted from config files are normalized
+	f := fixtures[0]
+	var stdout, stderr bytes.Buffer
+	messy := "\"" + strings.ToUpper(f.Root) + "\"\n"
+	args := []string{"decode-claim", "--calldata", f.Calldata, "--root", messy, "--sig", f.Signature, "--leaf", "0,1,2"}
+	if code := run(args, &stdout, &stderr); code != 0 || !strings.Contains(stdout.String(), `"root": "`+strings.ToLower(f.Root)+`"`) {
+		t.Errorf("decode-claim with a quoted uppercase root exited with %d: %s%s", code, stdout.String(), stderr.String())
+	}
+	stderr.Reset()
+	args[4] = f.Root[:len(f.Root)-2]
+	if code := run(args, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "decodes to 31 bytes") {
+		t.Errorf("decode-claim with a short root exited with %d: %s", code, stderr.String())
+	}
+}
+
+func TestStampCompare(t *testing.T) {
+	dir := t.TempDir()
+	stamps := map[string]string{}
+	for name, input := range map[string]string{
+		"a":        "alice\nbob\ncharlie\n",
+		"b":        "alice\nbob\ncharlie\n",
+		"reversed": "charlie\nbob\nalice\n",
+	} {
+		writeFile(t, dir, name+".txt", input)
+		config := writeFile(t, dir, name+".toml", "input = \""+name+".txt\"\noutput = \""+name+".json\"\n")
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"build", "--config", config}, &stdout, &stderr); code != 0 {
+			t.Fatalf("build %s exited with %d: %s", name, code, stderr.String())
+		}
+		stamps[name] = filepath.Join(dir, name+".stamp.json")
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"stamp-compare", stamps["a"], stamps["b"]}, &stdout, &stderr); code != 0 {
+		t.Fatalf("stamp-compare of identical builds exited with %d: %s%s", code, stdout.String(), stderr.String())
+	}
+
+	// Sorting gives both orders the same root; the stamp still tells them apart
+	stdout.Reset()
+	if code := run([]string{"stamp-compare", stamps["a"], stamps["reversed"]}, &stdout, &stderr); code != 1 {
+		t.Fatalf("stamp-compare of diverging builds exited with %d", code)
+	}
+	if got := stdout.String(); !strings.HasPrefix(got, "LeavesHash:") || strings.Contains(got, "Root") {
+		t.Errorf("Diff should name only LeavesHash, got %q", got)
+	}
+}
+
+func TestFsck(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "values.txt", "alice\nbob\ncharlie\n")
+	config := writeFile(t, dir, "campaign.toml", "input = \"values.txt\"\noutput = \"tree.json\"\n")
+	treeFile := filepath.Join(dir, "tree.json")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"build", "--config", config}, &stdout, &stderr); code != 0 {
+		t.Fatalf("build exited with %d: %s", code, stderr.String())
+	}
+	if code := run([]string{"fsck", treeFile}, &stdout, &stderr); code != 0 {
+		t.Fatalf("fsck of a complete file exited with %d: %s", code, stderr.String())
+	}
+
+	data, err := os.ReadFile(treeFile)
+	if err != nil {
+		t.Fatalf("Failed to read tree file: %v", err)
+	}
+	for _, offset := range []int{1, len(data) / 3, len(data) / 2, len(data) - 3} {
+		truncated := writeFile(t, dir, "truncated.json", string(data[:offset]))
+		stderr.Reset()
+		if code := run([]string{"fsck", truncated}, &stdout, &stderr); code != exitCorrupt {
+			t.Errorf("fsck of a file cut at byte %d exited with %d", offset, code)
+		}
+		if !strings.Contains(stderr.String(), merkletree.ErrTruncatedDump.Error()) {
+			t.Errorf("Cut at byte %d: unexpected error %q", offset, stderr.String())
+		}
+	}
+}
+
+func TestCodegen(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "values.txt", "alice\nbob\ncharlie\n")
+	config := writeFile(t, dir, "campaign.toml", "input = \"values.txt\"\noutput = \"tree.json\"\n")
+	treeFile := filepath.Join(dir, "tree.json")
+	outFile := filepath.Join(dir, "allowlist_gen.go")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"build", "--config", config}, &stdout, &stderr); code != 0 {
+		t.Fatalf("build exited with %d: %s", code, stderr.String())
+	}
+	args := []string{"codegen", "--tree", treeFile, "--pkg", "allowlist", "--var", "Allowlist", "--out", outFile}
+	if code := run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("codegen exited with %d: %s", code, stderr.String())
+	}
+
+	source, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	output, err := readBuildOutput(treeFile)
+	if err != nil {
+		t.Fatalf("Failed to read tree file: %v", err)
+	}
+	for _, want := range []string{"package allowlist", string(output.Manifest.Root), `"charlie",`, "func AllowlistVerify("} {
+		if !strings.Contains(string(source), want) {
+			t.Errorf("Generated source should contain %q", want)
+		}
+	}
+
+	stderr.Reset()
+	if code := run([]string{"codegen", "--tree", treeFile, "--pkg", "my-pkg"}, &stdout, &stderr); code != 1 {
+		t.Errorf("codegen with an invalid package name exited with %d", code)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "values.txt", "alice\nbob\ncharlie\n")
+	config := writeFile(t, dir, "campaign.toml", "input = \"values.txt\"\noutput = \"tree.json\"\n")
+	treeFile := filepath.Join(dir, "tree.json")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"build", "--config", config}, &stdout, &stderr); code != 0 {
+		t.Fatalf("build exited with %d: %s", code, stderr.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"audit", treeFile}, &stdout, &stderr); code != 0 {
+		t.Fatalf("audit exited with %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "3 leaves, proof length min 1, max 2") {
+		t.Errorf("Unexpected audit output:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"audit", "--json", treeFile}, &stdout, &stderr); code != 0 {
+		t.Fatalf("audit --json exited with %d: %s", code, stderr.String())
+	}
+	var report merkletree.ProofStatsReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("audit --json did not print JSON: %v", err)
+	}
+	if report.Leaves != 3 || report.MaxProofLength != 2 || len(report.DeepestLeaves) != 2 || report.EnvelopeBytes == 0 {
+		t.Errorf("Report = %+v", report)
+	}
+}
+
+func TestProveLog(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "values.txt", "alice\nbob\ncharlie\n")
+	config := writeFile(t, dir, "campaign.toml", "input = \"values.txt\"\noutput = \"tree.json\"\n")
+	treeFile := filepath.Join(dir, "tree.json")
+	logFile := filepath.Join(dir, "issued.jsonl")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"build", "--config", config}, &stdout, &stderr); code != 0 {
+		t.Fatalf("build exited with %d: %s", code, stderr.String())
+	}
+
+	stdout.Reset()
+	args := []string{"prove", "--value", "bob", "--log", logFile, "--requester", "ops", "--fail-closed", treeFile}
+	if code := run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("prove exited with %d: %s", code, stderr.String())
+	}
+	var claim merkletree.Claim[any]
+	if err := json.Unmarshal(stdout.Bytes(), &claim); err != nil {
+		t.Fatalf("prove output is not JSON: %v", err)
+	}
+
+	found, err := merkletree.FindByLeafHash(logFile, claim.LeafHash)
+	if err != nil {
+		t.Fatalf("FindByLeafHash failed: %v", err)
+	}
+	if len(found) != 1 || found[0].Requester != "ops" || found[0].ProofHash != merkletree.ProofHash(claim.Proof) {
+		t.Errorf("Recorded %+v for claim %+v", found, claim)
+	}
+}
+
+func TestWhy(t *testing.T) {
+	// A tree as OpenZeppelin's JavaScript library builds it over strings:
+	// keccak256(keccak256(abi.encode(value)))
+	abiEncoded := func(s string) []byte {
+		out := make([]byte, 96)
+		out[31], out[63] = 0x20, byte(len(s))
+		copy(out[64:], s)
+		return out
+	}
+	var leaves []merkletree.BytesLike
+	for _, v := range []string{"alice", "bob", "charlie"} {
+		leaves = append(leaves, merkletree.OpenZeppelinLeafHash(abiEncoded(v)))
+	}
+	tree, err := merkletree.MakeMerkleTree(leaves, merkletree.StandardNodeHash)
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	treeNodes := make([]merkletree.BytesLike, len(tree))
+	for i, node := range tree {
+		treeNodes[i] = node
+	}
+	proof, err := merkletree.GetProof(treeNodes, len(tree)-3)
+	if err != nil {
+		t.Fatalf("Failed to get proof: %v", err)
+	}
+	proofJSON, err := json.Marshal(proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"why", "--root", string(tree[0]), "--value", "alice", "--proof", string(proofJSON)}
+	if code := run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("why exited with %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "causes: abi-encoding, double-hash") {
+		t.Errorf("Unexpected why output:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	commaProof := make([]string, len(proof))
+	for i, node := range proof {
+		commaProof[i] = string(node)
+	}
+	args = []string{"why", "--json", "--root", string(tree[0]), "--value", "bob", "--proof", strings.Join(commaProof, ",")}
+	if code := run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("why --json exited with %d: %s", code, stderr.String())
+	}
+	var report merkletree.DiagnosisReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("why --json did not print JSON: %v", err)
+	}
+	if report.Verified || report.Match != nil || len(report.Candidates) == 0 {
+		t.Errorf("Expected no match for the wrong value, got %+v", report.Match)
+	}
+
+	if code := run([]string{"why", "--value", "alice"}, &stdout, &stderr); code == 0 {
+		t.Error("why without --root succeeded")
+	}
+}
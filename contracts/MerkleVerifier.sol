@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+
+import {MerkleProof} from "@openzeppelin/contracts/utils/cryptography/MerkleProof.sol";
+
+// Minimal verifier for GoMerkle trees, for integration tests. It adds nothing
+// to OpenZeppelin's MerkleProof but the leaf hashes of uint256 values, so
+// that leaf hashing is checked on chain too:
+//
+//   verifyValue        keccak256(bytes.concat(keccak256(abi.encode(value)))),
+//                      the leaf of CompatLatest and OpenZeppelin's StandardMerkleTree
+//   verifyPackedValue  keccak256(abi.encodePacked(value)), the leaf of CompatV0
+contract MerkleVerifier {
+    bytes32 public immutable root;
+
+    constructor(bytes32 root_) {
+        root = root_;
+    }
+
+    function verify(bytes32[] calldata proof, bytes32 leaf) external view returns (bool) {
+        return MerkleProof.verifyCalldata(proof, root, leaf);
+    }
+
+    function verifyValue(bytes32[] calldata proof, uint256 value) external view returns (bool) {
+        bytes32 leaf = keccak256(bytes.concat(keccak256(abi.encode(value))));
+        return MerkleProof.verifyCalldata(proof, root, leaf);
+    }
+
+    function verifyPackedValue(bytes32[] calldata proof, uint256 value) external view returns (bool) {
+        bytes32 leaf = keccak256(abi.encodePacked(value));
+        return MerkleProof.verifyCalldata(proof, root, leaf);
+    }
+
+    function multiProofVerify(
+        bytes32[] calldata proof,
+        bool[] calldata proofFlags,
+        bytes32[] calldata leaves
+    ) external view returns (bool) {
+        return MerkleProof.multiProofVerifyCalldata(proof, proofFlags, root, leaves);
+    }
+}
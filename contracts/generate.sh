@@ -0,0 +1,29 @@
+#!/bin/sh
+# Compiles MerkleVerifier.sol to the creation bytecode deployed by the evmtest
+# tests, evmtest/testdata/MerkleVerifier.bin, and its ABI, which the bindings in
+# evmtest/verifier.go are checked against. Records the compiler in
+# evmtest/testdata/MerkleVerifier.solc-version. Requires solc 0.8.24, the
+# version CI builds with, and npm.
+set -e
+cd "$(dirname "$0")"
+
+SOLC_VERSION=0.8.24
+OZ_VERSION=5.0.2
+
+version=$(solc --version | sed -n 's/^Version: //p')
+case "$version" in
+"$SOLC_VERSION"+*) ;;
+*)
+	echo "generate.sh: need solc $SOLC_VERSION, have ${version:-none}" >&2
+	exit 1
+	;;
+esac
+
+npm install --no-save "@openzeppelin/contracts@$OZ_VERSION"
+solc --optimize --bin --abi --base-path . --include-path node_modules \
+	-o build --overwrite MerkleVerifier.sol
+cp build/MerkleVerifier.bin ../evmtest/testdata/MerkleVerifier.bin
+cp build/MerkleVerifier.abi ../evmtest/testdata/MerkleVerifier.abi
+printf 'solc %s\n@openzeppelin/contracts %s\n' "$version" "$OZ_VERSION" \
+	>../evmtest/testdata/MerkleVerifier.solc-version
+rm -rf build node_modules
@@ -0,0 +1,21 @@
+// Package evmtest checks GoMerkle's proofs and multi-proofs against
+// OpenZeppelin's MerkleProof library running in an EVM, and holds the Go
+// bindings of contracts/MerkleVerifier.sol.
+//
+// It is a separate module so that the library itself keeps no dependency on
+// go-ethereum. The tests only build with the evmtest tag:
+//
+//	cd evmtest && go test -tags evmtest ./...
+//
+// They need the runtime bytecode of testdata/MerkleProofHarness.sol in
+// testdata/MerkleProofHarness.bin-runtime, and fail without it rather than
+// skip the EVM check. testdata/generate.sh compiles it with solc 0.8.24 and
+// records the compiler in testdata/MerkleProofHarness.solc-version; the CI evm
+// job runs the script, runs these tests and fails when the committed files
+// are missing or differ from its build. The MerkleVerifier tests deploy
+// contracts/MerkleVerifier.sol on a simulated chain and need its creation
+// bytecode in testdata/MerkleVerifier.bin, and fail without it.
+// contracts/generate.sh builds it with the same solc, together with
+// testdata/MerkleVerifier.abi, which the bindings in verifier.go are checked
+// against, and testdata/MerkleVerifier.solc-version; CI checks those as well.
+package evmtest
@@ -0,0 +1,226 @@
+//go:build evmtest
+
+package evmtest
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+
+	"github.com/smeneguz/GoMerkle/merkletree"
+)
+
+// harnessABI is the ABI of testdata/MerkleProofHarness.sol.
+const harnessABI = `[
+	{"type":"function","name":"multiProofVerify","stateMutability":"pure",
+	 "inputs":[{"name":"proof","type":"bytes32[]"},{"name":"proofFlags","type":"bool[]"},
+	           {"name":"root","type":"bytes32"},{"name":"leaves","type":"bytes32[]"}],
+	 "outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"processMultiProof","stateMutability":"pure",
+	 "inputs":[{"name":"proof","type":"bytes32[]"},{"name":"proofFlags","type":"bool[]"},
+	           {"name":"leaves","type":"bytes32[]"}],
+	 "outputs":[{"name":"","type":"bytes32"}]}
+]`
+
+// seed makes the randomized trees reproducible.
+const seed = 1962
+
+// divergence is a failing case, written to testdata so it can be replayed.
+type divergence struct {
+	Tree    []merkletree.HexString `json:"tree"`
+	Indices []int                  `json:"indices"`
+	Reason  string                 `json:"reason"`
+}
+
+// harness runs the compiled MerkleProofHarness.
+type harness struct {
+	code []byte
+	abi  abi.ABI
+}
+
+// loadHarness reads the harness bytecode. It is checked in, so a missing
+// file fails the test rather than skipping the EVM check.
+func loadHarness(t *testing.T) *harness {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "MerkleProofHarness.bin-runtime"))
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatal("testdata/MerkleProofHarness.bin-runtime not found; run testdata/generate.sh and commit the result")
+	}
+	if err != nil {
+		t.Fatalf("Failed to read harness: %v", err)
+	}
+
+	return newHarness(t, string(data))
+}
+
+// newHarness parses hex-encoded runtime bytecode.
+func newHarness(t *testing.T, bytecode string) *harness {
+	t.Helper()
+	code, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(bytecode), "0x"))
+	if err != nil {
+		t.Fatalf("Invalid harness bytecode: %v", err)
+	}
+	parsed, err := abi.JSON(strings.NewReader(harnessABI))
+	if err != nil {
+		t.Fatalf("Invalid harness ABI: %v", err)
+	}
+	return &harness{code: code, abi: parsed}
+}
+
+// call executes method on the harness and returns its single output.
+func (h *harness) call(method string, args ...any) (any, error) {
+	input, err := h.abi.Pack(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	ret, _, err := runtime.Execute(h.code, input, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s reverted: %w", method, err)
+	}
+	out, err := h.abi.Unpack(method, ret)
+	if err != nil {
+		return nil, err
+	}
+	return out[0], nil
+}
+
+// toBytes32 converts hex nodes to the ABI representation of bytes32[].
+func toBytes32(nodes []merkletree.HexString) [][32]byte {
+	out := make([][32]byte, len(nodes))
+	for i, n := range nodes {
+		out[i] = common.HexToHash(string(n))
+	}
+	return out
+}
+
+// check compares the local multi-proof of indices against the contract.
+// It returns a description of the divergence, or "" when both agree.
+func (h *harness) check(tree []merkletree.HexString, indices []int) string {
+	bytesTree := make([]merkletree.BytesLike, len(tree))
+	for i, n := range tree {
+		bytesTree[i] = n
+	}
+
+	multiproof, err := merkletree.GetMultiProof(bytesTree, indices)
+	if err != nil {
+		return fmt.Sprintf("GetMultiProof: %v", err)
+	}
+	localRoot, err := merkletree.ProcessMultiProof(multiproof, merkletree.StandardNodeHash)
+	if err != nil {
+		return fmt.Sprintf("ProcessMultiProof: %v", err)
+	}
+
+	proof, leaves := toBytes32(multiproof.Proof), toBytes32(multiproof.Leaves)
+	evmRoot, err := h.call("processMultiProof", proof, multiproof.ProofFlags, leaves)
+	if err != nil {
+		return err.Error()
+	}
+	if got := common.Hash(evmRoot.([32]byte)).Hex(); got != string(localRoot) {
+		return fmt.Sprintf("processMultiProof returned %s, ProcessMultiProof returned %s", got, localRoot)
+	}
+
+	valid, err := h.call("multiProofVerify", proof, multiproof.ProofFlags, common.HexToHash(string(tree[0])), leaves)
+	if err != nil {
+		return err.Error()
+	}
+	if localValid := localRoot == tree[0]; valid.(bool) != localValid {
+		return fmt.Sprintf("multiProofVerify returned %v, local verification %v", valid, localValid)
+	}
+	return ""
+}
+
+// randomTree builds a tree over n random leaves with StandardNodeHash.
+func randomTree(t *testing.T, rng *rand.Rand, n int) []merkletree.HexString {
+	t.Helper()
+	leaves := make([]merkletree.BytesLike, n)
+	for i := range leaves {
+		var leaf [32]byte
+		for j := range leaf {
+			leaf[j] = byte(rng.UintN(256))
+		}
+		leaves[i] = leaf[:]
+	}
+	tree, err := merkletree.MakeMerkleTree(leaves, merkletree.StandardNodeHash)
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	return tree
+}
+
+// randomLeafIndices picks distinct leaf positions of a tree of size n, in descending order.
+func randomLeafIndices(rng *rand.Rand, treeSize, leafCount int) []int {
+	first := treeSize - leafCount
+	picked := rng.Perm(leafCount)[:1+rng.IntN(leafCount)]
+	indices := make([]int, len(picked))
+	for i, p := range picked {
+		indices[i] = first + p
+	}
+	slices.Sort(indices)
+	slices.Reverse(indices)
+	return indices
+}
+
+// saveDivergence writes a failing case to testdata for TestReplayDivergences.
+func saveDivergence(t *testing.T, name string, d divergence) {
+	t.Helper()
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		t.Fatalf("Failed to encode divergence: %v", err)
+	}
+	path := filepath.Join("testdata", "divergence-"+name+".json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to save divergence: %v", err)
+	}
+	t.Logf("Saved failing case to %s", path)
+}
+
+func TestMultiProofMatchesOpenZeppelin(t *testing.T) {
+	h := loadHarness(t)
+	rng := rand.New(rand.NewPCG(seed, seed))
+
+	for trial := 0; trial < 300; trial++ {
+		leafCount := 1 + rng.IntN(64)
+		tree := randomTree(t, rng, leafCount)
+		indices := randomLeafIndices(rng, len(tree), leafCount)
+
+		if reason := h.check(tree, indices); reason != "" {
+			t.Errorf("Trial %d (%d leaves, indices %v): %s", trial, leafCount, indices, reason)
+			saveDivergence(t, fmt.Sprintf("%d-%d", seed, trial), divergence{Tree: tree, Indices: indices, Reason: reason})
+		}
+	}
+}
+
+func TestReplayDivergences(t *testing.T) {
+	h := loadHarness(t)
+
+	paths, err := filepath.Glob(filepath.Join("testdata", "divergence-*.json"))
+	if err != nil {
+		t.Fatalf("Failed to list divergences: %v", err)
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", path, err)
+			}
+			var d divergence
+			if err := json.Unmarshal(data, &d); err != nil {
+				t.Fatalf("Failed to decode %s: %v", path, err)
+			}
+			if reason := h.check(d.Tree, d.Indices); reason != "" {
+				t.Errorf("Still diverges: %s", reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+
+import {MerkleProof} from "@openzeppelin/contracts/utils/cryptography/MerkleProof.sol";
+
+// Exposes MerkleProof's multi-proof functions so they can be called directly.
+contract MerkleProofHarness {
+    function multiProofVerify(
+        bytes32[] calldata proof,
+        bool[] calldata proofFlags,
+        bytes32 root,
+        bytes32[] calldata leaves
+    ) external pure returns (bool) {
+        return MerkleProof.multiProofVerify(proof, proofFlags, root, leaves);
+    }
+
+    function processMultiProof(
+        bytes32[] calldata proof,
+        bool[] calldata proofFlags,
+        bytes32[] calldata leaves
+    ) external pure returns (bytes32) {
+        return MerkleProof.processMultiProof(proof, proofFlags, leaves);
+    }
+}
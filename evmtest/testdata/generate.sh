@@ -0,0 +1,26 @@
+#!/bin/sh
+# Compiles MerkleProofHarness.sol to the runtime bytecode used by the evmtest
+# tests and records the compiler in MerkleProofHarness.solc-version. Requires
+# solc 0.8.24, the version CI builds with, and npm.
+set -e
+cd "$(dirname "$0")"
+
+SOLC_VERSION=0.8.24
+OZ_VERSION=5.0.2
+
+version=$(solc --version | sed -n 's/^Version: //p')
+case "$version" in
+"$SOLC_VERSION"+*) ;;
+*)
+	echo "generate.sh: need solc $SOLC_VERSION, have ${version:-none}" >&2
+	exit 1
+	;;
+esac
+
+npm install --no-save "@openzeppelin/contracts@$OZ_VERSION"
+solc --optimize --bin-runtime --base-path . --include-path node_modules \
+	-o build --overwrite MerkleProofHarness.sol
+cp build/MerkleProofHarness.bin-runtime MerkleProofHarness.bin-runtime
+printf 'solc %s\n@openzeppelin/contracts %s\n' "$version" "$OZ_VERSION" \
+	>MerkleProofHarness.solc-version
+rm -rf build node_modules
@@ -0,0 +1,116 @@
+package evmtest
+
+// Bindings for contracts/MerkleVerifier.sol, in the shape abigen generates.
+// They do not embed the bytecode, which is built by contracts/generate.sh into
+// testdata/MerkleVerifier.bin, so the Solidity source stays the only copy of
+// the contract in the repository. TestMerkleVerifierABI checks
+// MerkleVerifierABI against the ABI solc writes next to the bytecode.
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// MerkleVerifierABI is the input ABI used to generate the binding from.
+const MerkleVerifierABI = `[
+	{"type":"constructor","inputs":[{"name":"root_","type":"bytes32"}],"stateMutability":"nonpayable"},
+	{"type":"function","name":"root","inputs":[],"outputs":[{"name":"","type":"bytes32"}],"stateMutability":"view"},
+	{"type":"function","name":"verify","stateMutability":"view",
+	 "inputs":[{"name":"proof","type":"bytes32[]"},{"name":"leaf","type":"bytes32"}],
+	 "outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"verifyValue","stateMutability":"view",
+	 "inputs":[{"name":"proof","type":"bytes32[]"},{"name":"value","type":"uint256"}],
+	 "outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"verifyPackedValue","stateMutability":"view",
+	 "inputs":[{"name":"proof","type":"bytes32[]"},{"name":"value","type":"uint256"}],
+	 "outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"multiProofVerify","stateMutability":"view",
+	 "inputs":[{"name":"proof","type":"bytes32[]"},{"name":"proofFlags","type":"bool[]"},
+	           {"name":"leaves","type":"bytes32[]"}],
+	 "outputs":[{"name":"","type":"bool"}]}
+]`
+
+// MerkleVerifier is a binding around a deployed MerkleVerifier contract.
+type MerkleVerifier struct {
+	contract *bind.BoundContract
+}
+
+// DeployMerkleVerifier deploys a new MerkleVerifier for root, from the
+// creation bytecode bin.
+func DeployMerkleVerifier(auth *bind.TransactOpts, backend bind.ContractBackend, bin []byte, root [32]byte) (common.Address, *types.Transaction, *MerkleVerifier, error) {
+	if len(bin) == 0 {
+		return common.Address{}, nil, nil, errors.New("MerkleVerifier bytecode is empty; run contracts/generate.sh")
+	}
+	parsed, err := abi.JSON(strings.NewReader(MerkleVerifierABI))
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	address, tx, contract, err := bind.DeployContract(auth, parsed, bin, backend, root)
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	return address, tx, &MerkleVerifier{contract: contract}, nil
+}
+
+// NewMerkleVerifier creates a binding to a MerkleVerifier deployed at address.
+func NewMerkleVerifier(address common.Address, backend bind.ContractBackend) (*MerkleVerifier, error) {
+	parsed, err := abi.JSON(strings.NewReader(MerkleVerifierABI))
+	if err != nil {
+		return nil, err
+	}
+	return &MerkleVerifier{contract: bind.NewBoundContract(address, parsed, backend, backend, backend)}, nil
+}
+
+// Root is a free data retrieval call binding the contract method 0xebf0c717.
+//
+// Solidity: function root() view returns(bytes32)
+func (v *MerkleVerifier) Root(opts *bind.CallOpts) ([32]byte, error) {
+	var out []any
+	if err := v.contract.Call(opts, &out, "root"); err != nil {
+		return [32]byte{}, err
+	}
+	return *abi.ConvertType(out[0], new([32]byte)).(*[32]byte), nil
+}
+
+// Verify is a free data retrieval call binding the contract method verify.
+//
+// Solidity: function verify(bytes32[] proof, bytes32 leaf) view returns(bool)
+func (v *MerkleVerifier) Verify(opts *bind.CallOpts, proof [][32]byte, leaf [32]byte) (bool, error) {
+	return v.callBool(opts, "verify", proof, leaf)
+}
+
+// VerifyValue is a free data retrieval call binding the contract method verifyValue.
+//
+// Solidity: function verifyValue(bytes32[] proof, uint256 value) view returns(bool)
+func (v *MerkleVerifier) VerifyValue(opts *bind.CallOpts, proof [][32]byte, value *big.Int) (bool, error) {
+	return v.callBool(opts, "verifyValue", proof, value)
+}
+
+// VerifyPackedValue is a free data retrieval call binding the contract method verifyPackedValue.
+//
+// Solidity: function verifyPackedValue(bytes32[] proof, uint256 value) view returns(bool)
+func (v *MerkleVerifier) VerifyPackedValue(opts *bind.CallOpts, proof [][32]byte, value *big.Int) (bool, error) {
+	return v.callBool(opts, "verifyPackedValue", proof, value)
+}
+
+// MultiProofVerify is a free data retrieval call binding the contract method multiProofVerify.
+//
+// Solidity: function multiProofVerify(bytes32[] proof, bool[] proofFlags, bytes32[] leaves) view returns(bool)
+func (v *MerkleVerifier) MultiProofVerify(opts *bind.CallOpts, proof [][32]byte, proofFlags []bool, leaves [][32]byte) (bool, error) {
+	return v.callBool(opts, "multiProofVerify", proof, proofFlags, leaves)
+}
+
+// callBool calls a view method returning a single bool.
+func (v *MerkleVerifier) callBool(opts *bind.CallOpts, method string, params ...any) (bool, error) {
+	var out []any
+	if err := v.contract.Call(opts, &out, method, params...); err != nil {
+		return false, err
+	}
+	return *abi.ConvertType(out[0], new(bool)).(*bool), nil
+}
@@ -0,0 +1,202 @@
+//go:build evmtest
+
+package evmtest
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+
+	"github.com/smeneguz/GoMerkle/merkletree"
+)
+
+// loadVerifierBin reads the MerkleVerifier creation bytecode. It is committed
+// to testdata, so a missing file fails the test rather than skipping the
+// deployment.
+func loadVerifierBin(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "MerkleVerifier.bin"))
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatal("testdata/MerkleVerifier.bin not found; run contracts/generate.sh and commit the result")
+	}
+	if err != nil {
+		t.Fatalf("Failed to read verifier bytecode: %v", err)
+	}
+	return common.FromHex(strings.TrimSpace(string(data)))
+}
+
+// deployVerifier deploys a MerkleVerifier for root on a new simulated chain.
+func deployVerifier(t *testing.T, root merkletree.HexString) *MerkleVerifier {
+	t.Helper()
+	bin := loadVerifierBin(t)
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	deployer := crypto.PubkeyToAddress(key.PublicKey)
+	backend := simulated.NewBackend(types.GenesisAlloc{deployer: {Balance: big.NewInt(1e18)}})
+	t.Cleanup(func() { backend.Close() })
+
+	auth, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+	if err != nil {
+		t.Fatalf("Failed to create transactor: %v", err)
+	}
+	_, _, verifier, err := DeployMerkleVerifier(auth, backend.Client(), bin, common.HexToHash(string(root)))
+	if err != nil {
+		t.Fatalf("Failed to deploy MerkleVerifier: %v", err)
+	}
+	backend.Commit()
+
+	deployed, err := verifier.Root(nil)
+	if err != nil {
+		t.Fatalf("Failed to read root: %v", err)
+	}
+	if common.Hash(deployed).Hex() != string(root) {
+		t.Fatalf("Deployed root %x, want %s", deployed, root)
+	}
+	return verifier
+}
+
+// DeployAndVerifyOnSimulatedBackend deploys a MerkleVerifier for the root of
+// tree on a simulated chain and checks there, for each value index in
+// leafIndices, its proof against its leaf hash and against its value hashed
+// on chain, then the multi-proof of all of them. Any proof the contract
+// rejects fails the test, naming the semantics that drifted.
+func DeployAndVerifyOnSimulatedBackend(t *testing.T, tree *merkletree.StandardMerkleTree[merkletree.U256], leafIndices []int) {
+	t.Helper()
+	verifier := deployVerifier(t, tree.Root())
+	verifyValue := verifier.VerifyPackedValue
+	if tree.Algorithm().LeafHash == merkletree.HashKeccak256Double {
+		verifyValue = verifier.VerifyValue
+	}
+
+	leafHashes := make([]merkletree.HexString, len(leafIndices))
+	for i, index := range leafIndices {
+		proof, err := tree.GetProof(index)
+		if err != nil {
+			t.Fatalf("Failed to get proof %d: %v", index, err)
+		}
+		value := tree.Values[index].Value
+		leafHashes[i] = tree.Tree[tree.Values[index].TreeIndex]
+
+		valid, err := verifier.Verify(nil, toBytes32(proof), common.HexToHash(string(leafHashes[i])))
+		if err != nil || !valid {
+			t.Errorf("Value %d: MerkleProof.verify rejects the proof of leaf %s (%v): node hashing or proof order drifted from on-chain semantics",
+				index, leafHashes[i], err)
+		}
+		valid, err = verifyValue(nil, toBytes32(proof), value.Big())
+		if err != nil || !valid {
+			t.Errorf("Value %d: the contract hashes %s to another leaf than %s (%v): leaf hashing %s drifted from on-chain semantics",
+				index, value, leafHashes[i], err, tree.Algorithm().LeafHash)
+		}
+	}
+
+	multiProof, err := tree.CombineToMultiProof(leafHashes)
+	if err != nil {
+		t.Fatalf("Failed to get multi-proof: %v", err)
+	}
+	valid, err := verifier.MultiProofVerify(nil, toBytes32(multiProof.Proof), multiProof.ProofFlags, toBytes32(multiProof.Leaves))
+	if err != nil || !valid {
+		t.Errorf("MerkleProof.multiProofVerify rejects the multi-proof of values %v (%v): multi-proof ordering drifted from on-chain semantics",
+			leafIndices, err)
+	}
+}
+
+// verifierTree builds a sorted standard tree over n uint256 values.
+func verifierTree(t *testing.T, n int, compatibility merkletree.CompatibilityMode) *merkletree.StandardMerkleTree[merkletree.U256] {
+	t.Helper()
+	values := make([]merkletree.U256, n)
+	for i := range values {
+		values[i] = merkletree.U256FromUint64(uint64(1000 + 7*i))
+	}
+	tree, err := merkletree.NewStandardMerkleTree(values, merkletree.MerkleTreeOptions{SortLeaves: merkletree.Bool(true), Compatibility: compatibility})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	return tree
+}
+
+func TestMerkleVerifierSortedTrees(t *testing.T) {
+	loadVerifierBin(t)
+	for _, compatibility := range []merkletree.CompatibilityMode{merkletree.CompatV0, merkletree.CompatLatest} {
+		for n := 1; n <= 32; n++ {
+			t.Run(fmt.Sprintf("%s/%d", compatibility, n), func(t *testing.T) {
+				tree := verifierTree(t, n, compatibility)
+				all := make([]int, n)
+				for i := range all {
+					all[i] = i
+				}
+				DeployAndVerifyOnSimulatedBackend(t, tree, all)
+			})
+		}
+	}
+}
+
+func TestMerkleVerifierRejects(t *testing.T) {
+	tree := verifierTree(t, 8, merkletree.CompatLatest)
+	verifier := deployVerifier(t, tree.Root())
+	proof, err := tree.GetProof(3)
+	if err != nil {
+		t.Fatalf("Failed to get proof: %v", err)
+	}
+	value := tree.Values[3].Value.Big()
+
+	if valid, err := verifier.VerifyValue(nil, toBytes32(proof), new(big.Int).Add(value, big.NewInt(1))); err != nil || valid {
+		t.Errorf("verifyValue accepted another value (%v)", err)
+	}
+	if valid, err := verifier.VerifyPackedValue(nil, toBytes32(proof), value); err != nil || valid {
+		t.Errorf("verifyPackedValue accepted a CompatLatest leaf (%v)", err)
+	}
+	if valid, err := verifier.Verify(nil, toBytes32(proof[1:]), common.HexToHash(string(tree.Tree[tree.Values[3].TreeIndex]))); err != nil || valid {
+		t.Errorf("verify accepted a truncated proof (%v)", err)
+	}
+}
+
+// TestMerkleVerifierABI checks the hand-written bindings against the ABI solc
+// builds from contracts/MerkleVerifier.sol.
+func TestMerkleVerifierABI(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "MerkleVerifier.abi"))
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatal("testdata/MerkleVerifier.abi not found; run contracts/generate.sh and commit the result")
+	}
+	if err != nil {
+		t.Fatalf("Failed to read verifier ABI: %v", err)
+	}
+	compiled, err := abi.JSON(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("Failed to parse compiled ABI: %v", err)
+	}
+	bound, err := abi.JSON(strings.NewReader(MerkleVerifierABI))
+	if err != nil {
+		t.Fatalf("Failed to parse MerkleVerifierABI: %v", err)
+	}
+
+	if got, want := bound.Constructor.String(), compiled.Constructor.String(); got != want {
+		t.Errorf("Constructor %q, compiled %q", got, want)
+	}
+	if len(bound.Methods) != len(compiled.Methods) {
+		t.Errorf("Bindings have %d methods, compiled ABI %d", len(bound.Methods), len(compiled.Methods))
+	}
+	for name, want := range compiled.Methods {
+		got, ok := bound.Methods[name]
+		if !ok {
+			t.Errorf("Method %s missing from MerkleVerifierABI", want.Sig)
+			continue
+		}
+		if got.String() != want.String() {
+			t.Errorf("Method %s is %q, compiled %q", name, got, want)
+		}
+	}
+}
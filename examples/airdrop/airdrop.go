@@ -0,0 +1,253 @@
+// Package airdrop is the end-to-end example of the library: it turns a CSV of
+// recipients into an OpenZeppelin-compatible tree, writes the tree dump and
+// one claim per recipient, and checks every claim with the library's own
+// verifiers before reporting.
+//
+// The input has a header line "address,amount" and one recipient per line.
+// Each leaf is the hash of abi.encode(address, uint256), so the root can be
+// set in a contract verifying with OpenZeppelin's MerkleProof.
+package airdrop
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/smeneguz/GoMerkle/merkletree"
+)
+
+// Config says where Run reads recipients and writes its outputs.
+type Config struct {
+	Input  string // CSV of recipients
+	OutDir string // Receives tree.json, report.json and claims/<address>.json
+}
+
+// Claim is what a recipient submits to claim their amount.
+type Claim struct {
+	Address  string                 `json:"address"`  // Lowercase 0x-prefixed address
+	Amount   string                 `json:"amount"`   // Decimal amount
+	Root     merkletree.HexString   `json:"root"`     // Root the proof verifies against
+	LeafHash merkletree.HexString   `json:"leafHash"` // keccak256(keccak256(abi.encode(address, amount)))
+	Proof    []merkletree.HexString `json:"proof"`    // Sibling hashes from the leaf to the root
+}
+
+// Report summarizes a run. It is also written to report.json.
+type Report struct {
+	Root        merkletree.HexString        `json:"root"`
+	Recipients  int                         `json:"recipients"`
+	TotalAmount string                      `json:"totalAmount"`
+	Verified    int                         `json:"verified"`         // Claims that passed every check
+	Failed      []string                    `json:"failed,omitempty"` // Addresses whose claim did not
+	Stats       merkletree.ProofStatsReport `json:"stats"`
+}
+
+// recipient is one line of the input.
+type recipient struct {
+	address [20]byte
+	amount  *big.Int
+}
+
+// maxAmount is the largest amount a uint256 holds.
+var maxAmount = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// Run builds the tree for cfg.Input and writes the outputs to cfg.OutDir,
+// creating it if needed. A report with failed claims is returned with an
+// error; the outputs are written either way.
+func Run(cfg Config) (Report, error) {
+	recipients, err := readRecipients(cfg.Input)
+	if err != nil {
+		return Report{}, err
+	}
+
+	values := make([][]byte, len(recipients))
+	metadata := make([]json.RawMessage, len(recipients))
+	total := new(big.Int)
+	for i, r := range recipients {
+		values[i] = encodeLeaf(r)
+		metadata[i], _ = json.Marshal(map[string]string{"address": r.hexAddress(), "amount": r.amount.String()})
+		total.Add(total, r.amount)
+	}
+	tree, err := merkletree.NewStandardMerkleTree(values, merkletree.MerkleTreeOptions{
+		Compatibility: merkletree.CompatLatest,
+		LeafMetadata:  metadata,
+	})
+	if err != nil {
+		return Report{}, err
+	}
+
+	if err := os.MkdirAll(filepath.Join(cfg.OutDir, "claims"), 0o755); err != nil {
+		return Report{}, err
+	}
+	dump, err := tree.Dump()
+	if err != nil {
+		return Report{}, err
+	}
+	dumpFile := filepath.Join(cfg.OutDir, "tree.json")
+	if err := writeJSON(dumpFile, dump); err != nil {
+		return Report{}, err
+	}
+
+	stats, err := tree.ProofStats()
+	if err != nil {
+		return Report{}, err
+	}
+	report := Report{
+		Root:        tree.Root(),
+		Recipients:  len(recipients),
+		TotalAmount: total.String(),
+		Stats:       stats,
+	}
+	for i, r := range recipients {
+		exported, err := tree.ExportClaim(i)
+		if err != nil {
+			return Report{}, err
+		}
+		claim := Claim{
+			Address:  r.hexAddress(),
+			Amount:   r.amount.String(),
+			Root:     exported.Root,
+			LeafHash: exported.LeafHash,
+			Proof:    exported.Proof,
+		}
+		if err := writeJSON(filepath.Join(cfg.OutDir, "claims", claim.Address+".json"), claim); err != nil {
+			return Report{}, err
+		}
+		if VerifyClaim(claim) && verifyInTree(tree, i, claim) {
+			report.Verified++
+		} else {
+			report.Failed = append(report.Failed, claim.Address)
+		}
+	}
+
+	// The dump must load back as it was written
+	data, err := os.ReadFile(dumpFile)
+	if err != nil {
+		return Report{}, err
+	}
+	if err := merkletree.VerifyDumpIntegrity(data, false); err != nil {
+		return Report{}, fmt.Errorf("%s: %w", dumpFile, err)
+	}
+
+	if err := writeJSON(filepath.Join(cfg.OutDir, "report.json"), report); err != nil {
+		return Report{}, err
+	}
+	if len(report.Failed) > 0 {
+		return report, fmt.Errorf("%d of %d claims failed verification", len(report.Failed), report.Recipients)
+	}
+	return report, nil
+}
+
+// VerifyClaim checks a claim the way a contract would: its leaf hash must be
+// that of its address and amount, and its proof must lead to its root.
+func VerifyClaim(claim Claim) bool {
+	r, err := parseRecipient(claim.Address, claim.Amount)
+	if err != nil {
+		return false
+	}
+	if merkletree.OpenZeppelinLeafHash(encodeLeaf(r)) != claim.LeafHash {
+		return false
+	}
+	envelope := merkletree.ProofEnvelope{Root: claim.Root, LeafHash: claim.LeafHash, Proof: claim.Proof}
+	valid, err := envelope.Verify(nil)
+	return err == nil && valid
+}
+
+// verifyInTree checks a claim against the tree that issued it.
+func verifyInTree(tree *merkletree.StandardMerkleTree[[]byte], index int, claim Claim) bool {
+	valid, err := tree.Verify(index, claim.Proof)
+	return err == nil && valid && claim.Root == tree.Root()
+}
+
+// readRecipients reads and validates the input CSV.
+func readRecipients(path string) ([]recipient, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%s: empty input", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if !strings.EqualFold(header[0], "address") || !strings.EqualFold(header[1], "amount") {
+		return nil, fmt.Errorf("%s: header is %q, want address,amount", path, strings.Join(header, ","))
+	}
+
+	var recipients []recipient
+	seen := make(map[[20]byte]int)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		line, _ := reader.FieldPos(0)
+		r, err := parseRecipient(record[0], record[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if first, ok := seen[r.address]; ok {
+			return nil, fmt.Errorf("%s:%d: %s is already listed on line %d", path, line, r.hexAddress(), first)
+		}
+		seen[r.address] = line
+		recipients = append(recipients, r)
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("%s: no recipients", path)
+	}
+	return recipients, nil
+}
+
+// parseRecipient parses an address and a decimal uint256 amount.
+func parseRecipient(address, amount string) (recipient, error) {
+	var r recipient
+	raw, ok := strings.CutPrefix(strings.ToLower(address), "0x")
+	if !ok || len(raw) != 40 {
+		return r, fmt.Errorf("address %q is not 0x followed by 40 hex digits", address)
+	}
+	if _, err := hex.Decode(r.address[:], []byte(raw)); err != nil {
+		return r, fmt.Errorf("address %q: %w", address, err)
+	}
+	r.amount, ok = new(big.Int).SetString(amount, 10)
+	if !ok || r.amount.Sign() < 0 || r.amount.Cmp(maxAmount) > 0 {
+		return r, fmt.Errorf("amount %q is not a uint256", amount)
+	}
+	return r, nil
+}
+
+// encodeLeaf returns abi.encode(address, uint256) for a recipient.
+func encodeLeaf(r recipient) []byte {
+	encoded := make([]byte, 64)
+	copy(encoded[12:32], r.address[:])
+	r.amount.FillBytes(encoded[32:])
+	return encoded
+}
+
+// hexAddress returns the address as lowercase 0x-prefixed hex.
+func (r recipient) hexAddress() string {
+	return "0x" + hex.EncodeToString(r.address[:])
+}
+
+// writeJSON writes v to path as indented JSON.
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,205 @@
+package airdrop
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smeneguz/GoMerkle/merkletree"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden outputs and the README example")
+
+// readmeFile holds the example section generated by TestReadmeExample.
+const readmeFile = "../../README.md"
+
+// Markers delimiting the generated README section.
+const (
+	readmeStart = "<!-- airdrop example: generated by examples/airdrop tests, do not edit -->"
+	readmeEnd   = "<!-- end airdrop example -->"
+)
+
+// runGolden runs the example on the test CSV into a temporary directory.
+func runGolden(t *testing.T) (Report, string) {
+	t.Helper()
+	out := t.TempDir()
+	report, err := Run(Config{Input: filepath.Join("testdata", "recipients.csv"), OutDir: out})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	return report, out
+}
+
+func TestRunGolden(t *testing.T) {
+	report, out := runGolden(t)
+	if report.Recipients != 5 || report.Verified != 5 || len(report.Failed) != 0 {
+		t.Errorf("Report = %+v", report)
+	}
+
+	// The dump carries the library version, so it is checked rather than compared
+	data, err := os.ReadFile(filepath.Join(out, "tree.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := merkletree.VerifyDumpIntegrity(data, false); err != nil {
+		t.Errorf("tree.json: %v", err)
+	}
+	var dump struct {
+		Tree []merkletree.HexString `json:"tree"`
+	}
+	if err := json.Unmarshal(data, &dump); err != nil || dump.Tree[0] != report.Root {
+		t.Errorf("tree.json root does not match the report: %v", err)
+	}
+
+	claims, err := filepath.Glob(filepath.Join(out, "claims", "*.json"))
+	if err != nil || len(claims) != 5 {
+		t.Fatalf("Expected 5 claim files, got %v", claims)
+	}
+	for _, path := range append(claims, filepath.Join(out, "report.json")) {
+		name, _ := filepath.Rel(out, path)
+		compareGolden(t, name, path)
+	}
+}
+
+// compareGolden compares an output with testdata/golden/name, or rewrites the
+// golden file with -update.
+func compareGolden(t *testing.T, name, path string) {
+	t.Helper()
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "golden", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from %s:\n%s", name, golden, got)
+	}
+}
+
+func TestVerifyClaimRejectsTampering(t *testing.T) {
+	_, out := runGolden(t)
+	data, err := os.ReadFile(filepath.Join(out, "claims", "0x3333333333333333333333333333333333333333.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claim Claim
+	if err := json.Unmarshal(data, &claim); err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyClaim(claim) {
+		t.Fatal("The written claim should verify")
+	}
+
+	tampered := claim
+	tampered.Amount = "2000000000000000000"
+	if VerifyClaim(tampered) {
+		t.Error("A claim with a raised amount should not verify")
+	}
+	tampered = claim
+	tampered.Proof = append([]merkletree.HexString{}, claim.Proof[1:]...)
+	if VerifyClaim(tampered) {
+		t.Error("A claim with a truncated proof should not verify")
+	}
+}
+
+func TestRunInputErrors(t *testing.T) {
+	const a, b = "0x1111111111111111111111111111111111111111", "0x2222222222222222222222222222222222222222"
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", "", "empty input"},
+		{"header", "who,what\n", "want address,amount"},
+		{"no recipients", "address,amount\n", "no recipients"},
+		{"short address", "address,amount\n0x1234,1\n", ":2: address"},
+		{"negative amount", "address,amount\n" + a + ",-1\n", "not a uint256"},
+		{"overflow", "address,amount\n" + a + ",1" + strings.Repeat("0", 78) + "\n", "not a uint256"},
+		{"duplicate", "address,amount\n" + a + ",1\n" + b + ",2\n" + "0X" + a[2:] + ",3\n", "already listed on line 2"},
+		{"columns", "address,amount\n" + a + "\n", "wrong number of fields"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, "recipients.csv")
+			if err := os.WriteFile(input, []byte(tt.input), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Run(Config{Input: input, OutDir: filepath.Join(dir, "out")})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Run error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadmeExample(t *testing.T) {
+	report, out := runGolden(t)
+	claim, err := os.ReadFile(filepath.Join(out, "claims", "0x5555555555555555555555555555555555555555.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var section strings.Builder
+	fmt.Fprintln(&section, readmeStart)
+	fmt.Fprintln(&section, "```go")
+	fmt.Fprintln(&section, `report, err := airdrop.Run(airdrop.Config{Input: "recipients.csv", OutDir: "out"})`)
+	fmt.Fprintf(&section, "fmt.Println(report.Root)     // %s\n", report.Root)
+	fmt.Fprintf(&section, "fmt.Println(report.Verified) // %d of %d claims checked\n", report.Verified, report.Recipients)
+	fmt.Fprintln(&section, "```")
+	fmt.Fprintln(&section)
+	fmt.Fprintln(&section, "with `recipients.csv`:")
+	fmt.Fprintln(&section)
+	fmt.Fprintln(&section, "```csv")
+	csv, err := os.ReadFile(filepath.Join("testdata", "recipients.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	section.Write(csv)
+	fmt.Fprintln(&section, "```")
+	fmt.Fprintln(&section)
+	fmt.Fprintln(&section, "`out/claims/0x5555555555555555555555555555555555555555.json`:")
+	fmt.Fprintln(&section)
+	fmt.Fprintln(&section, "```json")
+	section.Write(claim)
+	fmt.Fprintln(&section, "```")
+	fmt.Fprint(&section, readmeEnd)
+
+	readme, err := os.ReadFile(readmeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := bytes.Index(readme, []byte(readmeStart))
+	end := bytes.Index(readme, []byte(readmeEnd))
+	if start < 0 || end < start {
+		t.Fatalf("%s has no generated airdrop example section", readmeFile)
+	}
+	current := string(readme[start : end+len(readmeEnd)])
+	if current == section.String() {
+		return
+	}
+	if !*update {
+		t.Fatalf("The airdrop example in %s is stale; run go test ./examples/airdrop -update", readmeFile)
+	}
+	updated := string(readme[:start]) + section.String() + string(readme[end+len(readmeEnd):])
+	if err := os.WriteFile(readmeFile, []byte(updated), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
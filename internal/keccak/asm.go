@@ -0,0 +1,6 @@
+//go:build amd64 && !purego && gc
+
+package keccak
+
+// xcryptoAssembly reports whether golang.org/x/crypto/sha3 runs assembly here.
+const xcryptoAssembly = true
@@ -0,0 +1,109 @@
+// Package keccak selects the Keccak-256 implementation used by the module.
+//
+// The one backend built in is XCrypto, golang.org/x/crypto/sha3, which runs
+// an assembly permutation on amd64 and pure Go elsewhere or when built with
+// the purego tag. Both paths give the same digests, which the self test
+// checks against known answers. Backends are kept behind a name so that an
+// accelerated implementation can be added without touching the callers.
+package keccak
+
+import (
+	"fmt"
+	"hash"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// XCrypto is the name of the golang.org/x/crypto/sha3 backend.
+const XCrypto = "x/crypto"
+
+// backend is a Keccak-256 implementation with a pool of its hashers.
+type backend struct {
+	name string
+	pool sync.Pool // Values are hash.Hash
+}
+
+// newBackend returns a backend whose hashers come from newHash.
+func newBackend(name string, newHash func() hash.Hash) *backend {
+	return &backend{name: name, pool: sync.Pool{New: func() any { return newHash() }}}
+}
+
+// backends holds every backend by name.
+var backends = map[string]*backend{
+	XCrypto: newBackend(XCrypto, sha3.NewLegacyKeccak256),
+}
+
+// selected is the backend in use.
+var selected atomic.Pointer[backend]
+
+func init() {
+	selected.Store(backends[XCrypto])
+}
+
+// Backends returns the names of the built-in backends, sorted.
+func Backends() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Selected returns the name of the backend in use.
+func Selected() string {
+	return selected.Load().name
+}
+
+// Accelerated reports whether the backend in use runs an assembly permutation.
+func Accelerated() bool {
+	return selected.Load().name == XCrypto && xcryptoAssembly
+}
+
+// Use switches to the named backend and returns a function restoring the
+// previous one. It is meant for tests and benchmarks; hashes already computed
+// are unaffected.
+func Use(name string) (restore func(), err error) {
+	b, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown keccak backend %q", name)
+	}
+	previous := selected.Swap(b)
+	return func() { selected.Store(previous) }, nil
+}
+
+// New returns a new Keccak-256 hasher from the backend in use.
+func New() hash.Hash {
+	return selected.Load().pool.New().(hash.Hash)
+}
+
+// Sum256 returns the Keccak-256 of the concatenated parts, with a pooled
+// hasher of the backend in use.
+func Sum256(parts ...[]byte) [32]byte {
+	return selected.Load().sum(parts)
+}
+
+// SumWith is Sum256 with the named backend, for checking one against another.
+func SumWith(name string, parts ...[]byte) ([32]byte, error) {
+	b, ok := backends[name]
+	if !ok {
+		return [32]byte{}, fmt.Errorf("unknown keccak backend %q", name)
+	}
+	return b.sum(parts), nil
+}
+
+// sum hashes parts with a pooled hasher.
+func (b *backend) sum(parts [][]byte) [32]byte {
+	h := b.pool.Get().(hash.Hash)
+	for _, p := range parts {
+		h.Write(p)
+	}
+	var out [32]byte
+	h.Sum(out[:0])
+	h.Reset()
+	b.pool.Put(h)
+	return out
+}
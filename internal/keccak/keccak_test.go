@@ -0,0 +1,81 @@
+package keccak
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// knownAnswers are published Keccak-256 digests, with the original Keccak
+// padding that Ethereum's keccak256 uses rather than SHA-3's.
+var knownAnswers = []struct {
+	input  string
+	digest string
+}{
+	{"", "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+	{"abc", "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+	{"hello", "1c8aff950685c2ed4bc3174f3472287b56d9517b9c948127319a09a7a36deac8"},
+	{"The quick brown fox jumps over the lazy dog", "4d741b6f1eb29cb2a9b9911c82f56fa8d73b04959d3d9d222895df6c0b28aa15"},
+}
+
+func TestKnownAnswers(t *testing.T) {
+	for _, name := range Backends() {
+		for _, v := range knownAnswers {
+			got, err := SumWith(name, []byte(v.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hex.EncodeToString(got[:]) != v.digest {
+				t.Errorf("%s backend: keccak256(%q) = %x, want %s", name, v.input, got, v.digest)
+			}
+		}
+	}
+}
+
+func TestSplitWrites(t *testing.T) {
+	// Lengths around the 136-byte rate exercise the padding edge cases
+	for _, n := range []int{0, 1, 135, 136, 137, 272, 1000} {
+		data := []byte(strings.Repeat("\xa3", n))
+		want := Sum256(data)
+		h := New()
+		for i := 0; i < n; i += 7 {
+			h.Write(data[i:min(i+7, n)])
+		}
+		if got := h.Sum(nil); hex.EncodeToString(got) != hex.EncodeToString(want[:]) {
+			t.Errorf("%d bytes in 7-byte writes = %x, want %x", n, got, want)
+		}
+	}
+}
+
+func TestUse(t *testing.T) {
+	restore, err := Use(XCrypto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Selected() != XCrypto {
+		t.Errorf("Selected() = %s after Use(XCrypto)", Selected())
+	}
+	restore()
+	if Selected() != XCrypto {
+		t.Errorf("Selected() = %s after restore, want %s", Selected(), XCrypto)
+	}
+
+	if _, err := Use("generic"); err == nil {
+		t.Error("Use of an unknown backend should fail")
+	}
+}
+
+func BenchmarkSum256(b *testing.B) {
+	for _, size := range []int{64, 1024} {
+		data := make([]byte, size)
+		for _, name := range Backends() {
+			b.Run(fmt.Sprintf("%s/%dB", name, size), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				for range b.N {
+					SumWith(name, data)
+				}
+			})
+		}
+	}
+}
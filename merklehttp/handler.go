@@ -0,0 +1,455 @@
+// Package merklehttp serves proofs from a Merkle tree over HTTP.
+//
+// Endpoints:
+//
+//	GET  /root                      the tree root
+//	GET  /proof?index=N             the claim for the value at index N
+//	GET  /proof?value=V             the claim for value V (requires a value parser)
+//	GET  /claims?offset=N&limit=L   a page of claims in canonical leaf order
+//	GET  /claims?after=T&limit=L    the page after the one whose next token is T
+//	POST /verify                    verify a JSON proof envelope against the served root
+//	POST /verify?leafHash=H         verify a packed proof (application/octet-stream)
+//	POST /verify-batch              verify a JSON array of proof envelopes against the served root
+//
+// A packed proof is the proof nodes concatenated into one body, as contracts
+// taking the proof as `bytes` expect. Its leaf hash is a query parameter. It
+// is verified against the served root; a root given in the query must be
+// that root, or the proof is invalid.
+//
+// Envelopes are verified against the root of the served tree. One that names
+// another root is invalid, even if its proof leads there, and so is one whose
+// leaf hash, or the leafHash of a packed proof, is not a leaf of the served
+// tree, such as the root or an internal node.
+//
+// Verification stops when the request context ends, so a server deadline
+// bounds the time spent on a pathological request.
+//
+// Proof issuance can be limited with an IssuancePolicy such as TokenBucket;
+// denied requests get 429 Too Many Requests with a Retry-After header. Stats
+// reports how many distinct leaves each client has asked for, which exposes
+// clients enumerating the tree.
+//
+// Pages of /claims carry the root and algorithm, so each verifies on its own,
+// and follow canonical leaf order, so next tokens survive a server restart
+// with the same tree. The issuance policy is consulted for every claim of a
+// page; a refusal ends the page early, or fails it if nothing was issued.
+//
+// Claims are exported with ExportClaimFor, so a tree with an issuance
+// recorder records each proof served with its requester, the client address
+// unless Options.Requester says otherwise.
+//
+// Error responses are {"error": message, "code": code}. The code is the
+// error's merkletree.Code, such as MERKLE_VALUE_NOT_FOUND, or one of the
+// Code constants of this package; clients should match on it rather than on
+// the message.
+package merklehttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/smeneguz/GoMerkle/merkletree"
+)
+
+// Codes of errors raised by the handler itself, alongside the merkletree
+// codes of library errors in the "code" field of error bodies.
+const (
+	CodeBadRequest     = "MERKLE_BAD_REQUEST"     // The request is malformed
+	CodeIssuanceDenied = "MERKLE_ISSUANCE_DENIED" // The issuance policy refused the proof
+	CodeUnavailable    = "MERKLE_UNAVAILABLE"     // The request ran out of time
+	CodeInternal       = "MERKLE_INTERNAL"        // Any other failure
+)
+
+// maxEnvelopeBytes bounds the size of a request body accepted by /verify.
+const maxEnvelopeBytes = 1 << 20
+
+// DefaultMaxClaimsPerPage is the largest page /claims serves when
+// Options.MaxClaimsPerPage is zero.
+const DefaultMaxClaimsPerPage = 1000
+
+// Handler serves root, proof, and verification requests for a single tree.
+type Handler[T any] struct {
+	tree        *merkletree.MerkleTreeImpl[T]
+	parseValue  func(string) (T, error)
+	policy      IssuancePolicy
+	issuanceKey IssuanceKey
+	requester   func(*http.Request) string
+	maxPage     int
+	counters    *issuanceCounters
+	mux         *http.ServeMux
+}
+
+// Options configures a Handler. The zero value issues proofs without limits.
+type Options struct {
+	// IssuancePolicy is consulted before each proof is generated. If nil,
+	// every proof is issued.
+	IssuancePolicy IssuancePolicy
+
+	// IssuanceKey selects the key passed to IssuancePolicy. If nil,
+	// KeyByClientIP is used.
+	IssuanceKey IssuanceKey
+
+	// MaxTrackedClients bounds the number of clients counted by Stats; the
+	// least recently active are forgotten first. Zero means DefaultMaxTrackedClients.
+	MaxTrackedClients int
+
+	// MaxLeavesPerClient bounds the distinct leaves remembered per client.
+	// Zero means DefaultMaxLeavesPerClient.
+	MaxLeavesPerClient int
+
+	// Requester identifies who asked for a proof in the tree's issuance
+	// record; see merkletree.SetIssuanceRecorder. If nil, the client address
+	// is used.
+	Requester func(r *http.Request) string
+
+	// MaxClaimsPerPage caps the limit of a /claims page; larger limits are
+	// reduced to it. Zero means DefaultMaxClaimsPerPage.
+	MaxClaimsPerPage int
+}
+
+// NewHandler returns a handler serving the given tree.
+// parseValue converts the "value" query parameter to a tree value; if it is
+// nil, proofs can only be requested by index.
+func NewHandler[T any](tree *merkletree.MerkleTreeImpl[T], parseValue func(string) (T, error)) *Handler[T] {
+	return NewHandlerWithOptions(tree, parseValue, Options{})
+}
+
+// NewHandlerWithOptions is NewHandler with issuance limits.
+func NewHandlerWithOptions[T any](tree *merkletree.MerkleTreeImpl[T], parseValue func(string) (T, error), opts Options) *Handler[T] {
+	h := &Handler[T]{
+		tree:        tree,
+		parseValue:  parseValue,
+		policy:      opts.IssuancePolicy,
+		issuanceKey: opts.IssuanceKey,
+		requester:   opts.Requester,
+		maxPage:     opts.MaxClaimsPerPage,
+		counters:    newIssuanceCounters(opts.MaxTrackedClients, opts.MaxLeavesPerClient),
+		mux:         http.NewServeMux(),
+	}
+	if h.issuanceKey == nil {
+		h.issuanceKey = KeyByClientIP
+	}
+	if h.requester == nil {
+		h.requester = clientIP
+	}
+	if h.maxPage <= 0 {
+		h.maxPage = DefaultMaxClaimsPerPage
+	}
+	h.mux.HandleFunc("GET /root", h.handleRoot)
+	h.mux.HandleFunc("GET /proof", h.handleProof)
+	h.mux.HandleFunc("GET /claims", h.handleClaims)
+	h.mux.HandleFunc("POST /verify", h.handleVerify)
+	h.mux.HandleFunc("POST /verify-batch", h.handleVerifyBatch)
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// Stats returns the proof issuance counters.
+func (h *Handler[T]) Stats() IssuanceStats {
+	return h.counters.snapshot()
+}
+
+// handleRoot serves GET /root.
+func (h *Handler[T]) handleRoot(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]merkletree.HexString{"root": h.tree.Root()})
+}
+
+// handleProof serves GET /proof.
+func (h *Handler[T]) handleProof(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var leaf any
+	switch {
+	case query.Has("index"):
+		index, err := strconv.Atoi(query.Get("index"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("index must be an integer"))
+			return
+		}
+		leaf = index
+	case query.Has("value"):
+		if h.parseValue == nil {
+			writeError(w, http.StatusBadRequest, errors.New("lookup by value is not supported; use index"))
+			return
+		}
+		value, err := h.parseValue(query.Get("value"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid value: %w", err))
+			return
+		}
+		leaf = value
+	default:
+		writeError(w, http.StatusBadRequest, errors.New("index or value is required"))
+		return
+	}
+
+	leafHash, err := h.tree.LeafHashFromInput(leaf)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	client := clientIP(r)
+	if h.policy != nil {
+		if err := h.policy.Allow(h.issuanceKey(r, leafHash)); err != nil {
+			h.counters.recordDenied(client)
+			writeDenied(w, err)
+			return
+		}
+	}
+
+	claim, err := h.tree.ExportClaimFor(leaf, h.requester(r))
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	h.counters.recordIssued(client, leafHash)
+	writeJSON(w, http.StatusOK, claim)
+}
+
+// handleClaims serves GET /claims.
+func (h *Handler[T]) handleClaims(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := merkletree.PageOptions{After: query.Get("after"), Limit: h.maxPage, Requester: h.requester(r)}
+	if query.Has("offset") {
+		offset, err := strconv.Atoi(query.Get("offset"))
+		if err != nil || offset < 0 {
+			writeError(w, http.StatusBadRequest, errors.New("offset must be a non-negative integer"))
+			return
+		}
+		opts.Offset = offset
+	}
+	if query.Has("limit") {
+		limit, err := strconv.Atoi(query.Get("limit"))
+		if err != nil || limit < 1 {
+			writeError(w, http.StatusBadRequest, errors.New("limit must be a positive integer"))
+			return
+		}
+		opts.Limit = min(limit, h.maxPage)
+	}
+
+	client := clientIP(r)
+	refused := false
+	if h.policy != nil {
+		opts.Allow = func(leafHash merkletree.HexString) error {
+			err := h.policy.Allow(h.issuanceKey(r, leafHash))
+			refused = err != nil
+			return err
+		}
+	}
+	page, err := h.tree.ExportClaimsPage(opts)
+	if err != nil && refused {
+		h.counters.recordDenied(client)
+		writeDenied(w, err)
+		return
+	}
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	for _, claim := range page.Claims {
+		h.counters.recordIssued(client, claim.LeafHash)
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// handleVerify serves POST /verify, with a JSON envelope or a packed proof.
+func (h *Handler[T]) handleVerify(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxEnvelopeBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("error reading body: %w", err))
+		return
+	}
+
+	var valid bool
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/octet-stream" {
+		valid, err = h.verifyPacked(r, body)
+	} else {
+		var envelope merkletree.ProofEnvelope
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid proof envelope: %w", err))
+			return
+		}
+		pinned, served := h.pin(envelope)
+		valid, err = pinned.VerifyCtx(r.Context(), h.tree.NodeHash)
+		valid = valid && served
+	}
+	if err != nil {
+		writeError(w, verifyStatusFor(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"valid": valid})
+}
+
+// pin returns envelope set to verify against the served root, and whether
+// it names that root and a leaf of the served tree. Envelopes carry the root
+// and leaf hash they claim, so trusting either would let a client prove a
+// node that is no value: another root its proof leads to, or the served root
+// or an internal node given as the leaf with the rest of its path. An
+// envelope whose root does not parse is returned as is, for verification to
+// report.
+func (h *Handler[T]) pin(envelope merkletree.ProofEnvelope) (merkletree.ProofEnvelope, bool) {
+	root, err := merkletree.ToHex(envelope.Root)
+	if err != nil {
+		return envelope, false
+	}
+	served := h.tree.Root()
+	envelope.Root = served
+	return envelope, root.Normalize() == served.Normalize() && h.tree.HasLeafHash(envelope.LeafHash)
+}
+
+// verifyPacked verifies a packed proof body for the leafHash query parameter
+// against the served root. As with envelopes, the leaf hash must be a leaf of
+// the served tree.
+func (h *Handler[T]) verifyPacked(r *http.Request, body []byte) (bool, error) {
+	query := r.URL.Query()
+	if !query.Has("leafHash") {
+		return false, errors.New("leafHash is required with a packed proof")
+	}
+	proof, err := merkletree.ProofFromPackedBytes(body)
+	if err != nil {
+		return false, err
+	}
+	envelope := merkletree.ProofEnvelope{Root: h.tree.Root(), LeafHash: merkletree.HexString(query.Get("leafHash")), Proof: proof}
+	valid, err := envelope.VerifyCtx(r.Context(), h.tree.NodeHash)
+	valid = valid && h.tree.HasLeafHash(envelope.LeafHash)
+	if err != nil || !query.Has("root") {
+		return valid, err
+	}
+	// A root in the query is only checked against the served one, never
+	// verified against in its place
+	root, err := merkletree.ParseRoot(query.Get("root"))
+	if err != nil {
+		return false, err
+	}
+	return valid && root == h.tree.Root().Normalize(), nil
+}
+
+// batchItem is one entry of a /verify-batch response.
+type batchItem struct {
+	Evaluated bool   `json:"evaluated"`
+	Valid     bool   `json:"valid"`
+	Error     string `json:"error,omitempty"`
+	Code      string `json:"code,omitempty"`
+}
+
+// handleVerifyBatch serves POST /verify-batch. Each item is verified against
+// the served root and must name a leaf of the served tree, as for /verify.
+// When the request context ends part way, the items verified so far are
+// returned with complete set to false.
+func (h *Handler[T]) handleVerifyBatch(w http.ResponseWriter, r *http.Request) {
+	var envelopes []merkletree.ProofEnvelope
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxEnvelopeBytes)).Decode(&envelopes); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid batch: %w", err))
+		return
+	}
+
+	served := make([]bool, len(envelopes))
+	for i, envelope := range envelopes {
+		envelopes[i], served[i] = h.pin(envelope)
+	}
+	results, err := merkletree.BatchVerifyCtx(r.Context(), envelopes, h.tree.NodeHash)
+	items := make([]batchItem, len(results))
+	for i, result := range results {
+		items[i] = batchItem{Evaluated: result.Evaluated, Valid: result.Valid && served[i]}
+		if result.Err != nil {
+			items[i].Error = result.Err.Error()
+			items[i].Code = merkletree.Code(result.Err)
+		}
+	}
+
+	response := struct {
+		Results  []batchItem `json:"results"`
+		Complete bool        `json:"complete"`
+		Error    string      `json:"error,omitempty"`
+	}{Results: items, Complete: err == nil}
+	if err != nil {
+		response.Error = err.Error()
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// statusFor maps library errors to HTTP status codes.
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, merkletree.ErrValueNotFound), errors.Is(err, merkletree.ErrInvalidIndex):
+		return http.StatusNotFound
+	case errors.Is(err, merkletree.ErrAmbiguousValue):
+		return http.StatusConflict
+	case errors.Is(err, merkletree.ErrRecordFailed):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, merkletree.ErrInvalidPageToken), errors.Is(err, merkletree.ErrInvalidOptions),
+		errors.Is(err, merkletree.ErrInvalidLeafType):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// verifyStatusFor maps verification errors to HTTP status codes: a request
+// that ran out of time is unavailable, anything else is a malformed envelope.
+func verifyStatusFor(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadRequest
+}
+
+// writeJSON writes v as a JSON response with the given status.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeDenied answers a request refused by the issuance policy with 429,
+// and a Retry-After header when the policy said how long to wait.
+func writeDenied(w http.ResponseWriter, err error) {
+	var denied *DeniedError
+	if errors.As(err, &denied) && denied.RetryAfter > 0 {
+		seconds := max(1, int(math.Ceil(denied.RetryAfter.Seconds())))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+	writeError(w, http.StatusTooManyRequests, err)
+}
+
+// errorBody is the JSON body of an error response.
+type errorBody struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// writeError writes a JSON error body with the given status. The code is
+// the error's merkletree.Code, or one derived from the status when it has none.
+func writeError(w http.ResponseWriter, status int, err error) {
+	code := merkletree.Code(err)
+	if code == "" {
+		code = codeForStatus(status)
+	}
+	writeJSON(w, status, errorBody{Error: err.Error(), Code: code})
+}
+
+// codeForStatus returns the code of an error without one, by the status it
+// is answered with.
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusTooManyRequests:
+		return CodeIssuanceDenied
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	default:
+		return CodeInternal
+	}
+}
@@ -0,0 +1,502 @@
+package merklehttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/smeneguz/GoMerkle/merkletree"
+)
+
+// newTestServer serves a standard tree over the given values.
+func newTestServer(t *testing.T, values []string) (*httptest.Server, *merkletree.StandardMerkleTree[string]) {
+	t.Helper()
+	tree, err := merkletree.NewStandardMerkleTree(values, merkletree.MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	parse := func(s string) (string, error) { return s, nil }
+	server := httptest.NewServer(NewHandler(&tree.MerkleTreeImpl, parse))
+	t.Cleanup(server.Close)
+	return server, tree
+}
+
+// get performs a GET request and returns the status and body.
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+// verify posts an envelope to /verify and returns the reported validity.
+func verify(t *testing.T, server *httptest.Server, envelope string) bool {
+	t.Helper()
+	resp, err := http.Post(server.URL+"/verify", "application/json", strings.NewReader(envelope))
+	if err != nil {
+		t.Fatalf("POST /verify failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /verify returned %d", resp.StatusCode)
+	}
+	var result struct {
+		Valid bool `json:"valid"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return result.Valid
+}
+
+func TestHandlerSingleLeaf(t *testing.T) {
+	server, tree := newTestServer(t, []string{"only"})
+
+	status, body := get(t, server.URL+"/root")
+	if status != http.StatusOK || !strings.Contains(body, string(tree.Root())) {
+		t.Errorf("GET /root = %d %s", status, body)
+	}
+
+	status, body = get(t, server.URL+"/proof?value=only")
+	if status != http.StatusOK {
+		t.Fatalf("GET /proof = %d %s", status, body)
+	}
+	if !strings.Contains(body, `"proof":[]`) || !strings.Contains(body, `"note"`) {
+		t.Errorf("Single-leaf claim should have an empty proof array and a note: %s", body)
+	}
+
+	if !verify(t, server, body) {
+		t.Error("Single-leaf claim should verify")
+	}
+
+	leaf := string(tree.Root())
+	if !verify(t, server, `{"root":"`+leaf+`","leafHash":"`+leaf+`","proof":null}`) {
+		t.Error("Null proof should be accepted as empty")
+	}
+}
+
+func TestHandlerTwoLeaves(t *testing.T) {
+	server, _ := newTestServer(t, []string{"left", "right"})
+
+	for _, query := range []string{"index=0", "index=1", "value=left", "value=right"} {
+		status, body := get(t, server.URL+"/proof?"+query)
+		if status != http.StatusOK {
+			t.Fatalf("GET /proof?%s = %d %s", query, status, body)
+		}
+
+		var claim merkletree.Claim[string]
+		if err := json.Unmarshal([]byte(body), &claim); err != nil {
+			t.Fatalf("Failed to decode claim: %v", err)
+		}
+		if len(claim.Proof) != 1 {
+			t.Errorf("Two-leaf proof should have one sibling, got %v", claim.Proof)
+		}
+		if !verify(t, server, body) {
+			t.Errorf("Claim for %s should verify", query)
+		}
+	}
+}
+
+// nodeEnvelope returns an envelope claiming the node at tree index i as a
+// leaf, with the sibling path from it to the root. Its proof leads to the
+// root, so only a check that the node is a leaf rejects it.
+func nodeEnvelope(t *testing.T, tree []merkletree.HexString, i int) merkletree.ProofEnvelope {
+	t.Helper()
+	envelope := merkletree.ProofEnvelope{Root: tree[0], LeafHash: tree[i], Proof: []merkletree.HexString{}}
+	for ; i > 0; i, _ = merkletree.ParentIndex(i) {
+		s, err := merkletree.SiblingIndex(i)
+		if err != nil {
+			t.Fatalf("SiblingIndex(%d) failed: %v", i, err)
+		}
+		envelope.Proof = append(envelope.Proof, tree[s])
+	}
+	return envelope
+}
+
+func TestHandlerVerifyForeignRoot(t *testing.T) {
+	server, tree := newTestServer(t, []string{"a", "b", "c", "d", "e"})
+	other, err := merkletree.NewStandardMerkleTree([]string{"x", "y", "z"}, merkletree.MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	foreign, err := other.ExportClaim("y")
+	if err != nil {
+		t.Fatalf("ExportClaim failed: %v", err)
+	}
+	served, err := tree.ExportClaim("c")
+	if err != nil {
+		t.Fatalf("ExportClaim failed: %v", err)
+	}
+
+	envelopes := map[string]merkletree.ProofEnvelope{
+		"foreign tree":          foreign.Envelope(),
+		"root is the leaf hash": {Root: served.LeafHash, LeafHash: served.LeafHash, Proof: []merkletree.HexString{}},
+		"any 32-byte value":     {Root: "0x" + merkletree.HexString(strings.Repeat("ab", 32)), LeafHash: "0x" + merkletree.HexString(strings.Repeat("ab", 32))},
+		"served root as leaf":   nodeEnvelope(t, tree.Tree, 0),
+	}
+	// Internal nodes with the rest of their path lead to the served root
+	for i := 1; i < len(tree.Tree)/2; i++ {
+		envelopes[fmt.Sprintf("internal node %d", i)] = nodeEnvelope(t, tree.Tree, i)
+	}
+	for name, envelope := range envelopes {
+		body, _ := json.Marshal(envelope)
+		if verify(t, server, string(body)) {
+			t.Errorf("%s: envelope verifies against the served tree", name)
+		}
+	}
+
+	// The served root may be written in any case
+	envelope := served.Envelope()
+	envelope.Root = merkletree.HexString(strings.ToUpper(string(envelope.Root)))
+	body, _ := json.Marshal(envelope)
+	if !verify(t, server, string(body)) {
+		t.Error("A claim of the served tree with its root in upper case does not verify")
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	server, _ := newTestServer(t, []string{"a", "b"})
+
+	tests := []struct {
+		query  string
+		status int
+		code   string
+	}{
+		{"", http.StatusBadRequest, CodeBadRequest},
+		{"index=x", http.StatusBadRequest, CodeBadRequest},
+		{"index=5", http.StatusNotFound, merkletree.CodeInvalidIndex},
+		{"value=missing", http.StatusNotFound, merkletree.CodeValueNotFound},
+	}
+
+	for _, tt := range tests {
+		status, body := get(t, server.URL+"/proof?"+tt.query)
+		if status != tt.status {
+			t.Errorf("GET /proof?%s = %d %s, want %d", tt.query, status, body, tt.status)
+		}
+		var response errorBody
+		if err := json.Unmarshal([]byte(body), &response); err != nil || response.Error == "" {
+			t.Errorf("Error response should have an error field: %s", body)
+		}
+		if response.Code != tt.code {
+			t.Errorf("GET /proof?%s has code %q, want %q", tt.query, response.Code, tt.code)
+		}
+	}
+}
+
+// batchResponse is the body of a /verify-batch response.
+type batchResponse struct {
+	Results []struct {
+		Evaluated bool   `json:"evaluated"`
+		Valid     bool   `json:"valid"`
+		Error     string `json:"error"`
+	} `json:"results"`
+	Complete bool   `json:"complete"`
+	Error    string `json:"error"`
+}
+
+// postBatch serves a /verify-batch request with ctx and decodes the response.
+func postBatch(t *testing.T, handler http.Handler, ctx context.Context, batch []merkletree.ProofEnvelope) batchResponse {
+	t.Helper()
+	body, err := json.Marshal(batch)
+	if err != nil {
+		t.Fatalf("Failed to encode batch: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/verify-batch", bytes.NewReader(body)).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /verify-batch = %d %s", rec.Code, rec.Body.String())
+	}
+
+	var response batchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return response
+}
+
+func TestHandlerVerifyBatch(t *testing.T) {
+	tree, err := merkletree.NewStandardMerkleTree([]string{"a", "b", "c"}, merkletree.MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	handler := NewHandler(&tree.MerkleTreeImpl, nil)
+
+	var batch []merkletree.ProofEnvelope
+	for i := range 3 {
+		claim, err := tree.ExportClaim(i)
+		if err != nil {
+			t.Fatalf("Failed to export claim: %v", err)
+		}
+		batch = append(batch, claim.Envelope())
+	}
+	batch[1].Root = batch[0].LeafHash
+
+	// Items naming another root are invalid even when their proof leads there
+	other, err := merkletree.NewStandardMerkleTree([]string{"x", "y"}, merkletree.MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	foreign, err := other.ExportClaim(0)
+	if err != nil {
+		t.Fatalf("Failed to export claim: %v", err)
+	}
+	batch = append(batch, foreign.Envelope(),
+		merkletree.ProofEnvelope{Root: batch[0].LeafHash, LeafHash: batch[0].LeafHash, Proof: []merkletree.HexString{}})
+
+	// Items whose leaf hash is the served root or an internal node are invalid
+	// though their path leads to the served root
+	batch = append(batch, nodeEnvelope(t, tree.Tree, 0), nodeEnvelope(t, tree.Tree, 1))
+
+	response := postBatch(t, handler, context.Background(), batch)
+	if !response.Complete || len(response.Results) != 7 {
+		t.Fatalf("Expected a complete batch of 7, got %+v", response)
+	}
+	for i, r := range response.Results {
+		if want := i == 0 || i == 2; !r.Evaluated || r.Valid != want {
+			t.Errorf("Item %d: %+v, want valid %v", i, r, want)
+		}
+	}
+
+	// A request whose context has ended evaluates nothing and says so
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	response = postBatch(t, handler, canceled, batch)
+	if response.Complete || response.Error == "" {
+		t.Errorf("Canceled batch should be incomplete with an error, got %+v", response)
+	}
+	for i, r := range response.Results {
+		if r.Evaluated {
+			t.Errorf("Item %d should not be evaluated", i)
+		}
+	}
+
+	envelope, _ := json.Marshal(batch[0])
+	req := httptest.NewRequest(http.MethodPost, "/verify", bytes.NewReader(envelope)).WithContext(canceled)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("POST /verify with a canceled context = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+// listRecorder keeps the proofs it records, or fails them all.
+type listRecorder struct {
+	entries []merkletree.IssuedProof
+	fail    bool
+}
+
+func (r *listRecorder) Record(entry merkletree.IssuedProof) error {
+	if r.fail {
+		return errors.New("log unavailable")
+	}
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func TestHandlerRecordsIssuance(t *testing.T) {
+	tree, err := merkletree.NewStandardMerkleTree([]string{"a", "b", "c"}, merkletree.MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	recorder := &listRecorder{}
+	tree.SetIssuanceRecorder(recorder, merkletree.RecorderOptions{Policy: merkletree.RecordFailClosed})
+	parse := func(s string) (string, error) { return s, nil }
+	handler := NewHandlerWithOptions(&tree.MerkleTreeImpl, parse, Options{
+		Requester: func(r *http.Request) string { return r.Header.Get("X-Account") },
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/proof?value=b", nil)
+	req.Header.Set("X-Account", "acct-7")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /proof = %d %s", rec.Code, rec.Body.String())
+	}
+	if len(recorder.entries) != 1 || recorder.entries[0].Requester != "acct-7" || recorder.entries[0].ValueIndex != 1 {
+		t.Errorf("Recorded %+v", recorder.entries)
+	}
+
+	recorder.fail = true
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/proof?value=b", nil))
+	if rec.Code != http.StatusServiceUnavailable || strings.Contains(rec.Body.String(), `"proof"`) {
+		t.Errorf("GET /proof with a failing fail-closed recorder = %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+// getPage fetches a page of /claims and decodes it.
+func getPage(t *testing.T, url string) merkletree.ClaimsPage[string] {
+	t.Helper()
+	status, body := get(t, url)
+	if status != http.StatusOK {
+		t.Fatalf("GET %s returned %d: %s", url, status, body)
+	}
+	var page merkletree.ClaimsPage[string]
+	if err := json.Unmarshal([]byte(body), &page); err != nil {
+		t.Fatalf("Failed to decode page: %v", err)
+	}
+	return page
+}
+
+func TestHandlerClaimsPages(t *testing.T) {
+	// Limits that divide the leaf count, that do not, that equal the page
+	// cap, and that exceed it
+	const maxPage = 40
+	values := make([]string, 300)
+	for i := range values {
+		values[i] = fmt.Sprintf("claimant-%05d", i)
+	}
+	tree, err := merkletree.NewStandardMerkleTree(values, merkletree.MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	parse := func(s string) (string, error) { return s, nil }
+	server := httptest.NewServer(NewHandlerWithOptions(&tree.MerkleTreeImpl, parse, Options{MaxClaimsPerPage: maxPage}))
+	t.Cleanup(server.Close)
+
+	var leaves []merkletree.HexString
+	for _, hash := range tree.LeafHashes {
+		leaves = append(leaves, hash)
+	}
+
+	for _, limit := range []int{30, 33, maxPage, 500} {
+		var claims []merkletree.Claim[string]
+		url := fmt.Sprintf("%s/claims?limit=%d", server.URL, limit)
+		for {
+			page := getPage(t, url)
+			if page.Root != tree.Root() || page.Algorithm != tree.Algorithm() || page.Offset != len(claims) {
+				t.Fatalf("Limit %d: page header %s %+v at offset %d", limit, page.Root, page.Algorithm, page.Offset)
+			}
+			if want := min(limit, maxPage, len(leaves)-len(claims)); len(page.Claims) != want {
+				t.Fatalf("Limit %d: page of %d claims at offset %d, want %d", limit, len(page.Claims), page.Offset, want)
+			}
+			claims = append(claims, page.Claims...)
+			if page.Next == "" {
+				break
+			}
+			url = fmt.Sprintf("%s/claims?after=%s&limit=%d", server.URL, page.Next, limit)
+		}
+
+		if len(claims) != len(leaves) {
+			t.Fatalf("Limit %d: pages hold %d claims, want %d", limit, len(claims), len(leaves))
+		}
+		for i, claim := range claims {
+			if claim.LeafHash != leaves[i] {
+				t.Fatalf("Limit %d: claim %d is for %s, want %s", limit, i, claim.LeafHash, leaves[i])
+			}
+			if limit == 33 {
+				if valid, err := claim.Envelope().Verify(nil); err != nil || !valid {
+					t.Fatalf("Claim %d does not verify: %v", i, err)
+				}
+			}
+		}
+	}
+
+	page := getPage(t, server.URL+"/claims?offset=290")
+	if page.Offset != 290 || len(page.Claims) != 10 || page.Next != "" {
+		t.Errorf("Last page at %d has %d claims, next %q", page.Offset, len(page.Claims), page.Next)
+	}
+
+	for _, query := range []string{"offset=-1", "limit=0", "limit=x", "after=garbage", "after=0-00&offset=3"} {
+		if status, body := get(t, server.URL+"/claims?"+query); status != http.StatusBadRequest {
+			t.Errorf("%s: status %d (%s), want 400", query, status, body)
+		}
+	}
+}
+
+func TestHandlerClaimsPolicy(t *testing.T) {
+	tree, err := merkletree.NewStandardMerkleTree([]string{"a", "b", "c", "d", "e", "f"}, merkletree.MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	bucket := NewTokenBucket(0.001, 4)
+	handler := NewHandlerWithOptions(&tree.MerkleTreeImpl, nil, Options{IssuancePolicy: bucket, IssuanceKey: KeyByClientIP})
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	page := getPage(t, server.URL+"/claims")
+	if len(page.Claims) != 4 || page.Next == "" {
+		t.Fatalf("The policy allows 4 proofs, page has %d claims and next %q", len(page.Claims), page.Next)
+	}
+	status, _ := get(t, server.URL+"/claims?after="+page.Next)
+	if status != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 once the bucket is empty, got %d", status)
+	}
+	stats := handler.Stats()
+	if len(stats.Clients) != 1 || stats.Clients[0].DistinctLeaves != 4 || stats.Clients[0].Denied != 1 {
+		t.Errorf("Stats %+v, want 4 leaves issued and 1 denial", stats)
+	}
+}
+
+func TestHandlerVerifyPacked(t *testing.T) {
+	server, tree := newTestServer(t, []string{"a", "b", "c", "d", "e"})
+	claim, err := tree.ExportClaim("c")
+	if err != nil {
+		t.Fatalf("ExportClaim failed: %v", err)
+	}
+	packed, err := merkletree.ProofToPackedBytes(claim.Proof)
+	if err != nil {
+		t.Fatalf("ProofToPackedBytes failed: %v", err)
+	}
+
+	post := func(query string, body []byte) (int, string) {
+		t.Helper()
+		resp, err := http.Post(server.URL+"/verify?"+query, "application/octet-stream", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST /verify failed: %v", err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	if status, body := post("leafHash="+string(claim.LeafHash), packed); status != http.StatusOK || !strings.Contains(body, `"valid":true`) {
+		t.Errorf("Packed proof = %d %s", status, body)
+	}
+	if status, body := post("leafHash="+string(claim.LeafHash)+"&root="+string(claim.LeafHash), packed); status != http.StatusOK || !strings.Contains(body, `"valid":false`) {
+		t.Errorf("Packed proof against another root = %d %s", status, body)
+	}
+	if status, body := post("leafHash="+string(claim.LeafHash)+"&root="+strings.ToUpper(string(tree.Root())[2:]), packed); status != http.StatusOK || !strings.Contains(body, `"valid":true`) {
+		t.Errorf("Packed proof naming the served root = %d %s", status, body)
+	}
+	if status, body := post("leafHash="+string(claim.LeafHash)+"&root=0x1234", packed); status != http.StatusBadRequest {
+		t.Errorf("Packed proof with a malformed root = %d %s", status, body)
+	}
+
+	// A proof of nothing cannot be made valid by naming its leaf as the root
+	if status, body := post("leafHash="+string(claim.LeafHash)+"&root="+string(claim.LeafHash), nil); status != http.StatusOK || !strings.Contains(body, `"valid":false`) {
+		t.Errorf("Empty packed proof with the leaf hash as root = %d %s", status, body)
+	}
+	// Nor can the served root or an internal node pass as the leaf
+	for i := range len(tree.Tree) / 2 {
+		node := nodeEnvelope(t, tree.Tree, i)
+		nodePacked, err := merkletree.ProofToPackedBytes(node.Proof)
+		if err != nil {
+			t.Fatalf("ProofToPackedBytes failed: %v", err)
+		}
+		if status, body := post("leafHash="+string(node.LeafHash), nodePacked); status != http.StatusOK || !strings.Contains(body, `"valid":false`) {
+			t.Errorf("Packed proof of node %d = %d %s", i, status, body)
+		}
+	}
+	if status, body := post("leafHash="+string(claim.LeafHash), packed[1:]); status != http.StatusBadRequest {
+		t.Errorf("Truncated packed proof = %d %s", status, body)
+	}
+	if status, body := post("", packed); status != http.StatusBadRequest {
+		t.Errorf("Packed proof without leafHash = %d %s", status, body)
+	}
+}
@@ -0,0 +1,168 @@
+package merklehttp
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/smeneguz/GoMerkle/merkletree"
+)
+
+// ErrIssuanceDenied is wrapped by the errors of policies that refuse a proof.
+var ErrIssuanceDenied = merkletree.NewCodedError(CodeIssuanceDenied, "proof issuance denied")
+
+// IssuancePolicy decides whether a proof may be issued. The handler consults
+// it before generating each proof, with the key chosen by Options.IssuanceKey;
+// any error denies the request with 429 Too Many Requests. Implementations
+// must be safe for concurrent use.
+type IssuancePolicy interface {
+	Allow(key string) error
+}
+
+// DeniedError is returned by policies that know when the key may retry.
+// The handler sends a non-zero RetryAfter in the Retry-After header.
+type DeniedError struct {
+	Key        string        // Key that was denied
+	RetryAfter time.Duration // Wait before the next request can succeed
+}
+
+// Error implements the error interface.
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("%v for %s; retry after %v", ErrIssuanceDenied, e.Key, e.RetryAfter)
+}
+
+// Unwrap returns ErrIssuanceDenied.
+func (e *DeniedError) Unwrap() error {
+	return ErrIssuanceDenied
+}
+
+// ErrorCode returns CodeIssuanceDenied.
+func (e *DeniedError) ErrorCode() string {
+	return CodeIssuanceDenied
+}
+
+// IssuanceKey selects the key a request is rate limited by.
+type IssuanceKey func(r *http.Request, leafHash merkletree.HexString) string
+
+// KeyByClientIP limits each client address separately. The address is the
+// connection's, so behind a proxy every request shares the proxy's key unless
+// the proxy rewrites RemoteAddr.
+func KeyByClientIP(r *http.Request, _ merkletree.HexString) string {
+	return clientIP(r)
+}
+
+// KeyByLeafHash limits issuance of each proof, whoever asks for it.
+func KeyByLeafHash(_ *http.Request, leafHash merkletree.HexString) string {
+	return string(leafHash)
+}
+
+// clientIP returns the host part of the request's remote address.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// DefaultMaxBucketKeys is the number of keys a TokenBucket tracks when
+// MaxKeys is not set.
+const DefaultMaxBucketKeys = 10000
+
+// TokenBucket is an IssuancePolicy allowing each key Burst proofs at once,
+// refilled at Rate proofs per second.
+type TokenBucket struct {
+	Rate    float64          // Tokens added per second
+	Burst   int              // Bucket capacity
+	MaxKeys int              // Keys tracked at most; zero means DefaultMaxBucketKeys
+	Now     func() time.Time // Clock; nil means time.Now
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// bucket is the state of one key.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket returns a token bucket policy with the given rate and burst.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{Rate: rate, Burst: burst}
+}
+
+// Allow takes a token from the bucket of key, or returns a *DeniedError
+// saying when the next token will be available.
+func (b *TokenBucket) Allow(key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if b.buckets == nil {
+		b.buckets = make(map[string]*bucket)
+	}
+	k, ok := b.buckets[key]
+	if !ok {
+		b.makeRoom(now)
+		k = &bucket{tokens: float64(b.Burst), last: now}
+		b.buckets[key] = k
+	}
+	b.refill(k, now)
+
+	if k.tokens < 1 {
+		// Without a rate the bucket never refills, and there is no time to give
+		var wait time.Duration
+		if b.Rate > 0 {
+			wait = time.Duration(math.Ceil((1 - k.tokens) / b.Rate * float64(time.Second)))
+		}
+		return &DeniedError{Key: key, RetryAfter: wait}
+	}
+	k.tokens--
+	return nil
+}
+
+// refill adds the tokens accumulated since the bucket was last used.
+func (b *TokenBucket) refill(k *bucket, now time.Time) {
+	if elapsed := now.Sub(k.last).Seconds(); elapsed > 0 {
+		k.tokens = min(float64(b.Burst), k.tokens+elapsed*b.Rate)
+	}
+	k.last = now
+}
+
+// makeRoom keeps the number of tracked keys below MaxKeys. Buckets that have
+// refilled completely are forgotten first, since a new bucket starts full;
+// if that is not enough, arbitrary keys are forgotten and start over.
+func (b *TokenBucket) makeRoom(now time.Time) {
+	limit := b.MaxKeys
+	if limit <= 0 {
+		limit = DefaultMaxBucketKeys
+	}
+	if len(b.buckets) < limit {
+		return
+	}
+
+	for key, k := range b.buckets {
+		b.refill(k, now)
+		if k.tokens >= float64(b.Burst) {
+			delete(b.buckets, key)
+		}
+	}
+	for key := range b.buckets {
+		if len(b.buckets) < limit {
+			break
+		}
+		delete(b.buckets, key)
+	}
+}
+
+// now returns the current time from the injected clock.
+func (b *TokenBucket) now() time.Time {
+	if b.Now != nil {
+		return b.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,195 @@
+package merklehttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/smeneguz/GoMerkle/merkletree"
+)
+
+// fakeClock is a clock that only moves when advanced.
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestTokenBucket(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	bucket := NewTokenBucket(2, 3)
+	bucket.Now = clock.Now
+
+	for i := 0; i < 3; i++ {
+		if err := bucket.Allow("a"); err != nil {
+			t.Fatalf("Request %d within the burst should be allowed: %v", i, err)
+		}
+	}
+	err := bucket.Allow("a")
+	var denied *DeniedError
+	if !errors.As(err, &denied) || !errors.Is(err, ErrIssuanceDenied) {
+		t.Fatalf("Expected a *DeniedError, got %v", err)
+	}
+	if denied.RetryAfter != 500*time.Millisecond {
+		t.Errorf("RetryAfter = %v, want 500ms at 2 tokens per second", denied.RetryAfter)
+	}
+
+	if err := bucket.Allow("b"); err != nil {
+		t.Errorf("Other keys should have their own bucket: %v", err)
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if err := bucket.Allow("a"); err != nil {
+		t.Errorf("A token should be available after RetryAfter: %v", err)
+	}
+	if err := bucket.Allow("a"); err == nil {
+		t.Errorf("Only one token should have been refilled")
+	}
+
+	clock.Advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		if err := bucket.Allow("a"); err != nil {
+			t.Fatalf("Refill should be capped at the burst, request %d denied: %v", i, err)
+		}
+	}
+	if err := bucket.Allow("a"); err == nil {
+		t.Errorf("Refill should not exceed the burst")
+	}
+}
+
+func TestTokenBucketMaxKeys(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	bucket := &TokenBucket{Rate: 1, Burst: 1, MaxKeys: 10, Now: clock.Now}
+
+	for i := 0; i < 1000; i++ {
+		if err := bucket.Allow(fmt.Sprintf("key-%d", i)); err != nil {
+			t.Fatalf("First request of a new key should be allowed: %v", err)
+		}
+	}
+	if len(bucket.buckets) > 10 {
+		t.Errorf("Bucket tracks %d keys, limit is 10", len(bucket.buckets))
+	}
+}
+
+// newLimitedServer serves a standard tree over n values with the given options.
+func newLimitedServer(t *testing.T, n int, opts Options) (*httptest.Server, *Handler[string]) {
+	t.Helper()
+	values := make([]string, n)
+	for i := range values {
+		values[i] = fmt.Sprintf("value-%d", i)
+	}
+	tree, err := merkletree.NewStandardMerkleTree(values, merkletree.MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	handler := NewHandlerWithOptions(&tree.MerkleTreeImpl, nil, opts)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server, handler
+}
+
+func TestHandlerIssuancePolicy(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	bucket := NewTokenBucket(0.25, 2)
+	bucket.Now = clock.Now
+	server, handler := newLimitedServer(t, 4, Options{IssuancePolicy: bucket})
+
+	for i := 0; i < 2; i++ {
+		if status, body := get(t, fmt.Sprintf("%s/proof?index=%d", server.URL, i)); status != http.StatusOK {
+			t.Fatalf("Proof %d within the burst: %d %s", i, status, body)
+		}
+	}
+
+	resp, err := http.Get(server.URL + "/proof?index=2")
+	if err != nil {
+		t.Fatalf("GET /proof failed: %v", err)
+	}
+	var denied errorBody
+	err = json.NewDecoder(resp.Body).Decode(&denied)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", resp.StatusCode)
+	}
+	if err != nil || denied.Code != CodeIssuanceDenied {
+		t.Errorf("Denied response has code %q (%v), want %s", denied.Code, err, CodeIssuanceDenied)
+	}
+	if got := resp.Header.Get("Retry-After"); got != "4" {
+		t.Errorf("Retry-After = %q, want 4 seconds at 0.25 tokens per second", got)
+	}
+
+	clock.Advance(4 * time.Second)
+	if status, body := get(t, server.URL+"/proof?index=2"); status != http.StatusOK {
+		t.Errorf("Proof after Retry-After: %d %s", status, body)
+	}
+
+	stats := handler.Stats()
+	if stats.Issued != 3 || stats.Denied != 1 {
+		t.Errorf("Stats = %+v, want 3 issued and 1 denied", stats)
+	}
+}
+
+func TestHandlerIssuanceKeyByLeaf(t *testing.T) {
+	bucket := &TokenBucket{Rate: 0, Burst: 1}
+	server, _ := newLimitedServer(t, 4, Options{IssuancePolicy: bucket, IssuanceKey: KeyByLeafHash})
+
+	if status, _ := get(t, server.URL+"/proof?index=0"); status != http.StatusOK {
+		t.Fatalf("First proof of leaf 0 should be issued, got %d", status)
+	}
+	if status, _ := get(t, server.URL+"/proof?index=1"); status != http.StatusOK {
+		t.Errorf("Leaves should be limited separately, got %d", status)
+	}
+
+	resp, err := http.Get(server.URL + "/proof?index=0")
+	if err != nil {
+		t.Fatalf("GET /proof failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Second proof of leaf 0 should be denied, got %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Retry-After"); got != "" {
+		t.Errorf("A bucket that never refills has no Retry-After, got %q", got)
+	}
+}
+
+func TestHandlerStatsEnumeration(t *testing.T) {
+	server, handler := newLimitedServer(t, 32, Options{MaxLeavesPerClient: 10})
+
+	for i := 0; i < 32; i++ {
+		get(t, fmt.Sprintf("%s/proof?index=%d", server.URL, i))
+	}
+	get(t, server.URL+"/proof?index=0")
+
+	stats := handler.Stats()
+	if stats.Issued != 33 || len(stats.Clients) != 1 {
+		t.Fatalf("Stats = %+v, want 33 proofs to one client", stats)
+	}
+	client := stats.Clients[0]
+	if client.Issued != 33 || client.DistinctLeaves != 10 || !client.Saturated {
+		t.Errorf("Client stats = %+v, want 33 issued and 10 distinct leaves, saturated", client)
+	}
+}
+
+func TestIssuanceCountersBounds(t *testing.T) {
+	counters := newIssuanceCounters(3, 5)
+	for i := 0; i < 10; i++ {
+		counters.recordIssued(fmt.Sprintf("client-%d", i), "0x01")
+	}
+	counters.recordIssued("client-7", "0x02")
+
+	stats := counters.snapshot()
+	if stats.Issued != 11 || len(stats.Clients) != 3 {
+		t.Fatalf("Stats = %+v, want 11 issued and 3 tracked clients", stats)
+	}
+	if stats.Clients[0].Client != "client-7" || stats.Clients[0].DistinctLeaves != 2 {
+		t.Errorf("Client with most distinct leaves should come first: %+v", stats.Clients)
+	}
+	for _, c := range stats.Clients {
+		if c.Client != "client-7" && c.Client != "client-8" && c.Client != "client-9" {
+			t.Errorf("Least recently active clients should be evicted, found %s", c.Client)
+		}
+	}
+}
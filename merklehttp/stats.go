@@ -0,0 +1,130 @@
+package merklehttp
+
+import (
+	"container/list"
+	"sort"
+	"sync"
+
+	"github.com/smeneguz/GoMerkle/merkletree"
+)
+
+// Default bounds of the issuance counters, used when Options leaves them zero.
+const (
+	DefaultMaxTrackedClients  = 10000
+	DefaultMaxLeavesPerClient = 1000
+)
+
+// ClientStats counts the proofs issued to and denied for one client address.
+type ClientStats struct {
+	Client         string `json:"client"`         // Client address
+	Issued         int    `json:"issued"`         // Proofs issued
+	Denied         int    `json:"denied"`         // Requests refused by the issuance policy
+	DistinctLeaves int    `json:"distinctLeaves"` // Different leaves proven, capped at the tracking limit
+	Saturated      bool   `json:"saturated"`      // DistinctLeaves reached the limit and may be higher
+}
+
+// IssuanceStats summarizes proof issuance since the handler was created.
+type IssuanceStats struct {
+	Issued  int           `json:"issued"`  // Proofs issued to all clients
+	Denied  int           `json:"denied"`  // Requests refused to all clients
+	Clients []ClientStats `json:"clients"` // Tracked clients, most distinct leaves first
+}
+
+// issuanceCounters tracks per-client issuance in bounded memory: at most
+// maxClients clients, least recently active evicted first, and at most
+// maxLeaves distinct leaves per client.
+type issuanceCounters struct {
+	mu         sync.Mutex
+	maxClients int
+	maxLeaves  int
+	issued     int
+	denied     int
+	clients    map[string]*list.Element // Values are *clientCounter
+	recent     *list.List               // Most recently active first
+}
+
+// clientCounter is the state of one client.
+type clientCounter struct {
+	stats  ClientStats
+	leaves map[merkletree.HexString]struct{}
+}
+
+// newIssuanceCounters returns counters with the given bounds, or the defaults for zero.
+func newIssuanceCounters(maxClients, maxLeaves int) *issuanceCounters {
+	if maxClients <= 0 {
+		maxClients = DefaultMaxTrackedClients
+	}
+	if maxLeaves <= 0 {
+		maxLeaves = DefaultMaxLeavesPerClient
+	}
+	return &issuanceCounters{
+		maxClients: maxClients,
+		maxLeaves:  maxLeaves,
+		clients:    make(map[string]*list.Element),
+		recent:     list.New(),
+	}
+}
+
+// recordIssued counts a proof of leafHash issued to client.
+func (c *issuanceCounters) recordIssued(client string, leafHash merkletree.HexString) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.issued++
+	counter := c.touch(client)
+	counter.stats.Issued++
+	if _, seen := counter.leaves[leafHash]; seen {
+		return
+	}
+	if len(counter.leaves) < c.maxLeaves {
+		counter.leaves[leafHash] = struct{}{}
+		counter.stats.DistinctLeaves++
+		return
+	}
+	counter.stats.Saturated = true
+}
+
+// recordDenied counts a request of client refused by the policy.
+func (c *issuanceCounters) recordDenied(client string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.denied++
+	c.touch(client).stats.Denied++
+}
+
+// touch returns the counter of client, marking it most recently active and
+// evicting the least recently active client when the limit is reached.
+func (c *issuanceCounters) touch(client string) *clientCounter {
+	if elem, ok := c.clients[client]; ok {
+		c.recent.MoveToFront(elem)
+		return elem.Value.(*clientCounter)
+	}
+
+	if len(c.clients) >= c.maxClients {
+		oldest := c.recent.Back()
+		c.recent.Remove(oldest)
+		delete(c.clients, oldest.Value.(*clientCounter).stats.Client)
+	}
+	counter := &clientCounter{
+		stats:  ClientStats{Client: client},
+		leaves: make(map[merkletree.HexString]struct{}),
+	}
+	c.clients[client] = c.recent.PushFront(counter)
+	return counter
+}
+
+// snapshot returns a copy of the counters.
+func (c *issuanceCounters) snapshot() IssuanceStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := IssuanceStats{Issued: c.issued, Denied: c.denied, Clients: make([]ClientStats, 0, len(c.clients))}
+	for elem := c.recent.Front(); elem != nil; elem = elem.Next() {
+		stats.Clients = append(stats.Clients, elem.Value.(*clientCounter).stats)
+	}
+	sort.SliceStable(stats.Clients, func(i, j int) bool {
+		return stats.Clients[i].DistinctLeaves > stats.Clients[j].DistinctLeaves
+	})
+	return stats
+}
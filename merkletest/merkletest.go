@@ -0,0 +1,175 @@
+// Package merkletest checks that custom hash functions satisfy what package
+// merkletree assumes of them, for implementers of NodeHash and LeafHash:
+//
+//	func TestMyNodeHash(t *testing.T) {
+//		merkletest.CheckNodeHash(t, myNodeHash, merkletest.HashContract{SortPairs: true})
+//	}
+//
+// Each helper probes the function with edge-case inputs and reports every
+// violation with t.Errorf, naming the inputs and the output.
+package merkletest
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/smeneguz/GoMerkle/merkletree"
+)
+
+// HashContract describes the behaviour a node hash promises.
+type HashContract struct {
+	// SortPairs says the hash orders its children before hashing, like
+	// StandardNodeHash, so swapping them gives the same hash. Otherwise
+	// swapping two different children must change the hash, or proofs could
+	// not tell left from right.
+	SortPairs bool
+}
+
+// nodeProbes are the children CheckNodeHash combines pairwise: all zeros,
+// all ones, leading zeros, a single high byte, and two ordinary hashes.
+var nodeProbes = [][]byte{
+	make([]byte, 32),
+	bytes.Repeat([]byte{0xff}, 32),
+	append(make([]byte, 31), 0x01),
+	append([]byte{0x01}, make([]byte, 31)...),
+	bytes.Repeat([]byte{0x5a}, 32),
+	bytes.Repeat([]byte{0xa5}, 32),
+}
+
+// CheckNodeHash reports every way h breaks the contract of a NodeHash:
+//
+//   - it must not panic, even on empty or short children it should never be given;
+//   - on 32-byte children it must return a 32-byte hash, and the same hash
+//     every time, whether the children are passed as HexString or []byte;
+//   - swapping two different children must keep the hash if cfg.SortPairs is
+//     set, and change it otherwise.
+func CheckNodeHash(t testing.TB, h merkletree.NodeHash, cfg HashContract) {
+	t.Helper()
+
+	for _, pair := range [][2]merkletree.BytesLike{
+		{"", ""},
+		{[]byte{}, []byte{}},
+		{"0x", hexOf(nodeProbes[0])},
+		{hexOf(nodeProbes[1]), []byte{0x01}},
+	} {
+		if _, panicked := callNode(h, pair[0], pair[1]); panicked != nil {
+			t.Errorf("NodeHash(%s, %s) panicked: %v", describe(pair[0]), describe(pair[1]), panicked)
+		}
+	}
+
+	for i, a := range nodeProbes {
+		for j, b := range nodeProbes {
+			left, right := hexOf(a), hexOf(b)
+			name := fmt.Sprintf("NodeHash(%s, %s)", short(left), short(right))
+
+			hash, panicked := callNode(h, left, right)
+			if panicked != nil {
+				t.Errorf("%s panicked: %v", name, panicked)
+				continue
+			}
+			if !merkletree.IsValidMerkleNode(hash) {
+				t.Errorf("%s = %q: not a 32-byte hash", name, hash)
+				continue
+			}
+			if again, _ := callNode(h, left, right); again != hash {
+				t.Errorf("%s is not deterministic: %s, then %s", name, hash, again)
+			}
+			if raw, _ := callNode(h, a, b); !sameHash(raw, hash) {
+				t.Errorf("%s = %s for HexString children but %s for []byte children", name, hash, raw)
+			}
+
+			if i >= j {
+				continue
+			}
+			swapped, panicked := callNode(h, right, left)
+			switch {
+			case panicked != nil:
+				t.Errorf("NodeHash(%s, %s) panicked: %v", short(right), short(left), panicked)
+			case cfg.SortPairs && !sameHash(swapped, hash):
+				t.Errorf("%s = %s but swapped children give %s; SortPairs requires them equal", name, hash, swapped)
+			case !cfg.SortPairs && sameHash(swapped, hash):
+				t.Errorf("%s = %s for both child orders; without SortPairs they must differ", name, hash)
+			}
+		}
+	}
+}
+
+// CheckLeafHash reports every way h breaks the contract of a LeafHash on the
+// given values and the zero value of T: it must not panic, must return a
+// 32-byte hash, the same one every time, and different values must not
+// share a hash. Values should include the edge cases of T the caller cares
+// about, such as empty and maximum-length values.
+func CheckLeafHash[T any](t testing.TB, h func(T) merkletree.HexString, values ...T) {
+	t.Helper()
+
+	var zero T
+	values = append([]T{zero}, values...)
+	seen := make(map[string]int)
+	for i, value := range values {
+		name := fmt.Sprintf("LeafHash(%s)", describe(value))
+
+		hash, panicked := callLeaf(h, value)
+		if panicked != nil {
+			t.Errorf("%s panicked: %v", name, panicked)
+			continue
+		}
+		if !merkletree.IsValidMerkleNode(hash) {
+			t.Errorf("%s = %q: not a 32-byte hash", name, hash)
+			continue
+		}
+		if again, _ := callLeaf(h, value); again != hash {
+			t.Errorf("%s is not deterministic: %s, then %s", name, hash, again)
+		}
+
+		key := strings.ToLower(string(hash))
+		if first, ok := seen[key]; ok && !reflect.DeepEqual(values[first], value) {
+			t.Errorf("%s = %s, the hash of %s too", name, hash, describe(values[first]))
+		}
+		seen[key] = i
+	}
+}
+
+// callNode calls h, returning what it panicked with, if anything.
+func callNode(h merkletree.NodeHash, a, b merkletree.BytesLike) (hash merkletree.HexString, panicked any) {
+	defer func() { panicked = recover() }()
+	return h(a, b), nil
+}
+
+// callLeaf calls h, returning what it panicked with, if anything.
+func callLeaf[T any](h func(T) merkletree.HexString, value T) (hash merkletree.HexString, panicked any) {
+	defer func() { panicked = recover() }()
+	return h(value), nil
+}
+
+// sameHash reports whether two hashes are equal ignoring hex case.
+func sameHash(a, b merkletree.HexString) bool {
+	return strings.EqualFold(string(a), string(b))
+}
+
+// hexOf returns b as a HexString.
+func hexOf(b []byte) merkletree.HexString {
+	return merkletree.HexString(fmt.Sprintf("0x%x", b))
+}
+
+// short abbreviates a 32-byte hash for messages.
+func short(h merkletree.HexString) string {
+	if len(h) <= 14 {
+		return string(h)
+	}
+	return string(h[:8]) + "…" + string(h[len(h)-4:])
+}
+
+// describe formats a value for messages, keeping long ones short.
+func describe(v any) string {
+	s := fmt.Sprintf("%#v", v)
+	if h, ok := v.(merkletree.HexString); ok {
+		s = short(h)
+	}
+	if len(s) > 40 {
+		s = s[:37] + "..."
+	}
+	return s
+}
@@ -0,0 +1,126 @@
+package merkletest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/smeneguz/GoMerkle/merkletree"
+)
+
+// recorder is a testing.TB collecting the errors reported to it.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestBuiltinNodeHashes(t *testing.T) {
+	CheckNodeHash(t, merkletree.StandardNodeHash, HashContract{SortPairs: true})
+	CheckNodeHash(t, merkletree.SHA256NodeHash, HashContract{SortPairs: true})
+}
+
+func TestBuiltinLeafHashes(t *testing.T) {
+	strs := []string{"", "a", "0x00", strings.Repeat("z", 1024), "héllo"}
+	CheckLeafHash(t, merkletree.StandardLeafHash[string], strs...)
+	CheckLeafHash(t, merkletree.OpenZeppelinLeafHash[string], strs...)
+	CheckLeafHash(t, merkletree.StandardLeafHash[uint64], 1, 255, 1<<63, ^uint64(0))
+	CheckLeafHash(t, merkletree.StandardLeafHash[[]byte], []byte{0}, []byte{0, 0}, make([]byte, 32))
+
+	// FormatLeaf only takes 32-byte hashes; its zero value is rejected below
+	var r recorder
+	CheckLeafHash(&r, merkletree.FormatLeaf, merkletree.BytesLike(fmt.Sprintf("0x%064x", 1)), merkletree.BytesLike(make([]byte, 32)))
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "LeafHash(<nil>)") {
+		t.Errorf("Expected only the nil value to fail, got %q", r.errors)
+	}
+}
+
+func TestCheckNodeHashReports(t *testing.T) {
+	tests := []struct {
+		name     string
+		hash     merkletree.NodeHash
+		contract HashContract
+		want     string
+	}{
+		{
+			"unsorted hash claimed sorted",
+			func(a, b merkletree.BytesLike) merkletree.HexString {
+				return merkletree.StandardNodeHash(merkletree.StandardNodeHash(a, a), b)
+			},
+			HashContract{SortPairs: true},
+			"SortPairs requires them equal",
+		},
+		{
+			"sorted hash claimed unsorted",
+			merkletree.StandardNodeHash,
+			HashContract{},
+			"without SortPairs they must differ",
+		},
+		{
+			"short output",
+			func(a, b merkletree.BytesLike) merkletree.HexString { return "0x1234" },
+			HashContract{SortPairs: true},
+			"not a 32-byte hash",
+		},
+		{
+			"panics on empty children",
+			func(a, b merkletree.BytesLike) merkletree.HexString {
+				bytes, _ := merkletree.ToBytes(a)
+				_ = bytes[31]
+				return merkletree.StandardNodeHash(a, b)
+			},
+			HashContract{SortPairs: true},
+			"panicked",
+		},
+		{
+			"depends on representation",
+			func(a, b merkletree.BytesLike) merkletree.HexString {
+				if _, ok := a.([]byte); ok {
+					return merkletree.SHA256NodeHash(a, b)
+				}
+				return merkletree.StandardNodeHash(a, b)
+			},
+			HashContract{SortPairs: true},
+			"for []byte children",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r recorder
+			CheckNodeHash(&r, tt.hash, tt.contract)
+			for _, msg := range r.errors {
+				if strings.Contains(msg, tt.want) {
+					return
+				}
+			}
+			t.Errorf("Expected a report containing %q, got %q", tt.want, r.errors)
+		})
+	}
+}
+
+func TestCheckLeafHashReports(t *testing.T) {
+	calls := 0
+	nondeterministic := func(s string) merkletree.HexString {
+		calls++
+		return merkletree.StandardLeafHash(fmt.Sprint(s, calls))
+	}
+	constant := func(s string) merkletree.HexString { return merkletree.StandardLeafHash("same") }
+
+	var r recorder
+	CheckLeafHash(&r, nondeterministic, "a")
+	if len(r.errors) == 0 || !strings.Contains(r.errors[0], "not deterministic") {
+		t.Errorf("Expected a determinism report, got %q", r.errors)
+	}
+
+	r = recorder{}
+	CheckLeafHash(&r, constant, "a", "b")
+	if len(r.errors) != 2 || !strings.Contains(r.errors[0], `the hash of ""`) {
+		t.Errorf("Expected two collision reports, got %q", r.errors)
+	}
+}
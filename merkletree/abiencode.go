@@ -0,0 +1,386 @@
+package merkletree
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// abiType is a parsed Solidity type of a leaf encoding.
+type abiType struct {
+	name   string   // Canonical name, such as "uint256" or "bytes32[2][]"
+	kind   string   // address, bool, uint, int, bytesN, bytes, string or array
+	size   int      // Bits of uint and int, bytes of bytesN
+	elem   *abiType // Element type of an array
+	length int      // Length of a fixed-size array, or -1 for T[]
+}
+
+// ABIEncode returns abi.encode of values with the Solidity types of
+// leafEncoding, such as []string{"address", "uint256"}, as OpenZeppelin's
+// StandardMerkleTree encodes a value before hashing it.
+//
+// Supported types are address, bool, uintN, intN, bytesN, bytes, string and
+// arrays of them, T[] and T[k]. Addresses are Address values, byte slices or
+// 0x-prefixed hex strings. Integers are Go integers, U256, *big.Int,
+// json.Number, exact float64 values, or decimal or 0x-prefixed hex strings.
+// bytes and bytesN are byte slices or arrays, HexString values or
+// 0x-prefixed hex strings, and bytesN must be exactly N bytes long. Arrays
+// are Go slices or arrays.
+//
+// An unsupported type returns an error wrapping ErrInvalidLeafEncoding, and a
+// value that does not fit its type one wrapping ErrInvalidValue.
+func ABIEncode(leafEncoding []string, values []any) ([]byte, error) {
+	types, err := parseLeafEncoding(leafEncoding)
+	if err != nil {
+		return nil, err
+	}
+	return encodeLeaf(types, values)
+}
+
+// parseLeafEncoding parses the types of a leaf encoding.
+func parseLeafEncoding(leafEncoding []string) ([]*abiType, error) {
+	if len(leafEncoding) == 0 {
+		return nil, fmt.Errorf("%w: no types", ErrInvalidLeafEncoding)
+	}
+	types := make([]*abiType, len(leafEncoding))
+	for i, name := range leafEncoding {
+		t, err := parseABIType(strings.TrimSpace(name))
+		if err != nil {
+			return nil, fmt.Errorf("%w: type %d: %v", ErrInvalidLeafEncoding, i, err)
+		}
+		types[i] = t
+	}
+	return types, nil
+}
+
+// parseABIType parses one Solidity type, expanding the uint and int aliases.
+func parseABIType(name string) (*abiType, error) {
+	if strings.HasSuffix(name, "]") {
+		open := strings.LastIndexByte(name, '[')
+		if open <= 0 {
+			return nil, fmt.Errorf("malformed array type %q", name)
+		}
+		elem, err := parseABIType(name[:open])
+		if err != nil {
+			return nil, err
+		}
+		length := -1
+		if inner := name[open+1 : len(name)-1]; inner != "" {
+			if length, err = strconv.Atoi(inner); err != nil || length < 1 {
+				return nil, fmt.Errorf("invalid array length in %q", name)
+			}
+		}
+		suffix := "[]"
+		if length >= 0 {
+			suffix = "[" + strconv.Itoa(length) + "]"
+		}
+		return &abiType{name: elem.name + suffix, kind: "array", elem: elem, length: length}, nil
+	}
+
+	switch {
+	case name == "address" || name == "bool" || name == "bytes" || name == "string":
+		return &abiType{name: name, kind: name}, nil
+	case name == "uint" || name == "int":
+		return &abiType{name: name + "256", kind: name, size: 256}, nil
+	case strings.HasPrefix(name, "uint"), strings.HasPrefix(name, "int"):
+		kind := strings.TrimRight(name, "0123456789")
+		if bits, err := strconv.Atoi(name[len(kind):]); err == nil && bits%8 == 0 && bits >= 8 && bits <= 256 && (kind == "uint" || kind == "int") {
+			return &abiType{name: name, kind: kind, size: bits}, nil
+		}
+	case strings.HasPrefix(name, "bytes"):
+		if size, err := strconv.Atoi(name[len("bytes"):]); err == nil && size >= 1 && size <= 32 {
+			return &abiType{name: name, kind: "bytesN", size: size}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported type %q", name)
+}
+
+// dynamic reports whether the type is encoded after the head, behind an offset.
+func (t *abiType) dynamic() bool {
+	switch t.kind {
+	case "bytes", "string":
+		return true
+	case "array":
+		return t.length < 0 || t.elem.dynamic()
+	}
+	return false
+}
+
+// headSize returns the size of the type's slot in the head of a tuple.
+func (t *abiType) headSize() int {
+	if t.kind == "array" && !t.dynamic() {
+		return t.length * t.elem.headSize()
+	}
+	return abiWordSize
+}
+
+// encodeLeaf returns abi.encode of values with the given types, with an
+// error wrapping ErrInvalidValue naming the field that does not fit.
+func encodeLeaf(types []*abiType, values []any) ([]byte, error) {
+	if len(values) != len(types) {
+		return nil, fmt.Errorf("%w: %d fields for a leaf encoding of %d types", ErrInvalidValue, len(values), len(types))
+	}
+	encoded, err := encodeTuple(types, values, "field")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidValue, err)
+	}
+	return encoded, nil
+}
+
+// encodeTuple encodes values as an ABI tuple: the heads of all values, then
+// the dynamic ones, each behind an offset from the start of the tuple. what
+// names the values in errors.
+func encodeTuple(types []*abiType, values []any, what string) ([]byte, error) {
+	offset := 0
+	for _, t := range types {
+		offset += t.headSize()
+	}
+	var head, tail []byte
+	for i, t := range types {
+		encoded, err := t.encode(values[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s %d (%s): %v", what, i, t.name, err)
+		}
+		if t.dynamic() {
+			head = append(head, uintWord(uint64(offset+len(tail)))...)
+			tail = append(tail, encoded...)
+		} else {
+			head = append(head, encoded...)
+		}
+	}
+	return append(head, tail...), nil
+}
+
+// encode returns the encoding of a value of the type: its head slot for a
+// static type, or what its offset points to for a dynamic one.
+func (t *abiType) encode(value any) ([]byte, error) {
+	switch t.kind {
+	case "address":
+		address, err := abiAddress(value)
+		if err != nil {
+			return nil, err
+		}
+		return leftPad(address[:]), nil
+	case "bool":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("got %T, want bool", value)
+		}
+		if b {
+			return uintWord(1), nil
+		}
+		return uintWord(0), nil
+	case "uint", "int":
+		return t.encodeInteger(value)
+	case "bytesN":
+		data, err := abiBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) != t.size {
+			return nil, fmt.Errorf("got %d bytes, want %d", len(data), t.size)
+		}
+		return rightPad(data), nil
+	case "bytes", "string":
+		var data []byte
+		if s, ok := value.(string); ok && t.kind == "string" {
+			data = []byte(s)
+		} else if t.kind == "string" {
+			return nil, fmt.Errorf("got %T, want string", value)
+		} else {
+			var err error
+			if data, err = abiBytes(value); err != nil {
+				return nil, err
+			}
+		}
+		return append(uintWord(uint64(len(data))), rightPad(data)...), nil
+	default: // array
+		return t.encodeArray(value)
+	}
+}
+
+// encodeInteger encodes a uintN or intN value, two's complement if negative.
+func (t *abiType) encodeInteger(value any) ([]byte, error) {
+	n, err := abiInteger(value)
+	if err != nil {
+		return nil, err
+	}
+	min, max := new(big.Int), new(big.Int).Lsh(big.NewInt(1), uint(t.size))
+	if t.kind == "int" {
+		max.Rsh(max, 1)
+		min.Neg(max)
+	}
+	if n.Cmp(min) < 0 || n.Cmp(max) >= 0 {
+		return nil, fmt.Errorf("%s overflows %s", n, t.name)
+	}
+	if n.Sign() < 0 {
+		n = new(big.Int).Add(n, new(big.Int).Lsh(big.NewInt(1), 256))
+	}
+	return n.FillBytes(make([]byte, abiWordSize)), nil
+}
+
+// encodeArray encodes a slice or array value, prefixed with its length for T[].
+func (t *abiType) encodeArray(value any) ([]byte, error) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("got %T, want a slice or array", value)
+	}
+	if t.length >= 0 && rv.Len() != t.length {
+		return nil, fmt.Errorf("got %d elements, want %d", rv.Len(), t.length)
+	}
+	types := make([]*abiType, rv.Len())
+	elems := make([]any, rv.Len())
+	for i := range elems {
+		types[i] = t.elem
+		elems[i] = rv.Index(i).Interface()
+	}
+	encoded, err := encodeTuple(types, elems, "element")
+	if err != nil {
+		return nil, err
+	}
+	if t.length < 0 {
+		return append(uintWord(uint64(rv.Len())), encoded...), nil
+	}
+	return encoded, nil
+}
+
+// jsonFields returns value, which fits types, with its integers as decimal
+// strings and its addresses and bytes as 0x-prefixed hex, as OpenZeppelin's
+// dumps hold them, so it survives a round trip through JSON.
+func jsonFields(types []*abiType, value []any) []any {
+	fields := make([]any, len(value))
+	for i, field := range value {
+		fields[i] = types[i].jsonValue(field)
+	}
+	return fields
+}
+
+// jsonValue returns a field of the type as jsonFields does, or the field
+// unchanged if it does not fit the type.
+func (t *abiType) jsonValue(value any) any {
+	switch t.kind {
+	case "uint", "int":
+		if n, err := abiInteger(value); err == nil {
+			return n.String()
+		}
+	case "address":
+		if address, err := abiAddress(value); err == nil {
+			return "0x" + hex.EncodeToString(address[:])
+		}
+	case "bytesN", "bytes":
+		if data, err := abiBytes(value); err == nil {
+			return "0x" + hex.EncodeToString(data)
+		}
+	case "array":
+		if rv := reflect.ValueOf(value); rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+			elems := make([]any, rv.Len())
+			for i := range elems {
+				elems[i] = t.elem.jsonValue(rv.Index(i).Interface())
+			}
+			return elems
+		}
+	}
+	return value
+}
+
+// abiAddress converts an address field to its 20 bytes.
+func abiAddress(value any) ([20]byte, error) {
+	if address, ok := addressOf(value); ok {
+		return address, nil
+	}
+	switch v := value.(type) {
+	case string, HexString, []byte:
+		data, err := abiBytes(v)
+		if err != nil {
+			return [20]byte{}, err
+		}
+		if len(data) != 20 {
+			return [20]byte{}, fmt.Errorf("address is %d bytes, want 20", len(data))
+		}
+		return [20]byte(data), nil
+	}
+	return [20]byte{}, fmt.Errorf("got %T, want an address", value)
+}
+
+// abiBytes converts a bytes or bytesN field to its bytes. Strings must be
+// 0x-prefixed hex, as in OpenZeppelin's dumps.
+func abiBytes(value any) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		if !strings.HasPrefix(v, "0x") {
+			return nil, fmt.Errorf("%s is not 0x-prefixed hex", previewValue(v))
+		}
+		return ToBytes(v)
+	case HexString:
+		return ToBytes(v)
+	}
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Array && rv.Type().Elem().Kind() == reflect.Uint8 {
+		return byteArray(rv), nil
+	}
+	return nil, fmt.Errorf("got %T, want bytes", value)
+}
+
+// abiInteger converts an integer field to a big.Int.
+func abiInteger(value any) (*big.Int, error) {
+	switch v := value.(type) {
+	case *big.Int:
+		if v == nil {
+			return nil, fmt.Errorf("nil *big.Int")
+		}
+		return v, nil
+	case big.Int:
+		return &v, nil
+	case U256:
+		return v.Big(), nil
+	case json.Number:
+		return abiInteger(string(v))
+	case string:
+		digits, base := v, 10
+		if rest, ok := strings.CutPrefix(v, "0x"); ok {
+			digits, base = rest, 16
+		}
+		n, ok := new(big.Int).SetString(digits, base)
+		if !ok || digits == "" || strings.ContainsAny(digits, "+_") {
+			return nil, fmt.Errorf("%s is not an integer", previewValue(v))
+		}
+		return n, nil
+	case float64:
+		if v != math.Trunc(v) || math.Abs(v) > 1<<53 {
+			return nil, fmt.Errorf("%v is not an exact integer; use a decimal string", v)
+		}
+		return big.NewInt(int64(v)), nil
+	}
+	switch rv := reflect.ValueOf(value); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(rv.Uint()), nil
+	}
+	return nil, fmt.Errorf("got %T, want an integer", value)
+}
+
+// uintWord returns n as a 32-byte big-endian word.
+func uintWord(n uint64) []byte {
+	return new(big.Int).SetUint64(n).FillBytes(make([]byte, abiWordSize))
+}
+
+// leftPad pads data, at most one word long, with zeros on the left to a word.
+func leftPad(data []byte) []byte {
+	word := make([]byte, abiWordSize)
+	copy(word[abiWordSize-len(data):], data)
+	return word
+}
+
+// rightPad pads data with zeros on the right to a whole number of words.
+func rightPad(data []byte) []byte {
+	padded := make([]byte, (len(data)+abiWordSize-1)/abiWordSize*abiWordSize)
+	copy(padded, data)
+	return padded
+}
@@ -0,0 +1,134 @@
+package merkletree
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// abiWords joins 32-byte words written as hex, without 0x, into an encoding.
+func abiWords(t *testing.T, words ...string) []byte {
+	t.Helper()
+	var encoded []byte
+	for _, word := range words {
+		b, err := hex.DecodeString(strings.Repeat("0", 64-len(word)) + word)
+		if err != nil || len(b) != abiWordSize {
+			t.Fatalf("Bad test word %q", word)
+		}
+		encoded = append(encoded, b...)
+	}
+	return encoded
+}
+
+func TestABIEncodeSpecExamples(t *testing.T) {
+	// The examples of the Solidity ABI specification, without the selector.
+	// Text words are left-aligned, so they are written out in full.
+	tests := []struct {
+		name  string
+		types []string
+		value []any
+		want  []string
+	}{
+		{
+			name:  "f(uint,uint32[],bytes10,bytes)",
+			types: []string{"uint", "uint32[]", "bytes10", "bytes"},
+			value: []any{0x123, []uint32{0x456, 0x789}, []byte("1234567890"), []byte("Hello, world!")},
+			want: []string{
+				"123", "80",
+				"3132333435363738393000000000000000000000000000000000000000000000",
+				"e0", "2", "456", "789", "d",
+				"48656c6c6f2c20776f726c642100000000000000000000000000000000000000",
+			},
+		},
+		{
+			name:  "g(uint256[][],string[])",
+			types: []string{"uint256[][]", "string[]"},
+			value: []any{[][]int{{1, 2}, {3}}, []string{"one", "two", "three"}},
+			want: []string{
+				"40", "140",
+				"2", "40", "a0", "2", "1", "2", "1", "3",
+				"3", "60", "a0", "e0",
+				"3", "6f6e650000000000000000000000000000000000000000000000000000000000",
+				"3", "74776f0000000000000000000000000000000000000000000000000000000000",
+				"5", "7468726565000000000000000000000000000000000000000000000000000000",
+			},
+		},
+		{
+			name:  "static array and negative int",
+			types: []string{"uint256[2]", "int8", "bool"},
+			value: []any{[2]string{"1", "0x02"}, -1, true},
+			want:  []string{"1", "2", strings.Repeat("f", 64), "1"},
+		},
+	}
+	for _, tt := range tests {
+		got, err := ABIEncode(tt.types, tt.value)
+		if err != nil {
+			t.Fatalf("%s: ABIEncode failed: %v", tt.name, err)
+		}
+		if want := abiWords(t, tt.want...); hex.EncodeToString(got) != hex.EncodeToString(want) {
+			t.Errorf("%s:\ngot  %x\nwant %x", tt.name, got, want)
+		}
+	}
+}
+
+func TestABIEncodeValueForms(t *testing.T) {
+	// Every accepted form of the same address and amount encodes alike
+	want := abiAddressAmount(0x11, "5000000000000000000000")
+	amount, _ := new(big.Int).SetString("5000000000000000000000", 10)
+	var address Address
+	for i := range address {
+		address[i] = 0x11
+	}
+	forms := [][]any{
+		{"0x1111111111111111111111111111111111111111", "5000000000000000000000"},
+		{HexString("0x1111111111111111111111111111111111111111"), json.Number("5000000000000000000000")},
+		{address, amount},
+		{[20]byte(address), *amount},
+		{address[:], "0x10f0cf064dd59200000"},
+	}
+	for i, value := range forms {
+		got, err := ABIEncode([]string{"address", "uint256"}, value)
+		if err != nil {
+			t.Fatalf("Form %d: ABIEncode failed: %v", i, err)
+		}
+		if hex.EncodeToString(got) != hex.EncodeToString(want) {
+			t.Errorf("Form %d: got %x, want %x", i, got, want)
+		}
+	}
+}
+
+func TestABIEncodeErrors(t *testing.T) {
+	for _, types := range [][]string{nil, {"uint7"}, {"bytes33"}, {"int512"}, {"(address,uint256)"}, {"uint256[0]"}, {"[]"}, {"fixed128x18"}} {
+		if _, err := ABIEncode(types, []any{1}); !errors.Is(err, ErrInvalidLeafEncoding) {
+			t.Errorf("ABIEncode(%q) returned %v, want ErrInvalidLeafEncoding", types, err)
+		}
+	}
+
+	tests := []struct {
+		types []string
+		value []any
+	}{
+		{[]string{"uint256"}, []any{}},
+		{[]string{"uint8"}, []any{256}},
+		{[]string{"uint256"}, []any{-1}},
+		{[]string{"int8"}, []any{-129}},
+		{[]string{"uint256"}, []any{"12abc"}},
+		{[]string{"uint256"}, []any{1.5}},
+		{[]string{"uint256"}, []any{1e20}},
+		{[]string{"address"}, []any{"0x1234"}},
+		{[]string{"address"}, []any{"1111111111111111111111111111111111111111"}},
+		{[]string{"bytes4"}, []any{"0x1234"}},
+		{[]string{"bool"}, []any{"true"}},
+		{[]string{"string"}, []any{[]byte("text")}},
+		{[]string{"uint256[2]"}, []any{[]int{1}}},
+		{[]string{"uint256[]"}, []any{1}},
+	}
+	for _, tt := range tests {
+		if _, err := ABIEncode(tt.types, tt.value); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("ABIEncode(%q, %v) returned %v, want ErrInvalidValue", tt.types, tt.value, err)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package merkletree
+
+import (
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// Leaf orders recorded in an AlgorithmDescriptor.
+const (
+	LeafOrderInsertion  = "insertion"  // Leaves kept in input order
+	LeafOrderAscending  = "ascending"  // Leaves sorted by hash, smallest first
+	LeafOrderDescending = "descending" // Leaves sorted by hash, largest first
+	LeafOrderReversed   = "reversed"   // Leaves in input order, last first
+	LeafOrderShuffled   = "shuffled"   // Leaves sorted by keccak256(seed || leaf hash); see ShuffleSeed
+)
+
+// Hash names recorded in an AlgorithmDescriptor.
+const (
+	HashKeccak256Packed  = "keccak256-packed"   // StandardLeafHash
+	HashKeccak256Double  = "keccak256-double"   // OpenZeppelinLeafHash
+	HashKeccak256Bytes   = "keccak256-bytes"    // BytesLeafHash
+	HashKeccak256ABI     = "keccak256-abi"      // EncodedLeafHash
+	HashSHA256Bytes      = "sha256-bytes"       // SHA256LeafHash
+	HashBlake2b256Bytes  = "blake2b-256-bytes"  // Blake2b256LeafHash
+	HashKeccak256Sorted  = "keccak256-sorted"   // StandardNodeHash
+	HashSHA256Sorted     = "sha256-sorted"      // SHA256NodeHash
+	HashBlake2b256Sorted = "blake2b-256-sorted" // Blake2b256NodeHash
+	HashCustom           = "custom"             // A caller-supplied function
+)
+
+// Hash algorithm names accepted by SimpleMerkleTreeOptions.HashAlgorithm and
+// recorded in the hashAlgorithm and leafHashAlgorithm fields of a simple tree dump.
+const (
+	HashAlgorithmKeccak256           = "keccak256"
+	HashAlgorithmSHA256              = "sha256"
+	HashAlgorithmBlake2b256          = "blake2b-256"
+	HashAlgorithmKeccak256Positional = "keccak256-positional" // PositionalNodeHash
+)
+
+// namedNodeHash is a node hash that can be selected by name.
+type namedNodeHash struct {
+	hash       NodeHash
+	descriptor string // Name recorded in AlgorithmDescriptor.NodeHash
+}
+
+// namedNodeHashes maps hash algorithm names to their node hashes. It is
+// guarded by nodeHashesMu once RegisterNodeHash may run.
+var (
+	nodeHashesMu    sync.RWMutex
+	namedNodeHashes = map[string]namedNodeHash{
+		HashAlgorithmKeccak256:           {StandardNodeHash, HashKeccak256Sorted},
+		HashAlgorithmSHA256:              {SHA256NodeHash, HashSHA256Sorted},
+		HashAlgorithmBlake2b256:          {Blake2b256NodeHash, HashBlake2b256Sorted},
+		HashAlgorithmKeccak256Positional: {PositionalNodeHash, HashKeccak256Ordered},
+	}
+)
+
+// RegisterNodeHash makes fn selectable by name, like the built-in
+// "keccak256" and "sha256": SimpleMerkleTreeOptions.HashAlgorithm accepts
+// the name, dumps of trees built with it record the name instead of
+// "custom", and LoadSimpleMerkleTree resolves the name to fn, so it must be
+// registered in the loading program too. The name is also recorded as the
+// node hash of the tree's AlgorithmDescriptor.
+//
+// A tree built with NodeHash set to a registered function records its name
+// as well. Functions are told apart by their code, so closures created by
+// the same function literal are not; select those by name instead.
+//
+// It returns an *OptionError if name is empty, "custom" or already
+// registered, or if fn is nil.
+func RegisterNodeHash(name string, fn NodeHash) error {
+	if fn == nil {
+		return &OptionError{Option: "fn", Value: nil, Reason: "node hash must not be nil"}
+	}
+	if name == "" || name == HashCustom {
+		return &OptionError{Option: "name", Value: name, Reason: "is reserved"}
+	}
+	nodeHashesMu.Lock()
+	defer nodeHashesMu.Unlock()
+	if _, ok := namedNodeHashes[name]; ok {
+		return &OptionError{Option: "name", Value: name, Reason: "is already registered"}
+	}
+	namedNodeHashes[name] = namedNodeHash{hash: fn, descriptor: name}
+	return nil
+}
+
+// lookupNodeHash returns the node hash registered under name.
+func lookupNodeHash(name string) (namedNodeHash, bool) {
+	nodeHashesMu.RLock()
+	defer nodeHashesMu.RUnlock()
+	named, ok := namedNodeHashes[name]
+	return named, ok
+}
+
+// HashAlgorithms returns the names SimpleMerkleTreeOptions.HashAlgorithm
+// accepts, the built-in ones and those added with RegisterNodeHash, sorted.
+func HashAlgorithms() []string {
+	nodeHashesMu.RLock()
+	defer nodeHashesMu.RUnlock()
+	names := make([]string, 0, len(namedNodeHashes))
+	for name := range namedNodeHashes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// nodeHashName returns the name fn is registered under, if it is registered
+// under exactly one.
+func nodeHashName(fn NodeHash) (string, bool) {
+	code := reflect.ValueOf(fn).Pointer()
+	nodeHashesMu.RLock()
+	defer nodeHashesMu.RUnlock()
+	found := ""
+	for name, named := range namedNodeHashes {
+		if reflect.ValueOf(named.hash).Pointer() != code {
+			continue
+		}
+		if found != "" {
+			return "", false
+		}
+		found = name
+	}
+	return found, found != ""
+}
+
+// nodeHashByDescriptor returns the name and node hash whose descriptor is descriptor.
+func nodeHashByDescriptor(descriptor string) (string, NodeHash, bool) {
+	nodeHashesMu.RLock()
+	defer nodeHashesMu.RUnlock()
+	for name, named := range namedNodeHashes {
+		if named.descriptor == descriptor {
+			return name, named.hash, true
+		}
+	}
+	return "", nil, false
+}
+
+// AlgorithmDescriptor names every choice that determines a tree's root,
+// so a consumer of a dump knows how to rebuild or verify it.
+type AlgorithmDescriptor struct {
+	LeafHash  string `json:"leafHash"`  // How values are hashed into leaves
+	NodeHash  string `json:"nodeHash"`  // How two children are hashed into a parent
+	LeafOrder string `json:"leafOrder"` // Order of the leaves in the bottom level
+}
+
+// Algorithm returns the descriptor of the algorithm the tree was built with.
+func (m *MerkleTreeImpl[T]) Algorithm() AlgorithmDescriptor {
+	return m.algorithm
+}
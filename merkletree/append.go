@@ -0,0 +1,158 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// appendLeaf is a leaf hash with the index of its value, as laid out by AppendLeaves.
+type appendLeaf struct {
+	hash       HexString
+	valueIndex int
+}
+
+// AppendLeaves adds values to the tree, giving the same tree as building it
+// again over all of its values, without hashing the values already in it.
+// The new values get the next value indices. Unsorted trees put their leaves
+// after the existing ones, or before them for LeafOrderReversed; sorted trees
+// insert them in hash order, and shuffled trees in the order of their seed.
+// Every leaf moves in the flat layout, so the internal nodes are all hashed
+// again, with one goroutine.
+//
+// The root changes: proofs and claims issued before the append no longer
+// verify against the tree. Prefix search, duplicate detection and the hash
+// lookup are rebuilt to cover the new values, which carry no metadata.
+// Pre-generated proofs are generated again, or dropped once the tree grows
+// past PregenerateMaxLeaves.
+//
+// Values that do not hash to a 32-byte leaf fail with *InputError entries
+// indexed by their would-be value index, and the tree is left unchanged.
+// A tree whose values were dropped fails with ErrValuesDropped, and a
+// shuffled tree whose seed was withheld with ErrSeedWithheld.
+func (m *MerkleTreeImpl[T]) AppendLeaves(values []T) error {
+	if len(values) == 0 {
+		return nil
+	}
+	if m.valuesDropped {
+		return fmt.Errorf("%w: cannot append to the tree", ErrValuesDropped)
+	}
+	if m.algorithm.LeafOrder == LeafOrderShuffled && m.shuffleSeed == nil {
+		return fmt.Errorf("%w: cannot place new leaves in the shuffled order", ErrSeedWithheld)
+	}
+
+	if err := m.checkLeafHash(); err != nil {
+		return err
+	}
+
+	n := len(m.Values)
+	var errs []error
+	added := make([]appendLeaf, len(values))
+	leafHashes, err := hashLeaves(values, m.LeafHash, 1, nil)
+	if err != nil {
+		return err
+	}
+	for i, hash := range leafHashes {
+		if err := checkValueSize(n+i, values[i], m.maxValueBytes); err != nil {
+			errs = append(errs, err)
+		} else if err := leafHashError(values[i], hash); err != nil {
+			errs = append(errs, &InputError{Index: n + i, Err: err})
+		}
+		added[i] = appendLeaf{hash: hash, valueIndex: n + i}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	// The existing leaves in layout order, their hashes reused as they are
+	leafStart := len(m.Tree) - n
+	existing := make([]appendLeaf, n)
+	for i, v := range m.Values {
+		existing[v.TreeIndex-leafStart] = appendLeaf{hash: m.Tree[v.TreeIndex], valueIndex: i}
+	}
+	layout := m.appendLayout(existing, added)
+
+	hashes := make([]BytesLike, len(layout))
+	for i, leaf := range layout {
+		hashes[i] = leaf.hash
+	}
+	tree, err := makeMerkleTree(hashes, m.NodeHash, 1, nil)
+	if err != nil {
+		return err
+	}
+
+	m.Tree = tree
+	for _, v := range values {
+		m.Values = append(m.Values, struct {
+			Value     T
+			TreeIndex int
+		}{Value: v})
+	}
+	leafStart = len(tree) - len(layout)
+	for i, leaf := range layout {
+		m.Values[leaf.valueIndex].TreeIndex = leafStart + i
+	}
+	if m.metadata != nil {
+		m.metadata = append(m.metadata, make([]json.RawMessage, len(values))...)
+	}
+	m.invalidateIndexes()
+	if m.proofs != nil {
+		m.pregenerate()
+	}
+	return nil
+}
+
+// appendLayout places the added leaves among the existing ones the way
+// building the tree from scratch would, given that the existing leaves have
+// the lower value indices.
+func (m *MerkleTreeImpl[T]) appendLayout(existing, added []appendLeaf) []appendLeaf {
+	switch m.algorithm.LeafOrder {
+	case LeafOrderAscending, LeafOrderDescending:
+	case LeafOrderShuffled:
+		// Existing leaves have the lower value indices, so the stable sort
+		// puts them before equal added ones as building would
+		layout := append(existing, added...)
+		sortByShuffleKey(m.shuffleSeed, layout, func(l appendLeaf) []byte { return hexLeafBytes(l.hash) })
+		return layout
+	case LeafOrderReversed:
+		slices.Reverse(added)
+		return append(added, existing...)
+	default:
+		return append(existing, added...)
+	}
+
+	// Stable sorting keeps equal leaves in input order, so an existing leaf
+	// comes before an equal added one; CompatLatest sorts ascending and
+	// reverses, which puts the added one first
+	descending := m.algorithm.LeafOrder == LeafOrderDescending
+	addedFirst := m.compatibility == CompatLatest
+	compare := func(a, b appendLeaf) int {
+		result, _ := Compare(a.hash, b.hash)
+		if descending {
+			return -result
+		}
+		return result
+	}
+	slices.SortStableFunc(added, func(a, b appendLeaf) int {
+		if c := compare(a, b); c != 0 || !addedFirst {
+			return c
+		}
+		return b.valueIndex - a.valueIndex // Equal added leaves go last input first too
+	})
+
+	layout := make([]appendLeaf, 0, len(existing)+len(added))
+	i, j := 0, 0
+	for i < len(existing) && j < len(added) {
+		c := compare(existing[i], added[j])
+		if c < 0 || (c == 0 && !addedFirst) {
+			layout = append(layout, existing[i])
+			i++
+		} else {
+			layout = append(layout, added[j])
+			j++
+		}
+	}
+	layout = append(layout, existing[i:]...)
+	return append(layout, added[j:]...)
+}
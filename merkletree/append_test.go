@@ -0,0 +1,159 @@
+package merkletree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// appendValues returns n values with some repeated, to exercise equal leaves.
+func appendValues(n int) []string {
+	values := make([]string, n)
+	for i := range values {
+		values[i] = fmt.Sprintf("value-%d", i%(n-n/5))
+	}
+	return values
+}
+
+func TestAppendLeavesMatchesRebuild(t *testing.T) {
+	configs := map[string]MerkleTreeOptions{
+		"insertion":       {SortLeaves: Bool(false), Compatibility: CompatV0},
+		"ascending":       {SortLeaves: Bool(true), Compatibility: CompatV0},
+		"descending":      {SortLeaves: Bool(true), SortDescending: true, Compatibility: CompatV0},
+		"latest":          {Compatibility: CompatLatest},
+		"latest reversed": {Compatibility: CompatLatest, PreserveOrder: true},
+		"prefix index":    {SortLeaves: Bool(true), Compatibility: CompatV0, PrefixIndex: true},
+	}
+	values := appendValues(40)
+
+	for name, options := range configs {
+		for _, split := range []int{1, 7, 20, 39} {
+			t.Run(fmt.Sprintf("%s/%d", name, split), func(t *testing.T) {
+				want, err := NewStandardMerkleTree(values, options)
+				if err != nil {
+					t.Fatalf("Failed to create tree: %v", err)
+				}
+				got, err := NewStandardMerkleTree(values[:split], options)
+				if err != nil {
+					t.Fatalf("Failed to create tree: %v", err)
+				}
+				// Append in two steps to cover appending to an appended tree
+				mid := split + (len(values)-split)/2
+				if err := got.AppendLeaves(values[split:mid]); err != nil {
+					t.Fatalf("AppendLeaves failed: %v", err)
+				}
+				if err := got.AppendLeaves(values[mid:]); err != nil {
+					t.Fatalf("AppendLeaves failed: %v", err)
+				}
+
+				if !reflect.DeepEqual(got.Tree, want.Tree) {
+					t.Fatalf("Root after append %s, rebuilt %s", got.Root(), want.Root())
+				}
+				if !reflect.DeepEqual(got.Values, want.Values) {
+					t.Errorf("Values differ from the rebuilt tree")
+				}
+				if got.WarmIndexes(context.Background()) != nil || want.WarmIndexes(context.Background()) != nil {
+					t.Fatalf("WarmIndexes failed")
+				}
+				if !reflect.DeepEqual(got.HashLookup, want.HashLookup) || !reflect.DeepEqual(got.duplicates, want.duplicates) {
+					t.Errorf("Hash lookup differs from the rebuilt tree")
+				}
+				if !reflect.DeepEqual(got.prefixIndex, want.prefixIndex) {
+					t.Errorf("Prefix index differs from the rebuilt tree")
+				}
+				if err := got.Validate(); err != nil {
+					t.Errorf("Validate failed after append: %v", err)
+				}
+				for i := range values {
+					proof, err := got.GetProof(i)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if ok, err := got.Verify(i, proof); err != nil || !ok {
+						t.Errorf("Proof of value %d does not verify: %v", i, err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestAppendLeavesOldProofs(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"a", "b", "c"}, MerkleTreeOptions{Compatibility: CompatV0})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	claim, err := tree.ExportClaim(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.AppendLeaves([]string{"d"}); err != nil {
+		t.Fatal(err)
+	}
+	if tree.Root() == claim.Root {
+		t.Fatal("Appending should change the root")
+	}
+	if ok, _ := tree.Verify(0, claim.Proof); ok {
+		t.Error("A proof issued before the append should not verify against the new root")
+	}
+}
+
+func TestAppendLeavesErrors(t *testing.T) {
+	tree, err := NewSimpleMerkleTree([]BytesLike{"0x01", "0x02"}, SimpleMerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	root := tree.Root()
+
+	err = tree.AppendLeaves([]BytesLike{"0x03", 3.5, "0x04"})
+	var inputErr *InputError
+	if !errors.Is(err, ErrInvalidValue) || !errors.As(err, &inputErr) || inputErr.Index != 3 {
+		t.Errorf("Expected an InputError at value index 3, got %v", err)
+	}
+	if tree.Root() != root || len(tree.Values) != 2 {
+		t.Error("A failed append should leave the tree unchanged")
+	}
+
+	dropped, err := NewStandardMerkleTree([]string{"a", "b"}, MerkleTreeOptions{DropValuesAfterBuild: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dropped.AppendLeaves([]string{"c"}); !errors.Is(err, ErrValuesDropped) {
+		t.Errorf("Expected ErrValuesDropped, got %v", err)
+	}
+}
+
+// BenchmarkAppendLeaves appends 1,000 values of 4 KiB to a tree of 20,000,
+// against building the whole tree again.
+func BenchmarkAppendLeaves(b *testing.B) {
+	values := make([]string, 21_000)
+	for i := range values {
+		values[i] = fmt.Sprintf("%d:%s", i, strings.Repeat("x", 4096))
+	}
+	base, added := values[:20_000], values[20_000:]
+	options := MerkleTreeOptions{SortLeaves: Bool(false), Compatibility: CompatV0}
+
+	b.Run("rebuild", func(b *testing.B) {
+		for range b.N {
+			if _, err := NewStandardMerkleTree(values, options); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("append", func(b *testing.B) {
+		for range b.N {
+			b.StopTimer()
+			tree, err := NewStandardMerkleTree(base, options)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.StartTimer()
+			if err := tree.AppendLeaves(added); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package merkletree
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/smeneguz/GoMerkle/internal/keccak"
+)
+
+// useBackend switches the Keccak backend for the rest of the test.
+func useBackend(tb testing.TB, name string) {
+	tb.Helper()
+	restore, err := keccak.Use(name)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(restore)
+}
+
+// backendValues returns n distinct values to build trees from.
+func backendValues(n int) []string {
+	values := make([]string, n)
+	for i := range values {
+		values[i] = fmt.Sprintf("0x%040x:%d", i*7919, i)
+	}
+	return values
+}
+
+// backendRoot is the root of the default tree over backendValues(1000). CI
+// also runs the tests with the purego tag, so the assembly and pure-Go paths
+// of every backend must build it.
+const backendRoot = HexString("0xfad38a1eed939311625e8e58aedbb5c626301990318eba7531ff706954d77bc0")
+
+func TestBackendsBuildIdenticalRoots(t *testing.T) {
+	values := backendValues(1000)
+	roots := make(map[string]HexString)
+	for _, name := range keccak.Backends() {
+		t.Run(name, func(t *testing.T) {
+			useBackend(t, name)
+			if !strings.HasPrefix(SelectedBackend(), name) {
+				t.Errorf("SelectedBackend() = %q", SelectedBackend())
+			}
+			if err := SelfTest(); err != nil {
+				t.Fatalf("Self test failed: %v", err)
+			}
+
+			tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{})
+			if err != nil {
+				t.Fatalf("Failed to create tree: %v", err)
+			}
+			set, err := NewLeafSet[string](MerkleTreeOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, v := range values {
+				set.Add(v)
+			}
+			if root, err := set.Root(); err != nil || root != tree.Root() {
+				t.Errorf("LeafSet root %s differs from the tree root %s", root, tree.Root())
+			}
+			roots[name] = tree.Root()
+		})
+	}
+
+	for name, root := range roots {
+		if root != backendRoot {
+			t.Errorf("%s backend built root %s, want %s", name, root, backendRoot)
+		}
+	}
+}
+
+func BenchmarkBuildBackends(b *testing.B) {
+	values := backendValues(100_000)
+	for _, name := range keccak.Backends() {
+		b.Run(name, func(b *testing.B) {
+			useBackend(b, name)
+			for range b.N {
+				if _, err := NewStandardMerkleTree(values, MerkleTreeOptions{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
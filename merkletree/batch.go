@@ -0,0 +1,65 @@
+package merkletree
+
+import (
+	"context"
+	"errors"
+)
+
+// BatchResult is the outcome of verifying one proof envelope of a batch.
+type BatchResult struct {
+	Evaluated bool  // The item was verified; false if ctx ended first
+	Valid     bool  // The proof leads to the envelope's root
+	Err       error // Why the envelope could not be verified, if it was malformed
+}
+
+// BatchVerify verifies each envelope with nodeHash, in order.
+// If nodeHash is nil, StandardNodeHash is used.
+func BatchVerify(envelopes []ProofEnvelope, nodeHash NodeHash) []BatchResult {
+	results, _ := BatchVerifyCtx(context.Background(), envelopes, nodeHash)
+	return results
+}
+
+// BatchVerifyCtx is BatchVerify for callers with a deadline. It checks ctx
+// between items and between the hashes of each proof, and when ctx ends it
+// returns at once with the results so far and ctx.Err(). Items that were not
+// finished have Evaluated unset.
+func BatchVerifyCtx(ctx context.Context, envelopes []ProofEnvelope, nodeHash NodeHash) ([]BatchResult, error) {
+	return BatchVerifyWithOptions(ctx, envelopes, BatchOptions{NodeHash: nodeHash})
+}
+
+// BatchOptions configures BatchVerifyWithOptions.
+type BatchOptions struct {
+	// NodeHash hashes internal nodes; nil means StandardNodeHash.
+	NodeHash NodeHash
+
+	// PairCache, if set, memoizes node hashes across the proofs of the batch,
+	// and of any later batch given the same cache. A cache must only be used
+	// with one node hash. Results are the same with or without it.
+	PairCache *PairCache
+}
+
+// BatchVerifyWithOptions is BatchVerifyCtx with options, such as a PairCache
+// for batches of proofs from the same tree.
+func BatchVerifyWithOptions(ctx context.Context, envelopes []ProofEnvelope, opts BatchOptions) ([]BatchResult, error) {
+	nodeHash := opts.NodeHash
+	if nodeHash == nil {
+		nodeHash = StandardNodeHash
+	}
+	if opts.PairCache != nil {
+		nodeHash = opts.PairCache.wrap(nodeHash)
+	}
+
+	results := make([]BatchResult, len(envelopes))
+	for i, envelope := range envelopes {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		valid, err := envelope.VerifyCtx(ctx, nodeHash)
+		if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+			return results, ctxErr
+		}
+		results[i] = BatchResult{Evaluated: true, Valid: valid, Err: err}
+	}
+	return results, nil
+}
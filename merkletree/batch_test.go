@@ -0,0 +1,146 @@
+package merkletree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// slowNodeHash is StandardNodeHash taking delay per call.
+func slowNodeHash(delay time.Duration) NodeHash {
+	return func(a, b BytesLike) HexString {
+		time.Sleep(delay)
+		return StandardNodeHash(a, b)
+	}
+}
+
+// envelopes returns a proof envelope for every value of a tree over n values.
+func envelopes(t *testing.T, n int) []ProofEnvelope {
+	t.Helper()
+	values := make([]string, n)
+	for i := range values {
+		values[i] = fmt.Sprintf("value-%d", i)
+	}
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	out := make([]ProofEnvelope, n)
+	for i := range values {
+		claim, err := tree.ExportClaim(i)
+		if err != nil {
+			t.Fatalf("Failed to export claim: %v", err)
+		}
+		out[i] = claim.Envelope()
+	}
+	return out
+}
+
+func TestBatchVerify(t *testing.T) {
+	items := envelopes(t, 8)
+	items[3].Root = items[3].LeafHash
+	items[5].LeafHash = "0x1234"
+
+	results := BatchVerify(items, nil)
+	for i, r := range results {
+		if !r.Evaluated {
+			t.Errorf("Item %d should be evaluated", i)
+		}
+		wantValid := i != 3 && i != 5
+		if r.Valid != wantValid {
+			t.Errorf("Item %d: valid=%v, want %v", i, r.Valid, wantValid)
+		}
+		if (r.Err != nil) != (i == 5) {
+			t.Errorf("Item %d: unexpected error %v", i, r.Err)
+		}
+	}
+}
+
+func TestBatchVerifyCtxDeadline(t *testing.T) {
+	// 64 leaves give 6-node proofs: 60ms per item, about 4s for the batch
+	items := envelopes(t, 64)
+	goroutines := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	results, err := BatchVerifyCtx(ctx, items, slowNodeHash(10*time.Millisecond))
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed > 300*time.Millisecond {
+		t.Errorf("Batch should stop promptly after the deadline, took %v", elapsed)
+	}
+	if len(results) != len(items) {
+		t.Fatalf("Expected a result slot per item, got %d", len(results))
+	}
+
+	evaluated := 0
+	for i, r := range results {
+		if r.Evaluated {
+			evaluated++
+			if !r.Valid || r.Err != nil {
+				t.Errorf("Evaluated item %d should be valid: %+v", i, r)
+			}
+		} else if r.Valid {
+			t.Errorf("Unevaluated item %d should not be valid", i)
+		}
+	}
+	if evaluated == 0 || evaluated == len(items) {
+		t.Errorf("Expected a partial batch, %d of %d evaluated", evaluated, len(items))
+	}
+	// Items are evaluated in order, so the unevaluated ones form a suffix
+	if !results[evaluated-1].Evaluated || results[evaluated].Evaluated {
+		t.Errorf("Evaluated items should be a prefix of the batch")
+	}
+
+	if after := runtime.NumGoroutine(); after > goroutines {
+		t.Errorf("Goroutines leaked: %d before, %d after", goroutines, after)
+	}
+}
+
+func TestVerifyCtxLongProof(t *testing.T) {
+	values := make([]BytesLike, 1024)
+	for i := range values {
+		values[i] = fmt.Sprintf("value-%d", i)
+	}
+	slow := false
+	nodeHash := func(a, b BytesLike) HexString {
+		if slow {
+			time.Sleep(20 * time.Millisecond)
+		}
+		return StandardNodeHash(a, b)
+	}
+	tree, err := NewSimpleMerkleTree(values, SimpleMerkleTreeOptions{NodeHash: nodeHash})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	proof, err := tree.ProofForIndex(0)
+	if err != nil {
+		t.Fatalf("Failed to get proof: %v", err)
+	}
+
+	// A 10-node proof takes 200ms with the slow hash; the deadline stops it between hashes
+	slow = true
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := tree.VerifyCtx(ctx, 0, proof); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("VerifyCtx should stop between hashes, took %v", elapsed)
+	}
+
+	canceled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	if _, err := tree.VerifyCtx(canceled, 0, proof); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
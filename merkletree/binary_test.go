@@ -0,0 +1,167 @@
+package merkletree
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestBinaryDumpRoundTrip(t *testing.T) {
+	metadata := []json.RawMessage{json.RawMessage(`{"tier":1}`), nil, json.RawMessage(`"<b>"`)}
+	values := []any{"alice", "bob", "charlie"}
+	for _, tt := range []struct {
+		values  []any
+		options MerkleTreeOptions
+	}{
+		{values, MerkleTreeOptions{}},
+		{values, MerkleTreeOptions{SortLeaves: Bool(true), LeafMetadata: metadata}},
+		{values, MerkleTreeOptions{Compatibility: CompatLatest, ShuffleSeed: []byte("seed")}},
+		{append(values, func() {}), MerkleTreeOptions{QuarantineInvalid: true}},
+	} {
+		tree, err := NewStandardMerkleTree(tt.values, tt.options)
+		if err != nil {
+			t.Fatalf("Failed to create tree: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := tree.ExportBinary(&buf); err != nil {
+			t.Fatalf("ExportBinary failed: %v", err)
+		}
+		loaded, err := LoadStandardMerkleTreeBinary[any](&buf)
+		if err != nil {
+			t.Fatalf("LoadStandardMerkleTreeBinary failed: %v", err)
+		}
+
+		// The reloaded tree dumps exactly as the original
+		want, _ := tree.Dump()
+		got, err := loaded.Dump()
+		if err != nil {
+			t.Fatalf("Failed to dump loaded tree: %v", err)
+		}
+		wantJSON, _ := json.Marshal(want)
+		gotJSON, _ := json.Marshal(got)
+		if string(gotJSON) != string(wantJSON) {
+			t.Errorf("Loaded tree dumps\n%s\nwant\n%s", gotJSON, wantJSON)
+		}
+		proof, err := loaded.GetProof(1)
+		if err != nil {
+			t.Fatalf("GetProof failed: %v", err)
+		}
+		if valid, err := loaded.Verify(1, proof); err != nil || !valid {
+			t.Errorf("Proof of value 1 does not verify after loading: %v", err)
+		}
+	}
+
+	// Values with a leaf encoding keep their OpenZeppelin form
+	encoded, err := NewStandardMerkleTreeWithEncoding(ozReadmeValues(), []string{"address", "uint256"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := encoded.ExportBinary(&buf); err != nil {
+		t.Fatalf("ExportBinary failed: %v", err)
+	}
+	loaded, err := LoadStandardMerkleTreeBinary[[]any](&buf)
+	if err != nil || loaded.Root() != encoded.Root() || !slices.Equal(loaded.LeafEncoding(), encoded.LeafEncoding()) {
+		t.Errorf("Loaded encoded tree: %v", err)
+	}
+}
+
+func TestLoadStandardMerkleTreeBinaryRejects(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"alice", "bob", "charlie", "dave"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tree.ExportBinary(&buf); err != nil {
+		t.Fatalf("ExportBinary failed: %v", err)
+	}
+	data := buf.Bytes()
+
+	for _, cut := range []int{10, len(data) / 2, len(data) - 1} {
+		if _, err := LoadStandardMerkleTreeBinary[string](bytes.NewReader(data[:cut])); !errors.Is(err, ErrTruncatedDump) {
+			t.Errorf("Loading the first %d bytes: %v, want ErrTruncatedDump", cut, err)
+		}
+	}
+
+	tampered := bytes.Replace(data, []byte(`"bob"`), []byte(`"eve"`), 1)
+	if _, err := LoadStandardMerkleTreeBinary[string](bytes.NewReader(tampered)); !errors.Is(err, ErrTruncatedDump) {
+		t.Errorf("Expected an edited dump to fail its checksum, got %v", err)
+	}
+	if _, err := LoadStandardMerkleTreeBinary[string](bytes.NewReader(append(slices.Clone(data), 0))); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected trailing bytes to be rejected, got %v", err)
+	}
+
+	future := bytes.Replace(data, []byte(binaryFormat), []byte("standard-binary-v2"), 1)
+	var formatErr *FormatError
+	if _, err := LoadStandardMerkleTreeBinary[string](bytes.NewReader(future)); !errors.As(err, &formatErr) || formatErr.Format != "standard-binary-v2" {
+		t.Errorf("Expected an unknown version to fail with a *FormatError, got %v", err)
+	}
+	if _, err := LoadStandardMerkleTreeBinary[string](bytes.NewReader([]byte("[]\n"))); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected a bad header to fail with ErrInvalidDump, got %v", err)
+	}
+}
+
+func TestBinaryDumpSmallerThanJSON(t *testing.T) {
+	values := make([]string, 1000)
+	for i := range values {
+		values[i] = fmt.Sprintf("account-%04d", i)
+	}
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	var jsonDump, binaryDump bytes.Buffer
+	if err := tree.DumpTo(&jsonDump); err != nil {
+		t.Fatalf("DumpTo failed: %v", err)
+	}
+	if err := tree.ExportBinary(&binaryDump); err != nil {
+		t.Fatalf("ExportBinary failed: %v", err)
+	}
+	if binaryDump.Len() >= jsonDump.Len()*2/3 {
+		t.Errorf("Binary dump is %d bytes, JSON %d", binaryDump.Len(), jsonDump.Len())
+	}
+}
+
+// BenchmarkDumpFormats compares the size and load time of the JSON and binary
+// dumps of a 1M-leaf tree.
+func BenchmarkDumpFormats(b *testing.B) {
+	values := make([]string, 1_000_000)
+	for i := range values {
+		values[i] = fmt.Sprintf("account-%07d", i)
+	}
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{})
+	if err != nil {
+		b.Fatalf("Failed to create tree: %v", err)
+	}
+
+	for _, format := range []struct {
+		name  string
+		write func(*bytes.Buffer) error
+		load  func(*bytes.Reader) error
+	}{
+		{"json", func(buf *bytes.Buffer) error { return tree.DumpTo(buf) }, func(r *bytes.Reader) error {
+			_, err := LoadStandardMerkleTreeFrom[string](r)
+			return err
+		}},
+		{"binary", func(buf *bytes.Buffer) error { return tree.ExportBinary(buf) }, func(r *bytes.Reader) error {
+			_, err := LoadStandardMerkleTreeBinary[string](r)
+			return err
+		}},
+	} {
+		var buf bytes.Buffer
+		if err := format.write(&buf); err != nil {
+			b.Fatalf("Failed to write %s dump: %v", format.name, err)
+		}
+		b.Run(format.name, func(b *testing.B) {
+			b.ReportMetric(float64(buf.Len()), "bytes/dump")
+			for range b.N {
+				if err := format.load(bytes.NewReader(buf.Bytes())); err != nil {
+					b.Fatalf("Load failed: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package merkletree
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"slices"
+)
+
+// BitcoinMerkleTree is the Merkle tree of the transactions of a Bitcoin
+// block. Nodes are hashed with double SHA-256 over left || right, unsorted,
+// and a level with an odd count pairs its last node with itself. The shape
+// differs from the complete trees of this package, so it has its own
+// construction, and its proofs carry the direction of each step.
+//
+// Hashes go in and out in the byte order block explorers and bitcoind's RPC
+// show them, which is the reverse of the order they are hashed in.
+type BitcoinMerkleTree struct {
+	levels [][][32]byte // levels[0] are the txids in hashing order, the last level the root
+}
+
+// BitcoinProof proves that a transaction is in a block.
+type BitcoinProof struct {
+	// Siblings are the nodes paired with the path from the transaction to the
+	// root, in display byte order. A node paired with itself is its own sibling.
+	Siblings []HexString `json:"siblings"`
+
+	// Path is the position of the transaction in the block: bit k is set when
+	// the node at step k is the right child, so Siblings[k] is on the left.
+	Path uint64 `json:"path"`
+}
+
+// NewBitcoinMerkleTree builds the tree of txids, each 32 bytes in display
+// byte order, in block order with the coinbase first. Its root is the merkle
+// root of the block header. A txid that is not 32 bytes is reported as an
+// *InputError wrapping ErrInvalidNode.
+func NewBitcoinMerkleTree(txids [][]byte) (*BitcoinMerkleTree, error) {
+	if len(txids) == 0 {
+		return nil, ErrEmptyTree
+	}
+	level := make([][32]byte, len(txids))
+	for i, txid := range txids {
+		if len(txid) != 32 {
+			return nil, &InputError{Index: i, Err: fmt.Errorf("%w: txid is %d bytes", ErrInvalidNode, len(txid))}
+		}
+		level[i] = reversed32(txid)
+	}
+
+	t := &BitcoinMerkleTree{levels: [][][32]byte{level}}
+	for len(level) > 1 {
+		next := make([][32]byte, (len(level)+1)/2)
+		for i := range next {
+			left := level[2*i]
+			right := left
+			if 2*i+1 < len(level) {
+				right = level[2*i+1]
+			}
+			next[i] = doubleSHA256(left, right)
+		}
+		t.levels = append(t.levels, next)
+		level = next
+	}
+	return t, nil
+}
+
+// Root returns the merkle root in display byte order, as block explorers
+// show the header's merkle root.
+func (t *BitcoinMerkleTree) Root() HexString {
+	return displayHex(t.levels[len(t.levels)-1][0])
+}
+
+// Len returns the number of transactions.
+func (t *BitcoinMerkleTree) Len() int {
+	return len(t.levels[0])
+}
+
+// GetProof returns the proof of the transaction at index in the block.
+func (t *BitcoinMerkleTree) GetProof(index int) (BitcoinProof, error) {
+	if index < 0 || index >= t.Len() {
+		return BitcoinProof{}, fmt.Errorf("%w: transaction %d of %d", ErrInvalidIndex, index, t.Len())
+	}
+	proof := BitcoinProof{Path: uint64(index)}
+	for _, level := range t.levels[:len(t.levels)-1] {
+		sibling := min(index^1, len(level)-1)
+		proof.Siblings = append(proof.Siblings, displayHex(level[sibling]))
+		index /= 2
+	}
+	return proof, nil
+}
+
+// Verify reports whether proof proves txid against the tree's root.
+func (t *BitcoinMerkleTree) Verify(txid BytesLike, proof BitcoinProof) (bool, error) {
+	return VerifyBitcoinProof(t.Root(), txid, proof)
+}
+
+// VerifyBitcoinProof reports whether proof proves txid against root, both in
+// display byte order. It returns an error wrapping ErrInvalidNode if the
+// root, txid or a sibling is not 32 bytes.
+//
+// As in Bitcoin, a duplicated last node makes the proof of the last
+// transaction of an odd level also verify at the position past it, so check
+// Path against the transaction count when the position matters.
+func VerifyBitcoinProof(root, txid BytesLike, proof BitcoinProof) (bool, error) {
+	if proof.Path>>len(proof.Siblings) != 0 {
+		return false, nil // The path goes higher than the proof
+	}
+	node, err := bitcoinNode(txid)
+	if err != nil {
+		return false, fmt.Errorf("invalid txid: %w", err)
+	}
+	want, err := bitcoinNode(root)
+	if err != nil {
+		return false, fmt.Errorf("invalid root: %w", err)
+	}
+	for k, s := range proof.Siblings {
+		sibling, err := bitcoinNode(s)
+		if err != nil {
+			return false, fmt.Errorf("invalid sibling %d: %w", k, err)
+		}
+		if proof.Path>>k&1 == 1 {
+			node = doubleSHA256(sibling, node)
+		} else {
+			node = doubleSHA256(node, sibling)
+		}
+	}
+	return node == want, nil
+}
+
+// bitcoinNode reads a 32-byte hash in display byte order into hashing order.
+func bitcoinNode(value BytesLike) ([32]byte, error) {
+	b, err := ToBytes(value)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	if len(b) != 32 {
+		return [32]byte{}, fmt.Errorf("%w: got %d bytes", ErrInvalidNode, len(b))
+	}
+	return reversed32(b), nil
+}
+
+// doubleSHA256 returns SHA-256(SHA-256(left || right)).
+func doubleSHA256(left, right [32]byte) [32]byte {
+	first := sha256.Sum256(slices.Concat(left[:], right[:]))
+	return sha256.Sum256(first[:])
+}
+
+// reversed32 returns the 32 bytes of b in reverse order.
+func reversed32(b []byte) [32]byte {
+	var out [32]byte
+	copy(out[:], b)
+	slices.Reverse(out[:])
+	return out
+}
+
+// displayHex returns the hex of a node in hashing order as it is displayed.
+func displayHex(node [32]byte) HexString {
+	display := reversed32(node[:])
+	return HexString(fmt.Sprintf("0x%x", display))
+}
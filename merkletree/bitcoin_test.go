@@ -0,0 +1,127 @@
+package merkletree
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestBitcoinMerkleTree(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		txids []string
+		root  HexString
+	}{
+		{
+			// Block 100000, whose header's merkle root is on every block explorer
+			name: "block 100000",
+			txids: []string{
+				"8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87",
+				"fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4",
+				"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+				"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+			},
+			root: "0xf3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766",
+		},
+		{
+			// Block 170, with the first transaction between two people
+			name: "block 170",
+			txids: []string{
+				"b1fea52486ce0c62bb442b530a3f0132b826c74e473d1f2c220bfa78111c5082",
+				"f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+			},
+			root: "0x7dac2c5666815c17a3b36427de37bb9d2e2c5ccec3f8633eb91a4205cb4c10ff",
+		},
+		{
+			// The genesis block, whose only transaction is its root
+			name:  "genesis block",
+			txids: []string{"4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"},
+			root:  "0x4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b",
+		},
+		{
+			// Seven txids sha256("tx i"), so two levels duplicate their last
+			// node; the root was computed with Python's hashlib
+			name:  "odd levels",
+			txids: syntheticTxids(7),
+			root:  "0x824b0bfcb851cd0be798ed22ade00552bb6b46ca709e015d4423b5d95c68d729",
+		},
+	} {
+		txids := make([][]byte, len(tt.txids))
+		for i, txid := range tt.txids {
+			txids[i], _ = hex.DecodeString(txid)
+		}
+		tree, err := NewBitcoinMerkleTree(txids)
+		if err != nil {
+			t.Fatalf("%s: Failed to create tree: %v", tt.name, err)
+		}
+		if tree.Root() != tt.root {
+			t.Errorf("%s: root %s, want %s", tt.name, tree.Root(), tt.root)
+		}
+		for i, txid := range tt.txids {
+			proof, err := tree.GetProof(i)
+			if err != nil {
+				t.Fatalf("%s: GetProof(%d) failed: %v", tt.name, i, err)
+			}
+			if valid, err := VerifyBitcoinProof(tt.root, "0x"+txid, proof); err != nil || !valid {
+				t.Errorf("%s: proof of transaction %d does not verify: %v", tt.name, i, err)
+			}
+			if len(proof.Siblings) == 0 {
+				continue
+			}
+			// Directions matter, as pairs are not sorted
+			flipped := proof
+			flipped.Path ^= 1
+			if valid, _ := tree.Verify("0x"+txid, flipped); valid && proof.Siblings[0] != HexString("0x"+txid) {
+				t.Errorf("%s: proof of transaction %d verifies with the first step flipped", tt.name, i)
+			}
+		}
+	}
+}
+
+func TestBitcoinMerkleTreeErrors(t *testing.T) {
+	txids := make([][]byte, 3)
+	for i, txid := range syntheticTxids(3) {
+		txids[i], _ = hex.DecodeString(txid)
+	}
+	tree, err := NewBitcoinMerkleTree(txids)
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	// The last transaction is paired with itself
+	proof, _ := tree.GetProof(2)
+	if want := HexString(fmt.Sprintf("0x%x", txids[2])); proof.Siblings[0] != want || proof.Path != 2 {
+		t.Errorf("Proof of the last transaction %+v, want first sibling %s", proof, want)
+	}
+	past := proof
+	past.Path = 4
+	if valid, _ := tree.Verify(txids[2], past); valid {
+		t.Error("Expected a path longer than the proof to be rejected")
+	}
+
+	if _, err := tree.GetProof(3); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("Expected ErrInvalidIndex, got %v", err)
+	}
+	if _, err := tree.Verify("0x1234", proof); !errors.Is(err, ErrInvalidNode) {
+		t.Errorf("Expected a short txid to fail with ErrInvalidNode, got %v", err)
+	}
+	if _, err := NewBitcoinMerkleTree(nil); !errors.Is(err, ErrEmptyTree) {
+		t.Errorf("Expected ErrEmptyTree, got %v", err)
+	}
+	var inputErr *InputError
+	if _, err := NewBitcoinMerkleTree([][]byte{txids[0], {0x12}}); !errors.As(err, &inputErr) || inputErr.Index != 1 {
+		t.Errorf("Expected an *InputError for txid 1, got %v", err)
+	}
+}
+
+// syntheticTxids returns n txids, sha256("tx i") in hex.
+func syntheticTxids(n int) []string {
+	txids := make([]string, n)
+	for i := range txids {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("tx %d", i)))
+		txids[i] = hex.EncodeToString(sum[:])
+	}
+	return txids
+}
@@ -1,7 +1,10 @@
 package merkletree
 
 import (
+	"bytes"
+	"encoding"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
@@ -15,8 +18,108 @@ type BytesLike interface{}
 // HexString represents a hexadecimal string with "0x" prefix.
 type HexString string
 
+// Normalize returns h with a lowercase 0x prefix and lowercase digits, the
+// form ToHex returns, so that hashes compare equal whatever their case. It
+// does not validate h.
+func (h HexString) Normalize() HexString {
+	digits, ok := cutHexPrefix(string(h))
+	if !ok {
+		return HexString(strings.ToLower(string(h)))
+	}
+	if h[1] == 'x' && !strings.ContainsAny(digits, "ABCDEF") {
+		return h // Already normal; keep sharing its memory
+	}
+	return HexString("0x" + strings.ToLower(digits))
+}
+
+// Bytes decodes h as ToBytes does.
+func (h HexString) Bytes() ([]byte, error) {
+	return ToBytes(h)
+}
+
+// Len returns the number of bytes h decodes to: half its digits after a 0x
+// prefix, or its length without one, as ToBytes reads it. The digits are
+// not checked; use IsValid for that.
+func (h HexString) Len() int {
+	if digits, ok := cutHexPrefix(string(h)); ok {
+		return len(digits) / 2
+	}
+	return len(h)
+}
+
+// IsValid reports whether h is 0x- or 0X-prefixed hex with an even number
+// of digits, which may be none.
+func (h HexString) IsValid() bool {
+	digits, ok := cutHexPrefix(string(h))
+	return ok && isHexDigits(digits)
+}
+
+// MarshalJSON implements json.Marshaler, writing h in the form Normalize
+// returns. It does not validate h.
+func (h HexString) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(h.Normalize()))
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It rejects a string that is
+// not valid hex as IsValid defines it with an error wrapping ErrInvalidValue,
+// so malformed dumps fail when they are decoded, and normalizes the rest. An
+// empty string is left empty.
+func (h *HexString) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		*h = ""
+		return nil
+	}
+	digits, ok := cutHexPrefix(s)
+	if !ok {
+		return fmt.Errorf("%w: hex string %q has no 0x prefix", ErrInvalidValue, previewValue(s))
+	}
+	if _, err := decodeHexDigits(digits); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidValue, err)
+	}
+	*h = HexString(s).Normalize()
+	return nil
+}
+
+// isHexDigits reports whether s is an even number of hex digits.
+func isHexDigits(s string) bool {
+	if len(s)%2 != 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// cutHexPrefix returns s without its 0x or 0X prefix, and whether it had one.
+func cutHexPrefix(s string) (string, bool) {
+	if len(s) >= 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		return s[2:], true
+	}
+	return s, false
+}
+
 // ToBytes converts a BytesLike value to a byte array.
-// Supports: []byte, HexString, string (with or without "0x" prefix), and []int.
+// Supports: []byte, HexString, string (with or without "0x" prefix), []int,
+// unsigned integers and *big.Int. A 0x- or 0X-prefixed string is decoded as
+// hex, and an odd number of digits is an error wrapping ErrOddLengthHex; any
+// other string, hex-looking or not, is its raw bytes. Integers are
+// big-endian at their minimal width, at least one byte, so uint64(258) is
+// 0x0102 and 0 is 0x00; use ToFixedBytes for a fixed width. A negative
+// *big.Int is an error. Address and U256 are their raw 20 and 32 bytes.
+//
+// Other types are read through their encoding.BinaryMarshaler, or failing
+// that their encoding.TextMarshaler. The types above come first, matched
+// exactly, so a named string type that implements MarshalBinary is
+// marshalled rather than read as a string, while a HexString is always
+// read as a string.
 // Returns an error if the type is not supported or conversion fails.
 func ToBytes(value BytesLike) ([]byte, error) {
 	switch v := value.(type) {
@@ -26,13 +129,8 @@ func ToBytes(value BytesLike) ([]byte, error) {
 		// Recursively convert HexString to string and then to bytes
 		return ToBytes(string(v))
 	case string:
-		if strings.HasPrefix(v, "0x") {
-			hexData := v[2:] // Remove "0x" prefix
-			decoded, err := hex.DecodeString(hexData)
-			if err != nil {
-				return nil, fmt.Errorf("invalid hex string: %w", err)
-			}
-			return decoded, nil
+		if digits, ok := cutHexPrefix(v); ok {
+			return decodeHexDigits(digits)
 		}
 		return []byte(v), nil
 	case []int:
@@ -41,27 +139,106 @@ func ToBytes(value BytesLike) ([]byte, error) {
 			bytes[i] = byte(num)
 		}
 		return bytes, nil
+	case uint:
+		return minimalBytes(new(big.Int).SetUint64(uint64(v))), nil
+	case uint8:
+		return []byte{v}, nil
+	case uint16:
+		return minimalBytes(new(big.Int).SetUint64(uint64(v))), nil
+	case uint32:
+		return minimalBytes(new(big.Int).SetUint64(uint64(v))), nil
+	case uint64:
+		return minimalBytes(new(big.Int).SetUint64(v)), nil
+	case *big.Int:
+		if v == nil {
+			return nil, errors.New("nil *big.Int in ToBytes")
+		}
+		if v.Sign() < 0 {
+			return nil, fmt.Errorf("negative *big.Int %s in ToBytes", previewValue(v.String()))
+		}
+		return minimalBytes(v), nil
+	case Address:
+		return v[:], nil
+	case U256:
+		return v[:], nil
+	case encoding.BinaryMarshaler:
+		data, err := v.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("MarshalBinary of %T in ToBytes: %w", v, err)
+		}
+		return data, nil
+	case encoding.TextMarshaler:
+		data, err := v.MarshalText()
+		if err != nil {
+			return nil, fmt.Errorf("MarshalText of %T in ToBytes: %w", v, err)
+		}
+		return data, nil
 	default:
 		return nil, errors.New("unsupported type in ToBytes")
 	}
 }
 
+// decodeHexDigits decodes the digits of a 0x-prefixed string. An odd number of
+// digits is an error wrapping ErrOddLengthHex rather than being padded, as a
+// missing nibble is more likely a typo than a leading zero.
+func decodeHexDigits(digits string) ([]byte, error) {
+	if len(digits)%2 != 0 {
+		return nil, fmt.Errorf("%w: 0x%s has %d digits", ErrOddLengthHex, previewValue(digits), len(digits))
+	}
+	decoded, err := hex.DecodeString(digits)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex string: %w", err)
+	}
+	return decoded, nil
+}
+
+// minimalBytes returns the big-endian bytes of a non-negative n, at least one.
+func minimalBytes(n *big.Int) []byte {
+	if n.Sign() == 0 {
+		return []byte{0}
+	}
+	return n.Bytes()
+}
+
+// ToFixedBytes converts a value as ToBytes does and left-pads it with zeros
+// to size bytes, so ToFixedBytes(uint64(1), 32) is a uint256 word. It returns
+// an error if the value takes more than size bytes.
+func ToFixedBytes(value BytesLike, size int) ([]byte, error) {
+	data, err := ToBytes(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > size {
+		return nil, fmt.Errorf("value of %d bytes does not fit in %d", len(data), size)
+	}
+	fixed := make([]byte, size)
+	copy(fixed[size-len(data):], data)
+	return fixed, nil
+}
+
 // ToHex converts a BytesLike value to a HexString with "0x" prefix.
-// Supports: string, HexString, []byte, and []int.
+// Supports: string, HexString, []byte, []int, and the integers and
+// marshalers ToBytes reads. Strings follow ToBytes: a 0x- or 0X-prefixed
+// string must be valid hex of even length, and a bare one is hex-encoded
+// from its raw bytes, so ToHex("abc") is 0x616263. The result is always
+// lowercase with a 0x prefix, as Normalize returns it.
 // Returns an error if the type is not supported or conversion fails.
 func ToHex(value BytesLike) (HexString, error) {
 	switch v := value.(type) {
 	case string, HexString:
 		str := fmt.Sprintf("%v", v)
-		// Validate that it's a valid hex string
-		_, err := hex.DecodeString(strings.TrimPrefix(str, "0x"))
-		if err != nil {
-			return "", fmt.Errorf("invalid hex string: %w", err)
+		digits, ok := cutHexPrefix(str)
+		if !ok {
+			// Bare strings are raw bytes, as in ToBytes
+			return HexString("0x" + hex.EncodeToString([]byte(str))), nil
 		}
-		return HexString("0x" + strings.TrimPrefix(str, "0x")), nil
+		if _, err := decodeHexDigits(digits); err != nil {
+			return "", err
+		}
+		return HexString(str).Normalize(), nil
 	case []byte:
 		return HexString("0x" + hex.EncodeToString(v)), nil
-	case []int:
+	case []int, uint, uint8, uint16, uint32, uint64, *big.Int, Address, U256, encoding.BinaryMarshaler, encoding.TextMarshaler:
 		bytes, err := ToBytes(v)
 		if err != nil {
 			return "", err
@@ -86,25 +263,19 @@ func Concat(values ...BytesLike) ([]byte, error) {
 	return result, nil
 }
 
-// Compare compares two BytesLike values lexicographically as big integers.
+// Compare compares the bytes of two BytesLike values lexicographically, like
+// bytes.Compare, so a leading zero byte counts: 0x0011 sorts before 0x11.
+// This is the order OpenZeppelin uses for leaves and node pairs.
 // Returns -1 if a < b, 0 if a == b, 1 if a > b.
-// Returns an error if conversion to hex fails.
+// Returns an error if conversion to bytes fails.
 func Compare(a BytesLike, b BytesLike) (int, error) {
-	aHex, err := ToHex(a)
+	aBytes, err := ToBytes(a)
 	if err != nil {
 		return 0, err
 	}
-	bHex, err := ToHex(b)
+	bBytes, err := ToBytes(b)
 	if err != nil {
 		return 0, err
 	}
-
-	aBigInt := new(big.Int)
-	bBigInt := new(big.Int)
-
-	// Remove "0x" prefix and convert to BigInt
-	aBigInt.SetString(string(aHex)[2:], 16)
-	bBigInt.SetString(string(bHex)[2:], 16)
-
-	return aBigInt.Cmp(bBigInt), nil
+	return bytes.Compare(aBytes, bBytes), nil
 }
@@ -1,6 +1,13 @@
 package merkletree
 
 import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -65,6 +72,178 @@ func TestToBytes(t *testing.T) {
 	}
 }
 
+func TestToBytesIntegers(t *testing.T) {
+	large, _ := new(big.Int).SetString("5000000000000000000000", 10)
+	tests := []struct {
+		input BytesLike
+		want  string
+	}{
+		{uint8(0), "00"},
+		{uint8(0xff), "ff"},
+		{uint16(1), "01"},
+		{uint16(0x1234), "1234"},
+		{uint32(0x10000), "010000"},
+		{uint64(258), "0102"},
+		{uint64(math.MaxUint64), "ffffffffffffffff"},
+		{uint(7), "07"},
+		{big.NewInt(0), "00"},
+		{big.NewInt(256), "0100"},
+		{large, "010f0cf064dd59200000"},
+	}
+	for _, tt := range tests {
+		got, err := ToBytes(tt.input)
+		if err != nil || hex.EncodeToString(got) != tt.want {
+			t.Errorf("ToBytes(%T %v) = %x, %v; want %s", tt.input, tt.input, got, err, tt.want)
+		}
+		if h, err := ToHex(tt.input); err != nil || h != HexString("0x"+tt.want) {
+			t.Errorf("ToHex(%T %v) = %s, %v; want 0x%s", tt.input, tt.input, h, err, tt.want)
+		}
+	}
+
+	for _, bad := range []BytesLike{big.NewInt(-1), (*big.Int)(nil)} {
+		if _, err := ToBytes(bad); err == nil {
+			t.Errorf("ToBytes(%v) should fail", bad)
+		}
+		if _, err := ToHex(bad); err == nil {
+			t.Errorf("ToHex(%v) should fail", bad)
+		}
+	}
+}
+
+// txID is a domain type with a binary form.
+type txID [4]byte
+
+func (id txID) MarshalBinary() ([]byte, error) { return id[:], nil }
+
+// taggedName is a string type whose binary form differs from its text.
+type taggedName string
+
+func (n taggedName) MarshalBinary() ([]byte, error) { return append([]byte{0x01}, n...), nil }
+
+// textOnly only has a text form.
+type textOnly struct{ s string }
+
+func (v textOnly) MarshalText() ([]byte, error) { return []byte("text:" + v.s), nil }
+
+// bothForms has a binary and a text form.
+type bothForms struct{}
+
+func (bothForms) MarshalBinary() ([]byte, error) { return []byte{0xbb}, nil }
+func (bothForms) MarshalText() ([]byte, error)   { return []byte("text"), nil }
+
+// failingMarshaler cannot be marshalled.
+type failingMarshaler struct{}
+
+var errMarshal = errors.New("cannot marshal")
+
+func (failingMarshaler) MarshalBinary() ([]byte, error) { return nil, errMarshal }
+
+func TestToBytesMarshalers(t *testing.T) {
+	tests := []struct {
+		input BytesLike
+		want  string
+	}{
+		{txID{0xde, 0xad, 0xbe, 0xef}, "deadbeef"},
+		{taggedName("ab"), "016162"},    // A string alias is marshalled, not read as a string
+		{textOnly{"x"}, "746578743a78"}, // "text:x"
+		{bothForms{}, "bb"},             // MarshalBinary comes before MarshalText
+		{HexString("0x0102"), "0102"},   // HexString is decoded, not marshalled
+		{Address{19: 0x01}, strings.Repeat("00", 19) + "01"},
+		{U256FromUint64(1), strings.Repeat("00", 31) + "01"},
+	}
+	for _, tt := range tests {
+		got, err := ToBytes(tt.input)
+		if err != nil || hex.EncodeToString(got) != tt.want {
+			t.Errorf("ToBytes(%T) = %x, %v; want %s", tt.input, got, err, tt.want)
+		}
+		if h, err := ToHex(tt.input); err != nil || h != HexString("0x"+tt.want) {
+			t.Errorf("ToHex(%T) = %s, %v; want 0x%s", tt.input, h, err, tt.want)
+		}
+	}
+
+	_, err := ToBytes(failingMarshaler{})
+	if !errors.Is(err, errMarshal) || !strings.Contains(err.Error(), "failingMarshaler") {
+		t.Errorf("Expected the marshal error with its type, got %v", err)
+	}
+
+	// Simple trees take marshalers as values
+	tree, err := NewSimpleMerkleTree([]BytesLike{txID{1}, txID{2}, txID{3}}, SimpleMerkleTreeOptions{MerkleTreeOptions: MerkleTreeOptions{Compatibility: CompatLatest}})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if got, want := tree.Tree[tree.Values[0].TreeIndex], BytesLeafHash([]byte{1, 0, 0, 0}); got != want {
+		t.Errorf("Leaf of txID{1} is %s, want %s", got, want)
+	}
+}
+
+func TestHexStringPolicy(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string // Hex of the bytes, without 0x
+		wantErr error  // Only checked when set
+		fails   bool
+	}{
+		{input: "0x", want: ""},
+		{input: "0x00ff", want: "00ff"},
+		{input: "0xABcd", want: "abcd"},
+		{input: "0x123", wantErr: ErrOddLengthHex, fails: true},
+		{input: "0x0", wantErr: ErrOddLengthHex, fails: true},
+		{input: "0xzz", fails: true},
+		{input: "", want: ""},
+		{input: "abc", want: "616263"},                // Bare strings are raw bytes,
+		{input: "deadbeef", want: "6465616462656566"}, // even when they look like hex
+		{input: "0X12", want: "12"},
+		{input: "0XAB", want: "ab"},
+	}
+	for _, tt := range tests {
+		for _, input := range []BytesLike{tt.input, HexString(tt.input)} {
+			data, bytesErr := ToBytes(input)
+			hexed, hexErr := ToHex(input)
+			if tt.fails {
+				if bytesErr == nil || hexErr == nil {
+					t.Errorf("%T %q: ToBytes = %v, ToHex = %v; want errors", input, tt.input, bytesErr, hexErr)
+				}
+				if tt.wantErr != nil && (!errors.Is(bytesErr, tt.wantErr) || !errors.Is(hexErr, tt.wantErr)) {
+					t.Errorf("%T %q: errors %v and %v, want %v", input, tt.input, bytesErr, hexErr, tt.wantErr)
+				}
+				continue
+			}
+			if bytesErr != nil || hex.EncodeToString(data) != tt.want {
+				t.Errorf("ToBytes(%T %q) = %x, %v; want %s", input, tt.input, data, bytesErr, tt.want)
+			}
+			// ToHex agrees with ToBytes, in lowercase
+			if hexErr != nil || hexed != HexString("0x"+tt.want) {
+				t.Errorf("ToHex(%T %q) = %s, %v; want 0x%s", input, tt.input, hexed, hexErr, tt.want)
+			}
+		}
+	}
+	if Code(ErrOddLengthHex) != CodeOddLengthHex {
+		t.Errorf("ErrOddLengthHex has code %q", Code(ErrOddLengthHex))
+	}
+}
+
+func TestToFixedBytes(t *testing.T) {
+	tests := []struct {
+		input BytesLike
+		size  int
+		want  string
+	}{
+		{uint64(1), 8, "0000000000000001"},
+		{uint16(0x1234), 4, "00001234"},
+		{big.NewInt(256), 32, strings.Repeat("0", 60) + "0100"},
+		{[]byte{1, 2}, 2, "0102"},
+	}
+	for _, tt := range tests {
+		got, err := ToFixedBytes(tt.input, tt.size)
+		if err != nil || hex.EncodeToString(got) != tt.want {
+			t.Errorf("ToFixedBytes(%v, %d) = %x, %v; want %s", tt.input, tt.size, got, err, tt.want)
+		}
+	}
+	if _, err := ToFixedBytes(uint32(0x10000), 2); err == nil {
+		t.Error("ToFixedBytes should reject a value wider than its size")
+	}
+}
+
 func TestToHex(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -197,6 +376,26 @@ func TestCompare(t *testing.T) {
 			want:    -1,
 			wantErr: false,
 		},
+		{
+			name:    "leading zero byte",
+			a:       "0x0011",
+			b:       "0x11",
+			want:    -1,
+			wantErr: false,
+		},
+		{
+			name:    "zero hash and zero byte",
+			a:       HexString("0x" + strings.Repeat("00", 32)),
+			b:       "0x00",
+			want:    1,
+			wantErr: false,
+		},
+		{
+			name:    "invalid hex",
+			a:       "0xzz",
+			b:       "0x11",
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -212,3 +411,69 @@ func TestCompare(t *testing.T) {
 		})
 	}
 }
+
+func TestHexStringMethods(t *testing.T) {
+	tests := []struct {
+		h     HexString
+		valid bool
+		len   int
+	}{
+		{"0x", true, 0},
+		{"0x00ff", true, 2},
+		{"0XABCD", true, 2},
+		{"0x123", false, 1},
+		{"0xzz", false, 1},
+		{"abcd", false, 4}, // Raw bytes, as ToBytes reads it
+		{"", false, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.h.IsValid(); got != tt.valid {
+			t.Errorf("%q.IsValid() = %v, want %v", tt.h, got, tt.valid)
+		}
+		if got := tt.h.Len(); got != tt.len {
+			t.Errorf("%q.Len() = %d, want %d", tt.h, got, tt.len)
+		}
+		data, err := tt.h.Bytes()
+		if want, wantErr := ToBytes(tt.h); (err != nil) != (wantErr != nil) || string(data) != string(want) {
+			t.Errorf("%q.Bytes() = %x, %v; ToBytes gives %x, %v", tt.h, data, err, want, wantErr)
+		}
+		if tt.valid && len(data) != tt.len {
+			t.Errorf("%q decodes to %d bytes, Len() says %d", tt.h, len(data), tt.len)
+		}
+	}
+}
+
+func TestHexStringJSON(t *testing.T) {
+	data, err := json.Marshal([]HexString{"0xABcd", "0X01", ""})
+	if err != nil || string(data) != `["0xabcd","0x01",""]` {
+		t.Errorf("Marshal = %s, %v", data, err)
+	}
+
+	var decoded []HexString
+	if err := json.Unmarshal([]byte(`["0xABcd","0X01",""]`), &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if want := []HexString{"0xabcd", "0x01", ""}; !slices.Equal(decoded, want) {
+		t.Errorf("Unmarshal = %q, want %q", decoded, want)
+	}
+
+	for _, bad := range []string{`"0x123"`, `"0xzz"`, `"abcd"`, `12`} {
+		var h HexString
+		if err := json.Unmarshal([]byte(bad), &h); err == nil {
+			t.Errorf("Unmarshal(%s) should fail", bad)
+		} else if bad != `12` && !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("Unmarshal(%s) = %v, want ErrInvalidValue", bad, err)
+		}
+	}
+	var h HexString
+	if err := json.Unmarshal([]byte(`"0x123"`), &h); !errors.Is(err, ErrOddLengthHex) {
+		t.Errorf("Unmarshal of odd-length hex = %v, want ErrOddLengthHex", err)
+	}
+
+	// A malformed node fails when the dump is decoded
+	var dump StandardMerkleTreeData[string]
+	err = json.Unmarshal([]byte(`{"format":"standard-v1","tree":["0xnothex"],"values":[]}`), &dump)
+	if !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Decoding a dump with a malformed node = %v, want ErrInvalidValue", err)
+	}
+}
@@ -0,0 +1,333 @@
+package merkletree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/smeneguz/GoMerkle/internal/keccak"
+)
+
+// abiWordSize is the size of one ABI head slot.
+const abiWordSize = 32
+
+// Leaf encodings for ClaimMapping.LeafEncoding.
+const (
+	// LeafEncodingPacked hashes keccak256(abi.encodePacked(fields)), like StandardLeafHash.
+	LeafEncodingPacked = "packed"
+
+	// LeafEncodingOpenZeppelin hashes keccak256(bytes.concat(keccak256(abi.encode(fields)))),
+	// the leaf of OpenZeppelin's StandardMerkleTree.
+	LeafEncodingOpenZeppelin = "openzeppelin"
+)
+
+// ParsedClaim is the decoded calldata of a claim transaction.
+type ParsedClaim struct {
+	Selector  HexString   `json:"selector"`  // First 4 bytes of the calldata
+	Signature string      `json:"signature"` // Canonical function signature
+	Types     []string    `json:"types"`     // Canonical argument types
+	Args      []any       `json:"args"`      // Decoded arguments, see ParseSolidityProofCalldata
+	ProofArg  int         `json:"proofArg"`  // Index of the bytes32[] argument, or -1
+	Proof     []HexString `json:"proof"`     // The bytes32[] argument
+
+	words [][]byte // Head word of each static argument, nil for dynamic ones
+}
+
+// ClaimMapping describes how the arguments of a claim form its leaf.
+type ClaimMapping struct {
+	// LeafArgs are the indices of the arguments hashed into the leaf, in order.
+	// Nil means every static argument.
+	LeafArgs []int
+
+	// LeafEncoding is LeafEncodingPacked (the default) or LeafEncodingOpenZeppelin.
+	LeafEncoding string
+}
+
+// ParseSolidityProofCalldata decodes the calldata of a claim transaction for
+// the function with the given signature, such as
+// "claim(uint256,address,uint256,bytes32[])". The selector in data must match
+// the signature.
+//
+// Supported argument types are uintN, intN, address, bool, bytesN and one
+// bytes32[] holding the proof. Integers decode to *big.Int, addresses and
+// bytesN to HexString, and bytes32[] to []HexString. Values that Solidity
+// would reject, such as dirty high bits, are errors.
+func ParseSolidityProofCalldata(data []byte, abiSignature string) (ParsedClaim, error) {
+	name, types, err := parseFunctionSignature(abiSignature)
+	if err != nil {
+		return ParsedClaim{}, err
+	}
+	signature := name + "(" + strings.Join(types, ",") + ")"
+
+	if len(data) < 4 {
+		return ParsedClaim{}, fmt.Errorf("%w: %d bytes is too short for a selector", ErrInvalidCalldata, len(data))
+	}
+	if want := keccak256([]byte(signature))[:4]; !bytes.Equal(data[:4], want) {
+		return ParsedClaim{}, fmt.Errorf("%w: selector 0x%x does not match %s (0x%x)", ErrInvalidCalldata, data[:4], signature, want)
+	}
+
+	claim := ParsedClaim{
+		Selector:  HexString(fmt.Sprintf("0x%x", data[:4])),
+		Signature: signature,
+		Types:     types,
+		Args:      make([]any, len(types)),
+		ProofArg:  -1,
+		words:     make([][]byte, len(types)),
+	}
+
+	args := data[4:]
+	if len(args) < len(types)*abiWordSize {
+		return ParsedClaim{}, fmt.Errorf("%w: %d argument bytes, need at least %d", ErrInvalidCalldata, len(args), len(types)*abiWordSize)
+	}
+
+	for i, typ := range types {
+		word := args[i*abiWordSize : (i+1)*abiWordSize]
+		if typ == "bytes32[]" {
+			if claim.ProofArg >= 0 {
+				return ParsedClaim{}, fmt.Errorf("%w: more than one bytes32[] argument", ErrInvalidCalldata)
+			}
+			proof, err := decodeBytes32Array(args, word)
+			if err != nil {
+				return ParsedClaim{}, fmt.Errorf("%w: argument %d: %v", ErrInvalidCalldata, i, err)
+			}
+			claim.Args[i] = proof
+			claim.ProofArg = i
+			claim.Proof = proof
+			continue
+		}
+
+		value, err := decodeStaticWord(typ, word)
+		if err != nil {
+			return ParsedClaim{}, fmt.Errorf("%w: argument %d (%s): %v", ErrInvalidCalldata, i, typ, err)
+		}
+		claim.Args[i] = value
+		claim.words[i] = word
+	}
+	return claim, nil
+}
+
+// Leaf computes the leaf hash of the claim according to mapping.
+func (c ParsedClaim) Leaf(mapping ClaimMapping) (HexString, error) {
+	indices := mapping.LeafArgs
+	if indices == nil {
+		for i, word := range c.words {
+			if word != nil {
+				indices = append(indices, i)
+			}
+		}
+	}
+	if len(indices) == 0 {
+		return "", fmt.Errorf("%w: no leaf arguments", ErrInvalidCalldata)
+	}
+
+	var encoded []byte
+	for _, i := range indices {
+		if i < 0 || i >= len(c.words) || c.words[i] == nil {
+			return "", fmt.Errorf("%w: argument %d cannot be part of the leaf", ErrInvalidCalldata, i)
+		}
+		switch mapping.LeafEncoding {
+		case "", LeafEncodingPacked:
+			encoded = append(encoded, packedWord(c.Types[i], c.words[i])...)
+		case LeafEncodingOpenZeppelin:
+			encoded = append(encoded, c.words[i]...)
+		default:
+			return "", fmt.Errorf("%w: unknown leaf encoding %q", ErrInvalidCalldata, mapping.LeafEncoding)
+		}
+	}
+
+	leaf := keccak256(encoded)
+	if mapping.LeafEncoding == LeafEncodingOpenZeppelin {
+		leaf = keccak256(leaf)
+	}
+	return ToHex(leaf)
+}
+
+// Verify computes the claim's leaf and reports whether its proof leads to
+// root with StandardNodeHash. It also returns the leaf it computed.
+func (c ParsedClaim) Verify(root BytesLike, mapping ClaimMapping) (bool, HexString, error) {
+	if c.ProofArg < 0 {
+		return false, "", fmt.Errorf("%w: signature has no bytes32[] proof argument", ErrInvalidCalldata)
+	}
+	leaf, err := c.Leaf(mapping)
+	if err != nil {
+		return false, "", err
+	}
+
+	envelope := ProofEnvelope{LeafHash: leaf, Proof: c.Proof}
+	if envelope.Root, err = ToHex(root); err != nil {
+		return false, leaf, fmt.Errorf("error converting expected root: %w", err)
+	}
+	valid, err := envelope.Verify(StandardNodeHash)
+	return valid, leaf, err
+}
+
+// parseFunctionSignature splits "name(type,...)" and canonicalizes the types.
+func parseFunctionSignature(signature string) (string, []string, error) {
+	signature = strings.Join(strings.Fields(signature), "")
+	open := strings.IndexByte(signature, '(')
+	if open <= 0 || !strings.HasSuffix(signature, ")") {
+		return "", nil, fmt.Errorf("%w: malformed signature %q", ErrInvalidCalldata, signature)
+	}
+
+	name := signature[:open]
+	list := signature[open+1 : len(signature)-1]
+	if list == "" {
+		return name, nil, nil
+	}
+
+	types := strings.Split(list, ",")
+	for i, typ := range types {
+		canonical, err := canonicalType(typ)
+		if err != nil {
+			return "", nil, err
+		}
+		types[i] = canonical
+	}
+	return name, types, nil
+}
+
+// canonicalType validates a supported ABI type and expands uint and int aliases.
+func canonicalType(typ string) (string, error) {
+	switch {
+	case typ == "uint" || typ == "int":
+		return typ + "256", nil
+	case typ == "address" || typ == "bool" || typ == "bytes32[]":
+		return typ, nil
+	case strings.HasPrefix(typ, "uint"):
+		if bits, err := strconv.Atoi(typ[4:]); err == nil && bits%8 == 0 && bits >= 8 && bits <= 256 {
+			return typ, nil
+		}
+	case strings.HasPrefix(typ, "int"):
+		if bits, err := strconv.Atoi(typ[3:]); err == nil && bits%8 == 0 && bits >= 8 && bits <= 256 {
+			return typ, nil
+		}
+	case strings.HasPrefix(typ, "bytes"):
+		if size, err := strconv.Atoi(typ[5:]); err == nil && size >= 1 && size <= 32 {
+			return typ, nil
+		}
+	}
+	return "", fmt.Errorf("%w: unsupported type %q", ErrInvalidCalldata, typ)
+}
+
+// decodeStaticWord decodes one head word of a static type, rejecting
+// encodings that Solidity's decoder would reject.
+func decodeStaticWord(typ string, word []byte) (any, error) {
+	switch {
+	case typ == "bool":
+		if !isZero(word[:31]) || word[31] > 1 {
+			return nil, fmt.Errorf("invalid bool 0x%x", word)
+		}
+		return word[31] == 1, nil
+	case typ == "address":
+		if !isZero(word[:12]) {
+			return nil, fmt.Errorf("dirty high bytes in address 0x%x", word)
+		}
+		return HexString(fmt.Sprintf("0x%x", word[12:])), nil
+	case strings.HasPrefix(typ, "uint"):
+		size := typeSize(typ, "uint")
+		if !isZero(word[:32-size]) {
+			return nil, fmt.Errorf("value 0x%x overflows %s", word, typ)
+		}
+		return new(big.Int).SetBytes(word), nil
+	case strings.HasPrefix(typ, "int"):
+		size := typeSize(typ, "int")
+		value := new(big.Int).SetBytes(word)
+		if word[0]&0x80 != 0 {
+			value.Sub(value, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		limit := new(big.Int).Lsh(big.NewInt(1), uint(size*8-1))
+		if value.Cmp(limit) >= 0 || value.Cmp(new(big.Int).Neg(limit)) < 0 {
+			return nil, fmt.Errorf("value %s overflows %s", value, typ)
+		}
+		return value, nil
+	default: // bytesN
+		size := typeSize(typ, "bytes")
+		if !isZero(word[size:]) {
+			return nil, fmt.Errorf("dirty padding in %s 0x%x", typ, word)
+		}
+		return HexString(fmt.Sprintf("0x%x", word[:size])), nil
+	}
+}
+
+// decodeBytes32Array decodes a bytes32[] whose head word is offset.
+// The offset is relative to the start of the arguments.
+func decodeBytes32Array(args, offsetWord []byte) ([]HexString, error) {
+	offset, err := wordToInt(offsetWord, len(args))
+	if err != nil {
+		return nil, fmt.Errorf("offset: %v", err)
+	}
+	if offset+abiWordSize > len(args) {
+		return nil, fmt.Errorf("offset %d points past the end of the calldata", offset)
+	}
+
+	length, err := wordToInt(args[offset:offset+abiWordSize], len(args))
+	if err != nil {
+		return nil, fmt.Errorf("length: %v", err)
+	}
+	start := offset + abiWordSize
+	if length > (len(args)-start)/abiWordSize {
+		return nil, fmt.Errorf("%d elements at offset %d do not fit in the calldata", length, offset)
+	}
+
+	proof := make([]HexString, length)
+	for i := range proof {
+		proof[i] = HexString(fmt.Sprintf("0x%x", args[start+i*abiWordSize:start+(i+1)*abiWordSize]))
+	}
+	return proof, nil
+}
+
+// wordToInt reads a word as a non-negative integer no larger than limit.
+func wordToInt(word []byte, limit int) (int, error) {
+	if !isZero(word[:24]) {
+		return 0, fmt.Errorf("0x%x is too large", word)
+	}
+	n := binary.BigEndian.Uint64(word[24:])
+	if n > uint64(limit) {
+		return 0, fmt.Errorf("%d is larger than the calldata", n)
+	}
+	return int(n), nil
+}
+
+// packedWord returns the abi.encodePacked form of a static head word.
+func packedWord(typ string, word []byte) []byte {
+	switch {
+	case typ == "bool":
+		return word[31:]
+	case typ == "address":
+		return word[12:]
+	case strings.HasPrefix(typ, "uint"):
+		return word[32-typeSize(typ, "uint"):]
+	case strings.HasPrefix(typ, "int"):
+		return word[32-typeSize(typ, "int"):]
+	default: // bytesN
+		return word[:typeSize(typ, "bytes")]
+	}
+}
+
+// typeSize returns the size in bytes of a canonical uintN, intN or bytesN type.
+func typeSize(typ, prefix string) int {
+	n, _ := strconv.Atoi(typ[len(prefix):])
+	if prefix == "bytes" {
+		return n
+	}
+	return n / 8
+}
+
+// isZero reports whether every byte of b is zero.
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// keccak256 returns the Keccak-256 hash of data.
+func keccak256(data []byte) []byte {
+	hash := keccak.Sum256(data)
+	return hash[:]
+}
@@ -0,0 +1,162 @@
+package merkletree
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+)
+
+// calldataFixture is a claim transaction encoded with go-ethereum's ABI encoder.
+type calldataFixture struct {
+	Name         string    `json:"name"`
+	Signature    string    `json:"signature"`
+	Calldata     string    `json:"calldata"`
+	Root         HexString `json:"root"`
+	LeafArgs     []int     `json:"leafArgs"`
+	LeafEncoding string    `json:"leafEncoding"`
+	Valid        bool      `json:"valid"`
+}
+
+func loadCalldataFixtures(t *testing.T) []calldataFixture {
+	t.Helper()
+	data, err := os.ReadFile("testdata/calldata.json")
+	if err != nil {
+		t.Fatalf("Failed to read fixtures: %v", err)
+	}
+	var fixtures []calldataFixture
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		t.Fatalf("Failed to decode fixtures: %v", err)
+	}
+	return fixtures
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		t.Fatalf("Invalid hex %q: %v", s, err)
+	}
+	return b
+}
+
+func TestParseSolidityProofCalldata(t *testing.T) {
+	for _, f := range loadCalldataFixtures(t) {
+		if f.Root == "" {
+			continue
+		}
+		t.Run(f.Name, func(t *testing.T) {
+			claim, err := ParseSolidityProofCalldata(decodeHex(t, f.Calldata), f.Signature)
+			if err != nil {
+				t.Fatalf("Failed to parse: %v", err)
+			}
+			if claim.ProofArg != 3 || len(claim.Proof) == 0 {
+				t.Errorf("Expected the proof in argument 3, got %d with %d elements", claim.ProofArg, len(claim.Proof))
+			}
+			if _, ok := claim.Args[0].(*big.Int); !ok {
+				t.Errorf("uint256 should decode to *big.Int, got %T", claim.Args[0])
+			}
+
+			valid, _, err := claim.Verify(f.Root, ClaimMapping{LeafArgs: f.LeafArgs, LeafEncoding: f.LeafEncoding})
+			if err != nil {
+				t.Fatalf("Failed to verify: %v", err)
+			}
+			if valid != f.Valid {
+				t.Errorf("Expected valid=%v, got %v", f.Valid, valid)
+			}
+		})
+	}
+}
+
+func TestParseCalldataProofFirst(t *testing.T) {
+	f := loadCalldataFixtures(t)[6]
+	claim, err := ParseSolidityProofCalldata(decodeHex(t, f.Calldata), "claimFirst(bytes32[], address, uint96, bool)")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	if claim.ProofArg != 0 || claim.Proof == nil || len(claim.Proof) != 0 {
+		t.Errorf("Expected an empty proof in argument 0, got %d %v", claim.ProofArg, claim.Proof)
+	}
+	if claim.Args[1] != HexString("0x2222222222222222222222222222222222222222") {
+		t.Errorf("Unexpected address %v", claim.Args[1])
+	}
+	if claim.Args[2].(*big.Int).Int64() != 7 || claim.Args[3] != true {
+		t.Errorf("Unexpected arguments %v", claim.Args)
+	}
+
+	// packed: 20-byte address, 12-byte uint96, 1-byte bool
+	leaf, err := claim.Leaf(ClaimMapping{})
+	if err != nil {
+		t.Fatalf("Failed to compute leaf: %v", err)
+	}
+	packed := decodeHex(t, "2222222222222222222222222222222222222222"+"000000000000000000000007"+"01")
+	if want, _ := ToHex(keccak256(packed)); leaf != want {
+		t.Errorf("Leaf %s, want %s", leaf, want)
+	}
+}
+
+func TestParseCalldataDynamicOffsets(t *testing.T) {
+	f := loadCalldataFixtures(t)[1]
+	original := decodeHex(t, f.Calldata)
+	mapping := ClaimMapping{LeafArgs: f.LeafArgs}
+
+	// Move the array one word further, leaving a gap: still valid ABI
+	gapped := append([]byte(nil), original[:4+4*32]...)
+	gapped[4+3*32+31] = 0xa0
+	gapped = append(gapped, make([]byte, 32)...)
+	gapped = append(gapped, original[4+4*32:]...)
+
+	claim, err := ParseSolidityProofCalldata(gapped, f.Signature)
+	if err != nil {
+		t.Fatalf("Failed to parse gapped calldata: %v", err)
+	}
+	if valid, _, err := claim.Verify(f.Root, mapping); err != nil || !valid {
+		t.Errorf("Gapped calldata should verify: valid=%v err=%v", valid, err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func([]byte) []byte
+	}{
+		{"offset past end", func(b []byte) []byte { b[4+3*32+31] = 0xff; return b }},
+		{"huge offset", func(b []byte) []byte { b[4+3*32] = 0x01; return b }},
+		{"length past end", func(b []byte) []byte { b[4+4*32+31] = 0x09; return b }},
+		{"truncated", func(b []byte) []byte { return b[:len(b)-1] }},
+		{"dirty address", func(b []byte) []byte { b[4+32] = 0x01; return b }},
+		{"wrong selector", func(b []byte) []byte { b[0] ^= 0xff; return b }},
+		{"short", func(b []byte) []byte { return b[:3] }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.mutate(append([]byte(nil), original...))
+			if _, err := ParseSolidityProofCalldata(data, f.Signature); !errors.Is(err, ErrInvalidCalldata) {
+				t.Errorf("Expected ErrInvalidCalldata, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseCalldataTypes(t *testing.T) {
+	if _, err := ParseSolidityProofCalldata(nil, "claim(string)"); !errors.Is(err, ErrInvalidCalldata) {
+		t.Errorf("Expected unsupported type error, got %v", err)
+	}
+	if _, _, err := parseFunctionSignature("claim(uint,int8,bytes4)"); err != nil {
+		t.Errorf("Failed to parse signature: %v", err)
+	}
+
+	word := make([]byte, 32)
+	for i := range word {
+		word[i] = 0xff
+	}
+	if v, err := decodeStaticWord("int8", word); err != nil || v.(*big.Int).Int64() != -1 {
+		t.Errorf("int8 -1 decoded to %v, %v", v, err)
+	}
+	word[30] = 0x7f
+	if _, err := decodeStaticWord("int8", word); err == nil {
+		t.Error("Expected int8 overflow")
+	}
+}
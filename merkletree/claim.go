@@ -0,0 +1,153 @@
+package merkletree
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// singleLeafNote explains the empty proof of a claim from a one-leaf tree.
+const singleLeafNote = "single-leaf tree: the root is the leaf hash and the proof is empty"
+
+// Claim holds everything a claimant needs to prove that a value is in a tree.
+// Its JSON form is a superset of ProofEnvelope, so a claim can be passed to
+// VerifyEnvelope directly. The proof always serializes as an array, never null.
+type Claim[T any] struct {
+	Root        HexString       `json:"root"`                  // Root the proof verifies against
+	Value       T               `json:"value"`                 // Original value
+	ValueIndex  int             `json:"valueIndex"`            // Index of the value in insertion order
+	LeafHash    HexString       `json:"leafHash"`              // Hash of the value as stored in the tree
+	Proof       []HexString     `json:"proof"`                 // Sibling hashes from the leaf to the root
+	PackedProof HexString       `json:"packedProof,omitempty"` // Proof as concatenated bytes, if requested
+	Metadata    json.RawMessage `json:"metadata,omitempty"`    // Metadata attached to the value, if any
+	Note        string          `json:"note,omitempty"`        // Explanation for degenerate cases
+	Version     string          `json:"version,omitempty"`     // Library version that exported the claim
+}
+
+// ExportClaim builds the claim for a value or value index.
+// For a single-leaf tree the proof is empty and the claim carries a note
+// saying so, because the root equals the leaf hash.
+func (m *MerkleTreeImpl[T]) ExportClaim(leaf any) (Claim[T], error) {
+	return m.ExportClaimFor(leaf, "")
+}
+
+// ExportClaimFor is ExportClaim recording requester, a caller-chosen
+// identifier such as a client address, with the claim when an issuance
+// recorder is set.
+func (m *MerkleTreeImpl[T]) ExportClaimFor(leaf any, requester string) (Claim[T], error) {
+	valueIndex, err := m.getLeafIndex(leaf)
+	if err != nil {
+		return Claim[T]{}, err
+	}
+
+	proof, err := m.GetProof(valueIndex)
+	if err != nil {
+		return Claim[T]{}, err
+	}
+	return m.newClaim(valueIndex, proof, requester)
+}
+
+// newClaim builds and records the claim of the value at valueIndex with its proof.
+func (m *MerkleTreeImpl[T]) newClaim(valueIndex int, proof []HexString, requester string) (Claim[T], error) {
+	value, err := m.valueAt(valueIndex)
+	if err != nil {
+		return Claim[T]{}, err
+	}
+
+	claim := Claim[T]{
+		Root:       m.Root(),
+		Value:      value,
+		ValueIndex: valueIndex,
+		LeafHash:   m.Tree[m.Values[valueIndex].TreeIndex],
+		Proof:      proof,
+		Metadata:   m.metadataAt(valueIndex),
+		Version:    version,
+	}
+	if claim.Proof == nil {
+		claim.Proof = []HexString{}
+	}
+	if len(claim.Proof) == 0 {
+		claim.Note = singleLeafNote
+	}
+	if err := m.recordIssued(claim, requester); err != nil {
+		return Claim[T]{}, err
+	}
+	return claim, nil
+}
+
+// ProofEnvelope is the minimal self-contained proof: a leaf hash, the proof,
+// and the root it should verify against.
+type ProofEnvelope struct {
+	Root     HexString   `json:"root"`
+	LeafHash HexString   `json:"leafHash"`
+	Proof    []HexString `json:"proof"`
+	Version  string      `json:"version,omitempty"` // Library version that wrote the envelope
+}
+
+// MarshalJSON encodes the envelope, writing an empty proof as [] rather than
+// null and recording the current library version if Version is empty.
+func (e ProofEnvelope) MarshalJSON() ([]byte, error) {
+	type envelope ProofEnvelope
+	if e.Proof == nil {
+		e.Proof = []HexString{}
+	}
+	if e.Version == "" {
+		e.Version = version
+	}
+	return json.Marshal(envelope(e))
+}
+
+// Envelope returns the claim without its value, ready for VerifyEnvelope.
+func (c Claim[T]) Envelope() ProofEnvelope {
+	return ProofEnvelope{Root: c.Root, LeafHash: c.LeafHash, Proof: c.Proof, Version: c.Version}
+}
+
+// VerifyEnvelope parses a JSON proof envelope (or claim) and checks that its
+// proof leads from the leaf hash to the root. A missing or null proof is
+// treated as empty, which is valid only when the root equals the leaf hash.
+// If nodeHash is nil, StandardNodeHash is used.
+func VerifyEnvelope(data []byte, nodeHash NodeHash) (bool, error) {
+	return VerifyEnvelopeCtx(context.Background(), data, nodeHash)
+}
+
+// VerifyEnvelopeCtx is VerifyEnvelope stopping with ctx.Err() when ctx is done.
+func VerifyEnvelopeCtx(ctx context.Context, data []byte, nodeHash NodeHash) (bool, error) {
+	var envelope ProofEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return false, fmt.Errorf("invalid proof envelope: %w", err)
+	}
+	return envelope.VerifyCtx(ctx, nodeHash)
+}
+
+// Verify checks that the envelope's proof leads from its leaf hash to its root.
+// If nodeHash is nil, StandardNodeHash is used.
+func (e ProofEnvelope) Verify(nodeHash NodeHash) (bool, error) {
+	return e.VerifyCtx(context.Background(), nodeHash)
+}
+
+// VerifyCtx is Verify stopping with ctx.Err() when ctx is done, which it
+// checks before hashing each proof node.
+func (e ProofEnvelope) VerifyCtx(ctx context.Context, nodeHash NodeHash) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if nodeHash == nil {
+		nodeHash = StandardNodeHash
+	}
+
+	proof := make([]BytesLike, len(e.Proof))
+	for i, p := range e.Proof {
+		proof[i] = p
+	}
+
+	computedRoot, err := processProof(ctx, e.LeafHash, proof, nodeHash)
+	if err != nil {
+		return false, fmt.Errorf("error processing proof: %w", err)
+	}
+
+	root, err := ToHex(e.Root)
+	if err != nil {
+		return false, fmt.Errorf("error converting expected root: %w", err)
+	}
+	return computedRoot == root, nil
+}
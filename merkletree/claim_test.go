@@ -0,0 +1,111 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestExportClaimSingleLeaf(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"only"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	// The root of a one-leaf tree is the leaf hash itself
+	if tree.Root() != StandardLeafHash("only") {
+		t.Errorf("Root %s should equal the leaf hash %s", tree.Root(), StandardLeafHash("only"))
+	}
+
+	claim, err := tree.ExportClaim("only")
+	if err != nil {
+		t.Fatalf("Failed to export claim: %v", err)
+	}
+	if claim.Note == "" {
+		t.Error("Single-leaf claim should explain its empty proof")
+	}
+
+	data, err := json.Marshal(claim)
+	if err != nil {
+		t.Fatalf("Failed to marshal claim: %v", err)
+	}
+	if !strings.Contains(string(data), `"proof":[]`) {
+		t.Errorf("Empty proof should serialize as [], got %s", data)
+	}
+
+	valid, err := VerifyEnvelope(data, nil)
+	if err != nil || !valid {
+		t.Errorf("Claim should verify as an envelope: valid=%v err=%v", valid, err)
+	}
+}
+
+func TestExportClaimTwoLeaves(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"left", "right"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	// The root of a two-leaf tree is the node hash of both leaves
+	want := StandardNodeHash(StandardLeafHash("left"), StandardLeafHash("right"))
+	if tree.Root() != want {
+		t.Errorf("Root %s, want %s", tree.Root(), want)
+	}
+
+	for i, value := range []string{"left", "right"} {
+		claim, err := tree.ExportClaim(i)
+		if err != nil {
+			t.Fatalf("Failed to export claim %d: %v", i, err)
+		}
+		if claim.Value != value || len(claim.Proof) != 1 || claim.Note != "" {
+			t.Errorf("Unexpected claim %d: %+v", i, claim)
+		}
+
+		data, _ := json.Marshal(claim)
+		valid, err := VerifyEnvelope(data, nil)
+		if err != nil || !valid {
+			t.Errorf("Claim %d should verify: valid=%v err=%v", i, valid, err)
+		}
+	}
+}
+
+func TestVerifyEnvelopeEmptyProofForms(t *testing.T) {
+	leaf := StandardLeafHash("only")
+	other := StandardLeafHash("other")
+
+	tests := []struct {
+		name  string
+		json  string
+		valid bool
+	}{
+		{"empty array", `{"root":"` + string(leaf) + `","leafHash":"` + string(leaf) + `","proof":[]}`, true},
+		{"null", `{"root":"` + string(leaf) + `","leafHash":"` + string(leaf) + `","proof":null}`, true},
+		{"missing", `{"root":"` + string(leaf) + `","leafHash":"` + string(leaf) + `"}`, true},
+		{"wrong root", `{"root":"` + string(other) + `","leafHash":"` + string(leaf) + `","proof":null}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, err := VerifyEnvelope([]byte(tt.json), nil)
+			if err != nil {
+				t.Fatalf("VerifyEnvelope failed: %v", err)
+			}
+			if valid != tt.valid {
+				t.Errorf("Expected valid=%v, got %v", tt.valid, valid)
+			}
+		})
+	}
+
+	if _, err := VerifyEnvelope([]byte(`{"root":`), nil); err == nil {
+		t.Error("Malformed JSON should return an error")
+	}
+}
+
+func TestProofEnvelopeMarshalsEmptyProof(t *testing.T) {
+	data, err := json.Marshal(ProofEnvelope{Root: "0x01", LeafHash: "0x01"})
+	if err != nil {
+		t.Fatalf("Failed to marshal envelope: %v", err)
+	}
+	if !strings.Contains(string(data), `"proof":[]`) {
+		t.Errorf("Nil proof should serialize as [], got %s", data)
+	}
+}
@@ -0,0 +1,329 @@
+package merkletree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DefaultGoSourceLineWidth is the line width ExportGoSource keeps to.
+const DefaultGoSourceLineWidth = 100
+
+// goSourceTabWidth is the number of columns a tab counts for in line widths, as in gofmt.
+const goSourceTabWidth = 8
+
+// generatedNodeHashes maps the node hashes of AlgorithmDescriptor to the
+// functions generated code calls.
+var generatedNodeHashes = map[string]string{
+	HashKeccak256Sorted:  "merkletree.StandardNodeHash",
+	HashSHA256Sorted:     "merkletree.SHA256NodeHash",
+	HashBlake2b256Sorted: "merkletree.Blake2b256NodeHash",
+}
+
+// GoSourceOptions configures ExportGoSourceWithOptions.
+type GoSourceOptions struct {
+	// Package is the package clause of the generated file.
+	Package string
+
+	// VarName prefixes every generated identifier: VarName+"Root",
+	// VarName+"LeafHashes", VarName+"Values", VarName+"Proofs" and
+	// VarName+"Verify". Capitalize it to export them.
+	VarName string
+
+	// LineWidth is the longest line generated, with tabs counting 8 columns.
+	// Zero means DefaultGoSourceLineWidth. Proof nodes are indented twice, so
+	// widths under 85 fail with an *OptionError.
+	LineWidth int
+}
+
+// ExportGoSource writes a Go source file declaring the tree as package-level
+// variables, for embedding small trees such as allowlists in a binary: the
+// root, and for each value in value order its leaf hash, the value itself and
+// its proof. The file also declares a Verify helper checking a leaf hash and
+// proof against the root with this package. The output is gofmt-formatted,
+// with lines no longer than DefaultGoSourceLineWidth.
+//
+// Values must be of a type that can be written as a Go literal: booleans,
+// numbers, strings, byte slices, and slices, arrays and interfaces holding them.
+// Trees built with a custom node hash cannot be exported.
+func (m *MerkleTreeImpl[T]) ExportGoSource(w io.Writer, pkg, varName string) error {
+	return m.ExportGoSourceWithOptions(w, GoSourceOptions{Package: pkg, VarName: varName})
+}
+
+// ExportGoSourceWithOptions is ExportGoSource with a configurable line width.
+func (m *MerkleTreeImpl[T]) ExportGoSourceWithOptions(w io.Writer, opts GoSourceOptions) error {
+	width := opts.LineWidth
+	if width == 0 {
+		width = DefaultGoSourceLineWidth
+	}
+	nodeHash, ok := generatedNodeHashes[m.algorithm.NodeHash]
+
+	var errs []error
+	if !token.IsIdentifier(opts.Package) {
+		errs = append(errs, &OptionError{Option: "Package", Value: opts.Package, Reason: "must be a Go identifier"})
+	}
+	if !token.IsIdentifier(opts.VarName) {
+		errs = append(errs, &OptionError{Option: "VarName", Value: opts.VarName, Reason: "must be a Go identifier"})
+	}
+	if width < 0 {
+		errs = append(errs, &OptionError{Option: "LineWidth", Value: opts.LineWidth, Reason: "must not be negative"})
+	}
+	if !ok {
+		errs = append(errs, &OptionError{Option: "NodeHash", Value: m.algorithm.NodeHash, Reason: "only named node hashes can be generated"})
+	}
+	valueType, err := goTypeName(reflect.TypeFor[T]())
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	g := &goSourceWriter{width: width}
+	name := opts.VarName
+	g.printf("// Code generated by gomerkle; DO NOT EDIT.\n\n")
+	g.printf("package %s\n\n", opts.Package)
+	g.printf("import \"github.com/smeneguz/GoMerkle/merkletree\"\n\n")
+
+	g.printf("// %sRoot is the root of the tree.\n", name)
+	g.printf("const %sRoot = merkletree.HexString(\n\t%q,\n)\n\n", name, m.Root())
+
+	leafHashes := make([]string, len(m.Values))
+	values := make([]string, len(m.Values))
+	proofs := make([][]string, len(m.Values))
+	for i := range m.Values {
+		leafHashes[i] = strconv.Quote(string(m.Tree[m.Values[i].TreeIndex]))
+
+		value, err := m.valueAt(i)
+		if err != nil {
+			return err
+		}
+		if values[i], err = goLiteral(reflect.ValueOf(&value).Elem()); err != nil {
+			return fmt.Errorf("value %d: %w", i, err)
+		}
+
+		proof, err := m.GetProof(i)
+		if err != nil {
+			return err
+		}
+		proofs[i] = make([]string, len(proof))
+		for j, node := range proof {
+			proofs[i][j] = strconv.Quote(string(node))
+		}
+	}
+
+	g.printf("// %sLeafHashes holds the leaf hash of each value, in value order.\n", name)
+	g.printf("var %sLeafHashes = []merkletree.HexString{\n", name)
+	g.list(1, leafHashes)
+	g.printf("}\n\n")
+
+	g.printf("// %sValues holds the values of the tree, in value order.\n", name)
+	g.printf("var %sValues = []%s{\n", name, valueType)
+	for _, value := range values {
+		g.item(1, value)
+	}
+	g.printf("}\n\n")
+
+	g.printf("// %sProofs holds the proof of each value, in value order.\n", name)
+	g.printf("var %sProofs = [][]merkletree.HexString{\n", name)
+	for _, proof := range proofs {
+		if len(proof) == 0 {
+			g.printf("\t{},\n")
+			continue
+		}
+		g.printf("\t{\n")
+		g.list(2, proof)
+		g.printf("\t},\n")
+	}
+	g.printf("}\n\n")
+
+	g.printf("// %sVerify reports whether proof proves leafHash under %sRoot.\n", name, name)
+	g.printf("func %sVerify(\n\tleafHash merkletree.HexString,\n\tproof []merkletree.HexString,\n) bool {\n", name)
+	g.printf("\tnodes := make([]merkletree.BytesLike, len(proof))\n")
+	g.printf("\tfor i, node := range proof {\n\t\tnodes[i] = node\n\t}\n")
+	g.printf("\troot, err := merkletree.ProcessProof(leafHash, nodes, %s)\n", nodeHash)
+	g.printf("\treturn err == nil && root == %sRoot\n}\n", name)
+
+	source, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("generated source does not parse: %w", err)
+	}
+	for n, line := range strings.Split(string(source), "\n") {
+		if lineWidth(line) > width {
+			return &OptionError{Option: "LineWidth", Value: width, Reason: fmt.Sprintf("line %d of the generated source is %d columns", n+1, lineWidth(line))}
+		}
+	}
+	_, err = w.Write(source)
+	return err
+}
+
+// goSourceWriter accumulates generated source, wrapping lists at a width.
+type goSourceWriter struct {
+	buf   bytes.Buffer
+	width int
+}
+
+// printf appends formatted source.
+func (g *goSourceWriter) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// list writes comma-separated items at the given indent, as many per line as fit.
+func (g *goSourceWriter) list(indent int, items []string) {
+	line := ""
+	for _, item := range items {
+		next := item + ","
+		if line != "" {
+			next = line + " " + item + ","
+		}
+		if line != "" && indent*goSourceTabWidth+len(next) > g.width {
+			g.printf("%s%s\n", strings.Repeat("\t", indent), line)
+			next = item + ","
+		}
+		line = next
+	}
+	if line != "" {
+		g.printf("%s%s\n", strings.Repeat("\t", indent), line)
+	}
+}
+
+// item writes one list item on its own lines. A string literal too long for
+// the width is split into concatenated pieces.
+func (g *goSourceWriter) item(indent int, literal string) {
+	tabs := strings.Repeat("\t", indent)
+	room := g.width - indent*goSourceTabWidth - 1
+	if len(literal) <= room || literal[0] != '"' {
+		g.printf("%s%s,\n", tabs, literal)
+		return
+	}
+
+	s, err := strconv.Unquote(literal)
+	if err != nil {
+		g.printf("%s%s,\n", tabs, literal)
+		return
+	}
+	// Continuation lines are indented once more by gofmt; leave room for " +"
+	pieces := splitQuoted(s, g.width-(indent+1)*goSourceTabWidth-2)
+	for i, piece := range pieces {
+		switch {
+		case i == 0:
+			g.printf("%s%s +\n", tabs, piece)
+		case i == len(pieces)-1:
+			g.printf("%s\t%s,\n", tabs, piece)
+		default:
+			g.printf("%s\t%s +\n", tabs, piece)
+		}
+	}
+}
+
+// splitQuoted quotes s as consecutive literals of at most room columns each.
+// Pieces may split a multi-byte character; concatenation restores it.
+func splitQuoted(s string, room int) []string {
+	var pieces []string
+	for len(s) > 0 {
+		n := len(s)
+		for n > 1 && len(strconv.Quote(s[:n])) > room {
+			n = n * 3 / 4
+		}
+		for n < len(s) && len(strconv.Quote(s[:n+1])) <= room {
+			n++
+		}
+		pieces = append(pieces, strconv.Quote(s[:n]))
+		s = s[n:]
+	}
+	return pieces
+}
+
+// lineWidth returns the columns of a line, with tabs counting goSourceTabWidth.
+func lineWidth(line string) int {
+	return len(line) + strings.Count(line, "\t")*(goSourceTabWidth-1)
+}
+
+// goTypeName returns the Go type expression for t in generated code.
+func goTypeName(t reflect.Type) (string, error) {
+	switch {
+	case t == reflect.TypeFor[BytesLike]():
+		return "merkletree.BytesLike", nil
+	case t == reflect.TypeFor[HexString]():
+		return "merkletree.HexString", nil
+	case t.Kind() == reflect.Interface && t.NumMethod() == 0:
+		return "any", nil
+	case t.Name() != "" && t.PkgPath() == "":
+		return t.Name(), nil
+	case t.Name() == "" && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array):
+		elem, err := goTypeName(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		if t.Kind() == reflect.Array {
+			return fmt.Sprintf("[%d]%s", t.Len(), elem), nil
+		}
+		return "[]" + elem, nil
+	}
+	return "", fmt.Errorf("%w: values of type %v cannot be written as Go literals", ErrInvalidValue, t)
+}
+
+// goLiteral returns a Go literal for v, which has a type goTypeName accepts.
+// Values held in an interface are written with their dynamic type whenever an
+// untyped constant would default to a different one.
+func goLiteral(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return "nil", nil
+		}
+		elem := v.Elem()
+		literal, err := goLiteral(elem)
+		if err != nil {
+			return "", err
+		}
+		switch elem.Type() {
+		case reflect.TypeFor[string](), reflect.TypeFor[bool](), reflect.TypeFor[int](), reflect.TypeFor[float64]():
+			return literal, nil
+		}
+		if kind := elem.Kind(); kind == reflect.Slice || kind == reflect.Array {
+			return literal, nil // Composite literals carry their type
+		}
+		name, err := goTypeName(elem.Type())
+		if err != nil {
+			return "", err
+		}
+		return name + "(" + literal + ")", nil
+	case reflect.String:
+		return strconv.Quote(v.String()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		literal := strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())
+		if !strings.ContainsAny(literal, ".eE") {
+			literal += ".0" // Keep it a float constant
+		}
+		return literal, nil
+	case reflect.Slice, reflect.Array:
+		name, err := goTypeName(v.Type())
+		if err != nil {
+			return "", err
+		}
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return name + "(nil)", nil
+		}
+		elems := make([]string, v.Len())
+		for i := range elems {
+			if elems[i], err = goLiteral(v.Index(i)); err != nil {
+				return "", err
+			}
+		}
+		return name + "{" + strings.Join(elems, ", ") + "}", nil
+	}
+	return "", fmt.Errorf("%w: values of type %v cannot be written as Go literals", ErrInvalidValue, v.Type())
+}
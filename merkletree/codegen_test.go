@@ -0,0 +1,205 @@
+package merkletree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// codegenTestTree returns a sorted standard tree over n values.
+func codegenTestTree(t *testing.T, n int) *StandardMerkleTree[string] {
+	t.Helper()
+	values := make([]string, n)
+	for i := range values {
+		values[i] = fmt.Sprintf("0x%040x", i+1)
+	}
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(true)})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	return tree
+}
+
+func TestExportGoSourceFormatting(t *testing.T) {
+	tree := codegenTestTree(t, 16)
+
+	for _, width := range []int{90, DefaultGoSourceLineWidth, 200} {
+		var buf bytes.Buffer
+		err := tree.ExportGoSourceWithOptions(&buf, GoSourceOptions{Package: "allowlist", VarName: "Allowlist", LineWidth: width})
+		if err != nil {
+			t.Fatalf("Width %d: ExportGoSource failed: %v", width, err)
+		}
+		for n, line := range strings.Split(buf.String(), "\n") {
+			if lineWidth(line) > width {
+				t.Errorf("Width %d: line %d is %d columns: %s", width, n+1, lineWidth(line), line)
+			}
+		}
+		if !strings.HasPrefix(buf.String(), "// Code generated by gomerkle; DO NOT EDIT.") {
+			t.Errorf("Generated source should start with the generated-code marker")
+		}
+	}
+
+	// A width too narrow for an indented proof node cannot be honoured
+	var optErr *OptionError
+	err := tree.ExportGoSourceWithOptions(&bytes.Buffer{}, GoSourceOptions{Package: "allowlist", VarName: "Allowlist", LineWidth: 80})
+	if !errors.As(err, &optErr) || optErr.Option != "LineWidth" {
+		t.Errorf("Expected a LineWidth *OptionError, got %v", err)
+	}
+}
+
+func TestExportGoSourceLongValues(t *testing.T) {
+	long := strings.Repeat("long value é ", 30)
+	tree, err := NewStandardMerkleTree([]string{long, "short"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tree.ExportGoSource(&buf, "allowlist", "list"); err != nil {
+		t.Fatalf("ExportGoSource failed: %v", err)
+	}
+	for n, line := range strings.Split(buf.String(), "\n") {
+		if lineWidth(line) > DefaultGoSourceLineWidth {
+			t.Errorf("Line %d is %d columns", n+1, lineWidth(line))
+		}
+	}
+}
+
+func TestExportGoSourceOptions(t *testing.T) {
+	tree := codegenTestTree(t, 4)
+	err := tree.ExportGoSource(&bytes.Buffer{}, "my-package", "")
+	var optErr *OptionError
+	if !errors.As(err, &optErr) || !strings.Contains(err.Error(), "Package") || !strings.Contains(err.Error(), "VarName") {
+		t.Errorf("Expected Package and VarName option errors, got %v", err)
+	}
+
+	custom, err := NewSimpleMerkleTree([]BytesLike{fmt.Sprintf("0x%064x", 1)}, SimpleMerkleTreeOptions{
+		NodeHash: func(a, b BytesLike) HexString { return StandardNodeHash(b, a) },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if err := custom.ExportGoSource(&bytes.Buffer{}, "allowlist", "List"); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("Custom node hashes cannot be generated, got %v", err)
+	}
+
+	type point struct{ X, Y int }
+	points, err := NewStandardMerkleTree([]point{{1, 2}}, MerkleTreeOptions{})
+	if err == nil {
+		if err := points.ExportGoSource(&bytes.Buffer{}, "allowlist", "List"); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("Struct values cannot be written as literals, got %v", err)
+		}
+	}
+}
+
+// goTool returns the go command of the running toolchain, or skips the test.
+func goTool(t *testing.T) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping build of generated code in short mode")
+	}
+	gobin := filepath.Join(runtime.GOROOT(), "bin", "go")
+	if _, err := os.Stat(gobin); err != nil {
+		t.Skipf("go command not found: %v", err)
+	}
+	return gobin
+}
+
+func TestExportGoSourceCompiles(t *testing.T) {
+	gobin := goTool(t)
+	repo, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	standard := codegenTestTree(t, 16)
+	simple, err := NewSimpleMerkleTree([]BytesLike{fmt.Sprintf("0x%064x", 7), []byte{0x01, 0x02}, fmt.Sprintf("0x%064x", 9)},
+		SimpleMerkleTreeOptions{HashAlgorithm: HashAlgorithmSHA256})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module gentest\n\ngo 1.23.0\n\nrequire github.com/smeneguz/GoMerkle v0.0.0\n\n" +
+			"replace github.com/smeneguz/GoMerkle => " + repo + "\n",
+		"main.go": `package main
+
+import (
+	"fmt"
+
+	"gentest/allowlist"
+	"github.com/smeneguz/GoMerkle/merkletree"
+)
+
+func main() {
+	for i := range allowlist.AllowlistValues {
+		leaf := merkletree.StandardLeafHash(allowlist.AllowlistValues[i])
+		if leaf != allowlist.AllowlistLeafHashes[i] || !allowlist.AllowlistVerify(leaf, allowlist.AllowlistProofs[i]) {
+			panic(fmt.Sprintf("standard value %d does not verify", i))
+		}
+	}
+	if allowlist.AllowlistVerify(allowlist.AllowlistLeafHashes[0], allowlist.AllowlistProofs[1]) {
+		panic("wrong proof verified")
+	}
+	for i := range allowlist.SimpleValues {
+		leaf := merkletree.FormatLeaf(allowlist.SimpleValues[i])
+		if leaf != allowlist.SimpleLeafHashes[i] || !allowlist.SimpleVerify(leaf, allowlist.SimpleProofs[i]) {
+			panic(fmt.Sprintf("simple value %d does not verify", i))
+		}
+	}
+	fmt.Println("ok", len(allowlist.AllowlistValues), allowlist.AllowlistRoot)
+}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	goSum, err := os.ReadFile(filepath.Join(repo, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.sum"), goSum, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	pkgDir := filepath.Join(dir, "allowlist")
+	if err := os.Mkdir(pkgDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var standardSrc, simpleSrc bytes.Buffer
+	if err := standard.ExportGoSource(&standardSrc, "allowlist", "Allowlist"); err != nil {
+		t.Fatalf("ExportGoSource failed: %v", err)
+	}
+	if err := simple.ExportGoSource(&simpleSrc, "allowlist", "Simple"); err != nil {
+		t.Fatalf("ExportGoSource failed: %v", err)
+	}
+	for name, src := range map[string][]byte{"allowlist_gen.go": standardSrc.Bytes(), "simple_gen.go": simpleSrc.Bytes()} {
+		if err := os.WriteFile(filepath.Join(pkgDir, name), src, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(gobin, "run", "-mod=mod", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=", "GOPROXY=off", "GOWORK=off")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Generated code does not build or verify: %v\n%s", err, out)
+	}
+	if want := fmt.Sprintf("ok 16 %s", standard.Root()); strings.TrimSpace(string(out)) != want {
+		t.Errorf("Output = %q, want %q", out, want)
+	}
+
+	gofmt := exec.Command(filepath.Join(runtime.GOROOT(), "bin", "gofmt"), "-l", pkgDir)
+	if out, err := gofmt.CombinedOutput(); err != nil || len(out) > 0 {
+		t.Errorf("Generated code is not gofmt-clean: %v %s", err, out)
+	}
+}
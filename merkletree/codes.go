@@ -0,0 +1,89 @@
+package merkletree
+
+import "errors"
+
+// Error codes are stable identifiers for the errors of this package, for
+// API responses and exit statuses that must not depend on error messages.
+// Each sentinel error has its own code, which never changes once released;
+// typed errors carry the code of the sentinel they wrap.
+const (
+	CodeInvalidIndex          = "MERKLE_INVALID_INDEX"
+	CodeValueNotFound         = "MERKLE_VALUE_NOT_FOUND"
+	CodeInvalidProof          = "MERKLE_INVALID_PROOF"
+	CodeEmptyTree             = "MERKLE_EMPTY_TREE"
+	CodeInvalidNode           = "MERKLE_INVALID_NODE"
+	CodeNotLeafNode           = "MERKLE_NOT_LEAF_NODE"
+	CodeInvalidMultiProof     = "MERKLE_INVALID_MULTIPROOF"
+	CodeRootHasNoParent       = "MERKLE_ROOT_HAS_NO_PARENT"
+	CodeRootHasNoSibling      = "MERKLE_ROOT_HAS_NO_SIBLING"
+	CodeAmbiguousValue        = "MERKLE_AMBIGUOUS_VALUE"
+	CodeInvalidOptions        = "MERKLE_INVALID_OPTIONS"
+	CodeTooManyLeaves         = "MERKLE_TOO_MANY_LEAVES"
+	CodeInvalidValue          = "MERKLE_INVALID_VALUE"
+	CodeInvalidWitnessOptions = "MERKLE_INVALID_WITNESS_OPTIONS"
+	CodePairOrderMismatch     = "MERKLE_PAIR_ORDER_MISMATCH"
+	CodeFieldOverflow         = "MERKLE_FIELD_OVERFLOW"
+	CodeInvalidHashPrefix     = "MERKLE_INVALID_HASH_PREFIX"
+	CodeInvalidDump           = "MERKLE_INVALID_DUMP"
+	CodeSelfTest              = "MERKLE_SELF_TEST"
+	CodeQuarantined           = "MERKLE_QUARANTINED"
+	CodeInvalidCalldata       = "MERKLE_INVALID_CALLDATA"
+	CodeValuesDropped         = "MERKLE_VALUES_DROPPED"
+	CodeMissingCapability     = "MERKLE_MISSING_CAPABILITY"
+	CodeTruncatedDump         = "MERKLE_TRUNCATED_DUMP"
+	CodeCorruptTree           = "MERKLE_CORRUPT_TREE"
+	CodeCheckpointMismatch    = "MERKLE_CHECKPOINT_MISMATCH"
+	CodeProbeLimit            = "MERKLE_PROBE_LIMIT"
+	CodeRecordFailed          = "MERKLE_RECORD_FAILED"
+	CodeInvalidRoot           = "MERKLE_INVALID_ROOT"
+	CodeInvalidPageToken      = "MERKLE_INVALID_PAGE_TOKEN"
+	CodeResourceLimit         = "MERKLE_RESOURCE_LIMIT"
+	CodeSeedWithheld          = "MERKLE_SEED_WITHHELD"
+	CodeInvalidPackedProof    = "MERKLE_INVALID_PACKED_PROOF"
+	CodeValueTooLarge         = "MERKLE_VALUE_TOO_LARGE"
+	CodeInvalidLeafType       = "MERKLE_INVALID_LEAF_TYPE"
+	CodeHashFailed            = "MERKLE_HASH_FAILED"
+	CodeInvalidLeafEncoding   = "MERKLE_INVALID_LEAF_ENCODING"
+	CodeOddLengthHex          = "MERKLE_ODD_LENGTH_HEX"
+	CodeUnsortedTree          = "MERKLE_UNSORTED_TREE"
+	CodeValueIncluded         = "MERKLE_VALUE_INCLUDED"
+)
+
+// CodedError is implemented by errors that carry an error code.
+type CodedError interface {
+	error
+	ErrorCode() string
+}
+
+// Code returns the code of the nearest error in err's chain that carries
+// one, or "" if none does or err is nil. In an errors.Join the first coded
+// error wins.
+func Code(err error) string {
+	var coded CodedError
+	if errors.As(err, &coded) {
+		return coded.ErrorCode()
+	}
+	return ""
+}
+
+// NewCodedError returns a sentinel error with the given code and message.
+// Packages built on this one use it to give their own sentinels codes.
+func NewCodedError(code, message string) error {
+	return &codedError{code: code, message: message}
+}
+
+// codedError is a sentinel error with a code.
+type codedError struct {
+	code    string
+	message string
+}
+
+// Error implements the error interface.
+func (e *codedError) Error() string {
+	return e.message
+}
+
+// ErrorCode implements CodedError.
+func (e *codedError) ErrorCode() string {
+	return e.code
+}
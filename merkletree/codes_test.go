@@ -0,0 +1,200 @@
+package merkletree
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// codedSentinels lists every exported sentinel error. TestErrorCodesCoverage
+// fails when one is missing, so a new sentinel cannot ship without a code.
+var codedSentinels = map[string]error{
+	"ErrInvalidIndex":          ErrInvalidIndex,
+	"ErrValueNotFound":         ErrValueNotFound,
+	"ErrInvalidProof":          ErrInvalidProof,
+	"ErrEmptyTree":             ErrEmptyTree,
+	"ErrInvalidNode":           ErrInvalidNode,
+	"ErrNotLeafNode":           ErrNotLeafNode,
+	"ErrInvalidMultiProof":     ErrInvalidMultiProof,
+	"ErrRootHasNoParent":       ErrRootHasNoParent,
+	"ErrRootHasNoSibling":      ErrRootHasNoSibling,
+	"ErrAmbiguousValue":        ErrAmbiguousValue,
+	"ErrInvalidOptions":        ErrInvalidOptions,
+	"ErrTooManyLeaves":         ErrTooManyLeaves,
+	"ErrInvalidValue":          ErrInvalidValue,
+	"ErrInvalidWitnessOptions": ErrInvalidWitnessOptions,
+	"ErrPairOrderMismatch":     ErrPairOrderMismatch,
+	"ErrFieldOverflow":         ErrFieldOverflow,
+	"ErrInvalidHashPrefix":     ErrInvalidHashPrefix,
+	"ErrInvalidDump":           ErrInvalidDump,
+	"ErrSelfTest":              ErrSelfTest,
+	"ErrQuarantined":           ErrQuarantined,
+	"ErrInvalidCalldata":       ErrInvalidCalldata,
+	"ErrValuesDropped":         ErrValuesDropped,
+	"ErrMissingCapability":     ErrMissingCapability,
+	"ErrTruncatedDump":         ErrTruncatedDump,
+	"ErrInvalidTree":           ErrInvalidTree,
+	"ErrCheckpointMismatch":    ErrCheckpointMismatch,
+	"ErrProbeLimit":            ErrProbeLimit,
+	"ErrRecordFailed":          ErrRecordFailed,
+	"ErrInvalidRoot":           ErrInvalidRoot,
+	"ErrInvalidPageToken":      ErrInvalidPageToken,
+	"ErrResourceLimit":         ErrResourceLimit,
+	"ErrSeedWithheld":          ErrSeedWithheld,
+	"ErrInvalidPackedProof":    ErrInvalidPackedProof,
+	"ErrValueTooLarge":         ErrValueTooLarge,
+	"ErrInvalidLeafType":       ErrInvalidLeafType,
+	"ErrHashFailed":            ErrHashFailed,
+	"ErrInvalidLeafEncoding":   ErrInvalidLeafEncoding,
+	"ErrOddLengthHex":          ErrOddLengthHex,
+	"ErrUnsortedTree":          ErrUnsortedTree,
+	"ErrValueIncluded":         ErrValueIncluded,
+}
+
+// codedTypes holds an instance of every exported error type, likewise.
+var codedTypes = map[string]error{
+	"OptionError":        &OptionError{Option: "MaxLeaves", Value: -1, Reason: "must not be negative"},
+	"InputError":         &InputError{Index: 3, Err: ErrInvalidNode},
+	"DriftError":         &DriftError{Drift: DriftDoubleHash, Detail: "leaf hashed once"},
+	"ResourceLimitError": &ResourceLimitError{Limit: "MaxBuildDuration"},
+	"FormatError":        &FormatError{Format: "standard-v9"},
+	"ValueSizeError":     &ValueSizeError{Index: 2, Size: 1 << 20, Limit: 1024},
+}
+
+// exportedErrors returns the names of the exported Err variables and of the
+// exported types with an Error method declared in the package's sources.
+func exportedErrors(t *testing.T) (sentinels, types []string) {
+	t.Helper()
+	fset := token.NewFileSet()
+	packages, err := parser.ParseDir(fset, ".", func(info fs.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse package: %v", err)
+	}
+	for _, file := range packages["merkletree"].Files {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				if decl.Tok != token.VAR {
+					continue
+				}
+				for _, spec := range decl.Specs {
+					for _, name := range spec.(*ast.ValueSpec).Names {
+						if strings.HasPrefix(name.Name, "Err") {
+							sentinels = append(sentinels, name.Name)
+						}
+					}
+				}
+			case *ast.FuncDecl:
+				if decl.Recv == nil || decl.Name.Name != "Error" {
+					continue
+				}
+				recv := decl.Recv.List[0].Type
+				if star, ok := recv.(*ast.StarExpr); ok {
+					recv = star.X
+				}
+				if ident, ok := recv.(*ast.Ident); ok && ast.IsExported(ident.Name) {
+					types = append(types, ident.Name)
+				}
+			}
+		}
+	}
+	return sentinels, types
+}
+
+func TestErrorCodesCoverage(t *testing.T) {
+	sentinels, types := exportedErrors(t)
+	if len(sentinels) == 0 || len(types) == 0 {
+		t.Fatalf("Found %d sentinels and %d error types; the parser is looking in the wrong place", len(sentinels), len(types))
+	}
+	for _, name := range sentinels {
+		if _, ok := codedSentinels[name]; !ok {
+			t.Errorf("%s is not in codedSentinels; give it a code and add it", name)
+		}
+	}
+	for _, name := range types {
+		if _, ok := codedTypes[name]; !ok {
+			t.Errorf("%s is not in codedTypes; give it an ErrorCode method and add it", name)
+		}
+	}
+}
+
+func TestErrorCodesUnique(t *testing.T) {
+	format := regexp.MustCompile(`^MERKLE_[A-Z0-9_]+$`)
+	seen := make(map[string]string)
+	for name, err := range codedSentinels {
+		code := Code(err)
+		if !format.MatchString(code) {
+			t.Errorf("%s has code %q, want MERKLE_UPPER_SNAKE_CASE", name, code)
+		}
+		if other, ok := seen[code]; ok {
+			t.Errorf("%s and %s share code %s", name, other, code)
+		}
+		seen[code] = name
+	}
+
+	// A typed error carries the code of the sentinel it wraps
+	for name, err := range codedTypes {
+		if _, ok := err.(CodedError); !ok {
+			t.Errorf("%s does not implement CodedError", name)
+			continue
+		}
+		if got, want := Code(err), Code(errors.Unwrap(err)); got != want {
+			t.Errorf("%s has code %s, but wraps an error with code %s", name, got, want)
+		}
+	}
+}
+
+// TestErrorCodesStable pins the released codes, which clients match on.
+func TestErrorCodesStable(t *testing.T) {
+	for err, want := range map[error]string{
+		ErrValueNotFound: "MERKLE_VALUE_NOT_FOUND",
+		ErrInvalidProof:  "MERKLE_INVALID_PROOF",
+		ErrInvalidTree:   "MERKLE_CORRUPT_TREE",
+		ErrInvalidDump:   "MERKLE_INVALID_DUMP",
+		ErrInvalidIndex:  "MERKLE_INVALID_INDEX",
+	} {
+		if got := Code(err); got != want {
+			t.Errorf("Code(%v) = %s, want %s", err, got, want)
+		}
+	}
+}
+
+func TestCode(t *testing.T) {
+	if code := Code(nil); code != "" {
+		t.Errorf("Code(nil) = %q", code)
+	}
+	if code := Code(errors.New("plain")); code != "" {
+		t.Errorf("Code of an uncoded error = %q", code)
+	}
+	if code := Code(fmt.Errorf("loading: %w", ErrTruncatedDump)); code != CodeTruncatedDump {
+		t.Errorf("Code of a wrapped sentinel = %q", code)
+	}
+	if code := Code(&InputError{Err: errors.New("odd")}); code != CodeInvalidValue {
+		t.Errorf("Code of an InputError without a coded cause = %q", code)
+	}
+
+	// The nearest coded error wins, and the first of a join
+	_, err := NewStandardMerkleTree([]string{"a"}, MerkleTreeOptions{MaxLeaves: -1, MaxInputErrors: -1})
+	if code := Code(err); code != CodeInvalidOptions {
+		t.Errorf("Code of joined option errors = %q (%v)", code, err)
+	}
+	if code := Code(errors.Join(errors.New("plain"), ErrEmptyTree, ErrInvalidProof)); code != CodeEmptyTree {
+		t.Errorf("Code of a join = %q", code)
+	}
+
+	tree, err := NewStandardMerkleTree([]string{"a", "b"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if _, err := tree.GetProof("c"); Code(err) != CodeValueNotFound {
+		t.Errorf("Code of a missing value = %q (%v)", Code(err), err)
+	}
+}
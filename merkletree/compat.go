@@ -0,0 +1,102 @@
+package merkletree
+
+import (
+	"fmt"
+)
+
+// CompatibilityMode selects the version of the tree-building rules a tree
+// follows. Roots depend on it, so a tree only moves to newer rules when its
+// caller asks for them.
+type CompatibilityMode int
+
+const (
+	// CompatUnspecified builds exactly like CompatV0, and the tree reports a
+	// deprecation warning from Warnings. It is the zero value, so existing
+	// callers keep their roots.
+	CompatUnspecified CompatibilityMode = iota
+
+	// CompatV0 keeps every rule of the 0.x releases: leaves are hashed once
+	// over the packed value, leaves stay in input order unless SortLeaves is
+	// true, which a nil SortLeaves is not, and sorted leaves are laid out
+	// first leaf first.
+	CompatV0
+
+	// CompatLatest follows OpenZeppelin's StandardMerkleTree: standard tree
+	// leaves are hashed twice with OpenZeppelinLeafHash, leaf hashes are
+	// compared byte-wise, leaves are sorted unless PreserveOrder is set, and
+	// the bottom level is laid out last leaf first as OpenZeppelin does.
+	// SortLeaves is ignored and SortDescending is rejected. Simple tree
+	// values are hashed over their bytes with BytesLeafHash, so hex strings
+	// hash as the bytes they spell.
+	CompatLatest
+)
+
+// compatUnspecifiedWarning is reported by trees built without a CompatibilityMode.
+const compatUnspecifiedWarning = "no CompatibilityMode set; building with CompatV0 rules, " +
+	"which will stop being the default (set CompatV0 to keep this root, or CompatLatest to migrate)"
+
+// String returns the name of the mode.
+func (c CompatibilityMode) String() string {
+	switch c {
+	case CompatUnspecified:
+		return "unspecified"
+	case CompatV0:
+		return "v0"
+	case CompatLatest:
+		return "latest"
+	default:
+		return fmt.Sprintf("CompatibilityMode(%d)", int(c))
+	}
+}
+
+// OpenZeppelinLeafHash hashes a value the way OpenZeppelin's StandardMerkleTree
+// does, keccak256(keccak256(encoded)), with the packed encoding of
+// StandardLeafHash. Values that are already ABI-encoded, such as the bytes of
+// abi.encode(address, uint256), hash to OpenZeppelin's leaf.
+func OpenZeppelinLeafHash[T any](value T) HexString {
+	inner, err := keccak256HashedData(value)
+	if err != nil {
+		return HexString("")
+	}
+	outer, err := keccak256HashedData(inner)
+	if err != nil {
+		return HexString("")
+	}
+	hash, err := ToHex(outer)
+	if err != nil {
+		return HexString("")
+	}
+	return hash
+}
+
+// CompareBytes compares two values byte by byte, like bytes.Compare.
+//
+// Deprecated: Compare now compares byte-wise too; use it instead.
+func CompareBytes(a BytesLike, b BytesLike) (int, error) {
+	return Compare(a, b)
+}
+
+// RootUnderMode returns the root of a standard tree over values built with
+// mode and otherwise zero options, so that a migration can compare the root
+// users have today with the one they will have after moving to CompatLatest.
+func RootUnderMode[T any](values []T, mode CompatibilityMode) (HexString, error) {
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{Compatibility: mode})
+	if err != nil {
+		return "", err
+	}
+	return tree.Root(), nil
+}
+
+// Warnings returns the warnings raised while the tree was built, such as a
+// missing CompatibilityMode.
+func (m *MerkleTreeImpl[T]) Warnings() []string {
+	return m.warnings
+}
+
+// compatWarnings returns the warnings of building with these options.
+func (o MerkleTreeOptions) compatWarnings() []string {
+	if o.Compatibility == CompatUnspecified {
+		return []string{compatUnspecifiedWarning}
+	}
+	return nil
+}
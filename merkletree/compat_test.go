@@ -0,0 +1,450 @@
+package merkletree
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// updateGolden rewrites golden files from the current outputs.
+var updateGolden = flag.Bool("update", false, "rewrite testdata golden files")
+
+// compatV0Golden holds the roots every CompatV0 case must keep building.
+const compatV0Golden = "testdata/compat_v0.json"
+
+// compatCase is one tree whose V0 root is pinned.
+type compatCase struct {
+	tree       string // standard, simple or simple-sha256
+	values     string // strings, bytes32 or uint64
+	n          int
+	sort       bool
+	descending bool
+}
+
+// name identifies the case in the golden file.
+func (c compatCase) name() string {
+	order := "insertion"
+	switch {
+	case c.sort && c.descending:
+		order = "descending"
+	case c.sort:
+		order = "ascending"
+	}
+	return fmt.Sprintf("%s/%s/%d/%s", c.tree, c.values, c.n, order)
+}
+
+// compatCases lists every combination of tree type, value kind, size and order.
+func compatCases() []compatCase {
+	var cases []compatCase
+	for _, tree := range []string{"standard", "simple", "simple-sha256"} {
+		for _, values := range []string{"strings", "bytes32", "uint64"} {
+			for _, n := range []int{1, 2, 3, 5, 8, 13} {
+				cases = append(cases,
+					compatCase{tree, values, n, false, false},
+					compatCase{tree, values, n, true, false},
+					compatCase{tree, values, n, true, true},
+				)
+			}
+		}
+	}
+	return cases
+}
+
+// compatValues returns the n values of a kind.
+func compatValues(kind string, n int) []any {
+	values := make([]any, n)
+	for i := range values {
+		switch kind {
+		case "strings":
+			values[i] = fmt.Sprintf("value-%d", i)
+		case "bytes32":
+			sum := sha256.Sum256([]byte{byte(i)})
+			values[i] = sum[:]
+		case "uint64":
+			values[i] = uint64(i) * 7919
+		}
+	}
+	return values
+}
+
+// buildCompatCase builds the tree of a case with the given options and returns its root.
+func buildCompatCase(c compatCase, options MerkleTreeOptions) (HexString, error) {
+	options.SortLeaves = Bool(c.sort)
+	options.SortDescending = c.descending
+	values := compatValues(c.values, c.n)
+
+	switch c.tree {
+	case "standard":
+		tree, err := NewStandardMerkleTree(values, options)
+		if err != nil {
+			return "", err
+		}
+		return tree.Root(), nil
+	default:
+		leaves := make([]BytesLike, len(values))
+		for i, v := range values {
+			leaves[i] = v
+		}
+		simpleOptions := SimpleMerkleTreeOptions{MerkleTreeOptions: options}
+		if c.tree == "simple-sha256" {
+			simpleOptions.HashAlgorithm = HashAlgorithmSHA256
+		}
+		tree, err := NewSimpleMerkleTree(leaves, simpleOptions)
+		if err != nil {
+			return "", err
+		}
+		return tree.Root(), nil
+	}
+}
+
+// readCompatGolden reads the pinned V0 roots by case name.
+func readCompatGolden(t *testing.T) map[string]HexString {
+	t.Helper()
+	data, err := os.ReadFile(compatV0Golden)
+	if err != nil {
+		t.Fatalf("Failed to read golden roots: %v", err)
+	}
+	var golden map[string]HexString
+	if err := json.Unmarshal(data, &golden); err != nil {
+		t.Fatalf("Failed to parse golden roots: %v", err)
+	}
+	return golden
+}
+
+func TestCompatV0Golden(t *testing.T) {
+	if *updateGolden {
+		golden := make(map[string]HexString)
+		for _, c := range compatCases() {
+			root, err := buildCompatCase(c, MerkleTreeOptions{})
+			if err != nil {
+				t.Fatalf("%s: %v", c.name(), err)
+			}
+			golden[c.name()] = root
+		}
+		data, err := json.MarshalIndent(golden, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(compatV0Golden, append(data, '\n'), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	golden := readCompatGolden(t)
+	if len(golden) != len(compatCases()) {
+		t.Fatalf("Golden file has %d roots for %d cases", len(golden), len(compatCases()))
+	}
+	for _, c := range compatCases() {
+		// Leaving the mode unset must build exactly what CompatV0 builds
+		for _, mode := range []CompatibilityMode{CompatUnspecified, CompatV0} {
+			root, err := buildCompatCase(c, MerkleTreeOptions{Compatibility: mode})
+			if err != nil {
+				t.Fatalf("%s under %v: %v", c.name(), mode, err)
+			}
+			if root != golden[c.name()] {
+				t.Errorf("%s under %v: root %s drifted from pinned %s", c.name(), mode, root, golden[c.name()])
+			}
+		}
+	}
+}
+
+// ozKeccak is keccak256 for the reference implementation below.
+func ozKeccak(data []byte) []byte {
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(data)
+	return hash.Sum(nil)
+}
+
+// ozRoot computes a root the way OpenZeppelin's StandardMerkleTree.of does,
+// from scratch: leaves are keccak256(keccak256(encoded)), sorted unless
+// sortLeaves is false, placed at tree[len-1-i], and each parent is the
+// keccak256 of its sorted children.
+func ozRoot(encoded [][]byte, sortLeaves bool) HexString {
+	leaves := make([][]byte, len(encoded))
+	for i, e := range encoded {
+		leaves[i] = ozKeccak(ozKeccak(e))
+	}
+	if sortLeaves {
+		slices.SortStableFunc(leaves, bytes.Compare)
+	}
+
+	tree := make([][]byte, 2*len(leaves)-1)
+	for i, leaf := range leaves {
+		tree[len(tree)-1-i] = leaf
+	}
+	for i := len(tree) - 1 - len(leaves); i >= 0; i-- {
+		a, b := tree[2*i+1], tree[2*i+2]
+		if bytes.Compare(a, b) > 0 {
+			a, b = b, a
+		}
+		tree[i] = ozKeccak(append(slices.Clone(a), b...))
+	}
+	return HexString("0x" + hex.EncodeToString(tree[0]))
+}
+
+// abiAddressAmount returns abi.encode(address, uint256) for an address made of
+// one repeated byte and a decimal amount.
+func abiAddressAmount(addressByte byte, amount string) []byte {
+	encoded := make([]byte, 64)
+	for i := 12; i < 32; i++ {
+		encoded[i] = addressByte
+	}
+	n, _ := new(big.Int).SetString(amount, 10)
+	n.FillBytes(encoded[32:])
+	return encoded
+}
+
+func TestCompatLatestOpenZeppelin(t *testing.T) {
+	// The allowlist of OpenZeppelin's merkle-tree README, ABI-encoded as
+	// StandardMerkleTree.of(values, ["address", "uint256"]) encodes it
+	readme := [][]byte{
+		abiAddressAmount(0x11, "5000000000000000000"),
+		abiAddressAmount(0x22, "2500000000000000000"),
+	}
+	if got := ozRoot(readme, true); got != "0xd4dee0beab2d53f2cc83e567171bd2820e49898130a22622b10ead383e90bd77" {
+		t.Fatalf("Reference implementation drifted: %s", got)
+	}
+
+	vectors := [][][]byte{readme}
+	for _, n := range []int{1, 3, 5, 8, 13} {
+		values := make([][]byte, n)
+		for i := range values {
+			values[i] = abiAddressAmount(byte(i+1), fmt.Sprint((i+1)*1000))
+		}
+		vectors = append(vectors, values)
+	}
+
+	for _, values := range vectors {
+		for _, preserveOrder := range []bool{false, true} {
+			tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{Compatibility: CompatLatest, PreserveOrder: preserveOrder})
+			if err != nil {
+				t.Fatalf("Failed to create tree: %v", err)
+			}
+			if want := ozRoot(values, !preserveOrder); tree.Root() != want {
+				t.Errorf("%d values, preserveOrder=%v: root %s, OpenZeppelin builds %s", len(values), preserveOrder, tree.Root(), want)
+			}
+			for i := range values {
+				proof, err := tree.GetProof(i)
+				if err != nil {
+					t.Fatalf("GetProof(%d) failed: %v", i, err)
+				}
+				if valid, err := tree.Verify(i, proof); err != nil || !valid {
+					t.Errorf("Proof of value %d does not verify: %v", i, err)
+				}
+			}
+		}
+	}
+}
+
+func TestCompatWarnings(t *testing.T) {
+	values := []string{"a", "b", "c"}
+	for _, tt := range []struct {
+		mode     CompatibilityMode
+		warnings int
+	}{
+		{CompatUnspecified, 1},
+		{CompatV0, 0},
+		{CompatLatest, 0},
+	} {
+		tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{Compatibility: tt.mode})
+		if err != nil {
+			t.Fatalf("Failed to create tree: %v", err)
+		}
+		if got := len(tree.Warnings()); got != tt.warnings {
+			t.Errorf("%v: %d warnings, want %d: %v", tt.mode, got, tt.warnings, tree.Warnings())
+		}
+	}
+
+	simple, err := NewSimpleMerkleTree([]BytesLike{"a"}, SimpleMerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if len(simple.Warnings()) != 1 || !strings.Contains(simple.Warnings()[0], "CompatV0") {
+		t.Errorf("Simple trees without a mode should warn, got %v", simple.Warnings())
+	}
+}
+
+func TestCompatOptions(t *testing.T) {
+	tests := []struct {
+		options MerkleTreeOptions
+		option  string
+	}{
+		{MerkleTreeOptions{Compatibility: 7}, "Compatibility"},
+		{MerkleTreeOptions{PreserveOrder: true}, "PreserveOrder"},
+		{MerkleTreeOptions{Compatibility: CompatV0, PreserveOrder: true}, "PreserveOrder"},
+		{MerkleTreeOptions{Compatibility: CompatLatest, SortLeaves: Bool(true), SortDescending: true}, "SortDescending"},
+	}
+	for _, tt := range tests {
+		var optErr *OptionError
+		if err := tt.options.Validate(); !errors.As(err, &optErr) || optErr.Option != tt.option {
+			t.Errorf("%+v: expected an *OptionError for %s, got %v", tt.options, tt.option, err)
+		}
+	}
+}
+
+func TestRootUnderMode(t *testing.T) {
+	values := []string{"alice", "bob", "charlie", "dave"}
+
+	v0, err := RootUnderMode(values, CompatV0)
+	if err != nil {
+		t.Fatalf("RootUnderMode failed: %v", err)
+	}
+	current, err := NewStandardMerkleTree(values, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if v0 != current.Root() {
+		t.Errorf("CompatV0 root %s should match the root built today, %s", v0, current.Root())
+	}
+
+	latest, err := RootUnderMode(values, CompatLatest)
+	if err != nil {
+		t.Fatalf("RootUnderMode failed: %v", err)
+	}
+	if latest == v0 {
+		t.Errorf("CompatLatest should change the root")
+	}
+	encoded := make([][]byte, len(values))
+	for i, v := range values {
+		encoded[i] = []byte(v)
+	}
+	if want := ozRoot(encoded, true); latest != want {
+		t.Errorf("CompatLatest root %s, want %s", latest, want)
+	}
+}
+
+func TestBytesLeafHash(t *testing.T) {
+	// keccak256("hello"), whichever way its bytes are given
+	const hello = "0x1c8aff950685c2ed4bc3174f3472287b56d9517b9c948127319a09a7a36deac8"
+	for _, value := range []BytesLike{"0x68656c6c6f", HexString("0x68656c6c6f"), []byte("hello")} {
+		if got := BytesLeafHash(value); got != hello {
+			t.Errorf("BytesLeafHash(%#v) = %s, want %s", value, got, hello)
+		}
+	}
+	// Strings that are not hex are hashed as text
+	if got, want := BytesLeafHash("0xzz"), StandardLeafHash("0xzz"); got != want {
+		t.Errorf("BytesLeafHash(0xzz) = %s, want %s", got, want)
+	}
+	// Integers keep their packed width, though ToBytes reads them minimally
+	if got, want := BytesLeafHash(uint64(5)), StandardLeafHash(uint64(5)); got != want {
+		t.Errorf("BytesLeafHash(uint64(5)) = %s, want %s", got, want)
+	}
+
+	values := []BytesLike{"0x1111", HexString("0x2222"), []byte{0x33, 0x33}}
+	latest, err := NewSimpleMerkleTree(values, SimpleMerkleTreeOptions{MerkleTreeOptions: MerkleTreeOptions{Compatibility: CompatLatest}})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	asBytes, err := NewSimpleMerkleTree([]BytesLike{[]byte{0x11, 0x11}, []byte{0x22, 0x22}, "0x3333"},
+		SimpleMerkleTreeOptions{MerkleTreeOptions: MerkleTreeOptions{Compatibility: CompatLatest}})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if latest.Root() != asBytes.Root() {
+		t.Errorf("Hex strings and bytes build different roots: %s, %s", latest.Root(), asBytes.Root())
+	}
+
+	// CompatV0 still hashes strings as text
+	v0, err := NewSimpleMerkleTree([]BytesLike{"0x1111", "0x2222", "0x3333"}, SimpleMerkleTreeOptions{MerkleTreeOptions: MerkleTreeOptions{Compatibility: CompatV0}})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if v0.Root() == latest.Root() || v0.Algorithm().LeafHash != HashKeccak256Packed {
+		t.Errorf("CompatV0 tree has root %s and leaf hash %q", v0.Root(), v0.Algorithm().LeafHash)
+	}
+
+	proof, err := latest.GetProof(BytesLike("0x2222"))
+	if err != nil {
+		t.Fatalf("GetProof failed: %v", err)
+	}
+	nodes := make([]BytesLike, len(proof))
+	for i, node := range proof {
+		nodes[i] = node
+	}
+	if ok, err := VerifySimpleMerkleTreeWithOptions(latest.Root(), []byte{0x22, 0x22}, nodes, SimpleVerifyOptions{Compatibility: CompatLatest}); err != nil || !ok {
+		t.Errorf("Proof does not verify under CompatLatest: %v", err)
+	}
+	if ok, _ := VerifySimpleMerkleTreeWithOptions(latest.Root(), "0x2222", nodes, SimpleVerifyOptions{}); ok {
+		t.Errorf("Proof verifies with the text leaf hash")
+	}
+
+	// The leaf hash survives a dump
+	data, err := latest.Dump()
+	if err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+	if data.LeafHashAlgorithm != HashKeccak256Bytes {
+		t.Errorf("Dump has leafHashAlgorithm %q, want %q", data.LeafHashAlgorithm, HashKeccak256Bytes)
+	}
+	loaded, _, err := LoadSimpleMerkleTree(data, nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Root() != latest.Root() || loaded.Algorithm() != latest.Algorithm() {
+		t.Errorf("Loaded tree has root %s (%+v), want %s (%+v)", loaded.Root(), loaded.Algorithm(), latest.Root(), latest.Algorithm())
+	}
+	if err := loaded.AppendLeaves([]BytesLike{"0x4444"}); err != nil {
+		t.Fatalf("AppendLeaves failed: %v", err)
+	}
+	if got, want := loaded.Tree[loaded.Values[3].TreeIndex], BytesLeafHash([]byte{0x44, 0x44}); got != want {
+		t.Errorf("Appended leaf hash %s, want %s", got, want)
+	}
+}
+
+func TestCompareBytes(t *testing.T) {
+	// Numerically equal, but the longer value sorts first byte-wise
+	for name, compare := range map[string]func(a, b BytesLike) (int, error){"Compare": Compare, "CompareBytes": CompareBytes} {
+		if c, _ := compare("0x00ff", "0xff"); c >= 0 {
+			t.Errorf("%s(0x00ff, 0xff) = %d, want negative", name, c)
+		}
+		if c, _ := compare("0x01", "0x02"); c != -1 {
+			t.Errorf("%s(0x01, 0x02) = %d, want -1", name, c)
+		}
+	}
+}
+
+// TestLeadingZeroHashes checks trees whose leaf and node hashes start with
+// zero bytes against the OpenZeppelin reference, which compares byte-wise.
+func TestLeadingZeroHashes(t *testing.T) {
+	var values [][]byte
+	zeros := 0
+	for amount := 1; zeros < 3; amount++ {
+		value := abiAddressAmount(0x11, fmt.Sprint(amount))
+		leading := ozKeccak(ozKeccak(value))[0] == 0
+		if leading {
+			zeros++
+		}
+		if leading || len(values) < 5 {
+			values = append(values, value)
+		}
+	}
+
+	for _, preserveOrder := range []bool{false, true} {
+		tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{Compatibility: CompatLatest, PreserveOrder: preserveOrder})
+		if err != nil {
+			t.Fatalf("Failed to create tree: %v", err)
+		}
+		if want := ozRoot(values, !preserveOrder); tree.Root() != want {
+			t.Errorf("preserveOrder=%v: root %s, OpenZeppelin builds %s", preserveOrder, tree.Root(), want)
+		}
+	}
+
+	// A node pair hashes in byte order even when one node has a zero prefix
+	zero := HexString("0x00" + strings.Repeat("ff", 31))
+	other := HexString("0x01" + strings.Repeat("00", 31))
+	want := HexString("0x" + hex.EncodeToString(ozKeccak(append(hexLeafBytes(zero), hexLeafBytes(other)...))))
+	if got := StandardNodeHash(other, zero); got != want {
+		t.Errorf("StandardNodeHash = %s, want %s", got, want)
+	}
+}
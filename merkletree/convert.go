@@ -0,0 +1,101 @@
+package merkletree
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ToSimple re-expresses the tree as a SimpleMerkleTree in pre-hashed mode,
+// like one loaded with LoadFromLeavesDump: value i is the leaf hash of value
+// i, leaf hashing is the identity, and the node array is the same, so the
+// root and every proof are unchanged. Proofs are requested and verified with
+// leaf hashes. The values themselves are not needed, so this works on a tree
+// whose values were dropped; FromSimpleWithValues attaches them again.
+// Metadata and value indices carry over; the issuance recorder does not.
+func (m *StandardMerkleTree[T]) ToSimple() (*SimpleMerkleTree, error) {
+	if len(m.Values) == 0 {
+		return nil, ErrEmptyTree
+	}
+	hashes := make([]BytesLike, len(m.Values))
+	for i, v := range m.Values {
+		hashes[i] = m.Tree[v.TreeIndex]
+	}
+
+	t := &SimpleMerkleTree{
+		MerkleTreeImpl: convertTree(&m.MerkleTreeImpl, hashes),
+		hashAlgorithm:  HashAlgorithmKeccak256,
+	}
+	t.LeafHash = identityLeafHash
+	t.algorithm.LeafHash = HashIdentity
+	return t, nil
+}
+
+// FromSimpleWithValues builds a StandardMerkleTree from s, typically one made
+// by ToSimple, with values attached again: values[i] becomes value i and must
+// hash with leafHash to the leaf of value i of s. The node array is reused as
+// it is, so the root and every proof are unchanged. If leafHash is nil, the
+// leaf hash of a standard tree built under the compatibility mode of s is
+// used.
+//
+// A value count that differs from that of s fails with ErrInvalidValue, and
+// values that hash to a different leaf with *InputError entries joined with
+// errors.Join, indexed by value index.
+func FromSimpleWithValues[T any](s *SimpleMerkleTree, values []T, leafHash func(T) HexString) (*StandardMerkleTree[T], error) {
+	if len(values) != len(s.Values) {
+		return nil, fmt.Errorf("%w: %d values for a tree of %d", ErrInvalidValue, len(values), len(s.Values))
+	}
+	leafHashName := HashCustom
+	if leafHash == nil {
+		leafHash, leafHashName = StandardLeafHash[T], HashKeccak256Packed
+		if s.compatibility == CompatLatest {
+			leafHash, leafHashName = OpenZeppelinLeafHash[T], HashKeccak256Double
+		}
+	}
+
+	var errs []error
+	for i, value := range values {
+		hash, want := leafHash(value), s.Tree[s.Values[i].TreeIndex]
+		if hash != want {
+			errs = append(errs, &InputError{Index: i, Err: fmt.Errorf("%w: %T hashes to %s, the leaf is %s", ErrInvalidValue, value, previewValue(hash), want)})
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	t := &StandardMerkleTree[T]{MerkleTreeImpl: convertTree(&s.MerkleTreeImpl, values)}
+	t.LeafHash = leafHash
+	t.algorithm.LeafHash = leafHashName
+	return t, nil
+}
+
+// convertTree returns a copy of the structure of from holding values, which
+// are indexed like the values of from, and indexed as from was configured.
+// The caller sets the leaf hash.
+func convertTree[T, U any](from *MerkleTreeImpl[T], values []U) MerkleTreeImpl[U] {
+	to := MerkleTreeImpl[U]{
+		Tree: slices.Clone(from.Tree),
+		Values: make([]struct {
+			Value     U
+			TreeIndex int
+		}, len(values)),
+		NodeHash:         from.NodeHash,
+		algorithm:        from.algorithm,
+		compatibility:    from.compatibility,
+		maxPrefixResults: from.maxPrefixResults,
+		maxValueBytes:    from.maxValueBytes,
+		duplicatePolicy:  from.duplicatePolicy,
+		metadata:         slices.Clone(from.metadata),
+		quarantined:      from.quarantined,
+		shuffleSeed:      slices.Clone(from.shuffleSeed),
+		warnings:         slices.Clone(from.warnings),
+	}
+	for i, value := range values {
+		to.Values[i].Value = value
+		to.Values[i].TreeIndex = from.Values[i].TreeIndex
+	}
+	to.indexes = from.indexes
+	to.invalidateIndexes()
+	return to
+}
@@ -0,0 +1,127 @@
+package merkletree
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestToSimpleRoundTripPreservesProofs(t *testing.T) {
+	values := make([]string, 300)
+	for i := range values {
+		values[i] = fmt.Sprintf("holder-%03d", i)
+	}
+	values[150] = values[20] // A duplicate keeps its value indices apart
+
+	for _, opts := range []MerkleTreeOptions{{}, {SortLeaves: Bool(true)}, {Compatibility: CompatLatest}} {
+		standard, err := NewStandardMerkleTree(values, opts)
+		if err != nil {
+			t.Fatalf("Failed to create tree: %v", err)
+		}
+		simple, err := standard.ToSimple()
+		if err != nil {
+			t.Fatalf("ToSimple failed: %v", err)
+		}
+		back, err := FromSimpleWithValues(simple, values, nil)
+		if err != nil {
+			t.Fatalf("FromSimpleWithValues failed: %v", err)
+		}
+
+		if simple.Root() != standard.Root() || back.Root() != standard.Root() || !slices.Equal(back.Tree, standard.Tree) {
+			t.Fatalf("Options %+v: roots %s, %s, %s differ", opts, standard.Root(), simple.Root(), back.Root())
+		}
+		if simple.Algorithm().LeafHash != HashIdentity || back.Algorithm() != standard.Algorithm() {
+			t.Errorf("Options %+v: algorithms %+v and %+v, want identity leaves and %+v", opts, simple.Algorithm(), back.Algorithm(), standard.Algorithm())
+		}
+
+		for i := range values {
+			want, err := standard.GetProof(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			leafHash := standard.Tree[standard.Values[i].TreeIndex]
+			byIndex, err := simple.GetProof(i)
+			if err != nil || !slices.Equal(byIndex, want) {
+				t.Fatalf("Value %d: simple proof %v (err %v), want %v", i, byIndex, err, want)
+			}
+			restored, err := back.GetProof(i)
+			if err != nil || !slices.Equal(restored, want) {
+				t.Fatalf("Value %d: restored proof %v (err %v), want %v", i, restored, err, want)
+			}
+
+			// Lookups resolve a duplicate the same way in every representation
+			wantByValue, err := standard.GetProof(values[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+			byHash, err := simple.GetProof(BytesLike(leafHash))
+			if err != nil || !slices.Equal(byHash, wantByValue) {
+				t.Fatalf("Value %d: simple proof by leaf hash %v (err %v), want %v", i, byHash, err, wantByValue)
+			}
+			byValue, err := back.GetProof(values[i])
+			if err != nil || !slices.Equal(byValue, wantByValue) {
+				t.Fatalf("Value %d: restored proof by value %v (err %v), want %v", i, byValue, err, wantByValue)
+			}
+		}
+	}
+}
+
+func TestToSimpleWithoutValues(t *testing.T) {
+	values := []string{"a", "b", "c", "d", "e"}
+	standard, err := NewStandardMerkleTree(values, MerkleTreeOptions{DropValuesAfterBuild: true})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	simple, err := standard.ToSimple()
+	if err != nil {
+		t.Fatalf("ToSimple of a tree without values failed: %v", err)
+	}
+	dump, err := simple.Dump()
+	if err != nil || dump.LeafHashAlgorithm != HashIdentity {
+		t.Fatalf("Dump of the simple tree: leaf hash %q, err %v", dump.LeafHashAlgorithm, err)
+	}
+	loaded, _, err := LoadSimpleMerkleTree(dump, nil)
+	if err != nil || loaded.Root() != standard.Root() {
+		t.Fatalf("Loaded simple tree has root %s (err %v), want %s", loaded.Root(), err, standard.Root())
+	}
+	if _, err := FromSimpleWithValues(loaded, values, nil); err != nil {
+		t.Errorf("Attaching values to a loaded dump failed: %v", err)
+	}
+}
+
+func TestFromSimpleWithValuesRejectsMismatches(t *testing.T) {
+	standard, err := NewStandardMerkleTree([]string{"a", "b", "c"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	simple, err := standard.ToSimple()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := FromSimpleWithValues(simple, []string{"a", "b"}, nil); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Expected ErrInvalidValue for a short value list, got %v", err)
+	}
+
+	_, err = FromSimpleWithValues(simple, []string{"a", "x", "y"}, nil)
+	var inputErr *InputError
+	if !errors.As(err, &inputErr) || inputErr.Index != 1 || !errors.Is(err, ErrInvalidValue) {
+		t.Fatalf("Expected an *InputError for value 1, got %v", err)
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); !ok || len(joined.Unwrap()) != 2 {
+		t.Errorf("Expected both mismatches reported, got %v", err)
+	}
+
+	// A custom leaf hash is recorded as such
+	custom, err := FromSimpleWithValues(simple, []int{0, 1, 2}, func(i int) HexString {
+		return standard.Tree[standard.Values[i].TreeIndex]
+	})
+	if err != nil || custom.Algorithm().LeafHash != HashCustom || custom.Root() != standard.Root() {
+		t.Errorf("Custom leaf hash: algorithm %+v, err %v", custom.Algorithm(), err)
+	}
+
+	if _, err := (&StandardMerkleTree[string]{}).ToSimple(); !errors.Is(err, ErrEmptyTree) {
+		t.Errorf("Expected ErrEmptyTree, got %v", err)
+	}
+}
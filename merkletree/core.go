@@ -1,18 +1,21 @@
 package merkletree
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"math"
+	"slices"
 	"sort"
 )
 
 // MultiProof represents a multi-proof for verifying multiple leaves at once.
 // It contains the leaves to verify, the proof nodes, and flags indicating
 // which nodes should be combined during verification.
+// Its JSON form is described at MarshalJSON.
 type MultiProof struct {
-	Leaves     []HexString // Hashes of the leaves included in the proof
-	Proof      []HexString // List of nodes needed to compute the root
-	ProofFlags []bool      // Indicates which nodes should be combined
+	Leaves     []HexString `json:"leaves"`     // Hashes of the leaves included in the proof
+	Proof      []HexString `json:"proof"`      // List of nodes needed to compute the root
+	ProofFlags []bool      `json:"proofFlags"` // Indicates which nodes should be combined
 }
 
 // IsTreeNode checks if index i is a valid node in the tree.
@@ -42,7 +45,14 @@ func CheckLeafNode(tree []BytesLike, i int) error {
 }
 
 // IsValidMerkleNode checks if a node is a valid 32-byte Merkle node.
+// Hex strings are checked in place rather than decoded.
 func IsValidMerkleNode(node BytesLike) bool {
+	switch v := node.(type) {
+	case HexString:
+		return isMerkleNodeString(string(v))
+	case string:
+		return isMerkleNodeString(v)
+	}
 	bytes, err := ToBytes(node)
 	if err != nil {
 		return false
@@ -50,6 +60,16 @@ func IsValidMerkleNode(node BytesLike) bool {
 	return len(bytes) == 32
 }
 
+// isMerkleNodeString reports whether s reads as 32 bytes, as ToBytes reads
+// strings: 64 hex digits after a 0x prefix, or 32 raw bytes without one.
+func isMerkleNodeString(s string) bool {
+	h := HexString(s)
+	if _, ok := cutHexPrefix(s); ok {
+		return h.IsValid() && h.Len() == 32
+	}
+	return len(s) == 32
+}
+
 // CheckValidMerkleNode verifies that a node is a valid 32-byte Merkle node.
 // Returns an error if the node is invalid.
 func CheckValidMerkleNode(node BytesLike) error {
@@ -61,11 +81,22 @@ func CheckValidMerkleNode(node BytesLike) error {
 
 // MakeMerkleTree builds a Merkle tree from a list of leaf hashes.
 // The tree is represented as a flat array where the root is at index 0.
-// Returns an error if the input is empty.
+// Returns an error if the input is empty, and one wrapping ErrHashFailed if a
+// leaf is empty or nodeHash returns no hash. If nodeHash is nil,
+// StandardNodeHash is used.
 func MakeMerkleTree(hashes []BytesLike, nodeHash NodeHash) ([]HexString, error) {
+	return makeMerkleTree(hashes, nodeHash, 1, nil)
+}
+
+// makeMerkleTree builds a Merkle tree hashing internal nodes with up to workers
+// goroutines, stopping when guard reports a resource limit.
+func makeMerkleTree(hashes []BytesLike, nodeHash NodeHash, workers int, guard *buildGuard) ([]HexString, error) {
 	if len(hashes) == 0 {
 		return nil, ErrEmptyTree
 	}
+	if nodeHash == nil {
+		nodeHash = StandardNodeHash
+	}
 
 	// Convert all hashes to HexString
 	leaves := make([]HexString, len(hashes))
@@ -74,6 +105,9 @@ func MakeMerkleTree(hashes []BytesLike, nodeHash NodeHash) ([]HexString, error)
 		if err != nil {
 			return nil, fmt.Errorf("invalid hash at index %d: %w", i, err)
 		}
+		if len(leaf) <= len("0x") {
+			return nil, fmt.Errorf("%w: leaf %d is empty", ErrHashFailed, i)
+		}
 		leaves[i] = leaf
 	}
 
@@ -83,10 +117,8 @@ func MakeMerkleTree(hashes []BytesLike, nodeHash NodeHash) ([]HexString, error)
 	copy(tree[len(tree)-len(leaves):], leaves)
 
 	// Generate internal nodes from bottom to top
-	for i := len(tree) - len(leaves) - 1; i >= 0; i-- {
-		leftChild := tree[LeftChildIndex(i)]
-		rightChild := tree[RightChildIndex(i)]
-		tree[i] = nodeHash(leftChild, rightChild)
+	if err := hashInternalNodes(tree, len(tree)-len(leaves), nodeHash, workers, guard); err != nil {
+		return nil, err
 	}
 
 	return tree, nil
@@ -102,13 +134,18 @@ func GetProof(tree []BytesLike, index int) ([]HexString, error) {
 
 	var proof []HexString
 	for index > 0 {
-		siblingIdx := SiblingIndex(index)
+		siblingIdx, err := SiblingIndex(index)
+		if err != nil {
+			return nil, err
+		}
 		value, err := ToHex(tree[siblingIdx])
 		if err != nil {
 			return nil, fmt.Errorf("invalid sibling at index %d: %w", siblingIdx, err)
 		}
 		proof = append(proof, value)
-		index = ParentIndex(index)
+		if index, err = ParentIndex(index); err != nil {
+			return nil, err
+		}
 	}
 	return proof, nil
 }
@@ -117,6 +154,12 @@ func GetProof(tree []BytesLike, index int) ([]HexString, error) {
 // It applies the hash function repeatedly, combining the leaf with proof nodes.
 // Returns an error if any node is invalid.
 func ProcessProof(leaf BytesLike, proof []BytesLike, nodeHash NodeHash) (HexString, error) {
+	return processProof(context.Background(), leaf, proof, nodeHash)
+}
+
+// processProof is ProcessProof checking ctx before each hash, so that a long
+// proof or a slow hash function cannot run past a deadline.
+func processProof(ctx context.Context, leaf BytesLike, proof []BytesLike, nodeHash NodeHash) (HexString, error) {
 	// Verify that the leaf node is valid
 	if err := CheckValidMerkleNode(leaf); err != nil {
 		return "", fmt.Errorf("invalid leaf: %w", err)
@@ -136,6 +179,9 @@ func ProcessProof(leaf BytesLike, proof []BytesLike, nodeHash NodeHash) (HexStri
 	}
 
 	for _, sibling := range proof {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
 		siblingHex, err := ToHex(sibling)
 		if err != nil {
 			return "", fmt.Errorf("error converting sibling to hex: %w", err)
@@ -150,26 +196,106 @@ func ProcessProof(leaf BytesLike, proof []BytesLike, nodeHash NodeHash) (HexStri
 	return resultHex, nil
 }
 
+// ProcessProofAt computes the root from the leaf at position of leafCount
+// leaves, in tree order, and its proof as GetProof returns it. Unlike
+// ProcessProof it hashes each node on the side the position puts it, so it
+// verifies proofs of trees whose node hash keeps the order of its inputs,
+// such as PositionalNodeHash; with a sorted-pair hash it gives the root
+// ProcessProof does. A position not below leafCount fails with
+// ErrInvalidIndex, a proof that is not the length of the path from the
+// position with ErrInvalidProof, and a leaf or node that is not 32 bytes
+// with ErrInvalidNode.
+func ProcessProofAt(leaf BytesLike, position, leafCount int, proof []BytesLike, nodeHash NodeHash) (HexString, error) {
+	return processProofAt(context.Background(), leaf, position, leafCount, proof, nodeHash)
+}
+
+// processProofAt is ProcessProofAt checking ctx before each hash.
+func processProofAt(ctx context.Context, leaf BytesLike, position, leafCount int, proof []BytesLike, nodeHash NodeHash) (HexString, error) {
+	if position < 0 || position >= leafCount {
+		return "", fmt.Errorf("%w: leaf %d of %d", ErrInvalidIndex, position, leafCount)
+	}
+	if err := CheckValidMerkleNode(leaf); err != nil {
+		return "", fmt.Errorf("invalid leaf: %w", err)
+	}
+	node, _ := ToHex(leaf)
+	i := leafCount - 1 + position
+	for k, sibling := range proof {
+		if err := CheckValidMerkleNode(sibling); err != nil {
+			return "", fmt.Errorf("invalid proof node at index %d: %w", k, err)
+		}
+		if i == 0 {
+			return "", fmt.Errorf("%w: %d nodes for a path of %d", ErrInvalidProof, len(proof), k)
+		}
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		// Right children have even indices
+		if i%2 == 0 {
+			node = nodeHash(sibling, node)
+		} else {
+			node = nodeHash(node, sibling)
+		}
+		i = parentIndex(i)
+	}
+	if i != 0 {
+		return "", fmt.Errorf("%w: %d nodes for a longer path", ErrInvalidProof, len(proof))
+	}
+	return node, nil
+}
+
 // GetMultiProof generates a multi-proof for a set of leaf indices.
 // Multi-proofs allow verifying multiple leaves more efficiently than
 // individual proofs by sharing common proof nodes.
-// Returns an error if no indices are provided.
+//
+// The indices are positions of leaves in the flat tree, in any order but
+// each at most once. The algorithm pairs each node with the next one on its
+// stack, which only finds siblings when deeper (higher) indices come first,
+// so it sorts them in descending order, and ProcessMultiProof and the
+// Solidity verifier consume the leaves in that order.
+//
+// Leaves is therefore in descending tree index, whatever the order of
+// indices: Leaves[k] is the leaf at the k-th largest index. The order
+// depends on tree positions alone, never on hash values, so it is the same
+// whether leaves were sorted ascending, descending, or not at all. To match
+// the leaves back to the request, sort a copy of indices descending.
+//
+// Returns an error if no indices are provided, an index is not a leaf, or an
+// index is repeated, which wraps ErrInvalidIndex.
 func GetMultiProof(tree []BytesLike, indices []int) (MultiProof, error) {
 	if len(indices) == 0 {
 		return MultiProof{}, ErrEmptyTree
 	}
+	for _, i := range indices {
+		if err := CheckLeafNode(tree, i); err != nil {
+			return MultiProof{}, fmt.Errorf("%w: index %d", err, i)
+		}
+	}
+
+	indices = slices.Clone(indices)
+	slices.Sort(indices)
+	for k := 1; k < len(indices); k++ {
+		if indices[k] == indices[k-1] {
+			return MultiProof{}, fmt.Errorf("%w: duplicate index %d", ErrInvalidIndex, indices[k])
+		}
+	}
+	slices.Reverse(indices)
 
 	var proof []HexString
 	var proofFlags []bool
-	stack := make([]int, len(indices))
-	copy(stack, indices)
+	stack := slices.Clone(indices)
 
 	for len(stack) > 0 && stack[0] > 0 {
 		j := stack[0]
 		stack = stack[1:]
 
-		s := SiblingIndex(j)
-		p := ParentIndex(j)
+		s, err := SiblingIndex(j)
+		if err != nil {
+			return MultiProof{}, err
+		}
+		p, err := ParentIndex(j)
+		if err != nil {
+			return MultiProof{}, err
+		}
 
 		if len(stack) > 0 && s == stack[0] {
 			proofFlags = append(proofFlags, true)
@@ -203,72 +329,113 @@ func GetMultiProof(tree []BytesLike, indices []int) (MultiProof, error) {
 }
 
 // ProcessMultiProof verifies a multi-proof and computes the resulting root.
-// Returns an error if the multi-proof is invalid.
+// The shape of the multi-proof is checked before anything is hashed: every
+// leaf and proof node must be 32 bytes and there must be one flag fewer than
+// leaves and proof nodes together, one hash for each. A multi-proof of no
+// leaves must be a single proof node and no flags, which is returned as the
+// root: it proves nothing, so callers must not take it as proof of any value.
+// Each flag must find the nodes it combines, every hash must be 32 bytes and
+// every leaf and proof node must be consumed. A multi-proof that breaks any
+// of these fails with an error wrapping ErrInvalidMultiProof that names it.
 func ProcessMultiProof(multiproof MultiProof, nodeHash NodeHash) (HexString, error) {
-	stack := make([]HexString, len(multiproof.Leaves))
-	copy(stack, multiproof.Leaves)
-	proof := make([]HexString, len(multiproof.Proof))
-	copy(proof, multiproof.Proof)
-
-	for _, flag := range multiproof.ProofFlags {
-		if len(stack) < 1 || (!flag && len(proof) < 1) {
-			return "", ErrInvalidMultiProof
+	leaves, proofNodes, flags := multiproof.Leaves, multiproof.Proof, multiproof.ProofFlags
+	for i, leaf := range leaves {
+		if err := CheckValidMerkleNode(leaf); err != nil {
+			return "", fmt.Errorf("%w: leaf %d: %w", ErrInvalidMultiProof, i, err)
+		}
+	}
+	for i, node := range proofNodes {
+		if err := CheckValidMerkleNode(node); err != nil {
+			return "", fmt.Errorf("%w: proof node %d: %w", ErrInvalidMultiProof, i, err)
+		}
+	}
+	if want := len(leaves) + len(proofNodes) - 1; len(flags) != want {
+		return "", fmt.Errorf("%w: %d leaves and %d proof nodes need %d flags, got %d",
+			ErrInvalidMultiProof, len(leaves), len(proofNodes), max(want, 0), len(flags))
+	}
+	if len(leaves) == 0 {
+		if len(proofNodes) != 1 {
+			return "", fmt.Errorf("%w: a multi-proof of no leaves must be one proof node, got %d", ErrInvalidMultiProof, len(proofNodes))
 		}
+		return proofNodes[0], nil
+	}
 
+	stack := slices.Clone(leaves)
+	proof := proofNodes
+	for step, flag := range flags {
+		// The first operand is always a leaf or a hash, never a proof node
+		if len(stack) < 1 {
+			return "", fmt.Errorf("%w: flag %d has no leaf or hash to combine", ErrInvalidMultiProof, step)
+		}
 		a := stack[0]
 		stack = stack[1:]
 
 		var b HexString
 		if flag {
 			if len(stack) < 1 {
-				return "", ErrInvalidMultiProof
+				return "", fmt.Errorf("%w: flag %d combines two leaves or hashes, but only one is left", ErrInvalidMultiProof, step)
 			}
 			b = stack[0]
 			stack = stack[1:]
 		} else {
+			if len(proof) < 1 {
+				return "", fmt.Errorf("%w: flag %d takes a proof node, but none is left", ErrInvalidMultiProof, step)
+			}
 			b = proof[0]
 			proof = proof[1:]
 		}
 
-		leafA, err := ToHex(a)
-		if err != nil {
-			return "", fmt.Errorf("invalid leaf in multi-proof: %w", err)
-		}
-		leafB, err := ToHex(b)
-		if err != nil {
-			return "", fmt.Errorf("invalid leaf in multi-proof: %w", err)
+		hash := nodeHash(a, b)
+		if !IsValidMerkleNode(hash) {
+			return "", fmt.Errorf("%w: hash %d is not a 32-byte node", ErrInvalidMultiProof, step)
 		}
-		stack = append(stack, nodeHash(leafA, leafB))
+		stack = append(stack, hash)
 	}
 
-	if len(stack)+len(proof) != 1 {
-		return "", ErrInvalidMultiProof
+	if len(stack) != 1 || len(proof) != 0 {
+		return "", fmt.Errorf("%w: %d hashes and %d proof nodes left over, want the root alone",
+			ErrInvalidMultiProof, len(stack), len(proof))
 	}
-
-	if len(stack) == 1 {
-		return stack[0], nil
-	}
-	return proof[0], nil
+	return stack[0], nil
 }
 
 // ParentIndex returns the index of the parent node for a given node.
-// Returns an error if the node is the root (index 0).
-func ParentIndex(i int) int {
-	if i > 0 {
-		return int(math.Floor((float64(i) - 1) / 2))
+// Returns ErrRootHasNoParent for the root (index 0), and an error wrapping
+// ErrInvalidIndex for a negative index.
+func ParentIndex(i int) (int, error) {
+	switch {
+	case i == 0:
+		return 0, ErrRootHasNoParent
+	case i < 0:
+		return 0, fmt.Errorf("%w: node index %d", ErrInvalidIndex, i)
 	}
-	// Note: Callers should check i > 0 before calling this function
-	return 0
+	return parentIndex(i), nil
 }
 
 // SiblingIndex returns the index of the sibling node for a given node.
 // In a binary tree, a node's sibling is its parent's other child.
-func SiblingIndex(i int) int {
-	if i > 0 {
-		return i - int(math.Pow(-1, float64(i%2)))
+// Returns ErrRootHasNoSibling for the root (index 0), and an error wrapping
+// ErrInvalidIndex for a negative index.
+func SiblingIndex(i int) (int, error) {
+	switch {
+	case i == 0:
+		return 0, ErrRootHasNoSibling
+	case i < 0:
+		return 0, fmt.Errorf("%w: node index %d", ErrInvalidIndex, i)
 	}
-	// Note: Callers should check i > 0 before calling this function
-	return 0
+	return siblingIndex(i), nil
+}
+
+// parentIndex is ParentIndex for an index known to be above 0, for loops
+// that walk up to the root.
+func parentIndex(i int) int {
+	return (i - 1) / 2
+}
+
+// siblingIndex is SiblingIndex for an index known to be above 0. Left
+// children have odd indices and right children even ones.
+func siblingIndex(i int) int {
+	return i - 1 + 2*(i%2)
 }
 
 // LeftChildIndex returns the index of the left child for a given node.
@@ -283,7 +450,11 @@ func RightChildIndex(i int) int {
 
 // PrepareMerkleTree builds the Merkle tree and assigns correct indices to the leaves.
 // It handles optional leaf sorting and returns both the tree structure and indexed values.
-// Returns an error if tree construction fails.
+// Returns an error if tree construction fails. Invalid options and invalid values
+// are reported together in one joined error of *OptionError and *InputError
+// entries, with at most MaxInputErrors values listed.
+// With QuarantineInvalid, invalid values are left out of the tree instead;
+// the returned values then only contain the valid ones.
 func PrepareMerkleTree[T any](
 	values []T,
 	options MerkleTreeOptions,
@@ -293,6 +464,21 @@ func PrepareMerkleTree[T any](
 	Value     T
 	TreeIndex int
 }, error) {
+	tree, indexedValues, _, err := prepareMerkleTree(values, options, leafHash, nodeHash)
+	return tree, indexedValues, err
+}
+
+// prepareMerkleTree implements PrepareMerkleTree and also returns the values
+// quarantined under QuarantineInvalid.
+func prepareMerkleTree[T any](
+	values []T,
+	options MerkleTreeOptions,
+	leafHash func(T) HexString,
+	nodeHash NodeHash,
+) ([]HexString, []struct {
+	Value     T
+	TreeIndex int
+}, []quarantinedValue[T], error) {
 	// Use standard node hash if not provided
 	if nodeHash == nil {
 		nodeHash = StandardNodeHash
@@ -303,53 +489,69 @@ func PrepareMerkleTree[T any](
 		Value      T
 		ValueIndex int
 		Hash       HexString
-	}, len(values))
+	}, 0, len(values))
+
+	// Collect option problems first, then every invalid value up to the cap,
+	// so a misconfigured build reports everything in one error.
+	errs := options.validateInput(len(values))
+
+	// Refuse builds that will not fit before allocating anything for them
+	if len(errs) == 0 {
+		if err := options.Limits.checkEstimate(len(values), options); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	guard := newBuildGuard(options.Limits)
 
 	// Apply hash function to leaves
-	for i, value := range values {
-		hashedValues[i] = struct {
+	leafHashes, err := hashLeaves(values, leafHash, options.leafHashWorkers(), guard)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	quarantine, invalid := screenLeaves(values, leafHashes, options, func(i int) {
+		hashedValues = append(hashedValues, struct {
 			Value      T
 			ValueIndex int
 			Hash       HexString
 		}{
-			Value:      value,
-			ValueIndex: i,
-			Hash:       leafHash(value),
-		}
-	}
-
-	// Sort leaves if option is enabled
-	if options.SortLeaves {
-		sort.Slice(hashedValues, func(i, j int) bool {
-			result, err := Compare(hashedValues[i].Hash, hashedValues[j].Hash)
-			if err != nil {
-				return false
-			}
-			return result < 0
+			Value:      values[i],
+			ValueIndex: len(hashedValues),
+			Hash:       leafHashes[i],
 		})
+	})
+	if errs = append(errs, invalid...); len(errs) > 0 {
+		return nil, nil, nil, errors.Join(errs...)
 	}
 
+	orderLeaves(options, hashedValues, func(v struct {
+		Value      T
+		ValueIndex int
+		Hash       HexString
+	}) HexString {
+		return v.Hash
+	})
+
 	// Build the Merkle tree
 	hashes := make([]BytesLike, len(hashedValues))
 	for i, v := range hashedValues {
 		hashes[i] = v.Hash
 	}
 
-	tree, err := MakeMerkleTree(hashes, nodeHash)
+	tree, err := makeMerkleTree(hashes, nodeHash, options.nodeHashWorkers(), guard)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	// Assign correct indices to leaves
 	indexedValues := make([]struct {
 		Value     T
 		TreeIndex int
-	}, len(values))
+	}, len(hashedValues))
 
 	for leafIndex, hv := range hashedValues {
 		correctedIndex := len(tree) - len(hashedValues) + leafIndex
 		if correctedIndex < 0 || correctedIndex >= len(tree) {
-			return nil, nil, fmt.Errorf("tree index %d out of bounds (max: %d)", correctedIndex, len(tree)-1)
+			return nil, nil, nil, fmt.Errorf("tree index %d out of bounds (max: %d)", correctedIndex, len(tree)-1)
 		}
 		indexedValues[hv.ValueIndex] = struct {
 			Value     T
@@ -360,5 +562,96 @@ func PrepareMerkleTree[T any](
 		}
 	}
 
-	return tree, indexedValues, nil
+	return tree, indexedValues, quarantine, nil
+}
+
+// screenLeaves checks every value against MaxValueBytes and its leaf hash
+// against the 32-byte node size, calling keep with the index of each value
+// that passes. Under QuarantineInvalid the others are returned as the
+// quarantine; otherwise as errors, at most MaxInputErrors of them listed.
+func screenLeaves[T any](
+	values []T,
+	leafHashes []HexString,
+	options MerkleTreeOptions,
+	keep func(i int),
+) ([]quarantinedValue[T], []error) {
+	var quarantine []quarantinedValue[T]
+	var errs []error
+	maxInputErrors := options.maxInputErrors()
+	invalid := 0
+	for i, value := range values {
+		hash := leafHashes[i]
+		// An oversize value reports its own index; a bad hash is wrapped
+		var err, reported error
+		if err = checkValueSize(i, value, options.MaxValueBytes); err != nil {
+			reported = err
+		} else if err = leafHashError(value, hash); err != nil {
+			reported = &InputError{Index: i, Err: err}
+		}
+		if err != nil {
+			if options.QuarantineInvalid {
+				quarantine = append(quarantine, quarantinedValue[T]{
+					QuarantinedValue: QuarantinedValue{Index: i, Preview: previewValue(value), Err: err},
+					value:            value,
+				})
+				continue
+			}
+			invalid++
+			if invalid <= maxInputErrors {
+				errs = append(errs, reported)
+			}
+			continue
+		}
+		keep(i)
+	}
+	if invalid > maxInputErrors {
+		errs = append(errs, fmt.Errorf("%w: %d more invalid values not shown", ErrInvalidValue, invalid-maxInputErrors))
+	}
+	return quarantine, errs
+}
+
+// leafHashError returns an error wrapping ErrInvalidValue if hash, the leaf
+// hash of value, is not a 32-byte node, and also ErrHashFailed if the leaf
+// hash returned nothing because it cannot encode the value.
+func leafHashError(value any, hash HexString) error {
+	switch {
+	case hash == "":
+		return fmt.Errorf("%w: %T cannot be hashed (%w)", ErrInvalidValue, value, ErrHashFailed)
+	case !IsValidMerkleNode(hash):
+		return fmt.Errorf("%w: %T hashes to %s, not a 32-byte node", ErrInvalidValue, value, previewValue(hash))
+	}
+	return nil
+}
+
+// orderLeaves puts items, the leaves in input order, in the order their leaf
+// hashes take in the tree: sorted if SortLeaves is true, largest first with
+// SortDescending. The sort is stable so equal leaves keep their input order,
+// which makes the value-to-position mapping of duplicated values
+// deterministic. CompatLatest sorts byte-wise and reverses, as OpenZeppelin
+// fills the bottom level from the end. ShuffleSeed replaces all of these.
+func orderLeaves[E any](options MerkleTreeOptions, items []E, hash func(E) HexString) {
+	if options.ShuffleSeed != nil {
+		sortByShuffleKey(options.ShuffleSeed, items, func(item E) []byte {
+			return hexLeafBytes(hash(item))
+		})
+	} else if options.Compatibility == CompatLatest {
+		if !options.PreserveOrder {
+			sort.SliceStable(items, func(i, j int) bool {
+				result, err := Compare(hash(items[i]), hash(items[j]))
+				return err == nil && result < 0
+			})
+		}
+		slices.Reverse(items)
+	} else if options.sortLeaves() {
+		sort.SliceStable(items, func(i, j int) bool {
+			result, err := Compare(hash(items[i]), hash(items[j]))
+			if err != nil {
+				return false
+			}
+			if options.SortDescending {
+				return result > 0
+			}
+			return result < 0
+		})
+	}
 }
@@ -0,0 +1,72 @@
+package merkletree
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestTreeIndexArithmetic(t *testing.T) {
+	// Every parent up to 2^10, then random parents up to the largest whose
+	// children fit in an int, well past the 2^53 float64 can hold exactly
+	parents := make([]int, 0, 1<<11)
+	for p := range 1 << 10 {
+		parents = append(parents, p)
+	}
+	rng := rand.New(rand.NewPCG(2011, 2011))
+	for range 1 << 10 {
+		parents = append(parents, rng.IntN((math.MaxInt-2)/2))
+	}
+	parents = append(parents, 1<<53, 1<<53+1, (math.MaxInt-2)/2)
+
+	for _, p := range parents {
+		left, right := LeftChildIndex(p), RightChildIndex(p)
+		for _, child := range []int{left, right} {
+			if got, err := ParentIndex(child); err != nil || got != p {
+				t.Fatalf("ParentIndex(%d) = %d, %v; want %d", child, got, err, p)
+			}
+		}
+		if got, err := SiblingIndex(left); err != nil || got != right {
+			t.Fatalf("SiblingIndex(%d) = %d, %v; want %d", left, got, err, right)
+		}
+		if got, err := SiblingIndex(right); err != nil || got != left {
+			t.Fatalf("SiblingIndex(%d) = %d, %v; want %d", right, got, err, left)
+		}
+	}
+}
+
+func TestTreeIndexArithmeticRoot(t *testing.T) {
+	if _, err := ParentIndex(0); !errors.Is(err, ErrRootHasNoParent) {
+		t.Errorf("ParentIndex(0) returned %v, want ErrRootHasNoParent", err)
+	}
+	if _, err := SiblingIndex(0); !errors.Is(err, ErrRootHasNoSibling) {
+		t.Errorf("SiblingIndex(0) returned %v, want ErrRootHasNoSibling", err)
+	}
+	for _, i := range []int{-1, -2, math.MinInt} {
+		if _, err := ParentIndex(i); !errors.Is(err, ErrInvalidIndex) {
+			t.Errorf("ParentIndex(%d) returned %v, want ErrInvalidIndex", i, err)
+		}
+		if _, err := SiblingIndex(i); !errors.Is(err, ErrInvalidIndex) {
+			t.Errorf("SiblingIndex(%d) returned %v, want ErrInvalidIndex", i, err)
+		}
+	}
+}
+
+func TestProcessProofBareStringLeaf(t *testing.T) {
+	// A bare 32-character string is a valid node of 32 raw bytes, and is
+	// hashed as those bytes, not as 16 bytes of hex
+	leaf := "abcdefabcdefabcdefabcdefabcdefab"
+	sibling := StandardLeafHash("sibling")
+	got, err := ProcessProof(leaf, []BytesLike{sibling}, StandardNodeHash)
+	if err != nil {
+		t.Fatalf("ProcessProof failed: %v", err)
+	}
+	want, err := ProcessProof([]byte(leaf), []BytesLike{sibling}, StandardNodeHash)
+	if err != nil {
+		t.Fatalf("ProcessProof failed: %v", err)
+	}
+	if got != want {
+		t.Errorf("ProcessProof of the string leaf = %s, of its bytes %s", got, want)
+	}
+}
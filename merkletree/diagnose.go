@@ -0,0 +1,337 @@
+package merkletree
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// VerificationDrift names one way a proof built elsewhere differs from what
+// VerifyStandardMerkleTree expects.
+type VerificationDrift string
+
+const (
+	// DriftValueType means the value was encoded as another type, such as a
+	// hex string encoded as the bytes it spells or a decimal string as a uint256.
+	DriftValueType VerificationDrift = "value-type"
+
+	// DriftABIEncoding means the value was encoded with abi.encode, as
+	// OpenZeppelin's JavaScript StandardMerkleTree does, instead of abi.encodePacked.
+	DriftABIEncoding VerificationDrift = "abi-encoding"
+
+	// DriftDoubleHash means the leaf was hashed twice, as OpenZeppelinLeafHash
+	// and CompatLatest trees do.
+	DriftDoubleHash VerificationDrift = "double-hash"
+
+	// DriftPreHashed means the value given is already the leaf hash.
+	DriftPreHashed VerificationDrift = "pre-hashed"
+
+	// DriftNodeHash means the tree hashes its nodes with SHA-256.
+	DriftNodeHash VerificationDrift = "node-hash"
+)
+
+// DiagnosisCandidate is one way of hashing a value into a leaf and
+// verifying it, tried by DiagnoseStandardVerification.
+type DiagnosisCandidate struct {
+	ValueType    string              `json:"valueType"`        // How the value was read: "string", "bytes", "uint256", "address", "leaf" or its Go type
+	Encoding     string              `json:"encoding"`         // "packed", "abi", or "none" for a leaf
+	LeafHashes   int                 `json:"leafHashes"`       // Times the encoding was hashed: 1, 2, or 0 for a leaf
+	NodeHash     string              `json:"nodeHash"`         // HashKeccak256Sorted or HashSHA256Sorted
+	Leaf         HexString           `json:"leaf"`             // Leaf hash it gives
+	ComputedRoot HexString           `json:"computedRoot"`     // Root the proof leads to from that leaf
+	Verified     bool                `json:"verified"`         // Whether ComputedRoot is the expected root
+	Causes       []VerificationDrift `json:"causes,omitempty"` // How it differs from VerifyStandardMerkleTree
+}
+
+// DiagnosisReport explains why a value and proof do or do not verify against
+// a root with VerifyStandardMerkleTree.
+type DiagnosisReport struct {
+	Root        HexString            `json:"root"`            // Expected root
+	Verified    bool                 `json:"verified"`        // Verifies with VerifyStandardMerkleTree as given
+	Match       *DiagnosisCandidate  `json:"match,omitempty"` // Candidate with the fewest causes that verifies, if any
+	Explanation string               `json:"explanation"`     // Best guess at the cause, for people
+	Candidates  []DiagnosisCandidate `json:"candidates"`      // Every distinct candidate tried, fewest causes first
+}
+
+// Err returns nil if the value verifies as given. Otherwise it returns one
+// *DriftError per cause of Match joined with errors.Join, or an error
+// wrapping ErrInvalidProof when no candidate verifies.
+func (r DiagnosisReport) Err() error {
+	if r.Verified {
+		return nil
+	}
+	if r.Match == nil {
+		return fmt.Errorf("%w: %s", ErrInvalidProof, r.Explanation)
+	}
+	errs := make([]error, len(r.Match.Causes))
+	for i, cause := range r.Match.Causes {
+		errs[i] = &DriftError{Drift: cause, Detail: driftDetail(cause, *r.Match)}
+	}
+	return errors.Join(errs...)
+}
+
+// String summarizes the report: the explanation, then the causes and the
+// leaf of the match, if any.
+func (r DiagnosisReport) String() string {
+	if r.Match == nil || r.Verified {
+		return r.Explanation
+	}
+	causes := make([]string, len(r.Match.Causes))
+	for i, cause := range r.Match.Causes {
+		causes[i] = string(cause)
+	}
+	return fmt.Sprintf("%s\ncauses: %s\nleaf: %s (%s value, %s encoding, hashed %d times, %s nodes)",
+		r.Explanation, strings.Join(causes, ", "), r.Match.Leaf, r.Match.ValueType, r.Match.Encoding, r.Match.LeafHashes, r.Match.NodeHash)
+}
+
+// DiagnoseStandardVerification finds out why a proof generated elsewhere,
+// typically by OpenZeppelin's JavaScript library, does not verify with
+// VerifyStandardMerkleTree. It hashes value into a leaf under every
+// supported encoding, packed or abi.encode, hashed once or twice, with the
+// value read as its own type and, for strings, as the bytes, uint256 or
+// address it spells or as a leaf hash, and runs the proof from each leaf
+// with both keccak256 and SHA-256 node hashing. The report lists every
+// candidate and names the differences of the closest one that verifies.
+//
+// A root or proof node that is not 32 bytes fails with ErrInvalidRoot or
+// ErrInvalidNode, and a value no encoding supports with ErrInvalidValue.
+func DiagnoseStandardVerification(root BytesLike, value any, proof []BytesLike) (DiagnosisReport, error) {
+	expected, err := ToHex(root)
+	if err != nil || !IsValidMerkleNode(expected) {
+		return DiagnosisReport{}, fmt.Errorf("%w: %v is not a 32-byte root", ErrInvalidRoot, root)
+	}
+	for i, node := range proof {
+		if hexNode, err := ToHex(node); err != nil || !IsValidMerkleNode(hexNode) {
+			return DiagnosisReport{}, fmt.Errorf("%w: proof node %d", ErrInvalidNode, i)
+		}
+	}
+
+	report := DiagnosisReport{Root: expected, Candidates: []DiagnosisCandidate{}}
+	seen := make(map[[2]HexString]bool)
+	for _, reading := range readingsOf(value) {
+		for _, leaf := range leavesOf(reading) {
+			for _, node := range diagnosisNodeHashes {
+				candidate := leaf
+				candidate.NodeHash = node.descriptor
+				if node.descriptor != HashKeccak256Sorted {
+					candidate.Causes = append(slices.Clone(candidate.Causes), DriftNodeHash)
+				}
+				key := [2]HexString{candidate.Leaf, HexString(candidate.NodeHash)}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				if candidate.ComputedRoot, err = ProcessProof(candidate.Leaf, proof, node.hash); err != nil {
+					return DiagnosisReport{}, fmt.Errorf("error processing proof: %w", err)
+				}
+				candidate.Verified = candidate.ComputedRoot == expected
+				report.Candidates = append(report.Candidates, candidate)
+			}
+		}
+	}
+	if len(report.Candidates) == 0 {
+		return DiagnosisReport{}, fmt.Errorf("%w: no encoding supports %T", ErrInvalidValue, value)
+	}
+
+	slices.SortStableFunc(report.Candidates, func(a, b DiagnosisCandidate) int {
+		return len(a.Causes) - len(b.Causes)
+	})
+	for i, candidate := range report.Candidates {
+		if candidate.Verified {
+			report.Match = &report.Candidates[i]
+			break
+		}
+	}
+	report.Verified = report.Match != nil && len(report.Match.Causes) == 0
+	report.Explanation = explainDiagnosis(report)
+	return report, nil
+}
+
+// diagnosisNodeHashes are the node hashes tried, standard first.
+var diagnosisNodeHashes = []namedNodeHash{
+	{StandardNodeHash, HashKeccak256Sorted},
+	{SHA256NodeHash, HashSHA256Sorted},
+}
+
+// valueReading is one way of reading the value to diagnose.
+type valueReading struct {
+	name  string
+	value any
+	cause VerificationDrift // Empty for the value as given
+}
+
+// readingsOf returns the ways of reading value, as given first.
+func readingsOf(value any) []valueReading {
+	if h, ok := value.(HexString); ok {
+		value = string(h)
+	}
+	name := fmt.Sprintf("%T", value)
+	if _, ok := value.([]byte); ok {
+		name = "bytes"
+	}
+	readings := []valueReading{{name: name, value: value}}
+
+	var raw []byte
+	switch v := value.(type) {
+	case string:
+		if digits, ok := strings.CutPrefix(v, "0x"); ok {
+			if b, err := hex.DecodeString(digits); err == nil {
+				raw = b
+				readings = append(readings, valueReading{name: "bytes", value: b, cause: DriftValueType})
+				if a, err := ParseAddress(v); err == nil {
+					readings = append(readings, valueReading{name: "address", value: a, cause: DriftValueType})
+				}
+			}
+		}
+		if n, ok := new(big.Int).SetString(v, 0); ok {
+			if u, err := U256FromBig(n); err == nil {
+				readings = append(readings, valueReading{name: "uint256", value: u, cause: DriftValueType})
+			}
+		}
+	case []byte:
+		raw = v
+	}
+	if len(raw) == 32 {
+		readings = append(readings, valueReading{name: "leaf", value: raw, cause: DriftPreHashed})
+	}
+	return readings
+}
+
+// leavesOf returns the candidates a reading hashes to, without a node hash
+// yet, packed before abi and single before double.
+func leavesOf(reading valueReading) []DiagnosisCandidate {
+	var causes []VerificationDrift
+	if reading.cause != "" {
+		causes = append(causes, reading.cause)
+	}
+	if reading.cause == DriftPreHashed {
+		leaf, _ := ToHex(reading.value.([]byte))
+		return []DiagnosisCandidate{{ValueType: reading.name, Encoding: "none", Leaf: leaf, Causes: causes}}
+	}
+
+	var leaves []DiagnosisCandidate
+	for _, encoding := range []string{"packed", "abi"} {
+		encoded, err := abiEncodePacked(reading.value)
+		encodingCauses := causes
+		if encoding == "abi" {
+			encoded, err = abiEncode(reading.value)
+			encodingCauses = append(slices.Clone(causes), DriftABIEncoding)
+		}
+		if err != nil {
+			continue
+		}
+		hash := keccak256(encoded)
+		for hashes := 1; hashes <= 2; hashes++ {
+			leafCauses := encodingCauses
+			if hashes == 2 {
+				hash = keccak256(hash)
+				leafCauses = append(slices.Clone(encodingCauses), DriftDoubleHash)
+			}
+			leaf, _ := ToHex(hash)
+			leaves = append(leaves, DiagnosisCandidate{
+				ValueType:  reading.name,
+				Encoding:   encoding,
+				LeafHashes: hashes,
+				Leaf:       leaf,
+				Causes:     leafCauses,
+			})
+		}
+	}
+	return leaves
+}
+
+// abiEncode encodes one value as Solidity's abi.encode does: static types as
+// a 32-byte word, strings, bytes and arrays as an offset, a length and the
+// contents padded to words.
+func abiEncode(value any) ([]byte, error) {
+	switch v := value.(type) {
+	case string:
+		return abiDynamic(len(v), []byte(v)), nil
+	case []byte:
+		return abiDynamic(len(v), v), nil
+	case bool:
+		return leftPad(packedBool(v)), nil
+	case int8:
+		return packedInts([]int64{int64(v)}), nil
+	case int16:
+		return packedInts([]int64{int64(v)}), nil
+	case int32:
+		return packedInts([]int64{int64(v)}), nil
+	case int64:
+		return packedInts([]int64{v}), nil
+	case uint8:
+		return packedInts([]uint64{uint64(v)}), nil
+	case uint16:
+		return packedInts([]uint64{uint64(v)}), nil
+	case uint32:
+		return packedInts([]uint64{uint64(v)}), nil
+	case uint64:
+		return packedInts([]uint64{v}), nil
+	case Address:
+		return abiEncodePacked([]Address{v})
+	case U256:
+		return v[:], nil
+	case *big.Int:
+		return packedBigInt(v)
+	case []int8, []int16, []int32, []int64, []uint16, []uint32, []uint64, []Address, []U256:
+		packed, err := abiEncodePacked(v) // Elements are already padded to words
+		if err != nil {
+			return nil, err
+		}
+		return abiDynamic(len(packed)/abiWordSize, packed), nil
+	default:
+		if a, ok := addressOf(v); ok {
+			return abiEncodePacked([]Address{a})
+		}
+		return nil, fmt.Errorf("unsupported type in abiEncode: %T", v)
+	}
+}
+
+// abiDynamic returns the abi.encode encoding of a dynamic value of length
+// elements whose contents are data.
+func abiDynamic(length int, data []byte) []byte {
+	out := make([]byte, 2*abiWordSize, 3*abiWordSize+len(data))
+	out[abiWordSize-1] = abiWordSize // Offset of the contents
+	new(big.Int).SetInt64(int64(length)).FillBytes(out[abiWordSize : 2*abiWordSize])
+	out = append(out, data...)
+	if rest := len(data) % abiWordSize; rest != 0 {
+		out = append(out, make([]byte, abiWordSize-rest)...)
+	}
+	return out
+}
+
+// explainDiagnosis returns the explanation of a report.
+func explainDiagnosis(r DiagnosisReport) string {
+	switch {
+	case r.Verified:
+		return "the proof verifies with VerifyStandardMerkleTree"
+	case r.Match == nil:
+		return "no supported encoding verifies: the root, the proof or the value differs from the one the proof was generated for"
+	}
+	details := make([]string, len(r.Match.Causes))
+	for i, cause := range r.Match.Causes {
+		details[i] = driftDetail(cause, *r.Match)
+	}
+	return "the proof verifies when " + strings.Join(details, "; ")
+}
+
+// driftDetail describes a cause as it applies to a candidate.
+func driftDetail(cause VerificationDrift, c DiagnosisCandidate) string {
+	switch cause {
+	case DriftValueType:
+		return fmt.Sprintf("the value is encoded as a %s", c.ValueType)
+	case DriftABIEncoding:
+		return "the value is encoded with abi.encode, as OpenZeppelin's JavaScript StandardMerkleTree does, not abi.encodePacked"
+	case DriftDoubleHash:
+		return "the leaf is hashed twice, as OpenZeppelinLeafHash and CompatLatest trees do"
+	case DriftPreHashed:
+		return "the value is taken as the leaf hash itself, as a simple tree of leaf hashes does"
+	case DriftNodeHash:
+		return fmt.Sprintf("nodes are hashed with %s, as SHA256NodeHash does", c.NodeHash)
+	default:
+		return string(cause)
+	}
+}
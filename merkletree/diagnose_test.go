@@ -0,0 +1,162 @@
+package merkletree
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// diagnoseTree builds a tree over the given leaf hashes and returns its root
+// and the proof of the first leaf.
+func diagnoseTree(t *testing.T, leaves []HexString, nodeHash NodeHash) (HexString, []BytesLike) {
+	t.Helper()
+	tree, err := MakeMerkleTree(bytesLikeNodes(leaves), nodeHash)
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	proof, err := GetProof(bytesLikeNodes(tree), len(tree)-len(leaves))
+	if err != nil {
+		t.Fatalf("Failed to get proof: %v", err)
+	}
+	return tree[0], bytesLikeNodes(proof)
+}
+
+// abiEncodedString is abi.encode(s) for a string of at most 32 bytes, written
+// out word by word.
+func abiEncodedString(s string) []byte {
+	out := make([]byte, 96)
+	out[31] = 0x20
+	out[63] = byte(len(s))
+	copy(out[64:], s)
+	return out
+}
+
+func TestDiagnoseStandardVerification(t *testing.T) {
+	others := []HexString{StandardLeafHash("b"), StandardLeafHash("c"), StandardLeafHash("d")}
+	u := U256FromUint64(1000)
+	deadbeef := []byte{0xde, 0xad, 0xbe, 0xef}
+
+	tests := []struct {
+		name      string
+		leaf      HexString
+		nodeHash  NodeHash
+		value     any
+		wantCause []VerificationDrift
+	}{
+		{"standard", StandardLeafHash("alice"), StandardNodeHash, "alice", nil},
+		{"double hash", OpenZeppelinLeafHash("alice"), StandardNodeHash, "alice", []VerificationDrift{DriftDoubleHash}},
+		{"openzeppelin js", OpenZeppelinLeafHash(abiEncodedString("alice")), StandardNodeHash, "alice", []VerificationDrift{DriftABIEncoding, DriftDoubleHash}},
+		{"abi single hash", StandardLeafHash(abiEncodedString("alice")), StandardNodeHash, "alice", []VerificationDrift{DriftABIEncoding}},
+		{"decimal string as uint256", StandardLeafHash(u), StandardNodeHash, "1000", []VerificationDrift{DriftValueType}},
+		{"hex string as bytes", StandardLeafHash(deadbeef), StandardNodeHash, "0xdeadbeef", []VerificationDrift{DriftValueType}},
+		{"pre-hashed", StandardLeafHash("alice"), StandardNodeHash, string(StandardLeafHash("alice")), []VerificationDrift{DriftPreHashed}},
+		{"sha256 nodes", StandardLeafHash("alice"), SHA256NodeHash, "alice", []VerificationDrift{DriftNodeHash}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root, proof := diagnoseTree(t, append([]HexString{test.leaf}, others...), test.nodeHash)
+			report, err := DiagnoseStandardVerification(root, test.value, proof)
+			if err != nil {
+				t.Fatalf("Failed to diagnose: %v", err)
+			}
+			if report.Match == nil {
+				t.Fatalf("No candidate verifies: %s", report.Explanation)
+			}
+			if !slices.Equal(report.Match.Causes, test.wantCause) {
+				t.Errorf("Causes %v, want %v (%s)", report.Match.Causes, test.wantCause, report.Explanation)
+			}
+			if report.Verified != (test.wantCause == nil) {
+				t.Errorf("Verified = %v", report.Verified)
+			}
+			if report.Match.Leaf != test.leaf {
+				t.Errorf("Matched leaf %s, want %s", report.Match.Leaf, test.leaf)
+			}
+
+			err = report.Err()
+			if test.wantCause == nil {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			var drift *DriftError
+			if !errors.As(err, &drift) || drift.Drift != test.wantCause[0] || !errors.Is(err, ErrInvalidProof) {
+				t.Errorf("Expected a DriftError for %s wrapping ErrInvalidProof, got %v", test.wantCause[0], err)
+			}
+		})
+	}
+}
+
+func TestDiagnoseStandardVerificationTypedValue(t *testing.T) {
+	values := []U256{U256FromUint64(1), U256FromUint64(2), U256FromUint64(3)}
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{Compatibility: CompatLatest})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	proof, err := tree.GetProof(1)
+	if err != nil {
+		t.Fatalf("Failed to get proof: %v", err)
+	}
+
+	report, err := DiagnoseStandardVerification(tree.Root(), values[1], bytesLikeNodes(proof))
+	if err != nil {
+		t.Fatalf("Failed to diagnose: %v", err)
+	}
+	if report.Match == nil || !slices.Equal(report.Match.Causes, []VerificationDrift{DriftDoubleHash}) {
+		t.Errorf("Expected a double hash, got %+v", report.Match)
+	}
+}
+
+func TestDiagnoseStandardVerificationNoMatch(t *testing.T) {
+	root, proof := diagnoseTree(t, []HexString{StandardLeafHash("alice"), StandardLeafHash("bob")}, StandardNodeHash)
+	report, err := DiagnoseStandardVerification(root, "mallory", proof)
+	if err != nil {
+		t.Fatalf("Failed to diagnose: %v", err)
+	}
+	if report.Verified || report.Match != nil {
+		t.Errorf("Expected no match, got %+v", report.Match)
+	}
+	if len(report.Candidates) != 8 {
+		t.Errorf("Expected 8 candidates for a plain string, got %d", len(report.Candidates))
+	}
+	if err := report.Err(); !errors.Is(err, ErrInvalidProof) {
+		t.Errorf("Expected ErrInvalidProof, got %v", err)
+	}
+}
+
+func TestDiagnoseStandardVerificationErrors(t *testing.T) {
+	root, proof := diagnoseTree(t, []HexString{StandardLeafHash("a"), StandardLeafHash("b")}, StandardNodeHash)
+
+	if _, err := DiagnoseStandardVerification("0x1234", "a", proof); !errors.Is(err, ErrInvalidRoot) {
+		t.Errorf("Expected ErrInvalidRoot, got %v", err)
+	}
+	if _, err := DiagnoseStandardVerification(root, "a", []BytesLike{"0x12"}); !errors.Is(err, ErrInvalidNode) {
+		t.Errorf("Expected ErrInvalidNode, got %v", err)
+	}
+	if _, err := DiagnoseStandardVerification(root, struct{}{}, proof); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Expected ErrInvalidValue, got %v", err)
+	}
+}
+
+func TestABIEncode(t *testing.T) {
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{int8(-1), strings.Repeat("ff", 32)},
+		{uint16(0x0102), fmt.Sprintf("%064x", 0x0102)},
+		{[]byte{0xab}, fmt.Sprintf("%064x%064xab%062x", 0x20, 1, 0)},
+		{[]uint64{1, 2}, fmt.Sprintf("%064x%064x%064x%064x", 0x20, 2, 1, 2)},
+	}
+	for _, test := range tests {
+		got, err := abiEncode(test.value)
+		if err != nil {
+			t.Fatalf("abiEncode(%T): %v", test.value, err)
+		}
+		if hex := fmt.Sprintf("%x", got); hex != test.want {
+			t.Errorf("abiEncode(%T) = %s, want %s", test.value, hex, test.want)
+		}
+	}
+}
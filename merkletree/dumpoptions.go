@@ -0,0 +1,69 @@
+package merkletree
+
+import "fmt"
+
+// DumpOptions records the build options that decided the root of a dumped
+// tree, in the spirit of the metadata of OpenZeppelin's dumps, so that a
+// consumer holding only the values can build the same tree again. It is
+// derived from the tree's algorithm and compatibility mode when dumping, and
+// checked against the algorithm when loading.
+type DumpOptions struct {
+	Compatibility  string `json:"compatibility"`            // "v0" or "latest"
+	SortLeaves     bool   `json:"sortLeaves"`               // Leaves were sorted by hash
+	SortDescending bool   `json:"sortDescending,omitempty"` // Sorted largest first, under "v0"
+	PreserveOrder  bool   `json:"preserveOrder,omitempty"`  // Input order kept, under "latest"
+	HashAlgorithm  string `json:"hashAlgorithm"`            // Node hash name, or "custom"
+}
+
+// newDumpOptions returns the options of a tree built with algorithm under
+// compatibility and the node hash named hashAlgorithm, or nil if its leaf
+// order is unknown, as in trees loaded from dumps without an algorithm. A
+// shuffled tree records no sorting; its order comes from the dump's shuffleSeed.
+func newDumpOptions(algorithm AlgorithmDescriptor, compatibility CompatibilityMode, hashAlgorithm string) *DumpOptions {
+	if algorithm.LeafOrder == "" {
+		return nil
+	}
+	options := &DumpOptions{Compatibility: CompatV0.String(), HashAlgorithm: hashAlgorithm}
+	if compatibility == CompatLatest {
+		options.Compatibility = CompatLatest.String()
+	}
+	switch algorithm.LeafOrder {
+	case LeafOrderAscending:
+		options.SortLeaves = true
+	case LeafOrderDescending:
+		options.SortLeaves = true
+		options.SortDescending = compatibility != CompatLatest
+	case LeafOrderReversed:
+		options.PreserveOrder = true
+	}
+	return options
+}
+
+// TreeOptions returns the MerkleTreeOptions that build the tree again from
+// its values in the same order. Set ShuffleSeed from the dump for a shuffled
+// tree, and HashAlgorithm from these options for a simple tree.
+func (o DumpOptions) TreeOptions() MerkleTreeOptions {
+	options := MerkleTreeOptions{
+		SortLeaves:     Bool(o.SortLeaves),
+		SortDescending: o.SortDescending,
+		PreserveOrder:  o.PreserveOrder,
+		Compatibility:  CompatV0,
+	}
+	if o.Compatibility == CompatLatest.String() {
+		options.Compatibility = CompatLatest
+		options.SortDescending = false
+	}
+	return options
+}
+
+// checkDumpOptions returns an error wrapping ErrInvalidDump if a dump's
+// options, when it has any, differ from those its algorithm implies.
+func checkDumpOptions(options *DumpOptions, algorithm AlgorithmDescriptor, compatibility CompatibilityMode, hashAlgorithm string) error {
+	if options == nil {
+		return nil // Written before options were recorded
+	}
+	if want := newDumpOptions(algorithm, compatibility, hashAlgorithm); want != nil && *options != *want {
+		return fmt.Errorf("%w: options %+v contradict the algorithm, which implies %+v", ErrInvalidDump, *options, *want)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// rebuildOptions lists build options whose dumps must rebuild the same root.
+var rebuildOptions = []MerkleTreeOptions{
+	{Compatibility: CompatV0},
+	{Compatibility: CompatV0, SortLeaves: Bool(true)},
+	{Compatibility: CompatV0, SortLeaves: Bool(true), SortDescending: true},
+	{SortLeaves: Bool(true)},
+	{Compatibility: CompatLatest},
+	{Compatibility: CompatLatest, PreserveOrder: true},
+}
+
+func TestDumpOptionsRebuildStandard(t *testing.T) {
+	values := []string{"eve", "alice", "dave", "bob", "charlie"}
+	for _, options := range rebuildOptions {
+		tree, data := standardDumpJSON(t, values, options)
+		if data.Options == nil {
+			t.Fatalf("Dump of %+v has no options", options)
+		}
+
+		// Rebuild from the values alone, in dump order
+		dumped := make([]string, len(data.Values))
+		for i, v := range data.Values {
+			dumped[i] = v.Value
+		}
+		rebuilt, err := NewStandardMerkleTree(dumped, data.Options.TreeOptions())
+		if err != nil {
+			t.Fatalf("Failed to rebuild: %v", err)
+		}
+		if rebuilt.Root() != tree.Root() {
+			t.Errorf("Options %+v: dump options %+v rebuild root %s, want %s", options, *data.Options, rebuilt.Root(), tree.Root())
+		}
+		if _, err := LoadStandardMerkleTree(data); err != nil {
+			t.Errorf("Options %+v: load failed: %v", options, err)
+		}
+	}
+}
+
+func TestDumpOptionsRebuildSimple(t *testing.T) {
+	values := []BytesLike{"eve", "alice", "dave", "bob", "charlie"}
+	for _, options := range rebuildOptions {
+		for _, hash := range []string{HashAlgorithmKeccak256, HashAlgorithmSHA256} {
+			tree, err := NewSimpleMerkleTree(values, SimpleMerkleTreeOptions{MerkleTreeOptions: options, HashAlgorithm: hash})
+			if err != nil {
+				t.Fatalf("Failed to create tree: %v", err)
+			}
+			dump, err := tree.Dump()
+			if err != nil {
+				t.Fatalf("Failed to dump tree: %v", err)
+			}
+			encoded, _ := json.Marshal(dump)
+			var data SimpleMerkleTreeData
+			if err := json.Unmarshal(encoded, &data); err != nil {
+				t.Fatalf("Failed to unmarshal dump: %v", err)
+			}
+			if data.Options == nil || data.Options.HashAlgorithm != hash {
+				t.Fatalf("Dump options %+v, want hash %s", data.Options, hash)
+			}
+
+			rebuilt, err := NewSimpleMerkleTree(values, SimpleMerkleTreeOptions{
+				MerkleTreeOptions: data.Options.TreeOptions(),
+				HashAlgorithm:     data.Options.HashAlgorithm,
+			})
+			if err != nil {
+				t.Fatalf("Failed to rebuild: %v", err)
+			}
+			if rebuilt.Root() != tree.Root() {
+				t.Errorf("Options %+v, %s: rebuilt root %s, want %s", options, hash, rebuilt.Root(), tree.Root())
+			}
+			if _, _, err := LoadSimpleMerkleTree(data, nil); err != nil {
+				t.Errorf("Options %+v, %s: load failed: %v", options, hash, err)
+			}
+		}
+	}
+}
+
+func TestDumpOptionsContradictions(t *testing.T) {
+	_, data := standardDumpJSON(t, []string{"alice", "bob", "charlie"}, MerkleTreeOptions{SortLeaves: Bool(true)})
+
+	unsorted := *data.Options
+	unsorted.SortLeaves = false
+	data.Options = &unsorted
+	if _, err := LoadStandardMerkleTree(data); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected sortLeaves contradicting the leaf order to be rejected, got %v", err)
+	}
+
+	latest := unsorted
+	latest.SortLeaves, latest.Compatibility = true, CompatLatest.String()
+	data.Options = &latest
+	if _, err := LoadStandardMerkleTree(data); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected compatibility contradicting the leaf hash to be rejected, got %v", err)
+	}
+
+	// Dumps from before options load as they did
+	data.Options = nil
+	if _, err := LoadStandardMerkleTree(data); err != nil {
+		t.Errorf("Dump without options failed to load: %v", err)
+	}
+
+	simple, _ := NewSimpleMerkleTree([]BytesLike{"a", "b"}, SimpleMerkleTreeOptions{})
+	dump, _ := simple.Dump()
+	dump.Options.HashAlgorithm = HashAlgorithmSHA256
+	if _, _, err := LoadSimpleMerkleTree(dump, nil); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected a hash contradicting hashAlgorithm to be rejected, got %v", err)
+	}
+}
+
+func TestDumpOptionsPreHashed(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"alice", "bob", "charlie"}, MerkleTreeOptions{Compatibility: CompatLatest})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	simple, err := tree.ToSimple()
+	if err != nil {
+		t.Fatalf("ToSimple failed: %v", err)
+	}
+	dump, err := simple.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	loaded, _, err := LoadSimpleMerkleTree(dump, nil)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if loaded.compatibility != CompatLatest {
+		t.Errorf("Pre-hashed tree loaded as %v, want %v from its options", loaded.compatibility, CompatLatest)
+	}
+}
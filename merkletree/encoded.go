@@ -0,0 +1,139 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// NewStandardMerkleTreeWithEncoding builds the tree OpenZeppelin's
+// StandardMerkleTree.of(values, leafEncoding) builds: each value is a tuple
+// of fields ABI-encoded with the Solidity types of leafEncoding, as
+// ABIEncode does, and its leaf is keccak256(keccak256(abi.encode(...))).
+// The tree verifies with OpenZeppelin's MerkleProof, and its dump records
+// leafEncoding as OpenZeppelin's does.
+//
+// The tree follows CompatLatest, which an unspecified Compatibility selects;
+// CompatV0 is rejected, as its leaves are hashed differently. Leaves are
+// sorted as OpenZeppelin sorts them unless PreserveOrder is set, which is
+// OpenZeppelin's sortLeaves: false.
+//
+// An unsupported type in leafEncoding returns an error wrapping
+// ErrInvalidLeafEncoding. Values that do not fit it are reported as
+// *InputError entries wrapping ErrInvalidValue, joined with errors.Join, or
+// quarantined under QuarantineInvalid.
+func NewStandardMerkleTreeWithEncoding(values [][]any, leafEncoding []string, options MerkleTreeOptions) (*StandardMerkleTree[[]any], error) {
+	types, err := parseLeafEncoding(leafEncoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare merkle tree: %w", err)
+	}
+	switch options.Compatibility {
+	case CompatUnspecified:
+		options.Compatibility = CompatLatest
+	case CompatV0:
+		return nil, fmt.Errorf("failed to prepare merkle tree: %w", &OptionError{
+			Option: "Compatibility", Value: options.Compatibility, Reason: "encoded leaves follow CompatLatest"})
+	}
+	options = NewMerkleTreeOptions(&options)
+
+	if !options.QuarantineInvalid {
+		var errs []error
+		invalid, maxInputErrors := 0, options.maxInputErrors()
+		for i, value := range values {
+			if _, err := encodeLeaf(types, value); err != nil {
+				if invalid++; invalid <= maxInputErrors {
+					errs = append(errs, &InputError{Index: i, Err: err})
+				}
+			}
+		}
+		if invalid > maxInputErrors {
+			errs = append(errs, fmt.Errorf("%w: %d more invalid values not shown", ErrInvalidValue, invalid-maxInputErrors))
+		}
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("failed to prepare merkle tree: %w", errors.Join(errs...))
+		}
+	}
+
+	leafHash := encodedLeafHash(types)
+	tree, indexedValues, quarantine, err := prepareMerkleTree(values, options, leafHash, StandardNodeHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare merkle tree: %w", err)
+	}
+
+	t := &StandardMerkleTree[[]any]{
+		MerkleTreeImpl: MerkleTreeImpl[[]any]{
+			Tree:     tree,
+			Values:   indexedValues,
+			LeafHash: leafHash,
+			NodeHash: StandardNodeHash,
+		},
+	}
+	t.algorithm = AlgorithmDescriptor{
+		LeafHash:  HashKeccak256ABI,
+		NodeHash:  HashKeccak256Sorted,
+		LeafOrder: options.leafOrder(),
+	}
+	t.compatibility = options.Compatibility
+	t.leafEncoding = slices.Clone(leafEncoding)
+	t.maxValueBytes = options.MaxValueBytes
+	t.shuffleSeed = slices.Clone(options.ShuffleSeed)
+	t.warnings = options.compatWarnings()
+	t.setQuarantine(quarantine)
+	t.setMetadata(withoutQuarantined(options.LeafMetadata, quarantine))
+	t.configureIndexes(options)
+	t.configurePregeneration(options)
+	if options.DropValuesAfterBuild {
+		t.dropValues()
+	}
+	return t, nil
+}
+
+// LoadOZStandardDump reads a dump written by the StandardMerkleTree of
+// @openzeppelin/merkle-tree, such as StandardMerkleTree.dump() writes, and
+// loads it with LoadStandardMerkleTree. The tree is kept as the dump has it;
+// each value is ABI-encoded with the dump's leafEncoding and checked against
+// its leaf, so Root, GetProof and Verify give what the JS library gives. A
+// dump without a leafEncoding is not one of OpenZeppelin's and fails with
+// ErrInvalidDump, as does one with a type ABIEncode does not support.
+func LoadOZStandardDump(r io.Reader) (*StandardMerkleTree[[]any], error) {
+	var data StandardMerkleTreeData[[]any]
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDump, err)
+	}
+	if data.LeafEncoding == nil {
+		return nil, fmt.Errorf("%w: no leafEncoding, so not an OpenZeppelin dump", ErrInvalidDump)
+	}
+	return LoadStandardMerkleTree(data)
+}
+
+// EncodedLeafHash returns the leaf of value in a tree built by
+// NewStandardMerkleTreeWithEncoding with leafEncoding:
+// keccak256(keccak256(ABIEncode(leafEncoding, value))).
+func EncodedLeafHash(leafEncoding []string, value []any) (HexString, error) {
+	encoded, err := ABIEncode(leafEncoding, value)
+	if err != nil {
+		return "", err
+	}
+	return keccak256Hex(keccak256(encoded)), nil
+}
+
+// encodedLeafHash returns the leaf hash of a tree with the given types. It
+// returns "" for a value that does not fit them, as the standard hashes do.
+func encodedLeafHash(types []*abiType) func([]any) HexString {
+	return func(value []any) HexString {
+		encoded, err := encodeLeaf(types, value)
+		if err != nil {
+			return ""
+		}
+		return keccak256Hex(keccak256(encoded))
+	}
+}
+
+// LeafEncoding returns the Solidity types the tree's values are ABI-encoded
+// with, or nil if it was not built with NewStandardMerkleTreeWithEncoding or
+// loaded from a dump that records them.
+func (m *MerkleTreeImpl[T]) LeafEncoding() []string {
+	return slices.Clone(m.leafEncoding)
+}
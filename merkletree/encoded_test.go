@@ -0,0 +1,197 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// ozReadmeDump is the dump @openzeppelin/merkle-tree writes for the
+// allowlist of its README, StandardMerkleTree.of(values, ["address", "uint256"]).
+const ozReadmeDump = `{
+  "format": "standard-v1",
+  "leafEncoding": ["address", "uint256"],
+  "tree": [
+    "0xd4dee0beab2d53f2cc83e567171bd2820e49898130a22622b10ead383e90bd77",
+    "0xeb02c421cfa48976e66dfb29120745909ea3a0f843456c263cf8f1253483e283",
+    "0xb92c48e9d7abe27fd8dfd6b5dfdbfb1c9a463f80c712b66f3a5180a090cccafc"
+  ],
+  "values": [
+    {"value": ["0x1111111111111111111111111111111111111111", "5000000000000000000"], "treeIndex": 1},
+    {"value": ["0x2222222222222222222222222222222222222222", "2500000000000000000"], "treeIndex": 2}
+  ]
+}`
+
+func ozReadmeValues() [][]any {
+	return [][]any{
+		{"0x1111111111111111111111111111111111111111", "5000000000000000000"},
+		{"0x2222222222222222222222222222222222222222", "2500000000000000000"},
+	}
+}
+
+func TestNewStandardMerkleTreeWithEncoding(t *testing.T) {
+	var want StandardMerkleTreeData[[]any]
+	if err := json.Unmarshal([]byte(ozReadmeDump), &want); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+
+	tree, err := NewStandardMerkleTreeWithEncoding(ozReadmeValues(), []string{"address", "uint256"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if !slices.Equal(tree.Tree, want.Tree) {
+		t.Fatalf("Tree %v, OpenZeppelin builds %v", tree.Tree, want.Tree)
+	}
+	for i, value := range ozReadmeValues() {
+		leaf, err := EncodedLeafHash([]string{"address", "uint256"}, value)
+		if err != nil || leaf != want.Tree[want.Values[i].TreeIndex] {
+			t.Errorf("EncodedLeafHash(%v) = %s, %v; want %s", value, leaf, err, want.Tree[want.Values[i].TreeIndex])
+		}
+		proof, err := tree.GetProof(i)
+		if err != nil {
+			t.Fatalf("GetProof(%d) failed: %v", i, err)
+		}
+		if valid, err := tree.Verify(i, proof); err != nil || !valid {
+			t.Errorf("Proof of value %d does not verify: %v", i, err)
+		}
+	}
+	if got := tree.Algorithm().LeafHash; got != HashKeccak256ABI {
+		t.Errorf("Algorithm leaf hash %q, want %q", got, HashKeccak256ABI)
+	}
+
+	dump, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	if !slices.Equal(dump.LeafEncoding, []string{"address", "uint256"}) {
+		t.Errorf("Dump leafEncoding %v", dump.LeafEncoding)
+	}
+}
+
+func TestLoadOpenZeppelinDump(t *testing.T) {
+	var data StandardMerkleTreeData[[]any]
+	if err := json.Unmarshal([]byte(ozReadmeDump), &data); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	loaded, err := LoadStandardMerkleTree(data)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Root() != data.Tree[0] || loaded.Algorithm().LeafHash != HashKeccak256ABI {
+		t.Errorf("Loaded root %s (%+v), want %s", loaded.Root(), loaded.Algorithm(), data.Tree[0])
+	}
+	if !slices.Equal(loaded.LeafEncoding(), data.LeafEncoding) {
+		t.Errorf("Loaded leaf encoding %v, want %v", loaded.LeafEncoding(), data.LeafEncoding)
+	}
+
+	// A dump of the loaded tree loads again
+	dump, err := loaded.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	encoded, err := json.Marshal(dump)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	var again StandardMerkleTreeData[[]any]
+	if err := json.Unmarshal(encoded, &again); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if reloaded, err := LoadStandardMerkleTree(again); err != nil || reloaded.Root() != data.Tree[0] {
+		t.Errorf("Reloaded dump: %v", err)
+	}
+
+	// Its values must load as []any
+	var typed StandardMerkleTreeData[[]string]
+	if err := json.Unmarshal([]byte(ozReadmeDump), &typed); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if _, err := LoadStandardMerkleTree(typed); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected []string values to be rejected, got %v", err)
+	}
+
+	unsupported := data
+	unsupported.LeafEncoding = []string{"address", "fixed128x18"}
+	if _, err := LoadStandardMerkleTree(unsupported); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected an unsupported type to be rejected, got %v", err)
+	}
+}
+
+func TestNewStandardMerkleTreeWithEncodingErrors(t *testing.T) {
+	encoding := []string{"address", "uint256"}
+	if _, err := NewStandardMerkleTreeWithEncoding(ozReadmeValues(), []string{"address", "uint7"}, MerkleTreeOptions{}); !errors.Is(err, ErrInvalidLeafEncoding) {
+		t.Errorf("Expected ErrInvalidLeafEncoding, got %v", err)
+	}
+	if _, err := NewStandardMerkleTreeWithEncoding(ozReadmeValues(), encoding, MerkleTreeOptions{Compatibility: CompatV0}); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("Expected CompatV0 to be rejected, got %v", err)
+	}
+
+	values := append(ozReadmeValues(), []any{"0x1234", "1"}, []any{"0x3333333333333333333333333333333333333333"})
+	_, err := NewStandardMerkleTreeWithEncoding(values, encoding, MerkleTreeOptions{})
+	var inputErr *InputError
+	if !errors.Is(err, ErrInvalidValue) || !errors.As(err, &inputErr) || inputErr.Index != 2 {
+		t.Errorf("Expected an *InputError for value 2, got %v", err)
+	}
+
+	tree, err := NewStandardMerkleTreeWithEncoding(values, encoding, MerkleTreeOptions{QuarantineInvalid: true})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if tree.Root() != "0xd4dee0beab2d53f2cc83e567171bd2820e49898130a22622b10ead383e90bd77" {
+		t.Errorf("Quarantined tree root %s", tree.Root())
+	}
+}
+
+// ozStandardFixture is ozReadmeDump as the README's
+// fs.writeFileSync("tree.json", JSON.stringify(tree.dump())) writes it.
+const ozStandardFixture = "testdata/oz_standard_v1.json"
+
+func TestLoadOZStandardDump(t *testing.T) {
+	file, err := os.Open(ozStandardFixture)
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer file.Close()
+	tree, err := LoadOZStandardDump(file)
+	if err != nil {
+		t.Fatalf("LoadOZStandardDump failed: %v", err)
+	}
+
+	var want StandardMerkleTreeData[[]any]
+	if err := json.Unmarshal([]byte(ozReadmeDump), &want); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if tree.Root() != want.Tree[0] || !slices.Equal(tree.Tree, want.Tree) {
+		t.Errorf("Loaded tree %v, want %v", tree.Tree, want.Tree)
+	}
+	// In a tree of two leaves each proof is the other leaf
+	for i, v := range want.Values {
+		proof, err := tree.GetProof(i)
+		if err != nil {
+			t.Fatalf("GetProof(%d) failed: %v", i, err)
+		}
+		if sibling := want.Tree[3-v.TreeIndex]; !slices.Equal(proof, []HexString{sibling}) {
+			t.Errorf("GetProof(%d) = %v, want [%s]", i, proof, sibling)
+		}
+		if valid, err := tree.Verify(i, proof); err != nil || !valid {
+			t.Errorf("Proof of value %d does not verify: %v", i, err)
+		}
+		if valid, err := tree.Verify(v.Value, proof); err != nil || !valid {
+			t.Errorf("Proof of %v does not verify: %v", v.Value, err)
+		}
+	}
+
+	for _, bad := range []string{
+		`{"format": "standard-v1", "tree": ["0x00"], "values": []}`,
+		strings.Replace(ozReadmeDump, `"5000000000000000000"`, `"5000000000000000001"`, 1),
+		strings.Replace(ozReadmeDump, `"uint256"]`, `"fixed128x18"]`, 1),
+		`{"format":`,
+	} {
+		if _, err := LoadOZStandardDump(strings.NewReader(bad)); !errors.Is(err, ErrInvalidDump) {
+			t.Errorf("LoadOZStandardDump(%.40s) = %v, want ErrInvalidDump", bad, err)
+		}
+	}
+}
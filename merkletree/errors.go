@@ -1,33 +1,261 @@
 package merkletree
 
-import "errors"
+import "fmt"
 
 // Common errors returned by the merkletree package.
 var (
 	// ErrInvalidIndex is returned when an index is out of bounds.
-	ErrInvalidIndex = errors.New("index out of bounds")
+	ErrInvalidIndex = NewCodedError(CodeInvalidIndex, "index out of bounds")
 
 	// ErrValueNotFound is returned when a requested value does not exist in the tree.
-	ErrValueNotFound = errors.New("value not found in merkle tree")
+	ErrValueNotFound = NewCodedError(CodeValueNotFound, "value not found in merkle tree")
 
 	// ErrInvalidProof is returned when a proof verification fails.
-	ErrInvalidProof = errors.New("invalid merkle proof")
+	ErrInvalidProof = NewCodedError(CodeInvalidProof, "invalid merkle proof")
 
 	// ErrEmptyTree is returned when attempting to build a tree with no elements.
-	ErrEmptyTree = errors.New("cannot build merkle tree with zero elements")
+	ErrEmptyTree = NewCodedError(CodeEmptyTree, "cannot build merkle tree with zero elements")
 
 	// ErrInvalidNode is returned when a merkle node is not 32 bytes.
-	ErrInvalidNode = errors.New("merkle tree nodes must be 32 bytes")
+	ErrInvalidNode = NewCodedError(CodeInvalidNode, "merkle tree nodes must be 32 bytes")
 
 	// ErrNotLeafNode is returned when an index doesn't correspond to a leaf.
-	ErrNotLeafNode = errors.New("index is not a leaf node")
+	ErrNotLeafNode = NewCodedError(CodeNotLeafNode, "index is not a leaf node")
 
 	// ErrInvalidMultiProof is returned when multi-proof verification fails.
-	ErrInvalidMultiProof = errors.New("invalid multi-proof")
+	ErrInvalidMultiProof = NewCodedError(CodeInvalidMultiProof, "invalid multi-proof")
 
 	// ErrRootHasNoParent is returned when trying to get the parent of the root node.
-	ErrRootHasNoParent = errors.New("root node has no parent")
+	ErrRootHasNoParent = NewCodedError(CodeRootHasNoParent, "root node has no parent")
 
 	// ErrRootHasNoSibling is returned when trying to get the sibling of the root node.
-	ErrRootHasNoSibling = errors.New("root node has no sibling")
+	ErrRootHasNoSibling = NewCodedError(CodeRootHasNoSibling, "root node has no sibling")
+
+	// ErrAmbiguousValue is returned when a value occurs more than once and the
+	// tree requires lookups of duplicated values to use an index.
+	ErrAmbiguousValue = NewCodedError(CodeAmbiguousValue, "value occurs more than once in merkle tree")
+
+	// ErrInvalidOptions is wrapped by every *OptionError.
+	ErrInvalidOptions = NewCodedError(CodeInvalidOptions, "invalid merkle tree options")
+
+	// ErrTooManyLeaves is returned when the input has more values than MaxLeaves.
+	ErrTooManyLeaves = NewCodedError(CodeTooManyLeaves, "too many leaves")
+
+	// ErrInvalidValue is returned when a value cannot be hashed into a valid leaf.
+	ErrInvalidValue = NewCodedError(CodeInvalidValue, "invalid leaf value")
+
+	// ErrInvalidWitnessOptions is returned when circuit witness options are inconsistent.
+	ErrInvalidWitnessOptions = NewCodedError(CodeInvalidWitnessOptions, "invalid witness options")
+
+	// ErrPairOrderMismatch is returned when the requested witness selector
+	// semantics do not match how the tree's node hash orders its inputs.
+	ErrPairOrderMismatch = NewCodedError(CodePairOrderMismatch, "pair order does not match node hash")
+
+	// ErrFieldOverflow is returned when a hash does not fit the witness field modulus.
+	ErrFieldOverflow = NewCodedError(CodeFieldOverflow, "value exceeds field modulus")
+
+	// ErrInvalidHashPrefix is returned when a hash prefix is empty, too long, or not hex.
+	ErrInvalidHashPrefix = NewCodedError(CodeInvalidHashPrefix, "invalid hash prefix")
+
+	// ErrInvalidDump is returned when a tree dump cannot be loaded, including
+	// when its tree does not recompute with the hash it names.
+	ErrInvalidDump = NewCodedError(CodeInvalidDump, "invalid tree dump")
+
+	// ErrSelfTest is returned by SelfTest when a primitive disagrees with its known answer.
+	ErrSelfTest = NewCodedError(CodeSelfTest, "self test failed")
+
+	// ErrQuarantined is returned when looking up a value that was left out of
+	// the tree under QuarantineInvalid.
+	ErrQuarantined = NewCodedError(CodeQuarantined, "value was quarantined")
+
+	// ErrInvalidCalldata is returned when claim calldata cannot be decoded.
+	ErrInvalidCalldata = NewCodedError(CodeInvalidCalldata, "invalid calldata")
+
+	// ErrValuesDropped is returned when a value is needed from a tree built
+	// with DropValuesAfterBuild that has no ValueProvider.
+	ErrValuesDropped = NewCodedError(CodeValuesDropped, "values were dropped after build")
+
+	// ErrMissingCapability is returned by RequireCapabilities when this
+	// version of the library lacks a requested feature.
+	ErrMissingCapability = NewCodedError(CodeMissingCapability, "missing capability")
+
+	// ErrTruncatedDump is returned when a dump's integrity footer is missing
+	// or does not match, which usually means the file was cut short.
+	ErrTruncatedDump = NewCodedError(CodeTruncatedDump, "truncated or corrupted dump")
+
+	// ErrInvalidTree is returned when a tree's nodes do not recompute from
+	// their children or are not 32-byte hashes.
+	ErrInvalidTree = NewCodedError(CodeCorruptTree, "merkle tree structure is invalid")
+
+	// ErrCheckpointMismatch is returned when resuming a LeafSet checkpoint
+	// with options that build a different tree than the checkpoint's.
+	ErrCheckpointMismatch = NewCodedError(CodeCheckpointMismatch, "checkpoint was written with different options")
+
+	// ErrProbeLimit is returned by ProbeProof when no configuration matched
+	// and some were not tried because they would have exceeded MaxAttempts.
+	ErrProbeLimit = NewCodedError(CodeProbeLimit, "probe attempt limit reached")
+
+	// ErrRecordFailed is returned by ExportClaim when the issuance recorder
+	// failed under RecordFailClosed.
+	ErrRecordFailed = NewCodedError(CodeRecordFailed, "recording issued proof failed")
+
+	// ErrInvalidRoot is returned by ParseRoot for strings that are not a 32-byte root.
+	ErrInvalidRoot = NewCodedError(CodeInvalidRoot, "invalid root")
+
+	// ErrInvalidPageToken is returned for a page token that is malformed or
+	// does not point at the same leaf of this tree.
+	ErrInvalidPageToken = NewCodedError(CodeInvalidPageToken, "invalid page token")
+
+	// ErrResourceLimit is returned when a build exceeds its ResourceLimits.
+	// The error is a *ResourceLimitError saying which limit and how far the build got.
+	ErrResourceLimit = NewCodedError(CodeResourceLimit, "resource limit exceeded")
+
+	// ErrSeedWithheld is returned by AppendLeaves on a shuffled tree whose
+	// seed was withheld, as the new leaves cannot be placed without it.
+	ErrSeedWithheld = NewCodedError(CodeSeedWithheld, "shuffle seed withheld")
+
+	// ErrInvalidPackedProof is returned for a packed proof that is not a
+	// whole number of 32-byte nodes.
+	ErrInvalidPackedProof = NewCodedError(CodeInvalidPackedProof, "invalid packed proof")
+
+	// ErrValueTooLarge is returned for a value over MaxValueBytes. The error
+	// is a *ValueSizeError naming the value and its size.
+	ErrValueTooLarge = NewCodedError(CodeValueTooLarge, "value too large")
+
+	// ErrInvalidLeafType is returned when a leaf passed to GetProof, Verify or
+	// another lookup is neither a value index nor a value the tree can hash.
+	ErrInvalidLeafType = NewCodedError(CodeInvalidLeafType, "invalid leaf type")
+
+	// ErrHashFailed is returned when a hash function returned no hash, which
+	// the standard hashes do for input they cannot encode.
+	ErrHashFailed = NewCodedError(CodeHashFailed, "hash failed")
+
+	// ErrInvalidLeafEncoding is returned when a leaf encoding names a
+	// Solidity type that ABIEncode does not support.
+	ErrInvalidLeafEncoding = NewCodedError(CodeInvalidLeafEncoding, "invalid leaf encoding")
+
+	// ErrOddLengthHex is returned when a 0x-prefixed string has an odd
+	// number of hex digits.
+	ErrOddLengthHex = NewCodedError(CodeOddLengthHex, "odd-length hex string")
+
+	// ErrUnsortedTree is returned when a feature that relies on sorted leaves,
+	// such as non-inclusion proofs, is used on a tree whose leaves are not.
+	ErrUnsortedTree = NewCodedError(CodeUnsortedTree, "tree leaves are not sorted")
+
+	// ErrValueIncluded is returned by GetNonInclusionProof for a value that
+	// is in the tree.
+	ErrValueIncluded = NewCodedError(CodeValueIncluded, "value is in merkle tree")
 )
+
+// OptionError describes a single invalid option. It wraps ErrInvalidOptions.
+type OptionError struct {
+	Option string // Name of the option field
+	Value  any    // The rejected value
+	Reason string // Why the value is invalid
+}
+
+// Error implements the error interface.
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("option %s=%v: %s", e.Option, e.Value, e.Reason)
+}
+
+// Unwrap returns ErrInvalidOptions.
+func (e *OptionError) Unwrap() error {
+	return ErrInvalidOptions
+}
+
+// ErrorCode returns CodeInvalidOptions.
+func (e *OptionError) ErrorCode() string {
+	return CodeInvalidOptions
+}
+
+// InputError describes a single invalid input value.
+type InputError struct {
+	Index int   // Index of the value in the input
+	Err   error // What is wrong with it
+}
+
+// Error implements the error interface.
+func (e *InputError) Error() string {
+	return fmt.Sprintf("value %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode returns the code of the wrapped error, or CodeInvalidValue if it
+// has none.
+func (e *InputError) ErrorCode() string {
+	if code := Code(e.Err); code != "" {
+		return code
+	}
+	return CodeInvalidValue
+}
+
+// DriftError describes one difference between how a proof was generated and
+// what VerifyStandardMerkleTree expects. It wraps ErrInvalidProof.
+type DriftError struct {
+	Drift  VerificationDrift // Kind of difference
+	Detail string            // The difference in words
+}
+
+// Error implements the error interface.
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Drift, e.Detail)
+}
+
+// Unwrap returns ErrInvalidProof.
+func (e *DriftError) Unwrap() error {
+	return ErrInvalidProof
+}
+
+// ErrorCode returns CodeInvalidProof.
+func (e *DriftError) ErrorCode() string {
+	return CodeInvalidProof
+}
+
+// FormatError reports a dump whose format this version cannot read. It wraps
+// ErrInvalidDump.
+type FormatError struct {
+	Format string // The format the dump names
+}
+
+// Error implements the error interface.
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("%v: unsupported format %q", ErrInvalidDump, e.Format)
+}
+
+// Unwrap returns ErrInvalidDump.
+func (e *FormatError) Unwrap() error {
+	return ErrInvalidDump
+}
+
+// ErrorCode returns CodeInvalidDump.
+func (e *FormatError) ErrorCode() string {
+	return CodeInvalidDump
+}
+
+// ValueSizeError reports a value larger than MaxValueBytes. It wraps
+// ErrValueTooLarge.
+type ValueSizeError struct {
+	Index int // Index of the value in the input
+	Size  int // Size of the value in bytes
+	Limit int // MaxValueBytes
+}
+
+// Error implements the error interface.
+func (e *ValueSizeError) Error() string {
+	return fmt.Sprintf("value %d: %v: %d bytes, MaxValueBytes is %d", e.Index, ErrValueTooLarge, e.Size, e.Limit)
+}
+
+// Unwrap returns ErrValueTooLarge.
+func (e *ValueSizeError) Unwrap() error {
+	return ErrValueTooLarge
+}
+
+// ErrorCode returns CodeValueTooLarge.
+func (e *ValueSizeError) ErrorCode() string {
+	return CodeValueTooLarge
+}
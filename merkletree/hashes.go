@@ -2,10 +2,17 @@ package merkletree
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
+	"math/big"
+	"reflect"
+	"runtime"
 	"sort"
+	"strings"
 
-	"golang.org/x/crypto/sha3"
+	"github.com/smeneguz/GoMerkle/internal/keccak"
+	"golang.org/x/crypto/blake2b"
 )
 
 // LeafHash represents a function that computes the hash of a leaf.
@@ -31,11 +38,119 @@ func StandardLeafHash[T any](value T) HexString {
 	return encodedPackedHex
 }
 
+// BytesLeafHash hashes the bytes of value with Keccak256, reading them as
+// ToBytes does: a 0x-prefixed hex string or HexString is decoded, so
+// "0x2222", HexString("0x2222") and []byte{0x22, 0x22} hash alike. Values
+// ToBytes cannot read, such as strings that merely start with 0x, and
+// integers are hashed as StandardLeafHash hashes them. Simple trees hash
+// their values with it under CompatLatest.
+func BytesLeafHash(value BytesLike) HexString {
+	switch value.(type) {
+	case uint, uint8, uint16, uint32, uint64, *big.Int:
+		return StandardLeafHash(value) // At their packed width, not ToBytes's minimal one
+	}
+	data, err := ToBytes(value)
+	if err != nil {
+		return StandardLeafHash(value)
+	}
+	return StandardLeafHash(data)
+}
+
+// SHA256LeafHash hashes the bytes of value with SHA-256, reading them as
+// BytesLeafHash does, so "0x2222", HexString("0x2222") and []byte{0x22, 0x22}
+// hash alike. Integers and values ToBytes cannot read, such as plain
+// strings, are hashed in their packed encoding. Trees built with
+// NewSimpleMerkleTreeSHA256 hash their values with it.
+func SHA256LeafHash(value BytesLike) HexString {
+	data, err := leafBytes(value)
+	if err != nil {
+		return HexString("")
+	}
+	sum := sha256.Sum256(data)
+	return HexString(fmt.Sprintf("0x%x", sum))
+}
+
+// Blake2b256LeafHash hashes the bytes of value with BLAKE2b-256, reading
+// them as SHA256LeafHash does. Trees built with NewSimpleMerkleTreeBlake2b256
+// hash their values with it.
+func Blake2b256LeafHash(value BytesLike) HexString {
+	data, err := leafBytes(value)
+	if err != nil {
+		return HexString("")
+	}
+	sum := blake2b.Sum256(data)
+	return HexString(fmt.Sprintf("0x%x", sum))
+}
+
+// leafBytes returns the bytes SHA256LeafHash and Blake2b256LeafHash hash:
+// those of ToBytes, or the packed encoding of integers and of values ToBytes
+// cannot read.
+func leafBytes(value BytesLike) ([]byte, error) {
+	switch value.(type) {
+	case uint, uint8, uint16, uint32, uint64, *big.Int:
+		return abiEncodePacked(value) // At their packed width, not ToBytes's minimal one
+	}
+	if data, err := ToBytes(value); err == nil {
+		return data, nil
+	}
+	return abiEncodePacked(value)
+}
+
 // StandardNodeHash computes the standard hash of two child nodes.
 // It sorts the nodes lexicographically before hashing to ensure consistency
 // regardless of the order they are provided (this is important for proof verification).
 // Compatible with OpenZeppelin's Merkle tree implementation.
 func StandardNodeHash(a BytesLike, b BytesLike) HexString {
+	return sortedPairHash(a, b, func(data []byte) ([]byte, error) {
+		return keccak256HashedData(data)
+	})
+}
+
+// SHA256NodeHash computes the hash of two child nodes with SHA-256.
+// Like StandardNodeHash it sorts the nodes before hashing.
+func SHA256NodeHash(a BytesLike, b BytesLike) HexString {
+	return sortedPairHash(a, b, func(data []byte) ([]byte, error) {
+		sum := sha256.Sum256(data)
+		return sum[:], nil
+	})
+}
+
+// Blake2b256NodeHash computes the hash of two child nodes with BLAKE2b-256.
+// Like StandardNodeHash it sorts the nodes before hashing.
+func Blake2b256NodeHash(a BytesLike, b BytesLike) HexString {
+	return sortedPairHash(a, b, func(data []byte) ([]byte, error) {
+		sum := blake2b.Sum256(data)
+		return sum[:], nil
+	})
+}
+
+// PositionalNodeHash returns keccak256(left || right), without sorting the
+// nodes. Unlike the sorted-pair hashes above it records which side each node
+// is on, so a proof binds its leaf to a position, as sparse trees and
+// non-inclusion proofs need. It returns an empty hash if a node is invalid.
+func PositionalNodeHash(left, right BytesLike) HexString {
+	l, err := ToBytes(left)
+	if err != nil {
+		return ""
+	}
+	r, err := ToBytes(right)
+	if err != nil {
+		return ""
+	}
+	return HexString(fmt.Sprintf("0x%x", keccakSum(l, r)))
+}
+
+// nodeHashKeepsOrder reports whether nodeHash gives different hashes for the
+// two orders of a pair of nodes, as PositionalNodeHash does and the
+// sorted-pair hashes do not.
+func nodeHashKeepsOrder(nodeHash NodeHash) bool {
+	a, b := HexString("0x"+strings.Repeat("00", 32)), HexString("0x"+strings.Repeat("01", 32))
+	return nodeHash(a, b).Normalize() != nodeHash(b, a).Normalize()
+}
+
+// sortedPairHash sorts two nodes lexicographically, concatenates them and hashes
+// the result. It returns an empty hash if any step fails.
+func sortedPairHash(a BytesLike, b BytesLike, hash func([]byte) ([]byte, error)) HexString {
 	// Sort the two nodes to ensure consistency
 	nodes := []BytesLike{a, b}
 	sort.Slice(nodes, func(i, j int) bool {
@@ -51,7 +166,7 @@ func StandardNodeHash(a BytesLike, b BytesLike) HexString {
 		return HexString("")
 	}
 
-	hashed, err := keccak256HashedData(concatenated)
+	hashed, err := hash(concatenated)
 	if err != nil {
 		return HexString("")
 	}
@@ -66,51 +181,183 @@ func StandardNodeHash(a BytesLike, b BytesLike) HexString {
 
 // abiEncodePacked encodes arguments in a packed format similar to Solidity's abi.encodePacked.
 // It concatenates values without padding, which is different from standard ABI encoding.
+// An unsupported type is an error naming its Go type and argument position.
 func abiEncodePacked(args ...interface{}) ([]byte, error) {
 	var buf bytes.Buffer
 
-	for _, arg := range args {
+	for i, arg := range args {
 		switch v := arg.(type) {
 		case string:
 			buf.Write([]byte(v)) // Convert string to bytes without padding
 		case []byte:
 			buf.Write(v) // Write bytes directly
+		case bool:
+			buf.Write(packedBool(v))
 		case uint8, uint16, uint32, uint64, int8, int16, int32, int64:
 			buf.Write(uintToBytes(v)) // Convert integers to bytes
+		case Address:
+			buf.Write(v[:])
+		case U256:
+			buf.Write(v[:])
+		case *big.Int:
+			word, err := packedBigInt(v)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(word)
+		case []any:
+			packed, err := abiEncodePacked(v...) // A tuple, each field packed in turn
+			if err != nil {
+				return nil, fmt.Errorf("argument %d: %w", i, err)
+			}
+			buf.Write(packed)
+		case []int8:
+			buf.Write(packedInts(v))
+		case []int16:
+			buf.Write(packedInts(v))
+		case []int32:
+			buf.Write(packedInts(v))
+		case []int64:
+			buf.Write(packedInts(v))
+		case []uint16:
+			buf.Write(packedInts(v))
+		case []uint32:
+			buf.Write(packedInts(v))
+		case []uint64:
+			buf.Write(packedInts(v))
+		case []Address:
+			buf.Write(packedArray(v, func(a Address) []byte { return a[:] }, nil))
+		case []U256:
+			buf.Write(packedArray(v, func(u U256) []byte { return u[:] }, nil))
 		default:
-			return nil, fmt.Errorf("unsupported type in abiEncodePacked: %T", v)
+			if a, ok := addressOf(v); ok {
+				buf.Write(a[:])
+				continue
+			}
+			switch rv := reflect.ValueOf(v); {
+			case rv.Kind() == reflect.Array && rv.Type().Elem().Kind() == reflect.Uint8:
+				buf.Write(byteArray(rv)) // bytesN, written as is
+				continue
+			case rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array:
+				packed, err := packedElems(rv)
+				if err != nil {
+					return nil, fmt.Errorf("argument %d: %w", i, err)
+				}
+				buf.Write(packed)
+				continue
+			}
+			return nil, fmt.Errorf("unsupported type in abiEncodePacked: argument %d is %T", i, v)
 		}
 	}
 
 	return buf.Bytes(), nil
 }
 
+// packedElems encodes the elements of a slice or array as abi.encodePacked
+// encodes an array: each one padded to 32 bytes, integers sign- or
+// zero-extended, addresses left-aligned and bytesN right-aligned. Nested
+// slices are flattened into the words of their elements. Elements that are
+// themselves dynamic, strings and []byte, cannot be packed.
+func packedElems(rv reflect.Value) ([]byte, error) {
+	out := make([]byte, 0, abiWordSize*rv.Len())
+	for j := range rv.Len() {
+		elem := rv.Index(j)
+		for elem.Kind() == reflect.Interface && !elem.IsNil() {
+			elem = elem.Elem()
+		}
+		if _, ok := addressOf(elem.Interface()); ok {
+			packed, err := abiEncodePacked(elem.Interface())
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, leftPad(packed)...)
+			continue
+		}
+		switch {
+		case elem.Kind() == reflect.Array && elem.Type().Elem().Kind() == reflect.Uint8:
+			if elem.Len() > abiWordSize {
+				return nil, fmt.Errorf("element %d: %v is longer than a word", j, elem.Type())
+			}
+			out = append(out, rightPad(byteArray(elem))...)
+		case elem.Kind() == reflect.String, elem.Kind() == reflect.Slice && elem.Type().Elem().Kind() == reflect.Uint8:
+			return nil, fmt.Errorf("element %d: dynamic %v elements cannot be packed", j, elem.Type())
+		case elem.Kind() == reflect.Slice, elem.Kind() == reflect.Array:
+			packed, err := packedElems(elem)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", j, err)
+			}
+			out = append(out, packed...)
+		default:
+			packed, err := abiEncodePacked(elem.Interface())
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", j, err)
+			}
+			if len(packed) > abiWordSize {
+				return nil, fmt.Errorf("element %d: %v is longer than a word", j, elem.Type())
+			}
+			pad := byte(0)
+			if elem.CanInt() && elem.Int() < 0 {
+				pad = 0xff
+			}
+			for range abiWordSize - len(packed) {
+				out = append(out, pad)
+			}
+			out = append(out, packed...)
+		}
+	}
+	return out, nil
+}
+
+// byteArray returns the bytes of a [N]byte array.
+func byteArray(rv reflect.Value) []byte {
+	data := make([]byte, rv.Len())
+	reflect.Copy(reflect.ValueOf(data), rv)
+	return data
+}
+
+// packedBigInt encodes v as a uint256, a 32-byte big-endian word, as
+// abi.encodePacked does. Negative values and values over 256 bits are errors.
+func packedBigInt(v *big.Int) ([]byte, error) {
+	if v == nil {
+		return nil, fmt.Errorf("%w: nil *big.Int", ErrInvalidValue)
+	}
+	u, err := U256FromBig(v)
+	if err != nil {
+		return nil, err
+	}
+	return u[:], nil
+}
+
+// packedBool encodes a bool as abi.encodePacked does, as one byte.
+func packedBool(v bool) []byte {
+	if v {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
 // uintToBytes converts integer types to byte arrays without extra padding.
-// Uses big-endian byte order (most significant byte first).
+// Uses big-endian byte order (most significant byte first). Signed integers
+// are written in two's complement at their own width, so int16(-1) is 0xffff
+// as in Solidity.
 func uintToBytes(num interface{}) []byte {
 	switch v := num.(type) {
 	case uint8:
 		return []byte{v}
 	case uint16:
-		return []byte{byte(v >> 8), byte(v)}
+		return binary.BigEndian.AppendUint16(nil, v)
 	case uint32:
-		return []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+		return binary.BigEndian.AppendUint32(nil, v)
 	case uint64:
-		return []byte{
-			byte(v >> 56), byte(v >> 48), byte(v >> 40), byte(v >> 32),
-			byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v),
-		}
+		return binary.BigEndian.AppendUint64(nil, v)
 	case int8:
-		return []byte{byte(v)}
+		return []byte{uint8(v)}
 	case int16:
-		return []byte{byte(v >> 8), byte(v)}
+		return binary.BigEndian.AppendUint16(nil, uint16(v))
 	case int32:
-		return []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+		return binary.BigEndian.AppendUint32(nil, uint32(v))
 	case int64:
-		return []byte{
-			byte(v >> 56), byte(v >> 48), byte(v >> 40), byte(v >> 32),
-			byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v),
-		}
+		return binary.BigEndian.AppendUint64(nil, uint64(v))
 	default:
 		return nil
 	}
@@ -125,7 +372,16 @@ func keccak256HashedData(args ...interface{}) ([]byte, error) {
 	}
 
 	// Compute Keccak256 (Ethereum-specific SHA3)
-	hash := sha3.NewLegacyKeccak256()
-	hash.Write(encodedData)
-	return hash.Sum(nil), nil
+	hash := keccak.Sum256(encodedData)
+	return hash[:], nil
+}
+
+// SelectedBackend describes the Keccak-256 implementation in use, such as
+// "x/crypto (amd64 assembly)", for diagnostics. Without assembly, on other
+// architectures or with the purego tag, it is just "x/crypto".
+func SelectedBackend() string {
+	if keccak.Accelerated() {
+		return keccak.Selected() + " (" + runtime.GOARCH + " assembly)"
+	}
+	return keccak.Selected()
 }
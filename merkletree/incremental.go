@@ -0,0 +1,168 @@
+package merkletree
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// IncrementalMerkleTree is a tree that grows one value at a time. Values keep
+// their insertion order and the tree has the flat layout of
+// NewStandardMerkleTree, so its root is the root of the standard tree built
+// over the same values with the same options, for every number of values.
+//
+// The flat layout moves every leaf when one is added, so an append cannot
+// patch a single path: it hashes the new values and then every internal
+// node, reusing the leaf hashes of the values already in the tree. Appending
+// values together costs one such pass for all of them.
+//
+// Leaves are hashed as NewStandardMerkleTree hashes them under the same
+// Compatibility and nodes with StandardNodeHash, so proofs verify with
+// VerifyIncrementalMerkleTree, VerifyStandardMerkleTree and OpenZeppelin's
+// MerkleProof.
+type IncrementalMerkleTree[T any] struct {
+	leaves        []HexString // Leaf hashes in insertion order
+	tree          []HexString // Flat layout, as MerkleTreeImpl.Tree; nil while empty
+	values        []T
+	leafHash      func(T) HexString
+	compatibility CompatibilityMode
+	reversed      bool // Leaves are laid out last value first, as under CompatLatest
+	maxValueBytes int
+}
+
+// NewIncrementalMerkleTree builds an incremental tree over values, which may
+// be empty, with the leaf hash Compatibility selects. Appending values one by
+// one gives the same tree as passing them all here.
+//
+// The tree keeps insertion order, so options that order the leaves are
+// rejected with an *OptionError: SortLeaves set to true, SortDescending and
+// ShuffleSeed. Under CompatLatest, which sorts by default, PreserveOrder must
+// be set. Invalid values fail with *InputError entries joined with
+// errors.Join.
+func NewIncrementalMerkleTree[T any](values []T, options MerkleTreeOptions) (*IncrementalMerkleTree[T], error) {
+	const reason = "incremental trees keep insertion order"
+	switch {
+	case options.SortLeaves != nil && *options.SortLeaves:
+		return nil, &OptionError{Option: "SortLeaves", Value: true, Reason: reason}
+	case options.SortDescending:
+		return nil, &OptionError{Option: "SortDescending", Value: true, Reason: reason}
+	case options.ShuffleSeed != nil:
+		return nil, &OptionError{Option: "ShuffleSeed", Value: options.ShuffleSeed, Reason: reason}
+	case options.Compatibility == CompatLatest && !options.PreserveOrder:
+		return nil, &OptionError{Option: "PreserveOrder", Value: false, Reason: "must be set under CompatLatest, as " + reason}
+	}
+
+	t := &IncrementalMerkleTree[T]{
+		leafHash:      StandardLeafHash[T],
+		compatibility: options.Compatibility,
+		reversed:      options.leafOrder() == LeafOrderReversed,
+		maxValueBytes: options.MaxValueBytes,
+	}
+	if options.Compatibility == CompatLatest {
+		t.leafHash = OpenZeppelinLeafHash[T]
+	}
+	if err := t.Append(values...); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Append adds values to the end of the tree, hashing the new values and
+// then every internal node again. The root changes, so proofs issued before
+// no longer verify. Values that do not hash to a 32-byte leaf fail with
+// *InputError entries indexed by their would-be value index, and the tree is
+// left unchanged.
+func (t *IncrementalMerkleTree[T]) Append(values ...T) error {
+	if len(values) == 0 {
+		return nil
+	}
+	n := len(t.values)
+	leaves := make([]HexString, len(values))
+	var errs []error
+	for i, value := range values {
+		if err := checkValueSize(n+i, value, t.maxValueBytes); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		leaves[i] = t.leafHash(value)
+		if err := leafHashError(value, leaves[i]); err != nil {
+			errs = append(errs, &InputError{Index: n + i, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	all := append(slices.Clone(t.leaves), leaves...)
+	layout := make([]BytesLike, len(all))
+	for i, leaf := range all {
+		layout[t.position(i, len(all))] = leaf
+	}
+	tree, err := makeMerkleTree(layout, StandardNodeHash, 1, nil)
+	if err != nil {
+		return err
+	}
+	t.tree, t.leaves = tree, all
+	t.values = append(t.values, values...)
+	return nil
+}
+
+// position returns the position among n leaves, in the flat layout, of the
+// leaf of the value at index.
+func (t *IncrementalMerkleTree[T]) position(index, n int) int {
+	if t.reversed {
+		return n - 1 - index
+	}
+	return index
+}
+
+// Root returns the root of the tree, or "" while it is empty.
+func (t *IncrementalMerkleTree[T]) Root() HexString {
+	if len(t.tree) == 0 {
+		return ""
+	}
+	return t.tree[0]
+}
+
+// Len returns the number of values in the tree.
+func (t *IncrementalMerkleTree[T]) Len() int {
+	return len(t.values)
+}
+
+// Values returns the values in insertion order.
+func (t *IncrementalMerkleTree[T]) Values() []T {
+	return slices.Clone(t.values)
+}
+
+// GetProof returns the proof of the value at index, siblings nearest the leaf
+// first, as the standard tree over the same values gives it.
+func (t *IncrementalMerkleTree[T]) GetProof(index int) ([]HexString, error) {
+	if index < 0 || index >= len(t.values) {
+		return nil, fmt.Errorf("%w: value %d of %d", ErrInvalidIndex, index, len(t.values))
+	}
+	n := len(t.leaves)
+	proof := []HexString{}
+	for i := n - 1 + t.position(index, n); i > 0; i = parentIndex(i) {
+		proof = append(proof, t.tree[siblingIndex(i)])
+	}
+	return proof, nil
+}
+
+// Verify reports whether proof proves value against the tree's root.
+func (t *IncrementalMerkleTree[T]) Verify(value T, proof []HexString) (bool, error) {
+	return VerifyIncrementalMerkleTree(t.Root(), value, proof, t.compatibility)
+}
+
+// VerifyIncrementalMerkleTree reports whether proof proves value against root
+// in an incremental tree built under mode.
+func VerifyIncrementalMerkleTree[T any](root BytesLike, value T, proof []HexString, mode CompatibilityMode) (bool, error) {
+	leaf := StandardLeafHash(value)
+	if mode == CompatLatest {
+		leaf = OpenZeppelinLeafHash(value)
+	}
+	nodes := make([]BytesLike, len(proof))
+	for i, node := range proof {
+		nodes[i] = node
+	}
+	return verifySimpleLeaf(root, leaf, nodes, StandardNodeHash)
+}
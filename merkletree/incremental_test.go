@@ -0,0 +1,174 @@
+package merkletree
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestIncrementalMerkleTreeAppend(t *testing.T) {
+	configs := map[string]MerkleTreeOptions{
+		"v0":     {Compatibility: CompatV0},
+		"latest": {Compatibility: CompatLatest, PreserveOrder: true},
+	}
+	values := appendValues(33)
+
+	for name, options := range configs {
+		t.Run(name, func(t *testing.T) {
+			tree, err := NewIncrementalMerkleTree[string](nil, options)
+			if err != nil {
+				t.Fatalf("Failed to create tree: %v", err)
+			}
+			if tree.Root() != "" {
+				t.Errorf("Root of the empty tree = %s, want \"\"", tree.Root())
+			}
+			for n := 1; n <= len(values); n++ {
+				if err := tree.Append(values[n-1]); err != nil {
+					t.Fatalf("Append failed: %v", err)
+				}
+				want, err := NewIncrementalMerkleTree(values[:n], options)
+				if err != nil {
+					t.Fatalf("Failed to create tree: %v", err)
+				}
+				if tree.Root() != want.Root() || tree.Len() != n {
+					t.Fatalf("Root after %d appends %s, built at once %s", n, tree.Root(), want.Root())
+				}
+				for i := range n {
+					proof, err := tree.GetProof(i)
+					if err != nil {
+						t.Fatalf("GetProof(%d) failed: %v", i, err)
+					}
+					if ok, err := tree.Verify(values[i], proof); err != nil || !ok {
+						t.Fatalf("Proof of value %d of %d does not verify: %v", i, n, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestIncrementalMerkleTreeMatchesStandard(t *testing.T) {
+	configs := map[string]MerkleTreeOptions{
+		"v0":     {Compatibility: CompatV0},
+		"latest": {Compatibility: CompatLatest, PreserveOrder: true},
+	}
+	values := appendValues(33)
+
+	for name, options := range configs {
+		t.Run(name, func(t *testing.T) {
+			tree, err := NewIncrementalMerkleTree[string](nil, options)
+			if err != nil {
+				t.Fatalf("Failed to create tree: %v", err)
+			}
+			for n := 1; n <= len(values); n++ {
+				if err := tree.Append(values[n-1]); err != nil {
+					t.Fatalf("Append failed: %v", err)
+				}
+				standard, err := NewStandardMerkleTree(values[:n], options)
+				if err != nil {
+					t.Fatalf("Failed to create tree: %v", err)
+				}
+				if tree.Root() != standard.Root() {
+					t.Fatalf("Root of %d leaves %s, standard tree %s", n, tree.Root(), standard.Root())
+				}
+				for i := range n {
+					proof, _ := tree.GetProof(i)
+					if want, _ := standard.GetProof(i); !slices.Equal(proof, want) {
+						t.Fatalf("Proof of value %d of %d differs from the standard tree's", i, n)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestIncrementalMerkleTreeProofs(t *testing.T) {
+	values := []string{"a", "b", "c", "d", "e"}
+	tree, err := NewIncrementalMerkleTree(values, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	proof, err := tree.GetProof(4)
+	if err != nil {
+		t.Fatalf("GetProof failed: %v", err)
+	}
+	if ok, err := VerifyStandardMerkleTree(tree.Root(), "e", treeNodes(proof)); err != nil || !ok {
+		t.Errorf("Proof does not verify as a standard tree proof: %v", err)
+	}
+	if ok, _ := VerifyIncrementalMerkleTree(tree.Root(), "d", proof, CompatV0); ok {
+		t.Error("A proof should not verify for another value")
+	}
+	if _, err := tree.GetProof(5); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("Expected ErrInvalidIndex, got %v", err)
+	}
+
+	old, _ := tree.GetProof(0)
+	if err := tree.Append("f"); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if ok, _ := tree.Verify("a", old); ok {
+		t.Error("A proof issued before the append should not verify against the new root")
+	}
+}
+
+func TestIncrementalMerkleTreeErrors(t *testing.T) {
+	for _, options := range []MerkleTreeOptions{
+		{SortLeaves: Bool(true)},
+		{SortDescending: true},
+		{ShuffleSeed: []byte("seed")},
+		{Compatibility: CompatLatest},
+	} {
+		var optErr *OptionError
+		if _, err := NewIncrementalMerkleTree([]string{"a"}, options); !errors.Is(err, ErrInvalidOptions) || !errors.As(err, &optErr) {
+			t.Errorf("Options %+v: expected an OptionError, got %v", options, err)
+		}
+	}
+
+	tree, err := NewIncrementalMerkleTree([]any{"a", "b"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	root := tree.Root()
+	err = tree.Append("c", 3.5)
+	var inputErr *InputError
+	if !errors.Is(err, ErrInvalidValue) || !errors.As(err, &inputErr) || inputErr.Index != 3 {
+		t.Errorf("Expected an InputError at value index 3, got %v", err)
+	}
+	if tree.Root() != root || tree.Len() != 2 {
+		t.Error("A failed append should leave the tree unchanged")
+	}
+}
+
+// BenchmarkIncrementalAppend appends 100 values one at a time to a tree of
+// 10,000, against AppendLeaves on a standard tree. Both hash every internal
+// node again on each append.
+func BenchmarkIncrementalAppend(b *testing.B) {
+	values := make([]string, 10_100)
+	for i := range values {
+		values[i] = fmt.Sprintf("value-%d", i)
+	}
+	base, added := values[:10_000], values[10_000:]
+	options := MerkleTreeOptions{SortLeaves: Bool(false), Compatibility: CompatV0}
+
+	b.Run("incremental", func(b *testing.B) {
+		for range b.N {
+			b.StopTimer()
+			tree, _ := NewIncrementalMerkleTree(base, options)
+			b.StartTimer()
+			for _, value := range added {
+				tree.Append(value)
+			}
+		}
+	})
+	b.Run("AppendLeaves", func(b *testing.B) {
+		for range b.N {
+			b.StopTimer()
+			tree, _ := NewStandardMerkleTree(base, options)
+			b.StartTimer()
+			for _, value := range added {
+				tree.AppendLeaves([]string{value})
+			}
+		}
+	})
+}
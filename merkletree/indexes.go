@@ -0,0 +1,88 @@
+package merkletree
+
+import (
+	"context"
+	"sync"
+)
+
+// treeIndexes guards the indexes derived from the leaves, HashLookup and the
+// prefix index. Each is built once, on first use, however many goroutines
+// ask for it at the same time. A change to the leaves replaces the guards,
+// so the indexes are built again from the new leaves.
+type treeIndexes struct {
+	lookup sync.Once // Builds HashLookup and the duplicate occurrences
+	prefix sync.Once // Builds the prefix index
+
+	prefixIndex bool // The prefix index was requested with PrefixIndex
+	eager       bool // Indexes are built when the leaves are, under EagerIndexes
+}
+
+// configureIndexes applies the index options to the tree. Under EagerIndexes
+// the indexes are built at once; otherwise on first use.
+func (m *MerkleTreeImpl[T]) configureIndexes(options MerkleTreeOptions) {
+	m.duplicatePolicy = options.Duplicates
+	m.maxPrefixResults = options.MaxPrefixResults
+	if m.maxPrefixResults <= 0 {
+		m.maxPrefixResults = DefaultMaxPrefixResults
+	}
+	m.resetIndexes(&treeIndexes{prefixIndex: options.PrefixIndex, eager: options.EagerIndexes})
+}
+
+// invalidateIndexes drops the indexes after the leaves changed, keeping the
+// options they were configured with. It is not safe to call concurrently
+// with lookups, like any change to the leaves.
+func (m *MerkleTreeImpl[T]) invalidateIndexes() {
+	next := &treeIndexes{}
+	if m.indexes != nil {
+		next.prefixIndex, next.eager = m.indexes.prefixIndex, m.indexes.eager
+	}
+	m.resetIndexes(next)
+}
+
+// resetIndexes installs new guards and, if they are eager, builds the indexes.
+func (m *MerkleTreeImpl[T]) resetIndexes(indexes *treeIndexes) {
+	m.HashLookup, m.duplicates, m.prefixIndex = nil, nil, nil
+	m.indexes = indexes
+	if indexes.eager {
+		_ = m.WarmIndexes(context.Background())
+	}
+}
+
+// hashLookup returns HashLookup, building it on first use. The duplicate
+// occurrences are built with it.
+func (m *MerkleTreeImpl[T]) hashLookup() map[HexString]int {
+	if m.indexes != nil {
+		m.indexes.lookup.Do(m.buildHashLookup)
+	}
+	return m.HashLookup
+}
+
+// sortedPrefixIndex returns the prefix index, building it on first use, or
+// nil if PrefixIndex was not requested.
+func (m *MerkleTreeImpl[T]) sortedPrefixIndex() []prefixEntry {
+	if m.indexes != nil && m.indexes.prefixIndex {
+		m.indexes.prefix.Do(func() { m.prefixIndex = m.sortedLeafEntries() })
+	}
+	return m.prefixIndex
+}
+
+// WarmIndexes builds the indexes that are otherwise built on first use:
+// HashLookup, and the prefix index if PrefixIndex was requested. Servers call
+// it before taking traffic so the first requests do not pay for the build;
+// EagerIndexes does the same when the tree is built. Indexes already built
+// are kept. If ctx ends between two indexes, the rest are left for first use
+// and ctx.Err() is returned.
+//
+// HashLookup is only filled in once built, so code reading it directly
+// should call WarmIndexes first.
+func (m *MerkleTreeImpl[T]) WarmIndexes(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.hashLookup()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.sortedPrefixIndex()
+	return nil
+}
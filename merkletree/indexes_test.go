@@ -0,0 +1,234 @@
+package merkletree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// indexTestValues returns n distinct string values.
+func indexTestValues(n int) []string {
+	values := make([]string, n)
+	for i := range values {
+		values[i] = fmt.Sprintf("value-%d", i)
+	}
+	return values
+}
+
+func TestIndexesBuiltOnFirstUse(t *testing.T) {
+	tree, err := NewStandardMerkleTree(indexTestValues(16), MerkleTreeOptions{PrefixIndex: true})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if tree.HashLookup != nil || tree.prefixIndex != nil {
+		t.Fatalf("Indexes were built with the tree")
+	}
+
+	if _, err := tree.GetProof("value-3"); err != nil {
+		t.Fatalf("GetProof failed: %v", err)
+	}
+	if len(tree.HashLookup) != 16 {
+		t.Errorf("HashLookup has %d entries after a lookup, want 16", len(tree.HashLookup))
+	}
+	if tree.prefixIndex != nil {
+		t.Errorf("Prefix index was built by a value lookup")
+	}
+
+	if _, err := tree.FindByHashPrefix(leafHashOf(&tree.MerkleTreeImpl, 5)[:8]); err != nil {
+		t.Fatalf("FindByHashPrefix failed: %v", err)
+	}
+	if len(tree.prefixIndex) != 16 {
+		t.Errorf("Prefix index has %d entries after a search, want 16", len(tree.prefixIndex))
+	}
+}
+
+func TestEagerIndexes(t *testing.T) {
+	tree, err := NewStandardMerkleTree(indexTestValues(16), MerkleTreeOptions{PrefixIndex: true, EagerIndexes: true})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if len(tree.HashLookup) != 16 || len(tree.prefixIndex) != 16 {
+		t.Errorf("EagerIndexes built %d lookup and %d prefix entries, want 16 each",
+			len(tree.HashLookup), len(tree.prefixIndex))
+	}
+
+	// Without PrefixIndex there is no prefix index to build
+	tree, err = NewStandardMerkleTree(indexTestValues(16), MerkleTreeOptions{EagerIndexes: true})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if len(tree.HashLookup) != 16 || tree.prefixIndex != nil {
+		t.Errorf("EagerIndexes built %d lookup and %d prefix entries, want 16 and none",
+			len(tree.HashLookup), len(tree.prefixIndex))
+	}
+}
+
+func TestWarmIndexes(t *testing.T) {
+	tree, err := NewStandardMerkleTree(indexTestValues(16), MerkleTreeOptions{PrefixIndex: true})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := tree.WarmIndexes(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if tree.HashLookup != nil {
+		t.Errorf("WarmIndexes built HashLookup after ctx ended")
+	}
+
+	if err := tree.WarmIndexes(context.Background()); err != nil {
+		t.Fatalf("WarmIndexes failed: %v", err)
+	}
+	if len(tree.HashLookup) != 16 || len(tree.prefixIndex) != 16 {
+		t.Errorf("WarmIndexes built %d lookup and %d prefix entries, want 16 each",
+			len(tree.HashLookup), len(tree.prefixIndex))
+	}
+
+	// A second call keeps what was built
+	lookup := tree.HashLookup
+	if err := tree.WarmIndexes(context.Background()); err != nil {
+		t.Fatalf("WarmIndexes failed: %v", err)
+	}
+	if fmt.Sprintf("%p", tree.HashLookup) != fmt.Sprintf("%p", lookup) {
+		t.Errorf("WarmIndexes rebuilt HashLookup")
+	}
+}
+
+// TestIndexesConcurrentFirstUse is meant for go test -race: every goroutine
+// asks for the indexes of a fresh tree at the same time.
+func TestIndexesConcurrentFirstUse(t *testing.T) {
+	values := indexTestValues(256)
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{PrefixIndex: true})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	want := make([][]HexString, len(values))
+	for i := range values {
+		want[i], err = tree.GetProof(i)
+		if err != nil {
+			t.Fatalf("GetProof(%d) failed: %v", i, err)
+		}
+	}
+
+	const goroutines = 32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make(chan error, goroutines)
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			for i := g; i < len(values); i += goroutines / 4 {
+				proof, err := tree.GetProof(values[i])
+				if err != nil || !slices.Equal(proof, want[i]) {
+					errs <- fmt.Errorf("GetProof(%q) = %v, %v", values[i], proof, err)
+					return
+				}
+				matches, err := tree.FindByHashPrefix(leafHashOf(&tree.MerkleTreeImpl, i))
+				if err != nil || !slices.Equal(matches, []int{i}) {
+					errs <- fmt.Errorf("FindByHashPrefix of value %d = %v, %v", i, matches, err)
+					return
+				}
+			}
+			if g%2 == 0 {
+				if err := tree.WarmIndexes(context.Background()); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestIndexesInvalidatedByAppend(t *testing.T) {
+	values := indexTestValues(12)
+	for _, eager := range []bool{false, true} {
+		options := MerkleTreeOptions{PrefixIndex: true, EagerIndexes: eager}
+		tree, err := NewStandardMerkleTree(values[:8], options)
+		if err != nil {
+			t.Fatalf("Failed to create tree: %v", err)
+		}
+		if err := tree.WarmIndexes(context.Background()); err != nil {
+			t.Fatalf("WarmIndexes failed: %v", err)
+		}
+		if err := tree.AppendLeaves(values[8:]); err != nil {
+			t.Fatalf("AppendLeaves failed: %v", err)
+		}
+		if built := tree.HashLookup != nil; built != eager {
+			t.Errorf("eager=%v: HashLookup built after append = %v", eager, built)
+		}
+
+		for i, value := range values {
+			index, err := tree.getLeafIndex(value)
+			if err != nil || index != i {
+				t.Errorf("eager=%v: index of %q = %d, %v; want %d", eager, value, index, err, i)
+			}
+			matches, err := tree.FindByHashPrefix(leafHashOf(&tree.MerkleTreeImpl, i))
+			if err != nil || !slices.Equal(matches, []int{i}) {
+				t.Errorf("eager=%v: FindByHashPrefix of value %d = %v, %v", eager, i, matches, err)
+			}
+		}
+	}
+}
+
+func TestIndexesAfterConversion(t *testing.T) {
+	tree, err := NewStandardMerkleTree(indexTestValues(8), MerkleTreeOptions{PrefixIndex: true, EagerIndexes: true})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	simple, err := tree.ToSimple()
+	if err != nil {
+		t.Fatalf("ToSimple failed: %v", err)
+	}
+	// The standard tree's lookup is keyed by the same leaf hashes, but the
+	// converted tree builds its own
+	if len(simple.HashLookup) != 8 {
+		t.Errorf("Converted eager tree has %d lookup entries, want 8", len(simple.HashLookup))
+	}
+	for i, v := range tree.Values {
+		index, err := simple.getLeafIndex(tree.Tree[v.TreeIndex])
+		if err != nil || index != i {
+			t.Errorf("Converted index of leaf %d = %d, %v", i, index, err)
+		}
+	}
+	if len(tree.HashLookup) != 8 {
+		t.Errorf("Conversion changed the original tree's lookup")
+	}
+}
+
+func BenchmarkIndexes(b *testing.B) {
+	values := indexTestValues(100_000)
+	for _, eager := range []bool{false, true} {
+		b.Run(fmt.Sprintf("eager=%v", eager), func(b *testing.B) {
+			for range b.N {
+				if _, err := NewStandardMerkleTree(values, MerkleTreeOptions{PrefixIndex: true, EagerIndexes: eager}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+	b.Run("first-lookup", func(b *testing.B) {
+		for range b.N {
+			b.StopTimer()
+			tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{})
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.StartTimer()
+			if _, err := tree.GetProof(values[len(values)/2]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
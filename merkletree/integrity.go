@@ -0,0 +1,140 @@
+package merkletree
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DumpIntegrity is the footer written as the last field of every dump. A dump
+// cut short loses it, and a dump whose tree or values were cut or altered no
+// longer matches its checksums.
+type DumpIntegrity struct {
+	ValueCount   int       `json:"valueCount"`   // Number of entries in values
+	TreeSHA256   string    `json:"treeSha256"`   // SHA-256 of the compact JSON of tree
+	ValuesSHA256 string    `json:"valuesSha256"` // SHA-256 of the compact JSON of values
+	Root         HexString `json:"root"`         // Root of the tree, restated
+}
+
+// newDumpIntegrity computes the footer of a dump with the given sections.
+func newDumpIntegrity(tree []HexString, values any, valueCount int) (*DumpIntegrity, error) {
+	treeJSON, err := json.Marshal(tree)
+	if err != nil {
+		return nil, err
+	}
+	valuesJSON, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	integrity := &DumpIntegrity{
+		ValueCount:   valueCount,
+		TreeSHA256:   compactChecksum(treeJSON),
+		ValuesSHA256: compactChecksum(valuesJSON),
+	}
+	if len(tree) > 0 {
+		integrity.Root = tree[0]
+	}
+	return integrity, nil
+}
+
+// sectionChecksum returns the hex SHA-256 of the compact form of a JSON section,
+// so that indentation does not change it.
+func sectionChecksum(section []byte) string {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, section); err != nil {
+		compact.Reset()
+		compact.Write(section)
+	}
+	sum := sha256.Sum256(compact.Bytes())
+	return hex.EncodeToString(sum[:])
+}
+
+// compactChecksum returns the hex SHA-256 of a section that is already
+// compact, as json.Marshal writes it, without copying a large section of
+// values to compact it again.
+func compactChecksum(section []byte) string {
+	sum := sha256.Sum256(section)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyDumpIntegrity checks the integrity footer of a JSON tree dump of any
+// format. It returns an error wrapping ErrTruncatedDump if the JSON ends early,
+// if the footer is missing, or if the footer does not match the tree and values.
+// allowLegacy accepts dumps written before the footer existed, which are then
+// only checked for being complete JSON.
+func VerifyDumpIntegrity(data []byte, allowLegacy bool) error {
+	var dump struct {
+		Tree      json.RawMessage `json:"tree"`
+		Values    json.RawMessage `json:"values"`
+		Integrity *DumpIntegrity  `json:"integrity"`
+	}
+	if err := json.Unmarshal(data, &dump); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &syntaxErr) && syntaxErr.Offset >= int64(len(bytes.TrimSpace(data))) {
+			return fmt.Errorf("%w: JSON ends after %d bytes", ErrTruncatedDump, len(data))
+		}
+		return fmt.Errorf("%w: %v", ErrInvalidDump, err)
+	}
+
+	footer := dump.Integrity
+	if footer == nil {
+		if allowLegacy {
+			return nil
+		}
+		return fmt.Errorf("%w: no integrity footer", ErrTruncatedDump)
+	}
+
+	var tree []HexString
+	if err := json.Unmarshal(dump.Tree, &tree); err != nil {
+		return fmt.Errorf("%w: tree: %v", ErrInvalidDump, err)
+	}
+	var values []json.RawMessage
+	if err := json.Unmarshal(dump.Values, &values); err != nil {
+		return fmt.Errorf("%w: values: %v", ErrInvalidDump, err)
+	}
+
+	switch {
+	case len(values) != footer.ValueCount:
+		return fmt.Errorf("%w: %d values, footer says %d", ErrTruncatedDump, len(values), footer.ValueCount)
+	case sectionChecksum(dump.Tree) != footer.TreeSHA256:
+		return fmt.Errorf("%w: tree checksum mismatch", ErrTruncatedDump)
+	case sectionChecksum(dump.Values) != footer.ValuesSHA256:
+		return fmt.Errorf("%w: values checksum mismatch", ErrTruncatedDump)
+	case len(tree) == 0 || tree[0] != footer.Root:
+		return fmt.Errorf("%w: root does not match footer root %s", ErrTruncatedDump, footer.Root)
+	}
+	return nil
+}
+
+// LoadOptions configures LoadSimpleMerkleTreeFrom.
+type LoadOptions struct {
+	// NodeHash is the node hash of trees built with a custom one, as for
+	// LoadSimpleMerkleTree.
+	NodeHash NodeHash
+
+	// AllowLegacy accepts simple-v1 dumps written before the integrity footer existed.
+	AllowLegacy bool
+}
+
+// LoadSimpleMerkleTreeFrom reads a JSON simple tree dump from r, verifies its
+// integrity footer with VerifyDumpIntegrity, and loads it with LoadSimpleMerkleTree.
+// A dump that was cut short fails with ErrTruncatedDump.
+func LoadSimpleMerkleTreeFrom(r io.Reader, opts LoadOptions) (*SimpleMerkleTree, []string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := VerifyDumpIntegrity(data, opts.AllowLegacy); err != nil {
+		return nil, nil, err
+	}
+
+	var dump SimpleMerkleTreeData
+	if err := json.Unmarshal(data, &dump); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidDump, err)
+	}
+	return LoadSimpleMerkleTree(dump, opts.NodeHash)
+}
@@ -0,0 +1,123 @@
+package merkletree
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// simpleDumpJSON returns the indented JSON dump of a simple tree over values.
+func simpleDumpJSON(t *testing.T, values ...BytesLike) []byte {
+	t.Helper()
+	tree, err := NewSimpleMerkleTree(values, SimpleMerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	dump, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		t.Fatalf("Failed to marshal dump: %v", err)
+	}
+	return data
+}
+
+func TestLoadSimpleMerkleTreeFrom(t *testing.T) {
+	data := simpleDumpJSON(t, "alice", "bob", "charlie")
+
+	tree, _, err := LoadSimpleMerkleTreeFrom(bytes.NewReader(data), LoadOptions{})
+	if err != nil {
+		t.Fatalf("Failed to load dump: %v", err)
+	}
+	if len(tree.Values) != 3 {
+		t.Errorf("Loaded %d values, want 3", len(tree.Values))
+	}
+
+	// Re-indenting does not change the checksums
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		t.Fatalf("Failed to compact: %v", err)
+	}
+	if _, _, err := LoadSimpleMerkleTreeFrom(&compact, LoadOptions{}); err != nil {
+		t.Errorf("Compact dump should load: %v", err)
+	}
+}
+
+func TestLoadSimpleMerkleTreeFromDetectsTruncation(t *testing.T) {
+	data := simpleDumpJSON(t, "alice", "bob", "charlie", "dave", "eve")
+	complete := len(bytes.TrimRight(data, " \n"))
+
+	for offset := 0; offset < complete; offset++ {
+		_, _, err := LoadSimpleMerkleTreeFrom(bytes.NewReader(data[:offset]), LoadOptions{AllowLegacy: true})
+		if !errors.Is(err, ErrTruncatedDump) {
+			t.Fatalf("Truncation at byte %d of %d not detected: %v", offset, complete, err)
+		}
+	}
+}
+
+func TestVerifyDumpIntegrityFooter(t *testing.T) {
+	data := simpleDumpJSON(t, "alice", "bob", "charlie")
+	var dump map[string]json.RawMessage
+	if err := json.Unmarshal(data, &dump); err != nil {
+		t.Fatalf("Failed to decode dump: %v", err)
+	}
+	encode := func(dump map[string]json.RawMessage) []byte {
+		out, err := json.Marshal(dump)
+		if err != nil {
+			t.Fatalf("Failed to encode dump: %v", err)
+		}
+		return out
+	}
+
+	// A dump with its last value removed, as a streaming writer cut short would leave it
+	var values []json.RawMessage
+	json.Unmarshal(dump["values"], &values)
+	short := map[string]json.RawMessage{}
+	for k, v := range dump {
+		short[k] = v
+	}
+	short["values"], _ = json.Marshal(values[:2])
+	if err := VerifyDumpIntegrity(encode(short), false); !errors.Is(err, ErrTruncatedDump) {
+		t.Errorf("Missing value: expected ErrTruncatedDump, got %v", err)
+	}
+
+	tampered := map[string]json.RawMessage{}
+	for k, v := range dump {
+		tampered[k] = v
+	}
+	tampered["tree"] = json.RawMessage(strings.Replace(string(dump["tree"]), "0x", "0x00", 1))
+	if err := VerifyDumpIntegrity(encode(tampered), false); !errors.Is(err, ErrTruncatedDump) {
+		t.Errorf("Altered tree: expected ErrTruncatedDump, got %v", err)
+	}
+
+	delete(dump, "integrity")
+	legacy := encode(dump)
+	if err := VerifyDumpIntegrity(legacy, false); !errors.Is(err, ErrTruncatedDump) {
+		t.Errorf("Missing footer: expected ErrTruncatedDump, got %v", err)
+	}
+	if _, _, err := LoadSimpleMerkleTreeFrom(bytes.NewReader(legacy), LoadOptions{AllowLegacy: true}); err != nil {
+		t.Errorf("Legacy dump should load with AllowLegacy: %v", err)
+	}
+}
+
+func TestStandardDumpHasFooter(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"a", "b"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	dump, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	data, err := json.Marshal(dump)
+	if err != nil {
+		t.Fatalf("Failed to marshal dump: %v", err)
+	}
+	if err := VerifyDumpIntegrity(data, false); err != nil {
+		t.Errorf("Standard dump should pass its integrity check: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package merkletree
+
+import (
+	"encoding/hex"
+	"fmt"
+	"iter"
+	"strings"
+)
+
+// LeafHashes yields the leaf hashes in canonical tree order: the order of the
+// bottom level of Tree, which is the sorted order when the tree was built with
+// SortLeaves, and insertion order otherwise. i counts leaves from 0.
+// It can be ranged over directly, and stops as soon as yield returns false:
+//
+//	for i, hash := range tree.LeafHashes {
+//		...
+//	}
+func (m *MerkleTreeImpl[T]) LeafHashes(yield func(i int, h HexString) bool) {
+	first := len(m.Tree) - leafCount(len(m.Tree))
+	for i, hash := range m.Tree[first:] {
+		if !yield(i, hash) {
+			return
+		}
+	}
+}
+
+// LeafHashesBytes is LeafHashes with each hash decoded to 32 bytes, for
+// consumers that work on raw digests. A leaf that is not a 32-byte hex
+// string, which a valid tree never contains, ends the iteration.
+func (m *MerkleTreeImpl[T]) LeafHashesBytes(yield func(i int, h [32]byte) bool) {
+	for i, hash := range m.LeafHashes {
+		var digest [32]byte
+		decoded, err := hex.DecodeString(strings.TrimPrefix(string(hash), "0x"))
+		if err != nil || len(decoded) != len(digest) {
+			return
+		}
+		copy(digest[:], decoded)
+		if !yield(i, digest) {
+			return
+		}
+	}
+}
+
+// leafCount returns the number of leaves of a flat tree with size nodes.
+func leafCount(size int) int {
+	return (size + 1) / 2
+}
+
+// RootBuilder computes a root from leaf hashes alone, for cross-checking a
+// root recomputed elsewhere against one of our trees.
+type RootBuilder struct {
+	NodeHash NodeHash // Hash for internal nodes; nil means StandardNodeHash
+}
+
+// Root builds the root over leaves, given in canonical tree order with i
+// counting from 0 as yielded by LeafHashes:
+//
+//	root, err := merkletree.RootBuilder{}.Root(tree.LeafHashes)
+//
+// Returns ErrEmptyTree if there are no leaves, or ErrInvalidIndex if the
+// indices are not consecutive.
+func (b RootBuilder) Root(leaves iter.Seq2[int, HexString]) (HexString, error) {
+	nodeHash := b.NodeHash
+	if nodeHash == nil {
+		nodeHash = StandardNodeHash
+	}
+
+	var hashes []BytesLike
+	for i, hash := range leaves {
+		if i != len(hashes) {
+			return "", fmt.Errorf("%w: leaf %d yielded at position %d", ErrInvalidIndex, i, len(hashes))
+		}
+		hashes = append(hashes, hash)
+	}
+
+	tree, err := makeMerkleTree(hashes, nodeHash, 1, nil)
+	if err != nil {
+		return "", err
+	}
+	return tree[0], nil
+}
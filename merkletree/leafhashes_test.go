@@ -0,0 +1,91 @@
+package merkletree
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLeafHashesMatchTree(t *testing.T) {
+	values := []string{"delta", "alpha", "echo", "charlie", "bravo"}
+
+	for _, sorted := range []bool{false, true} {
+		tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(sorted)})
+		if err != nil {
+			t.Fatalf("Failed to create tree: %v", err)
+		}
+		first := len(tree.Tree) - len(values)
+
+		count := 0
+		for i, hash := range tree.LeafHashes {
+			if i != count || hash != tree.Tree[first+i] {
+				t.Errorf("sorted=%v: leaf %d is %s, want %s at position %d", sorted, i, hash, tree.Tree[first+count], count)
+			}
+			count++
+		}
+		if count != len(values) {
+			t.Errorf("sorted=%v: yielded %d leaves, want %d", sorted, count, len(values))
+		}
+
+		for i, digest := range tree.LeafHashesBytes {
+			want, _ := ToBytes(tree.Tree[first+i])
+			if string(digest[:]) != string(want) {
+				t.Errorf("sorted=%v: leaf %d bytes differ", sorted, i)
+			}
+		}
+
+		root, err := RootBuilder{}.Root(tree.LeafHashes)
+		if err != nil || root != tree.Root() {
+			t.Errorf("sorted=%v: RootBuilder gave %s (err %v), want %s", sorted, root, err, tree.Root())
+		}
+	}
+}
+
+func TestLeafHashesStopsEarly(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"a", "b", "c", "d"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	var seen []int
+	for i := range tree.LeafHashes {
+		seen = append(seen, i)
+		if i == 1 {
+			break
+		}
+	}
+	if len(seen) != 2 {
+		t.Errorf("Iteration should stop after break, saw %v", seen)
+	}
+
+	calls := 0
+	tree.LeafHashesBytes(func(int, [32]byte) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("yield returning false should stop iteration, got %d calls", calls)
+	}
+}
+
+func TestRootBuilderErrors(t *testing.T) {
+	empty := func(func(int, HexString) bool) {}
+	if _, err := (RootBuilder{}).Root(empty); !errors.Is(err, ErrEmptyTree) {
+		t.Errorf("Expected ErrEmptyTree, got %v", err)
+	}
+
+	gap := func(yield func(int, HexString) bool) {
+		_ = yield(0, StandardLeafHash("a")) && yield(2, StandardLeafHash("b"))
+	}
+	if _, err := (RootBuilder{}).Root(gap); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("Expected ErrInvalidIndex, got %v", err)
+	}
+
+	tree, err := NewSimpleMerkleTree([]BytesLike{"a", "b", "c"}, SimpleMerkleTreeOptions{HashAlgorithm: HashAlgorithmSHA256})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	root, err := RootBuilder{NodeHash: SHA256NodeHash}.Root(tree.LeafHashes)
+	if err != nil || root != tree.Root() {
+		t.Errorf("RootBuilder with SHA256NodeHash gave %s (err %v), want %s", root, err, tree.Root())
+	}
+}
@@ -0,0 +1,255 @@
+package merkletree
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"slices"
+
+	"github.com/smeneguz/GoMerkle/internal/keccak"
+)
+
+// checkpointFormat is the format identifier of LeafSet checkpoints.
+const checkpointFormat = "leafset-checkpoint-v1"
+
+// LeafSet ingests the values of a standard tree one at a time and keeps only
+// their 32-byte leaf hashes, for builds too large to hold every value in
+// memory. Once every value is in, Root computes the root the tree built by
+// NewStandardMerkleTree over the same values and options would have.
+//
+// Of the options, only those deciding the root are honoured: SortLeaves,
+// SortDescending, Compatibility, PreserveOrder, ShuffleSeed and MaxLeaves.
+//
+// A build that may be interrupted can write a Checkpoint now and then, and
+// continue later from ResumeLeafSet with the source positioned at Cursor.
+type LeafSet[T any] struct {
+	options   MerkleTreeOptions
+	algorithm AlgorithmDescriptor
+	leafHash  func(T) HexString
+	hashes    [][32]byte // Leaf hashes in input order
+}
+
+// checkpointHeader is the first line of a checkpoint.
+type checkpointHeader struct {
+	Format    string              `json:"format"`
+	Algorithm AlgorithmDescriptor `json:"algorithm"`
+	SeedHash  HexString           `json:"seedHash,omitempty"` // keccak256 of the shuffle seed, which is not written
+	Cursor    int                 `json:"cursor"`
+	Version   string              `json:"version,omitempty"`
+}
+
+// NewLeafSet returns an empty LeafSet for a standard tree built with options.
+// Invalid options fail with the same errors as NewStandardMerkleTree.
+func NewLeafSet[T any](options MerkleTreeOptions) (*LeafSet[T], error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	s := &LeafSet[T]{
+		options:  options,
+		leafHash: StandardLeafHash[T],
+		algorithm: AlgorithmDescriptor{
+			LeafHash:  HashKeccak256Packed,
+			NodeHash:  HashKeccak256Sorted,
+			LeafOrder: options.leafOrder(),
+		},
+	}
+	if options.Compatibility == CompatLatest {
+		s.leafHash, s.algorithm.LeafHash = OpenZeppelinLeafHash[T], HashKeccak256Double
+	}
+	return s, nil
+}
+
+// Add hashes value and appends its leaf. A value that does not hash to a
+// 32-byte leaf fails with an *InputError indexed by its position in the
+// input, and is not added.
+func (s *LeafSet[T]) Add(value T) error {
+	if s.options.MaxLeaves > 0 && len(s.hashes) >= s.options.MaxLeaves {
+		return fmt.Errorf("%w: MaxLeaves is %d", ErrTooManyLeaves, s.options.MaxLeaves)
+	}
+	if err := checkValueSize(len(s.hashes), value, s.options.MaxValueBytes); err != nil {
+		return err
+	}
+	hash := s.leafHash(value)
+	if err := leafHashError(value, hash); err != nil {
+		return &InputError{Index: len(s.hashes), Err: err}
+	}
+	leaf, _ := ToBytes(hash)
+	s.hashes = append(s.hashes, [32]byte(leaf))
+	return nil
+}
+
+// Cursor returns the number of values added so far, which is the position in
+// the input a build resumed from a checkpoint continues at.
+func (s *LeafSet[T]) Cursor() int {
+	return len(s.hashes)
+}
+
+// Algorithm describes the hashes and leaf order of the tree being built.
+func (s *LeafSet[T]) Algorithm() AlgorithmDescriptor {
+	return s.algorithm
+}
+
+// Root returns the root of the tree over every value added, or ErrEmptyTree
+// if there are none. Values can still be added afterwards.
+func (s *LeafSet[T]) Root() (HexString, error) {
+	if len(s.hashes) == 0 {
+		return "", ErrEmptyTree
+	}
+
+	leaves := slices.Clone(s.hashes)
+	compare := func(a, b [32]byte) int { return bytes.Compare(a[:], b[:]) }
+	switch s.algorithm.LeafOrder {
+	case LeafOrderAscending:
+		slices.SortStableFunc(leaves, compare)
+	case LeafOrderDescending:
+		slices.SortStableFunc(leaves, compare)
+		slices.Reverse(leaves)
+	case LeafOrderReversed:
+		slices.Reverse(leaves)
+	case LeafOrderShuffled:
+		sortByShuffleKey(s.options.ShuffleSeed, leaves, func(leaf [32]byte) []byte { return leaf[:] })
+	}
+
+	// Node i of the flat tree is internal[i] below n-1 and leaves[i-(n-1)]
+	// from there; only the internal nodes need room
+	n := len(leaves)
+	internal := make([][32]byte, n-1)
+	node := func(i int) []byte {
+		if i >= n-1 {
+			return leaves[i-(n-1)][:]
+		}
+		return internal[i][:]
+	}
+	hasher := keccak.New()
+	for i := n - 2; i >= 0; i-- {
+		a, b := node(LeftChildIndex(i)), node(RightChildIndex(i))
+		if bytes.Compare(a, b) > 0 {
+			a, b = b, a
+		}
+		hasher.Reset()
+		hasher.Write(a)
+		hasher.Write(b)
+		hasher.Sum(internal[i][:0])
+	}
+	return HexString("0x" + hex.EncodeToString(node(0))), nil
+}
+
+// seedHash returns the hash of the shuffle seed recorded in checkpoints.
+func (s *LeafSet[T]) seedHash() HexString {
+	if s.options.ShuffleSeed == nil {
+		return ""
+	}
+	return keccak256Hex(s.options.ShuffleSeed)
+}
+
+// Checkpoint writes the state of the set to w: a JSON header line with the
+// algorithm and cursor, the leaf hashes added so far as 32-byte records, and
+// the SHA-256 of everything before it as a 32-byte footer. A checkpoint that
+// was cut short or altered fails to resume.
+func (s *LeafSet[T]) Checkpoint(w io.Writer) error {
+	header, err := json.Marshal(checkpointHeader{
+		Format:    checkpointFormat,
+		Algorithm: s.algorithm,
+		SeedHash:  s.seedHash(),
+		Cursor:    len(s.hashes),
+		Version:   version,
+	})
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.New()
+	bw := bufio.NewWriter(io.MultiWriter(w, sum))
+	bw.Write(header)
+	bw.WriteByte('\n')
+	// bufio.Writer keeps its first error and reports it from Flush
+	for _, leaf := range s.hashes {
+		bw.Write(leaf[:])
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	_, err = w.Write(sum.Sum(nil))
+	return err
+}
+
+// ResumeLeafSet reads a checkpoint written by Checkpoint and returns the set
+// it saved, to continue adding values from its Cursor. The options must
+// build the same tree as those the checkpoint was written with, or it fails
+// with ErrCheckpointMismatch. A checkpoint that ends early or whose checksum
+// does not match fails with ErrTruncatedDump, and anything malformed with
+// ErrInvalidDump.
+func ResumeLeafSet[T any](r io.Reader, options MerkleTreeOptions) (*LeafSet[T], error) {
+	s, err := NewLeafSet[T](options)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.New()
+	br := bufio.NewReader(r)
+	line, err := br.ReadBytes('\n')
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: header ends after %d bytes", ErrTruncatedDump, len(line))
+	}
+	if err != nil {
+		return nil, err
+	}
+	sum.Write(line)
+
+	var header checkpointHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidDump, err)
+	}
+	switch {
+	case header.Format != checkpointFormat:
+		return nil, &FormatError{Format: header.Format}
+	case header.Cursor < 0:
+		return nil, fmt.Errorf("%w: negative cursor %d", ErrInvalidDump, header.Cursor)
+	case header.Algorithm != s.algorithm:
+		return nil, fmt.Errorf("%w: checkpoint builds %+v, options build %+v", ErrCheckpointMismatch, header.Algorithm, s.algorithm)
+	case header.SeedHash != s.seedHash():
+		return nil, fmt.Errorf("%w: checkpoint was shuffled with another seed", ErrCheckpointMismatch)
+	}
+
+	// The cursor comes from the file, so grow as records arrive rather than trusting it
+	var record [32]byte
+	for i := 0; i < header.Cursor; i++ {
+		if err := readCheckpointRecord(br, sum, record[:]); err != nil {
+			return nil, fmt.Errorf("%w: %d of %d leaves", err, i, header.Cursor)
+		}
+		s.hashes = append(s.hashes, record)
+	}
+
+	var footer [sha256.Size]byte
+	if err := readCheckpointRecord(br, nil, footer[:]); err != nil {
+		return nil, fmt.Errorf("%w: checksum missing", err)
+	}
+	if !bytes.Equal(footer[:], sum.Sum(nil)) {
+		return nil, fmt.Errorf("%w: checksum does not match", ErrTruncatedDump)
+	}
+	if rest, _ := io.ReadAll(br); len(rest) > 0 {
+		return nil, fmt.Errorf("%w: %d bytes after the checksum", ErrInvalidDump, len(rest))
+	}
+	return s, nil
+}
+
+// readCheckpointRecord fills record from r and adds it to sum, if not nil.
+// An early end of input is reported as ErrTruncatedDump.
+func readCheckpointRecord(r io.Reader, sum hash.Hash, record []byte) error {
+	if _, err := io.ReadFull(r, record); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrTruncatedDump
+		}
+		return err
+	}
+	if sum != nil {
+		sum.Write(record)
+	}
+	return nil
+}
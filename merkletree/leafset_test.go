@@ -0,0 +1,162 @@
+package merkletree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// leafSetValues returns n distinct string values.
+func leafSetValues(n int) []string {
+	values := make([]string, n)
+	for i := range values {
+		values[i] = fmt.Sprintf("account-%d", i)
+	}
+	return values
+}
+
+func TestLeafSetRoot(t *testing.T) {
+	for _, options := range []MerkleTreeOptions{
+		{},
+		{SortLeaves: Bool(true)},
+		{SortLeaves: Bool(true), SortDescending: true},
+		{Compatibility: CompatLatest},
+		{Compatibility: CompatLatest, PreserveOrder: true},
+	} {
+		for _, n := range []int{1, 2, 3, 7, 64, 100} {
+			values := leafSetValues(n)
+			tree, err := NewStandardMerkleTree(values, options)
+			if err != nil {
+				t.Fatalf("Failed to create tree: %v", err)
+			}
+			set, err := NewLeafSet[string](options)
+			if err != nil {
+				t.Fatalf("NewLeafSet failed: %v", err)
+			}
+			for _, v := range values {
+				if err := set.Add(v); err != nil {
+					t.Fatalf("Add failed: %v", err)
+				}
+			}
+			root, err := set.Root()
+			if err != nil {
+				t.Fatalf("Root failed: %v", err)
+			}
+			if root != tree.Root() || set.Algorithm() != tree.Algorithm() {
+				t.Errorf("%+v, %d values: root %s, tree builds %s", options, n, root, tree.Root())
+			}
+		}
+	}
+}
+
+func TestLeafSetResume(t *testing.T) {
+	options := MerkleTreeOptions{SortLeaves: Bool(true)}
+	values := leafSetValues(1000)
+	tree, err := NewStandardMerkleTree(values, options)
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	// Checkpoint twice and crash after each, losing what was added since
+	set, err := NewLeafSet[string](options)
+	if err != nil {
+		t.Fatalf("NewLeafSet failed: %v", err)
+	}
+	for _, crashAt := range []int{300, 900} {
+		var checkpoint bytes.Buffer
+		for set.Cursor() < len(values) {
+			if set.Cursor() == crashAt-50 {
+				if err := set.Checkpoint(&checkpoint); err != nil {
+					t.Fatalf("Checkpoint failed: %v", err)
+				}
+			}
+			if set.Cursor() == crashAt {
+				break
+			}
+			if err := set.Add(values[set.Cursor()]); err != nil {
+				t.Fatalf("Add failed: %v", err)
+			}
+		}
+
+		set, err = ResumeLeafSet[string](&checkpoint, options)
+		if err != nil {
+			t.Fatalf("ResumeLeafSet failed: %v", err)
+		}
+		if set.Cursor() != crashAt-50 {
+			t.Fatalf("Resumed at %d, want %d", set.Cursor(), crashAt-50)
+		}
+	}
+	for _, v := range values[set.Cursor():] {
+		if err := set.Add(v); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	root, err := set.Root()
+	if err != nil {
+		t.Fatalf("Root failed: %v", err)
+	}
+	if root != tree.Root() {
+		t.Errorf("Resumed build root %s, uninterrupted build %s", root, tree.Root())
+	}
+}
+
+func TestLeafSetResumeRejects(t *testing.T) {
+	options := MerkleTreeOptions{SortLeaves: Bool(true)}
+	set, err := NewLeafSet[string](options)
+	if err != nil {
+		t.Fatalf("NewLeafSet failed: %v", err)
+	}
+	for _, v := range leafSetValues(10) {
+		set.Add(v)
+	}
+	var buf bytes.Buffer
+	if err := set.Checkpoint(&buf); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	checkpoint := buf.Bytes()
+
+	for _, other := range []MerkleTreeOptions{{}, {SortLeaves: Bool(true), SortDescending: true}, {Compatibility: CompatLatest}} {
+		if _, err := ResumeLeafSet[string](bytes.NewReader(checkpoint), other); !errors.Is(err, ErrCheckpointMismatch) {
+			t.Errorf("Resuming under %+v: expected ErrCheckpointMismatch, got %v", other, err)
+		}
+	}
+
+	header := bytes.IndexByte(checkpoint, '\n') + 1
+	for _, cut := range []int{0, header / 2, header, header + 100, len(checkpoint) - 1} {
+		if _, err := ResumeLeafSet[string](bytes.NewReader(checkpoint[:cut]), options); !errors.Is(err, ErrTruncatedDump) {
+			t.Errorf("Checkpoint cut at %d: expected ErrTruncatedDump, got %v", cut, err)
+		}
+	}
+
+	corrupt := bytes.Clone(checkpoint)
+	corrupt[header+40] ^= 1
+	if _, err := ResumeLeafSet[string](bytes.NewReader(corrupt), options); !errors.Is(err, ErrTruncatedDump) {
+		t.Errorf("Corrupted checkpoint: expected ErrTruncatedDump, got %v", err)
+	}
+
+	extra := append(bytes.Clone(checkpoint), 0)
+	if _, err := ResumeLeafSet[string](bytes.NewReader(extra), options); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Trailing data: expected ErrInvalidDump, got %v", err)
+	}
+}
+
+func TestLeafSetLimits(t *testing.T) {
+	set, err := NewLeafSet[string](MerkleTreeOptions{MaxLeaves: 2})
+	if err != nil {
+		t.Fatalf("NewLeafSet failed: %v", err)
+	}
+	if _, err := set.Root(); !errors.Is(err, ErrEmptyTree) {
+		t.Errorf("Expected ErrEmptyTree, got %v", err)
+	}
+	set.Add("a")
+	set.Add("b")
+	if err := set.Add("c"); !errors.Is(err, ErrTooManyLeaves) {
+		t.Errorf("Expected ErrTooManyLeaves, got %v", err)
+	}
+
+	if _, err := NewLeafSet[string](MerkleTreeOptions{PreserveOrder: true}); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("Expected ErrInvalidOptions, got %v", err)
+	}
+}
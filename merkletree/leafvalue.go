@@ -0,0 +1,177 @@
+package merkletree
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+)
+
+// LeafValue is the set of value types StandardLeafHash can encode. Trees
+// built with NewStandardMerkleTreeStrict are limited to it, so a value type
+// that can never hash, such as a map or a struct, fails to compile instead
+// of failing at run time.
+//
+// Integers encode big-endian at their own width, signed ones in two's
+// complement, bool as one byte, Address as 20 bytes and U256 and *big.Int as
+// 32, like abi.encodePacked. Slices of fixed-size types encode each
+// element padded to 32 bytes, as abi.encodePacked does for arrays; []byte
+// is bytes, not an array. The types must match exactly: a named string type
+// is not a string, and int and uint are left out because their width
+// depends on the platform.
+type LeafValue interface {
+	string | []byte | bool |
+		int8 | int16 | int32 | int64 | uint8 | uint16 | uint32 | uint64 |
+		Address | U256 | *big.Int |
+		[]int8 | []int16 | []int32 | []int64 | []uint16 | []uint32 | []uint64 |
+		[]Address | []U256
+}
+
+// NewStandardMerkleTreeStrict is NewStandardMerkleTree for the value types
+// of LeafValue only.
+func NewStandardMerkleTreeStrict[T LeafValue](values []T, options MerkleTreeOptions) (*StandardMerkleTree[T], error) {
+	return NewStandardMerkleTree(values, options)
+}
+
+// VerifyStandardMerkleTreeStrict is VerifyStandardMerkleTree for the value
+// types of LeafValue only.
+func VerifyStandardMerkleTreeStrict[T LeafValue](root BytesLike, leaf T, proof []BytesLike) (bool, error) {
+	return VerifyStandardMerkleTree(root, leaf, proof)
+}
+
+// Address is a 20-byte Ethereum address. Its text form is 0x-prefixed hex.
+// It encodes as its 20 raw bytes, as Solidity's abi.encodePacked encodes an
+// address. Other 20-byte array types, such as go-ethereum's common.Address,
+// encode the same way.
+type Address [20]byte
+
+// ParseAddress parses a 0x-prefixed address of 40 hex digits, in any case.
+// An EIP-55 mixed-case checksum is accepted but not checked.
+func ParseAddress(s string) (Address, error) {
+	var a Address
+	digits, ok := strings.CutPrefix(s, "0x")
+	if !ok {
+		digits, ok = strings.CutPrefix(s, "0X")
+	}
+	if !ok || len(digits) != 2*len(a) {
+		return a, fmt.Errorf("%w: address %q is not 0x followed by 40 hex digits", ErrInvalidValue, previewValue(s))
+	}
+	if _, err := hex.Decode(a[:], []byte(digits)); err != nil {
+		return a, fmt.Errorf("%w: address %q: %v", ErrInvalidValue, previewValue(s), err)
+	}
+	return a, nil
+}
+
+// String returns the address as lowercase 0x-prefixed hex.
+func (a Address) String() string {
+	return "0x" + hex.EncodeToString(a[:])
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (a *Address) UnmarshalText(text []byte) error {
+	parsed, err := ParseAddress(string(text))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// U256 is a Solidity uint256, stored big-endian. Its text form is decimal.
+type U256 [32]byte
+
+// addressOf returns value as an Address if it is a 20-byte array, such as
+// an Address or go-ethereum's common.Address.
+func addressOf(value any) (Address, bool) {
+	if a, ok := value.(Address); ok {
+		return a, true
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Array || rv.Len() != len(Address{}) || rv.Type().Elem().Kind() != reflect.Uint8 {
+		return Address{}, false
+	}
+	var a Address
+	reflect.Copy(reflect.ValueOf(&a).Elem(), rv)
+	return a, true
+}
+
+// maxU256 is the largest value a U256 holds.
+var maxU256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// U256FromUint64 returns v as a U256.
+func U256FromUint64(v uint64) U256 {
+	var u U256
+	new(big.Int).SetUint64(v).FillBytes(u[:])
+	return u
+}
+
+// U256FromBig returns v as a U256, or an error wrapping ErrInvalidValue if it
+// is negative or does not fit in 256 bits.
+func U256FromBig(v *big.Int) (U256, error) {
+	var u U256
+	if v.Sign() < 0 || v.Cmp(maxU256) > 0 {
+		return u, fmt.Errorf("%w: %s is not a uint256", ErrInvalidValue, previewValue(v.String()))
+	}
+	v.FillBytes(u[:])
+	return u, nil
+}
+
+// Big returns the value as a big.Int.
+func (u U256) Big() *big.Int {
+	return new(big.Int).SetBytes(u[:])
+}
+
+// String returns the value in decimal.
+func (u U256) String() string {
+	return u.Big().String()
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (u U256) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting decimal or
+// 0x-prefixed hex.
+func (u *U256) UnmarshalText(text []byte) error {
+	v, ok := new(big.Int).SetString(string(text), 0)
+	if !ok {
+		return fmt.Errorf("%w: %q is not a number", ErrInvalidValue, previewValue(string(text)))
+	}
+	parsed, err := U256FromBig(v)
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+
+// packedInts encodes an integer array as abi.encodePacked does.
+func packedInts[E int8 | int16 | int32 | int64 | uint16 | uint32 | uint64](elems []E) []byte {
+	return packedArray(elems, func(e E) []byte { return uintToBytes(e) }, func(e E) bool { return e < 0 })
+}
+
+// packedArray encodes the elements of a Solidity array as abi.encodePacked
+// does, each padded to 32 bytes: integers sign- or zero-extended, addresses
+// left-padded with zeros.
+func packedArray[E any](elems []E, encode func(E) []byte, signed func(E) bool) []byte {
+	out := make([]byte, 0, 32*len(elems))
+	for _, e := range elems {
+		b := encode(e)
+		pad := byte(0)
+		if signed != nil && signed(e) {
+			pad = 0xff
+		}
+		for range 32 - len(b) {
+			out = append(out, pad)
+		}
+		out = append(out, b...)
+	}
+	return out
+}
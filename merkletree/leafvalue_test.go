@@ -0,0 +1,398 @@
+package merkletree
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"math/big"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// checkStrict builds a strict tree of values and checks every leaf and proof.
+func checkStrict[T LeafValue](t *testing.T, values ...T) {
+	t.Helper()
+	tree, err := NewStandardMerkleTreeStrict(values, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("%T: failed to create tree: %v", values, err)
+	}
+	for i, v := range values {
+		if hash := StandardLeafHash(v); !IsValidMerkleNode(hash) {
+			t.Errorf("%T value %d hashes to %q", v, i, hash)
+		}
+		proof, err := tree.GetProof(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bytesProof := make([]BytesLike, len(proof))
+		for j, p := range proof {
+			bytesProof[j] = p
+		}
+		if ok, err := VerifyStandardMerkleTreeStrict(tree.Root(), v, bytesProof); err != nil || !ok {
+			t.Errorf("%T value %d does not verify: %v", v, i, err)
+		}
+	}
+}
+
+func TestStrictEveryLeafValue(t *testing.T) {
+	a, b := Address{0x01}, Address{19: 0x02}
+	one, high := U256FromUint64(1), U256{0: 0x80}
+
+	checkStrict(t, "", "a")
+	checkStrict(t, false, true)
+	checkStrict(t, []byte{}, []byte{0x01})
+	checkStrict(t, int8(-1), int8(1))
+	checkStrict(t, int16(-1), int16(1))
+	checkStrict(t, int32(-1), int32(1))
+	checkStrict(t, int64(-1), int64(1))
+	checkStrict(t, uint8(0), uint8(1))
+	checkStrict(t, uint16(0), uint16(1))
+	checkStrict(t, uint32(0), uint32(1))
+	checkStrict(t, uint64(0), uint64(1))
+	checkStrict(t, a, b)
+	checkStrict(t, one, high)
+	checkStrict(t, big.NewInt(0), new(big.Int).Lsh(big.NewInt(1), 255))
+	checkStrict(t, []int8{}, []int8{-1})
+	checkStrict(t, []int16{}, []int16{-1})
+	checkStrict(t, []int32{}, []int32{-1})
+	checkStrict(t, []int64{}, []int64{-1})
+	checkStrict(t, []uint16{}, []uint16{1})
+	checkStrict(t, []uint32{}, []uint32{1})
+	checkStrict(t, []uint64{}, []uint64{1})
+	checkStrict(t, []Address{}, []Address{a, b})
+	checkStrict(t, []U256{}, []U256{one, high})
+}
+
+func TestPackedEncoding(t *testing.T) {
+	address, err := ParseAddress("0x00000000000000000000000000000000000000FF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	word := func(s string) string { return strings.Repeat("0", 64-len(s)) + s }
+
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{address, strings.Repeat("00", 19) + "ff"},
+		{U256FromUint64(258), word("102")},
+		{[]int8{-1, 2}, strings.Repeat("ff", 32) + word("02")},
+		{[]int64{-2}, strings.Repeat("ff", 31) + "fe"},
+		{[]uint16{0xffff}, word("ffff")},
+		{[]Address{address}, word("ff")},
+		{[]U256{U256FromUint64(1)}, word("1")},
+	}
+	for _, tt := range tests {
+		got, err := abiEncodePacked(tt.value)
+		if err != nil {
+			t.Fatalf("abiEncodePacked(%T): %v", tt.value, err)
+		}
+		if hex.EncodeToString(got) != tt.want {
+			t.Errorf("abiEncodePacked(%#v) = %x, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestPackedScalars(t *testing.T) {
+	// Outputs of Solidity's abi.encodePacked for each value on its own
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{true, "01"},
+		{false, "00"},
+		{int8(-1), "ff"},
+		{int8(-128), "80"},
+		{int8(127), "7f"},
+		{int16(-1), "ffff"},
+		{int16(-256), "ff00"},
+		{int32(-1), "ffffffff"},
+		{int32(-2147483648), "80000000"},
+		{int64(-1), "ffffffffffffffff"},
+		{int64(-2), "fffffffffffffffe"},
+		{int64(1 << 40), "0000010000000000"},
+		{uint16(0x1234), "1234"},
+		{uint64(1), "0000000000000001"},
+		{[]any{true, int16(-2), uint8(7)}, "01fffe07"},
+	}
+	for _, tt := range tests {
+		got, err := abiEncodePacked(tt.value)
+		if err != nil {
+			t.Fatalf("abiEncodePacked(%T): %v", tt.value, err)
+		}
+		if hex.EncodeToString(got) != tt.want {
+			t.Errorf("abiEncodePacked(%#v) = %x, want %s", tt.value, got, tt.want)
+		}
+	}
+
+	_, err := abiEncodePacked("a", true, struct{}{})
+	if err == nil || !strings.Contains(err.Error(), "argument 2 is struct {}") {
+		t.Errorf("Expected the error to name argument 2 and its type, got %v", err)
+	}
+	_, err = abiEncodePacked([]any{int8(1), 3.5})
+	if err == nil || !strings.Contains(err.Error(), "argument 0: ") || !strings.Contains(err.Error(), "argument 1 is float64") {
+		t.Errorf("Expected the error to name the nested argument, got %v", err)
+	}
+}
+
+func TestPackedSlices(t *testing.T) {
+	word := func(s string) string { return strings.Repeat("0", 64-len(s)) + s }
+	var hash [32]byte
+	for i := range hash {
+		hash[i] = byte(i)
+	}
+	address := Address{19: 0xaa}
+
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{hash, hex.EncodeToString(hash[:])},
+		{[4]byte{1, 2, 3, 4}, "01020304"},
+		{[]bool{true, false}, word("1") + word("0")},
+		{[]*big.Int{big.NewInt(1), big.NewInt(256)}, word("1") + word("100")},
+		{[][32]byte{hash}, hex.EncodeToString(hash[:])},
+		{[][4]byte{{1, 2, 3, 4}}, "01020304" + strings.Repeat("0", 56)},
+		{[2]int16{-1, 1}, strings.Repeat("f", 64) + word("1")},
+		{[][]uint64{{1, 2}, {3}}, word("1") + word("2") + word("3")},
+		{[]any{address, []uint64{7, 8}}, strings.Repeat("00", 19) + "aa" + word("7") + word("8")},
+		{[]any{hash, [][]int8{{-1}}}, hex.EncodeToString(hash[:]) + strings.Repeat("f", 64)},
+	}
+	for _, tt := range tests {
+		got, err := abiEncodePacked(tt.value)
+		if err != nil {
+			t.Fatalf("abiEncodePacked(%T): %v", tt.value, err)
+		}
+		if hex.EncodeToString(got) != tt.want {
+			t.Errorf("abiEncodePacked(%#v) = %x, want %s", tt.value, got, tt.want)
+		}
+	}
+
+	for _, bad := range []any{[]string{"a"}, [][]byte{{1}}, []int{1}, [][40]byte{{}}, []any{[]any{map[string]int{}}}} {
+		if _, err := abiEncodePacked(bad); err == nil {
+			t.Errorf("abiEncodePacked(%T) should fail", bad)
+		}
+	}
+
+	// (address, uint64[] tokenIds) leaves build a tree
+	tree, err := NewStandardMerkleTree([][]any{{address, []uint64{1, 2}}, {address, []uint64{3}}}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	proof, err := tree.GetProof(0)
+	if err != nil {
+		t.Fatalf("GetProof failed: %v", err)
+	}
+	if ok, err := tree.Verify(0, proof); err != nil || !ok {
+		t.Errorf("Proof does not verify: %v", err)
+	}
+}
+
+func TestPackedBigInt(t *testing.T) {
+	address, err := ParseAddress("0x1111111111111111111111111111111111111111")
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount, _ := new(big.Int).SetString("5000000000000000000000", 10)
+
+	// keccak256(abi.encodePacked(address, uint256)) in Solidity: 20 address
+	// bytes, then the amount as a 32-byte word
+	packed, _ := hex.DecodeString(strings.Repeat("11", 20) + strings.Repeat("0", 45) + "10f0cf064dd59200000")
+	want := HexString("0x14cc4a48ccdfa327a6383f323fcfb4e98fca06c980307286fd89082aa585f147")
+	if got := HexString("0x" + hex.EncodeToString(ozKeccak(packed))); got != want {
+		t.Fatalf("Reference hash drifted: %s", got)
+	}
+	if got := StandardLeafHash([]any{address, amount}); got != want {
+		t.Errorf("StandardLeafHash(address, *big.Int) = %s, want %s", got, want)
+	}
+	u, _ := U256FromBig(amount)
+	if got := StandardLeafHash([]any{address, u}); got != want {
+		t.Errorf("StandardLeafHash(address, U256) = %s, want %s", got, want)
+	}
+
+	tooLarge := new(big.Int).Lsh(big.NewInt(1), 256)
+	for _, bad := range []*big.Int{big.NewInt(-1), tooLarge, nil} {
+		if _, err := abiEncodePacked(bad); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("abiEncodePacked(%v) = %v, want ErrInvalidValue", bad, err)
+		}
+	}
+	if _, err := NewStandardMerkleTree([][]any{{address, amount}, {address, big.NewInt(-1)}}, MerkleTreeOptions{}); !errors.Is(err, ErrHashFailed) {
+		t.Errorf("Expected a negative amount to be rejected, got %v", err)
+	}
+}
+
+func TestAddressLeaves(t *testing.T) {
+	// The EIP-55 examples, mixed-case, all lowercase and all uppercase
+	eip55 := []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	}
+	// commonAddress stands in for go-ethereum's common.Address
+	type commonAddress [20]byte
+
+	for _, s := range eip55 {
+		mixed, err := ParseAddress(s)
+		if err != nil {
+			t.Fatalf("ParseAddress(%q): %v", s, err)
+		}
+		lower, _ := ParseAddress(strings.ToLower(s))
+		upper, _ := ParseAddress("0X" + strings.ToUpper(s[2:]))
+		if mixed != lower || mixed != upper {
+			t.Errorf("%s parses to %s, %s lowercase and %s uppercase", s, mixed, lower, upper)
+		}
+		if mixed.String() != strings.ToLower(s) {
+			t.Errorf("String() = %s, want %s", mixed, strings.ToLower(s))
+		}
+
+		// abi.encodePacked(address, uint256) and abi.encodePacked(address, uint64)
+		raw, _ := hex.DecodeString(s[2:])
+		for _, tt := range []struct {
+			amount any
+			word   string
+		}{
+			{U256FromUint64(1000), strings.Repeat("0", 61) + "3e8"},
+			{big.NewInt(1000), strings.Repeat("0", 61) + "3e8"},
+			{uint64(1000), "00000000000003e8"},
+		} {
+			amount, _ := hex.DecodeString(tt.word)
+			want := HexString("0x" + hex.EncodeToString(ozKeccak(append(slices.Clone(raw), amount...))))
+			for _, address := range []any{mixed, commonAddress(mixed), [20]byte(mixed)} {
+				if got := StandardLeafHash([]any{address, tt.amount}); got != want {
+					t.Errorf("StandardLeafHash(%T %s, %T) = %s, want %s", address, s, tt.amount, got, want)
+				}
+			}
+		}
+	}
+
+	values := make([][]any, len(eip55))
+	for i, s := range eip55 {
+		address, _ := ParseAddress(s)
+		values[i] = []any{address, U256FromUint64(uint64(i + 1))}
+	}
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	for i := range values {
+		proof, err := tree.GetProof(i)
+		if err != nil {
+			t.Fatalf("GetProof(%d): %v", i, err)
+		}
+		if ok, err := tree.Verify(i, proof); err != nil || !ok {
+			t.Errorf("Proof of value %d does not verify: %v", i, err)
+		}
+	}
+
+	// Other lengths are bytesN, not addresses, and are not padded
+	for _, value := range []any{[19]byte{}, [21]byte{}} {
+		if _, ok := addressOf(value); ok {
+			t.Errorf("%T should not be an address", value)
+		}
+	}
+	if _, err := abiEncodePacked([]any{[19]byte{}}); err != nil {
+		t.Errorf("abiEncodePacked([19]byte) failed: %v", err)
+	}
+}
+
+func TestAddressAndU256Text(t *testing.T) {
+	type row struct {
+		Account Address `json:"account"`
+		Amount  U256    `json:"amount"`
+	}
+	amount, _ := new(big.Int).SetString("1000000000000000000000", 10)
+	u, err := U256FromBig(amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := row{Account: Address{0xab, 19: 0xcd}, Amount: u}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"account":"0xab000000000000000000000000000000000000cd","amount":"1000000000000000000000"}`; string(data) != want {
+		t.Errorf("Marshal = %s, want %s", data, want)
+	}
+	var out row
+	if err := json.Unmarshal(data, &out); err != nil || out != in {
+		t.Errorf("Round trip = %+v, %v", out, err)
+	}
+	if err := json.Unmarshal([]byte(`{"amount":"0x10"}`), &out); err != nil || out.Amount != U256FromUint64(16) {
+		t.Errorf("Hex amount = %v, %v", out.Amount, err)
+	}
+
+	for _, bad := range []string{"0x1234", "ab000000000000000000000000000000000000cd", "0xzz000000000000000000000000000000000000cd"} {
+		if _, err := ParseAddress(bad); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("ParseAddress(%q) = %v, want ErrInvalidValue", bad, err)
+		}
+	}
+	if _, err := U256FromBig(new(big.Int).Lsh(big.NewInt(1), 256)); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("U256FromBig(2^256) = %v, want ErrInvalidValue", err)
+	}
+	if _, err := U256FromBig(big.NewInt(-1)); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("U256FromBig(-1) = %v, want ErrInvalidValue", err)
+	}
+}
+
+// TestStrictRejectsAtCompileTime type-checks programs instantiating the
+// strict constructor with types outside LeafValue, which must not compile.
+// The first case is a valid program, checking the harness itself.
+func TestStrictRejectsAtCompileTime(t *testing.T) {
+	if testing.Short() {
+		t.Skip("type-checks the package from source")
+	}
+	tests := []struct {
+		name  string
+		value string
+		want  string // Empty if the program must compile
+	}{
+		{"allowed", `[]merkletree.Address{{}}`, ""},
+		{"map", `[]map[string]int{{"a": 1}}`, "does not satisfy merkletree.LeafValue"},
+		{"channel", `[]chan int{make(chan int)}`, "does not satisfy merkletree.LeafValue"},
+		{"func", `[]func(){func() {}}`, "does not satisfy merkletree.LeafValue"},
+		{"struct", `[]struct{ A string }{{"a"}}`, "does not satisfy merkletree.LeafValue"},
+		{"int", `[]int{1}`, "does not satisfy merkletree.LeafValue"},
+		{"named string", `[]name{"a"}`, "does not satisfy merkletree.LeafValue"},
+		{"nested slice", `[][]string{{"a"}}`, "does not satisfy merkletree.LeafValue"},
+	}
+
+	fset := token.NewFileSet()
+	imports := importer.ForCompiler(fset, "source", nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `package example
+
+import "github.com/smeneguz/GoMerkle/merkletree"
+
+type name string
+
+var _, _ = merkletree.NewStandardMerkleTreeStrict(` + tt.value + `, merkletree.MerkleTreeOptions{})
+`
+			file, err := parser.ParseFile(fset, "example.go", src, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var errs []string
+			config := types.Config{Importer: imports, Error: func(err error) { errs = append(errs, err.Error()) }}
+			config.Check("example", fset, []*ast.File{file}, nil)
+
+			got := strings.Join(errs, "\n")
+			if tt.want == "" {
+				if got != "" {
+					t.Fatalf("Valid program failed to type-check: %s", got)
+				}
+				return
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("Type errors %q do not mention %q", got, tt.want)
+			}
+		})
+	}
+}
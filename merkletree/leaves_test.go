@@ -0,0 +1,181 @@
+package merkletree
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// leavesTestTree returns a sorted standard tree over n values.
+func leavesTestTree(t *testing.T, n int) *StandardMerkleTree[string] {
+	t.Helper()
+	values := make([]string, n)
+	for i := range values {
+		values[i] = fmt.Sprintf("value-%d", i)
+	}
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(true)})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	return tree
+}
+
+func TestDumpLeavesRoundTrip(t *testing.T) {
+	tree := leavesTestTree(t, 7)
+
+	for _, encoding := range []string{"", LeavesEncodingRaw, LeavesEncodingHex} {
+		t.Run("encoding="+encoding, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tree.DumpLeaves(&buf, LeavesDumpOptions{Encoding: encoding}); err != nil {
+				t.Fatalf("DumpLeaves failed: %v", err)
+			}
+			dump := buf.Bytes()
+
+			if err := VerifyLeavesDump(bytes.NewReader(dump)); err != nil {
+				t.Errorf("VerifyLeavesDump failed: %v", err)
+			}
+
+			loaded, err := LoadFromLeavesDump(bytes.NewReader(dump), nil)
+			if err != nil {
+				t.Fatalf("LoadFromLeavesDump failed: %v", err)
+			}
+			if loaded.Root() != tree.Root() {
+				t.Errorf("Loaded root %s, want %s", loaded.Root(), tree.Root())
+			}
+			if got := loaded.Algorithm(); got.LeafHash != HashIdentity || got.LeafOrder != LeafOrderAscending {
+				t.Errorf("Loaded algorithm = %+v", got)
+			}
+
+			// Proofs of the loaded tree match the original for the same leaf
+			for i, hash := range tree.LeafHashes {
+				proof, err := loaded.GetProof(hash)
+				if err != nil {
+					t.Fatalf("GetProof(%s) failed: %v", hash, err)
+				}
+				valid, err := loaded.Verify(i, proof)
+				if err != nil || !valid {
+					t.Errorf("Proof of leaf %d does not verify: %v", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDumpLeavesSize(t *testing.T) {
+	tree := leavesTestTree(t, 64)
+
+	var raw bytes.Buffer
+	if err := tree.DumpLeaves(&raw, LeavesDumpOptions{}); err != nil {
+		t.Fatalf("DumpLeaves failed: %v", err)
+	}
+	header, _, _ := strings.Cut(raw.String(), "\n")
+	if want := len(header) + 1 + 64*32; raw.Len() != want {
+		t.Errorf("Raw dump is %d bytes, want header plus 32 bytes per leaf (%d)", raw.Len(), want)
+	}
+}
+
+func TestVerifyLeavesDumpCorrupted(t *testing.T) {
+	tree := leavesTestTree(t, 5)
+
+	var buf bytes.Buffer
+	if err := tree.DumpLeaves(&buf, LeavesDumpOptions{}); err != nil {
+		t.Fatalf("DumpLeaves failed: %v", err)
+	}
+	dump := buf.Bytes()
+	headerEnd := bytes.IndexByte(dump, '\n') + 1
+
+	// Flip one bit of the third leaf record
+	corrupted := bytes.Clone(dump)
+	corrupted[headerEnd+2*32+5] ^= 0x01
+	if err := VerifyLeavesDump(bytes.NewReader(corrupted)); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected ErrInvalidDump for a corrupted leaf, got %v", err)
+	}
+	if _, err := LoadFromLeavesDump(bytes.NewReader(corrupted), nil); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected ErrInvalidDump loading a corrupted leaf, got %v", err)
+	}
+
+	for _, cut := range []int{headerEnd - 5, headerEnd, headerEnd + 40, len(dump) - 1} {
+		if err := VerifyLeavesDump(bytes.NewReader(dump[:cut])); !errors.Is(err, ErrTruncatedDump) {
+			t.Errorf("Dump cut at %d: expected ErrTruncatedDump, got %v", cut, err)
+		}
+	}
+
+	extra := append(bytes.Clone(dump), make([]byte, 32)...)
+	if err := VerifyLeavesDump(bytes.NewReader(extra)); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected ErrInvalidDump for trailing records, got %v", err)
+	}
+}
+
+func TestDumpLeavesOptions(t *testing.T) {
+	tree := leavesTestTree(t, 3)
+	var optErr *OptionError
+	if err := tree.DumpLeaves(&bytes.Buffer{}, LeavesDumpOptions{Encoding: "base64"}); !errors.As(err, &optErr) {
+		t.Errorf("Expected *OptionError for an unknown encoding, got %v", err)
+	}
+}
+
+func TestLeavesDumpCustomNodeHash(t *testing.T) {
+	nodeHash := func(a, b BytesLike) HexString { return StandardNodeHash(b, a) }
+	tree, err := NewSimpleMerkleTree([]BytesLike{
+		"0x0000000000000000000000000000000000000000000000000000000000000001",
+		"0x0000000000000000000000000000000000000000000000000000000000000002",
+		"0x0000000000000000000000000000000000000000000000000000000000000003",
+	}, SimpleMerkleTreeOptions{NodeHash: nodeHash})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tree.DumpLeaves(&buf, LeavesDumpOptions{Encoding: LeavesEncodingHex}); err != nil {
+		t.Fatalf("DumpLeaves failed: %v", err)
+	}
+
+	if err := VerifyLeavesDump(bytes.NewReader(buf.Bytes())); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Custom node hash dumps cannot be verified without the hash, got %v", err)
+	}
+	loaded, err := LoadFromLeavesDump(bytes.NewReader(buf.Bytes()), nodeHash)
+	if err != nil {
+		t.Fatalf("LoadFromLeavesDump failed: %v", err)
+	}
+	if loaded.Root() != tree.Root() {
+		t.Errorf("Loaded root %s, want %s", loaded.Root(), tree.Root())
+	}
+}
+
+func TestLeavesDumpTreeRedump(t *testing.T) {
+	tree := leavesTestTree(t, 4)
+	var buf bytes.Buffer
+	if err := tree.DumpLeaves(&buf, LeavesDumpOptions{}); err != nil {
+		t.Fatalf("DumpLeaves failed: %v", err)
+	}
+	loaded, err := LoadFromLeavesDump(&buf, nil)
+	if err != nil {
+		t.Fatalf("LoadFromLeavesDump failed: %v", err)
+	}
+
+	// A pre-hashed tree dumps and loads as one, rather than rehashing its values
+	data, err := loaded.Dump()
+	if err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+	if data.LeafHashAlgorithm != HashIdentity {
+		t.Errorf("LeafHashAlgorithm = %q, want %q", data.LeafHashAlgorithm, HashIdentity)
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("Failed to marshal dump: %v", err)
+	}
+	var decoded SimpleMerkleTreeData
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal dump: %v", err)
+	}
+	reloaded, _, err := LoadSimpleMerkleTree(decoded, nil)
+	if err != nil {
+		t.Fatalf("LoadSimpleMerkleTree failed: %v", err)
+	}
+	if reloaded.Root() != tree.Root() || reloaded.Algorithm().LeafHash != HashIdentity {
+		t.Errorf("Reloaded tree does not match: root %s, algorithm %+v", reloaded.Root(), reloaded.Algorithm())
+	}
+}
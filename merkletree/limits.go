@@ -0,0 +1,159 @@
+package merkletree
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/metrics"
+	"time"
+)
+
+// ResourceLimits bounds the memory and time a build may take, for running
+// in constrained runtimes where running out of memory kills the process with
+// no error. The zero value sets no limit and adds no checks.
+type ResourceLimits struct {
+	// MaxHeapBytesEstimate rejects a build whose EstimateBuildMemory peak is
+	// above it before anything is allocated, and stops a build whose heap has
+	// grown by more than it, polled between levels of the tree and every 65,536
+	// leaves. Zero means no limit.
+	MaxHeapBytesEstimate int64
+
+	// MaxBuildDuration stops a build that runs longer. It is checked every
+	// 1024 leaves or nodes hashed. Zero means no limit.
+	MaxBuildDuration time.Duration
+}
+
+// Stages reported in ResourceLimitError.Stage.
+const (
+	StageEstimate    = "estimate"     // Before the build, from EstimateBuildMemory
+	StageLeafHashing = "leaf hashing" // Hashing the values into leaves
+	StageNodeHashing = "node hashing" // Hashing the internal nodes
+)
+
+const (
+	// guardInterval is the number of leaves or nodes hashed between time checks.
+	guardInterval = 1024
+
+	// heapPollInterval is the number of leaves hashed between heap polls.
+	heapPollInterval = 1 << 16
+)
+
+// ResourceLimitError reports which ResourceLimits setting stopped a build
+// and how far it got. It wraps ErrResourceLimit.
+type ResourceLimitError struct {
+	Limit     string        // "MaxHeapBytesEstimate" or "MaxBuildDuration"
+	Stage     string        // StageEstimate, StageLeafHashing or StageNodeHashing
+	Done      int           // Leaves or nodes of Stage hashed when it tripped
+	Total     int           // Leaves or nodes Stage had to hash
+	HeapBytes int64         // Estimated peak, or heap growth when polled
+	Elapsed   time.Duration // Time since the build started
+}
+
+// Error implements the error interface.
+func (e *ResourceLimitError) Error() string {
+	if e.Stage == StageEstimate {
+		return fmt.Sprintf("%v: %s: estimated peak of %d bytes for %d leaves", ErrResourceLimit, e.Limit, e.HeapBytes, e.Total)
+	}
+	return fmt.Sprintf("%v: %s tripped during %s after %d of %d (heap grew %d bytes in %v)",
+		ErrResourceLimit, e.Limit, e.Stage, e.Done, e.Total, e.HeapBytes, e.Elapsed)
+}
+
+// Unwrap returns ErrResourceLimit.
+func (e *ResourceLimitError) Unwrap() error {
+	return ErrResourceLimit
+}
+
+// ErrorCode returns CodeResourceLimit.
+func (e *ResourceLimitError) ErrorCode() string {
+	return CodeResourceLimit
+}
+
+// optionErrors returns one *OptionError per invalid limit.
+func (l ResourceLimits) optionErrors() []error {
+	var errs []error
+	if l.MaxHeapBytesEstimate < 0 {
+		errs = append(errs, &OptionError{Option: "Limits.MaxHeapBytesEstimate", Value: l.MaxHeapBytesEstimate, Reason: "must not be negative"})
+	}
+	if l.MaxBuildDuration < 0 {
+		errs = append(errs, &OptionError{Option: "Limits.MaxBuildDuration", Value: l.MaxBuildDuration, Reason: "must not be negative"})
+	}
+	return errs
+}
+
+// checkEstimate fails if the estimated peak of a build of leafCount values
+// with opts is above MaxHeapBytesEstimate.
+func (l ResourceLimits) checkEstimate(leafCount int, opts MerkleTreeOptions) error {
+	if l.MaxHeapBytesEstimate <= 0 {
+		return nil
+	}
+	estimate, err := EstimateBuildMemory(leafCount, opts)
+	if err != nil || estimate.PeakBytes <= l.MaxHeapBytesEstimate {
+		return nil // Invalid inputs are reported by the build itself
+	}
+	return &ResourceLimitError{Limit: "MaxHeapBytesEstimate", Stage: StageEstimate, Total: leafCount, HeapBytes: estimate.PeakBytes}
+}
+
+// buildGuard enforces ResourceLimits while a tree is built. A nil guard,
+// used when no limit is set, checks nothing.
+type buildGuard struct {
+	limits   ResourceLimits
+	start    time.Time
+	baseHeap uint64
+}
+
+// Hooks replaced by tests to trip limits deterministically.
+var (
+	buildClock = time.Now
+	heapBytes  = readHeapBytes
+)
+
+// newBuildGuard returns the guard of a build with limits, or nil if none is set.
+func newBuildGuard(limits ResourceLimits) *buildGuard {
+	if limits.MaxHeapBytesEstimate <= 0 && limits.MaxBuildDuration <= 0 {
+		return nil
+	}
+	g := &buildGuard{limits: limits, start: buildClock()}
+	if limits.MaxHeapBytesEstimate > 0 {
+		g.baseHeap = heapBytes()
+	}
+	return g
+}
+
+// check fails once the build has run past MaxBuildDuration or, if pollHeap
+// is set, once the heap has grown by more than MaxHeapBytesEstimate. Garbage
+// counts toward the heap until it is collected, so growth over the limit is
+// measured again after a collection before the build is stopped.
+func (g *buildGuard) check(stage string, done, total int, pollHeap bool) error {
+	if g == nil {
+		return nil
+	}
+	elapsed := buildClock().Sub(g.start)
+	limit := ""
+	var grown int64
+	if g.limits.MaxBuildDuration > 0 && elapsed > g.limits.MaxBuildDuration {
+		limit = "MaxBuildDuration"
+	} else if pollHeap && g.limits.MaxHeapBytesEstimate > 0 {
+		if grown = g.heapGrowth(); grown > g.limits.MaxHeapBytesEstimate {
+			runtime.GC()
+			if grown = g.heapGrowth(); grown > g.limits.MaxHeapBytesEstimate {
+				limit = "MaxHeapBytesEstimate"
+			}
+		}
+	}
+	if limit == "" {
+		return nil
+	}
+	return &ResourceLimitError{Limit: limit, Stage: stage, Done: done, Total: total, HeapBytes: grown, Elapsed: elapsed}
+}
+
+// heapGrowth returns how much the heap has grown since the build started.
+func (g *buildGuard) heapGrowth() int64 {
+	return max(int64(heapBytes())-int64(g.baseHeap), 0)
+}
+
+// readHeapBytes returns the bytes of heap objects, live or not yet collected.
+// Unlike runtime.ReadMemStats it does not stop the world.
+func readHeapBytes() uint64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(sample)
+	return sample[0].Value.Uint64()
+}
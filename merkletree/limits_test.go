@@ -0,0 +1,140 @@
+package merkletree
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// limitValues returns n distinct string values.
+func limitValues(n int) []string {
+	values := make([]string, n)
+	for i := range values {
+		values[i] = fmt.Sprintf("value-%d", i)
+	}
+	return values
+}
+
+// stubClock makes buildClock advance by step on every call until the test ends.
+func stubClock(t *testing.T, step time.Duration) {
+	t.Helper()
+	now := time.Unix(0, 0)
+	buildClock = func() time.Time {
+		current := now
+		now = now.Add(step)
+		return current
+	}
+	t.Cleanup(func() { buildClock = time.Now })
+}
+
+// limitError returns the *ResourceLimitError in err, failing the test if there is none.
+func limitError(t *testing.T, err error) *ResourceLimitError {
+	t.Helper()
+	var limitErr *ResourceLimitError
+	if !errors.Is(err, ErrResourceLimit) || !errors.As(err, &limitErr) {
+		t.Fatalf("Expected a ResourceLimitError, got %v", err)
+	}
+	return limitErr
+}
+
+func TestResourceLimitsEstimate(t *testing.T) {
+	values := limitValues(100)
+	_, err := NewStandardMerkleTree(values, MerkleTreeOptions{Limits: ResourceLimits{MaxHeapBytesEstimate: 1000}})
+	limitErr := limitError(t, err)
+	if limitErr.Limit != "MaxHeapBytesEstimate" || limitErr.Stage != StageEstimate || limitErr.Total != 100 || limitErr.HeapBytes <= 1000 {
+		t.Errorf("Unexpected error %+v", limitErr)
+	}
+}
+
+func TestResourceLimitsDuration(t *testing.T) {
+	for _, parallelism := range []int{0, 4} {
+		t.Run(fmt.Sprintf("parallelism=%d", parallelism), func(t *testing.T) {
+			stubClock(t, time.Hour)
+			options := MerkleTreeOptions{Parallelism: parallelism, Limits: ResourceLimits{MaxBuildDuration: time.Minute}}
+			_, err := NewStandardMerkleTree(limitValues(10), options)
+			limitErr := limitError(t, err)
+			if limitErr.Limit != "MaxBuildDuration" || limitErr.Stage != StageLeafHashing || limitErr.Done != 0 || limitErr.Elapsed != time.Hour {
+				t.Errorf("Unexpected error %+v", limitErr)
+			}
+		})
+	}
+}
+
+func TestResourceLimitsDurationDuringNodeHashing(t *testing.T) {
+	// The start and the five leaf hashing checks of 5000 leaves take 6ms of
+	// the clock, so the limit trips at the first node hashing check
+	for _, parallelism := range []int{0, 4} {
+		t.Run(fmt.Sprintf("parallelism=%d", parallelism), func(t *testing.T) {
+			stubClock(t, time.Millisecond)
+			options := MerkleTreeOptions{NodeHashParallelism: parallelism, Limits: ResourceLimits{MaxBuildDuration: 5500 * time.Microsecond}}
+			_, err := NewStandardMerkleTree(limitValues(5000), options)
+			limitErr := limitError(t, err)
+			if limitErr.Stage != StageNodeHashing || limitErr.Total != 4999 || limitErr.Done >= limitErr.Total {
+				t.Errorf("Unexpected error %+v", limitErr)
+			}
+		})
+	}
+}
+
+func TestResourceLimitsHeap(t *testing.T) {
+	// The baseline and the leaf hashing poll see no growth; the first poll
+	// while hashing nodes, and the one after collecting, see 1 TiB
+	calls := 0
+	heapBytes = func() uint64 {
+		calls++
+		if calls <= 2 {
+			return 0
+		}
+		return 1 << 40
+	}
+	t.Cleanup(func() { heapBytes = readHeapBytes })
+
+	_, err := NewStandardMerkleTree(limitValues(5000), MerkleTreeOptions{Limits: ResourceLimits{MaxHeapBytesEstimate: 1 << 30}})
+	limitErr := limitError(t, err)
+	if limitErr.Limit != "MaxHeapBytesEstimate" || limitErr.Stage != StageNodeHashing || limitErr.HeapBytes != 1<<40 {
+		t.Errorf("Unexpected error %+v", limitErr)
+	}
+	if calls != 4 {
+		t.Errorf("Expected the heap to be read 4 times, got %d", calls)
+	}
+}
+
+func TestResourceLimitsWithinLimits(t *testing.T) {
+	values := limitValues(3000)
+	plain, err := NewStandardMerkleTree(values, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	limited, err := NewStandardMerkleTree(values, MerkleTreeOptions{Limits: ResourceLimits{MaxHeapBytesEstimate: 1 << 34, MaxBuildDuration: time.Hour}})
+	if err != nil {
+		t.Fatalf("Failed to create tree within limits: %v", err)
+	}
+	if plain.Root() != limited.Root() {
+		t.Errorf("Limits changed the root: %s, want %s", limited.Root(), plain.Root())
+	}
+}
+
+func TestResourceLimitsValidate(t *testing.T) {
+	err := MerkleTreeOptions{Limits: ResourceLimits{MaxHeapBytesEstimate: -1, MaxBuildDuration: -time.Second}}.Validate()
+	var optErr *OptionError
+	if !errors.As(err, &optErr) || optErr.Option != "Limits.MaxHeapBytesEstimate" {
+		t.Errorf("Expected an OptionError for Limits.MaxHeapBytesEstimate, got %v", err)
+	}
+	if err == nil || len(err.(interface{ Unwrap() []error }).Unwrap()) != 2 {
+		t.Errorf("Expected two option errors, got %v", err)
+	}
+}
+
+func BenchmarkBuildLimits(b *testing.B) {
+	values := limitValues(100_000)
+	for _, limits := range []ResourceLimits{{}, {MaxHeapBytesEstimate: 1 << 34, MaxBuildDuration: time.Hour}} {
+		b.Run(fmt.Sprintf("limited=%v", limits != ResourceLimits{}), func(b *testing.B) {
+			for range b.N {
+				if _, err := NewStandardMerkleTree(values, MerkleTreeOptions{Limits: limits}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package merkletree
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Format identifiers of tree dumps.
+const (
+	simpleFormat   = "simple-v1"
+	standardFormat = "standard-v1"
+)
+
+// legacyHashWarning is reported when a dump without hashAlgorithm is loaded.
+const legacyHashWarning = `dump has no hashAlgorithm; reading hash "custom" as keccak256 (use MigrateDump to record it)`
+
+// LoadSimpleMerkleTree rebuilds a SimpleMerkleTree from its dump and checks
+// that every leaf and node recomputes with the hashes the dump names.
+//
+// Dumps written before hashAlgorithm existed only say "custom"; they are read
+// as keccak256 and a warning is returned. Dumps with hashAlgorithm are read
+// strictly: unknown names are rejected, including names registered with
+// RegisterNodeHash in the program that wrote the dump but not in this one.
+// nodeHash is used only for trees built with an unregistered custom node
+// hash, and is required for them.
+func LoadSimpleMerkleTree(data SimpleMerkleTreeData, nodeHash NodeHash) (*SimpleMerkleTree, []string, error) {
+	return LoadSimpleMerkleTreeWithOptions(data, SimpleMerkleTreeOptions{NodeHash: nodeHash})
+}
+
+// LoadSimpleMerkleTreeWithOptions is LoadSimpleMerkleTree with the options
+// the tree is served with. options.NodeHash is the custom node hash the tree
+// was built with; options.HashAlgorithm, if set, must be the one the dump
+// names. The lookup options (Duplicates, PrefixIndex, EagerIndexes,
+// MaxPrefixResults) and PregenerateProofs apply as when building. Options
+// that decide the shape of the tree, such as SortLeaves, are ignored: the
+// dump records the tree as it was built.
+func LoadSimpleMerkleTreeWithOptions(data SimpleMerkleTreeData, options SimpleMerkleTreeOptions) (*SimpleMerkleTree, []string, error) {
+	nodeHash := options.NodeHash
+	if options.HashAlgorithm != "" && options.HashAlgorithm != cmp.Or(data.HashAlgorithm, HashAlgorithmKeccak256) {
+		return nil, nil, &OptionError{Option: "HashAlgorithm", Value: options.HashAlgorithm,
+			Reason: fmt.Sprintf("dump was built with %q", cmp.Or(data.HashAlgorithm, HashAlgorithmKeccak256))}
+	}
+	if data.Format != simpleFormat {
+		return nil, nil, &FormatError{Format: data.Format}
+	}
+	if len(data.Tree) == 0 {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidDump, ErrEmptyTree)
+	}
+
+	var warnings []string
+	hashAlgorithm := data.HashAlgorithm
+	switch {
+	case hashAlgorithm == "" && data.Hash != HashCustom:
+		if _, ok := lookupNodeHash(data.Hash); !ok {
+			return nil, nil, fmt.Errorf("%w: unknown hash %q", ErrInvalidDump, data.Hash)
+		}
+		if data.LeafHashAlgorithm != "" {
+			return nil, nil, fmt.Errorf("%w: leafHashAlgorithm is set but hashAlgorithm is not", ErrInvalidDump)
+		}
+		hashAlgorithm = data.Hash
+	case hashAlgorithm == "":
+		if data.LeafHashAlgorithm != "" {
+			return nil, nil, fmt.Errorf("%w: leafHashAlgorithm is set but hashAlgorithm is not", ErrInvalidDump)
+		}
+		if nodeHash != nil {
+			hashAlgorithm = HashCustom
+			break
+		}
+		hashAlgorithm = HashAlgorithmKeccak256
+		warnings = append(warnings, legacyHashWarning)
+	case data.LeafHashAlgorithm != HashAlgorithmKeccak256 && data.LeafHashAlgorithm != HashIdentity &&
+		data.LeafHashAlgorithm != HashKeccak256Bytes && data.LeafHashAlgorithm != HashSHA256Bytes &&
+		data.LeafHashAlgorithm != HashBlake2b256Bytes:
+		return nil, nil, fmt.Errorf("%w: unsupported leafHashAlgorithm %q", ErrInvalidDump, data.LeafHashAlgorithm)
+	case hashAlgorithm == HashCustom:
+		if nodeHash == nil {
+			return nil, nil, fmt.Errorf("%w: tree was built with a custom node hash, which must be supplied", ErrInvalidDump)
+		}
+	case data.Hash != "" && data.Hash != HashCustom && data.Hash != hashAlgorithm:
+		return nil, nil, fmt.Errorf("%w: hash %q contradicts hashAlgorithm %q", ErrInvalidDump, data.Hash, hashAlgorithm)
+	default:
+		if _, ok := lookupNodeHash(hashAlgorithm); !ok {
+			return nil, nil, fmt.Errorf("%w: unknown hashAlgorithm %q", ErrInvalidDump, hashAlgorithm)
+		}
+	}
+	if named, ok := lookupNodeHash(hashAlgorithm); ok {
+		nodeHash = named.hash
+	}
+
+	values := make([]struct {
+		Value     BytesLike
+		TreeIndex int
+	}, len(data.Values))
+	metadata := make([]json.RawMessage, len(data.Values))
+	for i, v := range data.Values {
+		if v.TreeIndex < 0 || v.TreeIndex >= len(data.Tree) {
+			return nil, nil, fmt.Errorf("%w: value %d has tree index %d outside the tree", ErrInvalidDump, i, v.TreeIndex)
+		}
+		values[i].Value = v.Value
+		values[i].TreeIndex = v.TreeIndex
+		metadata[i] = v.Metadata
+	}
+
+	leafHash := FormatLeaf
+	switch data.LeafHashAlgorithm {
+	case HashIdentity:
+		leafHash = identityLeafHash
+	case HashKeccak256Bytes:
+		leafHash = BytesLeafHash
+	case HashSHA256Bytes:
+		leafHash = SHA256LeafHash
+	case HashBlake2b256Bytes:
+		leafHash = Blake2b256LeafHash
+	}
+
+	t := &SimpleMerkleTree{
+		MerkleTreeImpl[BytesLike]{
+			Tree:     data.Tree,
+			Values:   values,
+			LeafHash: leafHash,
+			NodeHash: nodeHash,
+		},
+		hashAlgorithm,
+	}
+	if err := t.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("%w: tree does not recompute with hash %s: %v%s", ErrInvalidDump, hashAlgorithm, err, lossyValueHint(data.Values))
+	}
+
+	t.algorithm = simpleAlgorithm(hashAlgorithm, data.Algorithm.LeafOrder)
+	switch data.LeafHashAlgorithm {
+	case HashIdentity:
+		t.algorithm.LeafHash = HashIdentity
+		if data.Options != nil && data.Options.Compatibility == CompatLatest.String() {
+			t.compatibility = CompatLatest // Pre-hashed leaves do not say
+		}
+	case HashKeccak256Bytes:
+		// Only CompatLatest hashes the bytes; AppendLeaves follows its layout
+		t.algorithm.LeafHash, t.compatibility = HashKeccak256Bytes, CompatLatest
+	case HashSHA256Bytes, HashBlake2b256Bytes:
+		t.algorithm.LeafHash = data.LeafHashAlgorithm
+		if data.Options != nil && data.Options.Compatibility == CompatLatest.String() {
+			t.compatibility = CompatLatest // The leaf hash does not say
+		}
+	}
+	if err := checkDumpOptions(data.Options, t.algorithm, t.compatibility, hashAlgorithm); err != nil {
+		return nil, nil, err
+	}
+	if data.ShuffleSeed != "" {
+		seed, err := ToBytes(data.ShuffleSeed)
+		switch {
+		case err != nil || len(seed) == 0:
+			return nil, nil, fmt.Errorf("%w: shuffleSeed %q is not hex", ErrInvalidDump, data.ShuffleSeed)
+		case data.Algorithm.LeafOrder != LeafOrderShuffled:
+			return nil, nil, fmt.Errorf("%w: shuffleSeed is set but the leaf order is %q", ErrInvalidDump, data.Algorithm.LeafOrder)
+		}
+		if err := t.CheckShuffle(seed); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidDump, err)
+		}
+		t.shuffleSeed = seed
+	}
+	t.setMetadata(metadata)
+	t.quarantined = data.Quarantined
+	t.configureIndexes(options.MerkleTreeOptions)
+	t.configurePregeneration(options.MerkleTreeOptions)
+	return t, warnings, nil
+}
+
+// LoadStandardMerkleTree rebuilds a StandardMerkleTree from its dump and
+// checks that every leaf and node recomputes. The leaf hash, and with it the
+// compatibility mode, is taken from the dump's algorithm; dumps that predate
+// the algorithm field are read as CompatV0 trees. A dump that records a
+// leafEncoding, as those of NewStandardMerkleTreeWithEncoding and
+// OpenZeppelin do, is hashed with it and needs T to be []any. The dump must
+// hold one value per leaf, so dumps of trees built with DropValuesAfterBuild,
+// which cannot be written, have no counterpart here.
+func LoadStandardMerkleTree[T any](data StandardMerkleTreeData[T]) (*StandardMerkleTree[T], error) {
+	if data.Format != standardFormat {
+		return nil, &FormatError{Format: data.Format}
+	}
+	if len(data.Tree) == 0 {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDump, ErrEmptyTree)
+	}
+	if leaves := (len(data.Tree) + 1) / 2; len(data.Values) != leaves {
+		return nil, fmt.Errorf("%w: %d values for a tree of %d leaves", ErrInvalidDump, len(data.Values), leaves)
+	}
+
+	leafHash, compatibility := StandardLeafHash[T], CompatV0
+	leafHashName := cmp.Or(data.Algorithm.LeafHash, HashKeccak256Packed)
+	switch {
+	case data.LeafEncoding != nil || data.Algorithm.LeafHash == HashKeccak256ABI:
+		encoded, err := loadLeafEncoding(data)
+		if err != nil {
+			return nil, err
+		}
+		leafHash, leafHashName, compatibility = encoded, HashKeccak256ABI, CompatLatest
+	case data.Algorithm.LeafHash == "", data.Algorithm.LeafHash == HashKeccak256Packed:
+	case data.Algorithm.LeafHash == HashKeccak256Double:
+		leafHash, compatibility = OpenZeppelinLeafHash[T], CompatLatest
+	default:
+		return nil, fmt.Errorf("%w: unsupported leaf hash %q", ErrInvalidDump, data.Algorithm.LeafHash)
+	}
+	if data.Algorithm.NodeHash != "" && data.Algorithm.NodeHash != HashKeccak256Sorted {
+		return nil, fmt.Errorf("%w: unsupported node hash %q", ErrInvalidDump, data.Algorithm.NodeHash)
+	}
+
+	firstLeaf := len(data.Tree) - len(data.Values)
+	values := make([]struct {
+		Value     T
+		TreeIndex int
+	}, len(data.Values))
+	metadata := make([]json.RawMessage, len(data.Values))
+	seen := make(map[int]bool, len(data.Values))
+	for i, v := range data.Values {
+		if v.TreeIndex < firstLeaf || v.TreeIndex >= len(data.Tree) {
+			return nil, fmt.Errorf("%w: value %d has tree index %d, which is not a leaf", ErrInvalidDump, i, v.TreeIndex)
+		}
+		if seen[v.TreeIndex] {
+			return nil, fmt.Errorf("%w: value %d shares tree index %d with another value", ErrInvalidDump, i, v.TreeIndex)
+		}
+		seen[v.TreeIndex] = true
+		values[i].Value = v.Value
+		values[i].TreeIndex = v.TreeIndex
+		metadata[i] = v.Metadata
+	}
+
+	t := &StandardMerkleTree[T]{
+		MerkleTreeImpl: MerkleTreeImpl[T]{
+			Tree:     data.Tree,
+			Values:   values,
+			LeafHash: leafHash,
+			NodeHash: StandardNodeHash,
+		},
+	}
+	if !IsValidMerkleTree(t.Tree, t.NodeHash) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDump, ErrInvalidTree)
+	}
+	if err := t.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v%s", ErrInvalidDump, err, lossyValueHint(data.Values))
+	}
+
+	t.algorithm = AlgorithmDescriptor{
+		LeafHash:  leafHashName,
+		NodeHash:  HashKeccak256Sorted,
+		LeafOrder: data.Algorithm.LeafOrder,
+	}
+	t.compatibility = compatibility
+	if err := checkDumpOptions(data.Options, t.algorithm, compatibility, HashAlgorithmKeccak256); err != nil {
+		return nil, err
+	}
+	t.leafEncoding = slices.Clone(data.LeafEncoding)
+	if data.ShuffleSeed != "" {
+		seed, err := ToBytes(data.ShuffleSeed)
+		switch {
+		case err != nil || len(seed) == 0:
+			return nil, fmt.Errorf("%w: shuffleSeed %q is not hex", ErrInvalidDump, data.ShuffleSeed)
+		case data.Algorithm.LeafOrder != LeafOrderShuffled:
+			return nil, fmt.Errorf("%w: shuffleSeed is set but the leaf order is %q", ErrInvalidDump, data.Algorithm.LeafOrder)
+		}
+		if err := t.CheckShuffle(seed); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDump, err)
+		}
+		t.shuffleSeed = seed
+	}
+	t.setMetadata(metadata)
+	t.quarantined = data.Quarantined
+	t.configureIndexes(DefaultOptions)
+	return t, nil
+}
+
+// loadLeafEncoding returns the leaf hash of a dump that records a leaf
+// encoding, as OpenZeppelin's dumps do. Its values must load as []any.
+func loadLeafEncoding[T any](data StandardMerkleTreeData[T]) (func(T) HexString, error) {
+	if data.Algorithm.LeafHash != "" && data.Algorithm.LeafHash != HashKeccak256ABI {
+		return nil, fmt.Errorf("%w: leafEncoding is set but the leaf hash is %q", ErrInvalidDump, data.Algorithm.LeafHash)
+	}
+	types, err := parseLeafEncoding(data.LeafEncoding)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDump, err)
+	}
+	leafHash, ok := any(encodedLeafHash(types)).(func(T) HexString)
+	if !ok {
+		return nil, fmt.Errorf("%w: values of a leaf encoding load as []any, not %v", ErrInvalidDump, reflect.TypeFor[T]())
+	}
+	return leafHash, nil
+}
+
+// lossyValueHint explains a value that does not hash to its leaf when the
+// dump holds U+FFFD, which is what encoding/json writes for invalid UTF-8
+// in dumps that predate the {"$bytes": "0x..."} form.
+func lossyValueHint[T any](values []DumpValue[T]) string {
+	for i, v := range values {
+		if s, ok := any(v.Value).(string); ok && strings.ContainsRune(s, utf8.RuneError) {
+			return fmt.Sprintf(" (value %d contains U+FFFD; the dump may have lost bytes that were not valid UTF-8)", i)
+		}
+	}
+	return ""
+}
+
+// MigrateDump upgrades a JSON simple tree dump to record its hash algorithms.
+// The tree is loaded first, so a dump whose root does not recompute is
+// rejected rather than migrated. Dumps that are already current are rewritten
+// unchanged apart from formatting.
+func MigrateDump(old []byte) ([]byte, error) {
+	var data SimpleMerkleTreeData
+	if err := json.Unmarshal(old, &data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDump, err)
+	}
+
+	tree, _, err := LoadSimpleMerkleTree(data, nil)
+	if err != nil {
+		return nil, err
+	}
+	dump, err := tree.Dump()
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(dump, "", "  ")
+}
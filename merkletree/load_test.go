@@ -0,0 +1,496 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// legacySimpleDump is a simple-v1 dump written before hashAlgorithm existed.
+func legacySimpleDump(t *testing.T) ([]byte, HexString) {
+	t.Helper()
+	tree, err := NewSimpleMerkleTree([]BytesLike{"alice", "bob", "charlie"}, SimpleMerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	dump, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	data, err := json.Marshal(map[string]any{
+		"format": "simple-v1",
+		"tree":   tree.Tree,
+		"values": dump.Values,
+		"hash":   "custom",
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal legacy dump: %v", err)
+	}
+	return data, tree.Root()
+}
+
+func TestLoadLegacySimpleDump(t *testing.T) {
+	legacy, root := legacySimpleDump(t)
+
+	var data SimpleMerkleTreeData
+	if err := json.Unmarshal(legacy, &data); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+
+	tree, warnings, err := LoadSimpleMerkleTree(data, nil)
+	if err != nil {
+		t.Fatalf("Failed to load legacy dump: %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "keccak256") {
+		t.Errorf("Expected a keccak256 warning, got %v", warnings)
+	}
+	if tree.Root() != root {
+		t.Errorf("Loaded root %s, want %s", tree.Root(), root)
+	}
+	if tree.Algorithm().NodeHash != HashKeccak256Sorted {
+		t.Errorf("Legacy dump should load as %s, got %s", HashKeccak256Sorted, tree.Algorithm().NodeHash)
+	}
+}
+
+func TestMigrateDump(t *testing.T) {
+	legacy, root := legacySimpleDump(t)
+
+	migrated, err := MigrateDump(legacy)
+	if err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	var data SimpleMerkleTreeData
+	if err := json.Unmarshal(migrated, &data); err != nil {
+		t.Fatalf("Migrated dump is not valid JSON: %v", err)
+	}
+	if data.HashAlgorithm != HashAlgorithmKeccak256 || data.LeafHashAlgorithm != HashAlgorithmKeccak256 {
+		t.Errorf("Migrated dump should name keccak256, got %q/%q", data.HashAlgorithm, data.LeafHashAlgorithm)
+	}
+	if data.Hash != HashAlgorithmKeccak256 {
+		t.Errorf("Migrated dump should name keccak256 in hash too, got %q", data.Hash)
+	}
+
+	tree, warnings, err := LoadSimpleMerkleTree(data, nil)
+	if err != nil {
+		t.Fatalf("Failed to load migrated dump: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Migrated dump should load without warnings, got %v", warnings)
+	}
+	if tree.Root() != root {
+		t.Errorf("Migrated root %s, want %s", tree.Root(), root)
+	}
+
+	// Migrating again changes nothing
+	again, err := MigrateDump(migrated)
+	if err != nil || string(again) != string(migrated) {
+		t.Errorf("Second migration should be a no-op: err=%v", err)
+	}
+}
+
+func TestLoadSimpleDumpWrongHash(t *testing.T) {
+	tree, err := NewSimpleMerkleTree([]BytesLike{"alice", "bob", "charlie"}, SimpleMerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	// Claims sha256, but the tree was built with keccak256
+	data, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	data.HashAlgorithm = HashAlgorithmSHA256
+	if _, _, err := LoadSimpleMerkleTree(data, nil); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected ErrInvalidDump, got %v", err)
+	}
+
+	data.HashAlgorithm = "md5"
+	if _, _, err := LoadSimpleMerkleTree(data, nil); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected unknown hashAlgorithm to be rejected, got %v", err)
+	}
+}
+
+func TestLoadSimpleDumpSHA256(t *testing.T) {
+	tree, err := NewSimpleMerkleTree([]BytesLike{"alice", "bob", "charlie"}, SimpleMerkleTreeOptions{
+		HashAlgorithm: HashAlgorithmSHA256,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	keccak, _ := NewSimpleMerkleTree([]BytesLike{"alice", "bob", "charlie"}, SimpleMerkleTreeOptions{})
+	if tree.Root() == keccak.Root() {
+		t.Error("sha256 and keccak256 trees should have different roots")
+	}
+
+	dump, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	loaded, _, err := LoadSimpleMerkleTree(dump, nil)
+	if err != nil {
+		t.Fatalf("Failed to load sha256 dump: %v", err)
+	}
+	if loaded.Root() != tree.Root() || loaded.Algorithm() != tree.Algorithm() {
+		t.Errorf("Loaded tree differs: %v %v", loaded.Root(), loaded.Algorithm())
+	}
+}
+
+// domainNodeHash is a node hash registered by TestRegisterNodeHash: sha256 of
+// the sorted pair with a domain byte, distinct from every built-in hash.
+func domainNodeHash(a, b BytesLike) HexString {
+	return SHA256NodeHash(SHA256NodeHash(a, b), HexString("0x01"))
+}
+
+func TestRegisterNodeHash(t *testing.T) {
+	const name = "test-sha256-domain"
+	if err := RegisterNodeHash(name, domainNodeHash); err != nil {
+		t.Fatalf("RegisterNodeHash failed: %v", err)
+	}
+	t.Cleanup(func() {
+		nodeHashesMu.Lock()
+		delete(namedNodeHashes, name)
+		nodeHashesMu.Unlock()
+	})
+
+	if names := HashAlgorithms(); !slices.Contains(names, name) || !slices.Contains(names, HashAlgorithmSHA256) || !slices.IsSorted(names) {
+		t.Errorf("HashAlgorithms() = %v, want the sorted built-in and registered names", names)
+	}
+
+	values := []BytesLike{"alice", "bob", "charlie"}
+	byFunc, err := NewSimpleMerkleTree(values, SimpleMerkleTreeOptions{NodeHash: domainNodeHash})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	byName, err := NewSimpleMerkleTree(values, SimpleMerkleTreeOptions{HashAlgorithm: name})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if byFunc.Root() != byName.Root() || byFunc.Algorithm().NodeHash != name {
+		t.Errorf("Trees by function and name differ: %s %s, %+v", byFunc.Root(), byName.Root(), byFunc.Algorithm())
+	}
+
+	dump, err := byFunc.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	if dump.Hash != name || dump.HashAlgorithm != name {
+		t.Errorf("Dump names hash %q, hashAlgorithm %q; want %q", dump.Hash, dump.HashAlgorithm, name)
+	}
+	loaded, warnings, err := LoadSimpleMerkleTree(dump, nil)
+	if err != nil || len(warnings) != 0 || loaded.Root() != byFunc.Root() {
+		t.Errorf("Loading by name: %v, %v", warnings, err)
+	}
+
+	// A reader of only the deprecated field resolves it too
+	dump.HashAlgorithm, dump.LeafHashAlgorithm = "", ""
+	if loaded, _, err := LoadSimpleMerkleTree(dump, nil); err != nil || loaded.Root() != byFunc.Root() {
+		t.Errorf("Loading by hash: %v", err)
+	}
+
+	for _, bad := range []string{"", HashCustom, HashAlgorithmKeccak256, name} {
+		if err := RegisterNodeHash(bad, domainNodeHash); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("RegisterNodeHash(%q) = %v, want ErrInvalidOptions", bad, err)
+		}
+	}
+	if err := RegisterNodeHash("nil-hash", nil); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("RegisterNodeHash with nil = %v, want ErrInvalidOptions", err)
+	}
+}
+
+func TestSimpleDumpHashNames(t *testing.T) {
+	values := []BytesLike{"alice", "bob"}
+	custom := func(a, b BytesLike) HexString { return StandardNodeHash(b, a) }
+	tests := []struct {
+		options SimpleMerkleTreeOptions
+		want    string
+	}{
+		{SimpleMerkleTreeOptions{}, HashAlgorithmKeccak256},
+		{SimpleMerkleTreeOptions{NodeHash: StandardNodeHash}, HashAlgorithmKeccak256},
+		{SimpleMerkleTreeOptions{NodeHash: SHA256NodeHash}, HashAlgorithmSHA256},
+		{SimpleMerkleTreeOptions{NodeHash: custom}, HashCustom},
+	}
+	for _, tt := range tests {
+		tree, err := NewSimpleMerkleTree(values, tt.options)
+		if err != nil {
+			t.Fatalf("Failed to create tree: %v", err)
+		}
+		dump, err := tree.Dump()
+		if err != nil {
+			t.Fatalf("Failed to dump tree: %v", err)
+		}
+		if dump.Hash != tt.want || dump.HashAlgorithm != tt.want {
+			t.Errorf("Dump names hash %q, hashAlgorithm %q; want %q", dump.Hash, dump.HashAlgorithm, tt.want)
+		}
+		if tt.want == HashCustom {
+			if _, _, err := LoadSimpleMerkleTree(dump, nil); !errors.Is(err, ErrInvalidDump) {
+				t.Errorf("Loading a custom hash without it = %v, want ErrInvalidDump", err)
+			}
+			if _, _, err := LoadSimpleMerkleTree(dump, custom); err != nil {
+				t.Errorf("Loading a custom hash with it: %v", err)
+			}
+		}
+	}
+
+	// hash and hashAlgorithm must agree
+	tree, _ := NewSimpleMerkleTree(values, SimpleMerkleTreeOptions{})
+	dump, _ := tree.Dump()
+	dump.Hash = HashAlgorithmSHA256
+	if _, _, err := LoadSimpleMerkleTree(dump, nil); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected contradicting hash names to be rejected, got %v", err)
+	}
+}
+
+func TestSimpleHashAlgorithmOptionErrors(t *testing.T) {
+	values := []BytesLike{"a", "b"}
+
+	_, err := NewSimpleMerkleTree(values, SimpleMerkleTreeOptions{HashAlgorithm: "md5"})
+	var optionErr *OptionError
+	if !errors.As(err, &optionErr) || optionErr.Option != "HashAlgorithm" {
+		t.Errorf("Expected HashAlgorithm option error, got %v", err)
+	}
+
+	_, err = NewSimpleMerkleTree(values, SimpleMerkleTreeOptions{
+		HashAlgorithm: HashAlgorithmSHA256,
+		NodeHash:      StandardNodeHash,
+	})
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("Expected conflicting hash options to be rejected, got %v", err)
+	}
+}
+
+func TestSimpleDumpFieldOrder(t *testing.T) {
+	tree, err := NewSimpleMerkleTree([]BytesLike{"a", "b"}, SimpleMerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	dump, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	data, err := json.Marshal(dump)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+
+	var keys []string
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.Token() // opening brace
+	for dec.More() {
+		key, _ := dec.Token()
+		keys = append(keys, key.(string))
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			t.Fatalf("Failed to decode: %v", err)
+		}
+	}
+
+	want := "format,tree,values,hash,hashAlgorithm,leafHashAlgorithm,algorithm,options,version,integrity"
+	if got := strings.Join(keys, ","); got != want {
+		t.Errorf("Dump field order is %s, want %s", got, want)
+	}
+}
+
+// standardDumpJSON builds a standard tree over values and returns it with
+// its dump round-tripped through JSON.
+func standardDumpJSON[T any](t *testing.T, values []T, options MerkleTreeOptions) (*StandardMerkleTree[T], StandardMerkleTreeData[T]) {
+	t.Helper()
+	tree, err := NewStandardMerkleTree(values, options)
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	dump, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	encoded, err := json.Marshal(dump)
+	if err != nil {
+		t.Fatalf("Failed to marshal dump: %v", err)
+	}
+	var data StandardMerkleTreeData[T]
+	if err := json.Unmarshal(encoded, &data); err != nil {
+		t.Fatalf("Failed to unmarshal dump: %v", err)
+	}
+	return tree, data
+}
+
+func TestLoadStandardMerkleTree(t *testing.T) {
+	values := []string{"alice", "bob", "charlie", "dave", "eve"}
+	for _, options := range []MerkleTreeOptions{
+		{SortLeaves: Bool(true)},
+		{SortLeaves: Bool(false)},
+		{Compatibility: CompatLatest},
+		{ShuffleSeed: []byte("seed")},
+	} {
+		tree, data := standardDumpJSON(t, values, options)
+		loaded, err := LoadStandardMerkleTree(data)
+		if err != nil {
+			t.Fatalf("%+v: Load failed: %v", options, err)
+		}
+		if loaded.Root() != tree.Root() || loaded.Algorithm() != tree.Algorithm() {
+			t.Errorf("%+v: loaded root %s (%+v), want %s (%+v)", options, loaded.Root(), loaded.Algorithm(), tree.Root(), tree.Algorithm())
+		}
+		for i, value := range values {
+			want, err := tree.GetProof(value)
+			if err != nil {
+				t.Fatalf("GetProof(%q) failed: %v", value, err)
+			}
+			if got, err := loaded.GetProof(value); err != nil || !slices.Equal(got, want) {
+				t.Errorf("%+v: loaded proof of %q = %v, %v; want %v", options, value, got, err, want)
+			}
+			if got, err := loaded.GetProof(i); err != nil || !slices.Equal(got, want) {
+				t.Errorf("%+v: loaded proof of index %d = %v, %v; want %v", options, i, got, err, want)
+			}
+		}
+	}
+}
+
+func TestLoadStandardMerkleTreeTypedValues(t *testing.T) {
+	values := []U256{U256FromUint64(5000), U256FromUint64(2500), U256FromUint64(1)}
+	tree, data := standardDumpJSON(t, values, MerkleTreeOptions{})
+	loaded, err := LoadStandardMerkleTree(data)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Root() != tree.Root() {
+		t.Errorf("Loaded root %s, want %s", loaded.Root(), tree.Root())
+	}
+	want, _ := tree.GetProof(1)
+	if got, err := loaded.GetProof(1); err != nil || !slices.Equal(got, want) {
+		t.Errorf("Loaded proof %v, %v; want %v", got, err, want)
+	}
+}
+
+func TestLoadStandardMerkleTreeRejects(t *testing.T) {
+	_, data := standardDumpJSON(t, []string{"alice", "bob", "charlie"}, MerkleTreeOptions{})
+
+	unknown := data
+	unknown.Format = "standard-v9"
+	_, err := LoadStandardMerkleTree(unknown)
+	var formatErr *FormatError
+	if !errors.As(err, &formatErr) || formatErr.Format != "standard-v9" || !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected a *FormatError wrapping ErrInvalidDump, got %v", err)
+	}
+
+	missing := data
+	missing.Values = data.Values[:2]
+	if _, err := LoadStandardMerkleTree(missing); !errors.Is(err, ErrInvalidDump) || !strings.Contains(err.Error(), "2 values for a tree of 3 leaves") {
+		t.Errorf("Expected a value count error, got %v", err)
+	}
+
+	tampered := data
+	tampered.Tree = slices.Clone(data.Tree)
+	tampered.Tree[0] = HexString("0x" + strings.Repeat("00", 32))
+	if _, err := LoadStandardMerkleTree(tampered); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected a tampered root to be rejected, got %v", err)
+	}
+
+	swapped := data
+	swapped.Values = slices.Clone(data.Values)
+	swapped.Values[0].Value, swapped.Values[1].Value = swapped.Values[1].Value, swapped.Values[0].Value
+	if _, err := LoadStandardMerkleTree(swapped); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected values at the wrong leaves to be rejected, got %v", err)
+	}
+
+	shared := data
+	shared.Values = slices.Clone(data.Values)
+	shared.Values[1].TreeIndex = shared.Values[0].TreeIndex
+	if _, err := LoadStandardMerkleTree(shared); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected a shared tree index to be rejected, got %v", err)
+	}
+}
+
+func TestLoadSimpleMerkleTreeWithOptions(t *testing.T) {
+	values := []BytesLike{"alice", "bob", "charlie", "dave", "eve"}
+	// Concatenates the pair unsorted before hashing, unlike StandardNodeHash
+	ordered := func(left, right BytesLike) HexString {
+		return StandardLeafHash(string(concatBytes(t, left, right)))
+	}
+
+	for _, options := range []SimpleMerkleTreeOptions{
+		{MerkleTreeOptions: MerkleTreeOptions{SortLeaves: Bool(true)}},
+		{MerkleTreeOptions: MerkleTreeOptions{SortLeaves: Bool(false)}},
+		{MerkleTreeOptions: MerkleTreeOptions{SortLeaves: Bool(false)}, NodeHash: ordered},
+	} {
+		tree, err := NewSimpleMerkleTree(values, options)
+		if err != nil {
+			t.Fatalf("Failed to create tree: %v", err)
+		}
+		dump, err := tree.Dump()
+		if err != nil {
+			t.Fatalf("Failed to dump tree: %v", err)
+		}
+		encoded, err := json.Marshal(dump)
+		if err != nil {
+			t.Fatalf("Failed to marshal dump: %v", err)
+		}
+		var data SimpleMerkleTreeData
+		if err := json.Unmarshal(encoded, &data); err != nil {
+			t.Fatalf("Failed to unmarshal dump: %v", err)
+		}
+
+		loaded, warnings, err := LoadSimpleMerkleTreeWithOptions(data, SimpleMerkleTreeOptions{
+			MerkleTreeOptions: MerkleTreeOptions{PrefixIndex: true, EagerIndexes: true},
+			NodeHash:          options.NodeHash,
+		})
+		if err != nil || len(warnings) != 0 {
+			t.Fatalf("SortLeaves=%v: Load failed: %v %v", *options.SortLeaves, err, warnings)
+		}
+		if loaded.Root() != tree.Root() || len(loaded.HashLookup) != len(values) || loaded.prefixIndex == nil {
+			t.Errorf("SortLeaves=%v: loaded root %s with %d lookups, want %s with indexes",
+				*options.SortLeaves, loaded.Root(), len(loaded.HashLookup), tree.Root())
+		}
+		for _, value := range values {
+			want, _ := tree.GetProof(value)
+			if got, err := loaded.GetProof(value); err != nil || !slices.Equal(got, want) {
+				t.Errorf("SortLeaves=%v: proof of %v = %v, %v; want %v", *options.SortLeaves, value, got, err, want)
+			}
+		}
+	}
+}
+
+func TestLoadSimpleMerkleTreeWithOptionsRejects(t *testing.T) {
+	tree, err := NewSimpleMerkleTree([]BytesLike{"alice", "bob", "charlie"}, SimpleMerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	data, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+
+	var optErr *OptionError
+	_, _, err = LoadSimpleMerkleTreeWithOptions(data, SimpleMerkleTreeOptions{HashAlgorithm: HashAlgorithmSHA256})
+	if !errors.As(err, &optErr) || optErr.Option != "HashAlgorithm" {
+		t.Errorf("Expected an OptionError for a different hash algorithm, got %v", err)
+	}
+	if _, _, err := LoadSimpleMerkleTreeWithOptions(data, SimpleMerkleTreeOptions{HashAlgorithm: HashAlgorithmKeccak256}); err != nil {
+		t.Errorf("The dump's own hash algorithm was rejected: %v", err)
+	}
+
+	// A value that does not hash to its leaf
+	data.Values = slices.Clone(data.Values)
+	data.Values[0].Value = "mallory"
+	if _, _, err := LoadSimpleMerkleTreeWithOptions(data, SimpleMerkleTreeOptions{}); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected a mismatched leaf to be rejected, got %v", err)
+	}
+}
+
+// concatBytes returns the bytes of left followed by those of right.
+func concatBytes(t *testing.T, left, right BytesLike) []byte {
+	t.Helper()
+	l, err := ToBytes(left)
+	if err != nil {
+		t.Fatalf("ToBytes failed: %v", err)
+	}
+	r, err := ToBytes(right)
+	if err != nil {
+		t.Fatalf("ToBytes failed: %v", err)
+	}
+	return append(l, r...)
+}
@@ -0,0 +1,99 @@
+package merkletree
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Per-item memory costs on 64-bit platforms, measured with runtime.MemStats.
+// TestEstimateBuildMemory checks them against a real build.
+const (
+	// hexNodeBytes is one Tree entry: a 16-byte string header plus the
+	// 66-byte "0x..." string, which the allocator rounds up to 80 bytes.
+	hexNodeBytes = 16 + 80
+
+	// valueEntryBytes is one Values entry holding a two-word value such as a
+	// string or interface, plus its tree index. The contents the value points
+	// to belong to the caller and are not counted.
+	valueEntryBytes = 24
+
+	// lookupEntryBytes is one HashLookup entry including map overhead.
+	lookupEntryBytes = 35
+
+	// prefixEntryBytes is one prefix index entry; its hash shares the leaf's string.
+	prefixEntryBytes = 24
+
+	// transientLeafBytes is what construction keeps alive per leaf until it
+	// returns: the leaf hash string and its slice slot (96), the hashed value
+	// record sorted in place (40), and the boxed and converted leaf slices
+	// handed to tree assembly (48).
+	transientLeafBytes = 96 + 40 + 48
+)
+
+// MemoryEstimate is the expected heap usage of building a tree.
+type MemoryEstimate struct {
+	Nodes          int   // Number of nodes in Tree
+	TreeBytes      int64 // Tree array of hex strings
+	ValueBytes     int64 // Values entries, excluding the values' own contents
+	LookupBytes    int64 // HashLookup, plus the prefix index when enabled; built on first use
+	ProofBytes     int64 // Pre-generated proofs, when enabled and within the limit
+	TransientBytes int64 // Build buffers released when construction returns
+	RetainedBytes  int64 // Everything the finished tree keeps once its indexes are built
+	PeakBytes      int64 // Highest usage during construction
+}
+
+// EstimateBuildMemory estimates the memory needed to build a tree of
+// leafCount values with the given options, so a build that will not fit can
+// be rejected before it starts. The estimate is for 64-bit platforms and
+// values of at most two words, such as strings; garbage produced while
+// hashing and sorting is not included because it can be collected at any time.
+func EstimateBuildMemory(leafCount int, opts MerkleTreeOptions) (MemoryEstimate, error) {
+	if leafCount <= 0 {
+		return MemoryEstimate{}, ErrEmptyTree
+	}
+	if err := errors.Join(opts.validateInput(leafCount)...); err != nil {
+		return MemoryEstimate{}, err
+	}
+
+	n := int64(leafCount)
+	e := MemoryEstimate{
+		Nodes:          2*leafCount - 1,
+		TreeBytes:      (2*n - 1) * hexNodeBytes,
+		ValueBytes:     n * valueEntryBytes,
+		LookupBytes:    n * lookupEntryBytes,
+		TransientBytes: n * transientLeafBytes,
+	}
+	if opts.PrefixIndex {
+		e.LookupBytes += n * prefixEntryBytes
+	}
+	if opts.PregenerateProofs && leafCount <= opts.pregenerateLimit() {
+		// One int32 offset per value plus one int32 node index per proof step
+		e.ProofBytes = 4 * (n + 1 + int64(sumLeafDepths(leafCount)))
+	}
+	e.RetainedBytes = e.TreeBytes + e.ValueBytes + e.LookupBytes + e.ProofBytes
+
+	// The lookups and proofs are built after the build buffers are released.
+	e.PeakBytes = max(e.TreeBytes+e.ValueBytes+e.TransientBytes, e.RetainedBytes)
+	return e, nil
+}
+
+// String summarizes the estimate in human-readable units.
+func (e MemoryEstimate) String() string {
+	return fmt.Sprintf("peak %s, retained %s (tree %s, values %s, lookup %s)",
+		formatBytes(e.PeakBytes), formatBytes(e.RetainedBytes),
+		formatBytes(e.TreeBytes), formatBytes(e.ValueBytes), formatBytes(e.LookupBytes))
+}
+
+// formatBytes renders n bytes with a binary unit.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
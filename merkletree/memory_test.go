@@ -0,0 +1,69 @@
+package merkletree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+func TestEstimateBuildMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Builds a 50k-leaf tree")
+	}
+
+	const leafCount = 50000
+	values := make([]string, leafCount)
+	for i := range values {
+		values[i] = fmt.Sprintf("value-%d", i)
+	}
+
+	for _, options := range []MerkleTreeOptions{{}, {PrefixIndex: true}, {PregenerateProofs: true}} {
+		estimate, err := EstimateBuildMemory(leafCount, options)
+		if err != nil {
+			t.Fatalf("Failed to estimate: %v", err)
+		}
+
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		tree, err := NewStandardMerkleTree(values, options)
+		if err != nil {
+			t.Fatalf("Failed to create tree: %v", err)
+		}
+		if err := tree.WarmIndexes(context.Background()); err != nil {
+			t.Fatalf("WarmIndexes failed: %v", err)
+		}
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		runtime.KeepAlive(tree)
+
+		retained := int64(after.HeapAlloc) - int64(before.HeapAlloc)
+		ratio := float64(retained) / float64(estimate.RetainedBytes)
+		if ratio < 0.85 || ratio > 1.15 {
+			t.Errorf("%+v: measured %d retained bytes, estimated %d (ratio %.2f)",
+				options, retained, estimate.RetainedBytes, ratio)
+		}
+		if estimate.PeakBytes < estimate.RetainedBytes {
+			t.Errorf("Peak %d is below retained %d", estimate.PeakBytes, estimate.RetainedBytes)
+		}
+	}
+}
+
+func TestEstimateBuildMemoryErrors(t *testing.T) {
+	if _, err := EstimateBuildMemory(0, MerkleTreeOptions{}); !errors.Is(err, ErrEmptyTree) {
+		t.Errorf("Expected ErrEmptyTree, got %v", err)
+	}
+	if _, err := EstimateBuildMemory(10, MerkleTreeOptions{MaxLeaves: 5}); !errors.Is(err, ErrTooManyLeaves) {
+		t.Errorf("Expected ErrTooManyLeaves, got %v", err)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int64]string{512: "512 B", 1536: "1.5 KiB", 3 << 30: "3.0 GiB"} {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -1,7 +1,12 @@
 package merkletree
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 )
 
 // MerkleTreeImpl is the base structure for a Merkle tree.
@@ -12,9 +17,106 @@ type MerkleTreeImpl[T any] struct {
 		Value     T
 		TreeIndex int
 	}
-	LeafHash   func(T) HexString // Function to hash leaves
-	NodeHash   NodeHash          // Function to hash internal nodes
-	HashLookup map[HexString]int // Maps leaf hashes to value indices
+	LeafHash   func(T) HexString // Function to hash leaves; required
+	NodeHash   NodeHash          // Function to hash internal nodes; StandardNodeHash if nil
+	HashLookup map[HexString]int // Maps normalized leaf hashes to value indices; built on first use, see WarmIndexes
+
+	algorithm        AlgorithmDescriptor   // Hashes and leaf order used to build the tree
+	compatibility    CompatibilityMode     // Rules the tree was built with, for AppendLeaves
+	indexes          *treeIndexes          // Guards building HashLookup and prefixIndex on first use
+	prefixIndex      []prefixEntry         // Leaf hashes sorted for prefix search (optional)
+	maxPrefixResults int                   // Cap on FindByHashPrefix results
+	duplicates       map[HexString][]int   // Value indices of leaf hashes that occur more than once
+	duplicatePolicy  DuplicatePolicy       // How lookups resolve duplicated values
+	metadata         []json.RawMessage     // Per-value metadata, indexed like Values (optional)
+	quarantine       []quarantinedValue[T] // Values left out under QuarantineInvalid
+	quarantined      int                   // Number of values left out, also known after Load
+	valuesDropped    bool                  // Values were discarded under DropValuesAfterBuild
+	valueProvider    ValueProvider[T]      // Source of dropped values (optional)
+	warnings         []string              // Warnings raised while building
+	recorder         IssuanceRecorder      // Records exported claims (optional)
+	recorderOptions  RecorderOptions       // What to do when recorder fails
+	proofs           *proofTable           // Pre-generated proofs (optional)
+	pregenerateLimit int                   // PregenerateMaxLeaves, kept for AppendLeaves
+	maxValueBytes    int                   // MaxValueBytes, kept for AppendLeaves
+	shuffleSeed      []byte                // Seed of a shuffled leaf order, unless withheld
+	contextRedactor  ContextRedactor[T]    // Redacts values shown by GetProofWithContext (optional)
+	leafEncoding     []string              // Solidity types of ABI-encoded values (optional)
+}
+
+// Entry describes one value of the tree.
+type Entry[T any] struct {
+	ValueIndex int             // Index of the value in insertion order
+	Value      T               // Original value
+	TreeIndex  int             // Position of the leaf in Tree
+	LeafHash   HexString       // Hash of the value as stored in the tree
+	Metadata   json.RawMessage // Metadata attached to the value, if any
+}
+
+// Entries returns every value of the tree in insertion order,
+// together with its leaf and metadata.
+// Returns ErrValuesDropped if the values were dropped and there is no ValueProvider.
+func (m *MerkleTreeImpl[T]) Entries() ([]Entry[T], error) {
+	entries := make([]Entry[T], 0, len(m.Values))
+	for entry, err := range m.AllEntries {
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// AllEntries yields every value of the tree like Entries, one at a time and
+// in insertion order whatever order the leaves were sorted into, so a caller
+// can walk a large tree without copying it. A value that cannot be read, such
+// as a dropped value without a ValueProvider, is yielded as an error and ends
+// the iteration:
+//
+//	for entry, err := range tree.AllEntries {
+//		if err != nil {
+//			return err
+//		}
+//		proof, err := tree.GetProof(entry.ValueIndex)
+//		...
+//	}
+func (m *MerkleTreeImpl[T]) AllEntries(yield func(Entry[T], error) bool) {
+	for i, v := range m.Values {
+		value, err := m.valueAt(i)
+		if err != nil {
+			yield(Entry[T]{}, err)
+			return
+		}
+		entry := Entry[T]{
+			ValueIndex: i,
+			Value:      value,
+			TreeIndex:  v.TreeIndex,
+			LeafHash:   m.Tree[v.TreeIndex],
+			Metadata:   m.metadataAt(i),
+		}
+		if !yield(entry, nil) {
+			return
+		}
+	}
+}
+
+// metadataAt returns the metadata of the value at index, or nil if it has none.
+func (m *MerkleTreeImpl[T]) metadataAt(index int) json.RawMessage {
+	if index >= len(m.metadata) {
+		return nil
+	}
+	return m.metadata[index]
+}
+
+// setMetadata stores a copy of metadata, or nothing if no value has any.
+func (m *MerkleTreeImpl[T]) setMetadata(metadata []json.RawMessage) {
+	m.metadata = nil
+	for _, meta := range metadata {
+		if meta != nil {
+			m.metadata = append([]json.RawMessage(nil), metadata...)
+			return
+		}
+	}
 }
 
 // Root returns the root hash of the Merkle tree.
@@ -25,9 +127,47 @@ func (m *MerkleTreeImpl[T]) Root() HexString {
 	return m.Tree[0]
 }
 
+// buildHashLookup maps every leaf hash, normalized, to a value index.
+// When a hash occurs more than once, the value with the lowest tree index wins
+// and all occurrences are recorded so lookups can report the ambiguity.
+// It is called through hashLookup, which builds it once.
+func (m *MerkleTreeImpl[T]) buildHashLookup() {
+	lookup := make(map[HexString]int, len(m.Values))
+	m.duplicates = nil
+
+	for i, v := range m.Values {
+		hash := m.Tree[v.TreeIndex].Normalize()
+		existing, found := lookup[hash]
+		if !found {
+			lookup[hash] = i
+			continue
+		}
+
+		if m.duplicates == nil {
+			m.duplicates = make(map[HexString][]int)
+		}
+		if len(m.duplicates[hash]) == 0 {
+			m.duplicates[hash] = []int{existing}
+		}
+		m.duplicates[hash] = append(m.duplicates[hash], i)
+		if v.TreeIndex < m.Values[existing].TreeIndex {
+			lookup[hash] = i
+		}
+	}
+
+	// Keep occurrences in tree order so error messages are deterministic
+	for _, indices := range m.duplicates {
+		sort.Slice(indices, func(a, b int) bool {
+			return m.Values[indices[a]].TreeIndex < m.Values[indices[b]].TreeIndex
+		})
+	}
+	m.HashLookup = lookup
+}
+
 // getLeafIndex returns the index of a value in the Merkle tree.
 // The leaf parameter can be either an integer index or a value of type T.
 // Returns an error if the index is out of bounds or the value is not found.
+// A value that occurs more than once resolves according to the tree's DuplicatePolicy.
 func (m *MerkleTreeImpl[T]) getLeafIndex(leaf any) (int, error) {
 	switch v := leaf.(type) {
 	case int:
@@ -36,22 +176,52 @@ func (m *MerkleTreeImpl[T]) getLeafIndex(leaf any) (int, error) {
 		}
 		return v, nil
 	default:
-		hashedLeaf := m.LeafHash(v.(T))
-		if index, found := m.HashLookup[hashedLeaf]; found {
-			return index, nil
+		value, err := leafValue[T](leaf)
+		if err != nil {
+			return -1, err
+		}
+		if err := m.checkLeafHash(); err != nil {
+			return -1, err
+		}
+		hashedLeaf := m.LeafHash(value).Normalize()
+		index, found := m.hashLookup()[hashedLeaf]
+		if !found {
+			if err := m.checkQuarantined(value); err != nil {
+				return -1, err
+			}
+			if !IsValidMerkleNode(hashedLeaf) {
+				return -1, fmt.Errorf("%w: %T cannot be hashed to a leaf", ErrInvalidLeafType, leaf)
+			}
+			return -1, ErrValueNotFound
+		}
+		if occurrences := m.duplicates[hashedLeaf]; len(occurrences) > 1 && m.duplicatePolicy == DuplicatesRequireIndex {
+			return -1, fmt.Errorf("%w: %d occurrences at value indices %v; use ProofForIndex",
+				ErrAmbiguousValue, len(occurrences), occurrences)
 		}
-		return -1, ErrValueNotFound
+		return index, nil
 	}
 }
 
 // validateValueAt verifies that the value at the given index is valid in the Merkle tree.
 // Returns an error if the index is out of bounds or the hash doesn't match.
+// When values were dropped without a ValueProvider there is nothing to compare
+// and only the index is checked.
 func (m *MerkleTreeImpl[T]) validateValueAt(index int) error {
 	if index < 0 || index >= len(m.Values) {
 		return fmt.Errorf("%w: index %d (max: %d)", ErrInvalidIndex, index, len(m.Values)-1)
 	}
+	if m.valuesDropped && m.valueProvider == nil {
+		return nil
+	}
 
-	expectedHash := m.LeafHash(m.Values[index].Value)
+	value, err := m.valueAt(index)
+	if err != nil {
+		return err
+	}
+	if err := m.checkLeafHash(); err != nil {
+		return err
+	}
+	expectedHash := m.LeafHash(value)
 	actualHash := m.Tree[m.Values[index].TreeIndex]
 
 	if expectedHash != actualHash {
@@ -62,44 +232,87 @@ func (m *MerkleTreeImpl[T]) validateValueAt(index int) error {
 }
 
 // IsValidMerkleTree verifies if a Merkle tree is structurally valid.
-// It checks that each internal node's hash is correctly computed from its children.
+// It checks that each internal node's hash is correctly computed from its
+// children, that every node is a 32-byte hash and that the length is 2n-1.
+// A single-node tree has nothing to recompute and is valid if its node is.
+// ValidateTreeDetailed reports what was checked and what failed. If nodeHash
+// is nil, StandardNodeHash is used.
 func IsValidMerkleTree(tree []HexString, nodeHash NodeHash) bool {
-	if len(tree) == 0 {
-		return false
-	}
-
-	// Check every node to ensure children produce the correct value
-	for i, node := range tree {
-		left := LeftChildIndex(i)
-		right := RightChildIndex(i)
-
-		if right < len(tree) {
-			expected := nodeHash(tree[left], tree[right])
-			if expected != node {
-				return false
-			}
-		}
-	}
-	return true
+	_, err := ValidateTreeDetailed(tree, nodeHash)
+	return err == nil
 }
 
 // LeafHashFromInput computes the hash of a leaf, ensuring consistency with tree construction.
 // The leaf parameter can be either an integer index or a value of type T.
-// Returns an error if the index is invalid.
+// Returns an error if the index is invalid, or ErrInvalidLeafType if the
+// leaf is of another type or cannot be hashed.
 func (m *MerkleTreeImpl[T]) LeafHashFromInput(leaf any) (HexString, error) {
 	switch v := leaf.(type) {
 	case int:
 		if v < 0 || v >= len(m.Values) {
 			return "", fmt.Errorf("%w: leaf index %d (max: %d)", ErrInvalidIndex, v, len(m.Values)-1)
 		}
+		if m.valuesDropped {
+			return m.Tree[m.Values[v].TreeIndex], nil
+		}
+		if err := m.checkLeafHash(); err != nil {
+			return "", err
+		}
 		return m.LeafHash(m.Values[v].Value), nil
 	default:
-		return m.LeafHash(v.(T)), nil
+		value, err := leafValue[T](leaf)
+		if err != nil {
+			return "", err
+		}
+		if err := m.checkLeafHash(); err != nil {
+			return "", err
+		}
+		hash := m.LeafHash(value)
+		if !IsValidMerkleNode(hash) {
+			return "", fmt.Errorf("%w: %T cannot be hashed to a leaf", ErrInvalidLeafType, leaf)
+		}
+		return hash, nil
+	}
+}
+
+// HasLeafHash reports whether hash, in any case, is the leaf hash of a value
+// in the tree. Internal nodes and the root are not leaves, so a proof from
+// one of them proves no value; verifiers that take a leaf hash from a client
+// check it here first.
+func (m *MerkleTreeImpl[T]) HasLeafHash(hash BytesLike) bool {
+	leaf, err := ToHex(hash)
+	if err != nil {
+		return false
+	}
+	_, found := m.hashLookup()[leaf.Normalize()]
+	return found
+}
+
+// checkLeafHash returns an error wrapping ErrInvalidTree if the tree has no
+// LeafHash, as a MerkleTreeImpl built as a literal may not; the constructors
+// always set one.
+func (m *MerkleTreeImpl[T]) checkLeafHash() error {
+	if m.LeafHash == nil {
+		return fmt.Errorf("%w: tree has no LeafHash", ErrInvalidTree)
+	}
+	return nil
+}
+
+// leafValue returns leaf as a value of type T, or an error wrapping
+// ErrInvalidLeafType naming both types if it is of another type.
+func leafValue[T any](leaf any) (T, error) {
+	value, ok := leaf.(T)
+	if !ok {
+		return value, fmt.Errorf("%w: got %T, want %v", ErrInvalidLeafType, leaf, reflect.TypeFor[T]())
 	}
+	return value, nil
 }
 
 // GetProof generates a Merkle proof for a specific value.
 // The leaf parameter can be either an integer index or a value of type T.
+// When a value occurs more than once, the proof is for the occurrence with the
+// lowest tree index, unless the tree was built with DuplicatesRequireIndex,
+// in which case ErrAmbiguousValue is returned.
 // Returns the proof as a slice of hex strings, or an error if the value is not found.
 func (m *MerkleTreeImpl[T]) GetProof(leaf any) ([]HexString, error) {
 	valueIndex, err := m.getLeafIndex(leaf)
@@ -110,30 +323,46 @@ func (m *MerkleTreeImpl[T]) GetProof(leaf any) ([]HexString, error) {
 	if err := m.validateValueAt(valueIndex); err != nil {
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
-
-	treeIndex := m.Values[valueIndex].TreeIndex
-	bytesTree := make([]BytesLike, len(m.Tree))
-	for i, hexStr := range m.Tree {
-		hexStrVal, err := ToBytes(hexStr)
-		if err != nil {
-			return nil, fmt.Errorf("error converting tree node %d: %w", i, err)
-		}
-		bytesTree[i] = hexStrVal
+	if m.proofs != nil {
+		return m.proofs.proof(m.Tree, valueIndex), nil
 	}
 
-	proof, err := GetProof(bytesTree, treeIndex)
-	if err != nil {
-		return nil, fmt.Errorf("error generating proof: %w", err)
+	// Only the siblings on the path are read, so a proof costs O(log n)
+	// however large the tree is. Normalize leaves the hashes of built trees
+	// as they are and lowercases those of loaded ones, as decoding them did
+	var proof []HexString
+	for i := m.Values[valueIndex].TreeIndex; i > 0; i = parentIndex(i) {
+		proof = append(proof, m.Tree[siblingIndex(i)].Normalize())
 	}
 
 	// Empty proof is valid for single-value trees (root is the leaf)
 	return proof, nil
 }
 
+// ProofForIndex generates a Merkle proof for the value at the given value index.
+// Unlike GetProof it never interprets its argument as a value, which makes it
+// the unambiguous choice for trees of integers and for duplicated values.
+func (m *MerkleTreeImpl[T]) ProofForIndex(index int) ([]HexString, error) {
+	return m.GetProof(index)
+}
+
 // Verify checks if a proof is valid for a given leaf.
 // The leaf parameter can be either an integer index or a value of type T.
-// Returns true if the proof is valid, false otherwise.
+// Returns true if the proof is valid, false otherwise. When the node hash
+// keeps the order of its inputs, as PositionalNodeHash does, the proof is
+// hashed along the path from the leaf's position, so a value that is not in
+// the tree does not verify.
 func (m *MerkleTreeImpl[T]) Verify(leaf any, proof []HexString) (bool, error) {
+	return m.VerifyCtx(context.Background(), leaf, proof)
+}
+
+// VerifyCtx is Verify stopping with ctx.Err() when ctx is done, which it
+// checks before hashing each proof node.
+func (m *MerkleTreeImpl[T]) VerifyCtx(ctx context.Context, leaf any, proof []HexString) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
 	bytesProof := make([]BytesLike, len(proof))
 	for i, hexStr := range proof {
 		proofVal, err := ToBytes(hexStr)
@@ -152,8 +381,11 @@ func (m *MerkleTreeImpl[T]) Verify(leaf any, proof []HexString) (bool, error) {
 	if hashFunc == nil {
 		hashFunc = StandardNodeHash
 	}
+	if nodeHashKeepsOrder(hashFunc) {
+		return m.verifyAt(ctx, leaf, leafHash, bytesProof, hashFunc)
+	}
 
-	computedRoot, err := ProcessProof(leafHash, bytesProof, hashFunc)
+	computedRoot, err := processProof(ctx, leafHash, bytesProof, hashFunc)
 	if err != nil {
 		return false, fmt.Errorf("error processing proof: %w", err)
 	}
@@ -161,19 +393,34 @@ func (m *MerkleTreeImpl[T]) Verify(leaf any, proof []HexString) (bool, error) {
 	return computedRoot == m.Root(), nil
 }
 
-// Validate verifies if the tree is structurally valid.
-// It checks all values and the overall tree structure.
-// Returns an error if any validation fails.
-func (m *MerkleTreeImpl[T]) Validate() error {
-	for i := range m.Values {
-		if err := m.validateValueAt(i); err != nil {
-			return fmt.Errorf("validation failed at index %d: %w", i, err)
-		}
+// verifyAt verifies the proof of leaf, whose leaf hash is leafHash, at the
+// position of leaf in the tree, for node hashes that keep the order of their
+// inputs: the proof carries no sides, which the position gives. A value that
+// is not in the tree has no position and does not verify.
+func (m *MerkleTreeImpl[T]) verifyAt(ctx context.Context, leaf any, leafHash HexString, proof []BytesLike, nodeHash NodeHash) (bool, error) {
+	valueIndex, err := m.getLeafIndex(leaf)
+	if errors.Is(err, ErrValueNotFound) {
+		return false, nil
 	}
-
-	if !IsValidMerkleTree(m.Tree, m.NodeHash) {
-		return fmt.Errorf("merkle tree structure is invalid")
+	if err != nil {
+		return false, err
 	}
+	n := (len(m.Tree) + 1) / 2
+	computedRoot, err := processProofAt(ctx, leafHash, m.Values[valueIndex].TreeIndex-(n-1), n, proof, nodeHash)
+	if errors.Is(err, ErrInvalidProof) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error processing proof: %w", err)
+	}
+	return computedRoot.Normalize() == m.Root().Normalize(), nil
+}
 
-	return nil
+// Validate verifies if the tree is structurally valid.
+// It checks all values and the overall tree structure, hashing nodes with
+// StandardNodeHash if NodeHash is nil.
+// Returns an error if any validation fails, including a missing LeafHash.
+func (m *MerkleTreeImpl[T]) Validate() error {
+	_, err := m.ValidateDetailed()
+	return err
 }
@@ -0,0 +1,299 @@
+package merkletree
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lowestTreeIndex returns the value index of the occurrence of value with the lowest tree index.
+func lowestTreeIndex[T comparable](m *MerkleTreeImpl[T], value T) int {
+	best := -1
+	for i, v := range m.Values {
+		if v.Value == value && (best < 0 || v.TreeIndex < m.Values[best].TreeIndex) {
+			best = i
+		}
+	}
+	return best
+}
+
+func TestGetProofDuplicatesLowestIndex(t *testing.T) {
+	values := []string{"dup", "alpha", "dup", "beta", "gamma", "dup", "delta"}
+
+	for _, sortLeaves := range []bool{true, false} {
+		var first []HexString
+		for build := 0; build < 50; build++ {
+			tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(sortLeaves)})
+			if err != nil {
+				t.Fatalf("Failed to create tree: %v", err)
+			}
+
+			proof, err := tree.GetProof("dup")
+			if err != nil {
+				t.Fatalf("Failed to get proof: %v", err)
+			}
+
+			want, err := tree.ProofForIndex(lowestTreeIndex(&tree.MerkleTreeImpl, "dup"))
+			if err != nil {
+				t.Fatalf("Failed to get proof by index: %v", err)
+			}
+			if !reflect.DeepEqual(proof, want) {
+				t.Fatalf("sortLeaves=%v: proof by value is not for the lowest tree index", sortLeaves)
+			}
+
+			if first == nil {
+				first = proof
+			} else if !reflect.DeepEqual(proof, first) {
+				t.Fatalf("sortLeaves=%v: proof changed on rebuild %d", sortLeaves, build)
+			}
+
+			valid, err := tree.Verify("dup", proof)
+			if err != nil || !valid {
+				t.Fatalf("Proof should verify: valid=%v err=%v", valid, err)
+			}
+		}
+	}
+}
+
+func TestGetProofDuplicatesRequireIndex(t *testing.T) {
+	values := []string{"dup", "alpha", "dup", "beta", "dup"}
+
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{
+		SortLeaves: Bool(true),
+		Duplicates: DuplicatesRequireIndex,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	_, err = tree.GetProof("dup")
+	if !errors.Is(err, ErrAmbiguousValue) {
+		t.Fatalf("Expected ErrAmbiguousValue, got %v", err)
+	}
+
+	// Every occurrence is still reachable by index
+	for _, i := range []int{0, 2, 4} {
+		proof, err := tree.ProofForIndex(i)
+		if err != nil {
+			t.Fatalf("Failed to get proof for index %d: %v", i, err)
+		}
+		valid, err := tree.Verify(i, proof)
+		if err != nil || !valid {
+			t.Errorf("Proof for index %d should verify: valid=%v err=%v", i, valid, err)
+		}
+	}
+
+	// Unique values are unaffected by the policy
+	if _, err := tree.GetProof("alpha"); err != nil {
+		t.Errorf("Unique value should not be ambiguous: %v", err)
+	}
+}
+
+func TestDuplicateOccurrencesAreDeterministic(t *testing.T) {
+	values := []BytesLike{"x", "y", "x", "z", "x"}
+
+	var first error
+	for build := 0; build < 20; build++ {
+		tree, err := NewSimpleMerkleTree(values, SimpleMerkleTreeOptions{
+			MerkleTreeOptions: MerkleTreeOptions{SortLeaves: Bool(true), Duplicates: DuplicatesRequireIndex},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create tree: %v", err)
+		}
+
+		_, err = tree.GetProof(BytesLike("x"))
+		if first == nil {
+			first = err
+		} else if err.Error() != first.Error() {
+			t.Fatalf("Ambiguity error changed between builds: %q vs %q", err, first)
+		}
+	}
+}
+
+func TestAllEntriesSortedTree(t *testing.T) {
+	values := []string{"dave", "alice", "eve", "bob", "charlie"}
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(true)})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	var got []string
+	inTreeOrder := true
+	for entry, err := range tree.AllEntries {
+		if err != nil {
+			t.Fatalf("AllEntries failed: %v", err)
+		}
+		if entry.ValueIndex != len(got) || entry.LeafHash != tree.Tree[entry.TreeIndex] {
+			t.Errorf("Entry %d is %+v", len(got), entry)
+		}
+		if entry.TreeIndex != len(tree.Tree)-len(values)+entry.ValueIndex {
+			inTreeOrder = false
+		}
+		proof, err := tree.GetProof(entry.ValueIndex)
+		if err != nil {
+			t.Fatalf("GetProof(%d) failed: %v", entry.ValueIndex, err)
+		}
+		if valid, err := tree.Verify(entry.ValueIndex, proof); err != nil || !valid {
+			t.Errorf("Proof of %q does not verify: %v", entry.Value, err)
+		}
+		got = append(got, entry.Value)
+	}
+	if !reflect.DeepEqual(got, values) {
+		t.Errorf("AllEntries yielded %v, want insertion order %v", got, values)
+	}
+	if inTreeOrder {
+		t.Fatalf("Sorting left the values in insertion order; the test proves nothing")
+	}
+
+	entries, err := tree.Entries()
+	if err != nil || len(entries) != len(values) || entries[2].Value != "eve" {
+		t.Errorf("Entries = %v, %v", entries, err)
+	}
+}
+
+func TestAllEntriesStopsEarly(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"a", "b", "c", "d"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	seen := 0
+	for range tree.AllEntries {
+		seen++
+		if seen == 2 {
+			break
+		}
+	}
+	if seen != 2 {
+		t.Errorf("Iteration went on to %d entries after break", seen)
+	}
+
+	tree, err = NewStandardMerkleTree([]string{"a", "b"}, MerkleTreeOptions{DropValuesAfterBuild: true})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	for _, err := range tree.AllEntries {
+		if !errors.Is(err, ErrValuesDropped) {
+			t.Errorf("Expected ErrValuesDropped, got %v", err)
+		}
+	}
+}
+
+func TestWrongLeafType(t *testing.T) {
+	standard, err := NewStandardMerkleTree([]string{"a", "b", "c"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	simple, err := NewSimpleMerkleTree([]BytesLike{"a", "b", "c"}, SimpleMerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	proof, err := standard.GetProof(0)
+	if err != nil {
+		t.Fatalf("GetProof failed: %v", err)
+	}
+
+	for name, check := range map[string]func() error{
+		"standard GetProof": func() error { _, err := standard.GetProof([]byte("a")); return err },
+		"standard Verify":   func() error { _, err := standard.Verify(3.5, proof); return err },
+		"standard hash":     func() error { _, err := standard.LeafHashFromInput(HexString("a")); return err },
+		"simple GetProof":   func() error { _, err := simple.GetProof(struct{}{}); return err },
+		"simple Verify":     func() error { _, err := simple.Verify(3.5, proof); return err },
+	} {
+		err := check()
+		if !errors.Is(err, ErrInvalidLeafType) {
+			t.Errorf("%s: expected ErrInvalidLeafType, got %v", name, err)
+		}
+	}
+
+	_, err = standard.GetProof([]byte("a"))
+	if want := "got []uint8, want string"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Error %v does not name the types (%s)", err, want)
+	}
+
+	// A value of the right type that is not in the tree is still not found
+	if _, err := standard.GetProof("z"); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("Expected ErrValueNotFound, got %v", err)
+	}
+}
+
+func TestGetProofHexCase(t *testing.T) {
+	standard, err := NewStandardMerkleTree([]string{"alice", "bob", "charlie"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	simple, err := standard.ToSimple()
+	if err != nil {
+		t.Fatalf("ToSimple failed: %v", err)
+	}
+
+	for i, v := range simple.Values {
+		leaf := string(simple.Tree[v.TreeIndex])
+		want, err := simple.GetProof(i)
+		if err != nil {
+			t.Fatalf("GetProof(%d) failed: %v", i, err)
+		}
+		for _, variant := range []BytesLike{
+			strings.ToUpper(leaf[:2]) + leaf[2:],
+			"0x" + strings.ToUpper(leaf[2:]),
+			HexString("0X" + strings.ToUpper(leaf[2:])),
+		} {
+			got, err := simple.GetProof(variant)
+			if err != nil || !reflect.DeepEqual(got, want) {
+				t.Errorf("GetProof(%v) = %v, %v; want %v", variant, got, err, want)
+			}
+		}
+	}
+
+	// A tree whose own leaves are uppercase is found in lowercase too
+	upper := func(v HexString) HexString { return HexString("0X" + strings.ToUpper(string(v[2:]))) }
+	direct := &MerkleTreeImpl[HexString]{LeafHash: upper}
+	direct.Tree = []HexString{upper(standard.Tree[0]), upper(standard.Tree[1]), upper(standard.Tree[2])}
+	direct.Values = append(direct.Values, struct {
+		Value     HexString
+		TreeIndex int
+	}{standard.Tree[1], 1})
+	direct.configureIndexes(DefaultOptions)
+	if index, err := direct.getLeafIndex(standard.Tree[1].Normalize()); err != nil || index != 0 {
+		t.Errorf("Lookup of a lowercase leaf = %d, %v; want 0", index, err)
+	}
+}
+
+func TestHexStringNormalize(t *testing.T) {
+	for in, want := range map[HexString]HexString{
+		"0xABcd": "0xabcd",
+		"0XABCD": "0xabcd",
+		"0xabcd": "0xabcd",
+		"0x":     "0x",
+		"0X":     "0x",
+		"ABCD":   "abcd",
+	} {
+		if got := in.Normalize(); got != want {
+			t.Errorf("%q.Normalize() = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// BenchmarkGetProofTreeSize measures GetProof on trees of growing size: the
+// time per proof grows with the depth of the tree, not its number of nodes.
+func BenchmarkGetProofTreeSize(b *testing.B) {
+	for _, n := range []int{1 << 10, 1 << 14, 1 << 18} {
+		values := make([]string, n)
+		for i := range values {
+			values[i] = fmt.Sprintf("value-%d", i)
+		}
+		tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(false), Compatibility: CompatV0})
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(fmt.Sprintf("leaves=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := range b.N {
+				if _, err := tree.GetProof(i % n); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
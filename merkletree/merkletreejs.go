@@ -0,0 +1,179 @@
+package merkletree
+
+import (
+	"fmt"
+	"slices"
+)
+
+// jsLeafDigests are the digests MerkleTreeJSOptions.HashLeaves hashes leaves
+// with, by hash algorithm name.
+var jsLeafDigests = map[string]func(parts ...[]byte) []byte{
+	HashAlgorithmKeccak256: keccakSum,
+	HashAlgorithmSHA256:    sha256Sum,
+}
+
+// MerkleTreeJSOptions selects the options of the merkletreejs npm package
+// that NewMerkleTreeJS reproduces. Pairs are always sorted, as with its
+// sortPairs: true, so proofs carry no positions.
+type MerkleTreeJSOptions struct {
+	HashAlgorithm string // Named node hash, as in SimpleMerkleTreeOptions (optional, defaults to sha256)
+	HashLeaves    bool   // Hash each leaf first with the digest of HashAlgorithm, as hashLeaves: true; keccak256 and sha256 only
+	SortLeaves    bool   // Sort the leaves byte-wise first, as sortLeaves: true
+}
+
+// MerkleTreeJS is a Merkle tree laid out as the merkletreejs npm package lays
+// out trees. Unlike the trees of this package it is not a complete binary
+// tree: it is built layer by layer from the leaves in order, and the last
+// node of a layer with an odd count is carried up to the next layer as is.
+// For 2^n leaves the shape is that of a complete tree; for other counts only
+// this layout reproduces the roots merkletreejs computes.
+type MerkleTreeJS struct {
+	layers        [][]HexString // layers[0] are the leaves, the last layer the root
+	nodeHash      NodeHash
+	hashAlgorithm string
+}
+
+// NewMerkleTreeJS builds the tree merkletreejs builds from leaves with
+// { sortPairs: true } and the given options, such as
+// new MerkleTree(leaves, sha256, { sortPairs: true }) for pre-hashed leaves.
+// Without HashLeaves every leaf must be 32 bytes; a leaf that is not is
+// reported as an *InputError wrapping ErrInvalidNode.
+func NewMerkleTreeJS(leaves []BytesLike, options MerkleTreeJSOptions) (*MerkleTreeJS, error) {
+	if len(leaves) == 0 {
+		return nil, ErrEmptyTree
+	}
+	name := options.HashAlgorithm
+	if name == "" {
+		name = HashAlgorithmSHA256
+	}
+	named, ok := lookupNodeHash(name)
+	if !ok {
+		return nil, &OptionError{Option: "HashAlgorithm", Value: options.HashAlgorithm, Reason: "unknown hash algorithm"}
+	}
+	digest, ok := jsLeafDigests[name]
+	if options.HashLeaves && !ok {
+		return nil, &OptionError{Option: "HashLeaves", Value: true, Reason: fmt.Sprintf("no leaf digest for hash algorithm %q", name)}
+	}
+
+	layer := make([]HexString, len(leaves))
+	for i, leaf := range leaves {
+		b, err := ToBytes(leaf)
+		if err != nil {
+			return nil, &InputError{Index: i, Err: err}
+		}
+		if options.HashLeaves {
+			b = digest(b)
+		}
+		if len(b) != 32 {
+			return nil, &InputError{Index: i, Err: fmt.Errorf("%w: leaf is %d bytes", ErrInvalidNode, len(b))}
+		}
+		layer[i] = HexString(fmt.Sprintf("0x%x", b))
+	}
+	if options.SortLeaves {
+		// Lowercase hex strings of equal length sort as their bytes do
+		slices.Sort(layer)
+	}
+
+	t := &MerkleTreeJS{layers: [][]HexString{layer}, nodeHash: named.hash, hashAlgorithm: name}
+	for len(layer) > 1 {
+		next := make([]HexString, 0, (len(layer)+1)/2)
+		for i := 0; i < len(layer); i += 2 {
+			if i+1 == len(layer) {
+				next = append(next, layer[i])
+				continue
+			}
+			next = append(next, named.hash(layer[i], layer[i+1]))
+		}
+		t.layers = append(t.layers, next)
+		layer = next
+	}
+	return t, nil
+}
+
+// Root returns the root of the tree, merkletreejs's getHexRoot.
+func (t *MerkleTreeJS) Root() HexString {
+	return t.layers[len(t.layers)-1][0]
+}
+
+// Leaves returns the leaves in tree order, after any hashing and sorting.
+func (t *MerkleTreeJS) Leaves() []HexString {
+	return slices.Clone(t.layers[0])
+}
+
+// Layers returns the layers of the tree, leaves first and root last, as
+// merkletreejs's getHexLayers does.
+func (t *MerkleTreeJS) Layers() [][]HexString {
+	layers := make([][]HexString, len(t.layers))
+	for i, layer := range t.layers {
+		layers[i] = slices.Clone(layer)
+	}
+	return layers
+}
+
+// HashAlgorithm returns the name of the tree's node hash.
+func (t *MerkleTreeJS) HashAlgorithm() string {
+	return t.hashAlgorithm
+}
+
+// LeafIndex returns the position of leaf in tree order, or an error wrapping
+// ErrValueNotFound. With HashLeaves, leaf is the hashed leaf.
+func (t *MerkleTreeJS) LeafIndex(leaf BytesLike) (int, error) {
+	hex, err := ToHex(leaf)
+	if err != nil {
+		return 0, err
+	}
+	if i := slices.Index(t.layers[0], hex); i >= 0 {
+		return i, nil
+	}
+	return 0, fmt.Errorf("%w: leaf %s", ErrValueNotFound, hex)
+}
+
+// GetProof returns the proof of the leaf at index in tree order, the data of
+// merkletreejs's getHexProof. A carried-up node has no sibling in its layer,
+// so proofs of leaves near the end of the tree can be shorter than others.
+func (t *MerkleTreeJS) GetProof(index int) ([]HexString, error) {
+	if index < 0 || index >= len(t.layers[0]) {
+		return nil, fmt.Errorf("%w: leaf %d of %d", ErrInvalidIndex, index, len(t.layers[0]))
+	}
+	var proof []HexString
+	for _, layer := range t.layers[:len(t.layers)-1] {
+		if sibling := index ^ 1; sibling < len(layer) {
+			proof = append(proof, layer[sibling])
+		}
+		index /= 2
+	}
+	return proof, nil
+}
+
+// Verify reports whether proof proves leaf, a leaf as Leaves returns it,
+// against the tree's root.
+func (t *MerkleTreeJS) Verify(leaf BytesLike, proof []HexString) (bool, error) {
+	leafHex, err := ToHex(leaf)
+	if err != nil {
+		return false, err
+	}
+	nodes := make([]BytesLike, len(proof))
+	for i, node := range proof {
+		nodes[i] = node
+	}
+	return verifySimpleLeaf(t.Root(), leafHex, nodes, t.nodeHash)
+}
+
+// VerifyMerkleTreeJS reports whether proof proves leaf against root in a
+// merkletreejs tree with sorted pairs, whose nodes are hashed with the named
+// hash algorithm ("" for sha256). Leaf is the leaf as it is in the tree, so
+// for trees built with hashLeaves it is the hashed value.
+func VerifyMerkleTreeJS(root, leaf BytesLike, proof []BytesLike, hashAlgorithm string) (bool, error) {
+	if hashAlgorithm == "" {
+		hashAlgorithm = HashAlgorithmSHA256
+	}
+	named, ok := lookupNodeHash(hashAlgorithm)
+	if !ok {
+		return false, &OptionError{Option: "hashAlgorithm", Value: hashAlgorithm, Reason: "unknown hash algorithm"}
+	}
+	leafHex, err := ToHex(leaf)
+	if err != nil {
+		return false, err
+	}
+	return verifySimpleLeaf(root, leafHex, proof, named.hash)
+}
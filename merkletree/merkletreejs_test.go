@@ -0,0 +1,122 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+)
+
+// merkletreejsFixture is a tree of testdata/merkletreejs.json, written by
+// testdata/merkletreejs.mjs with new MerkleTree(leaves, sha256, options).
+type merkletreejsFixture struct {
+	Name    string `json:"name"`
+	Options struct {
+		HashLeaves bool `json:"hashLeaves"`
+		SortLeaves bool `json:"sortLeaves"`
+	} `json:"options"`
+	Leaves     []HexString   `json:"leaves"`
+	TreeLeaves []HexString   `json:"treeLeaves"`
+	Root       HexString     `json:"root"`
+	Proofs     [][]HexString `json:"proofs"`
+}
+
+func TestMerkleTreeJSFixtures(t *testing.T) {
+	data, err := os.ReadFile("testdata/merkletreejs.json")
+	if err != nil {
+		t.Fatalf("Failed to read fixtures: %v", err)
+	}
+	var fixtures []merkletreejsFixture
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		t.Fatalf("Failed to decode fixtures: %v", err)
+	}
+
+	for _, f := range fixtures {
+		tree, err := NewMerkleTreeJS(treeNodes(f.Leaves), MerkleTreeJSOptions{
+			HashLeaves: f.Options.HashLeaves,
+			SortLeaves: f.Options.SortLeaves,
+		})
+		if err != nil {
+			t.Fatalf("%s: Failed to create tree: %v", f.Name, err)
+		}
+		if tree.Root() != f.Root {
+			t.Errorf("%s: root %s, merkletreejs %s", f.Name, tree.Root(), f.Root)
+		}
+		if !slices.Equal(tree.Leaves(), f.TreeLeaves) {
+			t.Errorf("%s: leaves %v, merkletreejs %v", f.Name, tree.Leaves(), f.TreeLeaves)
+		}
+		for i, want := range f.Proofs {
+			proof, err := tree.GetProof(i)
+			if err != nil || !slices.Equal(proof, want) {
+				t.Errorf("%s: GetProof(%d) = %v, %v; merkletreejs %v", f.Name, i, proof, err, want)
+			}
+			if valid, err := VerifyMerkleTreeJS(f.Root, f.TreeLeaves[i], treeNodes(want), HashAlgorithmSHA256); err != nil || !valid {
+				t.Errorf("%s: merkletreejs proof %d does not verify: %v", f.Name, i, err)
+			}
+		}
+	}
+}
+
+func TestMerkleTreeJS(t *testing.T) {
+	leaves := treeNodes([]HexString{
+		"0x1111111111111111111111111111111111111111111111111111111111111111",
+		"0x2222222222222222222222222222222222222222222222222222222222222222",
+		"0x3333333333333333333333333333333333333333333333333333333333333333",
+	})
+	tree, err := NewMerkleTreeJS(leaves, MerkleTreeJSOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	// The third leaf is carried up, so its proof is the node above the first two
+	layers := tree.Layers()
+	if len(layers) != 3 || layers[1][1] != layers[0][2] {
+		t.Fatalf("Layers %v", layers)
+	}
+	if proof, _ := tree.GetProof(2); !slices.Equal(proof, []HexString{layers[1][0]}) {
+		t.Errorf("GetProof(2) = %v, want [%s]", proof, layers[1][0])
+	}
+	if root := SHA256NodeHash(layers[1][0], leaves[2]); tree.Root() != root {
+		t.Errorf("Root %s, want %s", tree.Root(), root)
+	}
+	for i, leaf := range tree.Leaves() {
+		proof, _ := tree.GetProof(i)
+		if valid, err := tree.Verify(leaf, proof); err != nil || !valid {
+			t.Errorf("Proof of leaf %d does not verify: %v", i, err)
+		}
+		if index, err := tree.LeafIndex(leaf); err != nil || index != i {
+			t.Errorf("LeafIndex(%s) = %d, %v; want %d", leaf, index, err, i)
+		}
+	}
+	if valid, _ := tree.Verify(tree.Leaves()[0], nil); valid {
+		t.Error("Expected an empty proof of leaf 0 to be rejected")
+	}
+
+	// With 2^n leaves the tree is complete, here hashed with keccak256
+	four := append(slices.Clone(leaves), HexString("0x4444444444444444444444444444444444444444444444444444444444444444"))
+	keccakTree, err := NewMerkleTreeJS(four, MerkleTreeJSOptions{HashAlgorithm: HashAlgorithmKeccak256})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if want := StandardNodeHash(StandardNodeHash(four[0], four[1]), StandardNodeHash(four[2], four[3])); keccakTree.Root() != want {
+		t.Errorf("keccak256 root %s, want %s", keccakTree.Root(), want)
+	}
+
+	if _, err := tree.GetProof(3); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("Expected ErrInvalidIndex, got %v", err)
+	}
+	if _, err := tree.LeafIndex(four[3]); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("Expected ErrValueNotFound, got %v", err)
+	}
+	if _, err := NewMerkleTreeJS(nil, MerkleTreeJSOptions{}); !errors.Is(err, ErrEmptyTree) {
+		t.Errorf("Expected ErrEmptyTree, got %v", err)
+	}
+	var inputErr *InputError
+	if _, err := NewMerkleTreeJS([]BytesLike{"0x1234"}, MerkleTreeJSOptions{}); !errors.As(err, &inputErr) || !errors.Is(err, ErrInvalidNode) {
+		t.Errorf("Expected a short leaf to be rejected, got %v", err)
+	}
+	if _, err := NewMerkleTreeJS(leaves, MerkleTreeJSOptions{HashAlgorithm: "md5"}); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("Expected an unknown hash to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestLeafMetadataFollowsSortedValues(t *testing.T) {
+	values := []string{"charlie", "alice", "bob", "dave"}
+	metadata := []json.RawMessage{
+		json.RawMessage(`{"campaign":"c"}`),
+		json.RawMessage(`{"campaign":"a"}`),
+		nil,
+		json.RawMessage(`{"campaign":"d"}`),
+	}
+
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(true), LeafMetadata: metadata})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	entries, err := tree.Entries()
+	if err != nil {
+		t.Fatalf("Failed to list entries: %v", err)
+	}
+	for _, entry := range entries {
+		if string(entry.Metadata) != string(metadata[entry.ValueIndex]) {
+			t.Errorf("Value %q has metadata %s, want %s", entry.Value, entry.Metadata, metadata[entry.ValueIndex])
+		}
+		if entry.LeafHash != StandardLeafHash(entry.Value) {
+			t.Errorf("Entry %d has leaf hash %s for value %q", entry.ValueIndex, entry.LeafHash, entry.Value)
+		}
+	}
+
+	claim, err := tree.ExportClaim("dave")
+	if err != nil {
+		t.Fatalf("Failed to export claim: %v", err)
+	}
+	if string(claim.Metadata) != `{"campaign":"d"}` {
+		t.Errorf("Claim has metadata %s", claim.Metadata)
+	}
+
+	// Metadata is not hashed
+	plain, _ := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(true)})
+	if plain.Root() != tree.Root() {
+		t.Error("Metadata should not change the root")
+	}
+}
+
+func TestLeafMetadataDumpLoadRoundTrip(t *testing.T) {
+	values := []BytesLike{"charlie", "alice", "bob"}
+	metadata := []json.RawMessage{
+		json.RawMessage(`{"vesting":"v3"}`),
+		json.RawMessage(`{"vesting":"v1"}`),
+		json.RawMessage(`{"vesting":"v2"}`),
+	}
+
+	tree, err := NewSimpleMerkleTree(values, SimpleMerkleTreeOptions{
+		MerkleTreeOptions: MerkleTreeOptions{SortLeaves: Bool(true), LeafMetadata: metadata},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	data, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("Failed to marshal dump: %v", err)
+	}
+	var dump SimpleMerkleTreeData
+	if err := json.Unmarshal(encoded, &dump); err != nil {
+		t.Fatalf("Failed to unmarshal dump: %v", err)
+	}
+
+	loaded, _, err := LoadSimpleMerkleTree(dump, nil)
+	if err != nil {
+		t.Fatalf("Failed to load dump: %v", err)
+	}
+	entries, err := loaded.Entries()
+	if err != nil {
+		t.Fatalf("Failed to list entries: %v", err)
+	}
+	for i, entry := range entries {
+		if entry.Value != values[i] || string(entry.Metadata) != string(metadata[i]) {
+			t.Errorf("Entry %d is %v with %s, want %v with %s", i, entry.Value, entry.Metadata, values[i], metadata[i])
+		}
+	}
+}
+
+func TestLeafMetadataErrors(t *testing.T) {
+	values := []string{"a", "b"}
+
+	_, err := NewStandardMerkleTree(values, MerkleTreeOptions{
+		LeafMetadata: []json.RawMessage{json.RawMessage(`{}`)},
+	})
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("Expected length mismatch to be rejected, got %v", err)
+	}
+
+	_, err = NewStandardMerkleTree(values, MerkleTreeOptions{
+		LeafMetadata: []json.RawMessage{json.RawMessage(`{}`), json.RawMessage(`{oops`)},
+	})
+	var optionErr *OptionError
+	if !errors.As(err, &optionErr) || optionErr.Option != "LeafMetadata[1]" {
+		t.Errorf("Expected invalid JSON to be reported for LeafMetadata[1], got %v", err)
+	}
+}
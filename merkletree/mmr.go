@@ -0,0 +1,304 @@
+package merkletree
+
+import (
+	"fmt"
+	"math/bits"
+	"slices"
+)
+
+// mmrFormat is the format identifier of MMR dumps.
+const mmrFormat = "mmr-v1"
+
+// MMROptions selects the node hash of an MMR, as SimpleMerkleTreeOptions
+// does for simple trees.
+type MMROptions struct {
+	NodeHash      NodeHash // Custom node hash function (optional)
+	HashAlgorithm string   // Named node hash, e.g. HashAlgorithmSHA256 or a RegisterNodeHash name (optional, defaults to keccak256)
+}
+
+// MMR is a Merkle Mountain Range: an append-only list of leaves kept as a
+// row of perfect binary trees, the peaks, of strictly decreasing height.
+// Nodes are numbered in the order they are added, each leaf followed by the
+// parents it completes, so a node keeps its position as the range grows and
+// an append hashes one node per tree it merges: O(log n) hashes.
+//
+// The root bags the peaks from the right: the last two are hashed together,
+// then each peak before them with the result. Leaves are 32-byte hashes the
+// caller computed, as for pre-hashed simple trees. With the sorted-pair node
+// hashes of this package a proof shows that a leaf is in the range but does
+// not fix its position; a node hash that keeps the order of its inputs does.
+type MMR struct {
+	nodes         []HexString // Every node, by position
+	leaves        int
+	nodeHash      NodeHash
+	hashAlgorithm string
+}
+
+// MMRProof proves that a leaf is at a position of an MMR of a given size.
+type MMRProof struct {
+	Position int         `json:"position"` // Position of the leaf among the nodes
+	Size     int         `json:"size"`     // Number of nodes of the MMR the proof is for
+	Siblings []HexString `json:"siblings"` // Path from the leaf up to its peak, nearest the leaf first
+	Peaks    []HexString `json:"peaks"`    // The other peaks, left to right
+}
+
+// MMRData is the exportable data of an MMR, in format "mmr-v1".
+type MMRData struct {
+	Format        string      `json:"format"`            // Format version identifier
+	HashAlgorithm string      `json:"hashAlgorithm"`     // Node hash name, or "custom"
+	LeafCount     int         `json:"leafCount"`         // Number of leaves
+	Nodes         []HexString `json:"nodes"`             // Every node, by position
+	Version       string      `json:"version,omitempty"` // Library version that wrote the dump
+}
+
+// NewMMR returns an empty MMR hashing nodes as options select.
+func NewMMR(options MMROptions) (*MMR, error) {
+	nodeHash, name, err := SimpleMerkleTreeOptions{NodeHash: options.NodeHash, HashAlgorithm: options.HashAlgorithm}.resolveNodeHash()
+	if err != nil {
+		return nil, err
+	}
+	return &MMR{nodeHash: nodeHash, hashAlgorithm: name}, nil
+}
+
+// Append adds leaf, a 32-byte hash, and returns its position. A leaf that is
+// not 32 bytes fails with an error wrapping ErrInvalidNode.
+func (m *MMR) Append(leaf BytesLike) (int, error) {
+	if err := CheckValidMerkleNode(leaf); err != nil {
+		return 0, fmt.Errorf("leaf %d: %w", m.leaves, err)
+	}
+	node, _ := ToHex(leaf)
+	position := len(m.nodes)
+	m.nodes = append(m.nodes, node)
+	m.leaves++
+
+	// The next position is a parent as long as it is higher than the last node
+	for h := 0; mmrHeight(len(m.nodes)) > h; h++ {
+		right := len(m.nodes) - 1
+		left := right - (2<<h - 1)
+		m.nodes = append(m.nodes, m.nodeHash(m.nodes[left], m.nodes[right]))
+	}
+	return position, nil
+}
+
+// Size returns the number of nodes.
+func (m *MMR) Size() int {
+	return len(m.nodes)
+}
+
+// LeafCount returns the number of leaves.
+func (m *MMR) LeafCount() int {
+	return m.leaves
+}
+
+// HashAlgorithm returns the name of the node hash, or "custom".
+func (m *MMR) HashAlgorithm() string {
+	return m.hashAlgorithm
+}
+
+// Peaks returns the peaks, left to right.
+func (m *MMR) Peaks() []HexString {
+	positions, _ := mmrPeaks(len(m.nodes))
+	peaks := make([]HexString, len(positions))
+	for i, p := range positions {
+		peaks[i] = m.nodes[p]
+	}
+	return peaks
+}
+
+// Root returns the bagged peaks, or "" while the MMR is empty.
+func (m *MMR) Root() HexString {
+	if len(m.nodes) == 0 {
+		return ""
+	}
+	return mmrBag(m.Peaks(), m.nodeHash)
+}
+
+// Proof returns the proof of the leaf at position against the current root.
+// A position out of range fails with an error wrapping ErrInvalidIndex, and
+// one of an inner node with ErrNotLeafNode.
+func (m *MMR) Proof(position int) (MMRProof, error) {
+	if position < 0 || position >= len(m.nodes) {
+		return MMRProof{}, fmt.Errorf("%w: position %d of %d", ErrInvalidIndex, position, len(m.nodes))
+	}
+	if mmrHeight(position) != 0 {
+		return MMRProof{}, fmt.Errorf("%w: position %d", ErrNotLeafNode, position)
+	}
+
+	proof := MMRProof{Position: position, Size: len(m.nodes), Siblings: []HexString{}, Peaks: []HexString{}}
+	peaks, _ := mmrPeaks(len(m.nodes))
+	for h := 0; !slices.Contains(peaks, position); h++ {
+		sibling, parent := mmrFamily(position, h)
+		proof.Siblings = append(proof.Siblings, m.nodes[sibling])
+		position = parent
+	}
+	for _, p := range peaks {
+		if p != position {
+			proof.Peaks = append(proof.Peaks, m.nodes[p])
+		}
+	}
+	return proof, nil
+}
+
+// Verify reports whether proof proves leaf against the current root.
+func (m *MMR) Verify(leaf BytesLike, proof MMRProof) (bool, error) {
+	return VerifyMMRProof(m.Root(), leaf, proof, m.nodeHash)
+}
+
+// VerifyMMRProof reports whether proof proves leaf at its position against
+// root, the root of an MMR of proof.Size nodes hashed with nodeHash
+// (StandardNodeHash if nil). It returns an error wrapping ErrInvalidProof if
+// the size is not that of an MMR, ErrInvalidIndex or ErrNotLeafNode if the
+// position is not a leaf of it, and ErrInvalidNode if a node is not 32 bytes.
+func VerifyMMRProof(root, leaf BytesLike, proof MMRProof, nodeHash NodeHash) (bool, error) {
+	if nodeHash == nil {
+		nodeHash = StandardNodeHash
+	}
+	peaks, ok := mmrPeaks(proof.Size)
+	switch {
+	case !ok || proof.Size == 0:
+		return false, fmt.Errorf("%w: %d nodes is not an MMR size", ErrInvalidProof, proof.Size)
+	case proof.Position < 0 || proof.Position >= proof.Size:
+		return false, fmt.Errorf("%w: position %d of %d", ErrInvalidIndex, proof.Position, proof.Size)
+	case mmrHeight(proof.Position) != 0:
+		return false, fmt.Errorf("%w: position %d", ErrNotLeafNode, proof.Position)
+	}
+	want, err := ToHex(root)
+	if err != nil {
+		return false, fmt.Errorf("invalid root: %w", err)
+	}
+	if err := CheckValidMerkleNode(leaf); err != nil {
+		return false, fmt.Errorf("invalid leaf: %w", err)
+	}
+	node, _ := ToHex(leaf)
+
+	position := proof.Position
+	for h, s := range proof.Siblings {
+		if slices.Contains(peaks, position) {
+			return false, nil // The proof goes past the peak
+		}
+		if err := CheckValidMerkleNode(s); err != nil {
+			return false, fmt.Errorf("invalid sibling %d: %w", h, err)
+		}
+		sibling, parent := mmrFamily(position, h)
+		if sibling < position {
+			node = nodeHash(s, node)
+		} else {
+			node = nodeHash(node, s)
+		}
+		position = parent
+	}
+	k := slices.Index(peaks, position)
+	if k < 0 || len(proof.Peaks) != len(peaks)-1 {
+		return false, nil // The proof stops short of the peak, or has the wrong peaks
+	}
+	for i, p := range proof.Peaks {
+		if err := CheckValidMerkleNode(p); err != nil {
+			return false, fmt.Errorf("invalid peak %d: %w", i, err)
+		}
+	}
+	return mmrBag(slices.Insert(slices.Clone(proof.Peaks), k, node), nodeHash).Normalize() == want.Normalize(), nil
+}
+
+// Dump returns the data of the MMR, for LoadMMR.
+func (m *MMR) Dump() MMRData {
+	return MMRData{
+		Format:        mmrFormat,
+		HashAlgorithm: m.hashAlgorithm,
+		LeafCount:     m.leaves,
+		Nodes:         slices.Clone(m.nodes),
+		Version:       version,
+	}
+}
+
+// LoadMMR restores an MMR from a dump, checking that every node recomputes
+// from the leaves. A named node hash is looked up as for simple trees;
+// nodeHash is required for, and only used by, MMRs with a custom node hash.
+// A dump in another format fails with a *FormatError, and anything that
+// does not add up with ErrInvalidDump.
+func LoadMMR(data MMRData, nodeHash NodeHash) (*MMR, error) {
+	if data.Format != mmrFormat {
+		return nil, &FormatError{Format: data.Format}
+	}
+	options := MMROptions{HashAlgorithm: data.HashAlgorithm}
+	if data.HashAlgorithm == HashCustom {
+		if nodeHash == nil {
+			return nil, fmt.Errorf("%w: the MMR was built with a custom node hash, which must be given", ErrInvalidDump)
+		}
+		options = MMROptions{NodeHash: nodeHash}
+	}
+	m, err := NewMMR(options)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDump, err)
+	}
+
+	if _, ok := mmrPeaks(len(data.Nodes)); !ok || mmrSize(data.LeafCount) != len(data.Nodes) {
+		return nil, fmt.Errorf("%w: %d nodes do not make an MMR of %d leaves", ErrInvalidDump, len(data.Nodes), data.LeafCount)
+	}
+	for i, node := range data.Nodes {
+		if mmrHeight(i) != 0 {
+			continue
+		}
+		if _, err := m.Append(node); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDump, err)
+		}
+	}
+	for i, node := range data.Nodes {
+		if m.nodes[i] != node.Normalize() {
+			return nil, fmt.Errorf("%w: node %d does not recompute", ErrInvalidDump, i)
+		}
+	}
+	return m, nil
+}
+
+// mmrHeight returns the height of the node at position, 0 for leaves.
+func mmrHeight(position int) int {
+	// Numbered from 1, the leftmost node of each height is all ones; jumping
+	// left by the size of the preceding subtree leads to it
+	p := uint64(position) + 1
+	for p&(p+1) != 0 {
+		p -= 1<<(bits.Len64(p)-1) - 1
+	}
+	return bits.Len64(p) - 1
+}
+
+// mmrFamily returns the positions of the sibling and parent of the node at
+// position, of height h.
+func mmrFamily(position, h int) (sibling, parent int) {
+	offset := 2<<h - 1 // Size of a subtree of height h
+	if mmrHeight(position+1) > h {
+		return position - offset, position + 1 // A right child, followed by its parent
+	}
+	return position + offset, position + offset + 1
+}
+
+// mmrPeaks returns the positions of the peaks of an MMR of size nodes, left
+// to right, or false if no MMR has that many nodes.
+func mmrPeaks(size int) ([]int, bool) {
+	var peaks []int
+	offset := 0
+	for h := bits.Len(uint(size)); h >= 0; h-- {
+		// Smaller peaks add up to less than one of height h, so the highest that fits is a peak
+		if n := 2<<h - 1; size-offset >= n {
+			offset += n
+			peaks = append(peaks, offset-1)
+		}
+	}
+	return peaks, offset == size
+}
+
+// mmrSize returns the number of nodes of an MMR of n leaves.
+func mmrSize(n int) int {
+	if n < 0 {
+		return -1
+	}
+	return 2*n - bits.OnesCount(uint(n))
+}
+
+// mmrBag hashes the peaks into the root, from the right.
+func mmrBag(peaks []HexString, nodeHash NodeHash) HexString {
+	root := peaks[len(peaks)-1]
+	for i := len(peaks) - 2; i >= 0; i-- {
+		root = nodeHash(peaks[i], root)
+	}
+	return root
+}
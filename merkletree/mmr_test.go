@@ -0,0 +1,227 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// mmrLeaf returns the i-th test leaf.
+func mmrLeaf(i int) HexString {
+	return HexString(fmt.Sprintf("0x%x", keccakSum([]byte(fmt.Sprintf("event-%d", i)))))
+}
+
+func newTestMMR(t *testing.T, n int, options MMROptions) (*MMR, []int) {
+	t.Helper()
+	m, err := NewMMR(options)
+	if err != nil {
+		t.Fatalf("Failed to create MMR: %v", err)
+	}
+	positions := make([]int, n)
+	for i := range positions {
+		if positions[i], err = m.Append(mmrLeaf(i)); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	return m, positions
+}
+
+func TestMMRShape(t *testing.T) {
+	// Positions of the first leaves, and the sizes after each append
+	m, positions := newTestMMR(t, 11, MMROptions{})
+	wantPositions := []int{0, 1, 3, 4, 7, 8, 10, 11, 15, 16, 18}
+	for i, want := range wantPositions {
+		if positions[i] != want {
+			t.Errorf("Leaf %d at position %d, want %d", i, positions[i], want)
+		}
+	}
+	if m.Size() != 19 || m.LeafCount() != 11 || len(m.Peaks()) != 3 {
+		t.Errorf("MMR of 11 leaves has %d nodes and %d peaks, want 19 and 3", m.Size(), len(m.Peaks()))
+	}
+
+	// Three leaves: a tree of two and a single peak
+	small, _ := newTestMMR(t, 3, MMROptions{})
+	pair := StandardNodeHash(mmrLeaf(0), mmrLeaf(1))
+	if want := StandardNodeHash(pair, mmrLeaf(2)); small.Root() != want {
+		t.Errorf("Root of 3 leaves = %s, want %s", small.Root(), want)
+	}
+	for n := range 100 {
+		if peaks, ok := mmrPeaks(mmrSize(n)); !ok || len(peaks) != popCount(n) {
+			t.Errorf("Size of %d leaves: peaks %v, %v", n, peaks, ok)
+		}
+	}
+	if _, ok := mmrPeaks(2); ok {
+		t.Error("2 nodes should not be an MMR size")
+	}
+}
+
+// popCount returns the number of set bits of n.
+func popCount(n int) int {
+	count := 0
+	for ; n > 0; n &= n - 1 {
+		count++
+	}
+	return count
+}
+
+func TestMMRProofs(t *testing.T) {
+	m, positions := newTestMMR(t, 3000, MMROptions{})
+	r := rand.New(rand.NewSource(1))
+	for range 200 {
+		i := r.Intn(len(positions))
+		proof, err := m.Proof(positions[i])
+		if err != nil {
+			t.Fatalf("Proof(%d) failed: %v", positions[i], err)
+		}
+		if ok, err := VerifyMMRProof(m.Root(), mmrLeaf(i), proof, nil); err != nil || !ok {
+			t.Fatalf("Proof of leaf %d does not verify: %v", i, err)
+		}
+		if ok, _ := m.Verify(mmrLeaf(i+1), proof); ok {
+			t.Fatalf("Proof of leaf %d verifies another leaf", i)
+		}
+	}
+
+	// Older proofs keep verifying against the root they were made for
+	oldRoot := m.Root()
+	old, _ := m.Proof(positions[1234])
+	for i := 3000; i < 3500; i++ {
+		if _, err := m.Append(mmrLeaf(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ok, _ := VerifyMMRProof(oldRoot, mmrLeaf(1234), old, nil); !ok {
+		t.Error("A proof should still verify against the root it was made for")
+	}
+	if ok, _ := m.Verify(mmrLeaf(1234), old); ok {
+		t.Error("A proof for a smaller MMR should not verify against the new root")
+	}
+	for range 100 {
+		i := r.Intn(3500)
+		position := mmrSize(i)
+		proof, err := m.Proof(position)
+		if err != nil {
+			t.Fatalf("Proof(%d) failed: %v", position, err)
+		}
+		if ok, err := m.Verify(mmrLeaf(i), proof); err != nil || !ok {
+			t.Fatalf("Proof of leaf %d after appending does not verify: %v", i, err)
+		}
+	}
+}
+
+func TestMMRProofJSON(t *testing.T) {
+	// One and four leaves make a single peak, so there are no other peaks
+	for _, n := range []int{1, 4} {
+		m, positions := newTestMMR(t, n, MMROptions{})
+		proof, err := m.Proof(positions[0])
+		if err != nil {
+			t.Fatalf("Proof failed: %v", err)
+		}
+		data, err := json.Marshal(proof)
+		if err != nil {
+			t.Fatalf("Failed to marshal proof: %v", err)
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			t.Fatalf("Failed to decode proof: %v", err)
+		}
+		if string(fields["peaks"]) != "[]" {
+			t.Errorf("%d leaves: peaks = %s, want []", n, fields["peaks"])
+		}
+		if n == 1 && string(fields["siblings"]) != "[]" {
+			t.Errorf("%d leaves: siblings = %s, want []", n, fields["siblings"])
+		}
+	}
+}
+
+func TestMMRProofErrors(t *testing.T) {
+	m, _ := newTestMMR(t, 11, MMROptions{HashAlgorithm: HashAlgorithmSHA256})
+	if _, err := m.Proof(2); !errors.Is(err, ErrNotLeafNode) {
+		t.Errorf("Expected ErrNotLeafNode, got %v", err)
+	}
+	if _, err := m.Proof(19); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("Expected ErrInvalidIndex, got %v", err)
+	}
+	if _, err := m.Append("0x1234"); !errors.Is(err, ErrInvalidNode) {
+		t.Errorf("Expected ErrInvalidNode, got %v", err)
+	}
+
+	proof, _ := m.Proof(8)
+	if ok, _ := m.Verify(mmrLeaf(5), proof); !ok {
+		t.Fatal("Proof does not verify")
+	}
+	if ok, _ := VerifyMMRProof(m.Root(), mmrLeaf(5), proof, nil); ok {
+		t.Error("Proof verifies with another node hash")
+	}
+	for name, change := range map[string]func(p *MMRProof){
+		"short":      func(p *MMRProof) { p.Siblings = p.Siblings[:1] },
+		"long":       func(p *MMRProof) { p.Siblings = append(p.Siblings, p.Peaks[0]) },
+		"extra peak": func(p *MMRProof) { p.Peaks = append(p.Peaks, p.Peaks[0]) },
+		"other size": func(p *MMRProof) { p.Size = 22 },
+		"wrong peak": func(p *MMRProof) { p.Peaks[0] = p.Siblings[0] },
+	} {
+		tampered := proof
+		tampered.Siblings = append([]HexString(nil), proof.Siblings...)
+		tampered.Peaks = append([]HexString(nil), proof.Peaks...)
+		change(&tampered)
+		if ok, _ := m.Verify(mmrLeaf(5), tampered); ok {
+			t.Errorf("%s: tampered proof verifies", name)
+		}
+	}
+	if _, err := m.Verify(mmrLeaf(5), MMRProof{Position: 8, Size: 20}); !errors.Is(err, ErrInvalidProof) {
+		t.Errorf("Expected ErrInvalidProof for a size that is not an MMR's, got %v", err)
+	}
+}
+
+func TestMMRDump(t *testing.T) {
+	m, _ := newTestMMR(t, 100, MMROptions{})
+	encoded, err := json.Marshal(m.Dump())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data MMRData
+	if err := json.Unmarshal(encoded, &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Format != "mmr-v1" || data.HashAlgorithm != HashAlgorithmKeccak256 {
+		t.Errorf("Dump has format %q and hash %q", data.Format, data.HashAlgorithm)
+	}
+	loaded, err := LoadMMR(data, nil)
+	if err != nil {
+		t.Fatalf("LoadMMR failed: %v", err)
+	}
+	if loaded.Root() != m.Root() || loaded.LeafCount() != 100 {
+		t.Fatalf("Loaded root %s, want %s", loaded.Root(), m.Root())
+	}
+	if position, err := loaded.Append(mmrLeaf(100)); err != nil || position != mmrSize(100) {
+		t.Errorf("Append after load = %d, %v; want %d", position, err, mmrSize(100))
+	}
+
+	bad := m.Dump()
+	bad.Nodes[5] = bad.Nodes[6]
+	if _, err := LoadMMR(bad, nil); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected ErrInvalidDump for a node that does not recompute, got %v", err)
+	}
+	bad = m.Dump()
+	bad.Nodes = bad.Nodes[:len(bad.Nodes)-1]
+	if _, err := LoadMMR(bad, nil); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected ErrInvalidDump for a truncated dump, got %v", err)
+	}
+	bad = m.Dump()
+	bad.Format = "mmr-v2"
+	var formatErr *FormatError
+	if _, err := LoadMMR(bad, nil); !errors.As(err, &formatErr) {
+		t.Errorf("Expected a FormatError, got %v", err)
+	}
+
+	custom, _ := newTestMMR(t, 5, MMROptions{NodeHash: SHA256NodeHash})
+	data = custom.Dump()
+	data.HashAlgorithm = HashCustom
+	if _, err := LoadMMR(data, nil); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected ErrInvalidDump without the custom node hash, got %v", err)
+	}
+	if loaded, err := LoadMMR(data, SHA256NodeHash); err != nil || loaded.Root() != custom.Root() {
+		t.Errorf("LoadMMR with the custom node hash = %v", err)
+	}
+}
@@ -0,0 +1,309 @@
+package merkletree
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// multiProofFormat is the format identifier of the JSON form of a multi-proof.
+const multiProofFormat = "multiproof-v1"
+
+// multiProofJSON is the JSON form of a MultiProof.
+type multiProofJSON struct {
+	Format     string      `json:"format"`
+	Leaves     []HexString `json:"leaves"`
+	Proof      []HexString `json:"proof"`
+	ProofFlags []bool      `json:"proofFlags"`
+}
+
+// MarshalJSON encodes the multi-proof as an object with a "format" of
+// "multiproof-v1" and the leaves, proof nodes and flags under "leaves",
+// "proof" and "proofFlags", in the order ProcessMultiProof consumes them.
+// Empty lists are written as [] rather than null.
+func (m MultiProof) MarshalJSON() ([]byte, error) {
+	data := multiProofJSON{Format: multiProofFormat, Leaves: m.Leaves, Proof: m.Proof, ProofFlags: m.ProofFlags}
+	if data.Leaves == nil {
+		data.Leaves = []HexString{}
+	}
+	if data.Proof == nil {
+		data.Proof = []HexString{}
+	}
+	if data.ProofFlags == nil {
+		data.ProofFlags = []bool{}
+	}
+	return json.Marshal(data)
+}
+
+// UnmarshalJSON decodes the form MarshalJSON writes and checks it, so that
+// the result can be passed to ProcessMultiProof as is: every leaf and proof
+// node must be 32 bytes, and there must be one flag fewer than leaves and
+// proof nodes together. A missing format is read as "multiproof-v1". An
+// unknown format or a multi-proof that fails these checks returns an error
+// wrapping ErrInvalidMultiProof.
+func (m *MultiProof) UnmarshalJSON(data []byte) error {
+	var decoded multiProofJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMultiProof, err)
+	}
+	if decoded.Format != "" && decoded.Format != multiProofFormat {
+		return fmt.Errorf("%w: unsupported format %q", ErrInvalidMultiProof, decoded.Format)
+	}
+	for i, leaf := range decoded.Leaves {
+		if !IsValidMerkleNode(leaf) {
+			return fmt.Errorf("%w: leaf %d is not a 32-byte node", ErrInvalidMultiProof, i)
+		}
+	}
+	for i, node := range decoded.Proof {
+		if !IsValidMerkleNode(node) {
+			return fmt.Errorf("%w: proof node %d is not a 32-byte node", ErrInvalidMultiProof, i)
+		}
+	}
+	if want := len(decoded.Leaves) + len(decoded.Proof) - 1; len(decoded.ProofFlags) != want {
+		return fmt.Errorf("%w: %d leaves and %d proof nodes need %d flags, got %d",
+			ErrInvalidMultiProof, len(decoded.Leaves), len(decoded.Proof), want, len(decoded.ProofFlags))
+	}
+	*m = MultiProof{Leaves: decoded.Leaves, Proof: decoded.Proof, ProofFlags: decoded.ProofFlags}
+	return nil
+}
+
+// multiProofMagic starts every CanonicalBytes encoding; the last byte is the
+// encoding version.
+var multiProofMagic = []byte{'G', 'M', 'M', 'P', 1}
+
+// CanonicalBytes returns a deterministic binary encoding of the multi-proof,
+// suitable as a cache key or for content addressing. Because GetMultiProof
+// orders its output by tree position, proofs for the same set of leaves of the
+// same tree encode to the same bytes whatever order the indices were given in.
+//
+// The layout is the 5-byte header "GMMP" followed by version 1, then the
+// number of leaves, proof nodes and flags as big-endian uint32s, the leaves
+// and proof nodes as 32 bytes each, and the flags packed eight per byte with
+// the first flag in the most significant bit.
+// Returns an error if a leaf or proof node is not a 32-byte hex string.
+func (m MultiProof) CanonicalBytes() ([]byte, error) {
+	size := len(multiProofMagic) + 12 + 32*(len(m.Leaves)+len(m.Proof)) + (len(m.ProofFlags)+7)/8
+	out := make([]byte, 0, size)
+	out = append(out, multiProofMagic...)
+	out = binary.BigEndian.AppendUint32(out, uint32(len(m.Leaves)))
+	out = binary.BigEndian.AppendUint32(out, uint32(len(m.Proof)))
+	out = binary.BigEndian.AppendUint32(out, uint32(len(m.ProofFlags)))
+
+	var err error
+	if out, err = appendNodes(out, "leaf", m.Leaves); err != nil {
+		return nil, err
+	}
+	if out, err = appendNodes(out, "proof node", m.Proof); err != nil {
+		return nil, err
+	}
+
+	flags := make([]byte, (len(m.ProofFlags)+7)/8)
+	for i, f := range m.ProofFlags {
+		if f {
+			flags[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return append(out, flags...), nil
+}
+
+// appendNodes appends each 32-byte node to out.
+func appendNodes(out []byte, kind string, nodes []HexString) ([]byte, error) {
+	for i, n := range nodes {
+		b, err := ToBytes(n)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s %d: %v", ErrInvalidMultiProof, kind, i, err)
+		}
+		if len(b) != 32 {
+			return nil, fmt.Errorf("%w: %s %d is %d bytes, want 32", ErrInvalidMultiProof, kind, i, len(b))
+		}
+		out = append(out, b...)
+	}
+	return out, nil
+}
+
+// ToCalldataArgs returns the proof, flags and leaves of the multi-proof as
+// the proof, proofFlags and leaves arguments of OpenZeppelin's
+// MerkleProof.multiProofVerify, which takes the leaves in the order the flags
+// consume them. That is the order GetMultiProof returns, descending tree
+// position; use SortForSolidity first if Leaves were reordered. Nodes are
+// normalized to lowercase hex, and nil slices are returned empty.
+func (m MultiProof) ToCalldataArgs() (proof []HexString, flags []bool, leaves []HexString) {
+	proof = make([]HexString, len(m.Proof))
+	for i, node := range m.Proof {
+		proof[i] = node.Normalize()
+	}
+	leaves = make([]HexString, len(m.Leaves))
+	for i, leaf := range m.Leaves {
+		leaves[i] = leaf.Normalize()
+	}
+	return proof, append([]bool{}, m.ProofFlags...), leaves
+}
+
+// SortForSolidity returns the multi-proof of the same leaves of tree with
+// the leaves in the order multiProofVerify expects and the flags and proof
+// recomputed to match, for multi-proofs whose Leaves were reordered after
+// GetMultiProof or assembled by hand. Each leaf is located in tree by hash;
+// a leaf repeated in Leaves must occur as often among the leaves of tree.
+// A leaf that is not in tree fails with ErrInvalidMultiProof.
+func (m MultiProof) SortForSolidity(tree []BytesLike) (MultiProof, error) {
+	if len(m.Leaves) == 0 {
+		return MultiProof{}, fmt.Errorf("%w: no leaves", ErrInvalidMultiProof)
+	}
+	positions := make(map[HexString][]int)
+	for i := len(tree) - 1; IsLeafNode(tree, i); i-- {
+		hash, err := ToHex(tree[i])
+		if err != nil {
+			return MultiProof{}, fmt.Errorf("invalid tree node at index %d: %w", i, err)
+		}
+		positions[hash] = append(positions[hash], i)
+	}
+
+	indices := make([]int, len(m.Leaves))
+	for i, leaf := range m.Leaves {
+		key := leaf.Normalize()
+		if len(positions[key]) == 0 {
+			return MultiProof{}, fmt.Errorf("%w: leaf %d (%s) is not a leaf of the tree", ErrInvalidMultiProof, i, leaf)
+		}
+		indices[i] = positions[key][0]
+		positions[key] = positions[key][1:]
+	}
+	return GetMultiProof(tree, indices)
+}
+
+// expandItem is a node on the ExpandMultiProof stack with the positions in
+// MultiProof.Leaves of the leaves below it.
+type expandItem struct {
+	hash   HexString
+	leaves []int
+}
+
+// ExpandMultiProof turns a multi-proof into one single proof per leaf, keyed
+// by leaf hash, for consumers that only verify single proofs. It replays the
+// flags as ProcessMultiProof does, and every node combined with another
+// becomes the next sibling of each leaf below the other. A leaf hash included
+// more than once keeps the proof of its first occurrence.
+//
+// Each proof is checked with ProcessProof against the root the multi-proof
+// computes, so nodeHash must not depend on the order of its arguments, as
+// StandardNodeHash does not. A multi-proof that does not process fails with
+// ErrInvalidMultiProof, as does one whose expanded proofs do not verify.
+func ExpandMultiProof(mp MultiProof, nodeHash NodeHash) (map[HexString][]HexString, error) {
+	if len(mp.Leaves) == 0 {
+		return nil, fmt.Errorf("%w: no leaves", ErrInvalidMultiProof)
+	}
+	for i, leaf := range mp.Leaves {
+		if !IsValidMerkleNode(leaf) {
+			return nil, fmt.Errorf("%w: leaf %d is not a 32-byte node", ErrInvalidMultiProof, i)
+		}
+	}
+	for i, node := range mp.Proof {
+		if !IsValidMerkleNode(node) {
+			return nil, fmt.Errorf("%w: proof node %d is not a 32-byte node", ErrInvalidMultiProof, i)
+		}
+	}
+
+	paths := make([][]HexString, len(mp.Leaves))
+	stack := make([]expandItem, len(mp.Leaves))
+	for i, leaf := range mp.Leaves {
+		stack[i] = expandItem{hash: leaf, leaves: []int{i}}
+	}
+	proof := mp.Proof
+
+	for step, flag := range mp.ProofFlags {
+		if (flag && len(stack) < 2) || (!flag && len(proof) < 1) {
+			return nil, fmt.Errorf("%w: flag %d has nothing to combine", ErrInvalidMultiProof, step)
+		}
+		a := stack[0]
+		stack = stack[1:]
+
+		var b expandItem
+		if flag {
+			b = stack[0]
+			stack = stack[1:]
+		} else {
+			b = expandItem{hash: proof[0]}
+			proof = proof[1:]
+		}
+
+		for _, leaf := range a.leaves {
+			paths[leaf] = append(paths[leaf], b.hash)
+		}
+		for _, leaf := range b.leaves {
+			paths[leaf] = append(paths[leaf], a.hash)
+		}
+		stack = append(stack, expandItem{hash: nodeHash(a.hash, b.hash), leaves: append(a.leaves, b.leaves...)})
+	}
+
+	// The stack never empties, so the root is on it and every proof node used
+	if len(stack)+len(proof) != 1 {
+		return nil, fmt.Errorf("%w: %d nodes left after the last flag, want 1", ErrInvalidMultiProof, len(stack)+len(proof))
+	}
+	root := stack[0].hash
+
+	proofs := make(map[HexString][]HexString, len(mp.Leaves))
+	for i, leaf := range mp.Leaves {
+		key := leaf.Normalize()
+		if _, seen := proofs[key]; seen {
+			continue
+		}
+		computed, err := ProcessProof(leaf, bytesLikeNodes(paths[i]), nodeHash)
+		if err != nil {
+			return nil, fmt.Errorf("%w: leaf %d: %v", ErrInvalidMultiProof, i, err)
+		}
+		if !strings.EqualFold(string(computed), string(root)) {
+			return nil, fmt.Errorf("%w: expanded proof of leaf %d gives root %s, want %s", ErrInvalidMultiProof, i, computed, root)
+		}
+		proofs[key] = paths[i]
+	}
+	return proofs, nil
+}
+
+// GetMultiProof returns the multi-proof of the given leaves, each a value
+// index or a value of type T as for GetProof, in any order. The leaves are looked up and validated like GetProof's and mapped
+// to their tree indices, which the package-level GetMultiProof orders as
+// ProcessMultiProof expects. An unknown value fails with ErrValueNotFound, a
+// leaf given twice with ErrInvalidIndex, and no leaves at all with
+// ErrEmptyTree.
+func (m *MerkleTreeImpl[T]) GetMultiProof(leaves ...any) (MultiProof, error) {
+	if len(leaves) == 0 {
+		return MultiProof{}, ErrEmptyTree
+	}
+	indices := make([]int, len(leaves))
+	for i, leaf := range leaves {
+		valueIndex, err := m.getLeafIndex(leaf)
+		if err != nil {
+			return MultiProof{}, fmt.Errorf("leaf %d: %w", i, err)
+		}
+		if err := m.validateValueAt(valueIndex); err != nil {
+			return MultiProof{}, fmt.Errorf("validation failed: %w", err)
+		}
+		indices[i] = m.Values[valueIndex].TreeIndex
+	}
+	return GetMultiProof(bytesLikeNodes(m.Tree), indices)
+}
+
+// CombineToMultiProof returns the multi-proof of the leaves with the given
+// hashes, in any order and case, for callers that hold leaf hashes rather
+// than values or indices. A hash that is not a leaf of the tree fails with
+// ErrValueNotFound, and one given twice with ErrInvalidIndex.
+func (m *MerkleTreeImpl[T]) CombineToMultiProof(leafHashes []HexString) (MultiProof, error) {
+	indices := make([]int, len(leafHashes))
+	for i, hash := range leafHashes {
+		valueIndex, found := m.hashLookup()[hash.Normalize()]
+		if !found {
+			return MultiProof{}, fmt.Errorf("%w: leaf hash %s", ErrValueNotFound, hash)
+		}
+		indices[i] = m.Values[valueIndex].TreeIndex
+	}
+	return GetMultiProof(bytesLikeNodes(m.Tree), indices)
+}
+
+// bytesLikeNodes returns the nodes as BytesLike, for the functions taking a tree or proof.
+func bytesLikeNodes(nodes []HexString) []BytesLike {
+	out := make([]BytesLike, len(nodes))
+	for i, n := range nodes {
+		out[i] = n
+	}
+	return out
+}
@@ -0,0 +1,543 @@
+package merkletree
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMultiProofCanonicalUnderPermutation(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1965, 1965))
+
+	for trial := 0; trial < 200; trial++ {
+		values := make([]string, 1+rng.IntN(40))
+		for i := range values {
+			values[i] = fmt.Sprintf("value-%d-%d", trial, i)
+		}
+		tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(rng.IntN(2) == 0)})
+		if err != nil {
+			t.Fatalf("Failed to create tree: %v", err)
+		}
+
+		var indices []int
+		for _, i := range rng.Perm(len(values))[:1+rng.IntN(len(values))] {
+			indices = append(indices, tree.Values[i].TreeIndex)
+		}
+
+		var want []byte
+		for perm := 0; perm < 5; perm++ {
+			rng.Shuffle(len(indices), func(i, j int) { indices[i], indices[j] = indices[j], indices[i] })
+
+			multiproof, err := GetMultiProof(treeNodes(tree.Tree), indices)
+			if err != nil {
+				t.Fatalf("Trial %d: failed to get multiproof for %v: %v", trial, indices, err)
+			}
+			root, err := ProcessMultiProof(multiproof, StandardNodeHash)
+			if err != nil || root != tree.Root() {
+				t.Fatalf("Trial %d: multiproof for %v gives root %s (err %v), want %s", trial, indices, root, err, tree.Root())
+			}
+
+			got, err := multiproof.CanonicalBytes()
+			if err != nil {
+				t.Fatalf("Trial %d: CanonicalBytes failed: %v", trial, err)
+			}
+			if want == nil {
+				want = got
+			} else if !bytes.Equal(got, want) {
+				t.Fatalf("Trial %d: CanonicalBytes differs for permutation %v", trial, indices)
+			}
+		}
+	}
+}
+
+func TestGetMultiProofRejectsDuplicateIndices(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"a", "b", "c", "d", "e"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	nodes := treeNodes(tree.Tree)
+	i, j := tree.Values[0].TreeIndex, tree.Values[3].TreeIndex
+
+	for _, tc := range []struct {
+		indices   []int
+		duplicate int
+	}{
+		{[]int{i, i}, i},
+		{[]int{j, i, j}, j},
+		{[]int{i, j, i, i}, i},
+	} {
+		_, err := GetMultiProof(nodes, tc.indices)
+		if !errors.Is(err, ErrInvalidIndex) || !strings.Contains(err.Error(), fmt.Sprintf("duplicate index %d", tc.duplicate)) {
+			t.Errorf("GetMultiProof(%v): expected ErrInvalidIndex for duplicate index %d, got %v", tc.indices, tc.duplicate, err)
+		}
+	}
+	if _, err := tree.GetMultiProof("a", 0); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("Expected ErrInvalidIndex for a value and its index, got %v", err)
+	}
+	if _, err := tree.CombineToMultiProof([]HexString{tree.Tree[i], tree.Tree[i]}); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("Expected ErrInvalidIndex for a repeated leaf hash, got %v", err)
+	}
+}
+
+func TestGetMultiProofLeafOrder(t *testing.T) {
+	tree, err := NewStandardMerkleTree(appendValues(13), MerkleTreeOptions{SortLeaves: Bool(true)})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	requested := []int{tree.Values[4].TreeIndex, tree.Values[11].TreeIndex, tree.Values[0].TreeIndex, tree.Values[7].TreeIndex}
+	multiproof, err := GetMultiProof(treeNodes(tree.Tree), requested)
+	if err != nil {
+		t.Fatalf("Failed to get multiproof: %v", err)
+	}
+
+	// The documented contract: sort descending, match by position
+	order := slices.Clone(requested)
+	slices.Sort(order)
+	slices.Reverse(order)
+	if len(multiproof.Leaves) != len(order) {
+		t.Fatalf("Got %d leaves for %d indices", len(multiproof.Leaves), len(order))
+	}
+	for k, index := range order {
+		if multiproof.Leaves[k] != tree.Tree[index] {
+			t.Errorf("Leaves[%d] = %s, want the leaf at tree index %d", k, multiproof.Leaves[k], index)
+		}
+	}
+	if root, err := ProcessMultiProof(multiproof, StandardNodeHash); err != nil || root != tree.Root() {
+		t.Errorf("Multiproof gives root %s (err %v), want %s", root, err, tree.Root())
+	}
+}
+
+func TestGetMultiProofRejectsNonLeaf(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"a", "b", "c"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	for _, index := range []int{0, -1, len(tree.Tree)} {
+		if _, err := GetMultiProof(treeNodes(tree.Tree), []int{index}); !errors.Is(err, ErrNotLeafNode) {
+			t.Errorf("Index %d: expected ErrNotLeafNode, got %v", index, err)
+		}
+	}
+}
+
+func TestMultiProofCanonicalBytes(t *testing.T) {
+	node := HexString("0x" + fmt.Sprintf("%064x", 1))
+	multiproof := MultiProof{
+		Leaves:     []HexString{node},
+		Proof:      []HexString{node, node},
+		ProofFlags: []bool{true, false, false, false, false, false, false, false, true},
+	}
+
+	got, err := multiproof.CanonicalBytes()
+	if err != nil {
+		t.Fatalf("CanonicalBytes failed: %v", err)
+	}
+	if want := 5 + 12 + 3*32 + 2; len(got) != want {
+		t.Fatalf("Encoding is %d bytes, want %d", len(got), want)
+	}
+	if flags := got[len(got)-2:]; flags[0] != 0x80 || flags[1] != 0x80 {
+		t.Errorf("Flags packed as %x, want 8080", flags)
+	}
+
+	multiproof.Proof[1] = "0x1234"
+	if _, err := multiproof.CanonicalBytes(); !errors.Is(err, ErrInvalidMultiProof) {
+		t.Errorf("Expected ErrInvalidMultiProof for a short node, got %v", err)
+	}
+}
+
+func TestExpandMultiProofVerifiesEachLeaf(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1988, 1988))
+
+	for trial := 0; trial < 200; trial++ {
+		values := make([]string, 1+rng.IntN(40))
+		for i := range values {
+			values[i] = fmt.Sprintf("value-%d-%d", trial, i)
+		}
+		tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(rng.IntN(2) == 0)})
+		if err != nil {
+			t.Fatalf("Failed to create tree: %v", err)
+		}
+
+		var hashes []HexString
+		for _, i := range rng.Perm(len(values))[:1+rng.IntN(len(values))] {
+			hashes = append(hashes, tree.Tree[tree.Values[i].TreeIndex])
+		}
+		multiproof, err := tree.CombineToMultiProof(hashes)
+		if err != nil {
+			t.Fatalf("Trial %d: CombineToMultiProof failed: %v", trial, err)
+		}
+		root, err := ProcessMultiProof(multiproof, StandardNodeHash)
+		if err != nil || root != tree.Root() {
+			t.Fatalf("Trial %d: multiproof gives root %s (err %v), want %s", trial, root, err, tree.Root())
+		}
+
+		proofs, err := ExpandMultiProof(multiproof, StandardNodeHash)
+		if err != nil {
+			t.Fatalf("Trial %d: ExpandMultiProof failed: %v", trial, err)
+		}
+		if len(proofs) != len(hashes) {
+			t.Fatalf("Trial %d: got %d proofs for %d leaves", trial, len(proofs), len(hashes))
+		}
+		for _, hash := range hashes {
+			proof, ok := proofs[hash]
+			if !ok {
+				t.Fatalf("Trial %d: no proof for leaf %s", trial, hash)
+			}
+			got, err := ProcessProof(hash, treeNodes(proof), StandardNodeHash)
+			if err != nil || got != root {
+				t.Fatalf("Trial %d: expanded proof of %s gives root %s (err %v), want %s", trial, hash, got, err, root)
+			}
+			if want, _ := tree.GetProof(tree.HashLookup[hash]); !slices.Equal(proof, want) {
+				t.Errorf("Trial %d: expanded proof of %s is %v, want %v", trial, hash, proof, want)
+			}
+		}
+	}
+}
+
+func TestExpandMultiProofRejectsInconsistent(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"a", "b", "c", "d", "e"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	multiproof, err := tree.CombineToMultiProof([]HexString{tree.Tree[len(tree.Tree)-1], tree.Tree[len(tree.Tree)-3]})
+	if err != nil {
+		t.Fatalf("CombineToMultiProof failed: %v", err)
+	}
+
+	cases := map[string]func(mp *MultiProof){
+		"no leaves":       func(mp *MultiProof) { mp.Leaves = nil },
+		"short leaf":      func(mp *MultiProof) { mp.Leaves[0] = "0x1234" },
+		"extra flag":      func(mp *MultiProof) { mp.ProofFlags = append(mp.ProofFlags, true) },
+		"missing flag":    func(mp *MultiProof) { mp.ProofFlags = mp.ProofFlags[:len(mp.ProofFlags)-1] },
+		"extra node":      func(mp *MultiProof) { mp.Proof = append(mp.Proof, mp.Proof[0]) },
+		"flipped flag":    func(mp *MultiProof) { mp.ProofFlags[0] = !mp.ProofFlags[0] },
+		"ordered hashing": nil,
+	}
+	for name, corrupt := range cases {
+		mp := MultiProof{
+			Leaves:     slices.Clone(multiproof.Leaves),
+			Proof:      slices.Clone(multiproof.Proof),
+			ProofFlags: slices.Clone(multiproof.ProofFlags),
+		}
+		nodeHash := StandardNodeHash
+		if corrupt == nil {
+			nodeHash = func(a, b BytesLike) HexString {
+				left, _ := ToBytes(a)
+				right, _ := ToBytes(b)
+				return HexString(fmt.Sprintf("0x%x", keccakSum(left, right)))
+			}
+		} else {
+			corrupt(&mp)
+		}
+		if _, err := ExpandMultiProof(mp, nodeHash); !errors.Is(err, ErrInvalidMultiProof) {
+			t.Errorf("%s: expected ErrInvalidMultiProof, got %v", name, err)
+		}
+	}
+}
+
+func TestProcessMultiProofRejectsMalformed(t *testing.T) {
+	tree, err := NewStandardMerkleTree(appendValues(8), MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	multiproof, err := tree.GetMultiProof(0, 2, 5)
+	if err != nil {
+		t.Fatalf("GetMultiProof failed: %v", err)
+	}
+	root := tree.Root()
+
+	cases := map[string]func(mp *MultiProof){
+		"no leaves, no proof":     func(mp *MultiProof) { mp.Leaves, mp.Proof, mp.ProofFlags = nil, nil, nil },
+		"no leaves, two nodes":    func(mp *MultiProof) { mp.Leaves, mp.Proof, mp.ProofFlags = nil, []HexString{root, root}, []bool{false} },
+		"no leaves, with flags":   func(mp *MultiProof) { mp.Leaves, mp.Proof, mp.ProofFlags = nil, []HexString{root}, []bool{false} },
+		"short leaf":              func(mp *MultiProof) { mp.Leaves[0] = "0x1234" },
+		"short proof node":        func(mp *MultiProof) { mp.Proof[0] = "0x1234" },
+		"extra flag":              func(mp *MultiProof) { mp.ProofFlags = append(mp.ProofFlags, false) },
+		"missing flag":            func(mp *MultiProof) { mp.ProofFlags = mp.ProofFlags[1:] },
+		"extra proof node":        func(mp *MultiProof) { mp.Proof = append(mp.Proof, root) },
+		"missing proof node":      func(mp *MultiProof) { mp.Proof = mp.Proof[1:] },
+		"all flags take leaves":   func(mp *MultiProof) { mp.ProofFlags = slices.Repeat([]bool{true}, len(mp.ProofFlags)) },
+		"all flags take proof":    func(mp *MultiProof) { mp.ProofFlags = slices.Repeat([]bool{false}, len(mp.ProofFlags)) },
+		"root as the only leaf":   func(mp *MultiProof) { mp.Leaves, mp.Proof, mp.ProofFlags = []HexString{root}, []HexString{root}, nil },
+		"leaves padded for flags": func(mp *MultiProof) { mp.Leaves = append(mp.Leaves, mp.Leaves[0]) },
+	}
+	for name, corrupt := range cases {
+		mp := MultiProof{
+			Leaves:     slices.Clone(multiproof.Leaves),
+			Proof:      slices.Clone(multiproof.Proof),
+			ProofFlags: slices.Clone(multiproof.ProofFlags),
+		}
+		corrupt(&mp)
+		if got, err := ProcessMultiProof(mp, StandardNodeHash); !errors.Is(err, ErrInvalidMultiProof) {
+			t.Errorf("%s: expected ErrInvalidMultiProof, got %s, %v", name, got, err)
+		}
+	}
+
+	mp := MultiProof{Leaves: slices.Clone(multiproof.Leaves), Proof: slices.Clone(multiproof.Proof), ProofFlags: multiproof.ProofFlags}
+	mp.Proof[0] = "0x1234"
+	if _, err := ProcessMultiProof(mp, StandardNodeHash); !errors.Is(err, ErrInvalidNode) {
+		t.Errorf("Expected a short proof node to wrap ErrInvalidNode, got %v", err)
+	}
+	short := func(a, b BytesLike) HexString { return "0x1234" }
+	if _, err := ProcessMultiProof(multiproof, short); !errors.Is(err, ErrInvalidMultiProof) {
+		t.Errorf("Expected ErrInvalidMultiProof for a node hash giving short nodes, got %v", err)
+	}
+}
+
+func TestProcessMultiProofEmptyLeaves(t *testing.T) {
+	tree, err := NewStandardMerkleTree(appendValues(8), MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	root := tree.Root()
+
+	// With no leaves the single proof node is the root, whatever it is, so
+	// such a multi-proof must not prove any value
+	empty := MultiProof{Proof: []HexString{root}}
+	if got, err := ProcessMultiProof(empty, StandardNodeHash); err != nil || got != root {
+		t.Fatalf("ProcessMultiProof of no leaves = %s, %v; want the proof node", got, err)
+	}
+	for _, values := range [][]string{{"value-0"}, {"not in the tree"}} {
+		if ok, _ := VerifyStandardMultiProof(root, values, empty); ok {
+			t.Errorf("A multi-proof of no leaves verifies %v", values)
+		}
+	}
+	if ok, _ := VerifyStandardMultiProof(root, []string{"value-0"}, MultiProof{Leaves: []HexString{StandardLeafHash("value-0")}, Proof: []HexString{root}, ProofFlags: []bool{false}}); ok {
+		t.Error("A value verifies with the root as its only proof node")
+	}
+}
+
+func TestCombineToMultiProofUnknownHash(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"a", "b", "c"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	leaf := HexString(strings.ToUpper(string(tree.Tree[len(tree.Tree)-1])[2:]))
+	if _, err := tree.CombineToMultiProof([]HexString{"0x" + leaf}); err != nil {
+		t.Errorf("Upper-case leaf hash should be found, got %v", err)
+	}
+	if _, err := tree.CombineToMultiProof([]HexString{tree.Root()}); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("Expected ErrValueNotFound for the root, got %v", err)
+	}
+}
+
+func TestTreeGetMultiProof(t *testing.T) {
+	values := []string{"alice", "bob", "charlie", "dave", "eve", "frank", "grace"}
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(true)})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	for name, leaves := range map[string][]any{
+		"one value":        {"charlie"},
+		"one index":        {4},
+		"two":              {"alice", 6},
+		"k of n":           {"grace", "bob", 3, "eve"},
+		"all in any order": {6, 5, 4, 3, 2, 1, 0},
+	} {
+		mp, err := tree.GetMultiProof(leaves...)
+		if err != nil {
+			t.Fatalf("%s: GetMultiProof failed: %v", name, err)
+		}
+		root, err := ProcessMultiProof(mp, tree.NodeHash)
+		if err != nil || root != tree.Root() {
+			t.Errorf("%s: multi-proof gives root %s, %v; want %s", name, root, err, tree.Root())
+		}
+
+		// The leaves are the requested values, once each
+		want := make(map[HexString]bool)
+		for _, leaf := range leaves {
+			index, _ := tree.getLeafIndex(leaf)
+			want[tree.Tree[tree.Values[index].TreeIndex]] = true
+		}
+		if len(mp.Leaves) != len(want) {
+			t.Errorf("%s: multi-proof has %d leaves, want %d", name, len(mp.Leaves), len(want))
+		}
+		for _, leaf := range mp.Leaves {
+			if !want[leaf] {
+				t.Errorf("%s: multi-proof has unrequested leaf %s", name, leaf)
+			}
+		}
+	}
+}
+
+func TestTreeGetMultiProofErrors(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"a", "b", "c"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if _, err := tree.GetMultiProof("a", "z"); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("Expected ErrValueNotFound, got %v", err)
+	}
+	if _, err := tree.GetMultiProof(0, 3); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("Expected ErrInvalidIndex, got %v", err)
+	}
+	if _, err := tree.GetMultiProof(); !errors.Is(err, ErrEmptyTree) {
+		t.Errorf("Expected ErrEmptyTree, got %v", err)
+	}
+}
+
+func TestMultiProofCalldataArgs(t *testing.T) {
+	// The README allowlist of @openzeppelin/merkle-tree: getMultiProof([0, 1])
+	// there returns both leaves by descending tree index, no proof nodes and a
+	// single true flag.
+	var data StandardMerkleTreeData[[]any]
+	if err := json.Unmarshal([]byte(ozReadmeDump), &data); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	tree, err := LoadStandardMerkleTree(data)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	multiproof, err := tree.GetMultiProof(0, 1)
+	if err != nil {
+		t.Fatalf("GetMultiProof failed: %v", err)
+	}
+	proof, flags, leaves := multiproof.ToCalldataArgs()
+	if len(proof) != 0 || !slices.Equal(flags, []bool{true}) || !slices.Equal(leaves, []HexString{data.Tree[2], data.Tree[1]}) {
+		t.Errorf("ToCalldataArgs = %v, %v, %v", proof, flags, leaves)
+	}
+	if proof == nil {
+		t.Error("An empty proof should be [] in calldata, not nil")
+	}
+
+	// In a five-leaf tree, OpenZeppelin orders leaves 8, 5 and 4 by
+	// descending tree index, pairs 8 and 5 with the nodes at 7 and 6, then 4
+	// with 3 and 2 with 1 from the queue.
+	five, err := NewStandardMerkleTree([]string{"a", "b", "c", "d", "e"}, MerkleTreeOptions{SortLeaves: Bool(false)})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	multiproof, err = multiProofAt(t, five, 4, 8, 5)
+	if err != nil {
+		t.Fatalf("GetMultiProof failed: %v", err)
+	}
+	proof, flags, leaves = multiproof.ToCalldataArgs()
+	if !slices.Equal(leaves, []HexString{five.Tree[8], five.Tree[5], five.Tree[4]}) ||
+		!slices.Equal(proof, []HexString{five.Tree[7], five.Tree[6]}) ||
+		!slices.Equal(flags, []bool{false, false, true, true}) {
+		t.Errorf("ToCalldataArgs = %v, %v, %v", proof, flags, leaves)
+	}
+}
+
+// multiProofAt returns the multi-proof of the leaves at the given
+// tree indices of tree.
+func multiProofAt(t *testing.T, tree *StandardMerkleTree[string], treeIndices ...int) (MultiProof, error) {
+	t.Helper()
+	hashes := make([]HexString, len(treeIndices))
+	for i, index := range treeIndices {
+		hashes[i] = tree.Tree[index]
+	}
+	return tree.CombineToMultiProof(hashes)
+}
+
+func TestMultiProofSortForSolidity(t *testing.T) {
+	tree, err := NewStandardMerkleTree(leafSetValues(11), MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	want, err := tree.GetMultiProof(7, 2, 9, 0)
+	if err != nil {
+		t.Fatalf("GetMultiProof failed: %v", err)
+	}
+
+	// Leaves in selection order no longer match the flags
+	shuffled := want
+	shuffled.Leaves = slices.Clone(want.Leaves)
+	slices.Reverse(shuffled.Leaves)
+	if root, err := ProcessMultiProof(shuffled, StandardNodeHash); err == nil && root == tree.Root() {
+		t.Fatal("Reversed leaves should not process to the root")
+	}
+
+	sorted, err := shuffled.SortForSolidity(treeNodes(tree.Tree))
+	if err != nil {
+		t.Fatalf("SortForSolidity failed: %v", err)
+	}
+	if !slices.Equal(sorted.Leaves, want.Leaves) || !slices.Equal(sorted.Proof, want.Proof) || !slices.Equal(sorted.ProofFlags, want.ProofFlags) {
+		t.Errorf("SortForSolidity = %+v, want %+v", sorted, want)
+	}
+	if root, err := ProcessMultiProof(sorted, StandardNodeHash); err != nil || root != tree.Root() {
+		t.Errorf("Sorted multi-proof gives root %s, %v", root, err)
+	}
+
+	foreign := want
+	foreign.Leaves = append(slices.Clone(want.Leaves), HexString("0x"+strings.Repeat("ab", 32)))
+	if _, err := foreign.SortForSolidity(treeNodes(tree.Tree)); !errors.Is(err, ErrInvalidMultiProof) {
+		t.Errorf("Expected ErrInvalidMultiProof for a foreign leaf, got %v", err)
+	}
+	if _, err := (MultiProof{}).SortForSolidity(treeNodes(tree.Tree)); !errors.Is(err, ErrInvalidMultiProof) {
+		t.Errorf("Expected ErrInvalidMultiProof for no leaves, got %v", err)
+	}
+}
+
+func TestMultiProofJSONRoundTrip(t *testing.T) {
+	tree, err := NewStandardMerkleTree(leafSetValues(9), MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	for _, leaves := range [][]any{{0}, {3, 1, 8}, {0, 1, 2, 3, 4, 5, 6, 7, 8}} {
+		multiproof, err := tree.GetMultiProof(leaves...)
+		if err != nil {
+			t.Fatalf("GetMultiProof failed: %v", err)
+		}
+		data, err := json.Marshal(multiproof)
+		if err != nil {
+			t.Fatalf("Failed to marshal: %v", err)
+		}
+		if !strings.HasPrefix(string(data), `{"format":"multiproof-v1","leaves":[`) || !strings.Contains(string(data), `"proofFlags":[`) {
+			t.Errorf("Unexpected JSON %s", data)
+		}
+
+		var decoded MultiProof
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Failed to unmarshal %s: %v", data, err)
+		}
+		if !slices.Equal(decoded.Leaves, multiproof.Leaves) || !slices.Equal(decoded.Proof, multiproof.Proof) || !slices.Equal(decoded.ProofFlags, multiproof.ProofFlags) {
+			t.Errorf("Round trip gave %+v, want %+v", decoded, multiproof)
+		}
+		if root, err := ProcessMultiProof(decoded, StandardNodeHash); err != nil || root != tree.Root() {
+			t.Errorf("Decoded multi-proof of %v gives root %s, %v", leaves, root, err)
+		}
+	}
+
+	// Empty lists are arrays, not null
+	data, err := json.Marshal(MultiProof{})
+	if err != nil || string(data) != `{"format":"multiproof-v1","leaves":[],"proof":[],"proofFlags":[]}` {
+		t.Errorf("Marshal of an empty multi-proof = %s, %v", data, err)
+	}
+}
+
+func TestMultiProofUnmarshalJSONRejects(t *testing.T) {
+	node := `"0x` + strings.Repeat("ab", 32) + `"`
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"unknown format", `{"format":"multiproof-v2","leaves":[` + node + `],"proof":[],"proofFlags":[]}`},
+		{"short leaf", `{"leaves":["0x1234"],"proof":[],"proofFlags":[]}`},
+		{"short proof node", `{"leaves":[` + node + `],"proof":["0x1234"],"proofFlags":[false]}`},
+		{"not hex", `{"leaves":["0xzz"],"proof":[],"proofFlags":[]}`},
+		{"too many flags", `{"leaves":[` + node + `],"proof":[` + node + `],"proofFlags":[false,true]}`},
+		{"too few flags", `{"leaves":[` + node + `,` + node + `],"proof":[` + node + `],"proofFlags":[true]}`},
+		{"empty", `{}`},
+		{"not an object", `[]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mp MultiProof
+			if err := json.Unmarshal([]byte(tt.json), &mp); !errors.Is(err, ErrInvalidMultiProof) {
+				t.Errorf("Unmarshal(%s) = %v, want ErrInvalidMultiProof", tt.json, err)
+			}
+		})
+	}
+
+	// Without a format, hand-written multi-proofs are read as multiproof-v1
+	var mp MultiProof
+	if err := json.Unmarshal([]byte(`{"leaves":[`+node+`],"proof":[`+node+`],"proofFlags":[false]}`), &mp); err != nil {
+		t.Errorf("Unmarshal without a format failed: %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package merkletree
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// NonInclusionProof shows that a value is not in a tree with sorted leaves:
+// its leaf hash falls between two adjacent leaves, or before the first or
+// after the last, each proven against the root.
+type NonInclusionProof struct {
+	LeafCount  int                   `json:"leafCount"`       // Number of leaves in the tree
+	Descending bool                  `json:"descending"`      // Leaves are sorted largest first
+	Left       *NonInclusionNeighbor `json:"left,omitempty"`  // Leaf before the value; nil if it sorts first
+	Right      *NonInclusionNeighbor `json:"right,omitempty"` // Leaf after the value; nil if it sorts last
+}
+
+// NonInclusionNeighbor is a leaf next to the position of an absent value,
+// with its proof.
+type NonInclusionNeighbor struct {
+	Index int         `json:"index"` // Position among the leaves in tree order, 0 for the first
+	Leaf  HexString   `json:"leaf"`  // Leaf hash
+	Proof []HexString `json:"proof"` // Proof of the leaf, as GetProof returns it
+}
+
+// NonInclusionTree is what a verifier must know of a tree to check a
+// non-inclusion proof. Every field must come from the publisher of the tree,
+// as the root does: a prover who picks the leaf count or the order can pass
+// off internal nodes as leaves, or bracket any value with the last leaf.
+type NonInclusionTree struct {
+	Root       BytesLike // Root of the tree
+	LeafCount  int       // Number of leaves
+	Descending bool      // Leaves are sorted largest first
+	NodeHash   NodeHash  // Node hash of the tree, which must keep the order of its inputs, such as PositionalNodeHash
+}
+
+// GetNonInclusionProof returns a proof that value is not in the tree, made of
+// the leaves on either side of where its leaf hash would be sorted. The tree
+// must have sorted leaves, ascending or descending, or it fails with
+// ErrUnsortedTree, and a node hash that keeps the order of its inputs, so
+// that proofs fix the positions of the leaves, or it fails with an
+// *OptionError. The sorted-pair hashes of standard trees do not qualify. A
+// value that is in the tree fails with ErrValueIncluded, and one that does
+// not hash to a 32-byte leaf with ErrInvalidValue.
+func (m *MerkleTreeImpl[T]) GetNonInclusionProof(value T) (NonInclusionProof, error) {
+	order := m.algorithm.LeafOrder
+	if order != LeafOrderAscending && order != LeafOrderDescending {
+		return NonInclusionProof{}, fmt.Errorf("%w: leaf order is %q", ErrUnsortedTree, order)
+	}
+	if err := checkNonInclusionNodeHash(m.NodeHash); err != nil {
+		return NonInclusionProof{}, err
+	}
+	if err := m.checkLeafHash(); err != nil {
+		return NonInclusionProof{}, err
+	}
+	leaf := m.LeafHash(value)
+	if err := leafHashError(value, leaf); err != nil {
+		return NonInclusionProof{}, err
+	}
+	leaf = leaf.Normalize()
+	if index, found := m.hashLookup()[leaf]; found {
+		return NonInclusionProof{}, fmt.Errorf("%w: value %d", ErrValueIncluded, index)
+	}
+
+	n := (len(m.Tree) + 1) / 2
+	leaves := m.Tree[len(m.Tree)-n:]
+	proof := NonInclusionProof{LeafCount: n, Descending: order == LeafOrderDescending}
+	next := sort.Search(n, func(i int) bool {
+		c, _ := Compare(leaves[i], leaf)
+		if proof.Descending {
+			return c < 0
+		}
+		return c > 0
+	})
+	if next > 0 {
+		proof.Left = m.nonInclusionNeighbor(n, next-1)
+	}
+	if next < n {
+		proof.Right = m.nonInclusionNeighbor(n, next)
+	}
+	return proof, nil
+}
+
+// nonInclusionNeighbor returns the leaf at position index of n with its proof.
+func (m *MerkleTreeImpl[T]) nonInclusionNeighbor(n, index int) *NonInclusionNeighbor {
+	i := len(m.Tree) - n + index
+	neighbor := &NonInclusionNeighbor{Index: index, Leaf: m.Tree[i], Proof: []HexString{}}
+	for ; i > 0; i = parentIndex(i) {
+		neighbor.Proof = append(neighbor.Proof, m.Tree[siblingIndex(i)])
+	}
+	return neighbor
+}
+
+// VerifyNonInclusion reports whether proof shows that value is not in the
+// tree, using the tree's hashes, leaf count and leaf order. A tree whose node
+// hash does not keep the order of its inputs fails with an *OptionError.
+func (m *MerkleTreeImpl[T]) VerifyNonInclusion(value T, proof NonInclusionProof) (bool, error) {
+	if err := m.checkLeafHash(); err != nil {
+		return false, err
+	}
+	leaf := m.LeafHash(value)
+	if err := leafHashError(value, leaf); err != nil {
+		return false, err
+	}
+	tree := NonInclusionTree{
+		Root:       m.Root(),
+		LeafCount:  (len(m.Tree) + 1) / 2,
+		Descending: m.algorithm.LeafOrder == LeafOrderDescending,
+		NodeHash:   m.NodeHash,
+	}
+	return VerifyNonInclusion(tree, leaf, proof)
+}
+
+// VerifyNonInclusion reports whether proof shows that the value whose leaf
+// hash is leaf is not in tree. The proof must be for the tree's leaf count
+// and order, and its leaves must sort strictly before and after leaf, have
+// adjacent positions, or be the first or last leaf when there is only one,
+// and verify against the root at those positions. The node hash must keep
+// the order of its inputs, so that a proof cannot place a leaf elsewhere;
+// otherwise, and for a missing node hash, it fails with an *OptionError. It
+// returns an error wrapping ErrInvalidIndex if a position is not below the
+// leaf count, and one wrapping ErrInvalidNode if a leaf or proof node is not
+// 32 bytes.
+func VerifyNonInclusion(tree NonInclusionTree, leaf BytesLike, proof NonInclusionProof) (bool, error) {
+	if err := checkNonInclusionNodeHash(tree.NodeHash); err != nil {
+		return false, err
+	}
+	want, err := ToHex(tree.Root)
+	if err != nil {
+		return false, fmt.Errorf("invalid root: %w", err)
+	}
+	if err := CheckValidMerkleNode(leaf); err != nil {
+		return false, fmt.Errorf("invalid leaf: %w", err)
+	}
+	if proof.LeafCount != tree.LeafCount || proof.Descending != tree.Descending {
+		return false, nil // The proof is for another tree
+	}
+
+	n, left, right := tree.LeafCount, proof.Left, proof.Right
+	for _, neighbor := range []*NonInclusionNeighbor{left, right} {
+		if neighbor == nil {
+			continue
+		}
+		if neighbor.Index < 0 || neighbor.Index >= n {
+			return false, fmt.Errorf("%w: leaf %d of %d", ErrInvalidIndex, neighbor.Index, n)
+		}
+		if err := CheckValidMerkleNode(neighbor.Leaf); err != nil {
+			return false, fmt.Errorf("invalid leaf %d: %w", neighbor.Index, err)
+		}
+	}
+	switch {
+	case left == nil && right == nil:
+		return false, nil
+	case left == nil && right.Index != 0:
+		return false, nil // Only a value before the first leaf has no left neighbor
+	case right == nil && left.Index != n-1:
+		return false, nil // Only a value after the last leaf has no right neighbor
+	case left != nil && right != nil && right.Index != left.Index+1:
+		return false, nil
+	}
+
+	sign := 1
+	if tree.Descending {
+		sign = -1
+	}
+	if left != nil {
+		if c, _ := Compare(left.Leaf, leaf); c*sign >= 0 {
+			return false, nil // The left neighbor does not sort before the value
+		}
+	}
+	if right != nil {
+		if c, _ := Compare(leaf, right.Leaf); c*sign >= 0 {
+			return false, nil // The right neighbor does not sort after the value
+		}
+	}
+
+	for _, neighbor := range []*NonInclusionNeighbor{left, right} {
+		if neighbor == nil {
+			continue
+		}
+		proof := make([]BytesLike, len(neighbor.Proof))
+		for k, p := range neighbor.Proof {
+			proof[k] = p
+		}
+		root, err := ProcessProofAt(neighbor.Leaf, neighbor.Index, n, proof, tree.NodeHash)
+		if errors.Is(err, ErrInvalidProof) {
+			return false, nil // The proof is not the length of the path
+		}
+		if err != nil {
+			return false, fmt.Errorf("invalid proof of leaf %d: %w", neighbor.Index, err)
+		}
+		if root.Normalize() != want.Normalize() {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// checkNonInclusionNodeHash rejects node hashes that cannot fix the positions
+// of the leaves of a non-inclusion proof.
+func checkNonInclusionNodeHash(nodeHash NodeHash) error {
+	if nodeHash == nil {
+		return &OptionError{Option: "NodeHash", Value: nil, Reason: "is required to verify non-inclusion"}
+	}
+	if !nodeHashKeepsOrder(nodeHash) {
+		return &OptionError{Option: "NodeHash", Value: "sorted pairs", Reason: "non-inclusion proofs need a node hash that keeps the order of its inputs"}
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+package merkletree
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// nonInclusionValues returns 1600 values for non-inclusion tests; every
+// fourth one goes in absent, the others in values.
+func nonInclusionValues() (values, absent []BytesLike) {
+	for i := range 1600 {
+		value := BytesLike(fmt.Sprintf("0x%064x", i))
+		if i%4 == 0 {
+			absent = append(absent, value)
+		} else {
+			values = append(values, value)
+		}
+	}
+	return values, absent
+}
+
+// nonInclusionTree returns what a verifier knows of tree.
+func nonInclusionTree(tree *SimpleMerkleTree) NonInclusionTree {
+	return NonInclusionTree{
+		Root:       tree.Root(),
+		LeafCount:  (len(tree.Tree) + 1) / 2,
+		Descending: tree.algorithm.LeafOrder == LeafOrderDescending,
+		NodeHash:   PositionalNodeHash,
+	}
+}
+
+func TestNonInclusionProof(t *testing.T) {
+	configs := map[string]MerkleTreeOptions{
+		"ascending":  {SortLeaves: Bool(true), Compatibility: CompatV0},
+		"descending": {SortLeaves: Bool(true), SortDescending: true, Compatibility: CompatV0},
+		"latest":     {Compatibility: CompatLatest},
+	}
+	values, absent := nonInclusionValues()
+
+	for name, options := range configs {
+		t.Run(name, func(t *testing.T) {
+			for _, n := range []int{1, 2, 5, 30} {
+				tree, err := NewSimpleMerkleTree(values[:n], SimpleMerkleTreeOptions{MerkleTreeOptions: options, NodeHash: PositionalNodeHash})
+				if err != nil {
+					t.Fatalf("Failed to create tree: %v", err)
+				}
+				var first, last bool
+				for _, value := range absent {
+					proof, err := tree.GetNonInclusionProof(value)
+					if err != nil {
+						t.Fatalf("GetNonInclusionProof(%v) on %d leaves failed: %v", value, n, err)
+					}
+					first = first || proof.Left == nil
+					last = last || proof.Right == nil
+					if ok, err := tree.VerifyNonInclusion(value, proof); err != nil || !ok {
+						t.Errorf("Proof that %v is not in %d leaves does not verify: %v", value, n, err)
+					}
+					if ok, err := VerifyNonInclusion(nonInclusionTree(tree), tree.LeafHash(value), proof); err != nil || !ok {
+						t.Errorf("VerifyNonInclusion of %v in %d leaves = %v, %v", value, n, ok, err)
+					}
+				}
+				if n == 30 && !(first && last) {
+					t.Errorf("Values before the first and after the last leaf were not covered")
+				}
+				for _, value := range values[:n] {
+					if _, err := tree.GetNonInclusionProof(value); !errors.Is(err, ErrValueIncluded) {
+						t.Fatalf("Expected ErrValueIncluded for %v, got %v", value, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestNonInclusionProofRejects(t *testing.T) {
+	values, absent := nonInclusionValues()
+	tree, err := NewSimpleMerkleTree(values[:7], SimpleMerkleTreeOptions{MerkleTreeOptions: MerkleTreeOptions{SortLeaves: Bool(true), Compatibility: CompatV0}, NodeHash: PositionalNodeHash})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	published := nonInclusionTree(tree)
+	leaves := slices.Clone(tree.Tree[len(tree.Tree)-7:])
+
+	// Find an absent value with neighbors on both sides
+	var leaf HexString
+	var proof NonInclusionProof
+	for i := 0; proof.Left == nil || proof.Right == nil; i++ {
+		leaf = tree.LeafHash(absent[i])
+		if proof, err = tree.GetNonInclusionProof(absent[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if proof.Left.Leaf != leaves[proof.Left.Index] || proof.Right.Leaf != leaves[proof.Right.Index] {
+		t.Fatalf("Neighbors %d and %d are not the leaves at those positions", proof.Left.Index, proof.Right.Index)
+	}
+
+	tamper := func(change func(p *NonInclusionProof)) NonInclusionProof {
+		p := proof
+		left, right := *p.Left, *p.Right
+		left.Proof, right.Proof = slices.Clone(left.Proof), slices.Clone(right.Proof)
+		p.Left, p.Right = &left, &right
+		change(&p)
+		return p
+	}
+	for name, tampered := range map[string]NonInclusionProof{
+		"present value": proof, // Checked against the leaf of the left neighbor below
+		"no neighbors":  tamper(func(p *NonInclusionProof) { p.Left, p.Right = nil, nil }),
+		"left only":     tamper(func(p *NonInclusionProof) { p.Right = nil }),
+		"right only":    tamper(func(p *NonInclusionProof) { p.Left = nil }),
+		"not adjacent":  tamper(func(p *NonInclusionProof) { p.Right.Index++ }),
+		"descending":    tamper(func(p *NonInclusionProof) { p.Descending = true }),
+		"leaf count":    tamper(func(p *NonInclusionProof) { p.LeafCount++ }),
+		"short proof":   tamper(func(p *NonInclusionProof) { p.Left.Proof = p.Left.Proof[1:] }),
+		"long proof":    tamper(func(p *NonInclusionProof) { p.Left.Proof = append(p.Left.Proof, p.Left.Proof[0]) }),
+		"swapped":       tamper(func(p *NonInclusionProof) { p.Left, p.Right = p.Right, p.Left }),
+	} {
+		target := leaf
+		if name == "present value" {
+			target = proof.Left.Leaf
+		}
+		if ok, err := VerifyNonInclusion(published, target, tampered); ok {
+			t.Errorf("%s: tampered proof verifies (err %v)", name, err)
+		}
+	}
+
+	// The tree the verifier knows must match the proof too
+	for name, change := range map[string]func(p *NonInclusionTree){
+		"leaf count": func(p *NonInclusionTree) { p.LeafCount++ },
+		"descending": func(p *NonInclusionTree) { p.Descending = true },
+	} {
+		other := published
+		change(&other)
+		if ok, err := VerifyNonInclusion(other, leaf, proof); ok {
+			t.Errorf("%s: proof verifies against another tree (err %v)", name, err)
+		}
+	}
+
+	other := published
+	other.LeafCount = 6
+	if _, err := VerifyNonInclusion(other, leaf, tamper(func(p *NonInclusionProof) { p.LeafCount, p.Right.Index = 6, 6 })); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("Expected ErrInvalidIndex, got %v", err)
+	}
+	if _, err := VerifyNonInclusion(published, leaf, tamper(func(p *NonInclusionProof) { p.Left.Proof[0] = "0x1234" })); !errors.Is(err, ErrInvalidNode) {
+		t.Errorf("Expected ErrInvalidNode, got %v", err)
+	}
+}
+
+// TestNonInclusionProofForgedNeighbor moves the leaf after a value that is in
+// the tree into the value's position, so that the two neighbors look adjacent
+// and bracket the value. Positions bound by the node hash expose the move.
+func TestNonInclusionProofForgedNeighbor(t *testing.T) {
+	values, _ := nonInclusionValues()
+	tree, err := NewSimpleMerkleTree(values[:7], SimpleMerkleTreeOptions{MerkleTreeOptions: MerkleTreeOptions{SortLeaves: Bool(true), Compatibility: CompatV0}, NodeHash: PositionalNodeHash})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	published := nonInclusionTree(tree)
+	n := published.LeafCount
+
+	for _, k := range []int{1, 3, 5} {
+		right := tree.nonInclusionNeighbor(n, k+1)
+		right.Index = k
+		forged := NonInclusionProof{LeafCount: n, Left: tree.nonInclusionNeighbor(n, k-1), Right: right}
+		if ok, err := VerifyNonInclusion(published, tree.Tree[n-1+k], forged); ok || err != nil {
+			t.Errorf("Forged proof that leaf %d is absent = %v, %v; want false", k, ok, err)
+		}
+	}
+}
+
+// TestNonInclusionTreeInclusion checks that the tree the README recommends
+// for non-inclusion proofs also proves inclusion, and survives a dump.
+func TestNonInclusionTreeInclusion(t *testing.T) {
+	values, absent := nonInclusionValues()
+	values = values[:13]
+	tree, err := NewSimpleMerkleTree(values, SimpleMerkleTreeOptions{
+		MerkleTreeOptions: MerkleTreeOptions{SortLeaves: Bool(true)},
+		NodeHash:          PositionalNodeHash,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if err := tree.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	n := len(values)
+	for i, value := range values {
+		proof, err := tree.GetProof(value)
+		if err != nil {
+			t.Fatalf("GetProof(%d) failed: %v", i, err)
+		}
+		if ok, err := tree.Verify(value, proof); err != nil || !ok {
+			t.Errorf("Proof of value %d does not verify: %v", i, err)
+		}
+		// Standalone, with the position of the leaf in the tree
+		position := tree.Values[i].TreeIndex - (n - 1)
+		if root, err := ProcessProofAt(tree.LeafHash(value), position, n, treeNodes(proof), PositionalNodeHash); err != nil || root != tree.Root() {
+			t.Errorf("ProcessProofAt of value %d = %s, %v; want %s", i, root, err, tree.Root())
+		}
+		if root, _ := ProcessProofAt(tree.LeafHash(value), (position+1)%n, n, treeNodes(proof), PositionalNodeHash); root == tree.Root() {
+			t.Errorf("Proof of value %d verifies at another position", i)
+		}
+		if ok, err := tree.Verify(values[(i+1)%n], proof); err != nil || ok {
+			t.Errorf("Proof of value %d verifies for another value: %v, %v", i, ok, err)
+		}
+	}
+	if ok, err := tree.Verify(absent[0], []HexString{}); err != nil || ok {
+		t.Errorf("A value not in the tree verifies: %v, %v", ok, err)
+	}
+
+	dump, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	if dump.HashAlgorithm != HashAlgorithmKeccak256Positional || dump.Algorithm.NodeHash != HashKeccak256Ordered {
+		t.Errorf("Dump records node hash %q, %q", dump.HashAlgorithm, dump.Algorithm.NodeHash)
+	}
+	loaded, _, err := LoadSimpleMerkleTree(dump, nil)
+	if err != nil {
+		t.Fatalf("Failed to load dump: %v", err)
+	}
+	if loaded.Root() != tree.Root() {
+		t.Errorf("Loaded root %s, want %s", loaded.Root(), tree.Root())
+	}
+	proof, err := loaded.GetNonInclusionProof(absent[0])
+	if err != nil {
+		t.Fatalf("GetNonInclusionProof on the loaded tree failed: %v", err)
+	}
+	if ok, err := VerifyNonInclusion(nonInclusionTree(tree), tree.LeafHash(absent[0]), proof); err != nil || !ok {
+		t.Errorf("Non-inclusion proof from the loaded tree does not verify: %v", err)
+	}
+	inclusion, err := loaded.GetProof(values[5])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := loaded.Verify(values[5], inclusion); err != nil || !ok {
+		t.Errorf("Inclusion proof from the loaded tree does not verify: %v", err)
+	}
+}
+
+func TestProcessProofAtErrors(t *testing.T) {
+	leaf := HexString("0x" + strings.Repeat("11", 32))
+	node := HexString("0x" + strings.Repeat("22", 32))
+	for name, tc := range map[string]struct {
+		position, leafCount int
+		proof               []BytesLike
+		want                error
+	}{
+		"negative position": {-1, 4, []BytesLike{node, node}, ErrInvalidIndex},
+		"past the end":      {4, 4, []BytesLike{node, node}, ErrInvalidIndex},
+		"short proof":       {1, 4, []BytesLike{node}, ErrInvalidProof},
+		"long proof":        {1, 4, []BytesLike{node, node, node}, ErrInvalidProof},
+		"invalid node":      {1, 4, []BytesLike{node, "0x1234"}, ErrInvalidNode},
+	} {
+		if _, err := ProcessProofAt(leaf, tc.position, tc.leafCount, tc.proof, PositionalNodeHash); !errors.Is(err, tc.want) {
+			t.Errorf("%s: expected %v, got %v", name, tc.want, err)
+		}
+	}
+}
+
+func TestNonInclusionProofSortedPairs(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"a", "b", "c"}, MerkleTreeOptions{SortLeaves: Bool(true), Compatibility: CompatV0})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	var optionErr *OptionError
+	if _, err := tree.GetNonInclusionProof("d"); !errors.Is(err, ErrInvalidOptions) || !errors.As(err, &optionErr) || optionErr.Option != "NodeHash" {
+		t.Errorf("Expected an OptionError for NodeHash, got %v", err)
+	}
+	for _, nodeHash := range []NodeHash{StandardNodeHash, nil} {
+		published := NonInclusionTree{Root: tree.Root(), LeafCount: 3, NodeHash: nodeHash}
+		if _, err := VerifyNonInclusion(published, StandardLeafHash("d"), NonInclusionProof{LeafCount: 3}); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("Expected ErrInvalidOptions, got %v", err)
+		}
+	}
+}
+
+func TestNonInclusionProofUnsorted(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"a", "b", "c"}, MerkleTreeOptions{SortLeaves: Bool(false), Compatibility: CompatV0})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if _, err := tree.GetNonInclusionProof("d"); !errors.Is(err, ErrUnsortedTree) {
+		t.Errorf("Expected ErrUnsortedTree, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package merkletree
+
+import (
+	"errors"
+	"fmt"
+)
+
+// BuildAndProve builds the standard tree over values, as NewStandardMerkleTree
+// would with the same options, and returns its root and the proof of target,
+// without keeping the tree. It is for trees built to answer one request.
+//
+// Only the leaves and internal nodes are built: there are no Values, lookup
+// indexes, metadata or pre-generated proofs, and target is found by its leaf
+// hash while the leaves are placed. The root and proof equal those of the
+// full tree, including for a duplicated target, which resolves as the
+// tree's Duplicates policy does.
+//
+// A build failure is returned like the constructor's. A target that is not
+// among the values returns an error wrapping ErrValueNotFound, and one that
+// was left out under QuarantineInvalid an error wrapping ErrQuarantined.
+func BuildAndProve[T any](values []T, target T, options MerkleTreeOptions) (HexString, []HexString, error) {
+	options = NewMerkleTreeOptions(&options)
+	leafHash := StandardLeafHash[T]
+	if options.Compatibility == CompatLatest {
+		leafHash = OpenZeppelinLeafHash[T]
+	}
+	return buildAndProve(values, leafHash(target), options, leafHash, StandardNodeHash, nil)
+}
+
+// BuildAndProveSimple is BuildAndProve for a simple tree, built as
+// NewSimpleMerkleTree would with the same options.
+func BuildAndProveSimple(values []BytesLike, target BytesLike, options SimpleMerkleTreeOptions) (HexString, []HexString, error) {
+	options.MerkleTreeOptions = NewMerkleTreeOptions(&options.MerkleTreeOptions)
+	nodeHash, _, hashErr := options.resolveNodeHash()
+	leafHash, _ := simpleLeafHash(options.Compatibility)
+	return buildAndProve(values, leafHash(target), options.MerkleTreeOptions, leafHash, nodeHash, hashErr)
+}
+
+// buildAndProve implements BuildAndProve with options already defaulted.
+// hashErr is a problem with the node hash, reported with the others.
+func buildAndProve[T any](
+	values []T,
+	target HexString,
+	options MerkleTreeOptions,
+	leafHash func(T) HexString,
+	nodeHash NodeHash,
+	hashErr error,
+) (HexString, []HexString, error) {
+	var errs []error
+	if hashErr != nil {
+		errs = append(errs, hashErr)
+	}
+	errs = append(errs, options.validateInput(len(values))...)
+	if len(errs) == 0 {
+		if err := options.Limits.checkEstimate(len(values), options); err != nil {
+			return "", nil, fmt.Errorf("failed to prepare merkle tree: %w", err)
+		}
+	}
+	guard := newBuildGuard(options.Limits)
+
+	leafHashes, err := hashLeaves(values, leafHash, options.leafHashWorkers(), guard)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to prepare merkle tree: %w", err)
+	}
+	leaves := leafHashes[:0]
+	quarantine, invalid := screenLeaves(values, leafHashes, options, func(i int) {
+		leaves = append(leaves, leafHashes[i])
+	})
+	if errs = append(errs, invalid...); len(errs) > 0 {
+		return "", nil, fmt.Errorf("failed to prepare merkle tree: %w", errors.Join(errs...))
+	}
+	if len(leaves) == 0 {
+		return "", nil, fmt.Errorf("failed to prepare merkle tree: %w", ErrEmptyTree)
+	}
+	orderLeaves(options, leaves, func(h HexString) HexString { return h })
+
+	// The lowest tree index wins, as in HashLookup
+	position, occurrences := -1, 0
+	for i, leaf := range leaves {
+		if leaf == target {
+			if position < 0 {
+				position = i
+			}
+			occurrences++
+		}
+	}
+	if position < 0 {
+		for _, q := range quarantine {
+			if leafHash(q.value) == target {
+				return "", nil, fmt.Errorf("%w: input index %d: %v", ErrQuarantined, q.Index, q.Err)
+			}
+		}
+		return "", nil, fmt.Errorf("%w: the target is not among the values", ErrValueNotFound)
+	}
+	if occurrences > 1 && options.Duplicates == DuplicatesRequireIndex {
+		return "", nil, fmt.Errorf("%w: the target occurs %d times", ErrAmbiguousValue, occurrences)
+	}
+
+	tree := make([]HexString, 2*len(leaves)-1)
+	first := len(leaves) - 1
+	copy(tree[first:], leaves)
+	if err := hashInternalNodes(tree, first, nodeHash, options.nodeHashWorkers(), guard); err != nil {
+		return "", nil, fmt.Errorf("failed to prepare merkle tree: %w", err)
+	}
+
+	var proof []HexString
+	for index := first + position; index > 0; index = parentIndex(index) {
+		proof = append(proof, tree[siblingIndex(index)])
+	}
+	return tree[0], proof, nil
+}
@@ -0,0 +1,150 @@
+package merkletree
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// longFormProof is the code BuildAndProve replaces.
+func longFormProof[T any](t testing.TB, values []T, target T, options MerkleTreeOptions) (HexString, []HexString) {
+	t.Helper()
+	tree, err := NewStandardMerkleTree(values, options)
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	proof, err := tree.GetProof(target)
+	if err != nil {
+		t.Fatalf("GetProof failed: %v", err)
+	}
+	return tree.Root(), proof
+}
+
+func TestBuildAndProveMatchesLongForm(t *testing.T) {
+	values := []string{"dave", "alice", "eve", "bob", "charlie", "alice", "frank"}
+	for name, options := range map[string]MerkleTreeOptions{
+		"sorted":     {SortLeaves: Bool(true)},
+		"unsorted":   {SortLeaves: Bool(false)},
+		"descending": {SortLeaves: Bool(true), SortDescending: true},
+		"latest":     {Compatibility: CompatLatest},
+		"shuffled":   {ShuffleSeed: []byte("seed")},
+		"parallel":   {SortLeaves: Bool(true), Parallelism: 4},
+		"quarantine": {SortLeaves: Bool(true), MaxValueBytes: 5, QuarantineInvalid: true},
+	} {
+		for _, target := range []string{"alice", "bob", "frank"} {
+			root, proof, err := BuildAndProve(values, target, options)
+			if err != nil {
+				t.Fatalf("%s: BuildAndProve(%q) failed: %v", name, target, err)
+			}
+			wantRoot, wantProof := longFormProof(t, values, target, options)
+			if root != wantRoot || !slices.Equal(proof, wantProof) {
+				t.Errorf("%s: BuildAndProve(%q) = %s %v, want %s %v", name, target, root, proof, wantRoot, wantProof)
+			}
+		}
+	}
+
+	// A single value has an empty proof
+	root, proof, err := BuildAndProve([]string{"alone"}, "alone", MerkleTreeOptions{})
+	if wantRoot, wantProof := longFormProof(t, []string{"alone"}, "alone", MerkleTreeOptions{}); err != nil || root != wantRoot || len(proof) != len(wantProof) {
+		t.Errorf("Single value gave %s %v, %v", root, proof, err)
+	}
+}
+
+func TestBuildAndProveSimple(t *testing.T) {
+	values := []BytesLike{"a", "b", "c", "d", "e"}
+	options := SimpleMerkleTreeOptions{HashAlgorithm: HashAlgorithmSHA256}
+	root, proof, err := BuildAndProveSimple(values, "c", options)
+	if err != nil {
+		t.Fatalf("BuildAndProveSimple failed: %v", err)
+	}
+	tree, err := NewSimpleMerkleTree(values, options)
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	want, err := tree.GetProof(BytesLike("c"))
+	if err != nil {
+		t.Fatalf("GetProof failed: %v", err)
+	}
+	if root != tree.Root() || !slices.Equal(proof, want) {
+		t.Errorf("BuildAndProveSimple = %s %v, want %s %v", root, proof, tree.Root(), want)
+	}
+
+	// Under CompatLatest the target is found by its bytes
+	latest := SimpleMerkleTreeOptions{MerkleTreeOptions: MerkleTreeOptions{Compatibility: CompatLatest}}
+	hexValues := []BytesLike{"0x1111", "0x2222", "0x3333"}
+	root, proof, err = BuildAndProveSimple(hexValues, []byte{0x22, 0x22}, latest)
+	if err != nil {
+		t.Fatalf("BuildAndProveSimple failed: %v", err)
+	}
+	if tree, err = NewSimpleMerkleTree(hexValues, latest); err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if want, _ = tree.GetProof(BytesLike("0x2222")); root != tree.Root() || !slices.Equal(proof, want) {
+		t.Errorf("BuildAndProveSimple under CompatLatest = %s %v, want %s %v", root, proof, tree.Root(), want)
+	}
+
+	var optErr *OptionError
+	_, _, err = BuildAndProveSimple(values, "c", SimpleMerkleTreeOptions{HashAlgorithm: "md4"})
+	if !errors.As(err, &optErr) || optErr.Option != "HashAlgorithm" {
+		t.Errorf("Expected an OptionError for HashAlgorithm, got %v", err)
+	}
+}
+
+func TestBuildAndProveErrors(t *testing.T) {
+	values := []string{"alice", "bob", "charlie"}
+
+	// A missing target is told apart from a failed build
+	_, _, err := BuildAndProve(values, "mallory", MerkleTreeOptions{})
+	if !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("Expected ErrValueNotFound, got %v", err)
+	}
+	var optErr *OptionError
+	_, _, err = BuildAndProve(values, "alice", MerkleTreeOptions{MaxLeaves: 2})
+	if errors.Is(err, ErrValueNotFound) || !errors.Is(err, ErrTooManyLeaves) {
+		t.Errorf("Expected a build failure, got %v", err)
+	}
+	_, _, err = BuildAndProve(values, "mallory", MerkleTreeOptions{MaxInputErrors: -1})
+	if errors.Is(err, ErrValueNotFound) || !errors.As(err, &optErr) {
+		t.Errorf("Expected an OptionError, got %v", err)
+	}
+	if _, _, err := BuildAndProve([]string{}, "alice", MerkleTreeOptions{}); !errors.Is(err, ErrEmptyTree) {
+		t.Errorf("Expected ErrEmptyTree, got %v", err)
+	}
+
+	long := strings.Repeat("x", 64)
+	_, _, err = BuildAndProve(append(values, long), long, MerkleTreeOptions{MaxValueBytes: 32, QuarantineInvalid: true})
+	if !errors.Is(err, ErrQuarantined) {
+		t.Errorf("Expected ErrQuarantined, got %v", err)
+	}
+
+	_, _, err = BuildAndProve([]string{"alice", "bob", "alice"}, "alice", MerkleTreeOptions{Duplicates: DuplicatesRequireIndex})
+	if !errors.Is(err, ErrAmbiguousValue) {
+		t.Errorf("Expected ErrAmbiguousValue, got %v", err)
+	}
+}
+
+func BenchmarkBuildAndProve(b *testing.B) {
+	for _, n := range []int{100, 10_000} {
+		values := make([]string, n)
+		for i := range values {
+			values[i] = fmt.Sprintf("0x%040x", i)
+		}
+		target := values[n/2]
+		b.Run(fmt.Sprintf("n=%d/one-shot", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				if _, _, err := BuildAndProve(values, target, MerkleTreeOptions{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("n=%d/constructor", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				longFormProof(b, values, target, MerkleTreeOptions{})
+			}
+		})
+	}
+}
@@ -1,25 +1,297 @@
 package merkletree
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
 // MerkleTreeOptions defines configuration options for building a Merkle tree.
 type MerkleTreeOptions struct {
 	// SortLeaves indicates whether leaves should be sorted before building the tree.
 	// Sorting leaves makes multi-proofs more efficient and ensures consistent tree
-	// structure regardless of input order.
-	SortLeaves bool `json:"sortLeaves"`
+	// structure regardless of input order. Set it with Bool(true) or Bool(false);
+	// nil leaves the choice to the compatibility mode, which under CompatV0 is
+	// input order, so the zero options keep their roots. CompatLatest ignores
+	// it and sorts unless PreserveOrder is set.
+	SortLeaves *bool `json:"sortLeaves,omitempty"`
+
+	// SortDescending sorts leaves from the largest hash to the smallest.
+	// It only has an effect when SortLeaves is true.
+	SortDescending bool `json:"sortDescending,omitempty"`
+
+	// PrefixIndex builds an index over the sorted leaf hashes so that
+	// FindByHashPrefix runs in O(log n) instead of scanning every leaf.
+	PrefixIndex bool `json:"prefixIndex,omitempty"`
+
+	// EagerIndexes builds HashLookup and, with PrefixIndex, the prefix index
+	// with the tree instead of on first use, so no lookup pays for them. For
+	// latency-sensitive servers; see also WarmIndexes.
+	EagerIndexes bool `json:"eagerIndexes,omitempty"`
+
+	// MaxPrefixResults caps the number of matches returned by FindByHashPrefix.
+	// Zero means DefaultMaxPrefixResults.
+	MaxPrefixResults int `json:"maxPrefixResults,omitempty"`
+
+	// Duplicates decides how value-based lookups resolve a value that occurs
+	// more than once. The default returns the occurrence with the lowest tree index.
+	Duplicates DuplicatePolicy `json:"duplicates,omitempty"`
+
+	// MaxLeaves rejects inputs with more values than this. Zero means no limit.
+	MaxLeaves int `json:"maxLeaves,omitempty"`
+
+	// MaxInputErrors caps how many invalid values are reported when
+	// construction fails. Zero means DefaultMaxInputErrors.
+	MaxInputErrors int `json:"maxInputErrors,omitempty"`
+
+	// MaxValueBytes rejects values larger than this many bytes, as measured
+	// by their text or byte length, with a *ValueSizeError, or quarantines
+	// them under QuarantineInvalid. Zero means no limit.
+	MaxValueBytes int `json:"maxValueBytes,omitempty"`
+
+	// LeafMetadata attaches JSON metadata to each value, in the same order as
+	// the values. It is not hashed, stays with its value when leaves are
+	// sorted, and is included in dumps, entries and claims. Nil means none.
+	LeafMetadata []json.RawMessage `json:"-"`
+
+	// QuarantineInvalid builds the tree from the valid values only, instead of
+	// failing, when some values cannot be hashed. The skipped values are listed
+	// by Quarantine and counted in the dump, and the value indices of the tree
+	// then count only the valid values.
+	QuarantineInvalid bool `json:"quarantineInvalid,omitempty"`
+
+	// Parallelism is the number of goroutines used to hash leaves and to
+	// assemble the tree. Zero means one: everything runs on the calling goroutine.
+	// Hash functions must be safe for concurrent use when it is above one.
+	Parallelism int `json:"parallelism,omitempty"`
+
+	// LeafHashParallelism overrides Parallelism for hashing the values into
+	// leaves, for leaf hashes that are slow but not CPU-bound. Zero means Parallelism.
+	LeafHashParallelism int `json:"leafHashParallelism,omitempty"`
+
+	// NodeHashParallelism overrides Parallelism for hashing internal nodes.
+	// Zero means Parallelism.
+	NodeHashParallelism int `json:"nodeHashParallelism,omitempty"`
+
+	// DropValuesAfterBuild discards the values once the tree and its hash
+	// lookup are built, keeping only their tree indices. Proofs by index and
+	// by value still work, but Dump, Entries and ExportClaim need a
+	// ValueProvider to return values and fail with ErrValuesDropped without one.
+	DropValuesAfterBuild bool `json:"dropValuesAfterBuild,omitempty"`
+
+	// Compatibility selects the tree-building rules. The zero value builds
+	// with CompatV0 rules and records a deprecation warning; see CompatibilityMode.
+	Compatibility CompatibilityMode `json:"compatibility,omitempty"`
+
+	// PreserveOrder keeps the leaves in input order under CompatLatest, like
+	// OpenZeppelin's sortLeaves: false. Other modes use SortLeaves instead.
+	PreserveOrder bool `json:"preserveOrder,omitempty"`
+
+	// PregenerateProofs computes the proof of every value while building, so
+	// GetProof only copies the sibling hashes out of Tree. The proofs are kept
+	// as node indices, about 4*(depth+1) bytes per value.
+	PregenerateProofs bool `json:"pregenerateProofs,omitempty"`
+
+	// PregenerateMaxLeaves is the largest tree whose proofs are pre-generated.
+	// Zero means DefaultPregenerateMaxLeaves.
+	PregenerateMaxLeaves int `json:"pregenerateMaxLeaves,omitempty"`
+
+	// SkipPregenerateAboveMax builds a tree above PregenerateMaxLeaves without
+	// pre-generated proofs instead of failing with ErrTooManyLeaves.
+	SkipPregenerateAboveMax bool `json:"skipPregenerateAboveMax,omitempty"`
+
+	// ShuffleSeed lays the leaves out in the order of keccak256(seed || leaf
+	// hash) instead of sorted or in input order, so the layout reveals
+	// nothing about the values, such as a ranking by amount. Anyone holding
+	// the seed rebuilds the same tree. It replaces SortLeaves and the
+	// CompatLatest order. Nil means no shuffle.
+	ShuffleSeed []byte `json:"shuffleSeed,omitempty"`
+
+	// Limits bounds the memory and time of the build; see ResourceLimits.
+	// They cannot change the tree, so they are not recorded with it.
+	Limits ResourceLimits `json:"-"`
+}
+
+// DefaultMaxInputErrors is the number of invalid values reported when
+// MerkleTreeOptions.MaxInputErrors is not set.
+const DefaultMaxInputErrors = 20
+
+// Validate checks every option and returns all problems at once, joined with
+// errors.Join. Each problem is an *OptionError.
+func (o MerkleTreeOptions) Validate() error {
+	return errors.Join(o.optionErrors()...)
+}
+
+// optionErrors returns one *OptionError per invalid option.
+func (o MerkleTreeOptions) optionErrors() []error {
+	var errs []error
+	if o.MaxPrefixResults < 0 {
+		errs = append(errs, &OptionError{Option: "MaxPrefixResults", Value: o.MaxPrefixResults, Reason: "must not be negative"})
+	}
+	if o.Duplicates != DuplicatesLowestIndex && o.Duplicates != DuplicatesRequireIndex {
+		errs = append(errs, &OptionError{Option: "Duplicates", Value: o.Duplicates, Reason: "unknown duplicate policy"})
+	}
+	if o.MaxLeaves < 0 {
+		errs = append(errs, &OptionError{Option: "MaxLeaves", Value: o.MaxLeaves, Reason: "must not be negative"})
+	}
+	if o.MaxInputErrors < 0 {
+		errs = append(errs, &OptionError{Option: "MaxInputErrors", Value: o.MaxInputErrors, Reason: "must not be negative"})
+	}
+	if o.MaxValueBytes < 0 {
+		errs = append(errs, &OptionError{Option: "MaxValueBytes", Value: o.MaxValueBytes, Reason: "must not be negative"})
+	}
+	if o.Parallelism < 0 {
+		errs = append(errs, &OptionError{Option: "Parallelism", Value: o.Parallelism, Reason: "must not be negative"})
+	}
+	if o.LeafHashParallelism < 0 {
+		errs = append(errs, &OptionError{Option: "LeafHashParallelism", Value: o.LeafHashParallelism, Reason: "must not be negative"})
+	}
+	if o.NodeHashParallelism < 0 {
+		errs = append(errs, &OptionError{Option: "NodeHashParallelism", Value: o.NodeHashParallelism, Reason: "must not be negative"})
+	}
+	if o.PregenerateMaxLeaves < 0 || o.PregenerateMaxLeaves > maxPregenerateLeaves {
+		errs = append(errs, &OptionError{Option: "PregenerateMaxLeaves", Value: o.PregenerateMaxLeaves, Reason: fmt.Sprintf("must be between 0 and %d", maxPregenerateLeaves)})
+	}
+	errs = append(errs, o.Limits.optionErrors()...)
+	if o.ShuffleSeed != nil {
+		if len(o.ShuffleSeed) == 0 {
+			errs = append(errs, &OptionError{Option: "ShuffleSeed", Value: o.ShuffleSeed, Reason: "must not be empty; leave it nil for no shuffle"})
+		}
+		if o.SortDescending {
+			errs = append(errs, &OptionError{Option: "SortDescending", Value: o.SortDescending, Reason: "cannot be combined with ShuffleSeed"})
+		}
+		if o.PreserveOrder {
+			errs = append(errs, &OptionError{Option: "PreserveOrder", Value: o.PreserveOrder, Reason: "cannot be combined with ShuffleSeed"})
+		}
+	}
+	switch o.Compatibility {
+	case CompatUnspecified, CompatV0:
+		if o.PreserveOrder {
+			errs = append(errs, &OptionError{Option: "PreserveOrder", Value: o.PreserveOrder, Reason: "only applies to CompatLatest; leave SortLeaves unset or false instead"})
+		}
+	case CompatLatest:
+		if o.SortDescending {
+			errs = append(errs, &OptionError{Option: "SortDescending", Value: o.SortDescending, Reason: "not supported under CompatLatest, which lays leaves out as OpenZeppelin does"})
+		}
+	default:
+		errs = append(errs, &OptionError{Option: "Compatibility", Value: o.Compatibility, Reason: "unknown compatibility mode"})
+	}
+	return errs
+}
+
+// validateInput checks the options and the number of values before any
+// hashing is done, returning every problem found.
+func (o MerkleTreeOptions) validateInput(count int) []error {
+	errs := o.optionErrors()
+	if o.MaxLeaves > 0 && count > o.MaxLeaves {
+		errs = append(errs, fmt.Errorf("%w: got %d values, MaxLeaves is %d", ErrTooManyLeaves, count, o.MaxLeaves))
+	}
+	if o.PregenerateProofs && !o.SkipPregenerateAboveMax && count > o.pregenerateLimit() {
+		errs = append(errs, fmt.Errorf("%w: got %d values, PregenerateMaxLeaves is %d", ErrTooManyLeaves, count, o.pregenerateLimit()))
+	}
+	if o.LeafMetadata != nil && len(o.LeafMetadata) != count {
+		errs = append(errs, &OptionError{Option: "LeafMetadata", Value: len(o.LeafMetadata), Reason: fmt.Sprintf("has %d entries for %d values", len(o.LeafMetadata), count)})
+	}
+	for i, meta := range o.LeafMetadata {
+		if meta != nil && !json.Valid(meta) {
+			errs = append(errs, &OptionError{Option: fmt.Sprintf("LeafMetadata[%d]", i), Value: previewValue(string(meta)), Reason: "is not valid JSON"})
+		}
+	}
+	return errs
+}
+
+// maxInputErrors returns the resolved cap on reported invalid values.
+func (o MerkleTreeOptions) maxInputErrors() int {
+	if o.MaxInputErrors <= 0 {
+		return DefaultMaxInputErrors
+	}
+	return o.MaxInputErrors
+}
+
+// leafHashWorkers returns the number of goroutines used to hash leaves.
+func (o MerkleTreeOptions) leafHashWorkers() int {
+	return workers(o.LeafHashParallelism, o.Parallelism)
+}
+
+// nodeHashWorkers returns the number of goroutines used to hash internal nodes.
+func (o MerkleTreeOptions) nodeHashWorkers() int {
+	return workers(o.NodeHashParallelism, o.Parallelism)
+}
+
+// workers returns stage if set, otherwise shared, and at least one.
+func workers(stage, shared int) int {
+	if stage > 0 {
+		return stage
+	}
+	if shared > 0 {
+		return shared
+	}
+	return 1
+}
+
+// DuplicatePolicy controls how lookups by value behave when the value occurs
+// several times in the tree.
+type DuplicatePolicy int
+
+const (
+	// DuplicatesLowestIndex resolves a duplicated value to the occurrence with
+	// the lowest tree index. The choice does not depend on map iteration or
+	// sort stability, so it is the same on every rebuild.
+	DuplicatesLowestIndex DuplicatePolicy = iota
+
+	// DuplicatesRequireIndex rejects value-based lookups of a duplicated value
+	// with ErrAmbiguousValue; callers must use ProofForIndex instead.
+	DuplicatesRequireIndex
+)
+
+// leafOrder returns the name of the leaf ordering these options produce.
+func (o MerkleTreeOptions) leafOrder() string {
+	switch {
+	case o.ShuffleSeed != nil:
+		return LeafOrderShuffled
+	case o.Compatibility == CompatLatest && o.PreserveOrder:
+		return LeafOrderReversed
+	case o.Compatibility == CompatLatest:
+		return LeafOrderDescending
+	case !o.sortLeaves():
+		return LeafOrderInsertion
+	case o.SortDescending:
+		return LeafOrderDescending
+	default:
+		return LeafOrderAscending
+	}
+}
+
+// sortLeaves returns whether leaves are sorted, resolving a nil SortLeaves
+// to the default of the compatibility mode.
+func (o MerkleTreeOptions) sortLeaves() bool {
+	if o.SortLeaves == nil {
+		return o.Compatibility == CompatLatest && !o.PreserveOrder
+	}
+	return *o.SortLeaves
+}
+
+// Bool returns a pointer to v, for setting MerkleTreeOptions.SortLeaves.
+func Bool(v bool) *bool {
+	return &v
 }
 
-// DefaultOptions represents the default configuration for a Merkle tree.
-// By default, leaves are sorted to enable more efficient multi-proofs.
+// DefaultOptions is the configuration NewMerkleTreeOptions(nil) returns.
+// It sorts leaves to enable more efficient multi-proofs, where the zero
+// MerkleTreeOptions keeps CompatV0's input order.
 var DefaultOptions = MerkleTreeOptions{
-	SortLeaves: true,
+	SortLeaves: Bool(true),
 }
 
 // NewMerkleTreeOptions creates a MerkleTreeOptions object with provided values.
-// If options is nil, returns DefaultOptions.
-// This function properly handles the zero-value case for boolean fields.
+// If options is nil, returns DefaultOptions. A nil SortLeaves is resolved to
+// the mode's default, so the result says whether leaves are sorted; it points
+// to a new bool, never to the caller's.
 func NewMerkleTreeOptions(options *MerkleTreeOptions) MerkleTreeOptions {
 	if options == nil {
-		return DefaultOptions
+		options = &DefaultOptions
 	}
-	return *options
+	resolved := *options
+	resolved.SortLeaves = Bool(resolved.sortLeaves())
+	return resolved
 }
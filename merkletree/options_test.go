@@ -0,0 +1,172 @@
+package merkletree
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// collectAs returns every error in err's tree that is of type E.
+func collectAs[E error](err error) []E {
+	var found []E
+	var walk func(error)
+	walk = func(e error) {
+		if typed, ok := e.(E); ok {
+			found = append(found, typed)
+		}
+		switch u := e.(type) {
+		case interface{ Unwrap() []error }:
+			for _, inner := range u.Unwrap() {
+				walk(inner)
+			}
+		case interface{ Unwrap() error }:
+			if inner := u.Unwrap(); inner != nil {
+				walk(inner)
+			}
+		}
+	}
+	if err != nil {
+		walk(err)
+	}
+	return found
+}
+
+func TestMerkleTreeOptionsValidate(t *testing.T) {
+	if err := DefaultOptions.Validate(); err != nil {
+		t.Errorf("Default options should be valid: %v", err)
+	}
+
+	options := MerkleTreeOptions{
+		MaxPrefixResults: -1,
+		Duplicates:       DuplicatePolicy(9),
+		MaxLeaves:        -5,
+	}
+	err := options.Validate()
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("Expected ErrInvalidOptions, got %v", err)
+	}
+
+	optionErrs := collectAs[*OptionError](err)
+	got := make([]string, len(optionErrs))
+	for i, e := range optionErrs {
+		got[i] = e.Option
+	}
+	want := []string{"MaxPrefixResults", "Duplicates", "MaxLeaves"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected errors for %v, got %v", want, got)
+	}
+}
+
+func TestConstructorReportsAllProblems(t *testing.T) {
+	type unsupported struct{ Name string }
+	values := []any{"ok", unsupported{"a"}, "fine", unsupported{"b"}, unsupported{"c"}}
+
+	_, err := NewStandardMerkleTree(values, MerkleTreeOptions{
+		Duplicates: DuplicatePolicy(9),
+		MaxLeaves:  3,
+	})
+	if err == nil {
+		t.Fatal("Expected construction to fail")
+	}
+
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Error("Joined error should include the invalid option")
+	}
+	if !errors.Is(err, ErrTooManyLeaves) {
+		t.Error("Joined error should include the MaxLeaves violation")
+	}
+	if !errors.Is(err, ErrInvalidValue) {
+		t.Error("Joined error should include the invalid values")
+	}
+
+	inputErrs := collectAs[*InputError](err)
+	if len(inputErrs) != 3 {
+		t.Fatalf("Expected 3 input errors, got %d: %v", len(inputErrs), err)
+	}
+	for i, wantIndex := range []int{1, 3, 4} {
+		if inputErrs[i].Index != wantIndex {
+			t.Errorf("Input error %d has index %d, want %d", i, inputErrs[i].Index, wantIndex)
+		}
+	}
+}
+
+func TestConstructorCapsInputErrors(t *testing.T) {
+	values := make([]any, 50)
+	for i := range values {
+		values[i] = struct{}{}
+	}
+
+	_, err := NewStandardMerkleTree(values, MerkleTreeOptions{})
+	if got := len(collectAs[*InputError](err)); got != DefaultMaxInputErrors {
+		t.Errorf("Expected %d input errors by default, got %d", DefaultMaxInputErrors, got)
+	}
+	if !strings.Contains(err.Error(), "30 more invalid values") {
+		t.Errorf("Error should summarize the values not shown: %v", err)
+	}
+
+	_, err = NewStandardMerkleTree(values, MerkleTreeOptions{MaxInputErrors: 1})
+	if got := len(collectAs[*InputError](err)); got != 1 {
+		t.Errorf("Expected 1 input error with MaxInputErrors=1, got %d", got)
+	}
+}
+
+func TestConstructorMaxLeaves(t *testing.T) {
+	values := []string{"a", "b", "c"}
+
+	if _, err := NewStandardMerkleTree(values, MerkleTreeOptions{MaxLeaves: 3}); err != nil {
+		t.Errorf("Tree at the limit should build: %v", err)
+	}
+	if _, err := NewStandardMerkleTree(values, MerkleTreeOptions{MaxLeaves: 2}); !errors.Is(err, ErrTooManyLeaves) {
+		t.Errorf("Expected ErrTooManyLeaves, got %v", err)
+	}
+}
+
+func TestSortLeavesTriState(t *testing.T) {
+	values := []string{"dave", "alice", "charlie", "bob"}
+	roots := make(map[string]HexString)
+	for name, options := range map[string]MerkleTreeOptions{
+		"unset":    {},
+		"sorted":   {SortLeaves: Bool(true)},
+		"unsorted": {SortLeaves: Bool(false)},
+		"default":  NewMerkleTreeOptions(nil),
+	} {
+		tree, err := NewStandardMerkleTree(values, options)
+		if err != nil {
+			t.Fatalf("%s: Failed to create tree: %v", name, err)
+		}
+		again, err := NewStandardMerkleTree(values, options)
+		if err != nil || again.Root() != tree.Root() {
+			t.Fatalf("%s: rebuilding gave %s, %v; want %s", name, again.Root(), err, tree.Root())
+		}
+		roots[name] = tree.Root()
+	}
+
+	if roots["sorted"] == roots["unsorted"] {
+		t.Errorf("Sorted and insertion-ordered trees share root %s", roots["sorted"])
+	}
+	// Unset keeps the CompatV0 input order; DefaultOptions sorts
+	if roots["unset"] != roots["unsorted"] || roots["default"] != roots["sorted"] {
+		t.Errorf("Unset built %s and DefaultOptions %s, want %s and %s", roots["unset"], roots["default"], roots["unsorted"], roots["sorted"])
+	}
+
+	for _, tt := range []struct {
+		options MerkleTreeOptions
+		want    bool
+	}{
+		{MerkleTreeOptions{}, false},
+		{MerkleTreeOptions{Compatibility: CompatLatest}, true},
+		{MerkleTreeOptions{Compatibility: CompatLatest, PreserveOrder: true}, false},
+		{MerkleTreeOptions{SortLeaves: Bool(true)}, true},
+	} {
+		resolved := NewMerkleTreeOptions(&tt.options)
+		if resolved.SortLeaves == nil || *resolved.SortLeaves != tt.want {
+			t.Errorf("%+v: resolved SortLeaves to %v, want %v", tt.options, resolved.SortLeaves, tt.want)
+		}
+	}
+
+	// The resolved options do not share DefaultOptions' bool
+	*NewMerkleTreeOptions(nil).SortLeaves = false
+	if !*DefaultOptions.SortLeaves {
+		t.Errorf("Changing resolved options changed DefaultOptions")
+	}
+}
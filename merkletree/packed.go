@@ -0,0 +1,88 @@
+package merkletree
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ProofToPackedBytes concatenates the nodes of a proof into one byte string,
+// the form of contracts that take the proof as `bytes` rather than bytes32[].
+// Every node must be 32 bytes.
+func ProofToPackedBytes(proof []HexString) ([]byte, error) {
+	packed := make([]byte, 0, len(proof)*abiWordSize)
+	for i, node := range proof {
+		b, err := ToBytes(node)
+		if err != nil {
+			return nil, fmt.Errorf("%w: node %d: %v", ErrInvalidPackedProof, i, err)
+		}
+		if len(b) != abiWordSize {
+			return nil, fmt.Errorf("%w: node %d is %d bytes: %v", ErrInvalidPackedProof, i, len(b), ErrInvalidNode)
+		}
+		packed = append(packed, b...)
+	}
+	return packed, nil
+}
+
+// ProofFromPackedBytes splits a packed proof into its 32-byte nodes. Its
+// length must be a multiple of 32; an empty packed proof is an empty proof.
+func ProofFromPackedBytes(packed []byte) ([]HexString, error) {
+	if len(packed)%abiWordSize != 0 {
+		return nil, fmt.Errorf("%w: %d bytes is not a multiple of %d", ErrInvalidPackedProof, len(packed), abiWordSize)
+	}
+	proof := make([]HexString, 0, len(packed)/abiWordSize)
+	for start := 0; start < len(packed); start += abiWordSize {
+		proof = append(proof, HexString("0x"+hex.EncodeToString(packed[start:start+abiWordSize])))
+	}
+	return proof, nil
+}
+
+// ProofToHexArray returns the nodes of a proof as lowercase 0x-prefixed
+// strings, the bytes32[] argument ethers.js passes to MerkleProof.verify, in
+// the same order. A node that is not 32 bytes returns an error wrapping
+// ErrInvalidNode.
+func ProofToHexArray(proof []HexString) ([]string, error) {
+	words := make([]string, len(proof))
+	for i, node := range proof {
+		b, err := ToBytes(node)
+		if err != nil {
+			return nil, fmt.Errorf("%w: proof node %d: %v", ErrInvalidNode, i, err)
+		}
+		if len(b) != abiWordSize {
+			return nil, fmt.Errorf("%w: proof node %d is %d bytes", ErrInvalidNode, i, len(b))
+		}
+		words[i] = "0x" + hex.EncodeToString(b)
+	}
+	return words, nil
+}
+
+// ProofToCalldata returns abi.encode(bytes32[]) of a proof as a 0x-prefixed
+// hex string: an offset word, a length word and then the nodes. Its nodes
+// are checked as by ProofToHexArray.
+func ProofToCalldata(proof []HexString) (string, error) {
+	words, err := ProofToHexArray(proof)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.Grow(2 + (2+len(words))*2*abiWordSize)
+	b.WriteString("0x")
+	b.WriteString(hex.EncodeToString(uintWord(abiWordSize)))
+	b.WriteString(hex.EncodeToString(uintWord(uint64(len(words)))))
+	for _, word := range words {
+		b.WriteString(word[2:])
+	}
+	return b.String(), nil
+}
+
+// packClaimProofs sets the PackedProof of each claim from its proof.
+func packClaimProofs[T any](claims []Claim[T]) error {
+	for i := range claims {
+		packed, err := ProofToPackedBytes(claims[i].Proof)
+		if err != nil {
+			return err
+		}
+		claims[i].PackedProof = HexString("0x" + hex.EncodeToString(packed))
+	}
+	return nil
+}
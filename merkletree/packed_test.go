@@ -0,0 +1,227 @@
+package merkletree
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// readClaimsPage reads an ExportClaims page file from dir.
+func readClaimsPage(t *testing.T, dir, name string) ClaimsPage[string] {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", name, err)
+	}
+	var page ClaimsPage[string]
+	if err := json.Unmarshal(data, &page); err != nil {
+		t.Fatalf("Failed to decode %s: %v", name, err)
+	}
+	return page
+}
+
+// decodeABIBytes decodes abi.encode(bytes) the way a contract does: an
+// offset word, then a length word at the offset, then the padded data.
+func decodeABIBytes(t *testing.T, encoded []byte) []byte {
+	t.Helper()
+	word := func(at int) int {
+		if at+abiWordSize > len(encoded) {
+			t.Fatalf("ABI data of %d bytes has no word at %d", len(encoded), at)
+		}
+		return int(binary.BigEndian.Uint64(encoded[at+abiWordSize-8 : at+abiWordSize]))
+	}
+	offset := word(0)
+	length := word(offset)
+	start := offset + abiWordSize
+	if start+length > len(encoded) {
+		t.Fatalf("ABI bytes of length %d overrun %d bytes", length, len(encoded))
+	}
+	return encoded[start : start+length]
+}
+
+func TestPackedProofRoundTrip(t *testing.T) {
+	tree, err := NewStandardMerkleTree(leafSetValues(13), MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	for i := range 13 {
+		proof, err := tree.GetProof(i)
+		if err != nil {
+			t.Fatalf("Failed to get proof: %v", err)
+		}
+		packed, err := ProofToPackedBytes(proof)
+		if err != nil {
+			t.Fatalf("ProofToPackedBytes failed: %v", err)
+		}
+		if len(packed) != len(proof)*32 {
+			t.Errorf("Packed %d nodes into %d bytes", len(proof), len(packed))
+		}
+		unpacked, err := ProofFromPackedBytes(packed)
+		if err != nil || !slices.Equal(unpacked, proof) {
+			t.Errorf("Round trip gave %v (%v), want %v", unpacked, err, proof)
+		}
+
+		// A contract taking `bytes proof` receives abi.encode(packed)
+		encoded, err := abiEncode(packed)
+		if err != nil {
+			t.Fatalf("abiEncode failed: %v", err)
+		}
+		decoded, err := ProofFromPackedBytes(decodeABIBytes(t, encoded))
+		if err != nil || !slices.Equal(decoded, proof) {
+			t.Errorf("ABI decoded proof %v (%v), want %v", decoded, err, proof)
+		}
+	}
+}
+
+func TestPackedProofErrors(t *testing.T) {
+	for _, n := range []int{1, 31, 33, 65} {
+		if _, err := ProofFromPackedBytes(make([]byte, n)); !errors.Is(err, ErrInvalidPackedProof) {
+			t.Errorf("%d bytes: expected ErrInvalidPackedProof, got %v", n, err)
+		}
+	}
+	if proof, err := ProofFromPackedBytes(nil); err != nil || len(proof) != 0 {
+		t.Errorf("Empty packed proof gave %v, %v", proof, err)
+	}
+	if _, err := ProofToPackedBytes([]HexString{"0x1234"}); !errors.Is(err, ErrInvalidPackedProof) {
+		t.Errorf("Expected ErrInvalidPackedProof for a short node, got %v", err)
+	}
+	if _, err := ProofToPackedBytes([]HexString{"0xzz"}); !errors.Is(err, ErrInvalidPackedProof) {
+		t.Errorf("Expected ErrInvalidPackedProof for a non-hex node, got %v", err)
+	}
+}
+
+func TestVerifySimpleMerkleTreePacked(t *testing.T) {
+	values := []BytesLike{}
+	for i := range 6 {
+		values = append(values, fmt.Sprintf("0x%064x", i))
+	}
+	tree, err := NewSimpleMerkleTree(values, SimpleMerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	proof, err := tree.GetProof(2)
+	if err != nil {
+		t.Fatalf("Failed to get proof: %v", err)
+	}
+	packed, err := ProofToPackedBytes(proof)
+	if err != nil {
+		t.Fatalf("ProofToPackedBytes failed: %v", err)
+	}
+
+	ok, err := VerifySimpleMerkleTreeWithOptions(tree.Root(), values[2], nil, SimpleVerifyOptions{PackedProof: packed})
+	if err != nil || !ok {
+		t.Errorf("Packed proof does not verify: %v", err)
+	}
+	if ok, _ := VerifySimpleMerkleTreeWithOptions(tree.Root(), values[3], nil, SimpleVerifyOptions{PackedProof: packed}); ok {
+		t.Error("Packed proof verified another value")
+	}
+	if _, err := VerifySimpleMerkleTreeWithOptions(tree.Root(), values[2], nil, SimpleVerifyOptions{PackedProof: packed[:40]}); !errors.Is(err, ErrInvalidPackedProof) {
+		t.Errorf("Expected ErrInvalidPackedProof, got %v", err)
+	}
+	if _, err := VerifySimpleMerkleTreeWithOptions(tree.Root(), values[2], []BytesLike{proof[0]}, SimpleVerifyOptions{PackedProof: packed}); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("Expected ErrInvalidOptions for both proof forms, got %v", err)
+	}
+}
+
+func TestExportClaimsPackedProof(t *testing.T) {
+	tree, err := NewStandardMerkleTree(leafSetValues(5), MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	dir := t.TempDir()
+	if _, err := tree.ExportClaims(dir, ClaimExportOptions{PackedProof: true}); err != nil {
+		t.Fatalf("ExportClaims failed: %v", err)
+	}
+	page := readClaimsPage(t, dir, "claims-00000.json")
+	for _, claim := range page.Claims {
+		packed, err := ToBytes(claim.PackedProof)
+		if err != nil {
+			t.Fatalf("packedProof %q is not hex", claim.PackedProof)
+		}
+		if proof, err := ProofFromPackedBytes(packed); err != nil || !slices.Equal(proof, claim.Proof) {
+			t.Errorf("packedProof %s does not match proof %v", claim.PackedProof, claim.Proof)
+		}
+	}
+
+	if _, err := tree.ExportClaims(dir, ClaimExportOptions{}); err != nil {
+		t.Fatalf("ExportClaims failed: %v", err)
+	}
+	if page := readClaimsPage(t, dir, "claims-00000.json"); page.Claims[0].PackedProof != "" {
+		t.Errorf("packedProof written without being requested: %s", page.Claims[0].PackedProof)
+	}
+}
+
+// decodeABIBytes32Array decodes abi.encode(bytes32[]) the way a contract
+// does: an offset word, then a length word at the offset, then the elements.
+func decodeABIBytes32Array(t *testing.T, encoded []byte) []HexString {
+	t.Helper()
+	if len(encoded) < 2*abiWordSize {
+		t.Fatalf("ABI data of %d bytes is too short", len(encoded))
+	}
+	offset := int(binary.BigEndian.Uint64(encoded[abiWordSize-8 : abiWordSize]))
+	length := int(binary.BigEndian.Uint64(encoded[offset+abiWordSize-8 : offset+abiWordSize]))
+	start := offset + abiWordSize
+	if start+length*abiWordSize != len(encoded) {
+		t.Fatalf("ABI bytes32[] of length %d does not fill %d bytes", length, len(encoded))
+	}
+	proof := make([]HexString, length)
+	for i := range proof {
+		proof[i] = HexString(fmt.Sprintf("0x%x", encoded[start+i*abiWordSize:start+(i+1)*abiWordSize]))
+	}
+	return proof
+}
+
+func TestProofToCalldata(t *testing.T) {
+	tree, err := NewStandardMerkleTree(leafSetValues(13), MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	for i := range 13 {
+		proof, err := tree.GetProof(i)
+		if err != nil {
+			t.Fatalf("Failed to get proof: %v", err)
+		}
+		calldata, err := ProofToCalldata(proof)
+		if err != nil {
+			t.Fatalf("ProofToCalldata failed: %v", err)
+		}
+		encoded, err := ToBytes(HexString(calldata))
+		if err != nil {
+			t.Fatalf("Calldata %s is not hex: %v", calldata, err)
+		}
+		if decoded := decodeABIBytes32Array(t, encoded); !slices.Equal(decoded, proof) {
+			t.Errorf("Proof %d decodes to %v, want %v", i, decoded, proof)
+		}
+
+		words, err := ProofToHexArray(proof)
+		if err != nil {
+			t.Fatalf("ProofToHexArray failed: %v", err)
+		}
+		for j, word := range words {
+			if word != string(proof[j]) {
+				t.Errorf("Word %d is %s, want %s", j, word, proof[j])
+			}
+		}
+	}
+
+	if calldata, err := ProofToCalldata(nil); err != nil || calldata != "0x"+strings.Repeat("0", 62)+"20"+strings.Repeat("0", 64) {
+		t.Errorf("Empty proof calldata %s, %v", calldata, err)
+	}
+	if words, err := ProofToHexArray([]HexString{"0X" + HexString(strings.Repeat("AB", 32))}); err != nil || words[0] != "0x"+strings.Repeat("ab", 32) {
+		t.Errorf("Uppercase node gave %v, %v", words, err)
+	}
+	for _, bad := range [][]HexString{{"0x1234"}, {"0x" + HexString(strings.Repeat("00", 33))}, {"0xzz"}} {
+		if _, err := ProofToCalldata(bad); !errors.Is(err, ErrInvalidNode) {
+			t.Errorf("ProofToCalldata(%v) = %v, want ErrInvalidNode", bad, err)
+		}
+		if _, err := ProofToHexArray(bad); !errors.Is(err, ErrInvalidNode) {
+			t.Errorf("ProofToHexArray(%v) = %v, want ErrInvalidNode", bad, err)
+		}
+	}
+}
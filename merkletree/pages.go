@@ -0,0 +1,281 @@
+package merkletree
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultClaimsPageLimit is the number of claims in a page when
+// PageOptions.Limit is zero.
+const DefaultClaimsPageLimit = 100
+
+// claimsManifestFormat is the format identifier of ExportClaims manifests.
+const claimsManifestFormat = "claims-manifest-v1"
+
+// PageOptions selects a page of claims for ExportClaimsPage.
+type PageOptions struct {
+	// After is the Next token of the previous page. The page starts at the
+	// leaf after the one it names.
+	After string
+
+	// Offset is the position in canonical leaf order of the first claim, for
+	// the first page or for jumping ahead. It cannot be combined with After.
+	Offset int
+
+	// Limit is the most claims in the page. Zero means DefaultClaimsPageLimit.
+	Limit int
+
+	// Requester is recorded with every claim of the page when an issuance
+	// recorder is set, as with ExportClaimFor.
+	Requester string
+
+	// Allow, if set, is called with the leaf hash of each claim before it is
+	// issued. When it refuses a claim the page ends before it, with a Next
+	// token to resume from; when it refuses the first, its error is returned.
+	Allow func(leafHash HexString) error
+}
+
+// ClaimsPage is a run of claims in canonical leaf order. It carries the root
+// and algorithm of the tree, so each page verifies on its own.
+type ClaimsPage[T any] struct {
+	Root      HexString           `json:"root"`              // Root every claim verifies against
+	Algorithm AlgorithmDescriptor `json:"algorithm"`         // Hashes and leaf order of the tree
+	Offset    int                 `json:"offset"`            // Position of the first claim in canonical leaf order
+	Total     int                 `json:"total"`             // Number of leaves in the tree
+	Claims    []Claim[T]          `json:"claims"`            // Claims of the page, in canonical leaf order
+	Next      string              `json:"next,omitempty"`    // After token of the next page; empty on the last page
+	Version   string              `json:"version,omitempty"` // Library version that exported the page
+}
+
+// ExportClaimsPage returns the claims of a page of leaves, in canonical leaf
+// order as yielded by LeafHashes. The order depends only on the tree, so a
+// Next token stays valid across restarts for as long as the tree is the same.
+// A token is the position and hash of the last leaf of its page; one that
+// does not name a leaf of this tree at that position, because the tree
+// changed, fails with ErrInvalidPageToken.
+//
+// An Offset past the last leaf gives an empty page. Invalid options fail with
+// *OptionError values joined with errors.Join.
+func (m *MerkleTreeImpl[T]) ExportClaimsPage(opts PageOptions) (ClaimsPage[T], error) {
+	start, limit, err := m.pageBounds(opts)
+	if err != nil {
+		return ClaimsPage[T]{}, err
+	}
+	return m.claimsPage(start, limit, opts.Requester, opts.Allow)
+}
+
+// pageBounds resolves the first leaf position and the limit of a page.
+func (m *MerkleTreeImpl[T]) pageBounds(opts PageOptions) (start, limit int, err error) {
+	var errs []error
+	if opts.Limit < 0 {
+		errs = append(errs, &OptionError{Option: "Limit", Value: opts.Limit, Reason: "must not be negative"})
+	}
+	if opts.Offset < 0 {
+		errs = append(errs, &OptionError{Option: "Offset", Value: opts.Offset, Reason: "must not be negative"})
+	}
+	if opts.After != "" && opts.Offset != 0 {
+		errs = append(errs, &OptionError{Option: "Offset", Value: opts.Offset, Reason: "cannot be combined with After"})
+	}
+	if err := errors.Join(errs...); err != nil {
+		return 0, 0, err
+	}
+
+	limit = opts.Limit
+	if limit == 0 {
+		limit = DefaultClaimsPageLimit
+	}
+	if opts.After == "" {
+		return opts.Offset, limit, nil
+	}
+	last, err := m.parsePageToken(opts.After)
+	if err != nil {
+		return 0, 0, err
+	}
+	return last + 1, limit, nil
+}
+
+// claimsPage builds the page of at most limit claims starting at leaf
+// position start.
+func (m *MerkleTreeImpl[T]) claimsPage(start, limit int, requester string, allow func(HexString) error) (ClaimsPage[T], error) {
+	leaves := len(m.Values)
+	page := ClaimsPage[T]{
+		Root:      m.Root(),
+		Algorithm: m.algorithm,
+		Offset:    start,
+		Total:     leaves,
+		Claims:    []Claim[T]{},
+		Version:   version,
+	}
+	end := max(start, leaves)
+	if start < leaves {
+		end = start + min(limit, leaves-start)
+	}
+	first := len(m.Tree) - leaves
+	for pos := start; pos < end; pos++ {
+		valueIndex := m.valueIndexAt(first + pos)
+		if allow != nil {
+			if err := allow(m.Tree[first+pos]); err != nil {
+				if pos == start {
+					return ClaimsPage[T]{}, err
+				}
+				end = pos
+				break
+			}
+		}
+		if err := m.validateValueAt(valueIndex); err != nil {
+			return ClaimsPage[T]{}, fmt.Errorf("validation failed: %w", err)
+		}
+		claim, err := m.newClaim(valueIndex, m.siblingPath(m.Values[valueIndex].TreeIndex), requester)
+		if err != nil {
+			return ClaimsPage[T]{}, err
+		}
+		page.Claims = append(page.Claims, claim)
+	}
+	if end < leaves && end > start {
+		page.Next = pageToken(end-1, page.Claims[len(page.Claims)-1].LeafHash)
+	}
+	return page, nil
+}
+
+// valueIndexAt returns the index of the value whose leaf is at treeIndex.
+func (m *MerkleTreeImpl[T]) valueIndexAt(treeIndex int) int {
+	hash := m.Tree[treeIndex].Normalize()
+	lookup := m.hashLookup()
+	for _, i := range m.duplicates[hash] {
+		if m.Values[i].TreeIndex == treeIndex {
+			return i
+		}
+	}
+	return lookup[hash]
+}
+
+// siblingPath returns the proof of the leaf at treeIndex, read straight from
+// Tree; GetProof converts every node first, which is too slow per claim.
+func (m *MerkleTreeImpl[T]) siblingPath(treeIndex int) []HexString {
+	proof := []HexString{}
+	for i := treeIndex; i > 0; i = parentIndex(i) {
+		proof = append(proof, m.Tree[siblingIndex(i)])
+	}
+	return proof
+}
+
+// pageToken returns the token of the page ending at the leaf at position pos.
+func pageToken(pos int, leafHash HexString) string {
+	return strconv.Itoa(pos) + "-" + strings.ToLower(strings.TrimPrefix(string(leafHash), "0x"))
+}
+
+// parsePageToken returns the leaf position a token names, checking that the
+// leaf there has the hash it records.
+func (m *MerkleTreeImpl[T]) parsePageToken(token string) (int, error) {
+	posText, hash, ok := strings.Cut(token, "-")
+	pos, err := strconv.Atoi(posText)
+	if !ok || err != nil || pos < 0 {
+		return 0, fmt.Errorf("%w: %q is not a position and leaf hash", ErrInvalidPageToken, token)
+	}
+	first := len(m.Tree) - len(m.Values)
+	if pos >= len(m.Values) || !strings.EqualFold(strings.TrimPrefix(string(m.Tree[first+pos]), "0x"), hash) {
+		return 0, fmt.Errorf("%w: leaf %d of this tree is not %s", ErrInvalidPageToken, pos, hash)
+	}
+	return pos, nil
+}
+
+// ClaimExportOptions configures ExportClaims.
+type ClaimExportOptions struct {
+	// PageSize is the number of claims per file. Zero means DefaultClaimsPageLimit.
+	PageSize int
+
+	// Requester is recorded with every claim when an issuance recorder is set.
+	Requester string
+
+	// PackedProof adds the packedProof field to every claim, the proof as one
+	// byte string for contracts that take it as `bytes`.
+	PackedProof bool
+}
+
+// ClaimsManifest lists the files written by ExportClaims.
+type ClaimsManifest struct {
+	Format    string               `json:"format"`            // Format version identifier
+	Root      HexString            `json:"root"`              // Root every claim verifies against
+	Algorithm AlgorithmDescriptor  `json:"algorithm"`         // Hashes and leaf order of the tree
+	Total     int                  `json:"total"`             // Number of claims across all files
+	PageSize  int                  `json:"pageSize"`          // Claims per file; the last may have fewer
+	Pages     []ClaimsManifestPage `json:"pages"`             // Files in canonical leaf order
+	Version   string               `json:"version,omitempty"` // Library version that wrote the manifest
+}
+
+// ClaimsManifestPage is one file of an ExportClaims export.
+type ClaimsManifestPage struct {
+	File   string `json:"file"`   // Name of the file, relative to the manifest
+	Offset int    `json:"offset"` // Position of its first claim in canonical leaf order
+	Count  int    `json:"count"`  // Number of claims in it
+	SHA256 string `json:"sha256"` // Hex SHA-256 of the file
+}
+
+// ExportClaims writes the claim of every value to dir as ClaimsPage JSON
+// files of opts.PageSize claims, claims-00000.json onwards, and lists them in
+// manifest.json, which it returns. The files follow canonical leaf order, so
+// exporting the same tree again writes the same files.
+func (m *MerkleTreeImpl[T]) ExportClaims(dir string, opts ClaimExportOptions) (ClaimsManifest, error) {
+	if opts.PageSize < 0 {
+		return ClaimsManifest{}, &OptionError{Option: "PageSize", Value: opts.PageSize, Reason: "must not be negative"}
+	}
+	pageSize := opts.PageSize
+	if pageSize == 0 {
+		pageSize = DefaultClaimsPageLimit
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return ClaimsManifest{}, err
+	}
+
+	manifest := ClaimsManifest{
+		Format:    claimsManifestFormat,
+		Root:      m.Root(),
+		Algorithm: m.algorithm,
+		Total:     len(m.Values),
+		PageSize:  pageSize,
+		Pages:     []ClaimsManifestPage{},
+		Version:   version,
+	}
+	for start := 0; start < len(m.Values); start += pageSize {
+		page, err := m.claimsPage(start, pageSize, opts.Requester, nil)
+		if err != nil {
+			return ClaimsManifest{}, err
+		}
+		if opts.PackedProof {
+			if err := packClaimProofs(page.Claims); err != nil {
+				return ClaimsManifest{}, err
+			}
+		}
+		data, err := json.MarshalIndent(page, "", "  ")
+		if err != nil {
+			return ClaimsManifest{}, err
+		}
+		name := fmt.Sprintf("claims-%05d.json", len(manifest.Pages))
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			return ClaimsManifest{}, err
+		}
+		sum := sha256.Sum256(data)
+		manifest.Pages = append(manifest.Pages, ClaimsManifestPage{
+			File:   name,
+			Offset: start,
+			Count:  len(page.Claims),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return ClaimsManifest{}, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manifest.json"), data, 0o644); err != nil {
+		return ClaimsManifest{}, err
+	}
+	return manifest, nil
+}
@@ -0,0 +1,251 @@
+package merkletree
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// pagedTree builds a sorted standard tree of n distinct values.
+func pagedTree(t *testing.T, n int) *StandardMerkleTree[string] {
+	t.Helper()
+	values := make([]string, n)
+	for i := range values {
+		values[i] = fmt.Sprintf("claimant-%05d", i)
+	}
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(true)})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	return tree
+}
+
+// referenceClaims returns the claim of every leaf of tree in canonical leaf
+// order, checking that each verifies and matches ExportClaim.
+func referenceClaims(t *testing.T, tree *StandardMerkleTree[string]) []Claim[string] {
+	t.Helper()
+	page, err := tree.ExportClaimsPage(PageOptions{Limit: len(tree.Values)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, hash := range tree.LeafHashes {
+		claim := page.Claims[i]
+		if claim.LeafHash != hash || claim.Root != tree.Root() {
+			t.Fatalf("Claim %d is for leaf %s under %s, want %s under %s", i, claim.LeafHash, claim.Root, hash, tree.Root())
+		}
+		if valid, err := claim.Envelope().Verify(tree.NodeHash); err != nil || !valid {
+			t.Fatalf("Claim %d does not verify: %v", i, err)
+		}
+		if i%997 == 0 {
+			want, err := tree.ExportClaim(claim.ValueIndex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := mustMarshal(t, claim); string(got) != string(mustMarshal(t, want)) {
+				t.Fatalf("Paged claim %d differs from ExportClaim:\n%s\n%s", i, got, mustMarshal(t, want))
+			}
+		}
+	}
+	return page.Claims
+}
+
+// checkPagedClaims checks that claims, gathered from pages in order, are the
+// verified reference claims.
+func checkPagedClaims(t *testing.T, reference, claims []Claim[string]) {
+	t.Helper()
+	if len(claims) != len(reference) {
+		t.Fatalf("Pages hold %d claims, tree has %d leaves", len(claims), len(reference))
+	}
+	for i := range claims {
+		if got, want := mustMarshal(t, claims[i]), mustMarshal(t, reference[i]); string(got) != string(want) {
+			t.Fatalf("Claim %d is\n%s\nwant\n%s", i, got, want)
+		}
+	}
+}
+
+func TestExportClaimsPageCoversTree(t *testing.T) {
+	tree := pagedTree(t, 10000)
+	reference := referenceClaims(t, tree)
+
+	for _, limit := range []int{1, 7, 100, 999, 10000, 20000} {
+		var claims []Claim[string]
+		opts := PageOptions{Limit: limit}
+		pages := 0
+		for {
+			page, err := tree.ExportClaimsPage(opts)
+			if err != nil {
+				t.Fatalf("Limit %d, page %d: %v", limit, pages, err)
+			}
+			if page.Root != tree.Root() || page.Algorithm != tree.Algorithm() || page.Total != 10000 || page.Offset != len(claims) {
+				t.Fatalf("Limit %d, page %d: header %s %+v offset %d total %d", limit, pages, page.Root, page.Algorithm, page.Offset, page.Total)
+			}
+			if len(page.Claims) > limit {
+				t.Fatalf("Limit %d: page of %d claims", limit, len(page.Claims))
+			}
+			claims = append(claims, page.Claims...)
+			pages++
+			if page.Next == "" {
+				break
+			}
+			opts = PageOptions{After: page.Next, Limit: limit}
+		}
+		if want := (10000 + limit - 1) / limit; pages != want {
+			t.Errorf("Limit %d: %d pages, want %d", limit, pages, want)
+		}
+		checkPagedClaims(t, reference, claims)
+	}
+}
+
+func TestExportClaimsPageTokenAcrossRebuild(t *testing.T) {
+	first := pagedTree(t, 50)
+	page, err := first.ExportClaimsPage(PageOptions{Limit: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A new process building the same tree resumes where the first left off
+	rebuilt := pagedTree(t, 50)
+	next, err := rebuilt.ExportClaimsPage(PageOptions{After: page.Next, Limit: 20})
+	if err != nil {
+		t.Fatalf("Token from the first tree rejected by the rebuilt one: %v", err)
+	}
+	if next.Offset != 20 || next.Claims[0].LeafHash != first.Tree[len(first.Tree)-50+20] {
+		t.Errorf("Resumed page starts at %d with %s", next.Offset, next.Claims[0].LeafHash)
+	}
+
+	other := pagedTree(t, 51)
+	if _, err := other.ExportClaimsPage(PageOptions{After: page.Next}); !errors.Is(err, ErrInvalidPageToken) {
+		t.Errorf("Expected ErrInvalidPageToken for a token of another tree, got %v", err)
+	}
+	for _, token := range []string{"garbage", "-1-00", "19", "x-" + string(first.Tree[0]), "100000-00"} {
+		if _, err := first.ExportClaimsPage(PageOptions{After: token}); !errors.Is(err, ErrInvalidPageToken) {
+			t.Errorf("Token %q: expected ErrInvalidPageToken, got %v", token, err)
+		}
+	}
+}
+
+func TestExportClaimsPageOptions(t *testing.T) {
+	tree := pagedTree(t, 10)
+
+	_, err := tree.ExportClaimsPage(PageOptions{Limit: -1, Offset: -1})
+	var optErr *OptionError
+	if !errors.As(err, &optErr) || !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("Expected option errors, got %v", err)
+	}
+	if _, err := tree.ExportClaimsPage(PageOptions{After: "0-00", Offset: 3}); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("Expected ErrInvalidOptions for After with Offset, got %v", err)
+	}
+
+	page, err := tree.ExportClaimsPage(PageOptions{Offset: 25})
+	if err != nil || len(page.Claims) != 0 || page.Next != "" {
+		t.Errorf("Offset past the end: %d claims, next %q, err %v", len(page.Claims), page.Next, err)
+	}
+	if data := mustMarshal(t, page); !json.Valid(data) || string(mustMarshal(t, page.Claims)) != "[]" {
+		t.Errorf("Empty page should encode claims as [], got %s", data)
+	}
+	if page, _ := tree.ExportClaimsPage(PageOptions{}); len(page.Claims) != 10 {
+		t.Errorf("Default limit gave %d claims", len(page.Claims))
+	}
+}
+
+func TestExportClaimsPageAllow(t *testing.T) {
+	tree := pagedTree(t, 10)
+	refusal := errors.New("quota used up")
+
+	issued := 0
+	allow := func(HexString) error {
+		if issued == 4 {
+			return refusal
+		}
+		issued++
+		return nil
+	}
+	page, err := tree.ExportClaimsPage(PageOptions{Limit: 10, Allow: allow})
+	if err != nil || len(page.Claims) != 4 || page.Next == "" {
+		t.Fatalf("Refusal after 4 claims: %d claims, next %q, err %v", len(page.Claims), page.Next, err)
+	}
+	if _, err := tree.ExportClaimsPage(PageOptions{After: page.Next, Allow: allow}); !errors.Is(err, refusal) {
+		t.Errorf("Refusing the first claim should return the refusal, got %v", err)
+	}
+	issued = 0
+	rest, err := tree.ExportClaimsPage(PageOptions{After: page.Next, Allow: allow})
+	if err != nil || rest.Offset != 4 || len(rest.Claims) != 4 {
+		t.Errorf("Resumed page at %d with %d claims, err %v", rest.Offset, len(rest.Claims), err)
+	}
+}
+
+func TestExportClaims(t *testing.T) {
+	tree := pagedTree(t, 10000)
+	dir := filepath.Join(t.TempDir(), "claims")
+
+	manifest, err := tree.ExportClaims(dir, ClaimExportOptions{PageSize: 777})
+	if err != nil {
+		t.Fatalf("ExportClaims failed: %v", err)
+	}
+	if manifest.Total != 10000 || len(manifest.Pages) != 13 || manifest.Root != tree.Root() {
+		t.Fatalf("Manifest has %d claims in %d pages under %s", manifest.Total, len(manifest.Pages), manifest.Root)
+	}
+
+	var written ClaimsManifest
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil || json.Unmarshal(data, &written) != nil || written.Pages[12].SHA256 != manifest.Pages[12].SHA256 {
+		t.Fatalf("manifest.json does not match the returned manifest: %v", err)
+	}
+
+	var claims []Claim[string]
+	for _, entry := range written.Pages {
+		data, err := os.ReadFile(filepath.Join(dir, entry.File))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != entry.SHA256 {
+			t.Errorf("%s does not match its checksum", entry.File)
+		}
+		var page ClaimsPage[string]
+		if err := json.Unmarshal(data, &page); err != nil {
+			t.Fatal(err)
+		}
+		if page.Offset != entry.Offset || len(page.Claims) != entry.Count || page.Root != written.Root {
+			t.Errorf("%s holds %d claims at %d, manifest says %d at %d", entry.File, len(page.Claims), page.Offset, entry.Count, entry.Offset)
+		}
+		claims = append(claims, page.Claims...)
+	}
+	checkPagedClaims(t, referenceClaims(t, tree), claims)
+
+	again := filepath.Join(t.TempDir(), "again")
+	if second, err := tree.ExportClaims(again, ClaimExportOptions{PageSize: 777}); err != nil || second.Pages[5].SHA256 != manifest.Pages[5].SHA256 {
+		t.Errorf("Exporting the same tree again should write the same files: %v", err)
+	}
+	if _, err := tree.ExportClaims(again, ClaimExportOptions{PageSize: -1}); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("Expected ErrInvalidOptions for a negative page size, got %v", err)
+	}
+}
+
+func TestExportClaimsPageDuplicates(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"a", "b", "a", "c", "a"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[int]bool{}
+	for opts := (PageOptions{Limit: 2}); ; {
+		page, err := tree.ExportClaimsPage(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, claim := range page.Claims {
+			seen[claim.ValueIndex] = true
+		}
+		if page.Next == "" {
+			break
+		}
+		opts.After = page.Next
+	}
+	if len(seen) != 5 {
+		t.Errorf("Pages cover value indices %v, want all 5", seen)
+	}
+}
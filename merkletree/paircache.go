@@ -0,0 +1,115 @@
+package merkletree
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultPairCacheSize is the number of node hashes NewPairCache keeps when
+// given a size of zero or less.
+const DefaultPairCacheSize = 1 << 16
+
+// PairCacheStats counts the lookups of a PairCache.
+type PairCacheStats struct {
+	Hits      uint64 // Node hashes answered from the cache
+	Misses    uint64 // Node hashes computed
+	Evictions uint64 // Entries dropped to stay within the size
+	Len       int    // Entries held now
+}
+
+// HitRate returns the fraction of lookups answered from the cache, or 0
+// before the first lookup.
+func (s PairCacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// PairCache memoizes node hashes by their pair of children, keeping the most
+// recently used. Proofs from one tree share the siblings near the root, so a
+// batch of them recomputes the same upper nodes over and over; with a cache
+// each is hashed once. It is safe for concurrent use, and can be shared by
+// batches verified with the same node hash.
+type PairCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element // Values are *pairEntry
+	recent  *list.List               // Most recently used first
+	stats   PairCacheStats
+}
+
+// pairEntry is one cached node hash.
+type pairEntry struct {
+	key  string
+	hash HexString
+}
+
+// NewPairCache returns an empty cache holding up to size node hashes, or
+// DefaultPairCacheSize if size is zero or less.
+func NewPairCache(size int) *PairCache {
+	if size <= 0 {
+		size = DefaultPairCacheSize
+	}
+	return &PairCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		recent:  list.New(),
+	}
+}
+
+// Stats returns the lookup counts so far.
+func (c *PairCache) Stats() PairCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Len = c.recent.Len()
+	return stats
+}
+
+// wrap returns nodeHash answering from the cache. Keys are the exact pair of
+// children, so node hashes that do not sort their inputs stay correct. The
+// cache must only ever wrap one node hash.
+func (c *PairCache) wrap(nodeHash NodeHash) NodeHash {
+	return func(a, b BytesLike) HexString {
+		// Proof processing always passes HexStrings, which need no conversion
+		left, okA := a.(HexString)
+		right, okB := b.(HexString)
+		if !okA || !okB {
+			var errA, errB error
+			left, errA = ToHex(a)
+			right, errB = ToHex(b)
+			if errA != nil || errB != nil {
+				return nodeHash(a, b)
+			}
+		}
+		key := string(left) + string(right)
+
+		c.mu.Lock()
+		if e, ok := c.entries[key]; ok {
+			c.recent.MoveToFront(e)
+			c.stats.Hits++
+			c.mu.Unlock()
+			return e.Value.(*pairEntry).hash
+		}
+		c.stats.Misses++
+		c.mu.Unlock()
+
+		// Hash outside the lock; a concurrent miss on the same pair only
+		// computes it twice
+		hash := nodeHash(left, right)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if _, ok := c.entries[key]; !ok {
+			c.entries[key] = c.recent.PushFront(&pairEntry{key: key, hash: hash})
+			if c.recent.Len() > c.size {
+				oldest := c.recent.Back()
+				c.recent.Remove(oldest)
+				delete(c.entries, oldest.Value.(*pairEntry).key)
+				c.stats.Evictions++
+			}
+		}
+		return hash
+	}
+}
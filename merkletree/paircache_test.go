@@ -0,0 +1,126 @@
+package merkletree
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestPairCacheMatchesUncached(t *testing.T) {
+	items := envelopes(t, 200)
+	rng := rand.New(rand.NewSource(1))
+	// A small cache shared across batches, so entries are evicted and reused
+	cache := NewPairCache(64)
+
+	for round := 0; round < 20; round++ {
+		batch := make([]ProofEnvelope, 50)
+		for i := range batch {
+			batch[i] = items[rng.Intn(len(items))]
+			batch[i].Proof = append([]HexString(nil), batch[i].Proof...)
+			switch rng.Intn(8) {
+			case 0: // Wrong sibling
+				if len(batch[i].Proof) > 0 {
+					batch[i].Proof[rng.Intn(len(batch[i].Proof))] = items[rng.Intn(len(items))].LeafHash
+				}
+			case 1: // Wrong root
+				batch[i].Root = items[rng.Intn(len(items))].LeafHash
+			case 2: // Malformed node
+				batch[i].LeafHash = "0x1234"
+			}
+		}
+
+		want := BatchVerify(batch, nil)
+		got, err := BatchVerifyWithOptions(context.Background(), batch, BatchOptions{PairCache: cache})
+		if err != nil {
+			t.Fatalf("BatchVerifyWithOptions failed: %v", err)
+		}
+		for i := range want {
+			if got[i].Valid != want[i].Valid || got[i].Evaluated != want[i].Evaluated || (got[i].Err == nil) != (want[i].Err == nil) {
+				t.Fatalf("Round %d item %d: cached %+v, uncached %+v", round, i, got[i], want[i])
+			}
+		}
+	}
+
+	stats := cache.Stats()
+	if stats.Hits == 0 || stats.Evictions == 0 || stats.Len != 64 {
+		t.Errorf("Stats = %+v, want hits, evictions and a full cache", stats)
+	}
+}
+
+func TestPairCacheHitRate(t *testing.T) {
+	items := envelopes(t, 256)
+	cache := NewPairCache(0)
+	results, err := BatchVerifyWithOptions(context.Background(), items, BatchOptions{PairCache: cache})
+	if err != nil {
+		t.Fatalf("BatchVerifyWithOptions failed: %v", err)
+	}
+	for i, r := range results {
+		if !r.Valid {
+			t.Fatalf("Item %d does not verify", i)
+		}
+	}
+
+	// 256 proofs of 8 nodes: every pair a proof hashes is either a leaf with
+	// its sibling, computed once per leaf, or a node with its sibling, which
+	// each leaf below the node computes again
+	stats := cache.Stats()
+	if stats.Hits+stats.Misses != 256*8 {
+		t.Fatalf("Lookups = %d, want %d", stats.Hits+stats.Misses, 256*8)
+	}
+	if rate := stats.HitRate(); rate < 0.7 {
+		t.Errorf("Hit rate %.2f, want at least 0.7", rate)
+	}
+	if (PairCacheStats{}).HitRate() != 0 {
+		t.Errorf("An unused cache should have a zero hit rate")
+	}
+}
+
+// benchmarkBatch returns 10k envelopes of random values of a tree over 1M leaves.
+func benchmarkBatch(b *testing.B) []ProofEnvelope {
+	b.Helper()
+	const leaves = 1 << 20
+	hashes := make([]BytesLike, leaves)
+	for i := range hashes {
+		hashes[i] = StandardLeafHash(fmt.Sprint(i))
+	}
+	tree, err := MakeMerkleTree(hashes, StandardNodeHash)
+	if err != nil {
+		b.Fatal(err)
+	}
+	nodes := make([]BytesLike, len(tree))
+	for i, node := range tree {
+		nodes[i] = node
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	batch := make([]ProofEnvelope, 10000)
+	for i := range batch {
+		index := len(tree) - 1 - rng.Intn(leaves)
+		proof, err := GetProof(nodes, index)
+		if err != nil {
+			b.Fatal(err)
+		}
+		batch[i] = ProofEnvelope{Root: tree[0], LeafHash: tree[index], Proof: proof}
+	}
+	return batch
+}
+
+func BenchmarkBatchVerifyPairCache(b *testing.B) {
+	batch := benchmarkBatch(b)
+
+	b.Run("uncached", func(b *testing.B) {
+		for range b.N {
+			BatchVerify(batch, nil)
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		var stats PairCacheStats
+		for range b.N {
+			cache := NewPairCache(len(batch) * 4)
+			BatchVerifyWithOptions(context.Background(), batch, BatchOptions{PairCache: cache})
+			stats = cache.Stats()
+		}
+		b.ReportMetric(stats.HitRate(), "hit-rate")
+	})
+}
@@ -0,0 +1,113 @@
+package merkletree
+
+import (
+	"fmt"
+	"sync"
+)
+
+// hashLeaves hashes every value with a pool of at most workers goroutines.
+// The result is in the order of values regardless of completion order. It
+// stops early with the error of guard when a resource limit trips.
+func hashLeaves[T any](values []T, leafHash func(T) HexString, workers int, guard *buildGuard) ([]HexString, error) {
+	hashes := make([]HexString, len(values))
+	if workers <= 1 || len(values) <= 1 {
+		for i, v := range values {
+			if i%guardInterval == 0 {
+				if err := guard.check(StageLeafHashing, i, len(values), i%heapPollInterval == 0); err != nil {
+					return nil, err
+				}
+			}
+			hashes[i] = leafHash(v)
+		}
+		return hashes, nil
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, len(values)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				hashes[i] = leafHash(values[i])
+			}
+		}()
+	}
+	for i := range values {
+		if i%guardInterval == 0 {
+			if err := guard.check(StageLeafHashing, i, len(values), i%heapPollInterval == 0); err != nil {
+				close(jobs)
+				wg.Wait()
+				return nil, err
+			}
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return hashes, nil
+}
+
+// hashInternalNodes fills the internal nodes of tree, whose leaves are already
+// in place, one level at a time from the bottom up. Each level only depends on
+// the level below it, so its nodes are split into chunks hashed concurrently.
+// Resource limits are checked between levels and every guardInterval nodes.
+// A node hash that returns no hash fails the build with ErrHashFailed.
+func hashInternalNodes(tree []HexString, internal int, nodeHash NodeHash, workers int, guard *buildGuard) error {
+	if workers <= 1 {
+		for i := internal - 1; i >= 0; i-- {
+			if newLevel := (i+1)&(i+2) == 0; newLevel || i%guardInterval == 0 {
+				if err := guard.check(StageNodeHashing, internal-1-i, internal, newLevel); err != nil {
+					return err
+				}
+			}
+			tree[i] = nodeHash(tree[LeftChildIndex(i)], tree[RightChildIndex(i)])
+			if tree[i] == "" {
+				return nodeHashFailed(i)
+			}
+		}
+		return nil
+	}
+
+	// Level d holds indices [2^d-1, 2^(d+1)-2]; find the deepest one with internal nodes.
+	levelStart := 0
+	for 2*levelStart+1 < internal {
+		levelStart = 2*levelStart + 1
+	}
+
+	for ; ; levelStart = (levelStart - 1) / 2 {
+		levelEnd := min(2*levelStart+1, internal) // exclusive
+		count := levelEnd - levelStart
+		if err := guard.check(StageNodeHashing, internal-levelEnd, internal, true); err != nil {
+			return err
+		}
+		chunk := (count + workers - 1) / workers
+
+		var wg sync.WaitGroup
+		for start := levelStart; start < levelEnd; start += chunk {
+			end := min(start+chunk, levelEnd)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := start; i < end; i++ {
+					tree[i] = nodeHash(tree[LeftChildIndex(i)], tree[RightChildIndex(i)])
+				}
+			}()
+		}
+		wg.Wait()
+		for i := levelStart; i < levelEnd; i++ {
+			if tree[i] == "" {
+				return nodeHashFailed(i)
+			}
+		}
+
+		if levelStart == 0 {
+			return nil
+		}
+	}
+}
+
+// nodeHashFailed reports that hashing the children of node i returned no hash.
+func nodeHashFailed(i int) error {
+	return fmt.Errorf("%w: the node hash of the children of node %d returned no hash", ErrHashFailed, i)
+}
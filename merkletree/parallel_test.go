@@ -0,0 +1,126 @@
+package merkletree
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParallelTreeIsDeterministic(t *testing.T) {
+	values := make([]string, 1000)
+	for i := range values {
+		values[i] = fmt.Sprintf("value-%d", i)
+	}
+
+	reference, err := NewStandardMerkleTree(values, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	settings := []MerkleTreeOptions{
+		{Parallelism: 4},
+		{LeafHashParallelism: 16, NodeHashParallelism: 3},
+		{Parallelism: 2, LeafHashParallelism: 64},
+		{NodeHashParallelism: 1000},
+	}
+	for _, options := range settings {
+		for run := 0; run < 3; run++ {
+			tree, err := NewStandardMerkleTree(values, options)
+			if err != nil {
+				t.Fatalf("Failed to create tree with %+v: %v", options, err)
+			}
+			if tree.Root() != reference.Root() {
+				t.Fatalf("Root with %+v is %s, want %s", options, tree.Root(), reference.Root())
+			}
+			for i := range tree.Tree {
+				if tree.Tree[i] != reference.Tree[i] {
+					t.Fatalf("Node %d differs with %+v", i, options)
+				}
+			}
+		}
+	}
+}
+
+func TestLeafHashParallelismScales(t *testing.T) {
+	values := make([]BytesLike, 32)
+	for i := range values {
+		values[i] = fmt.Sprintf("value-%d", i)
+	}
+	// The sleep keeps each call in flight long enough for the others to start
+	var inFlight, peak atomic.Int32
+	slowLeafHash := func(v BytesLike) HexString {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(time.Millisecond)
+		return FormatLeaf(v)
+	}
+
+	build := func(options MerkleTreeOptions) (HexString, int32) {
+		peak.Store(0)
+		tree, _, err := PrepareMerkleTree(values, options, slowLeafHash, StandardNodeHash)
+		if err != nil {
+			t.Fatalf("Failed to prepare tree: %v", err)
+		}
+		return tree[0], peak.Load()
+	}
+
+	sequentialRoot, sequential := build(MerkleTreeOptions{})
+	parallelRoot, parallel := build(MerkleTreeOptions{LeafHashParallelism: 16, NodeHashParallelism: 2})
+
+	if parallelRoot != sequentialRoot {
+		t.Errorf("Parallel root %s differs from sequential root %s", parallelRoot, sequentialRoot)
+	}
+	if sequential != 1 {
+		t.Errorf("Sequential build had %d leaf hashes in flight at once, want 1", sequential)
+	}
+	if parallel <= 1 || parallel > 16 {
+		t.Errorf("LeafHashParallelism=16 had at most %d leaf hashes in flight at once, want 2 to 16", parallel)
+	}
+}
+
+func TestParallelismOptionsValidate(t *testing.T) {
+	err := MerkleTreeOptions{Parallelism: -1, LeafHashParallelism: -2, NodeHashParallelism: -3}.Validate()
+	if got := len(collectAs[*OptionError](err)); got != 3 {
+		t.Errorf("Expected 3 option errors, got %d: %v", got, err)
+	}
+
+	options := MerkleTreeOptions{Parallelism: 8, LeafHashParallelism: 64}
+	if options.leafHashWorkers() != 64 || options.nodeHashWorkers() != 8 {
+		t.Errorf("Unexpected workers: leaf %d, node %d", options.leafHashWorkers(), options.nodeHashWorkers())
+	}
+	if (MerkleTreeOptions{}).nodeHashWorkers() != 1 {
+		t.Error("Unset parallelism should run on one goroutine")
+	}
+}
+
+func TestNodeHashFailure(t *testing.T) {
+	values := make([]BytesLike, 64)
+	for i := range values {
+		values[i] = fmt.Sprintf("value-%d", i)
+	}
+	for _, parallelism := range []int{1, 4} {
+		// The tenth pair hashed, deep in the tree, gets no hash
+		var calls atomic.Int32
+		failing := func(a, b BytesLike) HexString {
+			if calls.Add(1) == 10 {
+				return ""
+			}
+			return StandardNodeHash(a, b)
+		}
+		_, err := NewSimpleMerkleTree(values, SimpleMerkleTreeOptions{
+			MerkleTreeOptions: MerkleTreeOptions{Parallelism: parallelism},
+			NodeHash:          failing,
+		})
+		if !errors.Is(err, ErrHashFailed) || Code(err) != CodeHashFailed {
+			t.Errorf("Parallelism %d: expected ErrHashFailed, got %v", parallelism, err)
+		}
+	}
+
+	if _, err := MakeMerkleTree([]BytesLike{"", StandardLeafHash("a")}, StandardNodeHash); !errors.Is(err, ErrHashFailed) {
+		t.Errorf("Expected ErrHashFailed for an empty leaf, got %v", err)
+	}
+}
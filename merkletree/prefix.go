@@ -0,0 +1,117 @@
+package merkletree
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultMaxPrefixResults is the number of matches FindByHashPrefix returns
+// when MerkleTreeOptions.MaxPrefixResults is not set.
+const DefaultMaxPrefixResults = 100
+
+// prefixEntry pairs a leaf hash with the index of its value.
+type prefixEntry struct {
+	hash       string // Lowercase hex digits without the "0x" prefix
+	valueIndex int
+}
+
+// sortedLeafEntries returns every leaf hash with its value index, sorted by hash.
+func (m *MerkleTreeImpl[T]) sortedLeafEntries() []prefixEntry {
+	entries := make([]prefixEntry, len(m.Values))
+	for i, v := range m.Values {
+		entries[i] = prefixEntry{
+			hash:       strings.ToLower(strings.TrimPrefix(string(m.Tree[v.TreeIndex]), "0x")),
+			valueIndex: i,
+		}
+	}
+	sortPrefixEntries(entries)
+	return entries
+}
+
+// sortPrefixEntries orders entries by hash, breaking ties by value index.
+func sortPrefixEntries(entries []prefixEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].hash != entries[j].hash {
+			return entries[i].hash < entries[j].hash
+		}
+		return entries[i].valueIndex < entries[j].valueIndex
+	})
+}
+
+// normalizeHashPrefix validates a hex prefix and returns its lowercase digits.
+// The "0x" prefix is optional and odd-length (nibble) prefixes are allowed.
+func normalizeHashPrefix(prefix string) (string, error) {
+	digits := strings.TrimSpace(prefix)
+	if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+		digits = digits[2:]
+	}
+	if digits == "" {
+		return "", fmt.Errorf("%w: %q is empty", ErrInvalidHashPrefix, prefix)
+	}
+	if len(digits) > 64 {
+		return "", fmt.Errorf("%w: %q is longer than a 32-byte hash", ErrInvalidHashPrefix, prefix)
+	}
+	for i, c := range digits {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return "", fmt.Errorf("%w: invalid hex character %q at position %d", ErrInvalidHashPrefix, c, i)
+		}
+	}
+	return strings.ToLower(digits), nil
+}
+
+// FindByHashPrefix returns the indices of the values whose leaf hash starts
+// with the given hex prefix, ordered by leaf hash.
+// The prefix may omit "0x" and may have an odd number of digits.
+// At most MaxPrefixResults indices are returned.
+//
+// With the PrefixIndex option the lookup is a binary search over the sorted
+// leaf hashes; without it every leaf is scanned, which costs O(n) per call.
+func (m *MerkleTreeImpl[T]) FindByHashPrefix(prefix string) ([]int, error) {
+	digits, err := normalizeHashPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := m.maxPrefixResults
+	if limit <= 0 {
+		limit = DefaultMaxPrefixResults
+	}
+
+	entries := m.sortedPrefixIndex()
+	if entries == nil {
+		return m.scanHashPrefix(digits, limit), nil
+	}
+
+	start := sort.Search(len(entries), func(i int) bool {
+		return entries[i].hash >= digits
+	})
+
+	var matches []int
+	for i := start; i < len(entries) && len(matches) < limit; i++ {
+		if !strings.HasPrefix(entries[i].hash, digits) {
+			break
+		}
+		matches = append(matches, entries[i].valueIndex)
+	}
+	return matches, nil
+}
+
+// scanHashPrefix is the unindexed fallback of FindByHashPrefix.
+// It visits every leaf once and orders the matches like the index would.
+func (m *MerkleTreeImpl[T]) scanHashPrefix(digits string, limit int) []int {
+	var found []prefixEntry
+	for i, v := range m.Values {
+		hash := strings.ToLower(strings.TrimPrefix(string(m.Tree[v.TreeIndex]), "0x"))
+		if strings.HasPrefix(hash, digits) {
+			found = append(found, prefixEntry{hash: hash, valueIndex: i})
+		}
+	}
+	sortPrefixEntries(found)
+
+	var matches []int
+	for i := 0; i < len(found) && i < limit; i++ {
+		matches = append(matches, found[i].valueIndex)
+	}
+	return matches
+}
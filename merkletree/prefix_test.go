@@ -0,0 +1,166 @@
+package merkletree
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// prefixTestTrees builds the same tree with and without the prefix index.
+func prefixTestTrees(t *testing.T, maxResults int) (indexed, scanned *StandardMerkleTree[string]) {
+	t.Helper()
+	values := make([]string, 64)
+	for i := range values {
+		values[i] = fmt.Sprintf("value-%d", i)
+	}
+
+	var err error
+	indexed, err = NewStandardMerkleTree(values, MerkleTreeOptions{
+		SortLeaves: Bool(true), PrefixIndex: true, MaxPrefixResults: maxResults,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create indexed tree: %v", err)
+	}
+	scanned, err = NewStandardMerkleTree(values, MerkleTreeOptions{
+		SortLeaves: Bool(true), MaxPrefixResults: maxResults,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create unindexed tree: %v", err)
+	}
+	return indexed, scanned
+}
+
+// leafHashOf returns the leaf hash of the value at index i without "0x".
+func leafHashOf(m *MerkleTreeImpl[string], i int) string {
+	return strings.TrimPrefix(string(m.Tree[m.Values[i].TreeIndex]), "0x")
+}
+
+func TestFindByHashPrefix(t *testing.T) {
+	indexed, scanned := prefixTestTrees(t, 0)
+	target := 17
+	hash := leafHashOf(&indexed.MerkleTreeImpl, target)
+
+	tests := []struct {
+		name   string
+		prefix string
+	}{
+		{"full hash", "0x" + hash},
+		{"byte prefix without 0x", hash[:8]},
+		{"nibble prefix", hash[:5]},
+		{"single nibble", hash[:1]},
+		{"uppercase", "0X" + strings.ToUpper(hash[:6])},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := indexed.FindByHashPrefix(tt.prefix)
+			if err != nil {
+				t.Fatalf("FindByHashPrefix failed: %v", err)
+			}
+
+			found := false
+			for _, i := range got {
+				if i == target {
+					found = true
+				}
+				if !strings.HasPrefix(leafHashOf(&indexed.MerkleTreeImpl, i), strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(tt.prefix, "0x"), "0X"))) {
+					t.Errorf("Index %d does not match prefix %s", i, tt.prefix)
+				}
+			}
+			if !found {
+				t.Errorf("Expected index %d among %v", target, got)
+			}
+
+			// The linear scan must agree with the index
+			want, err := scanned.FindByHashPrefix(tt.prefix)
+			if err != nil {
+				t.Fatalf("Scan failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Index returned %v, scan returned %v", got, want)
+			}
+		})
+	}
+}
+
+func TestFindByHashPrefixAmbiguous(t *testing.T) {
+	indexed, scanned := prefixTestTrees(t, 0)
+
+	// With 64 leaves, every single hex digit matches about four leaves
+	total := 0
+	for _, digit := range "0123456789abcdef" {
+		got, err := indexed.FindByHashPrefix(string(digit))
+		if err != nil {
+			t.Fatalf("FindByHashPrefix(%c) failed: %v", digit, err)
+		}
+		want, _ := scanned.FindByHashPrefix(string(digit))
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Prefix %c: index returned %v, scan returned %v", digit, got, want)
+		}
+		total += len(got)
+	}
+
+	if total != 64 {
+		t.Errorf("Single-digit prefixes should partition all 64 leaves, got %d", total)
+	}
+}
+
+func TestFindByHashPrefixNoMatch(t *testing.T) {
+	indexed, scanned := prefixTestTrees(t, 0)
+
+	// Find a two-byte prefix that no leaf starts with
+	var missing string
+	for i := 0; i < 0x10000 && missing == ""; i++ {
+		candidate := fmt.Sprintf("%04x", i)
+		if got, _ := scanned.FindByHashPrefix(candidate); len(got) == 0 {
+			missing = candidate
+		}
+	}
+
+	for _, tree := range []*StandardMerkleTree[string]{indexed, scanned} {
+		got, err := tree.FindByHashPrefix(missing)
+		if err != nil {
+			t.Fatalf("FindByHashPrefix failed: %v", err)
+		}
+		if len(got) != 0 {
+			t.Errorf("Expected no matches for %s, got %v", missing, got)
+		}
+	}
+}
+
+func TestFindByHashPrefixMaxResults(t *testing.T) {
+	indexed, scanned := prefixTestTrees(t, 3)
+
+	for _, tree := range []*StandardMerkleTree[string]{indexed, scanned} {
+		capped := false
+		for _, digit := range "0123456789abcdef" {
+			got, err := tree.FindByHashPrefix(string(digit))
+			if err != nil {
+				t.Fatalf("FindByHashPrefix failed: %v", err)
+			}
+			if len(got) > 3 {
+				t.Errorf("Results for %c exceed the cap: %v", digit, got)
+			}
+			if len(got) == 3 {
+				capped = true
+			}
+		}
+
+		// 64 leaves over 16 digits guarantees some digit matches at least 4 leaves
+		if !capped {
+			t.Error("Expected at least one prefix to hit the cap")
+		}
+	}
+}
+
+func TestFindByHashPrefixInvalid(t *testing.T) {
+	indexed, _ := prefixTestTrees(t, 0)
+
+	for _, prefix := range []string{"", "0x", "xyz", "0x12g", strings.Repeat("a", 65)} {
+		if _, err := indexed.FindByHashPrefix(prefix); !errors.Is(err, ErrInvalidHashPrefix) {
+			t.Errorf("Prefix %q: expected ErrInvalidHashPrefix, got %v", prefix, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package merkletree
+
+// DefaultPregenerateMaxLeaves is the largest tree whose proofs are
+// pre-generated when MerkleTreeOptions.PregenerateMaxLeaves is zero.
+const DefaultPregenerateMaxLeaves = 1 << 16
+
+// maxPregenerateLeaves keeps every node index of a pre-generated tree within an int32.
+const maxPregenerateLeaves = 1 << 30
+
+// proofTable holds the proof of every value as indices into Tree. The proof
+// of value i is the nodes at nodes[offsets[i]:offsets[i+1]]; storing indices
+// rather than hashes keeps each shared sibling stored once, in Tree.
+type proofTable struct {
+	offsets []int32
+	nodes   []int32
+}
+
+// pregenerateLimit returns the resolved PregenerateMaxLeaves.
+func (o MerkleTreeOptions) pregenerateLimit() int {
+	if o.PregenerateMaxLeaves <= 0 {
+		return DefaultPregenerateMaxLeaves
+	}
+	return o.PregenerateMaxLeaves
+}
+
+// configurePregeneration pre-generates every proof if the options ask for it
+// and the tree is within the limit.
+func (m *MerkleTreeImpl[T]) configurePregeneration(options MerkleTreeOptions) {
+	m.proofs = nil
+	m.pregenerateLimit = 0
+	if options.PregenerateProofs {
+		m.pregenerateLimit = options.pregenerateLimit()
+		m.pregenerate()
+	}
+}
+
+// pregenerate builds the proof table, or drops it if the tree has grown past
+// the limit.
+func (m *MerkleTreeImpl[T]) pregenerate() {
+	if len(m.Values) > m.pregenerateLimit {
+		m.proofs = nil
+		return
+	}
+	table := &proofTable{
+		offsets: make([]int32, 0, len(m.Values)+1),
+		nodes:   make([]int32, 0, sumLeafDepths(len(m.Values))),
+	}
+	table.offsets = append(table.offsets, 0)
+	for _, v := range m.Values {
+		for i := v.TreeIndex; i > 0; i = parentIndex(i) {
+			table.nodes = append(table.nodes, int32(siblingIndex(i)))
+		}
+		table.offsets = append(table.offsets, int32(len(table.nodes)))
+	}
+	m.proofs = table
+}
+
+// proof materializes the proof of the value at valueIndex. Like GetProof it
+// returns nil for a single-leaf tree.
+func (t *proofTable) proof(tree []HexString, valueIndex int) []HexString {
+	indices := t.nodes[t.offsets[valueIndex]:t.offsets[valueIndex+1]]
+	if len(indices) == 0 {
+		return nil
+	}
+	proof := make([]HexString, len(indices))
+	for k, i := range indices {
+		proof[k] = tree[i]
+	}
+	return proof
+}
+
+// ProofsPregenerated reports whether the proofs of the tree were generated
+// when it was built, so GetProof serves them without touching the rest of the tree.
+func (m *MerkleTreeImpl[T]) ProofsPregenerated() bool {
+	return m.proofs != nil
+}
+
+// sumLeafDepths returns the total proof length of a tree of n leaves, whose
+// leaves are the nodes n-1 to 2n-2 of the flat array.
+func sumLeafDepths(n int) int {
+	total := 0
+	for first, last := n-1, 2*n-2; first <= last; {
+		depth := nodeDepth(first)
+		levelEnd := min(last, 1<<(depth+1)-2) // Last node at this depth
+		total += (levelEnd - first + 1) * depth
+		first = levelEnd + 1
+	}
+	return total
+}
@@ -0,0 +1,173 @@
+package merkletree
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestPregenerateProofsMatchGetProof(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 5, 8, 13, 100} {
+		values := make([]string, n)
+		for i := range values {
+			values[i] = fmt.Sprintf("value-%d", i%7) // Duplicates from 8 values on
+		}
+		for _, options := range []MerkleTreeOptions{
+			{SortLeaves: Bool(true), Compatibility: CompatV0},
+			{Compatibility: CompatLatest, PreserveOrder: true},
+			{Compatibility: CompatLatest, DropValuesAfterBuild: true},
+		} {
+			plain, err := NewStandardMerkleTree(values, options)
+			if err != nil {
+				t.Fatalf("Failed to create tree: %v", err)
+			}
+			options.PregenerateProofs = true
+			pregen, err := NewStandardMerkleTree(values, options)
+			if err != nil {
+				t.Fatalf("Failed to create tree: %v", err)
+			}
+			if plain.ProofsPregenerated() || !pregen.ProofsPregenerated() {
+				t.Fatalf("ProofsPregenerated = %v, %v; want false, true", plain.ProofsPregenerated(), pregen.ProofsPregenerated())
+			}
+			for i := range values {
+				want, err := plain.ProofForIndex(i)
+				if err != nil {
+					t.Fatalf("Failed to get proof: %v", err)
+				}
+				got, err := pregen.ProofForIndex(i)
+				if err != nil {
+					t.Fatalf("Failed to get pre-generated proof: %v", err)
+				}
+				if !slices.Equal(got, want) {
+					t.Errorf("%d values, value %d: pre-generated proof %v, want %v", n, i, got, want)
+				}
+			}
+			if _, err := pregen.ProofForIndex(n); !errors.Is(err, ErrInvalidIndex) {
+				t.Errorf("Expected ErrInvalidIndex, got %v", err)
+			}
+		}
+	}
+}
+
+func TestPregenerateProofsSimpleTree(t *testing.T) {
+	leaves := make([]BytesLike, 10)
+	for i := range leaves {
+		leaves[i] = fmt.Sprintf("0x%064x", i)
+	}
+	tree, err := NewSimpleMerkleTree(leaves, SimpleMerkleTreeOptions{MerkleTreeOptions: MerkleTreeOptions{SortLeaves: Bool(true), PregenerateProofs: true}})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if !tree.ProofsPregenerated() {
+		t.Fatal("Expected pre-generated proofs")
+	}
+	for i, leaf := range leaves {
+		proof, err := tree.GetProof(i)
+		if err != nil {
+			t.Fatalf("Failed to get proof: %v", err)
+		}
+		if ok, err := tree.Verify(leaf, proof); err != nil || !ok {
+			t.Errorf("Proof of leaf %d does not verify: %v", i, err)
+		}
+	}
+}
+
+func TestPregenerateMaxLeaves(t *testing.T) {
+	values := []string{"a", "b", "c", "d", "e"}
+
+	_, err := NewStandardMerkleTree(values, MerkleTreeOptions{PregenerateProofs: true, PregenerateMaxLeaves: 4})
+	if !errors.Is(err, ErrTooManyLeaves) {
+		t.Errorf("Expected ErrTooManyLeaves, got %v", err)
+	}
+
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{PregenerateProofs: true, PregenerateMaxLeaves: 4, SkipPregenerateAboveMax: true})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if tree.ProofsPregenerated() {
+		t.Error("Expected no pre-generated proofs above the limit")
+	}
+	if _, err := tree.GetProof("a"); err != nil {
+		t.Errorf("Failed to get proof: %v", err)
+	}
+
+	for _, limit := range []int{-1, maxPregenerateLeaves + 1} {
+		var optErr *OptionError
+		err := MerkleTreeOptions{PregenerateMaxLeaves: limit}.Validate()
+		if !errors.As(err, &optErr) || optErr.Option != "PregenerateMaxLeaves" {
+			t.Errorf("PregenerateMaxLeaves %d: expected an OptionError, got %v", limit, err)
+		}
+	}
+}
+
+func TestPregenerateProofsAppendLeaves(t *testing.T) {
+	options := MerkleTreeOptions{SortLeaves: Bool(true), Compatibility: CompatV0, PregenerateProofs: true, PregenerateMaxLeaves: 6}
+	tree, err := NewStandardMerkleTree([]string{"a", "b", "c"}, options)
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	if err := tree.AppendLeaves([]string{"d", "e"}); err != nil {
+		t.Fatalf("Failed to append: %v", err)
+	}
+	if !tree.ProofsPregenerated() {
+		t.Fatal("Expected pre-generated proofs within the limit")
+	}
+	for i := range tree.Values {
+		proof, err := tree.ProofForIndex(i)
+		if err != nil {
+			t.Fatalf("Failed to get proof: %v", err)
+		}
+		if ok, err := tree.Verify(i, proof); err != nil || !ok {
+			t.Errorf("Proof of value %d does not verify after append: %v", i, err)
+		}
+	}
+
+	if err := tree.AppendLeaves([]string{"f", "g"}); err != nil {
+		t.Fatalf("Failed to append: %v", err)
+	}
+	if tree.ProofsPregenerated() {
+		t.Error("Expected pre-generated proofs to be dropped past the limit")
+	}
+	proof, err := tree.GetProof("g")
+	if err != nil {
+		t.Fatalf("Failed to get proof: %v", err)
+	}
+	if ok, err := tree.Verify("g", proof); err != nil || !ok {
+		t.Errorf("Proof does not verify past the limit: %v", err)
+	}
+}
+
+func TestSumLeafDepths(t *testing.T) {
+	for n := 1; n <= 70; n++ {
+		want := 0
+		for i := n - 1; i <= 2*n-2; i++ {
+			want += nodeDepth(i)
+		}
+		if got := sumLeafDepths(n); got != want {
+			t.Errorf("sumLeafDepths(%d) = %d, want %d", n, got, want)
+		}
+	}
+}
+
+func BenchmarkGetProofPregenerated(b *testing.B) {
+	values := make([]string, 1<<16)
+	for i := range values {
+		values[i] = fmt.Sprintf("value-%d", i)
+	}
+
+	for _, pregenerate := range []bool{false, true} {
+		tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(true), Compatibility: CompatV0, PregenerateProofs: pregenerate})
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(fmt.Sprintf("pregenerate=%v", pregenerate), func(b *testing.B) {
+			for i := range b.N {
+				if _, err := tree.GetProof(values[i%len(values)]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
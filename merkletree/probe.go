@@ -0,0 +1,255 @@
+package merkletree
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/smeneguz/GoMerkle/internal/keccak"
+)
+
+// Hash names tried by ProbeProof besides those of AlgorithmDescriptor.
+const (
+	HashSHA256           = "sha256"                // Leaf: sha256(value)
+	HashSHA256Double     = "sha256-double"         // Leaf: sha256(sha256(value))
+	HashKeccak256Prefix  = "keccak256-prefix"      // Leaf: keccak256(0x00 || value)
+	HashSHA256Prefix     = "sha256-prefix"         // Leaf: sha256(0x00 || value), as in RFC 6962
+	HashKeccak256Ordered = "keccak256-ordered"     // Node: keccak256(left || right), unsorted
+	HashSHA256Ordered    = "sha256-ordered"        // Node: sha256(left || right), unsorted
+	HashSHA256PrefixNode = "sha256-prefix-ordered" // Node: sha256(0x01 || left || right), as in RFC 6962
+)
+
+// DefaultProbeMaxAttempts is the number of root computations ProbeProof
+// makes at most when ProbeOptions.MaxAttempts is zero.
+const DefaultProbeMaxAttempts = 1 << 16
+
+// probeLeafHash is a leaf hash convention ProbeProof tries.
+type probeLeafHash struct {
+	name string
+	hash func(value []byte) []byte // nil if the value cannot be a leaf under it
+}
+
+// probeNodeHash is a node hash convention ProbeProof tries.
+type probeNodeHash struct {
+	name string
+	hash func(left, right []byte) []byte
+
+	// sorted hashes order their children, so a proof carries no path. An
+	// ordered hash names its sorted twin: a proof that verifies with the twin
+	// also verifies with it along the path the sorting took.
+	sorted     bool
+	sortedTwin string
+}
+
+// probeLeafHashes lists the leaf conventions in the order they are tried.
+var probeLeafHashes = []probeLeafHash{
+	{HashIdentity, func(v []byte) []byte {
+		if len(v) != 32 {
+			return nil
+		}
+		return v
+	}},
+	{HashKeccak256Packed, func(v []byte) []byte { return keccakSum(v) }},
+	{HashKeccak256Double, func(v []byte) []byte { return keccakSum(keccakSum(v)) }},
+	{HashKeccak256Prefix, func(v []byte) []byte { return keccakSum([]byte{0x00}, v) }},
+	{HashSHA256, func(v []byte) []byte { return sha256Sum(v) }},
+	{HashSHA256Double, func(v []byte) []byte { return sha256Sum(sha256Sum(v)) }},
+	{HashSHA256Prefix, func(v []byte) []byte { return sha256Sum([]byte{0x00}, v) }},
+}
+
+// probeNodeHashes lists the node conventions in the order they are tried.
+var probeNodeHashes = []probeNodeHash{
+	{name: HashKeccak256Sorted, hash: sortedBytes(keccakSum), sorted: true},
+	{name: HashSHA256Sorted, hash: sortedBytes(sha256Sum), sorted: true},
+	{name: HashKeccak256Ordered, hash: func(l, r []byte) []byte { return keccakSum(l, r) }, sortedTwin: HashKeccak256Sorted},
+	{name: HashSHA256Ordered, hash: func(l, r []byte) []byte { return sha256Sum(l, r) }, sortedTwin: HashSHA256Sorted},
+	{name: HashSHA256PrefixNode, hash: func(l, r []byte) []byte { return sha256Sum([]byte{0x01}, l, r) }},
+}
+
+// keccakSum returns the Keccak-256 of the concatenated parts.
+func keccakSum(parts ...[]byte) []byte {
+	hash := keccak.Sum256(parts...)
+	return hash[:]
+}
+
+// sha256Sum returns the SHA-256 of the concatenated parts.
+func sha256Sum(parts ...[]byte) []byte {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return h.Sum(nil)
+}
+
+// sortedBytes returns a node hash over the two children in ascending order.
+func sortedBytes(hash func(parts ...[]byte) []byte) func(l, r []byte) []byte {
+	return func(l, r []byte) []byte {
+		if bytes.Compare(l, r) > 0 {
+			l, r = r, l
+		}
+		return hash(l, r)
+	}
+}
+
+// ProbeOptions configures ProbeProofWithOptions.
+type ProbeOptions struct {
+	// LeafHashes and NodeHashes restrict the conventions tried, by name.
+	// Empty means all of them.
+	LeafHashes []string
+	NodeHashes []string
+
+	// MaxAttempts caps the root computations. Sorted node hashes cost one per
+	// leaf hash and are always tried; an ordered one has to try every path,
+	// 2^len(proof), and is skipped if that would pass the cap. Zero means
+	// DefaultProbeMaxAttempts.
+	MaxAttempts int
+}
+
+// MatchedConfig is a combination of conventions under which a proof verifies.
+type MatchedConfig struct {
+	LeafHash string // Leaf hash name, such as HashKeccak256Double
+	NodeHash string // Node hash name, such as HashKeccak256Sorted
+
+	// Path is, for ordered node hashes, the directions that verified: bit k is
+	// set when the sibling at proof[k] is the left child. For a perfect tree
+	// it is the leaf's index. It is 0 for sorted node hashes.
+	Path uint64
+}
+
+// ProbeProof finds how a proof from an unknown tool was built: it hashes
+// rawLeafValue with every known leaf convention, folds the proof with every
+// known node convention, and returns the combinations that reach root, in
+// the order they were tried, sorted node hashes first. The conventions are those of this library,
+// OpenZeppelin's double-hashed leaves, plain and double SHA-256, and the
+// 0x00/0x01 domain prefixes of RFC 6962.
+//
+// A proof that verifies with a sorted node hash also verifies with the
+// ordered version of the same hash along some path; only the sorted one is
+// reported. If nothing matched and some combinations were skipped for the
+// attempt limit, the error wraps ErrProbeLimit.
+func ProbeProof(root BytesLike, rawLeafValue BytesLike, proof []BytesLike) ([]MatchedConfig, error) {
+	return ProbeProofWithOptions(root, rawLeafValue, proof, ProbeOptions{})
+}
+
+// ProbeProofWithOptions is ProbeProof restricted to some conventions or
+// with a different attempt limit.
+func ProbeProofWithOptions(root BytesLike, rawLeafValue BytesLike, proof []BytesLike, opts ProbeOptions) ([]MatchedConfig, error) {
+	leafHashes, nodeHashes, err := opts.candidates()
+	if err != nil {
+		return nil, err
+	}
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = DefaultProbeMaxAttempts
+	}
+
+	rootBytes, err := ToBytes(root)
+	if err != nil || len(rootBytes) != 32 {
+		return nil, fmt.Errorf("invalid root: %w", ErrInvalidNode)
+	}
+	value, err := ToBytes(rawLeafValue)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidValue, err)
+	}
+	siblings := make([][]byte, len(proof))
+	for i, node := range proof {
+		siblings[i], err = ToBytes(node)
+		if err != nil || len(siblings[i]) != 32 {
+			return nil, fmt.Errorf("invalid proof node at index %d: %w", i, ErrInvalidNode)
+		}
+	}
+
+	// Sorted node hashes are cheap and most common, so they all go first and
+	// an attempt limit only ever cuts ordered ones
+	var matches []MatchedConfig
+	attempts, skipped := 0, 0
+	for _, sorted := range []bool{true, false} {
+		for _, lh := range leafHashes {
+			leaf := lh.hash(value)
+			if leaf == nil {
+				continue
+			}
+			for _, nh := range nodeHashes {
+				if nh.sorted != sorted {
+					continue
+				}
+				if nh.sortedTwin != "" && slices.Contains(matches, MatchedConfig{LeafHash: lh.name, NodeHash: nh.sortedTwin}) {
+					continue
+				}
+
+				paths := uint64(1)
+				if !sorted {
+					if len(proof) >= 63 || 1<<len(proof) > maxAttempts-attempts {
+						skipped++
+						continue
+					}
+					paths = 1 << len(proof)
+				}
+				attempts += int(paths)
+
+				for path := range paths {
+					if bytes.Equal(foldProof(leaf, siblings, nh.hash, path), rootBytes) {
+						matches = append(matches, MatchedConfig{LeafHash: lh.name, NodeHash: nh.name, Path: path})
+						break
+					}
+				}
+			}
+		}
+	}
+
+	if len(matches) == 0 && skipped > 0 {
+		return nil, fmt.Errorf("%w: %d combinations not tried within %d attempts", ErrProbeLimit, skipped, maxAttempts)
+	}
+	return matches, nil
+}
+
+// foldProof hashes leaf up through siblings, putting the sibling at level k
+// on the left when bit k of path is set.
+func foldProof(leaf []byte, siblings [][]byte, hash func(l, r []byte) []byte, path uint64) []byte {
+	node := leaf
+	for k, sibling := range siblings {
+		if path>>k&1 == 1 {
+			node = hash(sibling, node)
+		} else {
+			node = hash(node, sibling)
+		}
+	}
+	return node
+}
+
+// candidates returns the conventions the options allow.
+func (o ProbeOptions) candidates() ([]probeLeafHash, []probeNodeHash, error) {
+	var errs []error
+	if o.MaxAttempts < 0 {
+		errs = append(errs, &OptionError{Option: "MaxAttempts", Value: o.MaxAttempts, Reason: "must not be negative"})
+	}
+
+	leafHashes := probeLeafHashes
+	if len(o.LeafHashes) > 0 {
+		leafHashes = nil
+		for _, name := range o.LeafHashes {
+			i := slices.IndexFunc(probeLeafHashes, func(h probeLeafHash) bool { return h.name == name })
+			if i < 0 {
+				errs = append(errs, &OptionError{Option: "LeafHashes", Value: name, Reason: "unknown leaf hash"})
+				continue
+			}
+			leafHashes = append(leafHashes, probeLeafHashes[i])
+		}
+	}
+
+	nodeHashes := probeNodeHashes
+	if len(o.NodeHashes) > 0 {
+		nodeHashes = nil
+		for _, name := range o.NodeHashes {
+			i := slices.IndexFunc(probeNodeHashes, func(h probeNodeHash) bool { return h.name == name })
+			if i < 0 {
+				errs = append(errs, &OptionError{Option: "NodeHashes", Value: name, Reason: "unknown node hash"})
+				continue
+			}
+			nodeHashes = append(nodeHashes, probeNodeHashes[i])
+		}
+	}
+	return leafHashes, nodeHashes, errors.Join(errs...)
+}
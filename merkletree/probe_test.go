@@ -0,0 +1,152 @@
+package merkletree
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// probeFixture is a proof of one raw value, built by some convention.
+type probeFixture struct {
+	root  HexString
+	value []byte
+	proof []BytesLike
+}
+
+// flatTreeFixture builds a flat tree over leaves with nodeHash and returns the proof of leaf i.
+func flatTreeFixture(t *testing.T, values [][]byte, leafHash func([]byte) []byte, nodeHash NodeHash, i int) probeFixture {
+	t.Helper()
+	leaves := make([]BytesLike, len(values))
+	for j, v := range values {
+		leaves[j] = leafHash(v)
+	}
+	tree, err := MakeMerkleTree(leaves, nodeHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := make([]BytesLike, len(tree))
+	for j, node := range tree {
+		nodes[j] = node
+	}
+	proof, err := GetProof(nodes, len(tree)-len(values)+i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return probeFixture{tree[0], values[i], toBytesLikes(proof)}
+}
+
+// rfc6962Fixture builds a perfect tree the way RFC 6962 does, with 0x00 and
+// 0x01 domain prefixes and unsorted pairs, and returns the proof of leaf i.
+func rfc6962Fixture(values [][]byte, i int) probeFixture {
+	level := make([][]byte, len(values))
+	for j, v := range values {
+		sum := sha256.Sum256(append([]byte{0x00}, v...))
+		level[j] = sum[:]
+	}
+	var proof []BytesLike
+	for index := i; len(level) > 1; index /= 2 {
+		proof = append(proof, level[index^1])
+		next := make([][]byte, len(level)/2)
+		for j := range next {
+			sum := sha256.Sum256(slices.Concat([]byte{0x01}, level[2*j], level[2*j+1]))
+			next[j] = sum[:]
+		}
+		level = next
+	}
+	return probeFixture{HexString(fmt.Sprintf("0x%x", level[0])), values[i], proof}
+}
+
+func toBytesLikes(nodes []HexString) []BytesLike {
+	out := make([]BytesLike, len(nodes))
+	for i, node := range nodes {
+		out[i] = node
+	}
+	return out
+}
+
+func TestProbeProof(t *testing.T) {
+	values := make([][]byte, 8)
+	for i := range values {
+		values[i] = []byte(fmt.Sprintf("account-%d", i))
+	}
+
+	tests := []struct {
+		name    string
+		fixture probeFixture
+		want    MatchedConfig
+	}{
+		{
+			"OpenZeppelin",
+			flatTreeFixture(t, values[:5], func(v []byte) []byte { return keccakSum(keccakSum(v)) }, StandardNodeHash, 3),
+			MatchedConfig{LeafHash: HashKeccak256Double, NodeHash: HashKeccak256Sorted},
+		},
+		{
+			"sha256 sorted",
+			flatTreeFixture(t, values[:7], func(v []byte) []byte { return sha256Sum(v) }, SHA256NodeHash, 6),
+			MatchedConfig{LeafHash: HashSHA256, NodeHash: HashSHA256Sorted},
+		},
+		{
+			"RFC 6962",
+			rfc6962Fixture(values, 5),
+			MatchedConfig{LeafHash: HashSHA256Prefix, NodeHash: HashSHA256PrefixNode, Path: 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.fixture
+			matches, err := ProbeProof(f.root, f.value, f.proof)
+			if err != nil {
+				t.Fatalf("ProbeProof failed: %v", err)
+			}
+			if len(matches) != 1 || matches[0] != tt.want {
+				t.Errorf("Matches = %+v, want exactly %+v", matches, tt.want)
+			}
+
+			// A wrong value matches nothing
+			matches, err = ProbeProof(f.root, []byte("someone else"), f.proof)
+			if err != nil || len(matches) != 0 {
+				t.Errorf("Wrong value: matches %+v, err %v", matches, err)
+			}
+		})
+	}
+}
+
+func TestProbeProofOptions(t *testing.T) {
+	values := make([][]byte, 8)
+	for i := range values {
+		values[i] = []byte{byte(i)}
+	}
+	f := rfc6962Fixture(values, 2)
+
+	// Restricted to sorted node hashes, the RFC 6962 proof matches nothing
+	matches, err := ProbeProofWithOptions(f.root, f.value, f.proof, ProbeOptions{NodeHashes: []string{HashKeccak256Sorted, HashSHA256Sorted}})
+	if err != nil || len(matches) != 0 {
+		t.Errorf("Sorted only: matches %+v, err %v", matches, err)
+	}
+
+	// 8 paths per ordered combination cannot fit in 7 attempts
+	_, err = ProbeProofWithOptions(f.root, f.value, f.proof, ProbeOptions{MaxAttempts: 7})
+	if !errors.Is(err, ErrProbeLimit) {
+		t.Errorf("Expected ErrProbeLimit, got %v", err)
+	}
+	matches, err = ProbeProofWithOptions(f.root, f.value, f.proof, ProbeOptions{
+		LeafHashes:  []string{HashSHA256Prefix},
+		NodeHashes:  []string{HashSHA256PrefixNode},
+		MaxAttempts: 8,
+	})
+	if err != nil || len(matches) != 1 || matches[0].Path != 2 {
+		t.Errorf("Restricted probe: matches %+v, err %v", matches, err)
+	}
+
+	var optErr *OptionError
+	_, err = ProbeProofWithOptions(f.root, f.value, f.proof, ProbeOptions{LeafHashes: []string{"md5"}, MaxAttempts: -1})
+	if !errors.As(err, &optErr) || !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("Expected option errors, got %v", err)
+	}
+	if _, err := ProbeProof("0x1234", f.value, f.proof); !errors.Is(err, ErrInvalidNode) {
+		t.Errorf("Expected ErrInvalidNode for a short root, got %v", err)
+	}
+}
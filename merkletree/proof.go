@@ -0,0 +1,53 @@
+package merkletree
+
+import "encoding/json"
+
+// Proof is a proof together with the leaf it proves and the root it leads
+// to, so it can be handed over and verified as one object, for example to a
+// frontend preparing an on-chain claim. Its JSON form is a superset of
+// ProofEnvelope, with the siblings under "proof", so VerifyEnvelope accepts it.
+type Proof struct {
+	LeafHash  HexString   `json:"leafHash"`  // Hash of the value as stored in the tree
+	LeafIndex int         `json:"leafIndex"` // Index of the value in insertion order
+	Siblings  []HexString `json:"proof"`     // Sibling hashes from the leaf to the root
+	Root      HexString   `json:"root"`      // Root the proof verifies against
+}
+
+// GetProofBundle returns the proof of a value or value index as a Proof.
+// It accepts the same arguments as GetProof.
+func (m *MerkleTreeImpl[T]) GetProofBundle(leaf any) (Proof, error) {
+	valueIndex, err := m.getLeafIndex(leaf)
+	if err != nil {
+		return Proof{}, err
+	}
+	siblings, err := m.GetProof(valueIndex)
+	if err != nil {
+		return Proof{}, err
+	}
+	return Proof{
+		LeafHash:  m.Tree[m.Values[valueIndex].TreeIndex],
+		LeafIndex: valueIndex,
+		Siblings:  siblings,
+		Root:      m.Root(),
+	}, nil
+}
+
+// Envelope returns the proof as a ProofEnvelope, ready for VerifyEnvelope.
+func (p Proof) Envelope() ProofEnvelope {
+	return ProofEnvelope{Root: p.Root, LeafHash: p.LeafHash, Proof: p.Siblings}
+}
+
+// Verify checks that the siblings lead from the leaf hash to the root.
+// If nodeHash is nil, StandardNodeHash is used.
+func (p Proof) Verify(nodeHash NodeHash) (bool, error) {
+	return p.Envelope().Verify(nodeHash)
+}
+
+// MarshalJSON encodes the proof, writing no siblings as [] rather than null.
+func (p Proof) MarshalJSON() ([]byte, error) {
+	type proof Proof
+	if p.Siblings == nil {
+		p.Siblings = []HexString{}
+	}
+	return json.Marshal(proof(p))
+}
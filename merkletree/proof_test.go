@@ -0,0 +1,66 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestGetProofBundle(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"alice", "bob", "charlie"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	bundle, err := tree.GetProofBundle("bob")
+	if err != nil {
+		t.Fatalf("GetProofBundle failed: %v", err)
+	}
+	proof, _ := tree.GetProof("bob")
+	if bundle.LeafIndex != 1 || bundle.Root != tree.Root() || !slices.Equal(bundle.Siblings, proof) {
+		t.Errorf("Bundle %+v does not match GetProof %v", bundle, proof)
+	}
+	if bundle.LeafHash != tree.Tree[tree.Values[1].TreeIndex] {
+		t.Errorf("Bundle leaf hash %s", bundle.LeafHash)
+	}
+	if valid, err := bundle.Verify(nil); err != nil || !valid {
+		t.Errorf("Bundle does not verify: %v", err)
+	}
+
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	if valid, err := VerifyEnvelope(data, nil); err != nil || !valid {
+		t.Errorf("Bundle JSON %s is not a valid envelope: %v", data, err)
+	}
+	var decoded Proof
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.LeafIndex != 1 || !slices.Equal(decoded.Siblings, proof) {
+		t.Errorf("Round trip gave %+v, %v", decoded, err)
+	}
+
+	bundle.LeafHash = tree.Tree[tree.Values[0].TreeIndex]
+	if valid, _ := bundle.Verify(nil); valid {
+		t.Error("Bundle with another leaf hash should not verify")
+	}
+	if _, err := tree.GetProofBundle("mallory"); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("Expected ErrValueNotFound, got %v", err)
+	}
+}
+
+func TestProofBundleSingleLeaf(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"alice"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	bundle, err := tree.GetProofBundle(0)
+	if err != nil {
+		t.Fatalf("GetProofBundle failed: %v", err)
+	}
+	data, _ := json.Marshal(bundle)
+	want := `{"leafHash":"` + string(tree.Root()) + `","leafIndex":0,"proof":[],"root":"` + string(tree.Root()) + `"}`
+	if string(data) != want {
+		t.Errorf("Marshal = %s, want %s", data, want)
+	}
+}
@@ -0,0 +1,125 @@
+package merkletree
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Kinds of SiblingContext.
+const (
+	SiblingLeaf = "leaf" // The sibling is the leaf of another value
+	SiblingNode = "node" // The sibling is an internal node
+)
+
+// ProofWithContext is a proof with, for its first levels, what each sibling
+// hash stands for, so a reviewer can see the values next to a claimed leaf.
+// The context is advisory: it is not covered by the root, and Verify and
+// VerifyEnvelope ignore it. Its JSON is a proof envelope with a context field.
+type ProofWithContext struct {
+	Root     HexString        `json:"root"`
+	LeafHash HexString        `json:"leafHash"`
+	Proof    []HexString      `json:"proof"`
+	Context  []SiblingContext `json:"context"`           // One entry per level, from the leaf up
+	Version  string           `json:"version,omitempty"` // Library version that wrote the proof
+}
+
+// SiblingContext describes the sibling hash at one level of a proof.
+type SiblingContext struct {
+	Level      int             `json:"level"`                // Index of the sibling in Proof
+	Hash       HexString       `json:"hash"`                 // The sibling hash, Proof[Level]
+	Kind       string          `json:"kind"`                 // SiblingLeaf or SiblingNode
+	ValueIndex *int            `json:"valueIndex,omitempty"` // Index of the sibling's value, for leaves
+	Value      json.RawMessage `json:"value,omitempty"`      // The sibling's value, when the tree has it
+	Redacted   bool            `json:"redacted,omitempty"`   // Value was replaced by the context redactor
+}
+
+// ContextRedactor replaces a sibling value before GetProofWithContext shows
+// it, for example with a masked address. It is given the value's index.
+type ContextRedactor[T any] func(valueIndex int, value T) any
+
+// SetContextRedactor sets the redactor applied to every value
+// GetProofWithContext shows. A nil redactor shows values as they are.
+func (m *MerkleTreeImpl[T]) SetContextRedactor(redact ContextRedactor[T]) {
+	m.contextRedactor = redact
+}
+
+// GetProofWithContext returns the proof of a value or value index, with the
+// context of the siblings of its first depth levels: the values of siblings
+// that are leaves, redacted if a ContextRedactor is set, and a marker for
+// siblings that are internal nodes. A tree whose values were dropped, with
+// no ValueProvider, shows leaf siblings by hash only.
+func (m *MerkleTreeImpl[T]) GetProofWithContext(leaf any, depth int) (ProofWithContext, error) {
+	if depth < 0 {
+		return ProofWithContext{}, &OptionError{Option: "depth", Value: depth, Reason: "must not be negative"}
+	}
+	valueIndex, err := m.getLeafIndex(leaf)
+	if err != nil {
+		return ProofWithContext{}, err
+	}
+	proof, err := m.GetProof(valueIndex)
+	if err != nil {
+		return ProofWithContext{}, err
+	}
+	if proof == nil {
+		proof = []HexString{}
+	}
+
+	firstLeaf := len(m.Tree) - len(m.Values)
+	treeIndex := m.Values[valueIndex].TreeIndex
+	result := ProofWithContext{
+		Root:     m.Root(),
+		LeafHash: m.Tree[treeIndex],
+		Proof:    proof,
+		Context:  []SiblingContext{},
+		Version:  version,
+	}
+	for level := 0; level < min(depth, len(proof)); level++ {
+		sibling := siblingIndex(treeIndex)
+		entry := SiblingContext{Level: level, Hash: m.Tree[sibling], Kind: SiblingNode}
+		if sibling >= firstLeaf {
+			if err := m.siblingValue(&entry, m.valueIndexAt(sibling)); err != nil {
+				return ProofWithContext{}, err
+			}
+		}
+		result.Context = append(result.Context, entry)
+		treeIndex = parentIndex(treeIndex)
+	}
+	return result, nil
+}
+
+// siblingValue fills in the leaf sibling at index in entry.
+func (m *MerkleTreeImpl[T]) siblingValue(entry *SiblingContext, index int) error {
+	entry.Kind = SiblingLeaf
+	entry.ValueIndex = &index
+
+	value, err := m.valueAt(index)
+	if errors.Is(err, ErrValuesDropped) {
+		return nil // Shown by hash only
+	}
+	if err != nil {
+		return err
+	}
+	var shown any = value
+	if m.contextRedactor != nil {
+		shown = m.contextRedactor(index, value)
+		entry.Redacted = true
+	}
+	entry.Value, err = marshalValue(shown)
+	if err != nil {
+		return fmt.Errorf("sibling value %d: %w", index, err)
+	}
+	return nil
+}
+
+// Envelope returns the proof without its context, ready for VerifyEnvelope.
+func (p ProofWithContext) Envelope() ProofEnvelope {
+	return ProofEnvelope{Root: p.Root, LeafHash: p.LeafHash, Proof: p.Proof, Version: p.Version}
+}
+
+// Verify checks that the proof leads from its leaf hash to its root,
+// ignoring the context. If nodeHash is nil, StandardNodeHash is used.
+func (p ProofWithContext) Verify(nodeHash NodeHash) (bool, error) {
+	return p.Envelope().VerifyCtx(context.Background(), nodeHash)
+}
@@ -0,0 +1,168 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// contextTree builds an unsorted five-value tree: its leaves sit at tree
+// indices 4 to 8, so some siblings are leaves and some internal nodes, and
+// the leaf at index 8 has a leaf sibling at both levels 0 and 1.
+func contextTree(t *testing.T, options MerkleTreeOptions) *StandardMerkleTree[string] {
+	t.Helper()
+	options.SortLeaves = Bool(false)
+	tree, err := NewStandardMerkleTree([]string{"alice", "bob", "charlie", "dave", "eve"}, options)
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	return tree
+}
+
+// leafAtTreeIndex returns the value index of the leaf at treeIndex.
+func leafAtTreeIndex(t *testing.T, tree *StandardMerkleTree[string], treeIndex int) int {
+	t.Helper()
+	for i, v := range tree.Values {
+		if v.TreeIndex == treeIndex {
+			return i
+		}
+	}
+	t.Fatalf("No value at tree index %d", treeIndex)
+	return -1
+}
+
+func TestGetProofWithContext(t *testing.T) {
+	tree := contextTree(t, MerkleTreeOptions{})
+	index := leafAtTreeIndex(t, tree, 8)
+
+	p, err := tree.GetProofWithContext(index, 10)
+	if err != nil {
+		t.Fatalf("GetProofWithContext failed: %v", err)
+	}
+	want, _ := tree.GetProof(index)
+	if !slices.Equal(p.Proof, want) || p.Root != tree.Root() {
+		t.Fatalf("Proof %v under %s, want %v under %s", p.Proof, p.Root, want, tree.Root())
+	}
+	if len(p.Context) != len(want) {
+		t.Fatalf("Context has %d levels, want %d (capped at the proof length)", len(p.Context), len(want))
+	}
+
+	// Tree index 8: sibling 7 is a leaf, then sibling 4 of parent 3 is a
+	// leaf, then sibling 2 of parent 1 is an internal node
+	for level, wantKind := range []string{SiblingLeaf, SiblingLeaf, SiblingNode} {
+		c := p.Context[level]
+		if c.Level != level || c.Hash != want[level] || c.Kind != wantKind {
+			t.Errorf("Level %d is %+v, want kind %s and hash %s", level, c, wantKind, want[level])
+			continue
+		}
+		if wantKind == SiblingNode {
+			if c.ValueIndex != nil || c.Value != nil {
+				t.Errorf("Internal sibling at level %d carries a value: %+v", level, c)
+			}
+			continue
+		}
+		siblingIndex := leafAtTreeIndex(t, tree, []int{7, 4}[level])
+		if c.ValueIndex == nil || *c.ValueIndex != siblingIndex {
+			t.Errorf("Level %d has value index %v, want %d", level, c.ValueIndex, siblingIndex)
+		}
+		var value string
+		if err := json.Unmarshal(c.Value, &value); err != nil || value != tree.Values[siblingIndex].Value {
+			t.Errorf("Level %d has value %s, want %q", level, c.Value, tree.Values[siblingIndex].Value)
+		}
+	}
+
+	if valid, err := p.Verify(nil); err != nil || !valid {
+		t.Errorf("Proof with context does not verify: %v", err)
+	}
+}
+
+func TestGetProofWithContextDepth(t *testing.T) {
+	tree := contextTree(t, MerkleTreeOptions{})
+	p, err := tree.GetProofWithContext("alice", 1)
+	if err != nil {
+		t.Fatalf("GetProofWithContext failed: %v", err)
+	}
+	if len(p.Context) != 1 || len(p.Proof) < 2 {
+		t.Errorf("Depth 1 gave %d context levels for a %d-node proof", len(p.Context), len(p.Proof))
+	}
+	if p, err := tree.GetProofWithContext("alice", 0); err != nil || len(p.Context) != 0 {
+		t.Errorf("Depth 0 gave %v, %v; want no context", p.Context, err)
+	}
+	var optErr *OptionError
+	if _, err := tree.GetProofWithContext("alice", -1); !errors.As(err, &optErr) {
+		t.Errorf("Expected an OptionError for a negative depth, got %v", err)
+	}
+	if _, err := tree.GetProofWithContext("mallory", 1); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("Expected ErrValueNotFound, got %v", err)
+	}
+}
+
+func TestGetProofWithContextRedacted(t *testing.T) {
+	tree := contextTree(t, MerkleTreeOptions{})
+	tree.SetContextRedactor(func(valueIndex int, value string) any {
+		return value[:1] + strings.Repeat("*", len(value)-1)
+	})
+	p, err := tree.GetProofWithContext(leafAtTreeIndex(t, tree, 8), 2)
+	if err != nil {
+		t.Fatalf("GetProofWithContext failed: %v", err)
+	}
+	for _, c := range p.Context {
+		var value string
+		if err := json.Unmarshal(c.Value, &value); err != nil || !c.Redacted || !strings.HasSuffix(value, "**") {
+			t.Errorf("Level %d is not redacted: %+v", c.Level, c)
+		}
+	}
+}
+
+func TestGetProofWithContextValuesDropped(t *testing.T) {
+	tree := contextTree(t, MerkleTreeOptions{DropValuesAfterBuild: true})
+	index := leafAtTreeIndex(t, tree, 8)
+	p, err := tree.GetProofWithContext(index, 3)
+	if err != nil {
+		t.Fatalf("GetProofWithContext failed: %v", err)
+	}
+	for _, c := range p.Context[:2] {
+		if c.Kind != SiblingLeaf || c.ValueIndex == nil || c.Value != nil || c.Hash != p.Proof[c.Level] {
+			t.Errorf("Level %d should be a leaf shown by hash only: %+v", c.Level, c)
+		}
+	}
+	if valid, err := p.Verify(nil); err != nil || !valid {
+		t.Errorf("Proof does not verify: %v", err)
+	}
+}
+
+func TestProofWithContextJSON(t *testing.T) {
+	tree := contextTree(t, MerkleTreeOptions{})
+	p, err := tree.GetProofWithContext(leafAtTreeIndex(t, tree, 8), 3)
+	if err != nil {
+		t.Fatalf("GetProofWithContext failed: %v", err)
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	for _, field := range []string{`"root":`, `"leafHash":`, `"proof":`, `"context":[{"level":0,`, `"kind":"leaf"`, `"kind":"node"`} {
+		if !strings.Contains(string(data), field) {
+			t.Errorf("JSON lacks %s: %s", field, data)
+		}
+	}
+
+	var decoded ProofWithContext
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if decoded.Root != p.Root || !slices.Equal(decoded.Proof, p.Proof) || len(decoded.Context) != len(p.Context) {
+		t.Errorf("Decoded %+v, want %+v", decoded, p)
+	}
+
+	// The context is advisory: tampering with it does not affect verification
+	decoded.Context[0].Value = json.RawMessage(`"mallory"`)
+	if valid, err := decoded.Verify(nil); err != nil || !valid {
+		t.Errorf("Tampered context made the proof fail: %v", err)
+	}
+	if valid, err := VerifyEnvelope(data, nil); err != nil || !valid {
+		t.Errorf("Proof with context does not verify as an envelope: %v", err)
+	}
+}
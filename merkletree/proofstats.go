@@ -0,0 +1,96 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// ProofStatsReport describes the proofs of every value in a tree, for sizing
+// what publishing the tree's claims will cost.
+type ProofStatsReport struct {
+	Leaves          int     `json:"leaves"`          // Number of values
+	MinProofLength  int     `json:"minProofLength"`  // Fewest nodes in a proof
+	MaxProofLength  int     `json:"maxProofLength"`  // Most nodes in a proof
+	MeanProofLength float64 `json:"meanProofLength"` // Average nodes per proof
+
+	// Histogram[n] is the number of values whose proof has n nodes.
+	Histogram []int `json:"histogram"`
+
+	// EnvelopeBytes is the total size of every value's ProofEnvelope encoded
+	// as compact JSON, as ExportClaim followed by Envelope would produce it.
+	EnvelopeBytes int64 `json:"envelopeBytes"`
+
+	// DeepestLeaves holds the indices of the values with the longest proofs,
+	// in ascending order.
+	DeepestLeaves []int `json:"deepestLeaves"`
+}
+
+// String summarizes the report in a few lines.
+func (r ProofStatsReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d leaves, proof length min %d, max %d, mean %.2f\n",
+		r.Leaves, r.MinProofLength, r.MaxProofLength, r.MeanProofLength)
+	for length, count := range r.Histogram {
+		if count > 0 {
+			fmt.Fprintf(&b, "  length %2d: %d\n", length, count)
+		}
+	}
+	fmt.Fprintf(&b, "envelope bytes: %d\n", r.EnvelopeBytes)
+	fmt.Fprintf(&b, "deepest leaves: %d", len(r.DeepestLeaves))
+	return b.String()
+}
+
+// nodeDepth returns the depth of a node in the flat tree, which is also the
+// length of its proof: the root is at depth 0 and node i is at floor(log2(i+1)).
+func nodeDepth(i int) int {
+	return bits.Len(uint(i+1)) - 1
+}
+
+// ProofStats reports the proof length of every value and the size of their
+// envelopes. Nothing is hashed and no proof is built: lengths follow from each
+// value's tree index, and sizes from the lengths of the sibling hashes.
+// Returns ErrEmptyTree for a tree without values.
+func (m *MerkleTreeImpl[T]) ProofStats() (ProofStatsReport, error) {
+	if len(m.Values) == 0 || len(m.Tree) == 0 {
+		return ProofStatsReport{}, ErrEmptyTree
+	}
+
+	// The envelope of an empty root, leaf hash and proof; every hash then adds
+	// its own length, every proof node its quotes and all but the first a comma
+	empty, err := json.Marshal(ProofEnvelope{})
+	if err != nil {
+		return ProofStatsReport{}, err
+	}
+	base := int64(len(empty) + len(m.Root()))
+
+	r := ProofStatsReport{
+		Leaves:         len(m.Values),
+		MinProofLength: nodeDepth(len(m.Tree) - 1),
+		Histogram:      make([]int, nodeDepth(len(m.Tree)-1)+1),
+	}
+	total := 0
+	for valueIndex, v := range m.Values {
+		depth := nodeDepth(v.TreeIndex)
+		r.Histogram[depth]++
+		total += depth
+		r.MinProofLength = min(r.MinProofLength, depth)
+		if depth > r.MaxProofLength {
+			r.MaxProofLength = depth
+			r.DeepestLeaves = r.DeepestLeaves[:0]
+		}
+		if depth == r.MaxProofLength {
+			r.DeepestLeaves = append(r.DeepestLeaves, valueIndex)
+		}
+
+		size := base + int64(len(m.Tree[v.TreeIndex])) + int64(max(depth-1, 0))
+		for i := v.TreeIndex; i > 0; i = parentIndex(i) {
+			size += int64(len(m.Tree[siblingIndex(i)]) + 2)
+		}
+		r.EnvelopeBytes += size
+	}
+	r.Histogram = r.Histogram[:r.MaxProofLength+1]
+	r.MeanProofLength = float64(total) / float64(len(m.Values))
+	return r, nil
+}
@@ -0,0 +1,100 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestProofStats(t *testing.T) {
+	// Value i of an unsorted tree of n leaves sits at tree index n-1+i, so
+	// its proof length is floor(log2(n+i))
+	tests := []struct {
+		n         int
+		min, max  int
+		mean      float64
+		histogram []int
+		deepest   []int
+	}{
+		{1, 0, 0, 0, []int{1}, []int{0}},
+		{2, 1, 1, 1, []int{0, 2}, []int{0, 1}},
+		{3, 1, 2, 5.0 / 3, []int{0, 1, 2}, []int{1, 2}},
+		{5, 2, 3, 12.0 / 5, []int{0, 0, 3, 2}, []int{3, 4}},
+		{12, 3, 4, 44.0 / 12, []int{0, 0, 0, 4, 8}, []int{4, 5, 6, 7, 8, 9, 10, 11}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.n), func(t *testing.T) {
+			values := make([]string, tt.n)
+			for i := range values {
+				values[i] = fmt.Sprintf("value-%d", i)
+			}
+			tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{})
+			if err != nil {
+				t.Fatalf("Failed to create tree: %v", err)
+			}
+
+			r, err := tree.ProofStats()
+			if err != nil {
+				t.Fatalf("ProofStats failed: %v", err)
+			}
+			if r.Leaves != tt.n || r.MinProofLength != tt.min || r.MaxProofLength != tt.max || r.MeanProofLength != tt.mean {
+				t.Errorf("Report = %+v, want min %d, max %d, mean %v", r, tt.min, tt.max, tt.mean)
+			}
+			if !slices.Equal(r.Histogram, tt.histogram) {
+				t.Errorf("Histogram = %v, want %v", r.Histogram, tt.histogram)
+			}
+			if !slices.Equal(r.DeepestLeaves, tt.deepest) {
+				t.Errorf("DeepestLeaves = %v, want %v", r.DeepestLeaves, tt.deepest)
+			}
+
+			var bytes int64
+			for i := range values {
+				claim, err := tree.ExportClaim(i)
+				if err != nil {
+					t.Fatalf("ExportClaim(%d) failed: %v", i, err)
+				}
+				data, err := json.Marshal(claim.Envelope())
+				if err != nil {
+					t.Fatal(err)
+				}
+				bytes += int64(len(data))
+			}
+			if r.EnvelopeBytes != bytes {
+				t.Errorf("EnvelopeBytes = %d, exporting every envelope gives %d", r.EnvelopeBytes, bytes)
+			}
+		})
+	}
+}
+
+func TestProofStatsSorted(t *testing.T) {
+	values := []string{"e", "d", "c", "b", "a"}
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(true)})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	r, err := tree.ProofStats()
+	if err != nil {
+		t.Fatalf("ProofStats failed: %v", err)
+	}
+
+	// Sorting moves values, so the deepest are whichever landed at indices 7 and 8
+	var want []int
+	for i, v := range tree.Values {
+		if v.TreeIndex >= 7 {
+			want = append(want, i)
+		}
+	}
+	if !slices.Equal(r.DeepestLeaves, want) {
+		t.Errorf("DeepestLeaves = %v, want %v", r.DeepestLeaves, want)
+	}
+}
+
+func TestProofStatsEmpty(t *testing.T) {
+	var tree MerkleTreeImpl[string]
+	if _, err := tree.ProofStats(); !errors.Is(err, ErrEmptyTree) {
+		t.Errorf("Expected ErrEmptyTree, got %v", err)
+	}
+}
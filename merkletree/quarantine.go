@@ -0,0 +1,111 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"unicode/utf8"
+)
+
+// maxPreviewLength is the number of characters of a value kept in a QuarantinedValue.
+const maxPreviewLength = 64
+
+// QuarantinedValue describes a value left out of the tree under QuarantineInvalid.
+type QuarantinedValue struct {
+	Index   int    // Index of the value in the input
+	Preview string // Shortened rendering of the value
+	Err     error  // Why the value could not be hashed
+}
+
+// quarantinedValue keeps the original value for lookups.
+type quarantinedValue[T any] struct {
+	QuarantinedValue
+	value T
+}
+
+// Quarantine returns the values left out of the tree because they could not
+// be hashed, in input order. It is empty unless the tree was built with
+// QuarantineInvalid. A tree loaded from a dump only knows QuarantinedCount.
+func (m *MerkleTreeImpl[T]) Quarantine() []QuarantinedValue {
+	report := make([]QuarantinedValue, len(m.quarantine))
+	for i, q := range m.quarantine {
+		report[i] = q.QuarantinedValue
+	}
+	return report
+}
+
+// QuarantinedCount returns the number of values left out of the tree.
+// A non-zero count means the tree covers only part of its input.
+func (m *MerkleTreeImpl[T]) QuarantinedCount() int {
+	return m.quarantined
+}
+
+// setQuarantine records the values left out of the tree.
+func (m *MerkleTreeImpl[T]) setQuarantine(quarantine []quarantinedValue[T]) {
+	m.quarantine = quarantine
+	m.quarantined = len(quarantine)
+}
+
+// checkQuarantined returns ErrQuarantined if value was left out of the tree.
+func (m *MerkleTreeImpl[T]) checkQuarantined(value T) error {
+	for _, q := range m.quarantine {
+		if reflect.DeepEqual(q.value, value) {
+			return fmt.Errorf("%w: input index %d: %v", ErrQuarantined, q.Index, q.Err)
+		}
+	}
+	return nil
+}
+
+// withoutQuarantined returns metadata without the entries of quarantined values,
+// so that it lines up with the values kept in the tree.
+func withoutQuarantined[T any](metadata []json.RawMessage, quarantine []quarantinedValue[T]) []json.RawMessage {
+	if metadata == nil || len(quarantine) == 0 {
+		return metadata
+	}
+
+	kept := make([]json.RawMessage, 0, len(metadata)-len(quarantine))
+	next := 0
+	for i, meta := range metadata {
+		if next < len(quarantine) && quarantine[next].Index == i {
+			next++
+			continue
+		}
+		kept = append(kept, meta)
+	}
+	return kept
+}
+
+// previewValue renders value for a report or an error message, truncated to
+// maxPreviewLength characters. Strings and byte slices are cut before they
+// are formatted, so a value of many megabytes costs no more than a short one.
+func previewValue(value any) string {
+	var preview string
+	switch v := value.(type) {
+	case string:
+		preview = truncateText(v)
+	case HexString:
+		preview = truncateText(string(v))
+	case []byte:
+		if len(v) > maxPreviewLength {
+			return fmt.Sprintf("%v", v[:maxPreviewLength]) + "..."
+		}
+		preview = fmt.Sprintf("%v", v)
+	default:
+		preview = fmt.Sprintf("%v", value)
+	}
+	if utf8.RuneCountInString(preview) <= maxPreviewLength {
+		return preview
+	}
+	runes := []rune(preview)
+	return string(runes[:maxPreviewLength]) + "..."
+}
+
+// truncateText cuts s to a prefix long enough for any preview: at most
+// maxPreviewLength runes of at most utf8.UTFMax bytes each, and one more so
+// the preview is still marked as truncated.
+func truncateText(s string) string {
+	if limit := (maxPreviewLength + 1) * utf8.UTFMax; len(s) > limit {
+		return s[:limit]
+	}
+	return s
+}
@@ -0,0 +1,115 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestQuarantineInvalid(t *testing.T) {
+	type row struct{ ID int }
+	values := []any{"alice", row{1}, "bob", 1.5, "charlie", row{99}}
+
+	// Strict by default
+	if _, err := NewStandardMerkleTree(values, MerkleTreeOptions{}); !errors.Is(err, ErrInvalidValue) {
+		t.Fatalf("Expected strict construction to fail, got %v", err)
+	}
+
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{QuarantineInvalid: true})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	valid, err := NewStandardMerkleTree([]any{"alice", "bob", "charlie"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if tree.Root() != valid.Root() {
+		t.Errorf("Root %s should equal the root of the valid subset %s", tree.Root(), valid.Root())
+	}
+
+	report := tree.Quarantine()
+	if len(report) != 3 || tree.QuarantinedCount() != 3 {
+		t.Fatalf("Expected 3 quarantined values, got %d", len(report))
+	}
+	for i, want := range []struct {
+		index   int
+		preview string
+	}{{1, "{1}"}, {3, "1.5"}, {5, "{99}"}} {
+		if report[i].Index != want.index || report[i].Preview != want.preview {
+			t.Errorf("Report entry %d is %+v, want index %d preview %q", i, report[i], want.index, want.preview)
+		}
+		if !errors.Is(report[i].Err, ErrInvalidValue) {
+			t.Errorf("Report entry %d has error %v", i, report[i].Err)
+		}
+	}
+
+	if _, err := tree.GetProof(any(1.5)); !errors.Is(err, ErrQuarantined) {
+		t.Errorf("Expected ErrQuarantined, got %v", err)
+	}
+	if _, err := tree.GetProof(any("dave")); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("Expected ErrValueNotFound for a value that was never input, got %v", err)
+	}
+	if _, err := tree.GetProof(any("bob")); err != nil {
+		t.Errorf("Valid values should still have proofs: %v", err)
+	}
+
+	dump, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	data, err := json.Marshal(dump)
+	if err != nil {
+		t.Fatalf("Failed to marshal dump: %v", err)
+	}
+	if !strings.Contains(string(data), `"quarantined":3`) {
+		t.Errorf("Dump should note the quarantined count: %s", data)
+	}
+}
+
+func TestQuarantineKeepsMetadataAligned(t *testing.T) {
+	values := []BytesLike{"alice", 1.5, "bob"}
+	metadata := []json.RawMessage{
+		json.RawMessage(`"a"`),
+		json.RawMessage(`"bad"`),
+		json.RawMessage(`"b"`),
+	}
+
+	tree, err := NewSimpleMerkleTree(values, SimpleMerkleTreeOptions{
+		MerkleTreeOptions: MerkleTreeOptions{QuarantineInvalid: true, LeafMetadata: metadata},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	entries, err := tree.Entries()
+	if err != nil {
+		t.Fatalf("Failed to list entries: %v", err)
+	}
+	for _, entry := range entries {
+		want := map[string]string{"alice": `"a"`, "bob": `"b"`}[entry.Value.(string)]
+		if string(entry.Metadata) != want {
+			t.Errorf("Value %v has metadata %s, want %s", entry.Value, entry.Metadata, want)
+		}
+	}
+
+	dump, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	loaded, _, err := LoadSimpleMerkleTree(dump, nil)
+	if err != nil {
+		t.Fatalf("Failed to load dump: %v", err)
+	}
+	if loaded.QuarantinedCount() != 1 {
+		t.Errorf("Loaded tree should know 1 value was quarantined, got %d", loaded.QuarantinedCount())
+	}
+}
+
+func TestQuarantineEverything(t *testing.T) {
+	_, err := NewStandardMerkleTree([]any{1.5, 2.5}, MerkleTreeOptions{QuarantineInvalid: true})
+	if !errors.Is(err, ErrEmptyTree) {
+		t.Errorf("Expected ErrEmptyTree when every value is quarantined, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package merkletree
+
+import (
+	"fmt"
+	"slices"
+)
+
+// RangeProof proves a run of consecutive leaves. Positions are in tree
+// order, as in NonInclusionNeighbor.Index: leaf p is at tree index
+// LeafCount-1+p. The leaves themselves are not part of the proof.
+type RangeProof struct {
+	LeafCount int         `json:"leafCount"` // Number of leaves in the tree
+	Nodes     []HexString `json:"nodes"`     // Nodes outside the range, in the order the verifier needs them
+}
+
+// GetRangeProof returns the proof of the leaves at positions [start, end),
+// in tree order. It holds only the nodes bordering the range; a multi-proof
+// of the same leaves has the same nodes and a flag for every hash besides.
+// A range that is empty, reversed or not within the leaves fails with
+// ErrInvalidIndex. The proof of all the leaves has no nodes.
+func (m *MerkleTreeImpl[T]) GetRangeProof(start, end int) (RangeProof, error) {
+	n := (len(m.Tree) + 1) / 2
+	if start < 0 || end > n || start >= end {
+		return RangeProof{}, fmt.Errorf("%w: range [%d, %d) of %d leaves", ErrInvalidIndex, start, end, n)
+	}
+
+	proof := RangeProof{LeafCount: n, Nodes: []HexString{}}
+	queue := make([]int, 0, end-start)
+	for p := end - 1; p >= start; p-- {
+		queue = append(queue, n-1+p)
+	}
+	for len(queue) > 0 && queue[0] > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		if s := siblingIndex(i); len(queue) > 0 && queue[0] == s {
+			queue = queue[1:]
+		} else {
+			proof.Nodes = append(proof.Nodes, m.Tree[s])
+		}
+		queue = append(queue, parentIndex(i))
+	}
+	return proof, nil
+}
+
+// VerifyRangeProof reports whether proof shows leafHashes, the leaves at
+// positions start onwards, against the tree's root using its node hash.
+func (m *MerkleTreeImpl[T]) VerifyRangeProof(start int, leafHashes []BytesLike, proof RangeProof) (bool, error) {
+	return verifyRangeProof(m.Root(), start, leafHashes, proof, m.NodeHash)
+}
+
+// VerifyRangeProof reports whether proof shows that leafHashes are the
+// leaves at positions start onwards, in tree order, of the standard tree with
+// the given root. The nodes are consumed as the positions of a tree of
+// LeafCount leaves require, so a proof with nodes left over or missing does
+// not verify. It returns an error wrapping ErrInvalidIndex if the range is
+// empty or not within LeafCount leaves, and one wrapping ErrInvalidNode if a
+// leaf or proof node is not 32 bytes.
+//
+// Sorted-pair node hashes do not record which side a node is on: the proof
+// shows the leaves are in the tree, not that they are at those positions.
+func VerifyRangeProof(root BytesLike, start int, leafHashes []BytesLike, proof RangeProof) (bool, error) {
+	return verifyRangeProof(root, start, leafHashes, proof, StandardNodeHash)
+}
+
+// verifyRangeProof recomputes the root from the leaves of the range, level by
+// level from the last position, as GetRangeProof walks it.
+func verifyRangeProof(root BytesLike, start int, leafHashes []BytesLike, proof RangeProof, nodeHash NodeHash) (bool, error) {
+	want, err := ToHex(root)
+	if err != nil {
+		return false, fmt.Errorf("invalid root: %w", err)
+	}
+	if nodeHash == nil {
+		nodeHash = StandardNodeHash
+	}
+	n, end := proof.LeafCount, start+len(leafHashes)
+	if start < 0 || end > n || start >= end {
+		return false, fmt.Errorf("%w: range [%d, %d) of %d leaves", ErrInvalidIndex, start, end, n)
+	}
+
+	type node struct {
+		index int
+		hash  HexString
+	}
+	queue := make([]node, 0, len(leafHashes))
+	for k, leaf := range slices.Backward(leafHashes) {
+		if err := CheckValidMerkleNode(leaf); err != nil {
+			return false, fmt.Errorf("invalid leaf %d: %w", start+k, err)
+		}
+		hash, _ := ToHex(leaf)
+		queue = append(queue, node{n - 1 + start + k, hash})
+	}
+	nodes := proof.Nodes
+	for len(queue) > 0 && queue[0].index > 0 {
+		a := queue[0]
+		queue = queue[1:]
+		var b HexString
+		if s := siblingIndex(a.index); len(queue) > 0 && queue[0].index == s {
+			b, queue = queue[0].hash, queue[1:]
+		} else {
+			if len(nodes) == 0 {
+				return false, nil // The proof is missing nodes
+			}
+			if err := CheckValidMerkleNode(nodes[0]); err != nil {
+				return false, fmt.Errorf("invalid proof node %d: %w", len(proof.Nodes)-len(nodes), err)
+			}
+			b, nodes = nodes[0], nodes[1:]
+		}
+		// Right children have even indices
+		parent := node{index: parentIndex(a.index)}
+		if a.index%2 == 0 {
+			parent.hash = nodeHash(b, a.hash)
+		} else {
+			parent.hash = nodeHash(a.hash, b)
+		}
+		queue = append(queue, parent)
+	}
+	return len(nodes) == 0 && queue[0].hash.Normalize() == want.Normalize(), nil
+}
@@ -0,0 +1,175 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// rangeLeaves returns the leaf hashes at positions [start, end) of tree.
+func rangeLeaves(tree []HexString, start, end int) []BytesLike {
+	n := (len(tree) + 1) / 2
+	leaves := make([]BytesLike, 0, end-start)
+	for _, leaf := range tree[n-1+start : n-1+end] {
+		leaves = append(leaves, leaf)
+	}
+	return leaves
+}
+
+func TestRangeProof(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 7, 8, 13} {
+		tree, err := NewStandardMerkleTree(appendValues(n), MerkleTreeOptions{SortLeaves: Bool(false), Compatibility: CompatV0})
+		if err != nil {
+			t.Fatalf("Failed to create tree: %v", err)
+		}
+		nodes := make([]BytesLike, len(tree.Tree))
+		for i, node := range tree.Tree {
+			nodes[i] = node
+		}
+
+		for start := 0; start < n; start++ {
+			for end := start + 1; end <= n; end++ {
+				proof, err := tree.GetRangeProof(start, end)
+				if err != nil {
+					t.Fatalf("GetRangeProof(%d, %d) on %d leaves failed: %v", start, end, n, err)
+				}
+				leaves := rangeLeaves(tree.Tree, start, end)
+				if ok, err := VerifyRangeProof(tree.Root(), start, leaves, proof); err != nil || !ok {
+					t.Errorf("Range [%d, %d) of %d leaves does not verify: %v", start, end, n, err)
+				}
+				if ok, err := tree.VerifyRangeProof(start, leaves, proof); err != nil || !ok {
+					t.Errorf("Range [%d, %d) of %d leaves does not verify against the tree: %v", start, end, n, err)
+				}
+
+				var indices []int
+				for p := start; p < end; p++ {
+					indices = append(indices, n-1+p)
+				}
+				multiproof, err := GetMultiProof(nodes, indices)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !slices.Equal(proof.Nodes, multiproof.Proof) {
+					t.Errorf("Range [%d, %d) of %d leaves: nodes differ from the multi-proof", start, end, n)
+				}
+			}
+		}
+
+		full, err := tree.GetRangeProof(0, n)
+		if err != nil || len(full.Nodes) != 0 {
+			t.Errorf("Proof of all %d leaves = %v, %v; want no nodes", n, full.Nodes, err)
+		}
+	}
+}
+
+func TestRangeProofPositional(t *testing.T) {
+	var values []BytesLike
+	for i := range 11 {
+		values = append(values, fmt.Sprintf("0x%064x", i+1))
+	}
+	tree, err := NewSimpleMerkleTree(values, SimpleMerkleTreeOptions{NodeHash: PositionalNodeHash})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	for _, r := range [][2]int{{0, 1}, {2, 9}, {3, 5}, {4, 11}, {10, 11}} {
+		proof, err := tree.GetRangeProof(r[0], r[1])
+		if err != nil {
+			t.Fatalf("GetRangeProof(%d, %d) failed: %v", r[0], r[1], err)
+		}
+		leaves := rangeLeaves(tree.Tree, r[0], r[1])
+		if ok, err := tree.VerifyRangeProof(r[0], leaves, proof); err != nil || !ok {
+			t.Errorf("Range %v does not verify: %v", r, err)
+		}
+		if len(leaves) > 1 {
+			swapped := slices.Clone(leaves)
+			swapped[0], swapped[1] = swapped[1], swapped[0]
+			if ok, _ := tree.VerifyRangeProof(r[0], swapped, proof); ok {
+				t.Errorf("Range %v verifies with its first two leaves swapped", r)
+			}
+		}
+	}
+}
+
+func TestRangeProofRejects(t *testing.T) {
+	tree, err := NewStandardMerkleTree(appendValues(10), MerkleTreeOptions{SortLeaves: Bool(false), Compatibility: CompatV0})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	for _, r := range [][2]int{{-1, 3}, {5, 11}, {4, 4}, {6, 2}} {
+		if _, err := tree.GetRangeProof(r[0], r[1]); !errors.Is(err, ErrInvalidIndex) {
+			t.Errorf("GetRangeProof(%d, %d): expected ErrInvalidIndex, got %v", r[0], r[1], err)
+		}
+	}
+
+	proof, err := tree.GetRangeProof(3, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaves := rangeLeaves(tree.Tree, 3, 7)
+	tamper := func(change func(p *RangeProof)) RangeProof {
+		p := RangeProof{LeafCount: proof.LeafCount, Nodes: slices.Clone(proof.Nodes)}
+		change(&p)
+		return p
+	}
+	for name, tampered := range map[string]RangeProof{
+		"missing node": tamper(func(p *RangeProof) { p.Nodes = p.Nodes[1:] }),
+		"extra node":   tamper(func(p *RangeProof) { p.Nodes = append(p.Nodes, p.Nodes[0]) }),
+		"leaf count":   tamper(func(p *RangeProof) { p.LeafCount++ }),
+		"wrong node":   tamper(func(p *RangeProof) { p.Nodes[0] = tree.Root() }),
+	} {
+		if ok, err := VerifyRangeProof(tree.Root(), 3, leaves, tampered); ok {
+			t.Errorf("%s: tampered proof verifies (err %v)", name, err)
+		}
+	}
+	if ok, _ := VerifyRangeProof(tree.Root(), 3, leaves[1:], proof); ok {
+		t.Error("A proof verifies for fewer leaves than it was made for")
+	}
+
+	if _, err := VerifyRangeProof(tree.Root(), 8, leaves, proof); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("Expected ErrInvalidIndex past the last leaf, got %v", err)
+	}
+	if _, err := VerifyRangeProof(tree.Root(), 3, nil, proof); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("Expected ErrInvalidIndex for no leaves, got %v", err)
+	}
+	if _, err := VerifyRangeProof(tree.Root(), 3, leaves, tamper(func(p *RangeProof) { p.Nodes[0] = "0x1234" })); !errors.Is(err, ErrInvalidNode) {
+		t.Errorf("Expected ErrInvalidNode, got %v", err)
+	}
+}
+
+func TestRangeProofSize(t *testing.T) {
+	const n, start, end = 10000, 2500, 7500
+	tree, err := NewStandardMerkleTree(appendValues(n), MerkleTreeOptions{SortLeaves: Bool(false), Compatibility: CompatV0})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	proof, err := tree.GetRangeProof(start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := VerifyRangeProof(tree.Root(), start, rangeLeaves(tree.Tree, start, end), proof); err != nil || !ok {
+		t.Fatalf("Range proof does not verify: %v", err)
+	}
+
+	var leaves []any
+	for i, v := range tree.Values {
+		if p := v.TreeIndex - (n - 1); p >= start && p < end {
+			leaves = append(leaves, i)
+		}
+	}
+	multiproof, err := tree.GetMultiProof(leaves...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rangeJSON, _ := json.Marshal(proof)
+	multiJSON, _ := json.Marshal(MultiProof{Proof: multiproof.Proof, ProofFlags: multiproof.ProofFlags})
+	t.Logf("%d leaves: range proof %d nodes, %d bytes; multi-proof %d nodes and %d flags, %d bytes",
+		end-start, len(proof.Nodes), len(rangeJSON), len(multiproof.Proof), len(multiproof.ProofFlags), len(multiJSON))
+	if len(proof.Nodes) != len(multiproof.Proof) {
+		t.Errorf("Range proof has %d nodes, multi-proof %d", len(proof.Nodes), len(multiproof.Proof))
+	}
+	if len(rangeJSON) >= len(multiJSON) {
+		t.Errorf("Range proof is %d bytes, no smaller than the %d of the multi-proof without its leaves", len(rangeJSON), len(multiJSON))
+	}
+}
@@ -0,0 +1,277 @@
+package merkletree
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRecordFileBytes is the size at which a FileRecorder rotates its
+// file when FileRecorderOptions.MaxBytes is zero.
+const DefaultRecordFileBytes = 64 << 20
+
+// IssuedProof records one proof handed out by ExportClaim.
+type IssuedProof struct {
+	Time       time.Time `json:"time"`                // When the claim was exported
+	Root       HexString `json:"root"`                // Root the proof verifies against
+	LeafHash   HexString `json:"leafHash"`            // Leaf the proof is for
+	ValueIndex int       `json:"valueIndex"`          // Index of the value in insertion order
+	Requester  string    `json:"requester,omitempty"` // Who asked for it, as given by the caller
+	ProofHash  HexString `json:"proofHash"`           // ProofHash of the proof issued
+}
+
+// IssuanceRecorder keeps a record of issued proofs, for example for audits.
+// Record is called once per exported claim, possibly from several goroutines.
+type IssuanceRecorder interface {
+	Record(entry IssuedProof) error
+}
+
+// RecordPolicy decides what ExportClaim does when its recorder fails.
+type RecordPolicy int
+
+const (
+	// RecordLogAndContinue logs the failure and issues the proof anyway.
+	RecordLogAndContinue RecordPolicy = iota
+
+	// RecordFailClosed refuses the proof with an error wrapping
+	// ErrRecordFailed, so that no proof is issued without a record.
+	RecordFailClosed
+)
+
+// RecorderOptions configures SetIssuanceRecorder.
+type RecorderOptions struct {
+	// Policy applies when Record fails. The zero value is RecordLogAndContinue.
+	Policy RecordPolicy
+
+	// Logf reports failures under RecordLogAndContinue. If nil, log.Printf is used.
+	Logf func(format string, args ...any)
+}
+
+// SetIssuanceRecorder makes ExportClaim and ExportClaimFor record every claim
+// they export with recorder. A nil recorder stops recording.
+func (m *MerkleTreeImpl[T]) SetIssuanceRecorder(recorder IssuanceRecorder, opts RecorderOptions) {
+	m.recorder = recorder
+	m.recorderOptions = opts
+}
+
+// recordIssued records a claim about to be returned, applying the policy
+// when the recorder fails.
+func (m *MerkleTreeImpl[T]) recordIssued(claim Claim[T], requester string) error {
+	if m.recorder == nil {
+		return nil
+	}
+	err := m.recorder.Record(IssuedProof{
+		Time:       time.Now().UTC(),
+		Root:       claim.Root,
+		LeafHash:   claim.LeafHash,
+		ValueIndex: claim.ValueIndex,
+		Requester:  requester,
+		ProofHash:  ProofHash(claim.Proof),
+	})
+	if err == nil {
+		return nil
+	}
+	if m.recorderOptions.Policy == RecordFailClosed {
+		return fmt.Errorf("%w: value %d: %v", ErrRecordFailed, claim.ValueIndex, err)
+	}
+	logf := m.recorderOptions.Logf
+	if logf == nil {
+		logf = log.Printf
+	}
+	logf("merkletree: recording proof of value %d: %v", claim.ValueIndex, err)
+	return nil
+}
+
+// ProofHash returns the Keccak-256 of the proof nodes concatenated, which
+// identifies a proof in an IssuedProof without storing it. A node that is not
+// valid hex hashes as its text.
+func ProofHash(proof []HexString) HexString {
+	parts := make([][]byte, len(proof))
+	for i, node := range proof {
+		b, err := ToBytes(node)
+		if err != nil {
+			b = []byte(node)
+		}
+		parts[i] = b
+	}
+	return HexString(fmt.Sprintf("0x%x", keccakSum(parts...)))
+}
+
+// FileRecorderOptions configures NewFileRecorder.
+type FileRecorderOptions struct {
+	// MaxBytes is the size past which the file is rotated. Zero means
+	// DefaultRecordFileBytes.
+	MaxBytes int64
+}
+
+// FileRecorder is an IssuanceRecorder appending one JSON line per proof to a
+// file. When the file grows past MaxBytes it is renamed to path.N, N counting
+// up from 1, and a new file is started; rotated files are never deleted.
+// It is safe for concurrent use.
+type FileRecorder struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	file     *os.File
+	size     int64
+}
+
+// NewFileRecorder opens path for appending, creating it if needed.
+func NewFileRecorder(path string, opts FileRecorderOptions) (*FileRecorder, error) {
+	if opts.MaxBytes < 0 {
+		return nil, &OptionError{Option: "MaxBytes", Value: opts.MaxBytes, Reason: "must not be negative"}
+	}
+	r := &FileRecorder{path: path, maxBytes: opts.MaxBytes}
+	if r.maxBytes == 0 {
+		r.maxBytes = DefaultRecordFileBytes
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens the current file for appending.
+func (r *FileRecorder) open() error {
+	file, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file, r.size = file, info.Size()
+	return nil
+}
+
+// Record appends entry as a JSON line, rotating the file first if it is full.
+func (r *FileRecorder) Record(entry IssuedProof) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return fmt.Errorf("recorder %s is closed", r.path)
+	}
+	if r.size > 0 && r.size+int64(len(line)) > r.maxBytes {
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := r.file.Write(line)
+	r.size += int64(n)
+	return err
+}
+
+// rotate renames the current file to the next free path.N and starts a new one.
+func (r *FileRecorder) rotate() error {
+	rotated, err := rotatedFiles(r.path)
+	if err != nil {
+		return err
+	}
+	next := 1
+	if len(rotated) > 0 {
+		next = rotated[len(rotated)-1].n + 1
+	}
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+	if err := os.Rename(r.path, r.path+"."+strconv.Itoa(next)); err != nil {
+		return err
+	}
+	return r.open()
+}
+
+// Close closes the file. Records after Close fail.
+func (r *FileRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// rotatedFile is a file rotated by a FileRecorder.
+type rotatedFile struct {
+	path string
+	n    int
+}
+
+// rotatedFiles returns the rotated files of path, oldest first.
+func rotatedFiles(path string) ([]rotatedFile, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	var files []rotatedFile
+	for _, match := range matches {
+		n, err := strconv.Atoi(strings.TrimPrefix(match, path+"."))
+		if err == nil && n > 0 {
+			files = append(files, rotatedFile{match, n})
+		}
+	}
+	slices.SortFunc(files, func(a, b rotatedFile) int { return a.n - b.n })
+	return files, nil
+}
+
+// FindByLeafHash returns every proof of leafHash recorded by a FileRecorder
+// writing to path, in the order they were written, searching its rotated
+// files too. Entries recorded concurrently may be written slightly out of
+// Time order.
+func FindByLeafHash(path string, leafHash HexString) ([]IssuedProof, error) {
+	rotated, err := rotatedFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(rotated)+1)
+	for _, f := range rotated {
+		paths = append(paths, f.path)
+	}
+	paths = append(paths, path)
+
+	var found []IssuedProof
+	for _, p := range paths {
+		file, err := os.Open(p)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(file)
+		for line := 1; scanner.Scan(); line++ {
+			var entry IssuedProof
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				file.Close()
+				return nil, fmt.Errorf("%s:%d: %w", p, line, err)
+			}
+			if strings.EqualFold(string(entry.LeafHash), string(leafHash)) {
+				found = append(found, entry)
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
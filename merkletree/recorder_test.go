@@ -0,0 +1,129 @@
+package merkletree
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// failingRecorder fails every record.
+type failingRecorder struct{}
+
+func (failingRecorder) Record(IssuedProof) error { return errors.New("disk full") }
+
+func TestRecorderPolicies(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"a", "b", "c"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	var logged []string
+	tree.SetIssuanceRecorder(failingRecorder{}, RecorderOptions{
+		Logf: func(format string, args ...any) { logged = append(logged, fmt.Sprintf(format, args...)) },
+	})
+	claim, err := tree.ExportClaim(1)
+	if err != nil || claim.ValueIndex != 1 {
+		t.Fatalf("Log-and-continue should still issue the proof: %+v, %v", claim, err)
+	}
+	if len(logged) != 1 || !strings.Contains(logged[0], "disk full") {
+		t.Errorf("Expected the failure to be logged, got %q", logged)
+	}
+
+	tree.SetIssuanceRecorder(failingRecorder{}, RecorderOptions{Policy: RecordFailClosed})
+	claim, err = tree.ExportClaim(1)
+	if !errors.Is(err, ErrRecordFailed) || claim.Root != "" {
+		t.Errorf("Fail-closed should refuse the proof: %+v, %v", claim, err)
+	}
+
+	tree.SetIssuanceRecorder(nil, RecorderOptions{Policy: RecordFailClosed})
+	if _, err := tree.ExportClaim(1); err != nil {
+		t.Errorf("Without a recorder nothing is recorded: %v", err)
+	}
+}
+
+func TestFileRecorderConcurrentRotation(t *testing.T) {
+	values := make([]string, 16)
+	for i := range values {
+		values[i] = fmt.Sprintf("value-%d", i)
+	}
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "issued.jsonl")
+	recorder, err := NewFileRecorder(path, FileRecorderOptions{MaxBytes: 4096})
+	if err != nil {
+		t.Fatalf("NewFileRecorder failed: %v", err)
+	}
+	tree.SetIssuanceRecorder(recorder, RecorderOptions{Policy: RecordFailClosed})
+
+	const workers, perWorker = 8, 40
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perWorker {
+				if _, err := tree.ExportClaimFor((w+i)%len(values), fmt.Sprintf("worker-%d", w)); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("ExportClaimFor failed: %v", err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tree.SetIssuanceRecorder(nil, RecorderOptions{})
+
+	rotated, err := filepath.Glob(path + ".*")
+	if err != nil || len(rotated) < 2 {
+		t.Fatalf("Expected several rotated files, got %v", rotated)
+	}
+
+	total := 0
+	for i := range values {
+		claim, err := tree.ExportClaim(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found, err := FindByLeafHash(path, claim.LeafHash)
+		if err != nil {
+			t.Fatalf("FindByLeafHash failed: %v", err)
+		}
+		for _, entry := range found {
+			if entry.ValueIndex != i || entry.ProofHash != ProofHash(claim.Proof) || entry.Root != tree.Root() {
+				t.Errorf("Entry for value %d does not match its claim: %+v", i, entry)
+			}
+			if !strings.HasPrefix(entry.Requester, "worker-") {
+				t.Errorf("Entry for value %d has requester %q", i, entry.Requester)
+			}
+		}
+		total += len(found)
+	}
+	if total != workers*perWorker {
+		t.Errorf("Found %d records across rotated files, want %d", total, workers*perWorker)
+	}
+
+	if err := recorder.Record(IssuedProof{}); err == nil {
+		t.Errorf("Recording after Close should fail")
+	}
+}
+
+func TestProofHash(t *testing.T) {
+	a := ProofHash([]HexString{HexString("0x" + strings.Repeat("11", 32)), HexString("0x" + strings.Repeat("22", 32))})
+	b := ProofHash([]HexString{HexString("0x" + strings.Repeat("22", 32)), HexString("0x" + strings.Repeat("11", 32))})
+	if a == b || !IsValidMerkleNode(a) || ProofHash(nil) == a {
+		t.Errorf("ProofHash should identify the proof and its order: %s, %s", a, b)
+	}
+}
@@ -0,0 +1,83 @@
+package merkletree
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// renderHashDigits is the number of hex digits Render shows of each hash.
+const renderHashDigits = 8
+
+// Render draws the tree top-down for debugging, one node per line in the
+// layout of OpenZeppelin's render, so trees built by both can be diffed: the
+// node's index in Tree and its hash cut to its first renderHashDigits hex
+// digits, indented under its parent, and for a leaf the index of its value.
+// Children are listed left before right.
+//
+// Returns ErrEmptyTree if the tree has no nodes, or ErrInvalidTree if a value
+// is not at a leaf.
+func (m *MerkleTreeImpl[T]) Render() (string, error) {
+	if len(m.Tree) == 0 {
+		return "", ErrEmptyTree
+	}
+	firstLeaf := len(m.Tree) - len(m.Values)
+	valueAt := make([]int, len(m.Values))
+	for i, v := range m.Values {
+		if v.TreeIndex < firstLeaf || v.TreeIndex >= len(m.Tree) {
+			return "", fmt.Errorf("%w: value %d is at tree index %d, not a leaf", ErrInvalidTree, i, v.TreeIndex)
+		}
+		valueAt[v.TreeIndex-firstLeaf] = i
+	}
+
+	type frame struct {
+		index int
+		path  []bool // For each ancestor below the root, whether a sibling follows it
+	}
+	var b strings.Builder
+	stack := []frame{{index: 0}}
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		for depth, more := range f.path {
+			switch {
+			case depth < len(f.path)-1 && more:
+				b.WriteString("│  ")
+			case depth < len(f.path)-1:
+				b.WriteString("   ")
+			case more:
+				b.WriteString("├─ ")
+			default:
+				b.WriteString("└─ ")
+			}
+		}
+		b.WriteString(strconv.Itoa(f.index))
+		b.WriteString(") ")
+		b.WriteString(renderHash(m.Tree[f.index]))
+		if f.index >= firstLeaf {
+			b.WriteString(" value ")
+			b.WriteString(strconv.Itoa(valueAt[f.index-firstLeaf]))
+		}
+
+		if right := RightChildIndex(f.index); right < len(m.Tree) {
+			stack = append(stack,
+				frame{index: right, path: append(f.path[:len(f.path):len(f.path)], false)},
+				frame{index: LeftChildIndex(f.index), path: append(f.path[:len(f.path):len(f.path)], true)},
+			)
+		}
+	}
+	return b.String(), nil
+}
+
+// renderHash cuts hash to renderHashDigits hex digits after its 0x prefix.
+func renderHash(hash HexString) string {
+	s := string(hash)
+	if len(s) <= len("0x")+renderHashDigits {
+		return s
+	}
+	return s[:len("0x")+renderHashDigits] + "..."
+}
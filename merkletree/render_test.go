@@ -0,0 +1,84 @@
+package merkletree
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"alice", "bob", "charlie"}, MerkleTreeOptions{SortLeaves: Bool(false)})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	out, err := tree.Render()
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	// Tree indices 0 to 4, with the leaves at 2, 3 and 4
+	h := func(i int) string { return string(tree.Tree[i][:10]) + "..." }
+	v := func(i int) string { return fmt.Sprintf(" value %d", leafAtTreeIndex(t, tree, i)) }
+	want := strings.Join([]string{
+		"0) " + h(0),
+		"├─ 1) " + h(1),
+		"│  ├─ 3) " + h(3) + v(3),
+		"│  └─ 4) " + h(4) + v(4),
+		"└─ 2) " + h(2) + v(2),
+	}, "\n")
+	if out != want {
+		t.Errorf("Render =\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestRenderSingleLeaf(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"alone"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	out, err := tree.Render()
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if want := "0) " + string(tree.Root()[:10]) + "... value 0"; out != want {
+		t.Errorf("Render = %q, want %q", out, want)
+	}
+
+	if _, err := (&MerkleTreeImpl[string]{}).Render(); !errors.Is(err, ErrEmptyTree) {
+		t.Errorf("Expected ErrEmptyTree, got %v", err)
+	}
+}
+
+func TestRenderLargeTree(t *testing.T) {
+	values := make([]string, 300)
+	for i := range values {
+		values[i] = fmt.Sprintf("value-%d", i)
+	}
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(true)})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	out, err := tree.Render()
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	lines := strings.Split(out, "\n")
+	if len(lines) != len(tree.Tree) {
+		t.Fatalf("Render has %d lines, want one per node (%d)", len(lines), len(tree.Tree))
+	}
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		_, node, _ := strings.Cut(strings.TrimLeft(line, "│├└─ "), ") ")
+		hash, value, leaf := strings.Cut(node, " value ")
+		if len(hash) != len("0x")+renderHashDigits+len("...") {
+			t.Fatalf("Hash not truncated in %q", line)
+		}
+		if leaf {
+			seen[value] = true
+		}
+	}
+	if len(seen) != len(values) {
+		t.Errorf("Render shows %d value indices, want %d", len(seen), len(values))
+	}
+}
@@ -0,0 +1,135 @@
+package merkletree
+
+import (
+	"fmt"
+	"slices"
+)
+
+// RFC6962Tree is the Merkle tree of RFC 6962 (Certificate Transparency).
+// Leaves are SHA-256(0x00 || entry) and nodes SHA-256(0x01 || left || right),
+// unsorted; a tree of n entries splits after the largest power of two below
+// n. Built bottom up, that is a tree whose odd last node at each level is
+// carried up as is.
+type RFC6962Tree struct {
+	levels [][]HexString // levels[0] are the leaf hashes, the last level the root
+}
+
+// RFC6962LeafHash returns the leaf hash of entry, SHA-256(0x00 || entry).
+func RFC6962LeafHash(entry []byte) HexString {
+	return HexString(fmt.Sprintf("0x%x", sha256Sum([]byte{0x00}, entry)))
+}
+
+// rfc6962NodeHash returns SHA-256(0x01 || left || right) of two nodes.
+func rfc6962NodeHash(left, right HexString) (HexString, error) {
+	l, err := ToBytes(left)
+	if err != nil {
+		return "", err
+	}
+	r, err := ToBytes(right)
+	if err != nil {
+		return "", err
+	}
+	return HexString(fmt.Sprintf("0x%x", sha256Sum([]byte{0x01}, l, r))), nil
+}
+
+// NewRFC6962Tree builds the tree of entries, in log order. An empty tree is
+// allowed; its root is SHA-256 of the empty string, as the RFC defines it.
+func NewRFC6962Tree(entries [][]byte) (*RFC6962Tree, error) {
+	level := make([]HexString, len(entries))
+	for i, entry := range entries {
+		level[i] = RFC6962LeafHash(entry)
+	}
+
+	t := &RFC6962Tree{levels: [][]HexString{level}}
+	for len(level) > 1 {
+		next := make([]HexString, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			node, err := rfc6962NodeHash(level[i], level[i+1])
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, node)
+		}
+		t.levels = append(t.levels, next)
+		level = next
+	}
+	return t, nil
+}
+
+// Root returns the Merkle Tree Hash of the tree.
+func (t *RFC6962Tree) Root() HexString {
+	top := t.levels[len(t.levels)-1]
+	if len(top) == 0 {
+		return HexString(fmt.Sprintf("0x%x", sha256Sum()))
+	}
+	return top[0]
+}
+
+// Len returns the number of entries, the tree size.
+func (t *RFC6962Tree) Len() int {
+	return len(t.levels[0])
+}
+
+// LeafHashes returns the leaf hashes in log order.
+func (t *RFC6962Tree) LeafHashes() []HexString {
+	return slices.Clone(t.levels[0])
+}
+
+// InclusionProof returns the audit path of the entry at index, PATH(index,
+// D[n]) of RFC 6962 section 2.1.1, nodes nearest the leaf first.
+func (t *RFC6962Tree) InclusionProof(index int) ([]HexString, error) {
+	if index < 0 || index >= t.Len() {
+		return nil, fmt.Errorf("%w: entry %d of %d", ErrInvalidIndex, index, t.Len())
+	}
+	var proof []HexString
+	for _, level := range t.levels[:len(t.levels)-1] {
+		if sibling := index ^ 1; sibling < len(level) {
+			proof = append(proof, level[sibling])
+		}
+		index /= 2
+	}
+	return proof, nil
+}
+
+// VerifyRFC6962Inclusion reports whether proof is the audit path of entry at
+// index in a tree of size entries with the given root, by the verification
+// algorithm of RFC 9162 section 2.1.3.2, which fixes that of RFC 6962. It
+// returns an error wrapping ErrInvalidIndex if index is not below size, and
+// one wrapping ErrInvalidNode if a node of proof is not 32 bytes.
+func VerifyRFC6962Inclusion(root BytesLike, entry []byte, index, size int, proof []BytesLike) (bool, error) {
+	if index < 0 || index >= size {
+		return false, fmt.Errorf("%w: entry %d of %d", ErrInvalidIndex, index, size)
+	}
+	want, err := ToHex(root)
+	if err != nil {
+		return false, fmt.Errorf("invalid root: %w", err)
+	}
+
+	fn, sn := index, size-1
+	r := RFC6962LeafHash(entry)
+	for i, p := range proof {
+		if err := CheckValidMerkleNode(p); err != nil {
+			return false, fmt.Errorf("invalid proof node at index %d: %w", i, err)
+		}
+		node, _ := ToHex(p)
+		if sn == 0 {
+			return false, nil // The proof is longer than the path
+		}
+		if fn&1 == 1 || fn == sn {
+			r, _ = rfc6962NodeHash(node, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r, _ = rfc6962NodeHash(r, node)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return sn == 0 && r == want, nil
+}
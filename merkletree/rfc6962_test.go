@@ -0,0 +1,123 @@
+package merkletree
+
+import (
+	"encoding/hex"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// rfc6962Entries are the leaf inputs of the Certificate Transparency
+// reference test vectors.
+var rfc6962Entries = []string{
+	"", "00", "10", "2021", "3031", "40414243",
+	"5051525354555657", "606162636465666768696a6b6c6d6e6f",
+}
+
+// rfc6962Roots are the Merkle Tree Hashes of the first 1 to 8 entries.
+var rfc6962Roots = []HexString{
+	"0x6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d",
+	"0xfac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125",
+	"0xaeb6bcfe274b70a14fb067a5e5578264db0fa9b51af5e0ba159158f329e06e77",
+	"0xd37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+	"0x4e3bbb1f7b478dcfe71fb631631519a3bca12c9aefca1612bfce4c13a86264d4",
+	"0x76e67dadbcdf1e10e1b74ddc608abd2f98dfb16fbce75277b5232a127f2087ef",
+	"0xddb89be403809e325750d3d263cd78929c2942b7942a34b77e122c9594a74c8c",
+	"0x5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328",
+}
+
+func rfc6962Tree(t *testing.T, n int) (*RFC6962Tree, [][]byte) {
+	t.Helper()
+	entries := make([][]byte, n)
+	for i := range entries {
+		entries[i], _ = hex.DecodeString(rfc6962Entries[i])
+	}
+	tree, err := NewRFC6962Tree(entries)
+	if err != nil {
+		t.Fatalf("Failed to create tree of %d entries: %v", n, err)
+	}
+	return tree, entries
+}
+
+func TestRFC6962TreeRoots(t *testing.T) {
+	for n, want := range rfc6962Roots {
+		if tree, _ := rfc6962Tree(t, n+1); tree.Root() != want {
+			t.Errorf("Root of %d entries = %s, want %s", n+1, tree.Root(), want)
+		}
+	}
+	if tree, _ := rfc6962Tree(t, 0); tree.Root() != "0xe3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("Root of the empty tree = %s", tree.Root())
+	}
+}
+
+func TestRFC6962InclusionProof(t *testing.T) {
+	for _, tt := range []struct {
+		index, size int
+		path        []HexString
+	}{
+		{0, 8, []HexString{
+			"0x96a296d224f285c67bee93c30f8a309157f0daa35dc5b87e410b78630a09cfc7",
+			"0x5f083f0a1a33ca076a95279832580db3e0ef4584bdff1f54c8a360f50de3031e",
+			"0x6b47aaf29ee3c2af9af889bc1fb9254dabd31177f16232dd6aab035ca39bf6e4",
+		}},
+		{5, 8, []HexString{
+			"0xbc1a0643b12e4d2d7c77918f44e0f4f79a838b6cf9ec5b5c283e1f4d88599e6b",
+			"0xca854ea128ed050b41b35ffc1b87b8eb2bde461e9e3b5596ece6b9d5975a0ae0",
+			"0xd37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+		}},
+		{2, 3, []HexString{"0xfac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125"}},
+		{1, 5, []HexString{
+			"0x6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d",
+			"0x5f083f0a1a33ca076a95279832580db3e0ef4584bdff1f54c8a360f50de3031e",
+			"0xbc1a0643b12e4d2d7c77918f44e0f4f79a838b6cf9ec5b5c283e1f4d88599e6b",
+		}},
+		{6, 7, []HexString{
+			"0x0ebc5d3437fbe2db158b9f126a1d118e308181031d0a949f8dededebc558ef6a",
+			"0xd37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+		}},
+	} {
+		tree, _ := rfc6962Tree(t, tt.size)
+		if path, err := tree.InclusionProof(tt.index); err != nil || !slices.Equal(path, tt.path) {
+			t.Errorf("InclusionProof(%d) of %d entries = %v, %v; want %v", tt.index, tt.size, path, err, tt.path)
+		}
+	}
+
+	// Every audit path verifies, and only for its own entry and index
+	for n := 1; n <= len(rfc6962Entries); n++ {
+		tree, entries := rfc6962Tree(t, n)
+		for i, entry := range entries {
+			path, err := tree.InclusionProof(i)
+			if err != nil {
+				t.Fatalf("InclusionProof(%d) failed: %v", i, err)
+			}
+			proof := treeNodes(path)
+			if valid, err := VerifyRFC6962Inclusion(tree.Root(), entry, i, n, proof); err != nil || !valid {
+				t.Errorf("Audit path of entry %d of %d does not verify: %v", i, n, err)
+			}
+			if valid, _ := VerifyRFC6962Inclusion(tree.Root(), []byte("other"), i, n, proof); valid {
+				t.Errorf("Audit path of entry %d of %d verifies another entry", i, n)
+			}
+			if n > 1 {
+				if valid, _ := VerifyRFC6962Inclusion(tree.Root(), entry, (i+1)%n, n, proof); valid {
+					t.Errorf("Audit path of entry %d of %d verifies at index %d", i, n, (i+1)%n)
+				}
+			}
+		}
+	}
+
+	one, _ := rfc6962Tree(t, 1)
+	if valid, _ := VerifyRFC6962Inclusion(one.Root(), nil, 0, 1, []BytesLike{rfc6962Roots[0]}); valid {
+		t.Error("Expected a proof longer than the path to be rejected")
+	}
+
+	tree, entries := rfc6962Tree(t, 3)
+	if _, err := tree.InclusionProof(3); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("Expected ErrInvalidIndex, got %v", err)
+	}
+	if _, err := VerifyRFC6962Inclusion(tree.Root(), entries[0], 3, 3, nil); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("Expected an index past the size to fail with ErrInvalidIndex, got %v", err)
+	}
+	if _, err := VerifyRFC6962Inclusion(tree.Root(), entries[0], 0, 3, []BytesLike{"0x1234"}); !errors.Is(err, ErrInvalidNode) {
+		t.Errorf("Expected a short node to fail with ErrInvalidNode, got %v", err)
+	}
+}
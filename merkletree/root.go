@@ -0,0 +1,73 @@
+package merkletree
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// rootBase64Prefix marks a base64-encoded root for ParseRoot.
+const rootBase64Prefix = "base64:"
+
+// ParseRoot reads a root as it comes from environment variables, flags and
+// config files. It trims surrounding whitespace and matching quotes, such as
+// the newline left by $(cat root.txt) or the quotes of a YAML value, accepts
+// hex with or without 0x in any case, and base64 after a "base64:" prefix.
+// The result is 0x-prefixed lowercase hex.
+//
+// Anything that does not decode to exactly 32 bytes fails with an error
+// wrapping ErrInvalidRoot that quotes the cleaned input and, when it decoded,
+// its length.
+func ParseRoot(s string) (HexString, error) {
+	cleaned := cleanRoot(s)
+	if cleaned == "" {
+		return "", fmt.Errorf("%w: empty", ErrInvalidRoot)
+	}
+
+	var root []byte
+	var err error
+	if encoded, ok := strings.CutPrefix(cleaned, rootBase64Prefix); ok {
+		root, err = base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			root, err = base64.RawStdEncoding.DecodeString(encoded)
+		}
+		if err != nil {
+			return "", fmt.Errorf("%w: %q is not valid base64", ErrInvalidRoot, cleaned)
+		}
+	} else {
+		digits := strings.ToLower(cleaned)
+		if len(digits) >= 2 && digits[:2] == "0x" {
+			digits = digits[2:]
+		}
+		if i := strings.IndexFunc(digits, func(r rune) bool { return !strings.ContainsRune("0123456789abcdef", r) }); i >= 0 {
+			r, _ := utf8.DecodeRuneInString(digits[i:])
+			return "", fmt.Errorf("%w: %q is not hex: unexpected %q", ErrInvalidRoot, cleaned, r)
+		}
+		if len(digits)%2 == 1 {
+			return "", fmt.Errorf("%w: %q has an odd number of hex digits (%d)", ErrInvalidRoot, cleaned, len(digits))
+		}
+		root, _ = hex.DecodeString(digits)
+	}
+
+	if len(root) != 32 {
+		return "", fmt.Errorf("%w: %q decodes to %d bytes, want 32", ErrInvalidRoot, cleaned, len(root))
+	}
+	return HexString("0x" + hex.EncodeToString(root)), nil
+}
+
+// cleanRoot trims whitespace and pairs of matching quotes around s.
+func cleanRoot(s string) string {
+	for {
+		s = strings.TrimSpace(s)
+		if len(s) < 2 {
+			return s
+		}
+		quote := s[0]
+		if (quote != '"' && quote != '\'' && quote != '`') || s[len(s)-1] != quote {
+			return s
+		}
+		s = s[1 : len(s)-1]
+	}
+}
@@ -0,0 +1,92 @@
+package merkletree
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseRoot(t *testing.T) {
+	const root = HexString("0xd820521c6bd1d8d1fe15a2a0b24e2f2e1a8b0ea0b7ca6e1c2bd8d4b4d2cd7fe1")
+	digits := string(root[2:])
+	raw, _ := ToBytes(root)
+
+	valid := []struct {
+		name  string
+		input string
+	}{
+		{"canonical", string(root)},
+		{"no prefix", digits},
+		{"uppercase", strings.ToUpper(string(root))},
+		{"uppercase prefix", "0X" + digits},
+		{"trailing newline", string(root) + "\n"},
+		{"crlf", string(root) + "\r\n"},
+		{"surrounding spaces", "  \t" + string(root) + " "},
+		{"double quotes", `"` + string(root) + `"`},
+		{"single quotes", `'` + digits + `'`},
+		{"quoted with newline", `"` + string(root) + `"` + "\n"},
+		{"spaces inside quotes", `" ` + string(root) + ` "`},
+		{"nested quotes", `"'` + string(root) + `'"`},
+		{"base64", "base64:" + base64.StdEncoding.EncodeToString(raw)},
+		{"base64 unpadded", "base64:" + base64.RawStdEncoding.EncodeToString(raw)},
+	}
+	for _, tt := range valid {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRoot(tt.input)
+			if err != nil || got != root {
+				t.Errorf("ParseRoot(%q) = %s, %v", tt.input, got, err)
+			}
+		})
+	}
+
+	invalid := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", "", "empty"},
+		{"only quotes", `""`, "empty"},
+		{"short", "0x1234", `"0x1234" decodes to 2 bytes`},
+		{"long", string(root) + "00", "decodes to 33 bytes"},
+		{"odd digits", string(root)[:65], "odd number of hex digits (63)"},
+		{"not hex", "0x" + strings.Repeat("zz", 32), "is not hex"},
+		{"unmatched quote", `"` + string(root), `"\"0xd8`},
+		{"inner space", digits[:32] + " " + digits[32:], "is not hex"},
+		{"bad base64", "base64:!!!", "not valid base64"},
+		{"short base64", "base64:" + base64.StdEncoding.EncodeToString(raw[:20]), "decodes to 20 bytes"},
+		{"base64 without prefix", base64.StdEncoding.EncodeToString(raw), "is not hex"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRoot(tt.input)
+			if !errors.Is(err, ErrInvalidRoot) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseRoot(%q) = %s, %v; want an error mentioning %q", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzParseRoot(f *testing.F) {
+	for _, seed := range []string{
+		"0xd820521c6bd1d8d1fe15a2a0b24e2f2e1a8b0ea0b7ca6e1c2bd8d4b4d2cd7fe1",
+		`"0X00"`, "base64:AAAA", "'`\"", "\n", "0x",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		root, err := ParseRoot(s)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidRoot) {
+				t.Fatalf("ParseRoot(%q) failed without ErrInvalidRoot: %v", s, err)
+			}
+			return
+		}
+		if !IsValidMerkleNode(root) || root != HexString(strings.ToLower(string(root))) {
+			t.Fatalf("ParseRoot(%q) = %q, not a canonical 32-byte root", s, root)
+		}
+		if again, err := ParseRoot(string(root)); err != nil || again != root {
+			t.Fatalf("ParseRoot is not idempotent on %q: %q, %v", root, again, err)
+		}
+	})
+}
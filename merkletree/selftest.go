@@ -0,0 +1,153 @@
+package merkletree
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/smeneguz/GoMerkle/internal/keccak"
+)
+
+// selfTestVectors holds the known-answer vectors checked by SelfTest.
+//
+//go:embed testdata/selftest.json
+var selfTestVectors []byte
+
+// selfTestDigests are the raw digests covered by SelfTest.
+var selfTestDigests = map[string]func([]byte) []byte{
+	HashAlgorithmKeccak256: keccak256,
+	HashAlgorithmSHA256: func(data []byte) []byte {
+		sum := sha256.Sum256(data)
+		return sum[:]
+	},
+}
+
+// knownAnswers is the layout of testdata/selftest.json.
+type knownAnswers struct {
+	Digests []struct {
+		Algorithm string `json:"algorithm"`
+		Input     string `json:"input"`
+		Digest    string `json:"digest"`
+	} `json:"digests"`
+	LeafHashes []struct {
+		Value string    `json:"value"`
+		Hash  HexString `json:"hash"`
+	} `json:"leafHashes"`
+	NodeHashes []struct {
+		Algorithm string    `json:"algorithm"`
+		Left      HexString `json:"left"`
+		Right     HexString `json:"right"`
+		Hash      HexString `json:"hash"`
+	} `json:"nodeHashes"`
+	Tree struct {
+		Values     []string    `json:"values"`
+		Root       HexString   `json:"root"`
+		ProofIndex int         `json:"proofIndex"`
+		Proof      []HexString `json:"proof"`
+	} `json:"tree"`
+}
+
+// SelfTest checks the hashing primitives against embedded known-answer
+// vectors: the keccak256 digests under every Keccak backend, the sha256
+// digests, StandardLeafHash, the named node hashes in both argument orders,
+// the root of a 4-leaf tree, and a proof.
+// It returns an error wrapping ErrSelfTest that names the first failing primitive.
+//
+// Services that must show the crypto behaves as expected can call it once
+// during initialization and refuse to start if it fails.
+func SelfTest() error {
+	var vectors knownAnswers
+	if err := json.Unmarshal(selfTestVectors, &vectors); err != nil {
+		return fmt.Errorf("%w: reading vectors: %v", ErrSelfTest, err)
+	}
+
+	for i, v := range vectors.Digests {
+		digest, ok := selfTestDigests[v.Algorithm]
+		if !ok {
+			return fmt.Errorf("%w: %s digest: unknown algorithm", ErrSelfTest, v.Algorithm)
+		}
+		if got := hex.EncodeToString(digest([]byte(v.Input))); got != v.Digest {
+			return fmt.Errorf("%w: %s digest vector %d: got %s, want %s", ErrSelfTest, v.Algorithm, i, got, v.Digest)
+		}
+		if v.Algorithm != HashAlgorithmKeccak256 {
+			continue
+		}
+		for _, backend := range keccak.Backends() {
+			sum, err := keccak.SumWith(backend, []byte(v.Input))
+			if got := hex.EncodeToString(sum[:]); err != nil || got != v.Digest {
+				return fmt.Errorf("%w: %s digest vector %d with the %s backend: got %s, want %s", ErrSelfTest, v.Algorithm, i, backend, got, v.Digest)
+			}
+		}
+	}
+
+	for i, v := range vectors.LeafHashes {
+		if got := StandardLeafHash(v.Value); got != v.Hash {
+			return fmt.Errorf("%w: StandardLeafHash vector %d: got %s, want %s", ErrSelfTest, i, got, v.Hash)
+		}
+	}
+
+	for i, v := range vectors.NodeHashes {
+		named, ok := lookupNodeHash(v.Algorithm)
+		if !ok {
+			return fmt.Errorf("%w: %s node hash: unknown algorithm", ErrSelfTest, v.Algorithm)
+		}
+		if got := named.hash(v.Left, v.Right); got != v.Hash {
+			return fmt.Errorf("%w: %s node hash vector %d: got %s, want %s", ErrSelfTest, named.descriptor, i, got, v.Hash)
+		}
+		if got := named.hash(v.Right, v.Left); got != v.Hash {
+			return fmt.Errorf("%w: %s node hash vector %d depends on argument order", ErrSelfTest, named.descriptor, i)
+		}
+	}
+
+	return selfTestTree(vectors)
+}
+
+// selfTestTree builds the vector tree with the registered keccak256 node hash
+// and checks its root, a proof, and the verification of that proof.
+func selfTestTree(vectors knownAnswers) error {
+	named, _ := lookupNodeHash(HashAlgorithmKeccak256)
+	nodeHash := named.hash
+
+	tree, err := NewSimpleMerkleTree(toBytesLike(vectors.Tree.Values), SimpleMerkleTreeOptions{NodeHash: nodeHash})
+	if err != nil {
+		return fmt.Errorf("%w: tree construction: %v", ErrSelfTest, err)
+	}
+	if tree.Root() != vectors.Tree.Root {
+		return fmt.Errorf("%w: tree root: got %s, want %s", ErrSelfTest, tree.Root(), vectors.Tree.Root)
+	}
+
+	proof, err := tree.ProofForIndex(vectors.Tree.ProofIndex)
+	if err != nil {
+		return fmt.Errorf("%w: proof generation: %v", ErrSelfTest, err)
+	}
+	if len(proof) != len(vectors.Tree.Proof) {
+		return fmt.Errorf("%w: proof generation: got %d siblings, want %d", ErrSelfTest, len(proof), len(vectors.Tree.Proof))
+	}
+	for i := range proof {
+		if proof[i] != vectors.Tree.Proof[i] {
+			return fmt.Errorf("%w: proof generation: sibling %d is %s, want %s", ErrSelfTest, i, proof[i], vectors.Tree.Proof[i])
+		}
+	}
+
+	bytesProof := make([]BytesLike, len(proof))
+	for i, p := range proof {
+		bytesProof[i] = p
+	}
+	value := vectors.Tree.Values[vectors.Tree.ProofIndex]
+	valid, err := VerifySimpleMerkleTree(vectors.Tree.Root, value, bytesProof, nodeHash)
+	if err != nil || !valid {
+		return fmt.Errorf("%w: proof verification: valid=%v err=%v", ErrSelfTest, valid, err)
+	}
+	return nil
+}
+
+// toBytesLike converts strings to BytesLike values.
+func toBytesLike(values []string) []BytesLike {
+	out := make([]BytesLike, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
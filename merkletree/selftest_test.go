@@ -0,0 +1,74 @@
+package merkletree
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	if err := SelfTest(); err != nil {
+		t.Fatalf("Self test failed: %v", err)
+	}
+}
+
+func TestSelfTestDetectsDivergence(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch func()
+		want  string
+	}{
+		{
+			name: "node hash",
+			patch: func() {
+				named := namedNodeHashes[HashAlgorithmSHA256]
+				named.hash = StandardNodeHash
+				namedNodeHashes[HashAlgorithmSHA256] = named
+			},
+			want: HashSHA256Sorted,
+		},
+		{
+			name: "order-sensitive node hash",
+			patch: func() {
+				named := namedNodeHashes[HashAlgorithmKeccak256]
+				named.hash = func(a, b BytesLike) HexString {
+					if a == HexString("0x38e47a7b719dce63662aeaf43440326f551b8a7ee198cee35cb5d517f2d296a2") {
+						return SHA256NodeHash(a, b)
+					}
+					return StandardNodeHash(a, b)
+				}
+				namedNodeHashes[HashAlgorithmKeccak256] = named
+			},
+			want: "argument order",
+		},
+		{
+			name: "digest",
+			patch: func() {
+				selfTestDigests[HashAlgorithmKeccak256] = selfTestDigests[HashAlgorithmSHA256]
+			},
+			want: "keccak256 digest",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			savedHashes := namedNodeHashes[HashAlgorithmKeccak256]
+			savedSHA := namedNodeHashes[HashAlgorithmSHA256]
+			savedDigest := selfTestDigests[HashAlgorithmKeccak256]
+			defer func() {
+				namedNodeHashes[HashAlgorithmKeccak256] = savedHashes
+				namedNodeHashes[HashAlgorithmSHA256] = savedSHA
+				selfTestDigests[HashAlgorithmKeccak256] = savedDigest
+			}()
+
+			tt.patch()
+			err := SelfTest()
+			if !errors.Is(err, ErrSelfTest) {
+				t.Fatalf("Expected ErrSelfTest, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Error %q does not name %q", err, tt.want)
+			}
+		})
+	}
+}
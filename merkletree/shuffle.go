@@ -0,0 +1,86 @@
+package merkletree
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"slices"
+
+	"github.com/smeneguz/GoMerkle/internal/keccak"
+)
+
+// shuffleKey returns the position key of a leaf in a shuffled tree,
+// keccak256(seed || leaf).
+func shuffleKey(seed, leaf []byte) [32]byte {
+	hasher := keccak.New()
+	hasher.Write(seed)
+	hasher.Write(leaf)
+	var key [32]byte
+	hasher.Sum(key[:0])
+	return key
+}
+
+// sortByShuffleKey orders items by the shuffle key of their leaves under
+// seed. The sort is stable, so equal leaves keep their order.
+func sortByShuffleKey[E any](seed []byte, items []E, leaf func(E) []byte) {
+	type keyed struct {
+		key  [32]byte
+		item E
+	}
+	sorted := make([]keyed, len(items))
+	for i, item := range items {
+		sorted[i] = keyed{shuffleKey(seed, leaf(item)), item}
+	}
+	slices.SortStableFunc(sorted, func(a, b keyed) int { return bytes.Compare(a.key[:], b.key[:]) })
+	for i, k := range sorted {
+		items[i] = k.item
+	}
+}
+
+// hexLeafBytes returns the bytes of a leaf hash, or nil if it is not hex.
+func hexLeafBytes(leaf HexString) []byte {
+	b, _ := ToBytes(leaf)
+	return b
+}
+
+// shuffleSeedHex returns the seed as recorded in dumps, empty if there is none.
+func shuffleSeedHex(seed []byte) HexString {
+	if seed == nil {
+		return ""
+	}
+	return HexString("0x" + hex.EncodeToString(seed))
+}
+
+// ShuffleSeed returns the seed the leaves were shuffled with, or nil if the
+// tree is not shuffled or its seed was withheld with RedactShuffleSeed.
+func (m *MerkleTreeImpl[T]) ShuffleSeed() []byte {
+	return slices.Clone(m.shuffleSeed)
+}
+
+// RedactShuffleSeed forgets the shuffle seed, so dumps of the tree say the
+// leaves were shuffled without saying how. The tree and its proofs are
+// unchanged; whoever holds the seed can still check the order with
+// CheckShuffle, but AppendLeaves then fails with ErrSeedWithheld.
+func (m *MerkleTreeImpl[T]) RedactShuffleSeed() {
+	m.shuffleSeed = nil
+}
+
+// CheckShuffle reports whether the leaves are laid out in the order seed
+// gives them, so an auditor holding the seed can confirm the layout of a
+// published tree without its values. A tree in another order fails with
+// ErrInvalidTree.
+func (m *MerkleTreeImpl[T]) CheckShuffle(seed []byte) error {
+	if len(seed) == 0 {
+		return &OptionError{Option: "seed", Value: seed, Reason: "must not be empty"}
+	}
+	first := len(m.Tree) - len(m.Values)
+	var previous [32]byte
+	for i, leaf := range m.Tree[first:] {
+		key := shuffleKey(seed, hexLeafBytes(leaf))
+		if i > 0 && bytes.Compare(previous[:], key[:]) > 0 {
+			return fmt.Errorf("%w: leaf %d is out of shuffle order for this seed", ErrInvalidTree, i)
+		}
+		previous = key
+	}
+	return nil
+}
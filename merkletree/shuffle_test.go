@@ -0,0 +1,171 @@
+package merkletree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// shuffledTree builds a standard tree over n values shuffled with seed.
+func shuffledTree(t *testing.T, n int, seed []byte) *StandardMerkleTree[string] {
+	t.Helper()
+	tree, err := NewStandardMerkleTree(leafSetValues(n), MerkleTreeOptions{ShuffleSeed: seed})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	return tree
+}
+
+func TestShuffleReproducible(t *testing.T) {
+	seed := []byte("drop-2026")
+	first, second := shuffledTree(t, 100, seed), shuffledTree(t, 100, bytes.Clone(seed))
+	if first.Root() != second.Root() {
+		t.Errorf("Same seed built roots %s and %s", first.Root(), second.Root())
+	}
+	if other := shuffledTree(t, 100, []byte("drop-2027")); other.Root() == first.Root() {
+		t.Errorf("Different seeds built the same root %s", first.Root())
+	}
+	if first.Algorithm().LeafOrder != LeafOrderShuffled {
+		t.Errorf("Leaf order %q, want %q", first.Algorithm().LeafOrder, LeafOrderShuffled)
+	}
+}
+
+func TestShuffleProofs(t *testing.T) {
+	tree := shuffledTree(t, 37, []byte{0x01})
+	for i, v := range leafSetValues(37) {
+		proof, err := tree.GetProof(i)
+		if err != nil {
+			t.Fatalf("Failed to get proof %d: %v", i, err)
+		}
+		ok, err := tree.Verify(v, proof)
+		if err != nil || !ok {
+			t.Errorf("Proof of %q does not verify: %v", v, err)
+		}
+	}
+}
+
+func TestCheckShuffle(t *testing.T) {
+	seed := []byte("seed")
+	tree := shuffledTree(t, 50, seed)
+	if err := tree.CheckShuffle(seed); err != nil {
+		t.Errorf("CheckShuffle with the seed failed: %v", err)
+	}
+	if err := tree.CheckShuffle([]byte("other")); !errors.Is(err, ErrInvalidTree) {
+		t.Errorf("Expected ErrInvalidTree for another seed, got %v", err)
+	}
+	var optErr *OptionError
+	if err := tree.CheckShuffle(nil); !errors.As(err, &optErr) {
+		t.Errorf("Expected an OptionError for no seed, got %v", err)
+	}
+}
+
+func TestShuffleRedact(t *testing.T) {
+	seed := []byte("secret")
+	tree := shuffledTree(t, 10, seed)
+	dump, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+	if dump.ShuffleSeed != "0x736563726574" {
+		t.Errorf("Dump records seed %q", dump.ShuffleSeed)
+	}
+
+	tree.RedactShuffleSeed()
+	if dump, _ = tree.Dump(); dump.ShuffleSeed != "" || dump.Algorithm.LeafOrder != LeafOrderShuffled {
+		t.Errorf("Redacted dump records seed %q, order %q", dump.ShuffleSeed, dump.Algorithm.LeafOrder)
+	}
+	if tree.ShuffleSeed() != nil {
+		t.Errorf("ShuffleSeed() = %x after redaction", tree.ShuffleSeed())
+	}
+	if err := tree.AppendLeaves([]string{"late"}); !errors.Is(err, ErrSeedWithheld) {
+		t.Errorf("Expected ErrSeedWithheld, got %v", err)
+	}
+	if err := tree.CheckShuffle(seed); err != nil {
+		t.Errorf("CheckShuffle after redaction failed: %v", err)
+	}
+}
+
+func TestShuffleAppendMatchesRebuild(t *testing.T) {
+	seed := []byte("append")
+	values := leafSetValues(20)
+	tree, err := NewStandardMerkleTree(values[:12], MerkleTreeOptions{ShuffleSeed: seed})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if err := tree.AppendLeaves(values[12:]); err != nil {
+		t.Fatalf("AppendLeaves failed: %v", err)
+	}
+	if rebuilt := shuffledTree(t, 20, seed); tree.Root() != rebuilt.Root() {
+		t.Errorf("Appended root %s, rebuilt %s", tree.Root(), rebuilt.Root())
+	}
+}
+
+func TestShuffleSimpleDumpRoundTrip(t *testing.T) {
+	seed := []byte{0xca, 0xfe}
+	var values []BytesLike
+	for i := range 9 {
+		values = append(values, fmt.Sprintf("0x%064x", i))
+	}
+	tree, err := NewSimpleMerkleTree(values, SimpleMerkleTreeOptions{MerkleTreeOptions: MerkleTreeOptions{ShuffleSeed: seed}})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	dump, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+	loaded, _, err := LoadSimpleMerkleTree(dump, nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !bytes.Equal(loaded.ShuffleSeed(), seed) || loaded.Root() != tree.Root() {
+		t.Errorf("Loaded seed %x, root %s", loaded.ShuffleSeed(), loaded.Root())
+	}
+
+	dump.ShuffleSeed = "0xbeef"
+	if _, _, err := LoadSimpleMerkleTree(dump, nil); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("Expected ErrInvalidDump for the wrong seed, got %v", err)
+	}
+}
+
+func TestShuffleLeafSet(t *testing.T) {
+	options := MerkleTreeOptions{ShuffleSeed: []byte("stream")}
+	set, err := NewLeafSet[string](options)
+	if err != nil {
+		t.Fatalf("NewLeafSet failed: %v", err)
+	}
+	for _, v := range leafSetValues(64) {
+		if err := set.Add(v); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	root, err := set.Root()
+	if err != nil {
+		t.Fatalf("Root failed: %v", err)
+	}
+	if tree := shuffledTree(t, 64, options.ShuffleSeed); root != tree.Root() {
+		t.Errorf("LeafSet root %s, tree builds %s", root, tree.Root())
+	}
+
+	var checkpoint bytes.Buffer
+	if err := set.Checkpoint(&checkpoint); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	if _, err := ResumeLeafSet[string](&checkpoint, MerkleTreeOptions{ShuffleSeed: []byte("other")}); !errors.Is(err, ErrCheckpointMismatch) {
+		t.Errorf("Expected ErrCheckpointMismatch for another seed, got %v", err)
+	}
+}
+
+func TestShuffleOptionsValidate(t *testing.T) {
+	for _, options := range []MerkleTreeOptions{
+		{ShuffleSeed: []byte{}},
+		{ShuffleSeed: []byte{1}, SortLeaves: Bool(true), SortDescending: true},
+		{ShuffleSeed: []byte{1}, PreserveOrder: true},
+	} {
+		var optErr *OptionError
+		if err := options.Validate(); !errors.As(err, &optErr) {
+			t.Errorf("%+v: expected an OptionError, got %v", options, err)
+		}
+	}
+}
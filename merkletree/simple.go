@@ -1,31 +1,72 @@
 package merkletree
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 )
 
 // SimpleMerkleTree represents a Merkle tree with standard hashing.
 // It's a simpler variant that works with BytesLike values.
 type SimpleMerkleTree struct {
 	MerkleTreeImpl[BytesLike]
+	hashAlgorithm string // Named node hash, or HashCustom
 }
 
 // SimpleMerkleTreeOptions represents the options for the Simple Merkle tree.
 type SimpleMerkleTreeOptions struct {
 	MerkleTreeOptions          // Include base Merkle tree options
 	NodeHash          NodeHash // Custom node hash function (optional)
+	HashAlgorithm     string   // Named node hash, e.g. HashAlgorithmSHA256 or a RegisterNodeHash name (optional, defaults to keccak256)
+}
+
+// resolveNodeHash returns the node hash selected by the options and the name
+// recorded for it in dumps: a NodeHash registered with RegisterNodeHash is
+// recorded by its name, any other as HashCustom. It falls back to StandardNodeHash on error so that
+// the remaining validation can still run.
+func (o SimpleMerkleTreeOptions) resolveNodeHash() (NodeHash, string, error) {
+	if o.NodeHash != nil {
+		if o.HashAlgorithm != "" {
+			return StandardNodeHash, HashCustom, &OptionError{Option: "HashAlgorithm", Value: o.HashAlgorithm, Reason: "cannot be combined with a custom NodeHash"}
+		}
+		if name, ok := nodeHashName(o.NodeHash); ok {
+			return o.NodeHash, name, nil
+		}
+		return o.NodeHash, HashCustom, nil
+	}
+
+	name := o.HashAlgorithm
+	if name == "" {
+		name = HashAlgorithmKeccak256
+	}
+	named, ok := lookupNodeHash(name)
+	if !ok {
+		return StandardNodeHash, HashCustom, &OptionError{Option: "HashAlgorithm", Value: o.HashAlgorithm, Reason: "unknown hash algorithm"}
+	}
+	return named.hash, name, nil
 }
 
 // SimpleMerkleTreeData represents the exportable data of a Simple Merkle tree.
 // This format can be serialized to JSON for storage or transmission.
 type SimpleMerkleTreeData struct {
-	Format string      `json:"format"` // Format version identifier
-	Tree   []HexString `json:"tree"`   // Complete tree structure
-	Values []struct {
-		Value     BytesLike `json:"value"`
-		TreeIndex int       `json:"treeIndex"`
-	} `json:"values"` // Values with their tree positions
-	Hash string `json:"hash"` // Hash function identifier
+	Format string                 `json:"format"` // Format version identifier
+	Tree   []HexString            `json:"tree"`   // Complete tree structure
+	Values []DumpValue[BytesLike] `json:"values"` // Values with their tree positions and metadata
+
+	// Hash is the same as HashAlgorithm. Dumps written before hashAlgorithm
+	// existed always say "custom" here.
+	//
+	// Deprecated: kept for simple-v1 readers; use HashAlgorithm.
+	Hash string `json:"hash"`
+
+	HashAlgorithm     string              `json:"hashAlgorithm,omitempty"`     // Node hash name, or "custom"
+	LeafHashAlgorithm string              `json:"leafHashAlgorithm,omitempty"` // Leaf hash name; "identity" for pre-hashed trees, "keccak256-bytes" for BytesLeafHash, "sha256-bytes" for SHA256LeafHash, "blake2b-256-bytes" for Blake2b256LeafHash
+	Algorithm         AlgorithmDescriptor `json:"algorithm"`                   // Hashes and leaf order used to build the tree
+	Options           *DumpOptions        `json:"options,omitempty"`           // Options that rebuild the tree from its values
+	Quarantined       int                 `json:"quarantined,omitempty"`       // Number of input values left out of the tree
+	ShuffleSeed       HexString           `json:"shuffleSeed,omitempty"`       // Seed of a shuffled leaf order, unless withheld
+	Version           string              `json:"version,omitempty"`           // Library version that wrote the dump
+	Integrity         *DumpIntegrity      `json:"integrity,omitempty"`         // Footer for detecting truncation; always last
 }
 
 // FormatLeaf converts a value to a hashed format for insertion in the Merkle tree.
@@ -34,46 +75,114 @@ func FormatLeaf(value BytesLike) HexString {
 	return StandardLeafHash(value)
 }
 
+// simpleLeafHash returns the leaf hash of simple trees built under mode and
+// its name: BytesLeafHash under CompatLatest, FormatLeaf otherwise.
+func simpleLeafHash(mode CompatibilityMode) (func(BytesLike) HexString, string) {
+	if mode == CompatLatest {
+		return BytesLeafHash, HashKeccak256Bytes
+	}
+	return FormatLeaf, HashKeccak256Packed
+}
+
+// dumpLeafHashAlgorithm returns the leafHashAlgorithm a simple tree dump
+// records for the leaf hash named leafHash in the tree's descriptor.
+func dumpLeafHashAlgorithm(leafHash string) string {
+	switch leafHash {
+	case HashIdentity, HashKeccak256Bytes, HashSHA256Bytes, HashBlake2b256Bytes:
+		return leafHash
+	}
+	return HashAlgorithmKeccak256
+}
+
 // NewSimpleMerkleTree creates a new SimpleMerkleTree with the given values.
-// Optionally accepts a custom node hash function via options.
+// The node hash is selected by name with options.HashAlgorithm, or supplied
+// directly with options.NodeHash. Values are hashed with FormatLeaf, or with
+// BytesLeafHash under CompatLatest.
 // Returns an error if tree construction fails.
 func NewSimpleMerkleTree(values []BytesLike, options SimpleMerkleTreeOptions) (*SimpleMerkleTree, error) {
+	return newSimpleMerkleTree(values, options, simpleLeafHash)
+}
+
+// NewSimpleMerkleTreeSHA256 creates a SimpleMerkleTree hashed with SHA-256
+// throughout: values are hashed with SHA256LeafHash and nodes with
+// SHA256NodeHash. Its dump records "sha256" as the hash algorithm and
+// "sha256-bytes" as the leaf hash algorithm, and its proofs verify with
+// VerifySimpleMerkleTreeSHA256. Compatibility only selects the leaf order.
+func NewSimpleMerkleTreeSHA256(values []BytesLike, options MerkleTreeOptions) (*SimpleMerkleTree, error) {
+	return newSimpleMerkleTree(values, SimpleMerkleTreeOptions{MerkleTreeOptions: options, HashAlgorithm: HashAlgorithmSHA256},
+		func(CompatibilityMode) (func(BytesLike) HexString, string) { return SHA256LeafHash, HashSHA256Bytes })
+}
+
+// NewSimpleMerkleTreeBlake2b256 is NewSimpleMerkleTreeSHA256 with BLAKE2b-256:
+// values are hashed with Blake2b256LeafHash and nodes with Blake2b256NodeHash,
+// the dump records "blake2b-256" and "blake2b-256-bytes", and proofs verify
+// with VerifySimpleMerkleTreeBlake2b256.
+func NewSimpleMerkleTreeBlake2b256(values []BytesLike, options MerkleTreeOptions) (*SimpleMerkleTree, error) {
+	return newSimpleMerkleTree(values, SimpleMerkleTreeOptions{MerkleTreeOptions: options, HashAlgorithm: HashAlgorithmBlake2b256},
+		func(CompatibilityMode) (func(BytesLike) HexString, string) {
+			return Blake2b256LeafHash, HashBlake2b256Bytes
+		})
+}
+
+// newSimpleMerkleTree builds a simple tree whose leaf hash, and its name,
+// leafHashFor selects for the compatibility mode.
+func newSimpleMerkleTree(values []BytesLike, options SimpleMerkleTreeOptions, leafHashFor func(CompatibilityMode) (func(BytesLike) HexString, string)) (*SimpleMerkleTree, error) {
 	options.MerkleTreeOptions = NewMerkleTreeOptions(&options.MerkleTreeOptions)
 
-	// Use standard node hash if not provided
-	if options.NodeHash == nil {
-		options.NodeHash = StandardNodeHash
-	}
+	nodeHash, hashAlgorithm, hashErr := options.resolveNodeHash()
+	leafHash, leafHashName := leafHashFor(options.Compatibility)
 
-	tree, indexedValues, err := PrepareMerkleTree(values, options.MerkleTreeOptions, FormatLeaf, options.NodeHash)
-	if err != nil {
+	tree, indexedValues, quarantine, err := prepareMerkleTree(values, options.MerkleTreeOptions, leafHash, nodeHash)
+	if err := errors.Join(hashErr, err); err != nil {
 		return nil, fmt.Errorf("failed to prepare merkle tree: %w", err)
 	}
 
-	// Build hash lookup map
-	hashLookup := make(map[HexString]int)
-	for i, v := range indexedValues {
-		hash := FormatLeaf(v.Value)
-		hashLookup[hash] = i
-	}
-
-	return &SimpleMerkleTree{
+	t := &SimpleMerkleTree{
 		MerkleTreeImpl[BytesLike]{
-			Tree:       tree,
-			Values:     indexedValues,
-			LeafHash:   FormatLeaf,
-			NodeHash:   options.NodeHash,
-			HashLookup: hashLookup,
+			Tree:     tree,
+			Values:   indexedValues,
+			LeafHash: leafHash,
+			NodeHash: nodeHash,
 		},
-	}, nil
+		hashAlgorithm,
+	}
+	t.algorithm = simpleAlgorithm(hashAlgorithm, options.leafOrder())
+	t.algorithm.LeafHash = leafHashName
+	t.compatibility = options.Compatibility
+	t.maxValueBytes = options.MaxValueBytes
+	t.shuffleSeed = slices.Clone(options.ShuffleSeed)
+	t.warnings = options.compatWarnings()
+	t.setQuarantine(quarantine)
+	t.setMetadata(withoutQuarantined(options.LeafMetadata, quarantine))
+	t.configureIndexes(options.MerkleTreeOptions)
+	t.configurePregeneration(options.MerkleTreeOptions)
+	if options.DropValuesAfterBuild {
+		t.dropValues()
+	}
+	return t, nil
+}
+
+// VerifySimpleMerkleTreeSHA256 verifies a proof of value from a tree built
+// with NewSimpleMerkleTreeSHA256 against root.
+func VerifySimpleMerkleTreeSHA256(root BytesLike, value BytesLike, proof []BytesLike) (bool, error) {
+	return verifySimpleLeaf(root, SHA256LeafHash(value), proof, SHA256NodeHash)
+}
+
+// VerifySimpleMerkleTreeBlake2b256 verifies a proof of value from a tree
+// built with NewSimpleMerkleTreeBlake2b256 against root.
+func VerifySimpleMerkleTreeBlake2b256(root BytesLike, value BytesLike, proof []BytesLike) (bool, error) {
+	return verifySimpleLeaf(root, Blake2b256LeafHash(value), proof, Blake2b256NodeHash)
 }
 
 // VerifySimpleMerkleTree verifies a Merkle proof for a specific value.
 // This is a standalone function that can verify proofs without instantiating a tree.
 // Returns true if the proof is valid, false otherwise.
 func VerifySimpleMerkleTree(root BytesLike, leaf BytesLike, proof []BytesLike, nodeHash NodeHash) (bool, error) {
-	leafHash := StandardLeafHash(leaf)
+	return verifySimpleLeaf(root, StandardLeafHash(leaf), proof, nodeHash)
+}
 
+// verifySimpleLeaf verifies a proof of a leaf hash against root.
+func verifySimpleLeaf(root BytesLike, leafHash HexString, proof []BytesLike, nodeHash NodeHash) (bool, error) {
 	// Use standard node hash if not provided
 	if nodeHash == nil {
 		nodeHash = StandardNodeHash
@@ -99,24 +208,88 @@ func VerifySimpleMerkleTree(root BytesLike, leaf BytesLike, proof []BytesLike, n
 	return computedRootVal == rootVal, nil
 }
 
+// SimpleVerifyOptions configures VerifySimpleMerkleTreeWithOptions.
+type SimpleVerifyOptions struct {
+	// NodeHash hashes internal nodes; nil means StandardNodeHash.
+	NodeHash NodeHash
+
+	// PackedProof, if set, is the proof as one byte string of concatenated
+	// 32-byte nodes, and the proof argument must be empty.
+	PackedProof []byte
+
+	// Compatibility is the mode the tree was built with, which selects the
+	// leaf hash: BytesLeafHash under CompatLatest, StandardLeafHash otherwise.
+	Compatibility CompatibilityMode
+}
+
+// VerifySimpleMerkleTreeWithOptions is VerifySimpleMerkleTree with options,
+// such as a proof in packed form. A packed proof whose length is not a
+// multiple of 32 fails with ErrInvalidPackedProof.
+func VerifySimpleMerkleTreeWithOptions(root BytesLike, leaf BytesLike, proof []BytesLike, opts SimpleVerifyOptions) (bool, error) {
+	if opts.PackedProof != nil {
+		if len(proof) > 0 {
+			return false, &OptionError{Option: "PackedProof", Value: len(opts.PackedProof), Reason: "cannot be combined with a proof argument"}
+		}
+		nodes, err := ProofFromPackedBytes(opts.PackedProof)
+		if err != nil {
+			return false, err
+		}
+		proof = make([]BytesLike, len(nodes))
+		for i, node := range nodes {
+			proof[i] = node
+		}
+	}
+	leafHash, _ := simpleLeafHash(opts.Compatibility)
+	return verifySimpleLeaf(root, leafHash(leaf), proof, opts.NodeHash)
+}
+
 // Dump exports the tree data for debugging, storage, or transmission.
 // The exported data can be serialized to JSON and later reconstructed.
-func (m *SimpleMerkleTree) Dump() SimpleMerkleTreeData {
+// Returns ErrValuesDropped if the values were dropped and there is no ValueProvider.
+func (m *SimpleMerkleTree) Dump() (SimpleMerkleTreeData, error) {
 	// Convert values to the format with JSON tags
-	values := make([]struct {
-		Value     BytesLike `json:"value"`
-		TreeIndex int       `json:"treeIndex"`
-	}, len(m.Values))
+	values := make([]DumpValue[BytesLike], len(m.Values))
 
 	for i, v := range m.Values {
-		values[i].Value = v.Value
+		value, err := m.valueAt(i)
+		if err != nil {
+			return SimpleMerkleTreeData{}, err
+		}
+		values[i].Value = value
 		values[i].TreeIndex = v.TreeIndex
+		values[i].Metadata = m.metadataAt(i)
+	}
+
+	integrity, err := newDumpIntegrity(m.Tree, values, len(values))
+	if err != nil {
+		return SimpleMerkleTreeData{}, err
 	}
 
 	return SimpleMerkleTreeData{
-		Format: "simple-v1",
-		Tree:   m.Tree,
-		Values: values,
-		Hash:   "custom",
+		Format:            simpleFormat,
+		Tree:              m.Tree,
+		Values:            values,
+		Hash:              m.hashAlgorithm,
+		HashAlgorithm:     m.hashAlgorithm,
+		LeafHashAlgorithm: dumpLeafHashAlgorithm(m.algorithm.LeafHash),
+		Algorithm:         m.algorithm,
+		Options:           newDumpOptions(m.algorithm, m.compatibility, m.hashAlgorithm),
+		Quarantined:       m.quarantined,
+		ShuffleSeed:       shuffleSeedHex(m.shuffleSeed),
+		Version:           version,
+		Integrity:         integrity,
+	}, nil
+}
+
+// simpleAlgorithm returns the descriptor of a simple tree built with the named node hash.
+func simpleAlgorithm(hashAlgorithm, leafOrder string) AlgorithmDescriptor {
+	nodeHash := HashCustom
+	if named, ok := lookupNodeHash(hashAlgorithm); ok {
+		nodeHash = named.descriptor
+	}
+	return AlgorithmDescriptor{
+		LeafHash:  HashKeccak256Packed,
+		NodeHash:  nodeHash,
+		LeafOrder: leafOrder,
 	}
 }
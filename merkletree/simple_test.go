@@ -1,6 +1,9 @@
 package merkletree
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
 	"testing"
 )
 
@@ -146,7 +149,10 @@ func TestSimpleMerkleTreeDump(t *testing.T) {
 	}
 
 	// Dump the tree
-	data := tree.Dump()
+	data, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
 
 	// Verify dump format
 	if data.Format != "simple-v1" {
@@ -172,7 +178,7 @@ func TestSimpleMerkleTreeWithSortedLeaves(t *testing.T) {
 
 	// Create tree with sorted leaves
 	tree1, err := NewSimpleMerkleTree(values, SimpleMerkleTreeOptions{
-		MerkleTreeOptions: MerkleTreeOptions{SortLeaves: true},
+		MerkleTreeOptions: MerkleTreeOptions{SortLeaves: Bool(true)},
 	})
 	if err != nil {
 		t.Fatalf("Failed to create sorted tree: %v", err)
@@ -187,7 +193,7 @@ func TestSimpleMerkleTreeWithSortedLeaves(t *testing.T) {
 	}
 
 	tree2, err := NewSimpleMerkleTree(valuesShuffled, SimpleMerkleTreeOptions{
-		MerkleTreeOptions: MerkleTreeOptions{SortLeaves: true},
+		MerkleTreeOptions: MerkleTreeOptions{SortLeaves: Bool(true)},
 	})
 	if err != nil {
 		t.Fatalf("Failed to create sorted tree 2: %v", err)
@@ -224,3 +230,128 @@ func TestSimpleMerkleTreeErrors(t *testing.T) {
 		}
 	})
 }
+
+// TestSimpleMerkleTreeSHA256 checks SHA-256 trees against roots computed
+// independently with Python's hashlib: leaves are sha256(value), sorted, and
+// paired with sha256(min || max).
+func TestSimpleMerkleTreeSHA256(t *testing.T) {
+	if got := SHA256LeafHash("abc"); got != "0xba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Errorf("SHA256LeafHash(abc) = %s", got)
+	}
+	if SHA256LeafHash("0x616263") != SHA256LeafHash("abc") || SHA256LeafHash([]byte("abc")) != SHA256LeafHash("abc") {
+		t.Error("SHA256LeafHash reads hex strings and bytes differently")
+	}
+
+	logLines := make([]BytesLike, 8)
+	for i := range logLines {
+		logLines[i] = fmt.Sprintf("log line %d", i)
+	}
+	for _, tt := range []struct {
+		values []BytesLike
+		root   HexString
+	}{
+		{[]BytesLike{"a", "b", "c", "d"}, "0x4c6aae040ffada3d02598207b8485fcbe161c03f4cb3f660e4d341e7496ff3b2"},
+		{logLines, "0x53ce86e7efec43b25ad40db6c4e8f00a2fb79a932433ff032a0ad5817cb3f9d4"},
+	} {
+		for _, mode := range []CompatibilityMode{CompatV0, CompatLatest} {
+			tree, err := NewSimpleMerkleTreeSHA256(tt.values, MerkleTreeOptions{SortLeaves: Bool(true), Compatibility: mode})
+			if err != nil {
+				t.Fatalf("Failed to create tree: %v", err)
+			}
+			if tree.Root() != tt.root {
+				t.Errorf("Root of %d values under %v = %s, want %s", len(tt.values), mode, tree.Root(), tt.root)
+			}
+			for i, value := range tt.values {
+				proof, err := tree.GetProof(i)
+				if err != nil {
+					t.Fatalf("GetProof(%d) failed: %v", i, err)
+				}
+				if valid, err := VerifySimpleMerkleTreeSHA256(tt.root, value, treeNodes(proof)); err != nil || !valid {
+					t.Errorf("Proof of %v does not verify: %v", value, err)
+				}
+			}
+		}
+	}
+
+	tree, err := NewSimpleMerkleTreeSHA256([]BytesLike{"a", "b", "c"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	dump, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	if dump.HashAlgorithm != HashAlgorithmSHA256 || dump.LeafHashAlgorithm != HashSHA256Bytes || dump.Algorithm.NodeHash != HashSHA256Sorted {
+		t.Errorf("Dump hashes %q, %q, %+v", dump.HashAlgorithm, dump.LeafHashAlgorithm, dump.Algorithm)
+	}
+	encoded, _ := json.Marshal(dump)
+	var data SimpleMerkleTreeData
+	if err := json.Unmarshal(encoded, &data); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	loaded, _, err := LoadSimpleMerkleTree(data, nil)
+	if err != nil {
+		t.Fatalf("Failed to load dump: %v", err)
+	}
+	if loaded.Root() != tree.Root() || loaded.Algorithm() != tree.Algorithm() {
+		t.Errorf("Loaded %s %+v, want %s %+v", loaded.Root(), loaded.Algorithm(), tree.Root(), tree.Algorithm())
+	}
+	proof, _ := loaded.GetProof("b")
+	if valid, err := loaded.Verify("b", proof); err != nil || !valid {
+		t.Errorf("Proof of b does not verify after loading: %v", err)
+	}
+}
+
+// TestSimpleMerkleTreeBlake2b256 checks a BLAKE2b-256 tree of 1000 random
+// values against the root testdata/blake2b256.py computes with hashlib.
+func TestSimpleMerkleTreeBlake2b256(t *testing.T) {
+	if got := Blake2b256LeafHash("abc"); got != "0xbddd813c634239723171ef3fee98579b94964e3bb1cb3e427262c8c068d52319" {
+		t.Errorf("Blake2b256LeafHash(abc) = %s", got)
+	}
+	a, b := Blake2b256LeafHash("a"), Blake2b256LeafHash("b")
+	want := HexString("0xe4a89351b8233cf105a5b3219c78503c858a1783fcd5ee0f0fb217d79becf323")
+	if Blake2b256NodeHash(a, b) != want || Blake2b256NodeHash(b, a) != want {
+		t.Errorf("Blake2b256NodeHash = %s, %s; want %s", Blake2b256NodeHash(a, b), Blake2b256NodeHash(b, a), want)
+	}
+
+	data, err := os.ReadFile("testdata/blake2b256.json")
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	var fixture struct {
+		Values []HexString `json:"values"`
+		Root   HexString   `json:"root"`
+	}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatalf("Failed to decode fixture: %v", err)
+	}
+	values := treeNodes(fixture.Values)
+	tree, err := NewSimpleMerkleTreeBlake2b256(values, MerkleTreeOptions{SortLeaves: Bool(true)})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if tree.Root() != fixture.Root {
+		t.Fatalf("Root %s, reference %s", tree.Root(), fixture.Root)
+	}
+	for i, value := range values {
+		proof, err := tree.GetProof(i)
+		if err != nil {
+			t.Fatalf("GetProof(%d) failed: %v", i, err)
+		}
+		if valid, err := VerifySimpleMerkleTreeBlake2b256(fixture.Root, value, treeNodes(proof)); err != nil || !valid {
+			t.Fatalf("Proof of value %d does not verify: %v", i, err)
+		}
+	}
+
+	dump, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	if dump.HashAlgorithm != HashAlgorithmBlake2b256 || dump.LeafHashAlgorithm != HashBlake2b256Bytes {
+		t.Errorf("Dump hashes %q, %q", dump.HashAlgorithm, dump.LeafHashAlgorithm)
+	}
+	loaded, _, err := LoadSimpleMerkleTree(dump, nil)
+	if err != nil || loaded.Root() != fixture.Root {
+		t.Errorf("Loaded dump: %v", err)
+	}
+}
@@ -0,0 +1,218 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+// DumpFormat is a serialization whose size SerializedDumpSizeEstimate predicts.
+type DumpFormat int
+
+const (
+	DumpFormatStandard  DumpFormat = iota // StandardMerkleTree.Dump marshalled with json.Marshal
+	DumpFormatSimple                      // SimpleMerkleTree.Dump marshalled with json.Marshal
+	DumpFormatLeavesRaw                   // DumpLeaves with LeavesEncodingRaw
+	DumpFormatLeavesHex                   // DumpLeaves with LeavesEncodingHex
+)
+
+// Sizes of the parts of a serialized tree.
+const (
+	hexNodeJSONBytes  = 2 + 66 // A node as a quoted "0x..." string
+	hexNodeLineBytes  = 66 + 1 // A node on its own line in a hex leaves dump
+	valueEntryJSONLen = len(`{"value":`) + len(`,"treeIndex":`) + len(`}`)
+)
+
+// TreeNodeCount returns the number of nodes in the flat array of a tree of
+// leafCount leaves built with opts, which is what len(Tree) will be. It fails
+// with ErrEmptyTree for no leaves, with the errors of opts, and with an error
+// wrapping ErrTooManyLeaves when the count does not fit in an int.
+//
+// TreeNodeCount, TreeDepthFor and LeafRange take the options of the build so
+// that layouts chosen by options are sized too; every current option leaves
+// the layout at 2*leafCount-1 nodes.
+func TreeNodeCount(leafCount int, opts MerkleTreeOptions) (int, error) {
+	if leafCount <= 0 {
+		return 0, ErrEmptyTree
+	}
+	if err := errors.Join(opts.validateInput(leafCount)...); err != nil {
+		return 0, err
+	}
+	if leafCount > math.MaxInt/2+1 {
+		return 0, fmt.Errorf("%w: %d leaves need more than %d nodes", ErrTooManyLeaves, leafCount, math.MaxInt)
+	}
+	return 2*leafCount - 1, nil
+}
+
+// TreeDepthFor returns the length of the longest proof of a tree of leafCount
+// leaves built with opts: 0 for a single leaf, and ceil(log2(leafCount)) in
+// general. It returns -1 where TreeNodeCount fails.
+func TreeDepthFor(leafCount int, opts MerkleTreeOptions) int {
+	nodes, err := TreeNodeCount(leafCount, opts)
+	if err != nil {
+		return -1
+	}
+	return nodeDepth(nodes - 1)
+}
+
+// LeafRange returns the first and last index of the leaves in the flat array
+// of a tree of leafCount leaves built with opts. Leaves fill the end of the
+// array whatever their order. It returns -1, -1 where TreeNodeCount fails.
+func LeafRange(leafCount int, opts MerkleTreeOptions) (firstIndex, lastIndex int) {
+	nodes, err := TreeNodeCount(leafCount, opts)
+	if err != nil {
+		return -1, -1
+	}
+	return nodes - leafCount, nodes - 1
+}
+
+// SerializedDumpSizeEstimate returns the size in bytes of a tree of leafCount
+// values built with opts, serialized in format. avgValueLen is the average
+// length of a value as JSON, such as 44 for a 42-character address string;
+// leaves dumps ignore it. The result is exact when every value has that
+// length and the tree has no leaf metadata or quarantined values; indented
+// JSON is larger. A simple tree is taken to use the default keccak256 hash.
+//
+// It returns -1 where TreeNodeCount fails, for a negative avgValueLen or an
+// unknown format, and when the size does not fit in an int64.
+func SerializedDumpSizeEstimate(leafCount, avgValueLen int, format DumpFormat, opts MerkleTreeOptions) int64 {
+	nodes, err := TreeNodeCount(leafCount, opts)
+	if err != nil || avgValueLen < 0 {
+		return -1
+	}
+	opts = NewMerkleTreeOptions(&opts)
+	n := uint64(leafCount)
+
+	var skeleton any
+	var body sizeSum
+	switch format {
+	case DumpFormatStandard, DumpFormatSimple:
+		first, last := LeafRange(leafCount, opts)
+		body.add(uint64(nodes), hexNodeJSONBytes)
+		body.add(uint64(nodes-1), 1) // Commas
+		body.add(n, uint64(valueEntryJSONLen))
+		body.add(n, uint64(avgValueLen))
+		body.add(decimalDigits(first, last), 1)
+		body.add(n-1, 1)
+		skeleton = dumpSkeleton(format, leafCount, opts)
+	case DumpFormatLeavesRaw:
+		body.add(n, 32)
+		skeleton = leavesSkeleton(leafCount, LeavesEncodingRaw, opts)
+	case DumpFormatLeavesHex:
+		body.add(n, hexNodeLineBytes)
+		skeleton = leavesSkeleton(leafCount, LeavesEncodingHex, opts)
+	default:
+		return -1
+	}
+
+	fixed, err := json.Marshal(skeleton)
+	if err != nil {
+		return -1
+	}
+	body.add(uint64(len(fixed)), 1)
+	if format == DumpFormatLeavesRaw || format == DumpFormatLeavesHex {
+		body.add(1, 1) // Newline after the header
+	}
+	if body.overflow || body.total > math.MaxInt64 {
+		return -1
+	}
+	return int64(body.total)
+}
+
+// dumpSkeleton returns the JSON dump of a tree of leafCount values with empty
+// tree and values sections, whose size is everything in the dump that does
+// not grow with the nodes and values.
+func dumpSkeleton(format DumpFormat, leafCount int, opts MerkleTreeOptions) any {
+	integrity := &DumpIntegrity{
+		ValueCount:   leafCount,
+		TreeSHA256:   strings.Repeat("0", 64),
+		ValuesSHA256: strings.Repeat("0", 64),
+		Root:         zeroNode,
+	}
+	if format == DumpFormatSimple {
+		_, leafHash := simpleLeafHash(opts.Compatibility)
+		algorithm := simpleAlgorithm(HashAlgorithmKeccak256, opts.leafOrder())
+		algorithm.LeafHash = leafHash
+		return SimpleMerkleTreeData{
+			Format:            simpleFormat,
+			Tree:              []HexString{},
+			Values:            make([]DumpValue[BytesLike], 0),
+			Hash:              HashAlgorithmKeccak256,
+			HashAlgorithm:     HashAlgorithmKeccak256,
+			LeafHashAlgorithm: dumpLeafHashAlgorithm(leafHash),
+			Algorithm:         algorithm,
+			Options:           newDumpOptions(algorithm, opts.Compatibility, HashAlgorithmKeccak256),
+			Version:           version,
+			Integrity:         integrity,
+		}
+	}
+
+	leafHash := HashKeccak256Packed
+	if opts.Compatibility == CompatLatest {
+		leafHash = HashKeccak256Double
+	}
+	algorithm := AlgorithmDescriptor{LeafHash: leafHash, NodeHash: HashKeccak256Sorted, LeafOrder: opts.leafOrder()}
+	return StandardMerkleTreeData[json.RawMessage]{
+		Format:    standardFormat,
+		Tree:      []HexString{},
+		Values:    make([]DumpValue[json.RawMessage], 0),
+		Algorithm: algorithm,
+		Options:   newDumpOptions(algorithm, opts.Compatibility, HashAlgorithmKeccak256),
+		Version:   version,
+		Integrity: integrity,
+	}
+}
+
+// leavesSkeleton returns the header line of a leaves dump of a standard tree.
+func leavesSkeleton(leafCount int, encoding string, opts MerkleTreeOptions) leavesHeader {
+	leafHash := HashKeccak256Packed
+	if opts.Compatibility == CompatLatest {
+		leafHash = HashKeccak256Double
+	}
+	return leavesHeader{
+		Format:    leavesFormat,
+		Algorithm: AlgorithmDescriptor{LeafHash: leafHash, NodeHash: HashKeccak256Sorted, LeafOrder: opts.leafOrder()},
+		LeafCount: leafCount,
+		Root:      zeroNode,
+		Encoding:  encoding,
+		Version:   version,
+	}
+}
+
+// zeroNode stands in for a node of the same length in a skeleton.
+var zeroNode = HexString("0x" + strings.Repeat("0", 64))
+
+// decimalDigits returns the total number of decimal digits of the integers
+// from lo to hi, both non-negative.
+func decimalDigits(lo, hi int) uint64 {
+	var total uint64
+	start, digits := uint64(0), uint64(1)
+	for end := uint64(9); start <= uint64(hi); digits++ {
+		from, to := max(start, uint64(lo)), min(end, uint64(hi))
+		if from <= to {
+			total += (to - from + 1) * digits
+		}
+		if end > math.MaxUint64/10 {
+			break
+		}
+		start, end = end+1, end*10+9
+	}
+	return total
+}
+
+// sizeSum adds products of sizes, noting when the total overflows.
+type sizeSum struct {
+	total    uint64
+	overflow bool
+}
+
+// add adds count*size to the sum.
+func (s *sizeSum) add(count, size uint64) {
+	hi, lo := bits.Mul64(count, size)
+	sum, carry := bits.Add64(s.total, lo, 0)
+	s.total = sum
+	s.overflow = s.overflow || hi != 0 || carry != 0
+}
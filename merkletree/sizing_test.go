@@ -0,0 +1,158 @@
+package merkletree
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// sizingCounts covers single leaves, perfect trees and the sizes either side
+// of a power of two, where the depth and the digits of tree indices change.
+var sizingCounts = []int{1, 2, 3, 4, 5, 7, 8, 9, 10, 16, 17, 50, 100, 101, 513}
+
+func TestTreeSizingMatchesBuild(t *testing.T) {
+	for _, opts := range []MerkleTreeOptions{{}, {SortLeaves: Bool(true)}, {Compatibility: CompatLatest}} {
+		for _, n := range sizingCounts {
+			values := make([]string, n)
+			for i := range values {
+				values[i] = fmt.Sprintf("value-%04d", i)
+			}
+			tree, err := NewStandardMerkleTree(values, opts)
+			if err != nil {
+				t.Fatalf("Failed to create tree: %v", err)
+			}
+
+			nodes, err := TreeNodeCount(n, opts)
+			if err != nil || nodes != len(tree.Tree) {
+				t.Errorf("%d leaves: TreeNodeCount is %d (err %v), tree has %d nodes", n, nodes, err, len(tree.Tree))
+			}
+
+			first, last := LeafRange(n, opts)
+			lowest, highest := len(tree.Tree), -1
+			for _, v := range tree.Values {
+				lowest, highest = min(lowest, v.TreeIndex), max(highest, v.TreeIndex)
+			}
+			if first != lowest || last != highest {
+				t.Errorf("%d leaves: LeafRange is %d-%d, leaves are at %d-%d", n, first, last, lowest, highest)
+			}
+
+			stats, err := tree.ProofStats()
+			if err != nil {
+				t.Fatalf("ProofStats failed: %v", err)
+			}
+			if depth := TreeDepthFor(n, opts); depth != stats.MaxProofLength {
+				t.Errorf("%d leaves: TreeDepthFor is %d, longest proof is %d", n, depth, stats.MaxProofLength)
+			}
+		}
+	}
+}
+
+func TestSerializedDumpSizeEstimateMatchesDump(t *testing.T) {
+	for _, opts := range []MerkleTreeOptions{{}, {SortLeaves: Bool(true), SortDescending: true}, {Compatibility: CompatLatest}} {
+		for _, n := range sizingCounts {
+			values := make([]string, n)
+			simpleValues := make([]BytesLike, n)
+			for i := range values {
+				values[i] = fmt.Sprintf("value-%04d", i)
+				simpleValues[i] = fmt.Sprintf("0x%064x", i)
+			}
+			standard, err := NewStandardMerkleTree(values, opts)
+			if err != nil {
+				t.Fatalf("Failed to create tree: %v", err)
+			}
+
+			dump, err := standard.Dump()
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkDumpSize(t, n, len(`"value-0000"`), DumpFormatStandard, opts, mustMarshal(t, dump))
+
+			for format, encoding := range map[DumpFormat]string{DumpFormatLeavesRaw: LeavesEncodingRaw, DumpFormatLeavesHex: LeavesEncodingHex} {
+				var buf bytes.Buffer
+				if err := standard.DumpLeaves(&buf, LeavesDumpOptions{Encoding: encoding}); err != nil {
+					t.Fatal(err)
+				}
+				checkDumpSize(t, n, 0, format, opts, buf.Bytes())
+			}
+
+			if opts.Compatibility == CompatLatest {
+				continue
+			}
+			simple, err := NewSimpleMerkleTree(simpleValues, SimpleMerkleTreeOptions{MerkleTreeOptions: opts})
+			if err != nil {
+				t.Fatalf("Failed to create simple tree: %v", err)
+			}
+			simpleDump, err := simple.Dump()
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkDumpSize(t, n, 2+66, DumpFormatSimple, opts, mustMarshal(t, simpleDump))
+		}
+	}
+}
+
+func checkDumpSize(t *testing.T, n, avgValueLen int, format DumpFormat, opts MerkleTreeOptions, dump []byte) {
+	t.Helper()
+	if got := SerializedDumpSizeEstimate(n, avgValueLen, format, opts); got != int64(len(dump)) {
+		t.Errorf("%d leaves, format %d, options %+v: estimate %d bytes, dump is %d", n, format, opts, got, len(dump))
+	}
+}
+
+func mustMarshal(t *testing.T, v any) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestTreeSizingLimits(t *testing.T) {
+	if _, err := TreeNodeCount(0, MerkleTreeOptions{}); !errors.Is(err, ErrEmptyTree) {
+		t.Errorf("Expected ErrEmptyTree for no leaves, got %v", err)
+	}
+	if _, err := TreeNodeCount(10, MerkleTreeOptions{MaxLeaves: 5}); !errors.Is(err, ErrTooManyLeaves) {
+		t.Errorf("Expected ErrTooManyLeaves past MaxLeaves, got %v", err)
+	}
+	if _, err := TreeNodeCount(5, MerkleTreeOptions{Parallelism: -1}); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("Expected ErrInvalidOptions, got %v", err)
+	}
+
+	largest := math.MaxInt/2 + 1
+	if nodes, err := TreeNodeCount(largest, MerkleTreeOptions{}); err != nil || nodes != math.MaxInt {
+		t.Errorf("TreeNodeCount(%d) is %d (err %v), want %d", largest, nodes, err, math.MaxInt)
+	}
+	if _, err := TreeNodeCount(largest+1, MerkleTreeOptions{}); !errors.Is(err, ErrTooManyLeaves) {
+		t.Errorf("Expected ErrTooManyLeaves when the nodes overflow, got %v", err)
+	}
+	if depth := TreeDepthFor(largest, MerkleTreeOptions{}); depth != bitsInt-2 {
+		t.Errorf("TreeDepthFor(%d) is %d, want %d", largest, depth, bitsInt-2)
+	}
+	if first, last := LeafRange(largest+1, MerkleTreeOptions{}); first != -1 || last != -1 {
+		t.Errorf("LeafRange past the limit is %d-%d, want -1", first, last)
+	}
+
+	for _, tc := range []struct {
+		n, avg int
+		format DumpFormat
+	}{
+		{0, 10, DumpFormatStandard},
+		{10, -1, DumpFormatStandard},
+		{10, 10, DumpFormat(99)},
+		{largest, 10, DumpFormatStandard},
+		{10, math.MaxInt, DumpFormatSimple},
+	} {
+		if got := SerializedDumpSizeEstimate(tc.n, tc.avg, tc.format, MerkleTreeOptions{}); got != -1 {
+			t.Errorf("SerializedDumpSizeEstimate(%d, %d, %d) is %d, want -1", tc.n, tc.avg, tc.format, got)
+		}
+	}
+	if got := SerializedDumpSizeEstimate(largest/64, 0, DumpFormatLeavesRaw, MerkleTreeOptions{}); got <= 0 {
+		t.Errorf("A raw leaves dump of %d leaves fits in an int64, got %d", largest/64, got)
+	}
+}
+
+// bitsInt is the size of an int in bits.
+const bitsInt = 32 << (^uint(0) >> 63)
@@ -0,0 +1,287 @@
+package merkletree
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// SparseDepth is the depth of a SparseMerkleTree: one level per bit of its
+// 256-bit keys.
+const SparseDepth = 256
+
+// SparseMerkleTreeOptions selects the hashes of a SparseMerkleTree.
+type SparseMerkleTreeOptions struct {
+	NodeHash NodeHash         // Hash of two children, which must keep their order (optional, defaults to PositionalNodeHash)
+	LeafHash LeafHash[[]byte] // Hash of a set value (optional, defaults to keccak256 of the value)
+}
+
+// SparseMerkleTree commits to a map from 256-bit keys to values. Every key
+// has a leaf at depth SparseDepth, on the path its bits spell from the most
+// significant, left for 0 and right for 1. The leaf of an unset key is 32
+// zero bytes and a subtree with no set key hashes to a default that depends
+// only on its height, so only the nodes on the paths of set keys are stored:
+// memory grows with the number of set keys, SparseDepth nodes for each.
+//
+// Proofs carry a sibling for each level and so prove a key's value or its
+// absence alike. They rely on the position of each node, so the node hash
+// must not sort its inputs as the hashes of the dense trees do.
+type SparseMerkleTree struct {
+	nodes    map[sparseNodeKey]HexString // Nodes that differ from the default of their height
+	values   map[[32]byte][]byte
+	defaults [SparseDepth + 1]HexString // defaults[d] is the empty subtree whose root is at depth d
+	nodeHash NodeHash
+	leafHash LeafHash[[]byte]
+}
+
+// sparseNodeKey locates a node: its depth and the first depth bits of the
+// keys below it, the other bits cleared.
+type sparseNodeKey struct {
+	depth int
+	path  [32]byte
+}
+
+// SparseMerkleProof proves the value of a key, or that it is unset. Siblings
+// equal to the default of their height are left out and marked in Bitmap.
+type SparseMerkleProof struct {
+	// Siblings are the siblings that are not defaults, nearest the leaf first.
+	Siblings []HexString `json:"siblings"`
+
+	// Bitmap has bit d, counted from the most significant bit of its 32
+	// bytes, set when the sibling at depth d+1 is in Siblings.
+	Bitmap HexString `json:"bitmap"`
+}
+
+// NewSparseMerkleTree returns an empty sparse tree. A node hash that gives
+// the same hash for both orders of a pair fails with an *OptionError.
+func NewSparseMerkleTree(options SparseMerkleTreeOptions) (*SparseMerkleTree, error) {
+	t := &SparseMerkleTree{
+		nodes:    make(map[sparseNodeKey]HexString),
+		values:   make(map[[32]byte][]byte),
+		nodeHash: options.NodeHash,
+		leafHash: options.LeafHash,
+	}
+	if t.nodeHash == nil {
+		t.nodeHash = PositionalNodeHash
+	}
+	if t.leafHash == nil {
+		t.leafHash = func(value []byte) HexString {
+			return HexString(fmt.Sprintf("0x%x", keccakSum(value)))
+		}
+	}
+	defaults, err := sparseDefaults(t.nodeHash)
+	if err != nil {
+		return nil, err
+	}
+	t.defaults = defaults
+	return t, nil
+}
+
+// sparseDefaults returns the roots of the empty subtrees at every depth,
+// rejecting node hashes that sort their inputs or do not give 32-byte nodes.
+func sparseDefaults(nodeHash NodeHash) ([SparseDepth + 1]HexString, error) {
+	var defaults [SparseDepth + 1]HexString
+	defaults[SparseDepth] = HexString(fmt.Sprintf("0x%x", make([]byte, 32)))
+	for d := SparseDepth - 1; d >= 0; d-- {
+		defaults[d] = nodeHash(defaults[d+1], defaults[d+1])
+		if !IsValidMerkleNode(defaults[d]) {
+			return defaults, &OptionError{Option: "NodeHash", Value: defaults[d], Reason: "must return 32-byte nodes"}
+		}
+	}
+	if !nodeHashKeepsOrder(nodeHash) {
+		return defaults, &OptionError{Option: "NodeHash", Value: "sorted pairs", Reason: "sparse trees need a node hash that keeps the order of its inputs"}
+	}
+	return defaults, nil
+}
+
+// sparseKey reads a 32-byte key.
+func sparseKey(key BytesLike) ([32]byte, error) {
+	b, err := ToBytes(key)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("%w: key: %v", ErrInvalidValue, err)
+	}
+	if len(b) != 32 {
+		return [32]byte{}, fmt.Errorf("%w: key is %d bytes, want 32", ErrInvalidValue, len(b))
+	}
+	return [32]byte(b), nil
+}
+
+// sparseBit returns bit d of key, counted from the most significant.
+func sparseBit(key [32]byte, d int) int {
+	return int(key[d/8]>>(7-d%8)) & 1
+}
+
+// sparsePrefix returns the first depth bits of key, the other bits cleared.
+func sparsePrefix(key [32]byte, depth int) [32]byte {
+	var prefix [32]byte
+	copy(prefix[:depth/8], key[:depth/8])
+	if depth%8 != 0 {
+		prefix[depth/8] = key[depth/8] & (0xff << (8 - depth%8))
+	}
+	return prefix
+}
+
+// node returns the node at depth on the path of key.
+func (t *SparseMerkleTree) node(key [32]byte, depth int) HexString {
+	if node, ok := t.nodes[sparseNodeKey{depth, sparsePrefix(key, depth)}]; ok {
+		return node
+	}
+	return t.defaults[depth]
+}
+
+// sibling returns the sibling of the node at depth on the path of key.
+func (t *SparseMerkleTree) sibling(key [32]byte, depth int) HexString {
+	flipped := key
+	flipped[(depth-1)/8] ^= 0x80 >> ((depth - 1) % 8)
+	return t.node(flipped, depth)
+}
+
+// Update sets the value of key, a 32-byte key, hashing the SparseDepth
+// nodes on its path. An empty value unsets the key. A key that is not 32
+// bytes, or a value that does not hash to a 32-byte leaf, fails with an
+// error wrapping ErrInvalidValue and leaves the tree unchanged.
+func (t *SparseMerkleTree) Update(key BytesLike, value []byte) error {
+	k, err := sparseKey(key)
+	if err != nil {
+		return err
+	}
+	leaf := t.defaults[SparseDepth]
+	if len(value) > 0 {
+		leaf = t.leafHash(value)
+		if err := leafHashError(value, leaf); err != nil {
+			return err
+		}
+		t.values[k] = slices.Clone(value)
+	} else {
+		delete(t.values, k)
+	}
+
+	node := leaf
+	for depth := SparseDepth; ; depth-- {
+		// Nodes equal to their default are not stored, so unsetting frees them
+		nk := sparseNodeKey{depth, sparsePrefix(k, depth)}
+		if node == t.defaults[depth] {
+			delete(t.nodes, nk)
+		} else {
+			t.nodes[nk] = node
+		}
+		if depth == 0 {
+			return nil
+		}
+		if sibling := t.sibling(k, depth); sparseBit(k, depth-1) == 0 {
+			node = t.nodeHash(node, sibling)
+		} else {
+			node = t.nodeHash(sibling, node)
+		}
+	}
+}
+
+// Get returns the value of key, or an error wrapping ErrValueNotFound if the
+// key is unset.
+func (t *SparseMerkleTree) Get(key BytesLike) ([]byte, error) {
+	k, err := sparseKey(key)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := t.values[k]
+	if !ok {
+		return nil, fmt.Errorf("%w: key 0x%x", ErrValueNotFound, k)
+	}
+	return slices.Clone(value), nil
+}
+
+// Len returns the number of set keys.
+func (t *SparseMerkleTree) Len() int {
+	return len(t.values)
+}
+
+// Keys returns the set keys in ascending order.
+func (t *SparseMerkleTree) Keys() [][32]byte {
+	return slices.SortedFunc(maps.Keys(t.values), func(a, b [32]byte) int {
+		return slices.Compare(a[:], b[:])
+	})
+}
+
+// Root returns the root of the tree. The root of the empty tree is the
+// default of depth 0.
+func (t *SparseMerkleTree) Root() HexString {
+	return t.node([32]byte{}, 0)
+}
+
+// Prove returns the proof of key, which shows its value if it is set and
+// that it is unset otherwise.
+func (t *SparseMerkleTree) Prove(key BytesLike) (SparseMerkleProof, error) {
+	k, err := sparseKey(key)
+	if err != nil {
+		return SparseMerkleProof{}, err
+	}
+	var bitmap [32]byte
+	proof := SparseMerkleProof{Siblings: []HexString{}}
+	for depth := SparseDepth; depth > 0; depth-- {
+		if sibling := t.sibling(k, depth); sibling != t.defaults[depth] {
+			proof.Siblings = append(proof.Siblings, sibling)
+			bitmap[(depth-1)/8] |= 0x80 >> ((depth - 1) % 8)
+		}
+	}
+	proof.Bitmap = HexString(fmt.Sprintf("0x%x", bitmap))
+	return proof, nil
+}
+
+// Verify reports whether proof shows that key has value against the tree's
+// root, or that key is unset if value is empty.
+func (t *SparseMerkleTree) Verify(key BytesLike, value []byte, proof SparseMerkleProof) (bool, error) {
+	return verifySparseProof(t.Root(), key, value, proof, t.nodeHash, t.leafHash, &t.defaults)
+}
+
+// VerifySparseMerkleProof reports whether proof shows that key has value in
+// the sparse tree with the given root, or that key is unset if value is
+// empty. The options must name the hashes the tree was built with. It
+// returns an error wrapping ErrInvalidNode if the bitmap or a sibling is
+// malformed, and one wrapping ErrInvalidValue for a key that is not 32 bytes.
+func VerifySparseMerkleProof(root, key BytesLike, value []byte, proof SparseMerkleProof, options SparseMerkleTreeOptions) (bool, error) {
+	t, err := NewSparseMerkleTree(options)
+	if err != nil {
+		return false, err
+	}
+	return verifySparseProof(root, key, value, proof, t.nodeHash, t.leafHash, &t.defaults)
+}
+
+// verifySparseProof recomputes the root from the leaf of key and the proof.
+func verifySparseProof(root, key BytesLike, value []byte, proof SparseMerkleProof, nodeHash NodeHash, leafHash LeafHash[[]byte], defaults *[SparseDepth + 1]HexString) (bool, error) {
+	k, err := sparseKey(key)
+	if err != nil {
+		return false, err
+	}
+	want, err := ToHex(root)
+	if err != nil {
+		return false, fmt.Errorf("invalid root: %w", err)
+	}
+	bitmap, err := ToBytes(proof.Bitmap)
+	if err != nil || len(bitmap) != 32 {
+		return false, fmt.Errorf("%w: bitmap must be 32 bytes", ErrInvalidNode)
+	}
+
+	node := defaults[SparseDepth]
+	if len(value) > 0 {
+		node = leafHash(value)
+	}
+	siblings := proof.Siblings
+	for depth := SparseDepth; depth > 0; depth-- {
+		sibling := defaults[depth]
+		if bitmap[(depth-1)/8]&(0x80>>((depth-1)%8)) != 0 {
+			if len(siblings) == 0 {
+				return false, nil // The bitmap marks more siblings than the proof has
+			}
+			if err := CheckValidMerkleNode(siblings[0]); err != nil {
+				return false, fmt.Errorf("invalid sibling at depth %d: %w", depth, err)
+			}
+			sibling, siblings = siblings[0], siblings[1:]
+		}
+		if sparseBit(k, depth-1) == 0 {
+			node = nodeHash(node, sibling)
+		} else {
+			node = nodeHash(sibling, node)
+		}
+	}
+	return len(siblings) == 0 && node.Normalize() == want.Normalize(), nil
+}
@@ -0,0 +1,198 @@
+package merkletree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// sparseKeys returns n keys from a fixed seed.
+func sparseKeys(n int, seed int64) [][]byte {
+	r := rand.New(rand.NewSource(seed))
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = make([]byte, 32)
+		r.Read(keys[i])
+	}
+	return keys
+}
+
+func newSparseTree(t *testing.T, options SparseMerkleTreeOptions) *SparseMerkleTree {
+	t.Helper()
+	tree, err := NewSparseMerkleTree(options)
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	return tree
+}
+
+func TestSparseMerkleTree(t *testing.T) {
+	tree := newSparseTree(t, SparseMerkleTreeOptions{})
+	empty := tree.Root()
+	keys := sparseKeys(20, 1)
+
+	for i, key := range keys {
+		if err := tree.Update(key, []byte(fmt.Sprintf("value-%d", i))); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	}
+	if tree.Len() != len(keys) || tree.Root() == empty {
+		t.Fatalf("Tree has %d keys and root %s after %d updates", tree.Len(), tree.Root(), len(keys))
+	}
+	for i, key := range keys {
+		want := []byte(fmt.Sprintf("value-%d", i))
+		if got, err := tree.Get(key); err != nil || !bytes.Equal(got, want) {
+			t.Errorf("Get(key %d) = %q, %v; want %q", i, got, err, want)
+		}
+		proof, err := tree.Prove(key)
+		if err != nil {
+			t.Fatalf("Prove failed: %v", err)
+		}
+		if ok, err := VerifySparseMerkleProof(tree.Root(), key, want, proof, SparseMerkleTreeOptions{}); err != nil || !ok {
+			t.Errorf("Proof of key %d does not verify: %v", i, err)
+		}
+		if ok, _ := tree.Verify(key, []byte("other"), proof); ok {
+			t.Errorf("Proof of key %d verifies another value", i)
+		}
+		if ok, _ := tree.Verify(key, nil, proof); ok {
+			t.Errorf("Proof of key %d verifies it as unset", i)
+		}
+	}
+
+	if got := tree.Keys(); len(got) != len(keys) || !slices.IsSortedFunc(got, func(a, b [32]byte) int { return bytes.Compare(a[:], b[:]) }) {
+		t.Errorf("Keys returned %d keys, want %d in ascending order", len(got), len(keys))
+	}
+
+	// The root depends on the set keys only, not on the order of updates
+	other := newSparseTree(t, SparseMerkleTreeOptions{})
+	for i := len(keys) - 1; i >= 0; i-- {
+		other.Update(keys[i], []byte(fmt.Sprintf("value-%d", i)))
+	}
+	if other.Root() != tree.Root() {
+		t.Errorf("Root after updates in reverse order %s, want %s", other.Root(), tree.Root())
+	}
+}
+
+func TestSparseMerkleTreeUpdateAndDelete(t *testing.T) {
+	tree := newSparseTree(t, SparseMerkleTreeOptions{})
+	empty := tree.Root()
+	keys := sparseKeys(10, 2)
+	for _, key := range keys {
+		tree.Update(key, []byte("first"))
+	}
+	before := tree.Root()
+
+	if err := tree.Update(keys[3], []byte("second")); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if tree.Root() == before || tree.Len() != len(keys) {
+		t.Errorf("Updating a key should change the root and keep the key count")
+	}
+	proof, _ := tree.Prove(keys[3])
+	if ok, _ := tree.Verify(keys[3], []byte("second"), proof); !ok {
+		t.Error("Proof of the updated value does not verify")
+	}
+	if ok, _ := tree.Verify(keys[3], []byte("first"), proof); ok {
+		t.Error("Proof verifies the old value")
+	}
+	tree.Update(keys[3], []byte("first"))
+	if tree.Root() != before {
+		t.Error("Restoring the value should restore the root")
+	}
+
+	if err := tree.Update(keys[3], nil); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if _, err := tree.Get(keys[3]); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("Expected ErrValueNotFound for a deleted key, got %v", err)
+	}
+	proof, _ = tree.Prove(keys[3])
+	if ok, err := tree.Verify(keys[3], nil, proof); err != nil || !ok {
+		t.Errorf("Proof that a deleted key is unset does not verify: %v", err)
+	}
+
+	for _, key := range keys {
+		tree.Update(key, []byte{})
+	}
+	if tree.Root() != empty || tree.Len() != 0 || len(tree.nodes) != 0 {
+		t.Errorf("Deleting every key should give the empty tree, got root %s with %d nodes", tree.Root(), len(tree.nodes))
+	}
+}
+
+func TestSparseMerkleTreeNonMembership(t *testing.T) {
+	tree := newSparseTree(t, SparseMerkleTreeOptions{})
+	for _, key := range sparseKeys(50, 3) {
+		tree.Update(key, []byte("set"))
+	}
+	for i, key := range sparseKeys(50, 4) {
+		proof, err := tree.Prove(key)
+		if err != nil {
+			t.Fatalf("Prove failed: %v", err)
+		}
+		if ok, err := tree.Verify(key, nil, proof); err != nil || !ok {
+			t.Errorf("Proof that key %d is unset does not verify: %v", i, err)
+		}
+		if ok, _ := tree.Verify(key, []byte("set"), proof); ok {
+			t.Errorf("Proof of unset key %d verifies a value", i)
+		}
+	}
+
+	// The proof of the empty tree has no siblings
+	empty := newSparseTree(t, SparseMerkleTreeOptions{})
+	proof, _ := empty.Prove(sparseKeys(1, 5)[0])
+	if len(proof.Siblings) != 0 {
+		t.Errorf("Proof in the empty tree has %d siblings", len(proof.Siblings))
+	}
+}
+
+func TestSparseMerkleTreeCustomHash(t *testing.T) {
+	sha256Pair := func(left, right BytesLike) HexString {
+		l, _ := ToBytes(left)
+		r, _ := ToBytes(right)
+		return HexString(fmt.Sprintf("0x%x", sha256Sum(l, r)))
+	}
+	options := SparseMerkleTreeOptions{NodeHash: sha256Pair, LeafHash: func(value []byte) HexString {
+		return HexString(fmt.Sprintf("0x%x", sha256Sum(value)))
+	}}
+	tree := newSparseTree(t, options)
+	key := sparseKeys(1, 6)[0]
+	tree.Update(key, []byte("value"))
+	if tree.Root() == newSparseTree(t, SparseMerkleTreeOptions{}).Root() {
+		t.Error("Trees with different hashes should have different roots")
+	}
+	proof, _ := tree.Prove(key)
+	if ok, err := VerifySparseMerkleProof(tree.Root(), key, []byte("value"), proof, options); err != nil || !ok {
+		t.Errorf("Proof with custom hashes does not verify: %v", err)
+	}
+	if ok, _ := VerifySparseMerkleProof(tree.Root(), key, []byte("value"), proof, SparseMerkleTreeOptions{}); ok {
+		t.Error("Proof verifies with other hashes")
+	}
+}
+
+func TestSparseMerkleTreeErrors(t *testing.T) {
+	var optErr *OptionError
+	if _, err := NewSparseMerkleTree(SparseMerkleTreeOptions{NodeHash: StandardNodeHash}); !errors.As(err, &optErr) {
+		t.Errorf("Expected an OptionError for a sorting node hash, got %v", err)
+	}
+
+	tree := newSparseTree(t, SparseMerkleTreeOptions{})
+	if err := tree.Update([]byte{1, 2, 3}, []byte("value")); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Expected ErrInvalidValue for a short key, got %v", err)
+	}
+	key := sparseKeys(1, 7)[0]
+	tree.Update(key, []byte("value"))
+	proof, _ := tree.Prove(key)
+
+	proof.Bitmap = "0x01"
+	if _, err := tree.Verify(key, []byte("value"), proof); !errors.Is(err, ErrInvalidNode) {
+		t.Errorf("Expected ErrInvalidNode for a short bitmap, got %v", err)
+	}
+	proof, _ = tree.Prove(key)
+	proof.Siblings = append(proof.Siblings, tree.Root())
+	if ok, _ := tree.Verify(key, []byte("value"), proof); ok {
+		t.Error("A proof with an extra sibling should not verify")
+	}
+}
@@ -0,0 +1,122 @@
+package merkletree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Stamp summarizes a built tree so that independent builds can be compared
+// cheaply. Two builds that agree on the root can still disagree on the order
+// of the values, their metadata, or the options used; the stamp tells them apart.
+type Stamp struct {
+	Root         HexString `json:"root"`         // Root of the tree
+	LeafCount    int       `json:"leafCount"`    // Number of values in the tree
+	LeavesHash   HexString `json:"leavesHash"`   // keccak256 of the leaf hashes in value order
+	MetadataHash HexString `json:"metadataHash"` // keccak256 of the leaf metadata in value order
+	OptionsHash  HexString `json:"optionsHash"`  // keccak256 of the canonical algorithm and options
+	Version      string    `json:"version"`      // Library version that built the tree
+}
+
+// stampOptions is the canonical form of everything besides the values that
+// decides how a tree is built and resolved. Settings that cannot change the
+// result, such as Parallelism, are left out.
+type stampOptions struct {
+	Algorithm   AlgorithmDescriptor `json:"algorithm"`
+	Duplicates  DuplicatePolicy     `json:"duplicates"`
+	Quarantined int                 `json:"quarantined"`
+}
+
+// BuildStamp returns the stamp of the tree.
+// Returns ErrEmptyTree if the tree has no nodes.
+func (m *MerkleTreeImpl[T]) BuildStamp() (Stamp, error) {
+	if len(m.Tree) == 0 {
+		return Stamp{}, ErrEmptyTree
+	}
+
+	var leaves []byte
+	for i, v := range m.Values {
+		leaf, err := ToBytes(m.Tree[v.TreeIndex])
+		if err != nil {
+			return Stamp{}, fmt.Errorf("leaf of value %d: %w", i, err)
+		}
+		leaves = append(leaves, leaf...)
+	}
+
+	// Each entry is length-prefixed so that a value without metadata cannot be
+	// confused with the next value's metadata
+	var metadata []byte
+	for i := range m.Values {
+		var compact bytes.Buffer
+		if meta := m.metadataAt(i); meta != nil {
+			if err := json.Compact(&compact, meta); err != nil {
+				return Stamp{}, fmt.Errorf("metadata of value %d: %w", i, err)
+			}
+		}
+		metadata = binary.BigEndian.AppendUint32(metadata, uint32(compact.Len()))
+		metadata = append(metadata, compact.Bytes()...)
+	}
+
+	options, err := json.Marshal(stampOptions{
+		Algorithm:   m.algorithm,
+		Duplicates:  m.duplicatePolicy,
+		Quarantined: m.quarantined,
+	})
+	if err != nil {
+		return Stamp{}, err
+	}
+
+	return Stamp{
+		Root:         m.Root(),
+		LeafCount:    len(m.Values),
+		LeavesHash:   keccak256Hex(leaves),
+		MetadataHash: keccak256Hex(metadata),
+		OptionsHash:  keccak256Hex(options),
+		Version:      version,
+	}, nil
+}
+
+// keccak256Hex returns the Keccak-256 hash of data as a hex string.
+func keccak256Hex(data []byte) HexString {
+	return HexString("0x" + hex.EncodeToString(keccak256(data)))
+}
+
+// FieldDiff is one component on which two stamps disagree.
+type FieldDiff struct {
+	Field string // Name of the Stamp field
+	A, B  string // The two values
+}
+
+// Diff lists the components on which two stamps disagree, in Stamp field order.
+type Diff []FieldDiff
+
+// String renders one line per differing component.
+func (d Diff) String() string {
+	lines := make([]string, len(d))
+	for i, f := range d {
+		lines[i] = fmt.Sprintf("%s: %s != %s", f.Field, f.A, f.B)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CompareStamps compares two stamps field by field. It reports whether they
+// are identical and, if not, exactly which components differ.
+func CompareStamps(a, b Stamp) (Diff, bool) {
+	var diff Diff
+	add := func(field, x, y string) {
+		if x != y {
+			diff = append(diff, FieldDiff{Field: field, A: x, B: y})
+		}
+	}
+	add("Root", string(a.Root), string(b.Root))
+	add("LeafCount", strconv.Itoa(a.LeafCount), strconv.Itoa(b.LeafCount))
+	add("LeavesHash", string(a.LeavesHash), string(b.LeavesHash))
+	add("MetadataHash", string(a.MetadataHash), string(b.MetadataHash))
+	add("OptionsHash", string(a.OptionsHash), string(b.OptionsHash))
+	add("Version", a.Version, b.Version)
+	return diff, len(diff) == 0
+}
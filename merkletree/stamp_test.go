@@ -0,0 +1,132 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// stampOf builds a standard tree and returns its stamp.
+func stampOf(t *testing.T, values []string, options MerkleTreeOptions) Stamp {
+	t.Helper()
+	tree, err := NewStandardMerkleTree(values, options)
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	stamp, err := tree.BuildStamp()
+	if err != nil {
+		t.Fatalf("BuildStamp failed: %v", err)
+	}
+	return stamp
+}
+
+func TestBuildStampIsDeterministic(t *testing.T) {
+	values := []string{"alice", "bob", "charlie"}
+	a := stampOf(t, values, MerkleTreeOptions{SortLeaves: Bool(true)})
+	b := stampOf(t, values, MerkleTreeOptions{SortLeaves: Bool(true), Parallelism: 4})
+
+	if diff, same := CompareStamps(a, b); !same {
+		t.Errorf("Identical builds should have identical stamps:\n%v", diff)
+	}
+	if a.Version != Version() || a.LeafCount != 3 {
+		t.Errorf("Unexpected stamp %+v", a)
+	}
+}
+
+func TestCompareStampsNamesDivergingField(t *testing.T) {
+	values := []string{"alice", "bob", "charlie"}
+	sorted := MerkleTreeOptions{SortLeaves: Bool(true)}
+	base := stampOf(t, values, sorted)
+
+	withVersion := base
+	withVersion.Version = "0.0.0"
+	withRoot := base
+	withRoot.Root = "0x" + "00"
+
+	tests := []struct {
+		name  string
+		stamp Stamp
+		want  []string
+	}{
+		{
+			// Sorting hides the input order from the root, but not from the leaf list
+			name:  "value order",
+			stamp: stampOf(t, []string{"charlie", "bob", "alice"}, sorted),
+			want:  []string{"LeavesHash"},
+		},
+		{
+			name: "metadata",
+			stamp: stampOf(t, values, MerkleTreeOptions{SortLeaves: Bool(true), LeafMetadata: []json.RawMessage{
+				json.RawMessage(`{"tier":1}`), nil, nil,
+			}}),
+			want: []string{"MetadataHash"},
+		},
+		{
+			name:  "options",
+			stamp: stampOf(t, values, MerkleTreeOptions{SortLeaves: Bool(true), Duplicates: DuplicatesRequireIndex}),
+			want:  []string{"OptionsHash"},
+		},
+		{
+			name:  "version",
+			stamp: withVersion,
+			want:  []string{"Version"},
+		},
+		{
+			name:  "root",
+			stamp: withRoot,
+			want:  []string{"Root"},
+		},
+		{
+			name:  "values",
+			stamp: stampOf(t, []string{"alice", "bob"}, sorted),
+			want:  []string{"Root", "LeafCount", "LeavesHash", "MetadataHash"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, same := CompareStamps(base, tt.stamp)
+			if same {
+				t.Fatal("Stamps should differ")
+			}
+			var fields []string
+			for _, f := range diff {
+				fields = append(fields, f.Field)
+			}
+			if !slices.Equal(fields, tt.want) {
+				t.Errorf("Diff names %v, want %v", fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildStampSurvivesDump(t *testing.T) {
+	tree, err := NewSimpleMerkleTree([]BytesLike{"a", "b", "c"}, SimpleMerkleTreeOptions{
+		MerkleTreeOptions: MerkleTreeOptions{LeafMetadata: []json.RawMessage{json.RawMessage(`{ "x": 1 }`), nil, nil}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	dump, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	loaded, _, err := LoadSimpleMerkleTree(dump, nil)
+	if err != nil {
+		t.Fatalf("Failed to load dump: %v", err)
+	}
+
+	before, _ := tree.BuildStamp()
+	after, _ := loaded.BuildStamp()
+	if diff, same := CompareStamps(before, after); !same {
+		t.Errorf("A loaded tree should keep its stamp:\n%v", diff)
+	}
+}
+
+func TestBuildStampEmptyTree(t *testing.T) {
+	var tree MerkleTreeImpl[string]
+	if _, err := tree.BuildStamp(); !errors.Is(err, ErrEmptyTree) {
+		t.Errorf("Expected ErrEmptyTree, got %v", err)
+	}
+}
@@ -1,6 +1,10 @@
 package merkletree
 
-import "fmt"
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
 
 // StandardMerkleTree represents a Merkle tree with standard encoding,
 // compatible with OpenZeppelin's Merkle tree implementation.
@@ -15,27 +19,41 @@ type StandardMerkleTree[T any] struct {
 func NewStandardMerkleTree[T any](values []T, options MerkleTreeOptions) (*StandardMerkleTree[T], error) {
 	options = NewMerkleTreeOptions(&options) // Use default options if not specified
 
-	tree, indexedValues, err := PrepareMerkleTree(values, options, StandardLeafHash[T], StandardNodeHash)
-	if err != nil {
-		return nil, fmt.Errorf("failed to prepare merkle tree: %w", err)
+	leafHash, leafHashName := StandardLeafHash[T], HashKeccak256Packed
+	if options.Compatibility == CompatLatest {
+		leafHash, leafHashName = OpenZeppelinLeafHash[T], HashKeccak256Double
 	}
 
-	// Build hash lookup map
-	hashLookup := make(map[HexString]int)
-	for i, v := range indexedValues {
-		hash := StandardLeafHash(v.Value)
-		hashLookup[hash] = i
+	tree, indexedValues, quarantine, err := prepareMerkleTree(values, options, leafHash, StandardNodeHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare merkle tree: %w", err)
 	}
 
-	return &StandardMerkleTree[T]{
+	t := &StandardMerkleTree[T]{
 		MerkleTreeImpl: MerkleTreeImpl[T]{
-			Tree:       tree,
-			Values:     indexedValues,
-			LeafHash:   StandardLeafHash[T],
-			NodeHash:   StandardNodeHash,
-			HashLookup: hashLookup,
+			Tree:     tree,
+			Values:   indexedValues,
+			LeafHash: leafHash,
+			NodeHash: StandardNodeHash,
 		},
-	}, nil
+	}
+	t.algorithm = AlgorithmDescriptor{
+		LeafHash:  leafHashName,
+		NodeHash:  HashKeccak256Sorted,
+		LeafOrder: options.leafOrder(),
+	}
+	t.compatibility = options.Compatibility
+	t.maxValueBytes = options.MaxValueBytes
+	t.shuffleSeed = slices.Clone(options.ShuffleSeed)
+	t.warnings = options.compatWarnings()
+	t.setQuarantine(quarantine)
+	t.setMetadata(withoutQuarantined(options.LeafMetadata, quarantine))
+	t.configureIndexes(options)
+	t.configurePregeneration(options)
+	if options.DropValuesAfterBuild {
+		t.dropValues()
+	}
+	return t, nil
 }
 
 // VerifyStandardMerkleTree verifies a Merkle proof for a specific value.
@@ -64,34 +82,101 @@ func VerifyStandardMerkleTree[T any](root BytesLike, leaf T, proof []BytesLike)
 	return computedRootVal == rootVal, nil
 }
 
+// VerifyStandardMultiProof verifies a multi-proof of several values against
+// root, without instantiating a tree. The values are hashed with
+// StandardLeafHash and must be the leaves of the multi-proof, in any order.
+// Returns false if they are not or if the proof leads to another root, and an
+// error wrapping ErrInvalidMultiProof if the flags do not fit the leaves and
+// proof nodes.
+func VerifyStandardMultiProof[T any](root BytesLike, leaves []T, multiproof MultiProof) (bool, error) {
+	if want := len(multiproof.Leaves) + len(multiproof.Proof) - 1; len(multiproof.ProofFlags) != want {
+		return false, fmt.Errorf("%w: %d leaves and %d proof nodes need %d flags, got %d",
+			ErrInvalidMultiProof, len(multiproof.Leaves), len(multiproof.Proof), want, len(multiproof.ProofFlags))
+	}
+	if len(leaves) != len(multiproof.Leaves) {
+		return false, nil
+	}
+	unmatched := make(map[HexString]int, len(leaves))
+	for _, leaf := range multiproof.Leaves {
+		unmatched[leaf.Normalize()]++
+	}
+	for _, value := range leaves {
+		hash := StandardLeafHash(value)
+		if unmatched[hash] == 0 {
+			return false, nil
+		}
+		unmatched[hash]--
+	}
+
+	computedRoot, err := ProcessMultiProof(multiproof, StandardNodeHash)
+	if err != nil {
+		return false, fmt.Errorf("error processing multi-proof: %w", err)
+	}
+	rootVal, err := ToHex(root)
+	if err != nil {
+		return false, fmt.Errorf("error converting expected root: %w", err)
+	}
+	return strings.EqualFold(string(computedRoot), string(rootVal)), nil
+}
+
 // StandardMerkleTreeData represents the exportable data of a Standard Merkle tree.
 // This format can be serialized to JSON for storage or transmission.
 type StandardMerkleTreeData[T any] struct {
-	Format string      `json:"format"` // Format version identifier
-	Tree   []HexString `json:"tree"`   // Complete tree structure
-	Values []struct {
-		Value     T   `json:"value"`
-		TreeIndex int `json:"treeIndex"`
-	} `json:"values"` // Values with their tree positions
+	Format       string              `json:"format"`                 // Format version identifier
+	LeafEncoding []string            `json:"leafEncoding,omitempty"` // Solidity types of ABI-encoded values
+	Tree         []HexString         `json:"tree"`                   // Complete tree structure
+	Values       []DumpValue[T]      `json:"values"`                 // Values with their tree positions and metadata
+	Algorithm    AlgorithmDescriptor `json:"algorithm"`              // Hashes and leaf order used to build the tree
+	Options      *DumpOptions        `json:"options,omitempty"`      // Options that rebuild the tree from its values
+	Quarantined  int                 `json:"quarantined,omitempty"`  // Number of input values left out of the tree
+	ShuffleSeed  HexString           `json:"shuffleSeed,omitempty"`  // Seed of a shuffled leaf order, unless withheld
+	Version      string              `json:"version,omitempty"`      // Library version that wrote the dump
+	Integrity    *DumpIntegrity      `json:"integrity,omitempty"`    // Footer for detecting truncation; always last
 }
 
 // Dump exports the tree data for debugging, storage, or transmission.
 // The exported data can be serialized to JSON and later reconstructed.
-func (m *StandardMerkleTree[T]) Dump() StandardMerkleTreeData[T] {
+// Returns ErrValuesDropped if the values were dropped and there is no ValueProvider.
+func (m *StandardMerkleTree[T]) Dump() (StandardMerkleTreeData[T], error) {
 	// Convert values to the format with JSON tags
-	values := make([]struct {
-		Value     T   `json:"value"`
-		TreeIndex int `json:"treeIndex"`
-	}, len(m.Values))
+	values := make([]DumpValue[T], len(m.Values))
+	types, _ := parseLeafEncoding(m.leafEncoding)
+
+	for i := range m.Values {
+		var err error
+		if values[i], err = m.dumpValue(i, types); err != nil {
+			return StandardMerkleTreeData[T]{}, err
+		}
+	}
 
-	for i, v := range m.Values {
-		values[i].Value = v.Value
-		values[i].TreeIndex = v.TreeIndex
+	integrity, err := newDumpIntegrity(m.Tree, values, len(values))
+	if err != nil {
+		return StandardMerkleTreeData[T]{}, err
 	}
 
 	return StandardMerkleTreeData[T]{
-		Format: "standard-v1",
-		Tree:   m.Tree,
-		Values: values,
+		Format:       standardFormat,
+		LeafEncoding: slices.Clone(m.leafEncoding),
+		Tree:         m.Tree,
+		Values:       values,
+		Algorithm:    m.algorithm,
+		Options:      newDumpOptions(m.algorithm, m.compatibility, HashAlgorithmKeccak256),
+		Quarantined:  m.quarantined,
+		ShuffleSeed:  shuffleSeedHex(m.shuffleSeed),
+		Version:      version,
+		Integrity:    integrity,
+	}, nil
+}
+
+// dumpValue returns the dump entry of the value at index i. types is the
+// parsed leaf encoding, if any, whose values are written as OpenZeppelin does.
+func (m *StandardMerkleTree[T]) dumpValue(i int, types []*abiType) (DumpValue[T], error) {
+	value, err := m.valueAt(i)
+	if err != nil {
+		return DumpValue[T]{}, err
+	}
+	if fields, ok := any(value).([]any); ok && types != nil {
+		value = any(jsonFields(types, fields)).(T)
 	}
+	return DumpValue[T]{Value: value, TreeIndex: m.Values[i].TreeIndex, Metadata: m.metadataAt(i)}, nil
 }
@@ -1,6 +1,8 @@
 package merkletree
 
 import (
+	"errors"
+	"slices"
 	"testing"
 )
 
@@ -145,6 +147,76 @@ func TestVerifyStandardMerkleTree(t *testing.T) {
 	}
 }
 
+func TestVerifyStandardMultiProof(t *testing.T) {
+	values := []string{"test1", "test2", "test3", "test4", "test5"}
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create merkle tree: %v", err)
+	}
+	multiproof, err := tree.GetMultiProof("test4", "test1", "test5")
+	if err != nil {
+		t.Fatalf("Failed to get multi-proof: %v", err)
+	}
+
+	// The values may come in any order
+	valid, err := VerifyStandardMultiProof(tree.Root(), []string{"test1", "test5", "test4"}, multiproof)
+	if err != nil || !valid {
+		t.Fatalf("Multi-proof should verify, got %v, %v", valid, err)
+	}
+
+	for name, leaves := range map[string][]string{
+		"other value":   {"test1", "test5", "test2"},
+		"missing value": {"test1", "test5"},
+		"repeat":        {"test1", "test1", "test5"},
+	} {
+		if valid, err := VerifyStandardMultiProof(tree.Root(), leaves, multiproof); err != nil || valid {
+			t.Errorf("%s: got %v, %v; want false without an error", name, valid, err)
+		}
+	}
+
+	other, err := NewStandardMerkleTree([]string{"x", "y"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create merkle tree: %v", err)
+	}
+	if valid, err := VerifyStandardMultiProof(other.Root(), []string{"test1", "test5", "test4"}, multiproof); err != nil || valid {
+		t.Errorf("Wrong root: got %v, %v; want false without an error", valid, err)
+	}
+
+	broken := multiproof
+	broken.ProofFlags = slices.Clone(multiproof.ProofFlags)[1:]
+	if _, err := VerifyStandardMultiProof(tree.Root(), []string{"test1", "test5", "test4"}, broken); !errors.Is(err, ErrInvalidMultiProof) {
+		t.Errorf("Expected ErrInvalidMultiProof for missing flags, got %v", err)
+	}
+}
+
+func TestStandardMerkleTreeUnsupportedType(t *testing.T) {
+	type point struct{ X, Y int }
+	_, err := NewStandardMerkleTree([]point{{1, 2}, {3, 4}}, MerkleTreeOptions{})
+	if !errors.Is(err, ErrHashFailed) || !errors.Is(err, ErrInvalidValue) {
+		t.Fatalf("Expected ErrHashFailed and ErrInvalidValue, got %v", err)
+	}
+	var inputErr *InputError
+	if !errors.As(err, &inputErr) || inputErr.Index != 0 || Code(err) != CodeInvalidValue {
+		t.Errorf("Expected an InputError for value 0 with code %s, got %v", CodeInvalidValue, err)
+	}
+
+	// AppendLeaves and LeafSet reject it the same way
+	tree, err := NewStandardMerkleTree([]any{"a", "b"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create merkle tree: %v", err)
+	}
+	if err := tree.AppendLeaves([]any{point{1, 2}}); !errors.Is(err, ErrHashFailed) {
+		t.Errorf("AppendLeaves: expected ErrHashFailed, got %v", err)
+	}
+	set, err := NewLeafSet[point](MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("NewLeafSet failed: %v", err)
+	}
+	if err := set.Add(point{1, 2}); !errors.Is(err, ErrHashFailed) {
+		t.Errorf("LeafSet.Add: expected ErrHashFailed, got %v", err)
+	}
+}
+
 func TestStandardMerkleTreeValidate(t *testing.T) {
 	values := []string{"a", "b", "c"}
 
@@ -169,7 +241,10 @@ func TestStandardMerkleTreeDump(t *testing.T) {
 	}
 
 	// Dump the tree
-	data := tree.Dump()
+	data, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
 
 	// Verify dump format
 	if data.Format != "standard-v1" {
@@ -196,7 +271,7 @@ func TestStandardMerkleTreeWithSortedLeaves(t *testing.T) {
 	values := []string{"delta", "alpha", "charlie", "bravo"}
 
 	// Create tree with sorted leaves
-	tree1, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: true})
+	tree1, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(true)})
 	if err != nil {
 		t.Fatalf("Failed to create sorted tree: %v", err)
 	}
@@ -204,7 +279,7 @@ func TestStandardMerkleTreeWithSortedLeaves(t *testing.T) {
 	// Create tree with different order but same values and sorting
 	valuesShuffled := []string{"bravo", "delta", "alpha", "charlie"}
 
-	tree2, err := NewStandardMerkleTree(valuesShuffled, MerkleTreeOptions{SortLeaves: true})
+	tree2, err := NewStandardMerkleTree(valuesShuffled, MerkleTreeOptions{SortLeaves: Bool(true)})
 	if err != nil {
 		t.Fatalf("Failed to create sorted tree 2: %v", err)
 	}
@@ -305,3 +380,110 @@ func TestStandardMerkleTreeSingleValue(t *testing.T) {
 		t.Error("Proof for single-value tree should be valid")
 	}
 }
+
+// treeNodes converts a tree's nodes to the form expected by the core functions.
+func treeNodes(tree []HexString) []BytesLike {
+	nodes := make([]BytesLike, len(tree))
+	for i, n := range tree {
+		nodes[i] = n
+	}
+	return nodes
+}
+
+func TestStandardMerkleTreeSortDescending(t *testing.T) {
+	values := []string{"delta", "alpha", "charlie", "bravo", "echo"}
+
+	asc, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(true)})
+	if err != nil {
+		t.Fatalf("Failed to create ascending tree: %v", err)
+	}
+	desc, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(true), SortDescending: true})
+	if err != nil {
+		t.Fatalf("Failed to create descending tree: %v", err)
+	}
+
+	if asc.Root() == desc.Root() {
+		t.Error("Ascending and descending trees should have different roots")
+	}
+
+	// Leaves occupy the last len(values) slots of the tree in sort order
+	leaves := desc.Tree[len(desc.Tree)-len(values):]
+	for i := 1; i < len(leaves); i++ {
+		if cmp, _ := Compare(leaves[i-1], leaves[i]); cmp < 0 {
+			t.Errorf("Descending leaves out of order at %d: %s < %s", i, leaves[i-1], leaves[i])
+		}
+	}
+
+	for _, tree := range []*StandardMerkleTree[string]{asc, desc} {
+		order := tree.Algorithm().LeafOrder
+		for _, v := range values {
+			proof, err := tree.GetProof(v)
+			if err != nil {
+				t.Fatalf("%s: failed to get proof for %s: %v", order, v, err)
+			}
+			valid, err := tree.Verify(v, proof)
+			if err != nil || !valid {
+				t.Errorf("%s: proof for %s should verify: valid=%v err=%v", order, v, valid, err)
+			}
+		}
+	}
+
+	descDump, err := desc.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	if descDump.Algorithm.LeafOrder != LeafOrderDescending {
+		t.Errorf("Dump should record descending order, got %+v", descDump.Algorithm)
+	}
+	ascDump, err := asc.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	if ascDump.Algorithm.LeafOrder != LeafOrderAscending {
+		t.Errorf("Dump should record ascending order, got %+v", ascDump.Algorithm)
+	}
+}
+
+func TestStandardMerkleTreeSortDescendingMultiProof(t *testing.T) {
+	values := []string{"a", "b", "c", "d", "e", "f", "g"}
+
+	for _, descending := range []bool{false, true} {
+		tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(true), SortDescending: descending})
+		if err != nil {
+			t.Fatalf("Failed to create tree: %v", err)
+		}
+
+		// Tree indices of values 1, 3 and 4; GetMultiProof orders them itself
+		indices := []int{tree.Values[1].TreeIndex, tree.Values[3].TreeIndex, tree.Values[4].TreeIndex}
+
+		multiproof, err := GetMultiProof(treeNodes(tree.Tree), indices)
+		if err != nil {
+			t.Fatalf("descending=%v: failed to get multiproof: %v", descending, err)
+		}
+
+		root, err := ProcessMultiProof(multiproof, StandardNodeHash)
+		if err != nil {
+			t.Fatalf("descending=%v: failed to process multiproof: %v", descending, err)
+		}
+		if root != tree.Root() {
+			t.Errorf("descending=%v: multiproof root %s, want %s", descending, root, tree.Root())
+		}
+	}
+}
+
+func TestStandardMerkleTreeAlgorithm(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"a", "b"}, MerkleTreeOptions{SortDescending: true})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	// SortDescending alone does nothing without SortLeaves
+	want := AlgorithmDescriptor{
+		LeafHash:  HashKeccak256Packed,
+		NodeHash:  HashKeccak256Sorted,
+		LeafOrder: LeafOrderInsertion,
+	}
+	if tree.Algorithm() != want {
+		t.Errorf("Algorithm() = %+v, want %+v", tree.Algorithm(), want)
+	}
+}
@@ -0,0 +1,275 @@
+package merkletree
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// DumpTo writes the JSON of Dump to w, byte for byte as json.Marshal of the
+// dump would, without building the dump in memory: the tree and the values
+// are encoded one entry at a time and the integrity footer is computed as
+// they are written. Returns ErrValuesDropped if the values were dropped and
+// there is no ValueProvider.
+func (m *StandardMerkleTree[T]) DumpTo(w io.Writer) error {
+	types, _ := parseLeafEncoding(m.leafEncoding)
+	out := &dumpWriter{w: bufio.NewWriter(w)}
+
+	out.raw("{")
+	out.field("format", standardFormat)
+	if len(m.leafEncoding) > 0 {
+		out.field("leafEncoding", m.leafEncoding)
+	}
+
+	treeSum := sha256.New()
+	out.key("tree")
+	out.array(treeSum, len(m.Tree), func(i int) (any, error) { return m.Tree[i], nil })
+	valuesSum := sha256.New()
+	out.key("values")
+	out.array(valuesSum, len(m.Values), func(i int) (any, error) { return m.dumpValue(i, types) })
+
+	out.field("algorithm", m.algorithm)
+	if options := newDumpOptions(m.algorithm, m.compatibility, HashAlgorithmKeccak256); options != nil {
+		out.field("options", options)
+	}
+	if m.quarantined != 0 {
+		out.field("quarantined", m.quarantined)
+	}
+	if seed := shuffleSeedHex(m.shuffleSeed); seed != "" {
+		out.field("shuffleSeed", seed)
+	}
+	out.field("version", version)
+
+	integrity := &DumpIntegrity{
+		ValueCount:   len(m.Values),
+		TreeSHA256:   hex.EncodeToString(treeSum.Sum(nil)),
+		ValuesSHA256: hex.EncodeToString(valuesSum.Sum(nil)),
+	}
+	if len(m.Tree) > 0 {
+		integrity.Root = m.Tree[0]
+	}
+	out.field("integrity", integrity)
+	out.raw("}")
+
+	if out.err != nil {
+		return out.err
+	}
+	return out.w.Flush()
+}
+
+// dumpWriter writes the fields of a JSON object, keeping the first error.
+type dumpWriter struct {
+	w     *bufio.Writer
+	err   error
+	comma bool // A field was written, so the next one needs a comma
+}
+
+// raw writes s as is.
+func (d *dumpWriter) raw(s string) {
+	if d.err == nil {
+		_, d.err = d.w.WriteString(s)
+	}
+}
+
+// key writes the name of the next field.
+func (d *dumpWriter) key(name string) {
+	if d.comma {
+		d.raw(",")
+	}
+	d.comma = true
+	d.raw(`"` + name + `":`)
+}
+
+// field writes a field with its value encoded by json.Marshal.
+func (d *dumpWriter) field(name string, value any) {
+	d.key(name)
+	d.value(value, nil)
+}
+
+// value writes the json.Marshal encoding of value, also to sum if it is not nil.
+func (d *dumpWriter) value(value any, sum hash.Hash) {
+	if d.err != nil {
+		return
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		d.err = err
+		return
+	}
+	if sum != nil {
+		sum.Write(data)
+	}
+	_, d.err = d.w.Write(data)
+}
+
+// array writes an array of n entries, produced by entry, also to sum.
+func (d *dumpWriter) array(sum hash.Hash, n int, entry func(i int) (any, error)) {
+	d.raw("[")
+	sum.Write([]byte("["))
+	for i := 0; i < n && d.err == nil; i++ {
+		if i > 0 {
+			d.raw(",")
+			sum.Write([]byte(","))
+		}
+		value, err := entry(i)
+		if err != nil {
+			d.err = err
+			return
+		}
+		d.value(value, sum)
+	}
+	d.raw("]")
+	sum.Write([]byte("]"))
+}
+
+// LoadStandardMerkleTreeFrom reads a JSON standard tree dump from r, such as
+// DumpTo writes, and loads it with LoadStandardMerkleTree. The tree and values
+// are decoded one entry at a time rather than read into memory as a whole
+// first. A dump with an integrity footer is checked against it as it is
+// read, and one that was cut short fails with ErrTruncatedDump.
+func LoadStandardMerkleTreeFrom[T any](r io.Reader) (*StandardMerkleTree[T], error) {
+	var data StandardMerkleTreeData[T]
+	treeSum, valuesSum := sha256.New(), sha256.New()
+
+	dec := json.NewDecoder(r)
+	err := readStreamObject(dec, func(key string) error {
+		switch key {
+		case "tree":
+			return readStreamArray(dec, treeSum, func(raw json.RawMessage) error {
+				var node HexString
+				if err := json.Unmarshal(raw, &node); err != nil {
+					return fmt.Errorf("tree node %d: %w", len(data.Tree), err)
+				}
+				data.Tree = append(data.Tree, node)
+				return nil
+			})
+		case "values":
+			return readStreamArray(dec, valuesSum, func(raw json.RawMessage) error {
+				var value DumpValue[T]
+				if err := json.Unmarshal(raw, &value); err != nil {
+					return fmt.Errorf("value %d: %w", len(data.Values), err)
+				}
+				data.Values = append(data.Values, value)
+				return nil
+			})
+		case "format":
+			return dec.Decode(&data.Format)
+		case "leafEncoding":
+			return dec.Decode(&data.LeafEncoding)
+		case "algorithm":
+			return dec.Decode(&data.Algorithm)
+		case "options":
+			return dec.Decode(&data.Options)
+		case "quarantined":
+			return dec.Decode(&data.Quarantined)
+		case "shuffleSeed":
+			return dec.Decode(&data.ShuffleSeed)
+		case "version":
+			return dec.Decode(&data.Version)
+		case "integrity":
+			return dec.Decode(&data.Integrity)
+		default:
+			var skip json.RawMessage
+			return dec.Decode(&skip)
+		}
+	})
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %v", ErrTruncatedDump, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDump, err)
+	}
+
+	if footer := data.Integrity; footer != nil {
+		switch {
+		case len(data.Values) != footer.ValueCount:
+			return nil, fmt.Errorf("%w: %d values, footer says %d", ErrTruncatedDump, len(data.Values), footer.ValueCount)
+		case hex.EncodeToString(treeSum.Sum(nil)) != footer.TreeSHA256:
+			return nil, fmt.Errorf("%w: tree checksum mismatch", ErrTruncatedDump)
+		case hex.EncodeToString(valuesSum.Sum(nil)) != footer.ValuesSHA256:
+			return nil, fmt.Errorf("%w: values checksum mismatch", ErrTruncatedDump)
+		case len(data.Tree) == 0 || data.Tree[0] != footer.Root:
+			return nil, fmt.Errorf("%w: root does not match footer root %s", ErrTruncatedDump, footer.Root)
+		}
+	}
+	return LoadStandardMerkleTree(data)
+}
+
+// readStreamObject reads a JSON object from dec, calling field with each key
+// to decode the value that follows it.
+func readStreamObject(dec *json.Decoder, field func(key string) error) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("unexpected %v", token)
+		}
+		if err := field(key); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// readStreamArray reads a JSON array from dec one element at a time, passing
+// each to element and writing the compact form of the array to sum, as
+// json.Marshal writes it. A null array has no elements.
+func readStreamArray(dec *json.Decoder, sum hash.Hash, element func(json.RawMessage) error) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		sum.Write([]byte("null"))
+		return nil
+	}
+	if token != json.Delim('[') {
+		return fmt.Errorf("got %v, want an array", token)
+	}
+
+	sum.Write([]byte("["))
+	var compact bytes.Buffer
+	for i := 0; dec.More(); i++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		if i > 0 {
+			sum.Write([]byte(","))
+		}
+		compact.Reset()
+		if err := json.Compact(&compact, raw); err != nil {
+			return err
+		}
+		sum.Write(compact.Bytes())
+		if err := element(raw); err != nil {
+			return err
+		}
+	}
+	sum.Write([]byte("]"))
+	return expectDelim(dec, ']')
+}
+
+// expectDelim reads the next token from dec and checks that it is delim.
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("got %v, want %v", token, delim)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package merkletree
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestDumpToMatchesDump(t *testing.T) {
+	metadata := []json.RawMessage{json.RawMessage(`{"tier": 1}`), nil, json.RawMessage(`"<b>"`)}
+	values := []any{"alice", "bob", "charlie"}
+	for _, tt := range []struct {
+		values  []any
+		options MerkleTreeOptions
+	}{
+		{values, MerkleTreeOptions{}},
+		{values, MerkleTreeOptions{SortLeaves: Bool(true), LeafMetadata: metadata}},
+		{values, MerkleTreeOptions{Compatibility: CompatLatest, ShuffleSeed: []byte("seed")}},
+		{append(values, func() {}), MerkleTreeOptions{QuarantineInvalid: true}},
+	} {
+		tree, err := NewStandardMerkleTree(tt.values, tt.options)
+		if err != nil {
+			t.Fatalf("Failed to create tree: %v", err)
+		}
+		dump, err := tree.Dump()
+		if err != nil {
+			t.Fatalf("Failed to dump tree: %v", err)
+		}
+		if tt.options.QuarantineInvalid && dump.Quarantined != 1 {
+			t.Errorf("Dump quarantined %d values, want 1", dump.Quarantined)
+		}
+		want, err := json.Marshal(dump)
+		if err != nil {
+			t.Fatalf("Failed to marshal: %v", err)
+		}
+		var got bytes.Buffer
+		if err := tree.DumpTo(&got); err != nil {
+			t.Fatalf("DumpTo failed: %v", err)
+		}
+		if got.String() != string(want) {
+			t.Errorf("DumpTo wrote\n%s\nwant\n%s", got.String(), want)
+		}
+		if err := VerifyDumpIntegrity(got.Bytes(), false); err != nil {
+			t.Errorf("DumpTo output fails its integrity check: %v", err)
+		}
+	}
+
+	// And with a leaf encoding, whose values are written as OpenZeppelin does
+	encoded, err := NewStandardMerkleTreeWithEncoding(ozReadmeValues(), []string{"address", "uint256"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	dump, _ := encoded.Dump()
+	want, _ := json.Marshal(dump)
+	var got bytes.Buffer
+	if err := encoded.DumpTo(&got); err != nil || got.String() != string(want) {
+		t.Errorf("DumpTo wrote %s, %v; want %s", got.String(), err, want)
+	}
+}
+
+func TestDumpToLoadFromLargeTree(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a 100k-leaf tree")
+	}
+	values := make([]string, 100_000)
+	for i := range values {
+		values[i] = fmt.Sprintf("account-%06d", i)
+	}
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(true)})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tree.DumpTo(&buf); err != nil {
+		t.Fatalf("DumpTo failed: %v", err)
+	}
+	loaded, err := LoadStandardMerkleTreeFrom[string](&buf)
+	if err != nil {
+		t.Fatalf("LoadStandardMerkleTreeFrom failed: %v", err)
+	}
+	if loaded.Root() != tree.Root() {
+		t.Errorf("Loaded root %s, want %s", loaded.Root(), tree.Root())
+	}
+	for _, i := range []int{0, 4242, 99_999} {
+		want, _ := tree.GetProof(values[i])
+		got, err := loaded.GetProof(values[i])
+		if err != nil || !slices.Equal(got, want) {
+			t.Errorf("Proof of %s after loading: %v, %v; want %v", values[i], got, err, want)
+		}
+		if valid, err := loaded.Verify(i, got); err != nil || !valid {
+			t.Errorf("Proof of %s does not verify after loading: %v", values[i], err)
+		}
+	}
+}
+
+func TestLoadStandardMerkleTreeFrom(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"alice", "bob", "charlie", "dave"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	dump, _ := tree.Dump()
+	indented, _ := json.MarshalIndent(dump, "", "  ")
+
+	// Indented dumps and OpenZeppelin dumps without a footer load too
+	if loaded, err := LoadStandardMerkleTreeFrom[string](bytes.NewReader(indented)); err != nil || loaded.Root() != tree.Root() {
+		t.Errorf("Loading an indented dump: %v", err)
+	}
+	if loaded, err := LoadStandardMerkleTreeFrom[[]any](bytes.NewReader([]byte(ozReadmeDump))); err != nil || loaded.LeafEncoding() == nil {
+		t.Errorf("Loading an OpenZeppelin dump: %v", err)
+	}
+
+	if _, err := LoadStandardMerkleTreeFrom[string](bytes.NewReader(indented[:len(indented)/2])); !errors.Is(err, ErrTruncatedDump) {
+		t.Errorf("Expected a cut dump to fail with ErrTruncatedDump, got %v", err)
+	}
+
+	tampered := bytes.Replace(indented, []byte(`"bob"`), []byte(`"eve"`), 1)
+	if _, err := LoadStandardMerkleTreeFrom[string](bytes.NewReader(tampered)); !errors.Is(err, ErrTruncatedDump) {
+		t.Errorf("Expected an edited dump to fail its checksum, got %v", err)
+	}
+
+	for _, bad := range []string{`[]`, `{"tree": {}}`, `{"format": 1}`} {
+		if _, err := LoadStandardMerkleTreeFrom[string](bytes.NewReader([]byte(bad))); !errors.Is(err, ErrInvalidDump) {
+			t.Errorf("LoadStandardMerkleTreeFrom(%s) = %v, want ErrInvalidDump", bad, err)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+"""Writes blake2b256.json, the fixture of TestSimpleMerkleTreeBlake2b256:
+
+    python3 blake2b256.py > blake2b256.json
+
+It builds the tree of NewSimpleMerkleTreeBlake2b256 with SortLeaves from
+hashlib alone: leaves are blake2b-256(value), sorted, stored after the n - 1
+internal nodes of a flat array, and node i is blake2b-256(min || max) of
+nodes 2i + 1 and 2i + 2.
+"""
+import hashlib
+import json
+import random
+
+
+def blake2b256(data):
+    return hashlib.blake2b(data, digest_size=32).digest()
+
+
+values = [random.Random(2037 + i).randbytes(32) for i in range(1000)]
+leaves = sorted(blake2b256(v) for v in values)
+
+tree = [b""] * (len(leaves) - 1) + leaves
+for i in range(len(leaves) - 2, -1, -1):
+    left, right = sorted((tree[2 * i + 1], tree[2 * i + 2]))
+    tree[i] = blake2b256(left + right)
+
+print(json.dumps({
+    "values": ["0x" + v.hex() for v in values],
+    "root": "0x" + tree[0].hex(),
+}, indent=2))
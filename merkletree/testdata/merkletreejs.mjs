@@ -0,0 +1,21 @@
+// Writes merkletreejs.json, the fixtures of TestMerkleTreeJSFixtures:
+//
+//   npm install merkletreejs && node merkletreejs.mjs > merkletreejs.json
+import { createHash } from 'node:crypto'
+import { MerkleTree } from 'merkletreejs'
+
+const sha256 = (b) => createHash('sha256').update(b).digest()
+const hexOf = (s) => '0x' + Buffer.from(s).toString('hex')
+
+const cases = [
+  { name: 'pre-hashed leaves', leaves: [0, 1, 2, 3, 4].map(i => '0x' + sha256(Buffer.from(`leaf-${i}`)).toString('hex')), options: { sortPairs: true } },
+  { name: 'three pre-hashed leaves', leaves: ['a', 'b', 'c'].map(s => '0x' + sha256(Buffer.from(s)).toString('hex')), options: { sortPairs: true } },
+  { name: 'hashed and sorted leaves', leaves: ['alice', 'bob', 'charlie', 'dave', 'eve', 'frank', 'grace'].map(hexOf), options: { sortPairs: true, hashLeaves: true, sortLeaves: true } },
+]
+
+const out = cases.map(({ name, leaves, options }) => {
+  const tree = new MerkleTree(leaves, sha256, options)
+  const treeLeaves = tree.getHexLeaves()
+  return { name, options, leaves, treeLeaves, root: tree.getHexRoot(), proofs: treeLeaves.map((l, i) => tree.getHexProof(l, i)) }
+})
+process.stdout.write(JSON.stringify(out, null, 2) + '\n')
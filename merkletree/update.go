@@ -0,0 +1,170 @@
+package merkletree
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// UpdateLeaf replaces the value at index, in insertion order, with newValue.
+// The result is the tree built over the new values, in every leaf order.
+// When the new leaf keeps its position, only the nodes on the path from it to
+// the root are hashed again: O(log n) hashes where a rebuild hashes every
+// value. That is always the case for unsorted trees. In a sorted or shuffled
+// tree the new leaf may belong elsewhere; the leaves are then laid out again
+// as AppendLeaves does and every internal node is hashed again, so the tree
+// stays in the order it reports and non-inclusion proofs and later appends
+// see the leaves where they are.
+//
+// The root changes, so proofs and claims issued before no longer verify.
+// Metadata attached to the value is kept. A built HashLookup is updated in
+// place unless the old or new leaf occurs more than once or the leaves moved;
+// otherwise the indexes are built again on first use. Pre-generated proofs
+// stay valid, or are generated again when the leaves moved.
+//
+// An index out of range fails with an error wrapping ErrInvalidIndex, and a
+// value that does not hash to a 32-byte leaf with an *InputError; either
+// leaves the tree unchanged. A tree whose values were dropped fails with
+// ErrValuesDropped, and a shuffled tree whose seed was withheld with
+// ErrSeedWithheld.
+func (m *MerkleTreeImpl[T]) UpdateLeaf(index int, newValue T) error {
+	if index < 0 || index >= len(m.Values) {
+		return fmt.Errorf("%w: value %d of %d", ErrInvalidIndex, index, len(m.Values))
+	}
+	if m.valuesDropped {
+		return fmt.Errorf("%w: cannot update the tree", ErrValuesDropped)
+	}
+	if m.algorithm.LeafOrder == LeafOrderShuffled && m.shuffleSeed == nil {
+		return fmt.Errorf("%w: cannot place the new leaf in the shuffled order", ErrSeedWithheld)
+	}
+	if err := m.checkLeafHash(); err != nil {
+		return err
+	}
+	if err := checkValueSize(index, newValue, m.maxValueBytes); err != nil {
+		return err
+	}
+	leaf := m.LeafHash(newValue)
+	if err := leafHashError(newValue, leaf); err != nil {
+		return &InputError{Index: index, Err: err}
+	}
+
+	nodeHash := m.NodeHash
+	if nodeHash == nil {
+		nodeHash = StandardNodeHash
+	}
+	i := m.Values[index].TreeIndex
+	if !m.leafFits(i, appendLeaf{hash: leaf, valueIndex: index}) {
+		return m.relayout(index, newValue, leaf)
+	}
+	old := m.Tree[i]
+	m.Tree[i] = leaf
+	for i > 0 {
+		i = parentIndex(i)
+		m.Tree[i] = nodeHash(m.Tree[LeftChildIndex(i)], m.Tree[RightChildIndex(i)])
+	}
+	m.Values[index].Value = newValue
+	m.updateHashLookup(index, old.Normalize(), leaf.Normalize())
+	return nil
+}
+
+// leafFits reports whether leaf, replacing the leaf at treeIndex, sorts
+// between the leaves on either side of it, so that the tree stays in its
+// order without moving any leaf.
+func (m *MerkleTreeImpl[T]) leafFits(treeIndex int, leaf appendLeaf) bool {
+	compare := m.leafCompare()
+	if compare == nil {
+		return true
+	}
+	leafStart := len(m.Tree) - len(m.Values)
+	neighbor := func(i int) appendLeaf { return appendLeaf{hash: m.Tree[i], valueIndex: m.valueIndexAt(i)} }
+	if treeIndex > leafStart && compare(neighbor(treeIndex-1), leaf) > 0 {
+		return false
+	}
+	return treeIndex == len(m.Tree)-1 || compare(leaf, neighbor(treeIndex+1)) < 0
+}
+
+// leafCompare returns how building the tree orders two leaves, or nil if the
+// order does not depend on their hashes. Equal leaves keep their input order,
+// except under CompatLatest, which sorts ascending and reverses.
+func (m *MerkleTreeImpl[T]) leafCompare() func(a, b appendLeaf) int {
+	switch m.algorithm.LeafOrder {
+	case LeafOrderAscending, LeafOrderDescending:
+		descending := m.algorithm.LeafOrder == LeafOrderDescending
+		inputFirst := m.compatibility != CompatLatest
+		return func(a, b appendLeaf) int {
+			result, _ := Compare(a.hash, b.hash)
+			if descending {
+				result = -result
+			}
+			switch {
+			case result != 0:
+				return result
+			case inputFirst:
+				return cmp.Compare(a.valueIndex, b.valueIndex)
+			}
+			return cmp.Compare(b.valueIndex, a.valueIndex)
+		}
+	case LeafOrderShuffled:
+		return func(a, b appendLeaf) int {
+			ka, kb := shuffleKey(m.shuffleSeed, hexLeafBytes(a.hash)), shuffleKey(m.shuffleSeed, hexLeafBytes(b.hash))
+			if result := bytes.Compare(ka[:], kb[:]); result != 0 {
+				return result
+			}
+			return cmp.Compare(a.valueIndex, b.valueIndex)
+		}
+	}
+	return nil
+}
+
+// relayout replaces the value at index with newValue, whose leaf hash is
+// leaf, and lays the leaves out again in the order of the tree.
+func (m *MerkleTreeImpl[T]) relayout(index int, newValue T, leaf HexString) error {
+	leaves := make([]appendLeaf, len(m.Values))
+	for i, v := range m.Values {
+		leaves[i] = appendLeaf{hash: m.Tree[v.TreeIndex], valueIndex: i}
+	}
+	leaves[index].hash = leaf
+	if m.algorithm.LeafOrder == LeafOrderShuffled {
+		sortByShuffleKey(m.shuffleSeed, leaves, func(l appendLeaf) []byte { return hexLeafBytes(l.hash) })
+	} else {
+		slices.SortFunc(leaves, m.leafCompare())
+	}
+
+	hashes := make([]BytesLike, len(leaves))
+	for i, l := range leaves {
+		hashes[i] = l.hash
+	}
+	tree, err := makeMerkleTree(hashes, m.NodeHash, 1, nil)
+	if err != nil {
+		return err
+	}
+	m.Tree = tree
+	leafStart := len(tree) - len(leaves)
+	for i, l := range leaves {
+		m.Values[l.valueIndex].TreeIndex = leafStart + i
+	}
+	m.Values[index].Value = newValue
+	m.invalidateIndexes()
+	if m.proofs != nil {
+		m.pregenerate()
+	}
+	return nil
+}
+
+// updateHashLookup moves the value at index from oldHash to newHash in a
+// built HashLookup. When either hash is duplicated, or the prefix index is
+// built, the indexes are dropped to be built again instead.
+func (m *MerkleTreeImpl[T]) updateHashLookup(index int, oldHash, newHash HexString) {
+	lookup := m.HashLookup
+	if lookup == nil || m.prefixIndex != nil || len(m.duplicates[oldHash]) > 0 {
+		m.invalidateIndexes()
+		return
+	}
+	if _, found := lookup[newHash]; found && oldHash != newHash {
+		m.invalidateIndexes()
+		return
+	}
+	delete(lookup, oldHash)
+	lookup[newHash] = index
+}
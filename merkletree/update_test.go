@@ -0,0 +1,233 @@
+package merkletree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestUpdateLeafMatchesRebuild(t *testing.T) {
+	configs := map[string]MerkleTreeOptions{
+		"insertion":    {SortLeaves: Bool(false), Compatibility: CompatV0},
+		"reversed":     {Compatibility: CompatLatest, PreserveOrder: true},
+		"eager":        {SortLeaves: Bool(false), Compatibility: CompatV0, EagerIndexes: true},
+		"prefix index": {SortLeaves: Bool(false), Compatibility: CompatV0, EagerIndexes: true, PrefixIndex: true},
+		"pregenerated": {SortLeaves: Bool(false), Compatibility: CompatV0, PregenerateProofs: true},
+		"ascending":    {SortLeaves: Bool(true), Compatibility: CompatV0, EagerIndexes: true},
+		"descending":   {SortLeaves: Bool(true), SortDescending: true, Compatibility: CompatV0},
+		"latest":       {Compatibility: CompatLatest, PregenerateProofs: true},
+		"shuffled":     {ShuffleSeed: []byte("seed"), Compatibility: CompatV0},
+	}
+
+	for name, options := range configs {
+		for _, index := range []int{0, 6, 12} {
+			// A value that moves the leaf in sorted trees, one that repeats
+			// another value, and the value itself, which keeps the leaf in place
+			for _, value := range []string{"updated", appendValues(13)[(index+5)%13], appendValues(13)[index]} {
+				t.Run(fmt.Sprintf("%s/%d/%s", name, index, value), func(t *testing.T) {
+					values := appendValues(13)
+					got, err := NewStandardMerkleTree(values, options)
+					if err != nil {
+						t.Fatalf("Failed to create tree: %v", err)
+					}
+					if err := got.UpdateLeaf(index, value); err != nil {
+						t.Fatalf("UpdateLeaf failed: %v", err)
+					}
+					values[index] = value
+					want, err := NewStandardMerkleTree(values, options)
+					if err != nil {
+						t.Fatalf("Failed to create tree: %v", err)
+					}
+
+					if !reflect.DeepEqual(got.Tree, want.Tree) {
+						t.Fatalf("Root after update %s, rebuilt %s", got.Root(), want.Root())
+					}
+					if got.WarmIndexes(context.Background()) != nil || want.WarmIndexes(context.Background()) != nil {
+						t.Fatalf("WarmIndexes failed")
+					}
+					if !reflect.DeepEqual(got.HashLookup, want.HashLookup) || !reflect.DeepEqual(got.duplicates, want.duplicates) {
+						t.Errorf("Hash lookup differs from the rebuilt tree")
+					}
+					if !reflect.DeepEqual(got.prefixIndex, want.prefixIndex) {
+						t.Errorf("Prefix index differs from the rebuilt tree")
+					}
+					for i := range values {
+						proof, err := got.GetProof(i)
+						if err != nil {
+							t.Fatal(err)
+						}
+						if wantProof, _ := want.GetProof(i); !reflect.DeepEqual(proof, wantProof) {
+							t.Errorf("Proof of value %d differs from the rebuilt tree", i)
+						}
+						if ok, err := got.Verify(i, proof); err != nil || !ok {
+							t.Errorf("Proof of value %d does not verify: %v", i, err)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestUpdateLeafSorted(t *testing.T) {
+	values := []string{"a", "b", "c", "d", "e"}
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(true), Compatibility: CompatV0})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if err := tree.UpdateLeaf(2, "z"); err != nil {
+		t.Fatalf("UpdateLeaf failed: %v", err)
+	}
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Validate failed after update: %v", err)
+	}
+	for i, value := range []string{"a", "b", "z", "d", "e"} {
+		proof, err := tree.GetProof(value)
+		if err != nil {
+			t.Fatalf("GetProof(%q) failed: %v", value, err)
+		}
+		if ok, err := tree.Verify(value, proof); err != nil || !ok {
+			t.Errorf("Proof of value %d does not verify: %v", i, err)
+		}
+	}
+	if _, err := tree.GetProof("c"); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("Expected ErrValueNotFound for the replaced value, got %v", err)
+	}
+}
+
+// TestUpdateLeafNonInclusion updates a sorted tree with a value that sorts
+// elsewhere. The tree must still be in its order, or a non-inclusion proof
+// for the new value would find a gap where it sorts.
+func TestUpdateLeafNonInclusion(t *testing.T) {
+	values, absent := nonInclusionValues()
+	values = values[:20]
+	options := SimpleMerkleTreeOptions{MerkleTreeOptions: MerkleTreeOptions{SortLeaves: Bool(true), Compatibility: CompatV0}, NodeHash: PositionalNodeHash}
+	tree, err := NewSimpleMerkleTree(values, options)
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	n := len(values)
+	leaves := tree.Tree[len(tree.Tree)-n:]
+
+	// Replace the first leaf with a value that sorts between two later ones
+	var added BytesLike
+	for _, value := range absent {
+		hash := tree.LeafHash(value)
+		if low, _ := Compare(hash, leaves[2]); low > 0 {
+			if high, _ := Compare(hash, leaves[n-1]); high < 0 {
+				added = value
+				break
+			}
+		}
+	}
+	replaced := tree.valueIndexAt(len(tree.Tree) - n)
+	if err := tree.UpdateLeaf(replaced, added); err != nil {
+		t.Fatalf("UpdateLeaf failed: %v", err)
+	}
+
+	if _, err := tree.GetNonInclusionProof(added); !errors.Is(err, ErrValueIncluded) {
+		t.Errorf("Expected ErrValueIncluded for the new value, got %v", err)
+	}
+	// The leaves on either side of the new one must not pass as adjacent
+	hash := tree.LeafHash(added)
+	leaves = tree.Tree[len(tree.Tree)-n:]
+	for p := 1; p < n; p++ {
+		if c, _ := Compare(leaves[p], hash); c <= 0 {
+			continue
+		}
+		prev := p - 1
+		if leaves[prev] == hash {
+			prev--
+		}
+		forged := NonInclusionProof{LeafCount: n, Left: tree.nonInclusionNeighbor(n, prev), Right: tree.nonInclusionNeighbor(n, p)}
+		if ok, err := tree.VerifyNonInclusion(added, forged); ok || err != nil {
+			t.Errorf("Proof that the new value is between leaves %d and %d = %v, %v; want false", prev, p, ok, err)
+		}
+		break
+	}
+
+	proof, err := tree.GetNonInclusionProof(values[replaced])
+	if err != nil {
+		t.Fatalf("GetNonInclusionProof of the replaced value failed: %v", err)
+	}
+	if ok, err := tree.VerifyNonInclusion(values[replaced], proof); err != nil || !ok {
+		t.Errorf("Proof that the replaced value is absent does not verify: %v", err)
+	}
+
+	// Later appends place their leaves among the leaves as they now are
+	if err := tree.AppendLeaves(absent[:3]); err != nil {
+		t.Fatalf("AppendLeaves failed: %v", err)
+	}
+	updated := slices.Clone(values)
+	updated[replaced] = added
+	want, err := NewSimpleMerkleTree(append(updated, absent[:3]...), options)
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	if !reflect.DeepEqual(tree.Tree, want.Tree) {
+		t.Errorf("Root after update and append %s, built %s", tree.Root(), want.Root())
+	}
+}
+
+func TestUpdateLeafDuplicates(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"a", "b", "a", "c"}, MerkleTreeOptions{SortLeaves: Bool(false), Compatibility: CompatV0})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	tree.WarmIndexes(context.Background())
+	if err := tree.UpdateLeaf(0, "d"); err != nil {
+		t.Fatalf("UpdateLeaf failed: %v", err)
+	}
+	if index, err := tree.getLeafIndex("a"); err != nil || index != 2 {
+		t.Errorf("getLeafIndex(a) = %d, %v; want 2", index, err)
+	}
+	if err := tree.UpdateLeaf(1, "c"); err != nil {
+		t.Fatalf("UpdateLeaf failed: %v", err)
+	}
+	tree.WarmIndexes(context.Background())
+	if len(tree.duplicates[tree.Tree[tree.Values[3].TreeIndex].Normalize()]) != 2 {
+		t.Error("An update that duplicates a value should show in the duplicate occurrences")
+	}
+}
+
+func TestUpdateLeafErrors(t *testing.T) {
+	tree, err := NewSimpleMerkleTree([]BytesLike{"0x01", "0x02"}, SimpleMerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	root := tree.Root()
+
+	for _, index := range []int{-1, 2} {
+		if err := tree.UpdateLeaf(index, "0x03"); !errors.Is(err, ErrInvalidIndex) {
+			t.Errorf("UpdateLeaf(%d): expected ErrInvalidIndex, got %v", index, err)
+		}
+	}
+	err = tree.UpdateLeaf(1, 3.5)
+	var inputErr *InputError
+	if !errors.Is(err, ErrInvalidValue) || !errors.As(err, &inputErr) || inputErr.Index != 1 {
+		t.Errorf("Expected an InputError at value index 1, got %v", err)
+	}
+	if tree.Root() != root {
+		t.Error("A failed update should leave the tree unchanged")
+	}
+
+	dropped, err := NewStandardMerkleTree([]string{"a", "b"}, MerkleTreeOptions{DropValuesAfterBuild: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dropped.UpdateLeaf(0, "c"); !errors.Is(err, ErrValuesDropped) {
+		t.Errorf("Expected ErrValuesDropped, got %v", err)
+	}
+
+	shuffled, err := NewStandardMerkleTree([]string{"a", "b"}, MerkleTreeOptions{ShuffleSeed: []byte("seed")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shuffled.RedactShuffleSeed()
+	if err := shuffled.UpdateLeaf(0, "c"); !errors.Is(err, ErrSeedWithheld) {
+		t.Errorf("Expected ErrSeedWithheld, got %v", err)
+	}
+}
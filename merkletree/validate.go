@@ -0,0 +1,118 @@
+package merkletree
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxReportedNodes is the number of mismatching and malformed nodes a
+// TreeReport lists individually; the counts cover all of them.
+const MaxReportedNodes = 16
+
+// NodeMismatch is an internal node whose stored hash differs from the hash
+// of its children.
+type NodeMismatch struct {
+	Index    int       // Index of the node in the tree
+	Expected HexString // Hash of its children
+	Actual   HexString // Hash stored in the tree
+}
+
+// TreeReport describes what ValidateTreeDetailed checked and found.
+type TreeReport struct {
+	Nodes           int            // Length of the tree
+	Leaves          int            // Number of leaves, (Nodes+1)/2
+	LengthValid     bool           // Nodes is 2n-1 for some n >= 1
+	InternalChecked int            // Internal nodes recomputed from their children
+	MismatchCount   int            // Internal nodes whose hash is wrong
+	Mismatches      []NodeMismatch // The first MaxReportedNodes of them, by index
+	InvalidCount    int            // Nodes that are not 32-byte hashes
+	InvalidNodes    []int          // The first MaxReportedNodes of them, by index
+}
+
+// Valid reports whether the tree passed every check.
+func (r TreeReport) Valid() bool {
+	return r.LengthValid && r.MismatchCount == 0 && r.InvalidCount == 0
+}
+
+// String summarizes the findings in one line.
+func (r TreeReport) String() string {
+	if r.Valid() {
+		return fmt.Sprintf("valid: %d nodes, %d leaves, %d internal nodes checked", r.Nodes, r.Leaves, r.InternalChecked)
+	}
+
+	var findings []string
+	if !r.LengthValid {
+		findings = append(findings, fmt.Sprintf("length %d is not 2n-1", r.Nodes))
+	}
+	if r.InvalidCount > 0 {
+		findings = append(findings, fmt.Sprintf("%d malformed nodes, first at %d", r.InvalidCount, r.InvalidNodes[0]))
+	}
+	if r.MismatchCount > 0 {
+		first := r.Mismatches[0]
+		findings = append(findings, fmt.Sprintf("%d mismatched nodes, first at %d: expected %s, got %s",
+			r.MismatchCount, first.Index, first.Expected, first.Actual))
+	}
+	return "invalid: " + strings.Join(findings, "; ")
+}
+
+// ValidateTreeDetailed checks a flat tree like IsValidMerkleTree and reports
+// what it checked: every internal node is recomputed from its children with
+// nodeHash, every node must be a 32-byte hash, and the length must be 2n-1.
+// A corrupted internal node shows up as a mismatch at its own index and at its
+// parent's; a corrupted leaf only at its parent's.
+//
+// The error wraps ErrInvalidTree and summarizes the report when the tree is
+// not valid, or is ErrEmptyTree for an empty tree. If nodeHash is nil,
+// StandardNodeHash is used.
+func ValidateTreeDetailed(tree []HexString, nodeHash NodeHash) (TreeReport, error) {
+	if nodeHash == nil {
+		nodeHash = StandardNodeHash
+	}
+	report := TreeReport{
+		Nodes:       len(tree),
+		Leaves:      leafCount(len(tree)),
+		LengthValid: len(tree)%2 == 1,
+	}
+	if len(tree) == 0 {
+		return report, ErrEmptyTree
+	}
+
+	for i, node := range tree {
+		if !IsValidMerkleNode(node) {
+			report.InvalidCount++
+			if len(report.InvalidNodes) < MaxReportedNodes {
+				report.InvalidNodes = append(report.InvalidNodes, i)
+			}
+		}
+
+		right := RightChildIndex(i)
+		if right >= len(tree) {
+			continue
+		}
+		report.InternalChecked++
+		if expected := nodeHash(tree[LeftChildIndex(i)], tree[right]); expected != node {
+			report.MismatchCount++
+			if len(report.Mismatches) < MaxReportedNodes {
+				report.Mismatches = append(report.Mismatches, NodeMismatch{Index: i, Expected: expected, Actual: node})
+			}
+		}
+	}
+
+	if !report.Valid() {
+		return report, fmt.Errorf("%w: %s", ErrInvalidTree, report)
+	}
+	return report, nil
+}
+
+// ValidateDetailed is Validate with the structural findings of
+// ValidateTreeDetailed. The values are checked first; the report is filled
+// in even when one of them does not match its leaf.
+func (m *MerkleTreeImpl[T]) ValidateDetailed() (TreeReport, error) {
+	report, treeErr := ValidateTreeDetailed(m.Tree, m.NodeHash)
+	for i := range m.Values {
+		if err := m.validateValueAt(i); err != nil {
+			return report, fmt.Errorf("validation failed at index %d: %w", i, err)
+		}
+	}
+	return report, treeErr
+}
@@ -0,0 +1,186 @@
+package merkletree
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// validTestTree returns the flat tree of a simple tree over n leaves.
+func validTestTree(t *testing.T, n int) []HexString {
+	t.Helper()
+	values := make([]BytesLike, n)
+	for i := range values {
+		values[i] = fmt.Sprintf("0x%064x", i+1)
+	}
+	tree, err := NewSimpleMerkleTree(values, SimpleMerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	return slices.Clone(tree.Tree)
+}
+
+func TestValidateTreeDetailedValid(t *testing.T) {
+	tree := validTestTree(t, 8)
+	report, err := ValidateTreeDetailed(tree, nil)
+	if err != nil {
+		t.Fatalf("Valid tree reported invalid: %v", err)
+	}
+	if report.Nodes != 15 || report.Leaves != 8 || report.InternalChecked != 7 || !report.LengthValid {
+		t.Errorf("Report = %+v", report)
+	}
+}
+
+func TestValidateTreeDetailedSingleNode(t *testing.T) {
+	tree := validTestTree(t, 1)
+	report, err := ValidateTreeDetailed(tree, nil)
+	if err != nil || !IsValidMerkleTree(tree, StandardNodeHash) {
+		t.Fatalf("Single-node tree should be valid: %v", err)
+	}
+	if report.InternalChecked != 0 || report.Leaves != 1 {
+		t.Errorf("Single-node report = %+v, want nothing to recompute and one leaf", report)
+	}
+
+	if _, err := ValidateTreeDetailed([]HexString{"0x1234"}, nil); !errors.Is(err, ErrInvalidTree) {
+		t.Errorf("A single malformed node should be invalid, got %v", err)
+	}
+	if _, err := ValidateTreeDetailed(nil, nil); !errors.Is(err, ErrEmptyTree) {
+		t.Errorf("Expected ErrEmptyTree, got %v", err)
+	}
+}
+
+func TestValidateTreeDetailedLocalizes(t *testing.T) {
+	// 8 leaves: root 0, mid-level 1-6, leaves 7-14
+	corrupt := HexString(fmt.Sprintf("0x%064x", 0xdead))
+	tests := []struct {
+		name       string
+		index      int
+		mismatches []int
+	}{
+		{"root", 0, []int{0}},
+		{"mid-level", 4, []int{1, 4}},
+		{"leaf", 10, []int{4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := validTestTree(t, 8)
+			original := tree[tt.index]
+			tree[tt.index] = corrupt
+
+			report, err := ValidateTreeDetailed(tree, nil)
+			if !errors.Is(err, ErrInvalidTree) {
+				t.Fatalf("Expected ErrInvalidTree, got %v", err)
+			}
+			if IsValidMerkleTree(tree, StandardNodeHash) {
+				t.Errorf("IsValidMerkleTree should agree with the report")
+			}
+
+			var got []int
+			for _, m := range report.Mismatches {
+				got = append(got, m.Index)
+			}
+			if !slices.Equal(got, tt.mismatches) || report.MismatchCount != len(tt.mismatches) {
+				t.Fatalf("Mismatches at %v, want %v", got, tt.mismatches)
+			}
+			// The deepest mismatch is the corrupted node itself, or the parent of a leaf
+			if deepest := report.Mismatches[len(report.Mismatches)-1]; deepest.Index == tt.index {
+				if deepest.Actual != corrupt || deepest.Expected != original {
+					t.Errorf("Mismatch at %d: expected %s, got %s", tt.index, deepest.Expected, deepest.Actual)
+				}
+			} else if deepest.Index != parentIndex(tt.index) {
+				t.Errorf("Deepest mismatch at %d does not localize node %d", deepest.Index, tt.index)
+			}
+			if report.InvalidCount != 0 {
+				t.Errorf("Corrupted hashes are well formed, got %d invalid nodes", report.InvalidCount)
+			}
+		})
+	}
+}
+
+func TestValidateTreeDetailedMalformed(t *testing.T) {
+	tree := validTestTree(t, 4)
+	tree[5] = "0x1234"
+	report, err := ValidateTreeDetailed(tree, nil)
+	if !errors.Is(err, ErrInvalidTree) {
+		t.Fatalf("Expected ErrInvalidTree, got %v", err)
+	}
+	if report.InvalidCount != 1 || !slices.Equal(report.InvalidNodes, []int{5}) {
+		t.Errorf("Invalid nodes = %v, want [5]", report.InvalidNodes)
+	}
+
+	even := validTestTree(t, 4)[:6]
+	report, err = ValidateTreeDetailed(even, nil)
+	if !errors.Is(err, ErrInvalidTree) || report.LengthValid {
+		t.Errorf("Even-length tree should fail the 2n-1 check: %+v, %v", report, err)
+	}
+}
+
+func TestValidateTreeDetailedCap(t *testing.T) {
+	tree := validTestTree(t, 64)
+	first := len(tree) - 64
+	for i := first; i < len(tree); i++ {
+		tree[i] = HexString(fmt.Sprintf("0x%064x", 0xbeef+i))
+	}
+
+	report, _ := ValidateTreeDetailed(tree, nil)
+	if report.MismatchCount != 32 || len(report.Mismatches) != MaxReportedNodes {
+		t.Errorf("Expected 32 mismatches with %d listed, got %d with %d listed",
+			MaxReportedNodes, report.MismatchCount, len(report.Mismatches))
+	}
+}
+
+func TestValidateDetailedMethod(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"a", "b", "c"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	report, err := tree.ValidateDetailed()
+	if err != nil || report.InternalChecked != 2 {
+		t.Fatalf("ValidateDetailed = %+v, %v", report, err)
+	}
+
+	tree.Tree[1] = tree.Tree[2]
+	report, err = tree.ValidateDetailed()
+	if !errors.Is(err, ErrInvalidTree) || report.MismatchCount == 0 {
+		t.Errorf("Expected a mismatch report, got %+v, %v", report, err)
+	}
+	if err := tree.Validate(); !errors.Is(err, ErrInvalidTree) {
+		t.Errorf("Validate should return the same error, got %v", err)
+	}
+}
+
+func TestValidateNilHashes(t *testing.T) {
+	built, err := NewStandardMerkleTree([]string{"alice", "bob", "charlie"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	// A nil NodeHash falls back to StandardNodeHash everywhere
+	tree := &MerkleTreeImpl[string]{Tree: built.Tree, Values: built.Values, LeafHash: StandardLeafHash[string]}
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Validate with a nil NodeHash failed: %v", err)
+	}
+	if !IsValidMerkleTree(tree.Tree, nil) {
+		t.Errorf("IsValidMerkleTree with a nil node hash rejected the tree")
+	}
+	if err := tree.AppendLeaves([]string{"dave"}); err != nil {
+		t.Fatalf("AppendLeaves with a nil NodeHash failed: %v", err)
+	}
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Validate after AppendLeaves failed: %v", err)
+	}
+
+	// A nil LeafHash is reported rather than called
+	tree = &MerkleTreeImpl[string]{Tree: built.Tree, Values: built.Values}
+	if err := tree.Validate(); !errors.Is(err, ErrInvalidTree) {
+		t.Errorf("Validate with a nil LeafHash returned %v, want ErrInvalidTree", err)
+	}
+	if _, err := tree.GetProof("alice"); !errors.Is(err, ErrInvalidTree) {
+		t.Errorf("GetProof with a nil LeafHash returned %v, want ErrInvalidTree", err)
+	}
+	if err := tree.AppendLeaves([]string{"dave"}); !errors.Is(err, ErrInvalidTree) {
+		t.Errorf("AppendLeaves with a nil LeafHash returned %v, want ErrInvalidTree", err)
+	}
+}
@@ -0,0 +1,147 @@
+package merkletree
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// bytesTag is the key of the JSON object a string value that is not valid
+// UTF-8 is written as, {"$bytes": "0x..."}. encoding/json would replace its
+// invalid bytes with U+FFFD, and the value would no longer hash to its leaf.
+const bytesTag = "$bytes"
+
+// DumpValue is one entry of the values section of a dump.
+//
+// String values that are not valid UTF-8 are written as {"$bytes": "0x..."}
+// and read back as the exact original bytes. Valid strings, including NUL and
+// characters outside the Basic Multilingual Plane, are written as plain JSON
+// strings, which keep them intact.
+type DumpValue[T any] struct {
+	Value     T               `json:"value"`
+	TreeIndex int             `json:"treeIndex"`
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+}
+
+// MarshalJSON encodes the entry, tagging a value that is not valid UTF-8.
+func (v DumpValue[T]) MarshalJSON() ([]byte, error) {
+	value, err := marshalValue(v.Value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		Value     json.RawMessage `json:"value"`
+		TreeIndex int             `json:"treeIndex"`
+		Metadata  json.RawMessage `json:"metadata,omitempty"`
+	}{value, v.TreeIndex, v.Metadata})
+}
+
+// UnmarshalJSON decodes the entry, restoring the bytes of a tagged value.
+func (v *DumpValue[T]) UnmarshalJSON(data []byte) error {
+	var entry struct {
+		Value     json.RawMessage `json:"value"`
+		TreeIndex int             `json:"treeIndex"`
+		Metadata  json.RawMessage `json:"metadata,omitempty"`
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return err
+	}
+	if err := unmarshalValue(entry.Value, &v.Value); err != nil {
+		return err
+	}
+	v.TreeIndex = entry.TreeIndex
+	v.Metadata = entry.Metadata
+	return nil
+}
+
+// MarshalJSON encodes the claim, tagging a value that is not valid UTF-8 as
+// DumpValue does.
+func (c Claim[T]) MarshalJSON() ([]byte, error) {
+	type claim Claim[T]
+	if !needsBytesTag(c.Value) {
+		return json.Marshal(claim(c))
+	}
+	value, err := marshalValue(c.Value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		claim
+		Value json.RawMessage `json:"value"`
+	}{claim(c), value})
+}
+
+// UnmarshalJSON decodes the claim, restoring the bytes of a tagged value.
+func (c *Claim[T]) UnmarshalJSON(data []byte) error {
+	type claim Claim[T]
+	aux := struct {
+		*claim
+		Value json.RawMessage `json:"value"`
+	}{claim: (*claim)(c)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	return unmarshalValue(aux.Value, &c.Value)
+}
+
+// needsBytesTag reports whether value is a string that is not valid UTF-8.
+func needsBytesTag(value any) bool {
+	s, ok := value.(string)
+	return ok && !utf8.ValidString(s)
+}
+
+// marshalValue encodes a value, as {"$bytes": "0x..."} if it is a string
+// that is not valid UTF-8.
+func marshalValue(value any) ([]byte, error) {
+	if needsBytesTag(value) {
+		s := value.(string)
+		return json.Marshal(map[string]HexString{bytesTag: HexString("0x" + hex.EncodeToString([]byte(s)))})
+	}
+	return json.Marshal(value)
+}
+
+// unmarshalValue decodes a value written by marshalValue into value. The
+// $bytes form is only recognised for string and BytesLike values, and must
+// hold nothing else.
+func unmarshalValue[T any](data []byte, value *T) error {
+	if len(data) == 0 {
+		return nil // Missing value, as encoding/json leaves it
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		switch target := any(value).(type) {
+		case *string:
+			s, err := decodeBytesTag(data)
+			if err == nil {
+				*target = s
+			}
+			return err
+		case *BytesLike:
+			s, err := decodeBytesTag(data)
+			if err == nil {
+				*target = s
+			}
+			return err
+		}
+	}
+	return json.Unmarshal(data, value)
+}
+
+// decodeBytesTag returns the string a {"$bytes": "0x..."} object holds.
+func decodeBytesTag(data []byte) (string, error) {
+	var tagged map[string]string
+	if err := json.Unmarshal(data, &tagged); err != nil {
+		return "", fmt.Errorf("%w: value is an object but not {%q: hex}", ErrInvalidValue, bytesTag)
+	}
+	encoded, ok := tagged[bytesTag]
+	if !ok || len(tagged) != 1 || !strings.HasPrefix(encoded, "0x") {
+		return "", fmt.Errorf("%w: value is an object but not {%q: hex}", ErrInvalidValue, bytesTag)
+	}
+	decoded, err := hex.DecodeString(encoded[2:])
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrInvalidValue, bytesTag, err)
+	}
+	return string(decoded), nil
+}
@@ -0,0 +1,166 @@
+package merkletree
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// awkwardStrings are string values that JSON handles badly or not at all.
+var awkwardStrings = []string{
+	"plain",
+	"\xff\xfe",          // Not UTF-8
+	"caf\xe9",           // Latin-1
+	"\xed\xa0\x80",      // UTF-8 encoded surrogate, which is invalid
+	"nul\x00inside",     // Valid, but unusual
+	"astral \U0001F600", // Outside the Basic Multilingual Plane
+}
+
+func TestDumpValueRoundTripStandard(t *testing.T) {
+	tree, err := NewStandardMerkleTree(awkwardStrings, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	dump, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+	data, err := json.Marshal(dump)
+	if err != nil {
+		t.Fatalf("Failed to marshal dump: %v", err)
+	}
+	if !bytes.Contains(data, []byte(`{"$bytes":"0xfffe"}`)) {
+		t.Errorf("Dump does not tag the invalid string: %s", data)
+	}
+	if bytes.Contains(data, []byte(`"$bytes":"0x6e756c`)) {
+		t.Errorf("Dump tags a valid string: %s", data)
+	}
+	if err := VerifyDumpIntegrity(data, false); err != nil {
+		t.Errorf("Integrity footer does not match: %v", err)
+	}
+
+	var loaded StandardMerkleTreeData[string]
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("Failed to unmarshal dump: %v", err)
+	}
+	values := make([]string, len(loaded.Values))
+	for i, v := range loaded.Values {
+		values[i] = v.Value
+	}
+	if !slices.Equal(values, awkwardStrings) {
+		t.Fatalf("Values %q, want %q", values, awkwardStrings)
+	}
+	rebuilt, err := NewStandardMerkleTree(values, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to rebuild tree: %v", err)
+	}
+	if rebuilt.Root() != tree.Root() {
+		t.Errorf("Rebuilt root %s, want %s", rebuilt.Root(), tree.Root())
+	}
+	if err := rebuilt.Validate(); err != nil {
+		t.Errorf("Rebuilt tree does not validate: %v", err)
+	}
+}
+
+func TestDumpValueRoundTripSimple(t *testing.T) {
+	values := make([]BytesLike, len(awkwardStrings))
+	for i, s := range awkwardStrings {
+		values[i] = s
+	}
+	tree, err := NewSimpleMerkleTree(values, SimpleMerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	dump, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+	data, err := json.Marshal(dump)
+	if err != nil {
+		t.Fatalf("Failed to marshal dump: %v", err)
+	}
+
+	loaded, _, err := LoadSimpleMerkleTreeFrom(bytes.NewReader(data), LoadOptions{})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Root() != tree.Root() {
+		t.Errorf("Loaded root %s, want %s", loaded.Root(), tree.Root())
+	}
+	if err := loaded.Validate(); err != nil {
+		t.Errorf("Loaded tree does not validate: %v", err)
+	}
+	for i, v := range loaded.Values {
+		if v.Value != values[i] {
+			t.Errorf("Value %d is %q, want %q", i, v.Value, values[i])
+		}
+	}
+}
+
+func TestDumpValueLossyLegacyDump(t *testing.T) {
+	tree, err := NewSimpleMerkleTree([]BytesLike{"ok", "\xff"}, SimpleMerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	dump, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+	// What encoding/json wrote for the value before it was tagged
+	for i := range dump.Values {
+		if dump.Values[i].Value == "\xff" {
+			dump.Values[i].Value = "\uFFFD"
+		}
+	}
+	_, _, err = LoadSimpleMerkleTree(dump, nil)
+	if !errors.Is(err, ErrInvalidDump) || !strings.Contains(err.Error(), "U+FFFD") {
+		t.Errorf("Expected ErrInvalidDump naming U+FFFD, got %v", err)
+	}
+}
+
+func TestDumpValueRejectsMalformedTag(t *testing.T) {
+	for _, data := range []string{
+		`{"value":{"$bytes":"fffe"},"treeIndex":1}`,
+		`{"value":{"$bytes":"0xzz"},"treeIndex":1}`,
+		`{"value":{"$bytes":"0xff","extra":"0x00"},"treeIndex":1}`,
+		`{"value":{"other":"0xff"},"treeIndex":1}`,
+	} {
+		var v DumpValue[string]
+		if err := json.Unmarshal([]byte(data), &v); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("%s: expected ErrInvalidValue, got %v", data, err)
+		}
+	}
+}
+
+func TestClaimRoundTripInvalidUTF8(t *testing.T) {
+	tree, err := NewStandardMerkleTree(awkwardStrings, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	for _, value := range awkwardStrings {
+		claim, err := tree.ExportClaim(value)
+		if err != nil {
+			t.Fatalf("ExportClaim failed: %v", err)
+		}
+		data, err := json.Marshal(claim)
+		if err != nil {
+			t.Fatalf("Failed to marshal claim: %v", err)
+		}
+		var decoded Claim[string]
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Failed to unmarshal claim: %v", err)
+		}
+		if decoded.Value != value || decoded.Root != claim.Root || !slices.Equal(decoded.Proof, claim.Proof) {
+			t.Errorf("Claim of %q decoded as %+v", value, decoded)
+		}
+		if ok, err := tree.Verify(decoded.Value, decoded.Proof); err != nil || !ok {
+			t.Errorf("Decoded claim of %q does not verify: %v", value, err)
+		}
+		if valid, err := VerifyEnvelope(data, nil); err != nil || !valid {
+			t.Errorf("Claim of %q does not verify as an envelope: %v", value, err)
+		}
+	}
+}
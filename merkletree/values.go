@@ -0,0 +1,50 @@
+package merkletree
+
+import "fmt"
+
+// ValueProvider supplies the values of a tree built with DropValuesAfterBuild
+// from external storage, such as a database or the original input file.
+type ValueProvider[T any] interface {
+	// GetValue returns the value at the given value index.
+	GetValue(index int) (T, error)
+}
+
+// SetValueProvider plugs in the source of values dropped with
+// DropValuesAfterBuild, so that Dump, Entries and ExportClaim work again.
+// Values it returns are checked against their leaf hash when validating.
+func (m *MerkleTreeImpl[T]) SetValueProvider(provider ValueProvider[T]) {
+	m.valueProvider = provider
+}
+
+// ValuesDropped reports whether the tree was built with DropValuesAfterBuild.
+func (m *MerkleTreeImpl[T]) ValuesDropped() bool {
+	return m.valuesDropped
+}
+
+// dropValues discards the values, keeping their tree indices. The indexes
+// are built from the leaves, so they can still be built afterwards.
+func (m *MerkleTreeImpl[T]) dropValues() {
+	var zero T
+	for i := range m.Values {
+		m.Values[i].Value = zero
+	}
+	m.valuesDropped = true
+}
+
+// valueAt returns the value at index, from the ValueProvider if values were dropped.
+// The index must be in range.
+func (m *MerkleTreeImpl[T]) valueAt(index int) (T, error) {
+	if !m.valuesDropped {
+		return m.Values[index].Value, nil
+	}
+
+	var zero T
+	if m.valueProvider == nil {
+		return zero, fmt.Errorf("%w: value %d", ErrValuesDropped, index)
+	}
+	value, err := m.valueProvider.GetValue(index)
+	if err != nil {
+		return zero, fmt.Errorf("value provider: value %d: %w", index, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,148 @@
+package merkletree
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// sliceProvider serves values from a slice, standing in for external storage.
+type sliceProvider []string
+
+func (p sliceProvider) GetValue(index int) (string, error) {
+	if index < 0 || index >= len(p) {
+		return "", ErrInvalidIndex
+	}
+	return p[index], nil
+}
+
+func TestDropValuesAfterBuild(t *testing.T) {
+	values := []string{"alice", "bob", "charlie", "dave", "eve"}
+	full, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(true)})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(true), DropValuesAfterBuild: true})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	if !tree.ValuesDropped() {
+		t.Fatal("ValuesDropped should report the option")
+	}
+	for i, v := range tree.Values {
+		if v.Value != "" {
+			t.Errorf("Value %d should be dropped, got %q", i, v.Value)
+		}
+	}
+	if tree.Root() != full.Root() {
+		t.Errorf("Dropping values changed the root: %s, want %s", tree.Root(), full.Root())
+	}
+
+	for i, v := range values {
+		want, _ := full.ProofForIndex(i)
+		for _, leaf := range []any{i, v} {
+			proof, err := tree.GetProof(leaf)
+			if err != nil {
+				t.Fatalf("GetProof(%v) failed: %v", leaf, err)
+			}
+			if fmt.Sprint(proof) != fmt.Sprint(want) {
+				t.Errorf("GetProof(%v) = %v, want %v", leaf, proof, want)
+			}
+			if valid, err := tree.Verify(leaf, proof); err != nil || !valid {
+				t.Errorf("Verify(%v) should succeed: valid=%v err=%v", leaf, valid, err)
+			}
+		}
+
+		hash := full.Tree[full.Values[i].TreeIndex]
+		if index, ok := tree.HashLookup[hash]; !ok || index != i {
+			t.Errorf("HashLookup[%s] = %d, %v; want %d", hash, index, ok, i)
+		}
+	}
+
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Validate should succeed without values: %v", err)
+	}
+	if _, err := tree.GetProof(any("mallory")); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("Expected ErrValueNotFound, got %v", err)
+	}
+	if _, err := tree.Dump(); !errors.Is(err, ErrValuesDropped) {
+		t.Errorf("Dump: expected ErrValuesDropped, got %v", err)
+	}
+	if _, err := tree.Entries(); !errors.Is(err, ErrValuesDropped) {
+		t.Errorf("Entries: expected ErrValuesDropped, got %v", err)
+	}
+	if _, err := tree.ExportClaim(0); !errors.Is(err, ErrValuesDropped) {
+		t.Errorf("ExportClaim: expected ErrValuesDropped, got %v", err)
+	}
+}
+
+func TestValueProvider(t *testing.T) {
+	values := []string{"alice", "bob", "charlie"}
+	tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{DropValuesAfterBuild: true})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	tree.SetValueProvider(sliceProvider(values))
+
+	dump, err := tree.Dump()
+	if err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+	for i, v := range dump.Values {
+		if v.Value != values[i] {
+			t.Errorf("Dumped value %d is %q, want %q", i, v.Value, values[i])
+		}
+	}
+	claim, err := tree.ExportClaim("bob")
+	if err != nil || claim.Value != "bob" {
+		t.Errorf("ExportClaim should return the provided value: %+v, %v", claim, err)
+	}
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Validate failed: %v", err)
+	}
+
+	// A provider serving the wrong data is caught by validation
+	tree.SetValueProvider(sliceProvider{"alice", "mallory", "charlie"})
+	if err := tree.Validate(); err == nil {
+		t.Error("Validate should detect a provider returning a different value")
+	}
+}
+
+func TestDropValuesAfterBuildMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Builds trees over 8 MiB of values")
+	}
+
+	// build creates values that only the tree references once it returns
+	build := func(drop bool) *StandardMerkleTree[string] {
+		values := make([]string, 2000)
+		for i := range values {
+			values[i] = fmt.Sprintf("%04d", i) + strings.Repeat("x", 4096)
+		}
+		tree, err := NewStandardMerkleTree(values, MerkleTreeOptions{DropValuesAfterBuild: drop})
+		if err != nil {
+			t.Fatalf("Failed to create tree: %v", err)
+		}
+		return tree
+	}
+
+	retained := func(drop bool) int64 {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		tree := build(drop)
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		runtime.KeepAlive(tree)
+		return int64(after.HeapAlloc) - int64(before.HeapAlloc)
+	}
+
+	kept, dropped := retained(false), retained(true)
+	t.Logf("Retained %s with values, %s without", formatBytes(kept), formatBytes(dropped))
+	if dropped*4 > kept {
+		t.Errorf("Dropping values should save most of the memory: %d bytes kept vs %d dropped", kept, dropped)
+	}
+}
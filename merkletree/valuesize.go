@@ -0,0 +1,43 @@
+package merkletree
+
+import "fmt"
+
+// checkValueSize returns a *ValueSizeError if the value at index is larger
+// than limit. A limit of zero or less allows any size.
+func checkValueSize(index int, value any, limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+	if size := valueSize(value); size > limit {
+		return &ValueSizeError{Index: index, Size: size, Limit: limit}
+	}
+	return nil
+}
+
+// valueSize returns the size MaxValueBytes limits: the length of a string or
+// byte slice, the total of a slice of them, or the length of the value's
+// default formatting for anything else.
+func valueSize(value any) int {
+	switch v := value.(type) {
+	case string:
+		return len(v)
+	case HexString:
+		return len(v)
+	case []byte:
+		return len(v)
+	case []string:
+		size := 0
+		for _, s := range v {
+			size += len(s)
+		}
+		return size
+	case []any:
+		size := 0
+		for _, e := range v {
+			size += valueSize(e)
+		}
+		return size
+	default:
+		return len(fmt.Sprint(value))
+	}
+}
@@ -0,0 +1,138 @@
+package merkletree
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// largeValues returns three small values around one of size bytes.
+func largeValues(size int) []string {
+	return []string{"alice", strings.Repeat("x", size), "bob", "charlie"}
+}
+
+func TestMaxValueBytes(t *testing.T) {
+	const limit = 4 << 20
+
+	// A 3 MB value is under the limit and builds
+	tree, err := NewStandardMerkleTree(largeValues(3<<20), MerkleTreeOptions{MaxValueBytes: limit})
+	if err != nil {
+		t.Fatalf("Failed to create tree under the limit: %v", err)
+	}
+	if _, err := tree.GetProof(1); err != nil {
+		t.Errorf("GetProof of the large value failed: %v", err)
+	}
+	if _, err := tree.Dump(); err != nil {
+		t.Errorf("Dump with a large value failed: %v", err)
+	}
+
+	// A 5 MB value is over it
+	_, err = NewStandardMerkleTree(largeValues(5<<20), MerkleTreeOptions{MaxValueBytes: limit})
+	var sizeErr *ValueSizeError
+	if !errors.As(err, &sizeErr) || !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("Expected a ValueSizeError, got %v", err)
+	}
+	if sizeErr.Index != 1 || sizeErr.Size != 5<<20 || sizeErr.Limit != limit {
+		t.Errorf("ValueSizeError is %+v, want index 1, size %d, limit %d", sizeErr, 5<<20, limit)
+	}
+	if Code(err) != CodeValueTooLarge {
+		t.Errorf("Code = %s, want %s", Code(err), CodeValueTooLarge)
+	}
+	if len(err.Error()) > 200 {
+		t.Errorf("Error message is %d bytes long", len(err.Error()))
+	}
+
+	// Without a limit, any size builds
+	if _, err := NewStandardMerkleTree(largeValues(5<<20), MerkleTreeOptions{}); err != nil {
+		t.Errorf("Failed to create tree without a limit: %v", err)
+	}
+
+	var optErr *OptionError
+	if _, err := NewStandardMerkleTree(largeValues(8), MerkleTreeOptions{MaxValueBytes: -1}); !errors.As(err, &optErr) {
+		t.Errorf("Expected an OptionError for a negative MaxValueBytes, got %v", err)
+	}
+}
+
+func TestMaxValueBytesQuarantine(t *testing.T) {
+	tree, err := NewStandardMerkleTree(largeValues(2<<20), MerkleTreeOptions{MaxValueBytes: 1 << 20, QuarantineInvalid: true})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	quarantine := tree.Quarantine()
+	if len(quarantine) != 1 || quarantine[0].Index != 1 || !errors.Is(quarantine[0].Err, ErrValueTooLarge) {
+		t.Fatalf("Quarantine is %+v, want the large value", quarantine)
+	}
+	if len(quarantine[0].Preview) > maxPreviewLength+len("...") {
+		t.Errorf("Preview is %d bytes long", len(quarantine[0].Preview))
+	}
+	if len(tree.Values) != 3 {
+		t.Errorf("Tree has %d values, want 3", len(tree.Values))
+	}
+}
+
+func TestMaxValueBytesAppendAndLeafSet(t *testing.T) {
+	options := MerkleTreeOptions{MaxValueBytes: 1 << 20}
+	tree, err := NewStandardMerkleTree([]string{"alice", "bob"}, options)
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	root := tree.Root()
+	err = tree.AppendLeaves([]string{"charlie", strings.Repeat("x", 2<<20)})
+	var sizeErr *ValueSizeError
+	if !errors.As(err, &sizeErr) || sizeErr.Index != 3 {
+		t.Fatalf("Expected a ValueSizeError for value 3, got %v", err)
+	}
+	if tree.Root() != root || len(tree.Values) != 2 {
+		t.Errorf("A rejected append changed the tree")
+	}
+
+	// The limit survives conversion
+	simple, err := tree.ToSimple()
+	if err != nil {
+		t.Fatalf("ToSimple failed: %v", err)
+	}
+	if simple.maxValueBytes != options.MaxValueBytes {
+		t.Errorf("Converted tree has MaxValueBytes %d", simple.maxValueBytes)
+	}
+
+	set, err := NewLeafSet[string](options)
+	if err != nil {
+		t.Fatalf("NewLeafSet failed: %v", err)
+	}
+	if err := set.Add("alice"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := set.Add(strings.Repeat("x", 2<<20)); !errors.As(err, &sizeErr) || sizeErr.Index != 1 {
+		t.Errorf("Expected a ValueSizeError for value 1, got %v", err)
+	}
+	if set.Cursor() != 1 {
+		t.Errorf("The large value was added")
+	}
+}
+
+// TestErrorPreviewsBounded checks that errors quoting a multi-megabyte
+// value stay short.
+func TestErrorPreviewsBounded(t *testing.T) {
+	large := strings.Repeat("9", 3<<20)
+	for name, err := range map[string]error{
+		"address": func() error { _, err := ParseAddress("0x" + large); return err }(),
+		"uint256": new(U256).UnmarshalText([]byte("z" + large)),
+	} {
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+			continue
+		}
+		if len(err.Error()) > 200 {
+			t.Errorf("%s: error message is %d bytes long", name, len(err.Error()))
+		}
+	}
+
+	for _, value := range []any{large, []byte(large), HexString(large), strings.Repeat("é", 1<<20)} {
+		if preview := previewValue(value); len(preview) > 4*maxPreviewLength+len("...") || !strings.HasSuffix(preview, "...") {
+			t.Errorf("Preview of a %T is %d bytes long", value, len(preview))
+		}
+	}
+	if preview := previewValue("short"); preview != "short" {
+		t.Errorf("Preview of a short value is %q", preview)
+	}
+}
@@ -0,0 +1,200 @@
+package merkletree
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// version is the semantic version of this library.
+// It is recorded in build manifests so a tree can be reproduced later
+// with the same implementation.
+const version = "0.2.0"
+
+// Version returns the semantic version of the merkletree library.
+func Version() string {
+	return version
+}
+
+// Capability names reported by Capabilities. Each is added in the release
+// that introduces the feature and never removed, so tooling can test for a
+// feature instead of comparing versions.
+const (
+	CapabilitySHA256              = "sha256"               // HashAlgorithmSHA256 node hash
+	CapabilityCanonicalMultiProof = "canonical-multiproof" // GetMultiProof accepts any index order; CanonicalBytes
+	CapabilityLeafMetadata        = "leaf-metadata"        // MerkleTreeOptions.LeafMetadata
+	CapabilityQuarantine          = "quarantine"           // MerkleTreeOptions.QuarantineInvalid
+	CapabilityPrefixIndex         = "prefix-index"         // FindByHashPrefix with MerkleTreeOptions.PrefixIndex
+	CapabilityParallelBuild       = "parallel-build"       // MerkleTreeOptions.Parallelism
+	CapabilityDropValues          = "drop-values"          // DropValuesAfterBuild and ValueProvider
+	CapabilityBuildStamp          = "build-stamp"          // BuildStamp and CompareStamps
+	CapabilityLeafHashIterator    = "leaf-hash-iterator"   // LeafHashes and RootBuilder
+	CapabilityVerifyContext       = "verify-context"       // VerifyCtx and BatchVerifyCtx
+	CapabilityCalldata            = "calldata"             // ParseSolidityProofCalldata
+	CapabilitySelfTest            = "self-test"            // SelfTest
+	CapabilityVersionedOutput     = "versioned-output"     // Dumps, claims and envelopes record Version
+	CapabilityDumpIntegrity       = "dump-integrity"       // Dump footers, VerifyDumpIntegrity and LoadSimpleMerkleTreeFrom
+	CapabilityLeavesDump          = "leaves-dump"          // DumpLeaves, VerifyLeavesDump and LoadFromLeavesDump
+	CapabilityDetailedValidation  = "detailed-validation"  // ValidateTreeDetailed and ValidateDetailed
+	CapabilityGoSource            = "go-source"            // ExportGoSource
+	CapabilityCompatibilityModes  = "compatibility-modes"  // MerkleTreeOptions.Compatibility and RootUnderMode
+	CapabilityProofStats          = "proof-stats"          // ProofStats
+	CapabilityLeafSetCheckpoints  = "leafset-checkpoints"  // LeafSet, Checkpoint and ResumeLeafSet
+	CapabilityPairCache           = "pair-cache"           // PairCache and BatchVerifyWithOptions
+	CapabilityHashConformance     = "hash-conformance"     // merkletest.CheckNodeHash and CheckLeafHash
+	CapabilityProbeProof          = "probe-proof"          // ProbeProof
+	CapabilityIssuanceRecorder    = "issuance-recorder"    // SetIssuanceRecorder, FileRecorder and FindByLeafHash
+	CapabilityKeccakBackends      = "keccak-backends"      // SelectedBackend
+	CapabilityAppendLeaves        = "append-leaves"        // AppendLeaves
+	CapabilityStrictLeafValues    = "strict-leaf-values"   // NewStandardMerkleTreeStrict, Address and U256
+	CapabilityParseRoot           = "parse-root"           // ParseRoot
+	CapabilityExpandMultiProof    = "expand-multiproof"    // ExpandMultiProof and CombineToMultiProof
+	CapabilityTreeSizing          = "tree-sizing"          // TreeNodeCount, TreeDepthFor, LeafRange and SerializedDumpSizeEstimate
+	CapabilityClaimPages          = "claim-pages"          // ExportClaimsPage, ExportClaims and GET /claims
+	CapabilitySimpleConversion    = "simple-conversion"    // ToSimple and FromSimpleWithValues
+	CapabilityProofPregeneration  = "proof-pregeneration"  // MerkleTreeOptions.PregenerateProofs
+	CapabilityVerifyDiagnosis     = "verify-diagnosis"     // DiagnoseStandardVerification and gomerkle why
+	CapabilityResourceLimits      = "resource-limits"      // MerkleTreeOptions.Limits and ErrResourceLimit
+	CapabilityShuffledOrder       = "shuffled-order"       // MerkleTreeOptions.ShuffleSeed and CheckShuffle
+	CapabilityPackedProofs        = "packed-proofs"        // ProofToPackedBytes, ProofFromPackedBytes and packed /verify bodies
+	CapabilityBytesValues         = "bytes-values"         // Non-UTF-8 string values written as {"$bytes": "0x..."}
+	CapabilityLazyIndexes         = "lazy-indexes"         // MerkleTreeOptions.EagerIndexes and WarmIndexes
+	CapabilityErrorCodes          = "error-codes"          // Code, CodedError and the Code constants
+	CapabilityStandardLoad        = "standard-load"        // LoadStandardMerkleTree
+	CapabilityProofContext        = "proof-context"        // GetProofWithContext and SetContextRedactor
+	CapabilitySimpleLoadOptions   = "simple-load-options"  // LoadSimpleMerkleTreeWithOptions
+	CapabilityMaxValueBytes       = "max-value-bytes"      // MerkleTreeOptions.MaxValueBytes and ValueSizeError
+	CapabilityEntryIterator       = "entry-iterator"       // AllEntries
+	CapabilityOneShotProof        = "one-shot-proof"       // BuildAndProve and BuildAndProveSimple
+	CapabilityRender              = "render"               // Render
+	CapabilityTreeMultiProof      = "tree-multiproof"      // MerkleTreeImpl.GetMultiProof
+	CapabilityVerifyMultiProof    = "verify-multiproof"    // VerifyStandardMultiProof
+	CapabilityBytesLeafHash       = "bytes-leaf-hash"      // BytesLeafHash for simple trees under CompatLatest
+	CapabilitySortLeavesTriState  = "sort-leaves-tristate" // MerkleTreeOptions.SortLeaves as *bool, and Bool
+	CapabilityLeafEncoding        = "leaf-encoding"        // NewStandardMerkleTreeWithEncoding, ABIEncode and leafEncoding dumps
+	CapabilityBigIntLeaves        = "big-int-leaves"       // *big.Int and []any tuple values in StandardLeafHash
+	CapabilityAddressArrays       = "address-arrays"       // 20-byte array types, such as common.Address, encode as addresses
+	CapabilityPackedSlices        = "packed-slices"        // Slices, nested slices and [N]byte arrays in StandardLeafHash
+	CapabilityHexStringMethods    = "hexstring-methods"    // HexString.Bytes, Len, IsValid and validating JSON
+	CapabilityProofBundle         = "proof-bundle"         // Proof and GetProofBundle
+	CapabilityProofCalldata       = "proof-calldata"       // ProofToHexArray and ProofToCalldata
+	CapabilityMultiProofCalldata  = "multiproof-calldata"  // MultiProof.ToCalldataArgs and SortForSolidity
+	CapabilityMultiProofJSON      = "multiproof-json"      // The multiproof-v1 JSON form of MultiProof
+	CapabilityNodeHashRegistry    = "node-hash-registry"   // RegisterNodeHash and HashAlgorithms; dumps name the hash in "hash"
+	CapabilityDumpOptions         = "dump-options"         // DumpOptions in the "options" field of dumps
+	CapabilityStreamingDump       = "streaming-dump"       // StandardMerkleTree.DumpTo and LoadStandardMerkleTreeFrom
+	CapabilityBinaryDump          = "binary-dump"          // StandardMerkleTree.ExportBinary and LoadStandardMerkleTreeBinary
+	CapabilityOZDumpLoader        = "oz-dump-loader"       // LoadOZStandardDump
+	CapabilityMerkleTreeJS        = "merkletreejs"         // NewMerkleTreeJS and VerifyMerkleTreeJS
+	CapabilitySHA256Trees         = "sha256-trees"         // NewSimpleMerkleTreeSHA256 and SHA256LeafHash
+	CapabilityBlake2b256          = "blake2b-256"          // NewSimpleMerkleTreeBlake2b256 and the "blake2b-256" node hash
+	CapabilityBitcoinTree         = "bitcoin-tree"         // NewBitcoinMerkleTree and VerifyBitcoinProof
+	CapabilityRFC6962             = "rfc6962"              // NewRFC6962Tree and VerifyRFC6962Inclusion
+	CapabilityIncrementalTree     = "incremental-tree"     // IncrementalMerkleTree, append-only with the roots of standard trees
+	CapabilityUpdateLeaf          = "update-leaf"          // UpdateLeaf rehashes only the path of a replaced value that keeps its place
+	CapabilityNonInclusion        = "non-inclusion"        // GetNonInclusionProof and VerifyNonInclusion for sorted trees with PositionalNodeHash, which ProcessProofAt and Verify prove inclusion in
+	CapabilitySparseTree          = "sparse-tree"          // SparseMerkleTree over 256-bit keys
+	CapabilityMMR                 = "mmr"                  // MMR, VerifyMMRProof and mmr-v1 dumps
+	CapabilityRangeProof          = "range-proof"          // GetRangeProof and VerifyRangeProof
+)
+
+// capabilities lists the features of this version, in the order they landed.
+var capabilities = []string{
+	CapabilitySHA256,
+	CapabilityPrefixIndex,
+	CapabilitySelfTest,
+	CapabilityParallelBuild,
+	CapabilityQuarantine,
+	CapabilityLeafMetadata,
+	CapabilityCalldata,
+	CapabilityCanonicalMultiProof,
+	CapabilityDropValues,
+	CapabilityBuildStamp,
+	CapabilityLeafHashIterator,
+	CapabilityVerifyContext,
+	CapabilityVersionedOutput,
+	CapabilityDumpIntegrity,
+	CapabilityLeavesDump,
+	CapabilityDetailedValidation,
+	CapabilityGoSource,
+	CapabilityCompatibilityModes,
+	CapabilityProofStats,
+	CapabilityLeafSetCheckpoints,
+	CapabilityPairCache,
+	CapabilityHashConformance,
+	CapabilityProbeProof,
+	CapabilityIssuanceRecorder,
+	CapabilityKeccakBackends,
+	CapabilityAppendLeaves,
+	CapabilityStrictLeafValues,
+	CapabilityParseRoot,
+	CapabilityExpandMultiProof,
+	CapabilityTreeSizing,
+	CapabilityClaimPages,
+	CapabilitySimpleConversion,
+	CapabilityProofPregeneration,
+	CapabilityVerifyDiagnosis,
+	CapabilityResourceLimits,
+	CapabilityShuffledOrder,
+	CapabilityPackedProofs,
+	CapabilityBytesValues,
+	CapabilityLazyIndexes,
+	CapabilityErrorCodes,
+	CapabilityStandardLoad,
+	CapabilityProofContext,
+	CapabilitySimpleLoadOptions,
+	CapabilityMaxValueBytes,
+	CapabilityEntryIterator,
+	CapabilityOneShotProof,
+	CapabilityRender,
+	CapabilityTreeMultiProof,
+	CapabilityVerifyMultiProof,
+	CapabilityBytesLeafHash,
+	CapabilitySortLeavesTriState,
+	CapabilityLeafEncoding,
+	CapabilityBigIntLeaves,
+	CapabilityAddressArrays,
+	CapabilityPackedSlices,
+	CapabilityHexStringMethods,
+	CapabilityProofBundle,
+	CapabilityProofCalldata,
+	CapabilityMultiProofCalldata,
+	CapabilityMultiProofJSON,
+	CapabilityNodeHashRegistry,
+	CapabilityDumpOptions,
+	CapabilityStreamingDump,
+	CapabilityBinaryDump,
+	CapabilityOZDumpLoader,
+	CapabilityMerkleTreeJS,
+	CapabilitySHA256Trees,
+	CapabilityBlake2b256,
+	CapabilityBitcoinTree,
+	CapabilityRFC6962,
+	CapabilityIncrementalTree,
+	CapabilityUpdateLeaf,
+	CapabilityNonInclusion,
+	CapabilitySparseTree,
+	CapabilityMMR,
+	CapabilityRangeProof,
+}
+
+// Capabilities returns the feature flags supported by this version of the library.
+func Capabilities() []string {
+	return slices.Clone(capabilities)
+}
+
+// RequireCapabilities returns an error wrapping ErrMissingCapability that
+// names every requested capability this version lacks, or nil if it has them
+// all. Tools can call it at startup to fail early on an old library.
+func RequireCapabilities(names ...string) error {
+	var missing []string
+	for _, name := range names {
+		if !slices.Contains(capabilities, name) && !slices.Contains(missing, name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: version %s lacks %s", ErrMissingCapability, version, strings.Join(missing, ", "))
+	}
+	return nil
+}
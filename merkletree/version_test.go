@@ -0,0 +1,90 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRequireCapabilities(t *testing.T) {
+	if err := RequireCapabilities(Capabilities()...); err != nil {
+		t.Errorf("Every listed capability should be satisfied: %v", err)
+	}
+	if err := RequireCapabilities(); err != nil {
+		t.Errorf("No requirements should be satisfied: %v", err)
+	}
+
+	err := RequireCapabilities(CapabilitySHA256, "teleportation", "time-travel", "teleportation")
+	if !errors.Is(err, ErrMissingCapability) {
+		t.Fatalf("Expected ErrMissingCapability, got %v", err)
+	}
+	msg := err.Error()
+	for _, name := range []string{"teleportation", "time-travel", Version()} {
+		if !strings.Contains(msg, name) {
+			t.Errorf("Error %q should mention %q", msg, name)
+		}
+	}
+	if strings.Contains(msg, CapabilitySHA256) || strings.Count(msg, "teleportation") != 1 {
+		t.Errorf("Error %q should list each missing capability once", msg)
+	}
+}
+
+func TestCapabilitiesIsACopy(t *testing.T) {
+	caps := Capabilities()
+	caps[0] = "tampered"
+	if Capabilities()[0] == "tampered" {
+		t.Error("Capabilities should return a copy")
+	}
+}
+
+func TestOutputsRecordVersion(t *testing.T) {
+	standard, err := NewStandardMerkleTree([]string{"a", "b", "c"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	simple, err := NewSimpleMerkleTree([]BytesLike{"a", "b", "c"}, SimpleMerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+	claim, err := standard.ExportClaim(1)
+	if err != nil {
+		t.Fatalf("Failed to export claim: %v", err)
+	}
+	standardDump, err := standard.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	simpleDump, err := simple.Dump()
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	stamp, err := standard.BuildStamp()
+	if err != nil {
+		t.Fatalf("Failed to stamp tree: %v", err)
+	}
+
+	outputs := map[string]any{
+		"standard dump":      standardDump,
+		"simple dump":        simpleDump,
+		"claim":              claim,
+		"envelope":           claim.Envelope(),
+		"hand-made envelope": ProofEnvelope{Root: "0x01", LeafHash: "0x01"},
+		"stamp":              stamp,
+	}
+	for name, output := range outputs {
+		data, err := json.Marshal(output)
+		if err != nil {
+			t.Fatalf("%s: failed to marshal: %v", name, err)
+		}
+		var written struct {
+			Version string `json:"version"`
+		}
+		if err := json.Unmarshal(data, &written); err != nil {
+			t.Fatalf("%s: failed to unmarshal: %v", name, err)
+		}
+		if written.Version != Version() {
+			t.Errorf("%s records version %q, want %q", name, written.Version, Version())
+		}
+	}
+}
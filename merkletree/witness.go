@@ -0,0 +1,254 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// WitnessFormat selects the encoding written by ExportCircuitWitness.
+type WitnessFormat int
+
+const (
+	// WitnessJSON writes a JSON object whose field elements are decimal strings,
+	// the input format used by circom and snarkjs.
+	WitnessJSON WitnessFormat = iota
+
+	// WitnessBinary writes raw 32-byte little-endian records in the order:
+	// leaf, siblings (Depth records), selectors (Depth records), root.
+	WitnessBinary
+)
+
+// PairOrder describes how the tree's node hash orders its two inputs,
+// which determines the meaning of the witness selector bits.
+type PairOrder int
+
+const (
+	// PairOrderSorted is for node hashes that sort the pair before hashing,
+	// like StandardNodeHash. A selector of 1 means the sibling is the smaller
+	// value and goes on the left.
+	PairOrderSorted PairOrder = iota
+
+	// PairOrderPositional is for node hashes that hash (left, right) in tree
+	// order. A selector of 1 means the path node is a right child.
+	PairOrderPositional
+)
+
+// WitnessOptions configures ExportCircuitWitness.
+type WitnessOptions struct {
+	// Depth is the fixed number of path levels in the witness.
+	// Zero means the actual proof length; a smaller non-zero value is an error.
+	Depth int
+
+	// PaddingHashes are the siblings used for levels beyond the real path,
+	// first padded level first. Missing entries are 32 zero bytes.
+	// Padded levels always have a zero selector.
+	PaddingHashes []HexString
+
+	// Format selects JSON or binary output.
+	Format WitnessFormat
+
+	// Modulus is the field every element must be below in JSON output.
+	// Nil means the BN254 scalar field used by circom and gnark.
+	Modulus *big.Int
+
+	// PairOrder must match the tree's node hash; see PairOrder.
+	PairOrder PairOrder
+}
+
+// CircuitWitness is the JSON form of a witness.
+type CircuitWitness struct {
+	Leaf       string   `json:"leaf"`       // Leaf hash as a decimal field element
+	Siblings   []string `json:"siblings"`   // Sibling path, padded to Depth
+	Selectors  []int    `json:"selectors"`  // Per-level selector bits, padded to Depth
+	PathLength int      `json:"pathLength"` // Number of real (unpadded) levels
+	Root       string   `json:"root"`       // Tree root as a decimal field element
+}
+
+// bn254ScalarField is the order of the BN254 scalar field.
+var bn254ScalarField, _ = new(big.Int).SetString(
+	"21888242871839275222246405745257275088548364400416034343698204186575808495617", 10)
+
+// BN254ScalarField returns the BN254 scalar field modulus used by default for JSON witnesses.
+func BN254ScalarField() *big.Int {
+	return new(big.Int).Set(bn254ScalarField)
+}
+
+// ExportCircuitWitness writes the Merkle path of the value at leafIndex in a
+// fixed-width form suitable for zk circuits: the leaf hash, the sibling path
+// padded to opts.Depth, and one selector bit per level.
+//
+// Folding the path reproduces the root for the real levels: at each level,
+// hash(sibling, current) when the selector is 1 and hash(current, sibling)
+// otherwise, with a plain order-respecting hash. Padded levels extend the path
+// beyond the root; circuits that use them must fold only PathLength levels or
+// supply padding hashes that match their own tree shape.
+func (m *MerkleTreeImpl[T]) ExportCircuitWitness(w io.Writer, leafIndex int, opts WitnessOptions) error {
+	if err := m.validateValueAt(leafIndex); err != nil {
+		return err
+	}
+
+	nodeHash := m.NodeHash
+	if nodeHash == nil {
+		nodeHash = StandardNodeHash
+	}
+
+	index := m.Values[leafIndex].TreeIndex
+	current := m.Tree[index]
+
+	var siblings []HexString
+	var selectors []int
+	for index > 0 {
+		sibling := m.Tree[siblingIndex(index)]
+
+		commutative := nodeHash(current, sibling) == nodeHash(sibling, current)
+		var selector int
+		switch opts.PairOrder {
+		case PairOrderSorted:
+			if !commutative {
+				return fmt.Errorf("%w: tree node hash is order-sensitive; use PairOrderPositional", ErrPairOrderMismatch)
+			}
+			cmp, err := Compare(sibling, current)
+			if err != nil {
+				return fmt.Errorf("error comparing path nodes: %w", err)
+			}
+			if cmp < 0 {
+				selector = 1
+			}
+		case PairOrderPositional:
+			if commutative && current != sibling {
+				return fmt.Errorf("%w: tree node hash sorts pairs, so positional selectors do not describe the hashing; use PairOrderSorted", ErrPairOrderMismatch)
+			}
+			if index%2 == 0 {
+				selector = 1
+			}
+		default:
+			return fmt.Errorf("%w: unknown pair order %d", ErrInvalidWitnessOptions, opts.PairOrder)
+		}
+
+		siblings = append(siblings, sibling)
+		selectors = append(selectors, selector)
+		current = m.Tree[parentIndex(index)]
+		index = parentIndex(index)
+	}
+
+	pathLength := len(siblings)
+	depth := opts.Depth
+	if depth == 0 {
+		depth = pathLength
+	}
+	if depth < pathLength {
+		return fmt.Errorf("%w: depth %d is smaller than the path length %d", ErrInvalidWitnessOptions, depth, pathLength)
+	}
+	for level := 0; level < depth-pathLength; level++ {
+		padding := HexString("0x" + zeroHashHex)
+		if level < len(opts.PaddingHashes) {
+			padding = opts.PaddingHashes[level]
+		}
+		if err := CheckValidMerkleNode(padding); err != nil {
+			return fmt.Errorf("%w: padding hash %d: %v", ErrInvalidWitnessOptions, level, err)
+		}
+		siblings = append(siblings, padding)
+		selectors = append(selectors, 0)
+	}
+
+	leaf := m.Tree[m.Values[leafIndex].TreeIndex]
+	switch opts.Format {
+	case WitnessJSON:
+		return writeWitnessJSON(w, leaf, siblings, selectors, pathLength, m.Root(), opts.Modulus)
+	case WitnessBinary:
+		return writeWitnessBinary(w, leaf, siblings, selectors, m.Root())
+	default:
+		return fmt.Errorf("%w: unknown format %d", ErrInvalidWitnessOptions, opts.Format)
+	}
+}
+
+// zeroHashHex is the hex encoding of 32 zero bytes.
+const zeroHashHex = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// fieldElement converts a 32-byte node to a big-endian integer and checks it against modulus.
+func fieldElement(node HexString, modulus *big.Int) (*big.Int, error) {
+	b, err := ToBytes(node)
+	if err != nil {
+		return nil, err
+	}
+	n := new(big.Int).SetBytes(b)
+	if n.Cmp(modulus) >= 0 {
+		return nil, fmt.Errorf("%w: %s does not fit the field", ErrFieldOverflow, node)
+	}
+	return n, nil
+}
+
+// writeWitnessJSON writes the witness as decimal field elements.
+func writeWitnessJSON(w io.Writer, leaf HexString, siblings []HexString, selectors []int, pathLength int, root HexString, modulus *big.Int) error {
+	if modulus == nil {
+		modulus = bn254ScalarField
+	}
+
+	witness := CircuitWitness{
+		Siblings:   make([]string, len(siblings)),
+		Selectors:  selectors,
+		PathLength: pathLength,
+	}
+
+	leafElem, err := fieldElement(leaf, modulus)
+	if err != nil {
+		return fmt.Errorf("leaf: %w", err)
+	}
+	witness.Leaf = leafElem.String()
+
+	for i, s := range siblings {
+		elem, err := fieldElement(s, modulus)
+		if err != nil {
+			return fmt.Errorf("sibling %d: %w", i, err)
+		}
+		witness.Siblings[i] = elem.String()
+	}
+
+	rootElem, err := fieldElement(root, modulus)
+	if err != nil {
+		return fmt.Errorf("root: %w", err)
+	}
+	witness.Root = rootElem.String()
+
+	return json.NewEncoder(w).Encode(witness)
+}
+
+// writeWitnessBinary writes the witness as little-endian 32-byte records.
+func writeWitnessBinary(w io.Writer, leaf HexString, siblings []HexString, selectors []int, root HexString) error {
+	records := make([]HexString, 0, 2*len(siblings)+2)
+	records = append(records, leaf)
+	records = append(records, siblings...)
+
+	var buf []byte
+	for _, r := range records {
+		b, err := ToBytes(r)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, reverseBytes(b)...)
+	}
+	for _, s := range selectors {
+		record := make([]byte, 32)
+		record[0] = byte(s)
+		buf = append(buf, record...)
+	}
+	rootBytes, err := ToBytes(root)
+	if err != nil {
+		return err
+	}
+	buf = append(buf, reverseBytes(rootBytes)...)
+
+	_, err = w.Write(buf)
+	return err
+}
+
+// reverseBytes returns a reversed copy of b.
+func reverseBytes(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
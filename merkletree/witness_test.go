@@ -0,0 +1,195 @@
+package merkletree
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// positionalNodeHash hashes (left, right) in tree order without sorting.
+func positionalNodeHash(a BytesLike, b BytesLike) HexString {
+	left, _ := ToBytes(a)
+	right, _ := ToBytes(b)
+	h := sha3.NewLegacyKeccak256()
+	h.Write(left)
+	h.Write(right)
+	return HexString("0x" + hex.EncodeToString(h.Sum(nil)))
+}
+
+// foldBinaryWitness parses a binary witness and folds the first pathLength
+// levels with an order-respecting keccak, returning the computed and stored roots.
+func foldBinaryWitness(t *testing.T, data []byte, depth, pathLength int) (computed, stored HexString) {
+	t.Helper()
+	if len(data) != 32*(2*depth+2) {
+		t.Fatalf("Expected %d bytes, got %d", 32*(2*depth+2), len(data))
+	}
+	record := func(i int) []byte { return reverseBytes(data[32*i : 32*(i+1)]) }
+
+	current := HexString("0x" + hex.EncodeToString(record(0)))
+	for level := 0; level < pathLength; level++ {
+		sibling := HexString("0x" + hex.EncodeToString(record(1+level)))
+		if data[32*(1+depth+level)] == 1 {
+			current = positionalNodeHash(sibling, current)
+		} else {
+			current = positionalNodeHash(current, sibling)
+		}
+	}
+	return current, HexString("0x" + hex.EncodeToString(record(2*depth+1)))
+}
+
+func TestExportCircuitWitnessBinaryPinned(t *testing.T) {
+	tree, err := NewStandardMerkleTree([]string{"a", "b", "c", "d"}, MerkleTreeOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create tree: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tree.ExportCircuitWitness(&buf, 2, WitnessOptions{Format: WitnessBinary}); err != nil {
+		t.Fatalf("Failed to export witness: %v", err)
+	}
+
+	want := "" +
+		"b2b999220b797dc8698f435a5a4694a8ccad7acdbfdde30f06531f3c39b6420b" + // leaf "c"
+		"f3d2b375ff74b60aa10afc9be114bc829c4c2f330285dc7ab16e2362858e91f1" + // sibling "d"
+		"f8e16a0c5b028f08257c603e362a87b301d2b06b7d37b0aeef89b146d8215b80" + // sibling node 1
+		"0000000000000000000000000000000000000000000000000000000000000000" + // selector level 0
+		"0100000000000000000000000000000000000000000000000000000000000000" + // selector level 1
+		"bfe19ccedd9929deb9b552255f349dbaa6876e53d7599285c57dd0e9903f2068" // root
+	if got := hex.EncodeToString(buf.Bytes()); got != want {
+		t.Errorf("Witness bytes changed:\ngot  %s\nwant %s", got, want)
+	}
+
+	computed, stored := foldBinaryWitness(t, buf.Bytes(), 2, 2)
+	if stored != tree.Root() {
+		t.Errorf("Stored root %s does not match tree root %s", stored, tree.Root())
+	}
+	if computed != tree.Root() {
+		t.Errorf("Folded root %s does not match tree root %s", computed, tree.Root())
+	}
+}
+
+func TestExportCircuitWitnessFoldsToRoot(t *testing.T) {
+	values := []string{"one", "two", "three", "four", "five"}
+
+	sorted, err := NewStandardMerkleTree(values, MerkleTreeOptions{SortLeaves: Bool(true)})
+	if err != nil {
+		t.Fatalf("Failed to create sorted tree: %v", err)
+	}
+
+	leaves := make([]BytesLike, len(values))
+	for i, v := range values {
+		leaves[i] = v
+	}
+	positional, err := NewSimpleMerkleTree(leaves, SimpleMerkleTreeOptions{NodeHash: positionalNodeHash})
+	if err != nil {
+		t.Fatalf("Failed to create positional tree: %v", err)
+	}
+
+	for i := range values {
+		proof, _ := sorted.ProofForIndex(i)
+		var buf bytes.Buffer
+		opts := WitnessOptions{Format: WitnessBinary, Depth: 4, PairOrder: PairOrderSorted}
+		if err := sorted.ExportCircuitWitness(&buf, i, opts); err != nil {
+			t.Fatalf("sorted: failed to export witness for leaf %d: %v", i, err)
+		}
+		computed, _ := foldBinaryWitness(t, buf.Bytes(), 4, len(proof))
+		if computed != sorted.Root() {
+			t.Errorf("sorted: leaf %d folds to %s, want %s", i, computed, sorted.Root())
+		}
+	}
+
+	for i := range values {
+		proof, _ := positional.ProofForIndex(i)
+		var buf bytes.Buffer
+		opts := WitnessOptions{Format: WitnessBinary, Depth: 4, PairOrder: PairOrderPositional}
+		if err := positional.ExportCircuitWitness(&buf, i, opts); err != nil {
+			t.Fatalf("positional: failed to export witness for leaf %d: %v", i, err)
+		}
+		computed, _ := foldBinaryWitness(t, buf.Bytes(), 4, len(proof))
+		if computed != positional.Root() {
+			t.Errorf("positional: leaf %d folds to %s, want %s", i, computed, positional.Root())
+		}
+	}
+}
+
+func TestExportCircuitWitnessPairOrderMismatch(t *testing.T) {
+	standard, _ := NewStandardMerkleTree([]string{"a", "b", "c"}, MerkleTreeOptions{})
+	err := standard.ExportCircuitWitness(&bytes.Buffer{}, 0, WitnessOptions{PairOrder: PairOrderPositional})
+	if !errors.Is(err, ErrPairOrderMismatch) {
+		t.Errorf("Expected ErrPairOrderMismatch for positional selectors on sorted pairs, got %v", err)
+	}
+
+	positional, _ := NewSimpleMerkleTree([]BytesLike{"a", "b", "c"}, SimpleMerkleTreeOptions{NodeHash: positionalNodeHash})
+	err = positional.ExportCircuitWitness(&bytes.Buffer{}, 0, WitnessOptions{PairOrder: PairOrderSorted})
+	if !errors.Is(err, ErrPairOrderMismatch) {
+		t.Errorf("Expected ErrPairOrderMismatch for sorted selectors on positional hash, got %v", err)
+	}
+}
+
+func TestExportCircuitWitnessJSON(t *testing.T) {
+	tree, _ := NewStandardMerkleTree([]string{"a", "b", "c", "d"}, MerkleTreeOptions{})
+
+	// Keccak outputs are 256-bit, so use a modulus every hash fits under
+	modulus := new(big.Int).Lsh(big.NewInt(1), 256)
+	padding := HexString("0x" + "11" + zeroHashHex[2:])
+
+	var buf bytes.Buffer
+	opts := WitnessOptions{Depth: 4, PaddingHashes: []HexString{padding}, Modulus: modulus}
+	if err := tree.ExportCircuitWitness(&buf, 2, opts); err != nil {
+		t.Fatalf("Failed to export witness: %v", err)
+	}
+
+	var witness CircuitWitness
+	if err := json.Unmarshal(buf.Bytes(), &witness); err != nil {
+		t.Fatalf("Failed to parse witness: %v", err)
+	}
+
+	if witness.PathLength != 2 || len(witness.Siblings) != 4 || len(witness.Selectors) != 4 {
+		t.Fatalf("Unexpected witness shape: %+v", witness)
+	}
+
+	leaf, _ := new(big.Int).SetString(witness.Leaf, 10)
+	if HexString("0x"+hex.EncodeToString(leaf.FillBytes(make([]byte, 32)))) != tree.Tree[tree.Values[2].TreeIndex] {
+		t.Error("Leaf element does not match the leaf hash")
+	}
+
+	// The first padded level uses the supplied hash, the second defaults to zero
+	paddedOne, _ := new(big.Int).SetString(witness.Siblings[2], 10)
+	if HexString("0x"+hex.EncodeToString(paddedOne.FillBytes(make([]byte, 32)))) != padding {
+		t.Errorf("First padding sibling = %s, want %s", witness.Siblings[2], padding)
+	}
+	if witness.Siblings[3] != "0" || witness.Selectors[2] != 0 || witness.Selectors[3] != 0 {
+		t.Errorf("Unexpected default padding: %v %v", witness.Siblings, witness.Selectors)
+	}
+}
+
+func TestExportCircuitWitnessErrors(t *testing.T) {
+	tree, _ := NewStandardMerkleTree([]string{"a", "b", "c", "d"}, MerkleTreeOptions{})
+
+	t.Run("depth too small", func(t *testing.T) {
+		err := tree.ExportCircuitWitness(&bytes.Buffer{}, 0, WitnessOptions{Depth: 1})
+		if !errors.Is(err, ErrInvalidWitnessOptions) {
+			t.Errorf("Expected ErrInvalidWitnessOptions, got %v", err)
+		}
+	})
+
+	t.Run("invalid index", func(t *testing.T) {
+		err := tree.ExportCircuitWitness(&bytes.Buffer{}, 9, WitnessOptions{})
+		if !errors.Is(err, ErrInvalidIndex) {
+			t.Errorf("Expected ErrInvalidIndex, got %v", err)
+		}
+	})
+
+	t.Run("field overflow", func(t *testing.T) {
+		// No 32-byte hash of this tree fits a 20-bit field
+		err := tree.ExportCircuitWitness(&bytes.Buffer{}, 0, WitnessOptions{Modulus: big.NewInt(1 << 20)})
+		if !errors.Is(err, ErrFieldOverflow) {
+			t.Errorf("Expected ErrFieldOverflow, got %v", err)
+		}
+	})
+}